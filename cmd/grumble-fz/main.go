@@ -0,0 +1,474 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Command grumble-fz inspects and repairs the freeze files (main.fz
+// and log.fz) a grumble server keeps in its data directory.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/freezer"
+	"mumble.info/grumble/pkg/password"
+)
+
+var usage = `usage: grumble-fz <command> [options] <server-dir> [args]
+
+ <server-dir> is a server's directory in grumble's data
+ directory, such as $DATADIR/servers/1.
+
+ Commands that modify the freeze files merge log.fz into
+ main.fz, keep the old files as main.fz.<time> and
+ log.fz.<time>, and must only be used while grumble is
+ not running.
+
+ dump [--merged] <server-dir>
+     Print main.fz and the transactions of log.fz as JSON.
+     With --merged, print the server's state after
+     replaying log.fz instead.
+
+ check <server-dir>
+     Check that the freeze files can be read, and that
+     the channel tree, links and users are consistent.
+
+ set-superuser-password <server-dir> <password>
+     Set the SuperUser password.
+
+ delete-channel <server-dir> <channel-id>
+     Delete a channel and its subchannels.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "dump":
+		err = dump(args)
+	case "check":
+		err = check(args)
+	case "set-superuser-password":
+		err = setSuperUserPassword(args)
+	case "delete-channel":
+		err = deleteChannel(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "grumble-fz: unknown command %q\n\n%v", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "grumble-fz %v: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs parses the options and arguments of a command, which
+// takes a server directory followed by nargs other arguments.
+func parseArgs(fset *flag.FlagSet, args []string, nargs int) ([]string, error) {
+	fset.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := fset.Parse(args); err != nil {
+		return nil, err
+	}
+	if fset.NArg() != nargs+1 {
+		return nil, errors.New("wrong number of arguments, see grumble-fz help")
+	}
+	return fset.Args(), nil
+}
+
+// frozen holds the contents of a server's freeze files.
+type frozen struct {
+	dir  string
+	main *freezer.Server
+	// The transaction groups of log.fz that could be read.
+	log [][]interface{}
+	// The error that stopped reading log.fz, if any.
+	logErr error
+}
+
+// load reads the freeze files of the server in dir. A missing log.fz
+// is treated as empty. Errors in log.fz are recorded in logErr, since
+// the transactions before them are still usable.
+func load(dir string) (*frozen, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "main.fz"))
+	if err != nil {
+		return nil, err
+	}
+	fz := &frozen{dir: dir, main: &freezer.Server{}}
+	if err = proto.Unmarshal(buf, fz.main); err != nil {
+		return nil, fmt.Errorf("main.fz: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "log.fz"))
+	if os.IsNotExist(err) {
+		return fz, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	walker, err := freezer.NewReaderWalker(f)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		entries, err := walker.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			fz.logErr = fmt.Errorf("log.fz: transaction %v: %v", len(fz.log), err)
+			break
+		}
+		fz.log = append(fz.log, entries)
+	}
+	return fz, nil
+}
+
+// merged returns the server's state after replaying the log.
+func (fz *frozen) merged() *freezer.Server {
+	fs := proto.Clone(fz.main).(*freezer.Server)
+	for _, entries := range fz.log {
+		freezer.Apply(fs, entries)
+	}
+	return fs
+}
+
+// save replaces the freeze files with fs and an empty log, keeping
+// the old files as backups.
+func (fz *frozen) save(fs *freezer.Server) error {
+	buf, err := proto.Marshal(fs)
+	if err != nil {
+		return err
+	}
+
+	suffix := backupSuffix(fz.dir)
+	for _, name := range []string{"main.fz", "log.fz"} {
+		fn := filepath.Join(fz.dir, name)
+		err = os.Rename(fn, fn+suffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil {
+			fmt.Printf("Kept the old %v as %v\n", name, name+suffix)
+		}
+	}
+
+	f, err := ioutil.TempFile(fz.dir, ".main.fz_")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), filepath.Join(fz.dir, "main.fz")); err != nil {
+		return err
+	}
+
+	// The server expects a log file to exist.
+	return ioutil.WriteFile(filepath.Join(fz.dir, "log.fz"), nil, 0600)
+}
+
+// backupSuffix returns a suffix for backups of the freeze files that
+// does not clash with earlier backups.
+func backupSuffix(dir string) string {
+	base := "." + time.Now().Format("20060102-150405")
+	suffix := base
+	for n := 1; ; n++ {
+		_, err1 := os.Stat(filepath.Join(dir, "main.fz"+suffix))
+		_, err2 := os.Stat(filepath.Join(dir, "log.fz"+suffix))
+		if os.IsNotExist(err1) && os.IsNotExist(err2) {
+			return suffix
+		}
+		suffix = fmt.Sprintf("%v-%v", base, n)
+	}
+}
+
+// loadForEdit loads the merged state of the server in dir. Edits are
+// applied on top of the readable part of a damaged log.
+func loadForEdit(dir string) (*frozen, *freezer.Server, error) {
+	fz, err := load(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fz.logErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; the rest of the log is discarded\n", fz.logErr)
+	}
+	return fz, fz.merged(), nil
+}
+
+// logEntry is the JSON representation of a log entry.
+type logEntry struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func entryType(v interface{}) string {
+	switch v.(type) {
+	case *freezer.Server:
+		return "Server"
+	case *freezer.ConfigKeyValuePair:
+		return "ConfigKeyValuePair"
+	case *freezer.BanList:
+		return "BanList"
+	case *freezer.User:
+		return "User"
+	case *freezer.UserRemove:
+		return "UserRemove"
+	case *freezer.Channel:
+		return "Channel"
+	case *freezer.ChannelRemove:
+		return "ChannelRemove"
+	case *freezer.Invite:
+		return "Invite"
+	case *freezer.InviteRemove:
+		return "InviteRemove"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func dump(args []string) error {
+	fset := flag.NewFlagSet("dump", flag.ContinueOnError)
+	merged := fset.Bool("merged", false, "")
+	args, err := parseArgs(fset, args, 0)
+	if err != nil {
+		return err
+	}
+	fz, err := load(args[0])
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if *merged {
+		if fz.logErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", fz.logErr)
+		}
+		return enc.Encode(fz.merged())
+	}
+
+	out := struct {
+		Main     *freezer.Server `json:"main"`
+		Log      [][]logEntry    `json:"log"`
+		LogError string          `json:"log_error,omitempty"`
+	}{Main: fz.main, Log: [][]logEntry{}}
+	for _, entries := range fz.log {
+		tx := []logEntry{}
+		for _, v := range entries {
+			tx = append(tx, logEntry{Type: entryType(v), Value: v})
+		}
+		out.Log = append(out.Log, tx)
+	}
+	if fz.logErr != nil {
+		out.LogError = fz.logErr.Error()
+	}
+	return enc.Encode(out)
+}
+
+// validate returns the problems found in a server's merged state.
+func validate(fs *freezer.Server) []string {
+	var problems []string
+
+	channels := make(map[uint32]*freezer.Channel)
+	for _, fc := range fs.Channels {
+		if fc.Id == nil || fc.Name == nil {
+			problems = append(problems, fmt.Sprintf("channel without id or name: %v", fc))
+			continue
+		}
+		if _, ok := channels[*fc.Id]; ok {
+			problems = append(problems, fmt.Sprintf("duplicate channel %v", *fc.Id))
+		}
+		channels[*fc.Id] = fc
+	}
+	if _, ok := channels[0]; !ok {
+		problems = append(problems, "no root channel (id 0)")
+	}
+	for id, fc := range channels {
+		if id == 0 {
+			continue
+		}
+		if fc.ParentId == nil {
+			problems = append(problems, fmt.Sprintf("channel %v has no parent", id))
+		} else if _, ok := channels[*fc.ParentId]; !ok {
+			problems = append(problems, fmt.Sprintf("channel %v has a missing parent %v", id, *fc.ParentId))
+		} else {
+			// Walk up the tree to find cycles.
+			seen := map[uint32]bool{id: true}
+			for p := fc.GetParentId(); p != 0; p = channels[p].GetParentId() {
+				if seen[p] {
+					problems = append(problems, fmt.Sprintf("channel %v is part of a parent cycle", id))
+					break
+				}
+				seen[p] = true
+				if _, ok := channels[p]; !ok {
+					break
+				}
+			}
+		}
+		for _, link := range fc.Links {
+			if _, ok := channels[link]; !ok {
+				problems = append(problems, fmt.Sprintf("channel %v links to missing channel %v", id, link))
+			}
+		}
+	}
+
+	users := make(map[uint32]bool)
+	names := make(map[string]uint32)
+	for _, fu := range fs.Users {
+		if fu.Id == nil || fu.Name == nil {
+			problems = append(problems, fmt.Sprintf("user without id or name: %v", fu))
+			continue
+		}
+		if users[*fu.Id] {
+			problems = append(problems, fmt.Sprintf("duplicate user %v", *fu.Id))
+		}
+		users[*fu.Id] = true
+		if other, ok := names[*fu.Name]; ok {
+			problems = append(problems, fmt.Sprintf("users %v and %v share the name %q", other, *fu.Id, *fu.Name))
+		}
+		names[*fu.Name] = *fu.Id
+	}
+	if !users[0] {
+		problems = append(problems, "no SuperUser (user 0)")
+	}
+
+	return problems
+}
+
+func check(args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("check", flag.ContinueOnError), args, 0)
+	if err != nil {
+		return err
+	}
+	fz, err := load(args[0])
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	if fz.logErr != nil {
+		problems = append(problems, fz.logErr.Error())
+	}
+	fs := fz.merged()
+	problems = append(problems, validate(fs)...)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%v problem(s) found", len(problems))
+	}
+	fmt.Printf("OK: %v channels, %v users, %v log transactions\n", len(fs.Channels), len(fs.Users), len(fz.log))
+	return nil
+}
+
+func setSuperUserPassword(args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("set-superuser-password", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	fz, fs, err := loadForEdit(args[0])
+	if err != nil {
+		return err
+	}
+	val, err := password.Hash(args[1], password.DefaultParams)
+	if err != nil {
+		return err
+	}
+	freezer.Apply(fs, []interface{}{&freezer.ConfigKeyValuePair{
+		Key:   proto.String("SuperUserPassword"),
+		Value: proto.String(val),
+	}})
+	if err = fz.save(fs); err != nil {
+		return err
+	}
+	fmt.Println("SuperUser password set")
+	return nil
+}
+
+func deleteChannel(args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("delete-channel", flag.ContinueOnError), args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.ParseUint(args[1], 10, 32)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		return errors.New("the root channel cannot be deleted")
+	}
+	fz, fs, err := loadForEdit(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Collect the channel and its subchannels.
+	doomed := map[uint32]bool{uint32(id): true}
+	for grew := true; grew; {
+		grew = false
+		for _, fc := range fs.Channels {
+			if fc.ParentId != nil && doomed[*fc.ParentId] && !doomed[fc.GetId()] {
+				doomed[fc.GetId()] = true
+				grew = true
+			}
+		}
+	}
+
+	found := false
+	channels := []*freezer.Channel{}
+	for _, fc := range fs.Channels {
+		if doomed[fc.GetId()] {
+			found = found || fc.GetId() == uint32(id)
+			continue
+		}
+		links := []uint32{}
+		for _, link := range fc.Links {
+			if !doomed[link] {
+				links = append(links, link)
+			}
+		}
+		fc.Links = links
+		channels = append(channels, fc)
+	}
+	if !found {
+		return fmt.Errorf("no channel %v", id)
+	}
+	fs.Channels = channels
+
+	// Users whose last channel is gone start out in the root channel.
+	for _, fu := range fs.Users {
+		if doomed[fu.GetLastChannelId()] {
+			fu.LastChannelId = proto.Uint32(0)
+		}
+	}
+
+	if err = fz.save(fs); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %v channel(s)\n", len(doomed))
+	return nil
+}
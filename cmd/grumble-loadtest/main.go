@@ -0,0 +1,229 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Command grumble-loadtest connects a number of simulated clients to
+// a grumble server and has all of them talk at once, to measure how
+// well the server routes voice under load.
+//
+// Every client joins the server's default channel and sends a voice
+// packet every 1/rate seconds, either encrypted over UDP or tunnelled
+// through its TLS connection. Each packet carries the time it was
+// sent, so the clients that receive it can tell how long it took.
+// At the end, the number of packets sent, received and lost, and the
+// latency of the received packets are printed.
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mumble.info/grumble/pkg/client"
+	"mumble.info/grumble/pkg/cryptstate"
+)
+
+var usage = `usage: grumble-loadtest [options]
+
+ Connect -clients simulated clients to the server at -addr and
+ have all of them talk for -duration. Use -transport to choose
+ whether voice is sent over UDP, tunnelled over TCP, or both
+ (alternating between clients).
+
+ The server's connection and message limits apply to the
+ simulated clients, which all connect from the same host.
+
+options:
+`
+
+var (
+	addr      = flag.String("addr", "localhost:64738", "address of the server")
+	clients   = flag.Int("clients", 10, "number of clients to connect")
+	transport = flag.String("transport", "udp", "how voice is sent: udp, tcp or mixed")
+	duration  = flag.Duration("duration", 30*time.Second, "how long the clients talk")
+	rate      = flag.Int("rate", 50, "voice packets sent per second by each client")
+	size      = flag.Int("size", 60, "size of the audio in each voice packet, in bytes")
+	mode      = flag.String("crypto", cryptstate.DefaultMode, "crypto mode: "+strings.Join(cryptstate.SupportedModes(), ", "))
+	name      = flag.String("name", "loadtest", "prefix of the clients' user names")
+	password  = flag.String("password", "", "server password")
+	connDelay = flag.Duration("connect-delay", 20*time.Millisecond, "time between connecting two clients")
+)
+
+// The send time embedded in each voice packet.
+const stampSize = 8
+
+// stats collects the results of all clients.
+type stats struct {
+	sent     uint64
+	received uint64
+	invalid  uint64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+}
+
+func (s *stats) receive(latency time.Duration) {
+	atomic.AddUint64(&s.received, 1)
+	s.mu.Lock()
+	s.latencies = append(s.latencies, latency)
+	s.mu.Unlock()
+}
+
+// A talker is a simulated client.
+type talker struct {
+	name   string
+	udp    bool
+	stats  *stats
+	client *client.Client
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *clients < 2 {
+		fatal(errors.New("at least 2 clients are needed"))
+	}
+	if *rate <= 0 {
+		fatal(errors.New("rate must be positive"))
+	}
+	if *size < stampSize || *size > 900 {
+		fatal(fmt.Errorf("size must be between %v and 900 bytes", stampSize))
+	}
+	if *transport != "udp" && *transport != "tcp" && *transport != "mixed" {
+		fatal(fmt.Errorf("unknown transport %q", *transport))
+	}
+
+	s := &stats{}
+	var all []*talker
+	for i := 0; i < *clients; i++ {
+		t := &talker{
+			name:  fmt.Sprintf("%v-%v", *name, i),
+			udp:   *transport == "udp" || *transport == "mixed" && i%2 == 0,
+			stats: s,
+		}
+		if err := t.connect(); err != nil {
+			fatal(fmt.Errorf("%v: %v", t.name, err))
+		}
+		all = append(all, t)
+		time.Sleep(*connDelay)
+	}
+	fmt.Printf("%v clients connected, talking for %v\n", len(all), *duration)
+
+	// Give the server a moment to learn the UDP addresses.
+	time.Sleep(time.Second)
+	atomic.StoreUint64(&s.received, 0)
+	s.mu.Lock()
+	s.latencies = nil
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	stop := time.Now().Add(*duration)
+	for _, t := range all {
+		wg.Add(1)
+		go func(t *talker) {
+			defer wg.Done()
+			t.talk(stop)
+		}(t)
+	}
+	wg.Wait()
+
+	// Wait for the packets still on their way.
+	time.Sleep(time.Second)
+	for _, t := range all {
+		t.client.Close()
+	}
+	s.report(len(all))
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "grumble-loadtest: %v\n", err)
+	os.Exit(1)
+}
+
+// report prints the results of a run with n clients.
+func (s *stats) report(n int) {
+	sent := atomic.LoadUint64(&s.sent)
+	received := atomic.LoadUint64(&s.received)
+	// Every packet is routed to the other clients in the channel.
+	expected := sent * uint64(n-1)
+	loss := 0.0
+	if expected > 0 && received < expected {
+		loss = 100 * float64(expected-received) / float64(expected)
+	}
+	fmt.Printf("sent:     %v packets\n", sent)
+	fmt.Printf("received: %v of %v packets (%.2f%% lost)\n", received, expected, loss)
+	if invalid := atomic.LoadUint64(&s.invalid); invalid > 0 {
+		fmt.Printf("invalid:  %v packets\n", invalid)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.latencies) == 0 {
+		return
+	}
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	var total time.Duration
+	for _, l := range s.latencies {
+		total += l
+	}
+	percentile := func(p int) time.Duration {
+		return s.latencies[(len(s.latencies)-1)*p/100]
+	}
+	fmt.Printf("latency:  avg %v, p50 %v, p99 %v, max %v\n",
+		total/time.Duration(len(s.latencies)), percentile(50), percentile(99), s.latencies[len(s.latencies)-1])
+}
+
+// connect connects t to the server.
+func (t *talker) connect() error {
+	c, err := client.Dial(*addr, client.Config{
+		Username:    t.name,
+		Password:    *password,
+		CryptoModes: []string{*mode},
+		DisableUDP:  !t.udp,
+		OnAudio:     t.receive,
+	})
+	if err != nil {
+		return err
+	}
+	t.client = c
+	return nil
+}
+
+// talk sends t's voice packets until stop.
+func (t *talker) talk(stop time.Time) {
+	ticker := time.NewTicker(time.Second / time.Duration(*rate))
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if now.After(stop) {
+			return
+		}
+		frame := make([]byte, *size)
+		binary.BigEndian.PutUint64(frame, uint64(now.UnixNano()))
+		if err := t.client.SendAudio(0, frame, false); err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", t.name, err)
+			return
+		}
+		atomic.AddUint64(&t.stats.sent, 1)
+	}
+}
+
+// receive handles a voice packet routed to t by the server.
+func (t *talker) receive(audio *client.Audio) {
+	if len(audio.Data) < stampSize {
+		atomic.AddUint64(&t.stats.invalid, 1)
+		return
+	}
+	sent := time.Unix(0, int64(binary.BigEndian.Uint64(audio.Data)))
+	t.stats.receive(time.Since(sent))
+}
@@ -0,0 +1,395 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements persistent access tokens.
+//
+// Unlike the tokens a client sends when it connects, persistent access
+// tokens are stored with a registered user, and apply whenever the
+// user is connected. Each token is bound to a channel, and only
+// applies to the token groups of that channel and its subchannels.
+// Tokens may expire.
+//
+// Users with write permission in a channel (the channel's owners) can
+// give tokens for it with the /token chat command.
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// An AccessToken is an access token held by a registered user.
+type AccessToken struct {
+	Token     string
+	ChannelId int
+	// Unix time after which the token no longer applies.
+	// Zero means never.
+	Expires int64
+}
+
+// expired checks whether the token has expired at the given time.
+func (token *AccessToken) expired(now time.Time) bool {
+	return token.Expires > 0 && now.Unix() > token.Expires
+}
+
+// appliesTo checks whether the token applies to the channel with the
+// given ACL context.
+func (token *AccessToken) appliesTo(server *Server, ctx *acl.Context) bool {
+	channel, ok := server.Channels[token.ChannelId]
+	if !ok {
+		return false
+	}
+	for iter := ctx; iter != nil; iter = iter.Parent {
+		if iter == &channel.ACL {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopedTokens implements acl.ScopedTokenHolder. It returns the
+// unexpired access tokens of the client's user that apply in ctx.
+func (client *Client) ScopedTokens(ctx *acl.Context) []string {
+	if client.user == nil {
+		return nil
+	}
+	var tokens []string
+	now := time.Now()
+	for i := range client.user.AccessTokens {
+		token := &client.user.AccessTokens[i]
+		if !token.expired(now) && token.appliesTo(client.server, ctx) {
+			tokens = append(tokens, token.Token)
+		}
+	}
+	return tokens
+}
+
+// AddAccessToken gives user token in channel. A duration of zero
+// means the token doesn't expire. If the user already holds the token
+// in the channel, its expiry is replaced.
+func (server *Server) AddAccessToken(user *User, channel *Channel, token string, duration time.Duration) {
+	var expires int64
+	if duration > 0 {
+		expires = time.Now().Add(duration).Unix()
+	}
+	server.removeAccessToken(user, channel.Id, token)
+	user.AccessTokens = append(user.AccessTokens, AccessToken{
+		Token:     token,
+		ChannelId: channel.Id,
+		Expires:   expires,
+	})
+	server.accessTokensChanged(user)
+}
+
+// RemoveAccessToken takes token in the given channel away from user.
+// It returns false if the user doesn't hold the token.
+func (server *Server) RemoveAccessToken(user *User, channelId int, token string) bool {
+	if !server.removeAccessToken(user, channelId, token) {
+		return false
+	}
+	server.accessTokensChanged(user)
+	return true
+}
+
+func (server *Server) removeAccessToken(user *User, channelId int, token string) bool {
+	for i, held := range user.AccessTokens {
+		if held.ChannelId == channelId && strings.EqualFold(held.Token, token) {
+			user.AccessTokens = append(user.AccessTokens[:i], user.AccessTokens[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// accessTokensChanged persists the access tokens of user, and updates
+// the permissions of its connected clients.
+func (server *Server) accessTokensChanged(user *User) {
+	server.UpdateFrozenUserRecord(user)
+	server.ClearCaches()
+	for _, client := range server.clients {
+		if client.user == user {
+			server.grantsChanged(client)
+		}
+	}
+}
+
+// expireAccessTokens drops expired access tokens, and the tokens of
+// channels that no longer exist.
+func (server *Server) expireAccessTokens() {
+	now := time.Now()
+	for _, user := range server.Users {
+		if len(user.AccessTokens) == 0 {
+			continue
+		}
+		kept := user.AccessTokens[:0]
+		for _, token := range user.AccessTokens {
+			if _, exists := server.Channels[token.ChannelId]; exists && !token.expired(now) {
+				kept = append(kept, token)
+			}
+		}
+		if len(kept) != len(user.AccessTokens) {
+			user.AccessTokens = kept
+			server.accessTokensChanged(user)
+		}
+	}
+}
+
+// sendServerText sends a private text message from the server to client.
+func (server *Server) sendServerText(client *Client, text string) {
+	client.sendMessage(&mumbleproto.TextMessage{
+		Session: []uint32{client.Session()},
+		Message: proto.String(text),
+	})
+}
+
+// tokenCommand implements the /token chat command:
+//
+//	/token add <user> <token> [duration]
+//	/token remove <user> <token>
+//	/token list [user]
+//
+// The command acts on the tokens of the sender's current channel, and
+// requires write permission there.
+func (server *Server) tokenCommand(client *Client, args []string, text string) {
+	channel := client.Channel
+	if len(args) < 2 {
+		server.sendChatCommandUsage(client, args[0])
+		return
+	}
+
+	var user *User
+	if len(args) > 2 {
+		var ok bool
+		user, ok = server.UserNameMap[args[2]]
+		if !ok {
+			server.sendServerText(client, fmt.Sprintf("No registered user named %v", html.EscapeString(args[2])))
+			return
+		}
+	}
+
+	switch {
+	case args[1] == "add" && (len(args) == 4 || len(args) == 5):
+		var duration time.Duration
+		if len(args) == 5 {
+			var err error
+			duration, err = time.ParseDuration(args[4])
+			if err != nil || duration <= 0 {
+				server.sendServerText(client, "Invalid duration")
+				return
+			}
+		}
+		server.AddAccessToken(user, channel, args[3], duration)
+		server.audit(client, auditlog.Entry{
+			Action:  "token.add",
+			Target:  user.Name,
+			Details: fmt.Sprintf("channel %v duration %v", channel.Id, duration),
+		})
+		server.sendServerText(client, fmt.Sprintf("Token given to %v", html.EscapeString(user.Name)))
+	case args[1] == "remove" && len(args) == 4:
+		if !server.RemoveAccessToken(user, channel.Id, args[3]) {
+			server.sendServerText(client, "No such token")
+			return
+		}
+		server.audit(client, auditlog.Entry{
+			Action:  "token.remove",
+			Target:  user.Name,
+			Details: fmt.Sprintf("channel %v", channel.Id),
+		})
+		server.sendServerText(client, fmt.Sprintf("Token taken from %v", html.EscapeString(user.Name)))
+	case args[1] == "list" && len(args) <= 3:
+		var lines []string
+		for _, at := range server.accessTokens(user) {
+			if at.Channel == channel.Id {
+				line := html.EscapeString(at.Name + ": " + at.Token)
+				if len(at.Expires) > 0 {
+					line += " (expires " + at.Expires + ")"
+				}
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			server.sendServerText(client, "No tokens in this channel")
+			return
+		}
+		server.sendServerText(client, strings.Join(lines, "<br />"))
+	default:
+		server.sendChatCommandUsage(client, args[0])
+	}
+}
+
+// apiAccessToken is the JSON representation of an AccessToken.
+type apiAccessToken struct {
+	User     uint32 `json:"user"`
+	Name     string `json:"name,omitempty"`
+	Token    string `json:"token"`
+	Channel  int    `json:"channel"`
+	Duration string `json:"duration,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+}
+
+// accessTokens lists the access tokens of user, or of all users if
+// user is nil.
+func (server *Server) accessTokens(user *User) []apiAccessToken {
+	tokens := []apiAccessToken{}
+	for _, u := range server.Users {
+		if user != nil && u != user {
+			continue
+		}
+		for _, token := range u.AccessTokens {
+			at := apiAccessToken{
+				User:    u.Id,
+				Name:    u.Name,
+				Token:   token.Token,
+				Channel: token.ChannelId,
+			}
+			if token.Expires > 0 {
+				at.Expires = time.Unix(token.Expires, 0).UTC().Format(time.RFC3339)
+			}
+			tokens = append(tokens, at)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].User != tokens[j].User {
+			return tokens[i].User < tokens[j].User
+		}
+		return tokens[i].Channel < tokens[j].Channel
+	})
+	return tokens
+}
+
+func init() {
+	registerChatCommand("token", &chatCommand{
+		usage: []string{
+			"token add <user> <token> [duration]",
+			"token remove <user> <token>",
+			"token list [user]",
+		},
+		help: "Give users access tokens for your channel",
+		perm: acl.WritePermission,
+		run:  (*Server).tokenCommand,
+	})
+	registerAPIEndpoint("tokens", handleAPITokens)
+}
+
+// handleAPITokens implements /servers/<id>/tokens.
+//
+//	GET                               lists access tokens, optionally of ?user=<id>
+//	POST                              gives a token: {"user": 3, "token": "t", "channel": 1, "duration": "24h"}
+//	DELETE /<user>/<channel>/<token>  takes a token away
+func handleAPITokens(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var req apiAccessToken
+	var duration time.Duration
+	filter := int64(-1)
+	switch r.Method {
+	case http.MethodGet:
+		if s := r.URL.Query().Get("user"); len(s) > 0 {
+			id, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				apiError(w, http.StatusBadRequest, "invalid user")
+				return
+			}
+			filter = int64(id)
+		}
+	case http.MethodPost:
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if len(req.Token) == 0 {
+			apiError(w, http.StatusBadRequest, "missing token")
+			return
+		}
+		if len(req.Duration) > 0 {
+			var err error
+			duration, err = time.ParseDuration(req.Duration)
+			if err != nil || duration <= 0 {
+				apiError(w, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+	case http.MethodDelete:
+		if len(args) != 3 {
+			apiError(w, http.StatusNotFound, "expected /tokens/<user>/<channel>/<token>")
+			return
+		}
+		uid, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			apiError(w, http.StatusNotFound, "invalid user")
+			return
+		}
+		cid, err := strconv.Atoi(args[1])
+		if err != nil {
+			apiError(w, http.StatusNotFound, "invalid channel")
+			return
+		}
+		req.User, req.Channel, req.Token = uint32(uid), cid, args[2]
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		if r.Method == http.MethodGet {
+			var user *User
+			if filter >= 0 {
+				var ok bool
+				if user, ok = server.Users[uint32(filter)]; !ok {
+					status, reply = http.StatusNotFound, map[string]string{"error": "no such user"}
+					return
+				}
+			}
+			reply = server.accessTokens(user)
+			return
+		}
+
+		user, ok := server.Users[req.User]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such user"}
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			channel, ok := server.Channels[req.Channel]
+			if !ok {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such channel"}
+				return
+			}
+			server.AddAccessToken(user, channel, req.Token, duration)
+			server.auditAPI(auditlog.Entry{
+				Action:  "token.add",
+				Target:  user.Name,
+				Details: fmt.Sprintf("channel %v duration %v", channel.Id, duration),
+			})
+			status = http.StatusCreated
+		case http.MethodDelete:
+			if !server.RemoveAccessToken(user, req.Channel, req.Token) {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such token"}
+				return
+			}
+			server.auditAPI(auditlog.Entry{
+				Action:  "token.remove",
+				Target:  user.Name,
+				Details: fmt.Sprintf("channel %v", req.Channel),
+			})
+		}
+		reply = server.accessTokens(user)
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
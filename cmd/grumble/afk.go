@@ -0,0 +1,95 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the /afk chat command.
+//
+// A user who steps away gives /afk to be muted and deafened, and, if
+// AFKChannel is set, moved to that channel. Giving /afk again undoes
+// both, moving the user back to the channel it came from if it may
+// still enter it. Unmuting oneself meanwhile ends the absence without
+// moving back.
+
+import (
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+func init() {
+	registerChatCommand("afk", &chatCommand{
+		usage: []string{"afk"},
+		help:  "Mark yourself as away, or as back",
+		run:   (*Server).afkCommand,
+	})
+}
+
+// afkChannel returns the channel absent users are moved to, or nil if
+// they stay where they are.
+func (server *Server) afkChannel() *Channel {
+	id := server.cfg.IntValue("AFKChannel")
+	if id <= 0 {
+		return nil
+	}
+	return server.Channels[id]
+}
+
+// afkCommand implements /afk.
+func (server *Server) afkCommand(client *Client, args []string, text string) {
+	if len(args) != 1 {
+		server.sendChatCommandUsage(client, args[0])
+		return
+	}
+	if client.queued {
+		client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_Text, "You are waiting in the queue")
+		return
+	}
+	if client.held {
+		client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_Text, "The server is under maintenance")
+		return
+	}
+
+	away := !client.afk
+	client.afk = away
+	client.SelfMute = away
+	client.SelfDeaf = away
+	userstate := &mumbleproto.UserState{
+		Session:  proto.Uint32(client.Session()),
+		Actor:    proto.Uint32(client.Session()),
+		SelfMute: proto.Bool(away),
+		SelfDeaf: proto.Bool(away),
+	}
+
+	afkChannel := server.afkChannel()
+	var dst *Channel
+	if away {
+		if client.Channel != nil {
+			client.afkReturn = client.Channel.Id
+		}
+		// Users are moved to the AFK channel whether they may enter it
+		// or not.
+		if afkChannel != nil && afkChannel != client.Channel {
+			dst = afkChannel
+		}
+	} else if afkChannel != nil && client.Channel == afkChannel {
+		back, ok := server.Channels[client.afkReturn]
+		if ok && back != afkChannel && acl.HasPermission(&back.ACL, client, acl.EnterPermission) && !server.channelFull(back) {
+			dst = back
+		}
+	}
+	if dst != nil {
+		userstate.ChannelId = proto.Uint32(uint32(dst.Id))
+		server.userEnterChannel(client, dst, userstate)
+	}
+	if err := server.broadcastProtoMessage(userstate); err != nil {
+		server.Panic("Unable to broadcast UserState")
+	}
+
+	if away {
+		server.sendServerText(client, "You are now away. Use /afk again when you are back.")
+	} else {
+		server.sendServerText(client, "Welcome back")
+	}
+}
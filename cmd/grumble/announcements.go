@@ -0,0 +1,273 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements scheduled announcements.
+//
+// An announcement is a text message, such as the server rules or an
+// event reminder, that the server sends to the users in some channels,
+// or in every channel, whenever its cron schedule fires. Schedules are
+// in the server's local time; an announcement due while the server was
+// down is not sent late. Admins manage announcements through the admin
+// API.
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/cron"
+)
+
+// An Announcement is a text message sent on a schedule.
+type Announcement struct {
+	Id uint32
+	// The cron expression giving when the announcement is sent
+	Schedule string
+	// The message, as HTML
+	Text string
+	// The channels whose users get the announcement; all channels if
+	// empty.
+	Channels []int
+	Enabled  bool
+	Created  int64
+
+	// The parsed schedule, nil if Schedule doesn't parse
+	schedule *cron.Schedule
+	// When the announcement is next sent; zero if never
+	next time.Time
+}
+
+// reschedule works out when the announcement is next sent after now.
+func (announcement *Announcement) reschedule(now time.Time) {
+	announcement.next = time.Time{}
+	if announcement.Enabled && announcement.schedule != nil {
+		announcement.next = announcement.schedule.Next(now)
+	}
+}
+
+// sendAnnouncements sends the announcements that are due.
+func (server *Server) sendAnnouncements() {
+	now := time.Now()
+	for _, announcement := range server.Announcements {
+		if announcement.next.IsZero() || now.Before(announcement.next) {
+			continue
+		}
+		server.announce(announcement)
+		announcement.reschedule(now)
+	}
+}
+
+// announce sends an announcement to the users in its channels.
+func (server *Server) announce(announcement *Announcement) {
+	channels := make(map[int]bool)
+	for _, id := range announcement.Channels {
+		channels[id] = true
+	}
+	sent := 0
+	for _, client := range server.clients {
+		if client.state != StateClientReady || client.Channel == nil {
+			continue
+		}
+		if len(channels) > 0 && !channels[client.Channel.Id] {
+			continue
+		}
+		server.sendServerText(client, announcement.Text)
+		sent++
+	}
+	server.Printf("Sent announcement %v to %v users", announcement.Id, sent)
+}
+
+func init() {
+	registerAPIEndpoint("announcements", handleAPIAnnouncements)
+}
+
+// apiAnnouncement is the JSON representation of an Announcement.
+type apiAnnouncement struct {
+	Id       uint32 `json:"id"`
+	Schedule string `json:"schedule"`
+	Text     string `json:"text"`
+	Channels []int  `json:"channels"`
+	Enabled  bool   `json:"enabled"`
+	Created  string `json:"created"`
+	Next     string `json:"next,omitempty"`
+}
+
+func (announcement *Announcement) apiAnnouncement() apiAnnouncement {
+	aa := apiAnnouncement{
+		Id:       announcement.Id,
+		Schedule: announcement.Schedule,
+		Text:     announcement.Text,
+		Channels: append([]int{}, announcement.Channels...),
+		Enabled:  announcement.Enabled,
+		Created:  time.Unix(announcement.Created, 0).UTC().Format(time.RFC3339),
+	}
+	if !announcement.next.IsZero() {
+		aa.Next = announcement.next.Format(time.RFC3339)
+	}
+	return aa
+}
+
+// handleAPIAnnouncements implements /servers/<id>/announcements.
+//
+//	GET     lists the announcements; .../announcements/<id> shows one
+//	POST    creates an announcement:
+//	        {"schedule": "0 20 * * fri", "text": "Game night!",
+//	         "channels": [3, 4], "enabled": true}
+//	        channels defaults to all channels, and enabled to true
+//	PUT     .../announcements/<id> changes the fields given
+//	POST    .../announcements/<id>/send sends an announcement at once
+//	DELETE  .../announcements/<id> deletes an announcement
+func handleAPIAnnouncements(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var id uint64
+	if len(args) > 0 {
+		var err error
+		id, err = strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			apiError(w, http.StatusBadRequest, "invalid announcement")
+			return
+		}
+	}
+	var req struct {
+		Schedule *string `json:"schedule"`
+		Text     *string `json:"text"`
+		Channels *[]int  `json:"channels"`
+		Enabled  *bool   `json:"enabled"`
+	}
+	send := false
+	switch {
+	case r.Method == http.MethodGet && len(args) <= 1:
+	case r.Method == http.MethodPost && len(args) == 0:
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if req.Schedule == nil || req.Text == nil {
+			apiError(w, http.StatusBadRequest, "schedule and text are required")
+			return
+		}
+	case r.Method == http.MethodPost && len(args) == 2 && args[1] == "send":
+		send = true
+	case r.Method == http.MethodPut && len(args) == 1:
+		if !readJSON(w, r, &req) {
+			return
+		}
+	case r.Method == http.MethodDelete && len(args) == 1:
+	case len(args) > 2 || len(args) == 2 && args[1] != "send":
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var schedule *cron.Schedule
+	if req.Schedule != nil {
+		var err error
+		schedule, err = cron.Parse(*req.Schedule)
+		if err != nil {
+			apiError(w, http.StatusBadRequest, "invalid schedule: "+err.Error())
+			return
+		}
+	}
+	if req.Text != nil && len(strings.TrimSpace(*req.Text)) == 0 {
+		apiError(w, http.StatusBadRequest, "text must not be empty")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		if req.Channels != nil {
+			for _, channelId := range *req.Channels {
+				if _, ok := server.Channels[channelId]; !ok {
+					status, reply = http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("no such channel: %v", channelId)}
+					return
+				}
+			}
+		}
+
+		if r.Method == http.MethodGet && len(args) == 0 {
+			announcements := []apiAnnouncement{}
+			for _, announcement := range server.Announcements {
+				announcements = append(announcements, announcement.apiAnnouncement())
+			}
+			sort.Slice(announcements, func(i, j int) bool { return announcements[i].Id < announcements[j].Id })
+			reply = announcements
+			return
+		}
+
+		var announcement *Announcement
+		if r.Method == http.MethodPost && !send {
+			announcement = &Announcement{
+				Id:      server.nextAnnouncementId,
+				Enabled: true,
+				Created: time.Now().Unix(),
+			}
+			server.nextAnnouncementId++
+			server.Announcements[announcement.Id] = announcement
+			status = http.StatusCreated
+		} else {
+			var ok bool
+			announcement, ok = server.Announcements[uint32(id)]
+			if !ok {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such announcement"}
+				return
+			}
+		}
+
+		var action string
+		switch {
+		case r.Method == http.MethodGet:
+			reply = announcement.apiAnnouncement()
+			return
+		case send:
+			server.announce(announcement)
+			action = "announcement.send"
+		case r.Method == http.MethodDelete:
+			delete(server.Announcements, announcement.Id)
+			server.DeleteFrozenAnnouncement(announcement.Id)
+			action = "announcement.delete"
+			status = http.StatusNoContent
+		default:
+			if req.Schedule != nil {
+				announcement.Schedule = strings.TrimSpace(*req.Schedule)
+				announcement.schedule = schedule
+			}
+			if req.Text != nil {
+				announcement.Text = *req.Text
+			}
+			if req.Channels != nil {
+				announcement.Channels = append([]int(nil), *req.Channels...)
+			}
+			if req.Enabled != nil {
+				announcement.Enabled = *req.Enabled
+			}
+			announcement.reschedule(time.Now())
+			server.UpdateFrozenAnnouncement(announcement)
+			action = "announcement.update"
+			if status == http.StatusCreated {
+				action = "announcement.create"
+			}
+		}
+		server.auditAPI(auditlog.Entry{
+			Action:  action,
+			Details: fmt.Sprintf("announcement %v schedule %q", announcement.Id, announcement.Schedule),
+		})
+		reply = announcement.apiAnnouncement()
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements Grumble's administrative HTTP API.
+//
+// The API is served on a separate listener (see the --api-addr argument)
+// and speaks JSON. All requests must carry the API token found in the
+// data directory as a bearer token:
+//
+//     Authorization: Bearer <contents of $DATADIR/api.token>
+//
+// Endpoints that operate on a specific virtual server live under
+// /servers/<id>/<endpoint>. These are registered using registerAPIEndpoint.
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiHandlerFunc handles an admin API request for a single virtual server.
+// The args slice holds the path elements following the endpoint name.
+type apiHandlerFunc func(server *Server, w http.ResponseWriter, r *http.Request, args []string)
+
+// apiMux routes all requests to the admin API. Global endpoints can be
+// registered on it directly.
+var apiMux = http.NewServeMux()
+
+// apiEndpoints maps endpoint names to their per-server handlers.
+var apiEndpoints = map[string]apiHandlerFunc{}
+
+var errAPITimeout = errors.New("timed out waiting for server")
+
+// registerAPIEndpoint registers fn as the handler for /servers/<id>/<name>.
+func registerAPIEndpoint(name string, fn apiHandlerFunc) {
+	if _, exists := apiEndpoints[name]; exists {
+		panic("api: endpoint registered twice: " + name)
+	}
+	apiEndpoints[name] = fn
+}
+
+func init() {
+	apiMux.HandleFunc("/servers", handleAPIServerList)
+	apiMux.HandleFunc("/servers/", handleAPIServer)
+}
+
+// apiServerInfo is the JSON representation of a virtual server
+// in the admin API.
+type apiServerInfo struct {
+	Id       int64 `json:"id"`
+	Running  bool  `json:"running"`
+	Port     int   `json:"port"`
+	Users    int   `json:"users"`
+	Channels int   `json:"channels"`
+}
+
+func handleAPIServerList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	ids := []int64{}
+	for id := range servers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	list := []apiServerInfo{}
+	for _, id := range ids {
+		server := servers[id]
+		info := apiServerInfo{Id: id, Port: server.Port()}
+		err := server.runSync(func() {
+			info.Running = true
+			info.Users = len(server.clients)
+			info.Channels = len(server.Channels)
+		})
+		if err != nil {
+			info.Running = false
+		}
+		list = append(list, info)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func handleAPIServer(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/servers/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		apiError(w, http.StatusNotFound, "invalid server id")
+		return
+	}
+	server, ok := servers[id]
+	if !ok {
+		apiError(w, http.StatusNotFound, "no such server")
+		return
+	}
+	if len(parts) < 2 {
+		apiError(w, http.StatusNotFound, "no endpoint given")
+		return
+	}
+	fn, ok := apiEndpoints[parts[1]]
+	if !ok {
+		apiError(w, http.StatusNotFound, "no such endpoint")
+		return
+	}
+	fn(server, w, r, parts[2:])
+}
+
+// runSync runs fn on the server's synchronous handler goroutine and
+// waits for it to complete. It is used by the admin API to safely
+// access the server's state.
+func (server *Server) runSync(fn func()) error {
+	if !server.isRunning() {
+		return errors.New("server not running")
+	}
+
+	done := make(chan bool)
+	call := func() {
+		defer close(done)
+		fn()
+	}
+
+	select {
+	case server.syncCalls <- call:
+	case <-time.After(10 * time.Second):
+		return errAPITimeout
+	}
+	<-done
+	return nil
+}
+
+// writeJSON writes v as the JSON body of a response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	enc.Encode(v)
+}
+
+// apiError writes a JSON error response.
+func apiError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// readJSON decodes the JSON body of r into v. On failure, an error
+// response is written to w and false is returned.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// loadAPIToken reads the admin API token from the data directory.
+// If no token exists yet, a new random token is generated and stored.
+func loadAPIToken() (string, error) {
+	return loadTokenFile("api.token", "admin API token")
+}
+
+// loadTokenFile reads a secret token from the named file in the data
+// directory. If the file does not exist, a new random token is
+// generated and stored in it.
+func loadTokenFile(name, what string) (string, error) {
+	fn := filepath.Join(Args.DataDir, name)
+	buf, err := ioutil.ReadFile(fn)
+	if err == nil {
+		token := strings.TrimSpace(string(buf))
+		if len(token) > 0 {
+			return token, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	raw := make([]byte, 32)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+	err = ioutil.WriteFile(fn, []byte(token+"\n"), 0600)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Generated new %v in %v", what, fn)
+	return token, nil
+}
+
+// apiAuth wraps an http.Handler, rejecting requests that do not
+// carry the correct bearer token.
+func apiAuth(token string, h http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			apiError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// StartAPI launches the admin API on the given address.
+func StartAPI(addr string) error {
+	token, err := loadAPIToken()
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      apiAuth(token, apiMux),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	tcpaddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return err
+	}
+	l, err := listenTCP(tcpaddr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := srv.Serve(l)
+		if err != nil {
+			log.Printf("Admin API stopped: %v", err)
+		}
+	}()
+	log.Printf("Admin API listening on %v", addr)
+	return nil
+}
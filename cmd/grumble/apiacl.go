@@ -0,0 +1,248 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
+)
+
+// apiACL is the JSON representation of an ACL entry. Permissions are
+// given by name (see acl.ParsePermission).
+type apiACL struct {
+	UserId    *int     `json:"user_id,omitempty"`
+	Group     string   `json:"group,omitempty"`
+	ApplyHere bool     `json:"apply_here"`
+	ApplySubs bool     `json:"apply_subs"`
+	Allow     []string `json:"allow"`
+	Deny      []string `json:"deny"`
+}
+
+// newAPIACL returns the JSON representation of entry.
+func newAPIACL(entry acl.ACL) apiACL {
+	a := apiACL{
+		Group:     entry.Group,
+		ApplyHere: entry.ApplyHere,
+		ApplySubs: entry.ApplySubs,
+		Allow:     entry.Allow.Names(),
+		Deny:      entry.Deny.Names(),
+	}
+	if entry.IsUserACL() {
+		userId := entry.UserId
+		a.UserId = &userId
+		a.Group = ""
+	}
+	return a
+}
+
+// apiExplanation is the JSON representation of an acl.Explanation.
+type apiExplanation struct {
+	Permission string  `json:"permission"`
+	Granted    bool    `json:"granted"`
+	Reason     string  `json:"reason"`
+	Channel    *int    `json:"channel,omitempty"`
+	Index      *int    `json:"index,omitempty"`
+	Entry      *apiACL `json:"entry,omitempty"`
+	Inherited  bool    `json:"inherited"`
+	Text       string  `json:"text"`
+}
+
+func init() {
+	registerAPIEndpoint("acls", handleAPIACLs)
+	registerAPIEndpoint("explain", handleAPIExplain)
+}
+
+// handleAPIACLs implements /servers/<id>/acls/<channel>. It covers
+// the permissions Mumble's ACL editor doesn't know about.
+//
+//	GET  lists the ACL entries defined on the channel
+//	PUT  replaces them: [{"group": "all", "apply_here": true, "deny": ["htmlmessage"]}]
+func handleAPIACLs(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 1 {
+		apiError(w, http.StatusNotFound, "expected /acls/<channel>")
+		return
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid channel")
+		return
+	}
+
+	var entries []acl.ACL
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req []apiACL
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for i, a := range req {
+			entry := acl.ACL{UserId: -1, ApplyHere: a.ApplyHere, ApplySubs: a.ApplySubs}
+			switch {
+			case a.UserId != nil && len(a.Group) == 0:
+				entry.UserId = *a.UserId
+			case a.UserId == nil && len(a.Group) > 0:
+				entry.Group = a.Group
+			default:
+				apiError(w, http.StatusBadRequest, fmt.Sprintf("entry %v: give either user_id or group", i+1))
+				return
+			}
+			if entry.Allow, err = acl.ParsePermission(strings.Join(a.Allow, ",")); err != nil {
+				apiError(w, http.StatusBadRequest, fmt.Sprintf("entry %v: %v", i+1, err))
+				return
+			}
+			if entry.Deny, err = acl.ParsePermission(strings.Join(a.Deny, ",")); err != nil {
+				apiError(w, http.StatusBadRequest, fmt.Sprintf("entry %v: %v", i+1, err))
+				return
+			}
+			entries = append(entries, entry)
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		channel, ok := server.Channels[id]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such channel"}
+			return
+		}
+		if r.Method == http.MethodPut {
+			channel.ACL.ACLs = entries
+			server.pushPermissions()
+			if !channel.IsTemporary() {
+				server.UpdateFrozenChannelACLs(channel)
+			}
+			server.auditAPI(auditlog.Entry{
+				Action:  "acl.edit",
+				Target:  channel.Name,
+				Details: fmt.Sprintf("channel %v: %v ACL entries, %v groups", channel.Id, len(channel.ACL.ACLs), len(channel.ACL.Groups)),
+			})
+		}
+		acls := []apiACL{}
+		for _, entry := range channel.ACL.ACLs {
+			acls = append(acls, newAPIACL(entry))
+		}
+		reply = acls
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
+
+// channelOfACL returns the channel whose ACL context is ctx.
+func (server *Server) channelOfACL(ctx *acl.Context) *Channel {
+	for _, channel := range server.Channels {
+		if &channel.ACL == ctx {
+			return channel
+		}
+	}
+	return nil
+}
+
+// explainPermission tells why client has, or lacks, permission perm
+// in channel, and on which channel the deciding ACL entry is.
+func (server *Server) explainPermission(client *Client, channel *Channel, perm acl.Permission) (acl.Explanation, *Channel) {
+	e := acl.Explain(&channel.ACL, client, perm)
+	if e.Context == nil {
+		return e, nil
+	}
+	return e, server.channelOfACL(e.Context)
+}
+
+// describeExplanation describes e, whose deciding entry is on channel.
+func describeExplanation(e acl.Explanation, channel *Channel) string {
+	if channel == nil {
+		return e.String()
+	}
+	return fmt.Sprintf("%v on channel %v (%v)", e, channel.Id, channel.Name)
+}
+
+// handleAPIExplain implements /servers/<id>/explain/<session>. It tells
+// which ACL entries decide the session's permissions in a channel.
+//
+//	GET  ?channel=<id>&permission=speak,enter; all permissions if none are given
+func handleAPIExplain(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 1 {
+		apiError(w, http.StatusNotFound, "expected /explain/<session>")
+		return
+	}
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	session, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid session")
+		return
+	}
+	query := r.URL.Query()
+	channelId := 0
+	if s := query.Get("channel"); len(s) > 0 {
+		if channelId, err = strconv.Atoi(s); err != nil {
+			apiError(w, http.StatusBadRequest, "invalid channel")
+			return
+		}
+	}
+	perms, err := acl.ParsePermission(query.Get("permission"))
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if perms == acl.NonePermission {
+		perms = acl.AllPermissions
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		client, ok := server.clients[uint32(session)]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such session"}
+			return
+		}
+		channel, ok := server.Channels[channelId]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such channel"}
+			return
+		}
+		explanations := []apiExplanation{}
+		for _, name := range perms.Names() {
+			perm, _ := acl.ParsePermission(name)
+			e, on := server.explainPermission(client, channel, perm)
+			a := apiExplanation{
+				Permission: name,
+				Granted:    e.Granted,
+				Reason:     string(e.Reason),
+				Inherited:  e.Inherited,
+				Text:       describeExplanation(e, on),
+			}
+			if on != nil {
+				a.Channel = &on.Id
+			}
+			if e.Entry != nil {
+				entry := newAPIACL(*e.Entry)
+				a.Index, a.Entry = &e.Index, &entry
+			}
+			explanations = append(explanations, a)
+		}
+		reply = explanations
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mumble.info/grumble/pkg/auditlog"
+)
+
+// apiUser is the JSON representation of a registered user.
+type apiUser struct {
+	Id             uint32 `json:"id"`
+	Name           string `json:"name"`
+	Email          string `json:"email,omitempty"`
+	HasCertificate bool   `json:"has_certificate"`
+	Online         bool   `json:"online"`
+	LastSeen       string `json:"last_seen,omitempty"`
+	LastChannel    int    `json:"last_channel"`
+	LastAddress    string `json:"last_address,omitempty"`
+	ConnectedTime  string `json:"connected_time,omitempty"`
+}
+
+func init() {
+	registerAPIEndpoint("users", handleAPIUsers)
+	registerAPIEndpoint("superuser-password", handleAPISuperUserPassword)
+}
+
+// handleAPIUsers implements /servers/<id>/users.
+//
+//	GET   lists the registered users, with when they were last seen,
+//	      in which channel and from which address, and the time they
+//	      have spent connected
+//	POST  creates a registration: {"name": "alice", "email": "alice@example.com"}
+//
+// /servers/<id>/users/<user>/certificates manages the certificates of
+// a user; see handleAPIUserCertificates.
+//
+// GET takes the optional parameters name, which lists only the users
+// whose name contains it, and prefix, whose name starts with it (both
+// ignoring case); sort, which is id (the default), name or last_seen;
+// order, which is asc (the default) or desc; and offset and limit,
+// which page through the users. The X-Total-Count header holds the
+// number of matching users.
+func handleAPIUsers(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) >= 2 && args[1] == "certificates" {
+		handleAPIUserCertificates(server, w, r, args[0], args[2:])
+		return
+	}
+
+	var req apiUser
+	var q userQuery
+	switch r.Method {
+	case http.MethodGet:
+		params := r.URL.Query()
+		q.Name = params.Get("name")
+		q.Prefix = params.Get("prefix")
+		q.Sort = params.Get("sort")
+		switch params.Get("order") {
+		case "", "asc":
+		case "desc":
+			q.Desc = true
+		default:
+			apiError(w, http.StatusBadRequest, "invalid order")
+			return
+		}
+		for _, p := range []struct {
+			name string
+			dst  *int
+		}{{"offset", &q.Offset}, {"limit", &q.Limit}} {
+			if v := params.Get(p.name); len(v) > 0 {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 0 {
+					apiError(w, http.StatusBadRequest, "invalid "+p.name)
+					return
+				}
+				*p.dst = n
+			}
+		}
+	case http.MethodPost:
+		if !readJSON(w, r, &req) {
+			return
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		if r.Method == http.MethodPost {
+			user, err := server.CreateRegistration(req.Name, req.Email)
+			if err != nil {
+				status, reply = http.StatusBadRequest, map[string]string{"error": err.Error()}
+				return
+			}
+			server.auditAPI(auditlog.Entry{
+				Action:  "user.register",
+				Target:  user.Name,
+				Details: fmt.Sprintf("user %v", user.Id),
+			})
+			status, reply = http.StatusCreated, apiUser{Id: user.Id, Name: user.Name, Email: user.Email}
+			return
+		}
+
+		matches, total, err := server.searchUsers(q)
+		if err != nil {
+			status, reply = http.StatusBadRequest, map[string]string{"error": err.Error()}
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+		users := []apiUser{}
+		online := server.onlineUsers()
+		for _, user := range matches {
+			client := online[user]
+			entry := apiUser{
+				Id:             user.Id,
+				Name:           user.Name,
+				Email:          user.Email,
+				HasCertificate: len(user.Certificates) > 0,
+				Online:         client != nil,
+				LastChannel:    user.LastChannelId,
+				LastAddress:    user.LastAddress,
+				ConnectedTime:  userConnectedTime(user, client).String(),
+			}
+			if user.LastActive > 0 {
+				entry.LastSeen = time.Unix(int64(user.LastActive), 0).UTC().Format(time.RFC3339)
+			}
+			users = append(users, entry)
+		}
+		reply = users
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
+
+// handleAPISuperUserPassword implements /servers/<id>/superuser-password.
+//
+//	PUT  sets the SuperUser password: {"password": "secret"}
+func handleAPISuperUserPassword(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		Password string `json:"password"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if len(req.Password) == 0 {
+		apiError(w, http.StatusBadRequest, "password must not be empty")
+		return
+	}
+
+	// Hashing the password is slow, so keep it off the handler
+	// goroutine.
+	val := server.hashConfigPassword(req.Password)
+	err := server.runSync(func() {
+		// SetSuperUserPassword hands the new value to the handler
+		// goroutine, which is the one running this function, so store
+		// it directly.
+		server.cfg.Set("SuperUserPassword", val)
+		server.UpdateConfig("SuperUserPassword", val)
+		server.auditAPI(auditlog.Entry{Action: "superuser.password", Target: "SuperUser"})
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
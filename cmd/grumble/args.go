@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"text/template"
+	"time"
 )
 
 type UsageArgs struct {
@@ -21,6 +22,10 @@ var usageTmpl = `usage: grumble [options]
  grumble {{.Version}} ({{.BuildDate}})
  target: {{.OS}}, {{.Arch}}
 
+ Options that don't run a one-off task can also be
+ given as environment variables, such as
+ GRUMBLE_DATADIR or GRUMBLE_API_ADDR.
+
  --help
      Shows this help listing.
 
@@ -30,6 +35,12 @@ var usageTmpl = `usage: grumble [options]
  --log <log-path> (default: $DATADIR/grumble.log)
      Log file path.
 
+ --config <config-path> (default: $DATADIR/grumble.{toml,yaml,ini} or murmur.ini)
+     Configuration file path. The format is chosen
+     by the file's extension; murmur.ini files are
+     read as Murmur configuration. The file is
+     re-read when grumble receives SIGHUP.
+
  --regen-keys
      Force grumble to regenerate its global RSA
      keypair (and certificate).
@@ -37,21 +48,153 @@ var usageTmpl = `usage: grumble [options]
      The global keypair lives in the root of the
      grumble data directory.
 
+ --api-addr <host:port>
+     Serve the admin API on the given address.
+     Requests must be authenticated using the token
+     stored in $DATADIR/api.token.
+
+ --debug-addr <host:port>
+     Serve net/http/pprof and expvar on the given
+     loopback address, under /debug/pprof/ and
+     /debug/vars.
+
+ --health-addr <host:port>
+     Serve the /healthz and /readyz health checks,
+     for liveness and readiness probes, on the given
+     address. They need no authentication.
+
+ --ice-addr <host:port>
+     Serve a subset of Murmur's Ice interface on the
+     given address. Requests must pass the secret
+     stored in $DATADIR/ice.secret in the "secret"
+     context key.
+
+ --replication-addr <host:port>
+     Stream the state of all virtual servers to the
+     standby instances that connect to the given
+     address.
+
+ --replicate-from <host:port>
+     Run as a standby of the Grumble instance serving
+     replication on the given address: keep a copy of
+     its servers' state, and start them only once
+     promoted, by SIGUSR1 or --failover-after.
+
+ --replication-peer <fingerprint>
+     The SHA-256 fingerprint of the other instance's
+     certificate ($DATADIR/cert.pem), which is checked
+     by both sides of a replication stream.
+
+ --failover-after <duration>
+     Promote a standby once the primary has been
+     unreachable for this long. (default 0: only
+     promote on SIGUSR1)
+
+ --geoip <ip2asn-tsv-path>
+     Load an IP-to-country/ASN database (in the
+     iptoasn.com TSV format) and keep per-country
+     and per-ASN connection statistics.
+
+ --setsuperuserpw <server-id>
+     Set the SuperUser password of a virtual server to
+     the first line read from standard input, and exit.
+     Grumble must not already be running.
+
+ --migrate
+     Report the schema version of the data directory
+     and the migrations that are applied to it when
+     grumble starts, and exit.
+
+ --migrate-to <version>
+     Migrate the data directory up or down to the
+     given schema version, and exit. Grumble must not
+     already be running.
+
+ --blob-cache <MiB>
+     Keep up to this many MiB of recently used blobs
+     (user textures and comments, channel descriptions)
+     in memory. 0 disables the cache. (default 16)
+
+ --verify-blobstore
+     Re-hash all blobs in the blob store, report those
+     whose content doesn't match their name, and the
+     users and channels that refer to corrupt or
+     missing blobs, and exit.
+
+ --quarantine-blobs
+     With --verify-blobstore, move corrupt blobs to
+     $DATADIR/blob/quarantine.
+
+ --backup-target <dir | s3://bucket/prefix>
+     Back up the data directory to the given directory
+     or S3 bucket. S3 credentials are read from
+     AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, the
+     region from AWS_REGION, and AWS_ENDPOINT_URL may
+     point at an S3-compatible service.
+
+ --backup-schedule <cron-spec>
+     When to back up, as the five fields of a crontab
+     line, in local time. (default "0 3 * * *")
+
+ --backup-keep-daily <count>, --backup-keep-weekly <count>
+     Keep the latest backup of each of this many days
+     and weeks, and remove the others. (default 7, 4)
+
+ --restore-backup <name | latest>
+     Replace the data directory's contents with those
+     of a backup from the --backup-target, and exit.
+     Grumble must not already be running.
+
  --import-murmurdb <murmur-sqlite-path>
      Import a Murmur SQLite database into grumble.
 
      Use the --cleanup argument to force grumble to
      clean up its data directory when doing the
      import. This is *DESTRUCTIVE*! Use with care.
+
+ --import-timeout <duration>
+     Give up on a query to the Murmur database that
+     takes longer than this. (default 30s)
+
+ --import-retries <count>
+     Retry a query this many times while a running
+     Murmur holds a lock on its database. (default 5)
 `
 
 type args struct {
-	ShowHelp  bool
-	DataDir   string
-	LogPath   string
-	RegenKeys bool
-	SQLiteDB  string
-	CleanUp   bool
+	ShowHelp   bool
+	DataDir    string
+	LogPath    string
+	ConfigPath string
+	RegenKeys  bool
+	APIAddr    string
+	DebugAddr  string
+	HealthAddr string
+	IceAddr    string
+	GeoIPDB    string
+	SetSUPW    string
+	Migrate    bool
+	MigrateTo  string
+	SQLiteDB   string
+	CleanUp    bool
+
+	BlobCacheSize   int64
+	VerifyBlobStore bool
+	QuarantineBlobs bool
+
+	BackupTarget     string
+	BackupSchedule   string
+	BackupKeepDaily  int
+	BackupKeepWeekly int
+	RestoreBackup    string
+
+	ReplicationAddr string
+	ReplicateFrom   string
+	ReplicationPeer string
+	FailoverAfter   time.Duration
+
+	ImportTimeout time.Duration
+	ImportRetries int
 }
 
 func defaultDataDir() string {
@@ -93,8 +236,33 @@ func init() {
 	flag.BoolVar(&Args.ShowHelp, "help", false, "")
 	flag.StringVar(&Args.DataDir, "datadir", defaultDataDir(), "")
 	flag.StringVar(&Args.LogPath, "log", defaultLogPath(), "")
+	flag.StringVar(&Args.ConfigPath, "config", "", "")
 	flag.BoolVar(&Args.RegenKeys, "regen-keys", false, "")
+	flag.StringVar(&Args.APIAddr, "api-addr", "", "")
+	flag.StringVar(&Args.DebugAddr, "debug-addr", "", "")
+	flag.StringVar(&Args.HealthAddr, "health-addr", "", "")
+	flag.StringVar(&Args.IceAddr, "ice-addr", "", "")
+	flag.StringVar(&Args.ReplicationAddr, "replication-addr", "", "")
+	flag.StringVar(&Args.ReplicateFrom, "replicate-from", "", "")
+	flag.StringVar(&Args.ReplicationPeer, "replication-peer", "", "")
+	flag.DurationVar(&Args.FailoverAfter, "failover-after", 0, "")
+	flag.StringVar(&Args.GeoIPDB, "geoip", "", "")
+	flag.StringVar(&Args.SetSUPW, "setsuperuserpw", "", "")
+	flag.BoolVar(&Args.Migrate, "migrate", false, "")
+	flag.StringVar(&Args.MigrateTo, "migrate-to", "", "")
+
+	flag.Int64Var(&Args.BlobCacheSize, "blob-cache", defaultBlobCacheSize, "")
+	flag.BoolVar(&Args.VerifyBlobStore, "verify-blobstore", false, "")
+	flag.BoolVar(&Args.QuarantineBlobs, "quarantine-blobs", false, "")
+
+	flag.StringVar(&Args.BackupTarget, "backup-target", "", "")
+	flag.StringVar(&Args.BackupSchedule, "backup-schedule", defaultBackupSchedule, "")
+	flag.IntVar(&Args.BackupKeepDaily, "backup-keep-daily", defaultBackupKeepDaily, "")
+	flag.IntVar(&Args.BackupKeepWeekly, "backup-keep-weekly", defaultBackupKeepWeekly, "")
+	flag.StringVar(&Args.RestoreBackup, "restore-backup", "", "")
 
 	flag.StringVar(&Args.SQLiteDB, "import-murmurdb", "", "")
 	flag.BoolVar(&Args.CleanUp, "cleanup", false, "")
+	flag.DurationVar(&Args.ImportTimeout, "import-timeout", defaultMurmurQueryTimeout, "")
+	flag.IntVar(&Args.ImportRetries, "import-retries", defaultMurmurBusyRetries, "")
 }
@@ -0,0 +1,241 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the audit log of administrative actions.
+//
+// Kicks, bans, mutes, channel and ACL edits and registration changes
+// are recorded in $DATADIR/servers/<id>/audit.jsonl, along with who
+// made them. The log can be queried and exported through the admin API.
+// Entries older than AuditLogRetention days are removed once an hour.
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/ban"
+)
+
+// The number of entries returned by an audit log query without a limit.
+const defaultAuditQueryLimit = 100
+
+// auditLog returns the server's audit log, opening it if needed.
+func (server *Server) auditLog() (*auditlog.Log, error) {
+	server.auditLock.Lock()
+	defer server.auditLock.Unlock()
+
+	if server.audits != nil {
+		return server.audits, nil
+	}
+	err := os.MkdirAll(server.dir, 0750)
+	if err != nil {
+		return nil, err
+	}
+	l, err := auditlog.Open(filepath.Join(server.dir, "audit.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	server.audits = l
+	return l, nil
+}
+
+// pruneAuditLog removes the entries older than AuditLogRetention days
+// from the audit log, in the background. Offenses that the escalation
+// rules still count are kept.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) pruneAuditLog() {
+	days := server.cfg.IntValue("AuditLogRetention")
+	if days <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -days)
+	if policy := server.escalationPolicy; policy != nil {
+		if since := time.Now().Add(-policy.MaxWindow()); since.Before(before) {
+			before = since
+		}
+	}
+	go func() {
+		l, err := server.auditLog()
+		if err != nil {
+			server.Printf("Unable to open audit log: %v", err)
+			return
+		}
+		n, err := l.Prune(before)
+		if err != nil {
+			server.Printf("Unable to prune audit log: %v", err)
+		} else if n > 0 {
+			server.Printf("Removed %v audit log entries older than %v days", n, days)
+		}
+	}()
+}
+
+// audit records an administrative action taken by actor. If actor is
+// nil, the entry's Actor is kept, or set to "server" if empty.
+func (server *Server) audit(actor *Client, entry auditlog.Entry) {
+	if actor != nil {
+		entry.Actor = actor.ShownName()
+		entry.ActorId = actor.UserId()
+	} else {
+		if len(entry.Actor) == 0 {
+			entry.Actor = "server"
+		}
+		entry.ActorId = -1
+	}
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	l, err := server.auditLog()
+	if err == nil {
+		err = l.Append(entry)
+	}
+	if err != nil {
+		server.Printf("Unable to write audit log entry: %v", err)
+	}
+
+	server.auditLock.Lock()
+	offenses := server.offenses
+	server.auditLock.Unlock()
+	if offenses != nil {
+		offenses.add(entry.Target, entry.Action, entry.Time)
+	}
+}
+
+// auditAPI records an administrative action taken through the admin API.
+func (server *Server) auditAPI(entry auditlog.Entry) {
+	entry.Actor = "api"
+	server.audit(nil, entry)
+}
+
+// auditBanListChanges records the bans added and removed by an edit
+// of the ban list.
+func (server *Server) auditBanListChanges(actor *Client, old, updated []ban.Ban) {
+	key := func(b ban.Ban) string {
+		return fmt.Sprintf("%v/%v %v %v %v %v %v", b.IP, b.Mask, b.Username, b.CertHash, b.Reason, b.Start, b.Duration)
+	}
+	describe := func(b ban.Ban) auditlog.Entry {
+		return auditlog.Entry{
+			Target:  banTarget(b),
+			Reason:  b.Reason,
+			Details: fmt.Sprintf("%v/%v hash %v duration %v", b.IP, b.Mask, b.CertHash, b.Duration),
+		}
+	}
+
+	oldKeys := make(map[string]bool)
+	for _, b := range old {
+		oldKeys[key(b)] = true
+	}
+	newKeys := make(map[string]bool)
+	for _, b := range updated {
+		k := key(b)
+		newKeys[k] = true
+		if !oldKeys[k] {
+			entry := describe(b)
+			entry.Action = "ban.add"
+			server.audit(actor, entry)
+		}
+	}
+	for _, b := range old {
+		if !newKeys[key(b)] {
+			entry := describe(b)
+			entry.Action = "ban.remove"
+			server.audit(actor, entry)
+		}
+	}
+}
+
+// banTarget names who b bans: the banned user, or the address if the
+// ban has no name.
+func banTarget(b ban.Ban) string {
+	if len(b.Username) == 0 {
+		return b.IP.String()
+	}
+	return b.Username
+}
+
+func init() {
+	registerAPIEndpoint("audit", handleAPIAudit)
+}
+
+// handleAPIAudit implements /servers/<id>/audit.
+//
+//	GET /audit         lists entries as JSON
+//	GET /audit/export  downloads entries as JSONL
+//
+// Both accept the query parameters action, actor, target, since and
+// until (RFC 3339 times) and limit. Without a limit, /audit returns
+// the 100 most recent entries, and /audit/export returns all entries.
+func handleAPIAudit(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	export := false
+	if len(args) == 1 && args[0] == "export" {
+		export = true
+	} else if len(args) != 0 {
+		apiError(w, http.StatusNotFound, "expected /audit or /audit/export")
+		return
+	}
+
+	params := r.URL.Query()
+	q := auditlog.Query{
+		Action: params.Get("action"),
+		Actor:  params.Get("actor"),
+		Target: params.Get("target"),
+	}
+	var err error
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if v := params.Get(p.name); len(v) > 0 {
+			*p.dst, err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				apiError(w, http.StatusBadRequest, "invalid "+p.name)
+				return
+			}
+		}
+	}
+	if v := params.Get("limit"); len(v) > 0 {
+		q.Limit, err = strconv.Atoi(v)
+		if err != nil || q.Limit < 0 {
+			apiError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	} else if !export {
+		q.Limit = defaultAuditQueryLimit
+	}
+
+	l, err := server.auditLog()
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if export {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment; filename=audit-"+strconv.FormatInt(server.Id, 10)+".jsonl")
+		err = l.Export(w, q)
+		if err != nil {
+			server.Printf("Unable to export audit log: %v", err)
+		}
+		return
+	}
+
+	entries, err := l.Query(q)
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements automatic registration of clients with strong
+// certificates.
+//
+// When CertAutoRegister is set, a client that isn't registered and
+// presents a certificate issued by a trusted CA is registered on
+// connect, under the common name of its certificate. The trusted CAs
+// are those used to verify client certificates (see CertCAFile), or
+// those in CertAutoRegisterCAFile.
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"mumble.info/grumble/pkg/auditlog"
+)
+
+// strongCertificate returns the client's certificate if it was issued
+// by a CA trusted for automatic registration, and nil otherwise.
+func (server *Server) strongCertificate(client *Client) *x509.Certificate {
+	certs := client.peerCertificates()
+	if len(certs) == 0 {
+		return nil
+	}
+	if len(server.cfg.StringValue("CertAutoRegisterCAFile")) == 0 {
+		if client.IsVerified() {
+			return certs[0]
+		}
+		return nil
+	}
+
+	roots, err := server.certPool("CertAutoRegisterCAFile")
+	if err != nil {
+		server.Printf("Unable to load CAs for automatic registration: %v", err)
+		return nil
+	}
+	if !verifyClientCertificate(certs, roots) {
+		return nil
+	}
+	return certs[0]
+}
+
+// autoRegister registers client under the common name of cert. It
+// fails if the name is taken by another registration.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) autoRegister(client *Client, cert *x509.Certificate) (*User, error) {
+	name := strings.TrimSpace(cert.Subject.CommonName)
+	if len(name) == 0 {
+		return nil, errors.New("certificate has no common name")
+	}
+	if strings.EqualFold(name, "SuperUser") {
+		return nil, errors.New("certificate claims the SuperUser name")
+	}
+	if user, exists := server.registeredUser(name); exists {
+		server.audit(nil, auditlog.Entry{
+			Action:  "user.autoregister.conflict",
+			Actor:   "autoregister",
+			Target:  name,
+			Details: fmt.Sprintf("certificate %v, name registered to user %v", client.CertHash(), user.Id),
+		})
+		return nil, fmt.Errorf("name %q is registered to user %v", name, user.Id)
+	}
+	if _, exists := server.UserCertMap[client.CertHash()]; exists {
+		return nil, errors.New("certificate already registered")
+	}
+
+	user, err := NewUser(server.nextUserId, name)
+	if err != nil {
+		return nil, err
+	}
+	user.Email = client.Email
+	if len(user.Email) == 0 && len(cert.EmailAddresses) > 0 {
+		user.Email = cert.EmailAddresses[0]
+	}
+	user.Certificates = []UserCertificate{{Hash: client.CertHash(), Added: time.Now().Unix()}}
+
+	server.nextUserId += 1
+	server.Users[user.Id] = user
+	server.UserNameMap[user.Name] = user
+	server.UserCertMap[client.CertHash()] = user
+	server.UpdateFrozenUserRecord(user)
+
+	server.audit(nil, auditlog.Entry{
+		Action:  "user.register",
+		Actor:   "autoregister",
+		Target:  user.Name,
+		Details: fmt.Sprintf("user %v certificate %v", user.Id, client.CertHash()),
+	})
+	return user, nil
+}
+
+func init() {
+	registerAPIEndpoint("autoregister", handleAPIAutoRegister)
+}
+
+// handleAPIAutoRegister implements /servers/<id>/autoregister.
+//
+//	GET  shows whether automatic registration is enabled
+//	PUT  enables or disables it: {"enabled": true}
+func handleAPIAutoRegister(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if !readJSON(w, r, &req) {
+			return
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var enabled bool
+	err := server.runSync(func() {
+		if r.Method != http.MethodGet {
+			val := fmt.Sprintf("%v", req.Enabled)
+			server.cfg.Set("CertAutoRegister", val)
+			server.UpdateConfig("CertAutoRegister", val)
+			server.auditAPI(auditlog.Entry{Action: "config.autoregister", Details: val})
+		}
+		enabled = server.cfg.BoolValue("CertAutoRegister")
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": enabled})
+}
@@ -0,0 +1,289 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements scheduled backups of the data directory.
+//
+// With --backup-target set, the data directory, that is the servers'
+// snapshots and logs, the blob store, the certificate and the
+// configuration, is archived on the schedule given by
+// --backup-schedule, to a local directory or an S3 bucket. Each server
+// is snapshotted first, so that the archive holds its full state.
+// After each backup, old backups are removed as --backup-keep-daily
+// and --backup-keep-weekly allow.
+//
+// The admin API lists the backups at /backups, and starts one with a
+// POST. --restore-backup restores the data directory from a backup
+// while Grumble is stopped.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mumble.info/grumble/pkg/backup"
+	"mumble.info/grumble/pkg/cron"
+)
+
+const (
+	defaultBackupSchedule   = "0 3 * * *"
+	defaultBackupKeepDaily  = 7
+	defaultBackupKeepWeekly = 4
+)
+
+var errBackupRunning = errors.New("a backup is already running")
+
+// backupState tracks the backup that is running, and the outcome of
+// the last one.
+var backupState struct {
+	sync.Mutex
+	running bool
+	last    string
+	lastErr error
+}
+
+func init() {
+	apiMux.HandleFunc("/backups", handleAPIBackups)
+}
+
+// backupTarget opens the target given by --backup-target.
+func backupTarget() (backup.Target, error) {
+	if len(Args.BackupTarget) == 0 {
+		return nil, errors.New("no backup target configured (see --backup-target)")
+	}
+	return backup.Open(Args.BackupTarget)
+}
+
+// skipBackupPath reports whether fn is left out of backups: the log
+// file, snapshots being written, and a backup directory inside the
+// data directory.
+func skipBackupPath(fn string) bool {
+	if strings.HasPrefix(filepath.Base(fn), ".main.fz_") {
+		return true
+	}
+	skip := []string{Args.LogPath}
+	if !strings.HasPrefix(Args.BackupTarget, "s3://") {
+		skip = append(skip, Args.BackupTarget)
+	}
+	abs, err := filepath.Abs(fn)
+	if err != nil {
+		return false
+	}
+	for _, path := range skip {
+		if path, err := filepath.Abs(path); err == nil && path == abs {
+			return true
+		}
+	}
+	return false
+}
+
+// runBackup snapshots all servers, archives the data directory to the
+// backup target and removes expired backups. It returns the name of
+// the new backup.
+func runBackup() (name string, err error) {
+	backupState.Lock()
+	if backupState.running {
+		backupState.Unlock()
+		return "", errBackupRunning
+	}
+	backupState.running = true
+	backupState.Unlock()
+	defer func() {
+		backupState.Lock()
+		backupState.running = false
+		backupState.last = name
+		backupState.lastErr = err
+		backupState.Unlock()
+	}()
+
+	target, err := backupTarget()
+	if err != nil {
+		return "", err
+	}
+
+	// Fold each running server's log into a fresh snapshot. Servers
+	// that aren't running were snapshotted when they stopped.
+	for _, server := range servers {
+		var ferr error
+		if server.runSync(func() { ferr = server.FreezeToFile() }) == nil && ferr != nil {
+			return "", ferr
+		}
+	}
+
+	name = backup.Name(time.Now())
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(backup.Archive(pw, Args.DataDir, skipBackupPath))
+	}()
+	err = target.Put(name, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Backed up data directory to %v", name)
+
+	names, err := target.List()
+	if err != nil {
+		return name, err
+	}
+	retention := backup.Retention{Daily: Args.BackupKeepDaily, Weekly: Args.BackupKeepWeekly}
+	for _, old := range retention.Expired(names) {
+		if err := target.Delete(old); err != nil {
+			return name, err
+		}
+		log.Printf("Removed expired backup %v", old)
+	}
+	return name, nil
+}
+
+// StartBackups runs backups on the schedule given by --backup-schedule,
+// in local time.
+func StartBackups() error {
+	if _, err := backupTarget(); err != nil {
+		return err
+	}
+	schedule, err := cron.Parse(Args.BackupSchedule)
+	if err != nil {
+		return fmt.Errorf("invalid backup schedule %q: %v", Args.BackupSchedule, err)
+	}
+	go func() {
+		for {
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				log.Printf("Backup schedule %q never runs", Args.BackupSchedule)
+				return
+			}
+			time.Sleep(time.Until(next))
+			if _, err := runBackup(); err != nil {
+				log.Printf("Backup failed: %v", err)
+			}
+		}
+	}()
+	log.Printf("Backing up data directory to %v on schedule %q", Args.BackupTarget, Args.BackupSchedule)
+	return nil
+}
+
+// restoreBackup replaces the data directory's contents with those of
+// the backup name, or of the latest backup if name is "latest". The
+// backup is extracted in full before anything is replaced, so that a
+// damaged backup leaves the data directory alone. Files that aren't
+// in the backup, such as the log file, are kept.
+func restoreBackup(name string) error {
+	target, err := backupTarget()
+	if err != nil {
+		return err
+	}
+	if name == "latest" {
+		names, err := target.List()
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return errors.New("no backups found")
+		}
+		name = names[len(names)-1]
+	}
+
+	r, err := target.Get(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp, err := ioutil.TempDir(Args.DataDir, ".restore_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := backup.Restore(r, tmp); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(tmp)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		dst := filepath.Join(Args.DataDir, entry.Name())
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(tmp, entry.Name()), dst); err != nil {
+			return err
+		}
+	}
+	log.Printf("Restored data directory from %v", name)
+	return nil
+}
+
+// apiBackups is the JSON representation of the backups in the admin API.
+type apiBackups struct {
+	Backups   []string `json:"backups"`
+	Running   bool     `json:"running"`
+	Last      string   `json:"last,omitempty"`
+	LastError string   `json:"last_error,omitempty"`
+}
+
+// handleAPIBackups implements /backups.
+//
+//	GET  lists the stored backups, oldest first, and the outcome of the last one.
+//	POST starts a backup.
+func handleAPIBackups(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		target, err := backupTarget()
+		if err != nil {
+			apiError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		names, err := target.List()
+		if err != nil {
+			apiError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		resp := apiBackups{Backups: names}
+		backupState.Lock()
+		resp.Running = backupState.running
+		resp.Last = backupState.last
+		if backupState.lastErr != nil {
+			resp.LastError = backupState.lastErr.Error()
+		}
+		backupState.Unlock()
+		if resp.Backups == nil {
+			resp.Backups = []string{}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	case http.MethodPost:
+		if _, err := backupTarget(); err != nil {
+			apiError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		backupState.Lock()
+		running := backupState.running
+		backupState.Unlock()
+		if running {
+			apiError(w, http.StatusConflict, errBackupRunning.Error())
+			return
+		}
+		// Backups of large data directories take longer than the API
+		// may take to answer, so the backup runs in the background.
+		go func() {
+			if _, err := runBackup(); err != nil {
+				log.Printf("Backup failed: %v", err)
+			}
+		}()
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
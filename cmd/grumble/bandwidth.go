@@ -0,0 +1,433 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the per-client bandwidth accounting.
+//
+// Every client counts the bytes it sends and receives, split into voice
+// (UDP and tunnelled voice packets) and control traffic, both in total
+// and for each of the last bandwidthWindow seconds. The recent traffic
+// gives the bandwidth reported in UserStats, the admin API's "top
+// talkers" report at /servers/<id>/talkers, and the Prometheus metrics
+// served at /metrics on the diagnostics port, next to the voice loss
+// statistics (see voiceloss.go).
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The number of seconds of traffic a client remembers.
+const bandwidthWindow = 60
+
+// The number of seconds the bandwidth in UserStats is averaged over.
+const userStatsBandwidthWindow = 5
+
+// The kinds of traffic counted.
+const (
+	trafficControlIn = iota
+	trafficControlOut
+	trafficVoiceIn
+	trafficVoiceOut
+	numTrafficKinds
+)
+
+// trafficKindNames names the kinds of traffic for the admin API and
+// the metrics.
+var trafficKindNames = [numTrafficKinds]string{"control_in", "control_out", "voice_in", "voice_out"}
+
+// trafficCounter counts the bytes sent and received by a client.
+// It is updated from the client's network goroutines, so its
+// fields must only be accessed atomically, or with recent's lock.
+type trafficCounter struct {
+	in  uint64
+	out uint64
+	// The voice share of in and out.
+	voiceIn  uint64
+	voiceOut uint64
+	// The voice packets received from the client.
+	voicePackets uint64
+
+	recent bandwidthRecorder
+}
+
+func (tc *trafficCounter) addIn(n int) {
+	atomic.AddUint64(&tc.in, uint64(n))
+	tc.recent.add(time.Now(), trafficControlIn, n)
+}
+
+func (tc *trafficCounter) addOut(n int) {
+	atomic.AddUint64(&tc.out, uint64(n))
+	tc.recent.add(time.Now(), trafficControlOut, n)
+}
+
+func (tc *trafficCounter) addVoiceIn(n int) {
+	atomic.AddUint64(&tc.in, uint64(n))
+	atomic.AddUint64(&tc.voiceIn, uint64(n))
+	tc.recent.add(time.Now(), trafficVoiceIn, n)
+}
+
+func (tc *trafficCounter) addVoiceOut(n int) {
+	atomic.AddUint64(&tc.out, uint64(n))
+	atomic.AddUint64(&tc.voiceOut, uint64(n))
+	tc.recent.add(time.Now(), trafficVoiceOut, n)
+}
+
+func (tc *trafficCounter) addVoicePacket() {
+	atomic.AddUint64(&tc.voicePackets, 1)
+}
+
+// load returns the current byte counts.
+func (tc *trafficCounter) load() (in uint64, out uint64) {
+	return atomic.LoadUint64(&tc.in), atomic.LoadUint64(&tc.out)
+}
+
+// totals returns the byte counts by kind of traffic.
+func (tc *trafficCounter) totals() (totals [numTrafficKinds]uint64) {
+	in, out := tc.load()
+	totals[trafficVoiceIn] = atomic.LoadUint64(&tc.voiceIn)
+	totals[trafficVoiceOut] = atomic.LoadUint64(&tc.voiceOut)
+	totals[trafficControlIn] = in - totals[trafficVoiceIn]
+	totals[trafficControlOut] = out - totals[trafficVoiceOut]
+	return
+}
+
+// A bandwidthRecorder keeps the traffic of the last bandwidthWindow
+// seconds, one bucket per second.
+type bandwidthRecorder struct {
+	mutex   sync.Mutex
+	buckets [bandwidthWindow]bandwidthBucket
+}
+
+type bandwidthBucket struct {
+	second int64
+	bytes  [numTrafficKinds]uint64
+}
+
+// add counts n bytes of the given kind of traffic at now.
+func (br *bandwidthRecorder) add(now time.Time, kind int, n int) {
+	sec := now.Unix()
+	br.mutex.Lock()
+	bucket := &br.buckets[sec%bandwidthWindow]
+	if bucket.second != sec {
+		*bucket = bandwidthBucket{second: sec}
+	}
+	bucket.bytes[kind] += uint64(n)
+	br.mutex.Unlock()
+}
+
+// rates returns the average bytes per second of each kind of traffic
+// over the last seconds whole seconds before now.
+func (br *bandwidthRecorder) rates(now time.Time, seconds int) (rates [numTrafficKinds]float64) {
+	if seconds <= 0 || seconds >= bandwidthWindow {
+		seconds = bandwidthWindow - 1
+	}
+	sec := now.Unix()
+	br.mutex.Lock()
+	for _, bucket := range br.buckets {
+		if bucket.second < sec && bucket.second >= sec-int64(seconds) {
+			for kind, n := range bucket.bytes {
+				rates[kind] += float64(n)
+			}
+		}
+	}
+	br.mutex.Unlock()
+	for kind := range rates {
+		rates[kind] /= float64(seconds)
+	}
+	return
+}
+
+// bandwidth returns the client's recent voice bandwidth in bytes per
+// second, as reported in UserStats.
+func (client *Client) bandwidth() uint32 {
+	rates := client.traffic.recent.rates(time.Now(), userStatsBandwidthWindow)
+	return uint32(rates[trafficVoiceIn])
+}
+
+func init() {
+	registerAPIEndpoint("talkers", handleAPITalkers)
+}
+
+// apiTalker is the JSON representation of a client's traffic in the
+// admin API. Rates are in bytes per second, totals in bytes.
+type apiTalker struct {
+	Session      uint32             `json:"session"`
+	Name         string             `json:"name"`
+	Channel      uint32             `json:"channel"`
+	VoicePackets uint64             `json:"voice_packets"`
+	Rates        map[string]float64 `json:"rates"`
+	Totals       map[string]uint64  `json:"totals"`
+
+	sortKey float64
+}
+
+// handleAPITalkers implements /servers/<id>/talkers.
+//
+//	GET  lists the connected clients by their traffic, highest first
+//
+// The optional sort parameter is one of control_in, control_out,
+// voice_in (the default), voice_out or total. window sets the number of
+// seconds the rates are averaged over (default and at most 59), and
+// limit the number of clients listed.
+func handleAPITalkers(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	query := r.URL.Query()
+	sortBy := query.Get("sort")
+	if sortBy == "" {
+		sortBy = "voice_in"
+	}
+	sortKind := -1
+	for kind, name := range trafficKindNames {
+		if name == sortBy {
+			sortKind = kind
+		}
+	}
+	if sortKind < 0 && sortBy != "total" {
+		apiError(w, http.StatusBadRequest, "invalid sort")
+		return
+	}
+	window := bandwidthWindow - 1
+	if v := query.Get("window"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n >= bandwidthWindow {
+			apiError(w, http.StatusBadRequest, fmt.Sprintf("window must be between 1 and %v", bandwidthWindow-1))
+			return
+		}
+		window = n
+	}
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			apiError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+
+	talkers := []apiTalker{}
+	now := time.Now()
+	err := server.runSync(func() {
+		for _, client := range server.clients {
+			if client.state != StateClientReady {
+				continue
+			}
+			rates := client.traffic.recent.rates(now, window)
+			totals := client.traffic.totals()
+			talker := apiTalker{
+				Session:      client.Session(),
+				Name:         client.ShownName(),
+				Channel:      uint32(client.Channel.Id),
+				VoicePackets: atomic.LoadUint64(&client.traffic.voicePackets),
+				Rates:        map[string]float64{},
+				Totals:       map[string]uint64{},
+			}
+			for kind, name := range trafficKindNames {
+				talker.Rates[name] = rates[kind]
+				talker.Totals[name] = totals[kind]
+				if sortKind < 0 || sortKind == kind {
+					talker.sortKey += rates[kind]
+				}
+			}
+			talkers = append(talkers, talker)
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	sort.Slice(talkers, func(i, j int) bool {
+		if talkers[i].sortKey != talkers[j].sortKey {
+			return talkers[i].sortKey > talkers[j].sortKey
+		}
+		return talkers[i].Session < talkers[j].Session
+	})
+	if limit > 0 && len(talkers) > limit {
+		talkers = talkers[:limit]
+	}
+	writeJSON(w, http.StatusOK, talkers)
+}
+
+// handleMetrics serves the servers' client and traffic statistics in
+// the Prometheus text format.
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	type clientMetrics struct {
+		server  int64
+		session uint32
+		name    string
+		packets uint64
+		totals  [numTrafficKinds]uint64
+		voice   voiceStatsSnapshot
+	}
+	// The number of ready clients whose voice goes over UDP and TCP,
+	// and the number of UDP fallbacks, by server.
+	type transportMetrics struct {
+		udp, tcp  int
+		fallbacks uint64
+	}
+	var clients []clientMetrics
+	counts := map[int64]int{}
+	transports := map[int64]transportMetrics{}
+	geo := map[int64]geoMetrics{}
+	ids := []int64{}
+	for id := range servers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		server := servers[id]
+		server.runSync(func() {
+			counts[id] = len(server.clients)
+			transport := transportMetrics{fallbacks: server.udpFallbacks}
+			for _, client := range server.clients {
+				if client.state != StateClientReady {
+					continue
+				}
+				if client.usesUDP() {
+					transport.udp++
+				} else {
+					transport.tcp++
+				}
+				clients = append(clients, clientMetrics{
+					server:  id,
+					session: client.Session(),
+					name:    client.ShownName(),
+					packets: atomic.LoadUint64(&client.traffic.voicePackets),
+					totals:  client.traffic.totals(),
+					voice:   client.voiceStats.snapshot(),
+				})
+			}
+			transports[id] = transport
+			if geoDB != nil {
+				countries, asns := server.geoStatsSnapshot()
+				geo[id] = geoMetrics{countries, asns}
+			}
+		})
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].server != clients[j].server {
+			return clients[i].server < clients[j].server
+		}
+		return clients[i].session < clients[j].session
+	})
+
+	fmt.Fprintln(w, "# HELP grumble_connections Connections to the server, in any state.")
+	fmt.Fprintln(w, "# TYPE grumble_connections gauge")
+	for _, id := range ids {
+		if n, ok := counts[id]; ok {
+			fmt.Fprintf(w, "grumble_connections{server=\"%v\"} %v\n", id, n)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP grumble_clients Connected clients by the transport their voice is sent over.")
+	fmt.Fprintln(w, "# TYPE grumble_clients gauge")
+	for _, id := range ids {
+		if t, ok := transports[id]; ok {
+			fmt.Fprintf(w, "grumble_clients{server=\"%v\",transport=\"udp\"} %v\n", id, t.udp)
+			fmt.Fprintf(w, "grumble_clients{server=\"%v\",transport=\"tcp\"} %v\n", id, t.tcp)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP grumble_udp_fallbacks_total Times a client's UDP path was found dead and its voice moved to TCP.")
+	fmt.Fprintln(w, "# TYPE grumble_udp_fallbacks_total counter")
+	for _, id := range ids {
+		if t, ok := transports[id]; ok {
+			fmt.Fprintf(w, "grumble_udp_fallbacks_total{server=\"%v\"} %v\n", id, t.fallbacks)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP grumble_client_traffic_bytes_total Bytes sent and received by connected clients.")
+	fmt.Fprintln(w, "# TYPE grumble_client_traffic_bytes_total counter")
+	for _, c := range clients {
+		for kind, n := range c.totals {
+			parts := strings.SplitN(trafficKindNames[kind], "_", 2)
+			writeMetric(w, "grumble_client_traffic_bytes_total", n,
+				"server", strconv.FormatInt(c.server, 10),
+				"session", strconv.FormatUint(uint64(c.session), 10),
+				"user", c.name,
+				"kind", parts[0],
+				"direction", parts[1])
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP grumble_client_voice_packets_total Voice packets received from connected clients.")
+	fmt.Fprintln(w, "# TYPE grumble_client_voice_packets_total counter")
+	for _, c := range clients {
+		writeMetric(w, "grumble_client_voice_packets_total", c.packets,
+			"server", strconv.FormatInt(c.server, 10),
+			"session", strconv.FormatUint(uint64(c.session), 10),
+			"user", c.name)
+	}
+
+	fmt.Fprintln(w, "# HELP grumble_client_voice_frames_total Voice packets of connected clients by how their sequence numbers arrived: in order (good), late, or never (lost).")
+	fmt.Fprintln(w, "# TYPE grumble_client_voice_frames_total counter")
+	for _, c := range clients {
+		for _, count := range []struct {
+			result string
+			n      uint32
+		}{{"good", c.voice.good}, {"late", c.voice.late}, {"lost", c.voice.lost}} {
+			writeMetric(w, "grumble_client_voice_frames_total", count.n,
+				"server", strconv.FormatInt(c.server, 10),
+				"session", strconv.FormatUint(uint64(c.session), 10),
+				"user", c.name,
+				"result", count.result)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP grumble_client_voice_lost_bursts_total Gaps of two or more lost voice packets in a row.")
+	fmt.Fprintln(w, "# TYPE grumble_client_voice_lost_bursts_total counter")
+	for _, c := range clients {
+		writeMetric(w, "grumble_client_voice_lost_bursts_total", c.voice.lostBursts,
+			"server", strconv.FormatInt(c.server, 10),
+			"session", strconv.FormatUint(uint64(c.session), 10),
+			"user", c.name)
+	}
+
+	fmt.Fprintln(w, "# HELP grumble_client_voice_jitter_milliseconds Interarrival jitter of connected clients' voice packets.")
+	fmt.Fprintln(w, "# TYPE grumble_client_voice_jitter_milliseconds gauge")
+	for _, c := range clients {
+		writeMetric(w, "grumble_client_voice_jitter_milliseconds", c.voice.jitter,
+			"server", strconv.FormatInt(c.server, 10),
+			"session", strconv.FormatUint(uint64(c.session), 10),
+			"user", c.name)
+	}
+
+	writeGeoMetrics(w, ids, geo)
+
+	cache := blobCacheStats()
+	fmt.Fprintln(w, "# HELP grumble_blob_cache_requests_total Blob reads by whether the blob cache held them.")
+	fmt.Fprintln(w, "# TYPE grumble_blob_cache_requests_total counter")
+	fmt.Fprintf(w, "grumble_blob_cache_requests_total{result=\"hit\"} %v\n", cache.Hits)
+	fmt.Fprintf(w, "grumble_blob_cache_requests_total{result=\"miss\"} %v\n", cache.Misses)
+	fmt.Fprintln(w, "# HELP grumble_blob_cache_bytes Size of the blobs held by the blob cache.")
+	fmt.Fprintln(w, "# TYPE grumble_blob_cache_bytes gauge")
+	fmt.Fprintf(w, "grumble_blob_cache_bytes %v\n", cache.Bytes)
+}
+
+// metricLabelEscaper escapes label values in the Prometheus text format.
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetric writes a sample of the named metric with the given label
+// names and values.
+func writeMetric(w io.Writer, name string, value interface{}, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", labels[i], metricLabelEscaper.Replace(labels[i+1])))
+	}
+	fmt.Fprintf(w, "%v{%v} %v\n", name, strings.Join(pairs, ","), value)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the streaming of blobs to clients.
+//
+// Textures, comments and channel descriptions are sent to clients that
+// ask for them, and inline to clients too old to ask. Rather than read
+// a blob into memory and encode it along with its message, the message
+// is encoded without it, and the blob is then copied from the blob
+// store to the connection as the message's last field. A client has
+// channelSyncTimeout to take each chunk of the blob.
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// Field numbers of the blobs in the Mumble protocol.
+const (
+	channelDescriptionField = 5
+	userTextureField        = 11
+	userCommentField        = 14
+)
+
+// A deadlineWriter extends the connection's write deadline before
+// each write.
+type deadlineWriter struct {
+	client *Client
+}
+
+func (w deadlineWriter) Write(p []byte) (int, error) {
+	w.client.conn.SetWriteDeadline(time.Now().Add(channelSyncTimeout))
+	n, err := w.client.conn.Write(p)
+	w.client.traffic.addOut(n)
+	return n, err
+}
+
+// sendBlobMessage sends msg to the client, with the blob identified by
+// key appended as its field. A blob that can't be read is left out,
+// and the message isn't sent.
+func (client *Client) sendBlobMessage(msg proto.Message, field int, key string) error {
+	rc, size, err := client.server.blobs.GetReader(key)
+	if err != nil {
+		// Nothing was sent yet, so the blob can be left out.
+		client.Printf("Unable to read blob %v: %v", key, err)
+		return nil
+	}
+	defer rc.Close()
+
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var hdr [2 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(hdr[:], uint64(field)<<3|2)
+	n += binary.PutUvarint(hdr[n:], uint64(size))
+	length := int64(len(data)) + int64(n) + size
+
+	frame := make([]byte, 6, 6+len(data)+n)
+	binary.BigEndian.PutUint16(frame, mumbleproto.MessageType(msg))
+	binary.BigEndian.PutUint32(frame[2:], uint32(length))
+	frame = append(frame, data...)
+	frame = append(frame, hdr[:n]...)
+
+	client.sendLock.Lock()
+	defer client.sendLock.Unlock()
+	defer client.conn.SetWriteDeadline(time.Time{})
+	w := deadlineWriter{client}
+	if _, err := w.Write(frame); err != nil {
+		return err
+	}
+	// The blob is only verified once it is read to the end, so a
+	// corrupt blob leaves the connection mid-message. The client must
+	// be disconnected then.
+	written, err := io.CopyN(w, rc, size)
+	if err == nil {
+		_, err = rc.Read(make([]byte, 1))
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err == nil && written != size {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// sendUserBlobs streams the texture and comment of target's user to
+// the client, for clients too old to ask for them.
+func (client *Client) sendUserBlobs(target *Client) error {
+	if target.user == nil {
+		return nil
+	}
+	session := proto.Uint32(target.Session())
+	if target.user.HasTexture() {
+		err := client.sendBlobMessage(&mumbleproto.UserState{Session: session}, userTextureField, target.user.TextureBlob)
+		if err != nil {
+			return err
+		}
+	}
+	if target.user.HasComment() {
+		err := client.sendBlobMessage(&mumbleproto.UserState{Session: session}, userCommentField, target.user.CommentBlob)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendChannelDescription streams the description of channel to the
+// client.
+func (client *Client) sendChannelDescription(channel *Channel) error {
+	if !channel.HasDescription() {
+		return nil
+	}
+	chanstate := &mumbleproto.ChannelState{ChannelId: proto.Uint32(uint32(channel.Id))}
+	return client.sendBlobMessage(chanstate, channelDescriptionField, channel.DescriptionBlob)
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the --verify-blobstore command.
+//
+// The blob store keeps user textures and comments and channel
+// descriptions under the SHA1 hash of their content. Verification
+// re-hashes every blob and reports those whose content no longer
+// matches their name. With --quarantine-blobs, corrupt blobs are moved
+// to the blob store's quarantine directory, so that clients are no
+// longer sent them. Either way, the users and channels of all servers
+// that refer to a corrupt or missing blob are logged.
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"mumble.info/grumble/pkg/blobstore"
+)
+
+// blobReferences maps the keys of the blobs referred to by the loaded
+// servers that use the blob store bs to descriptions of what refers to
+// them.
+func blobReferences(bs blobstore.BlobStore) map[string][]string {
+	refs := make(map[string][]string)
+	add := func(key string, format string, args ...interface{}) {
+		if len(key) > 0 {
+			refs[key] = append(refs[key], fmt.Sprintf(format, args...))
+		}
+	}
+	for _, server := range servers {
+		if server.blobs != bs {
+			continue
+		}
+		for _, user := range server.Users {
+			add(user.TextureBlob, "[%v] texture of user %v (%v)", server.Id, user.Id, user.Name)
+			add(user.CommentBlob, "[%v] comment of user %v (%v)", server.Id, user.Id, user.Name)
+		}
+		for _, channel := range server.Channels {
+			add(channel.DescriptionBlob, "[%v] description of channel %v (%v)", server.Id, channel.Id, channel.Name)
+		}
+	}
+	return refs
+}
+
+// verifyBlobStore verifies the blob stores, quarantining corrupt blobs
+// if quarantine is set. It returns the number of problems found.
+func verifyBlobStore(quarantine bool) (int, error) {
+	problems := 0
+	for _, dir := range sortedBlobDirs() {
+		n, err := verifyBlobDir(dir, blobStores[dir], quarantine)
+		if err != nil {
+			return 0, fmt.Errorf("%v: %v", dir, err)
+		}
+		problems += n
+	}
+	return problems, nil
+}
+
+// verifyBlobDir verifies the blob store bs in dir.
+func verifyBlobDir(dir string, bs blobstore.BlobStore, quarantine bool) (int, error) {
+	checked, bad, err := bs.Verify()
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Verified %v blobs in %v, %v corrupt", checked, dir, len(bad))
+
+	corrupt := make(map[string]bool)
+	for _, blob := range bad {
+		corrupt[blob.Key] = true
+		log.Printf("Blob %v is corrupt: its content hashes to %v", blob.Key, blob.Sum)
+		if quarantine {
+			if err := bs.Quarantine(blob.Key); err != nil {
+				return 0, err
+			}
+			log.Printf("Moved blob %v to quarantine", blob.Key)
+		}
+	}
+
+	refs := blobReferences(bs)
+	keys := []string{}
+	for key := range refs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	dangling := 0
+	for _, key := range keys {
+		reason := ""
+		if corrupt[key] {
+			reason = "corrupt"
+			if quarantine {
+				reason = "quarantined"
+			}
+		} else if rc, _, err := bs.GetReader(key); err == blobstore.ErrNoSuchKey || err == blobstore.ErrBadKey {
+			reason = "missing"
+		} else if err == nil {
+			rc.Close()
+		}
+		if len(reason) == 0 {
+			continue
+		}
+		for _, ref := range refs[key] {
+			dangling++
+			log.Printf("%v refers to %v blob %v", ref, reason, key)
+		}
+	}
+	return len(bad) + dangling, nil
+}
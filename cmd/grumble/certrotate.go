@@ -0,0 +1,211 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements certificate rotation for registered users.
+//
+// Registrations are bound to the hash of a certificate, so a user whose
+// certificate expires, or who moves to a new device, would otherwise
+// need an admin to bind the new one. Instead, when CertRotation is set
+// (the default), a user connecting under a registered name with another
+// certificate may give one of two secrets as the password:
+//
+//   - a one-time rotation code, obtained with /certificate rotate while
+//     connected with the old certificate, or
+//   - the account password, set with /certificate password.
+//
+// With a rotation code, the new certificate replaces the old one. With
+// the password, it is added to the user's certificates, so that rotating
+// on one device doesn't sign the user's other devices out.
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/password"
+)
+
+// How long a rotation code is valid.
+const certRotationTimeout = time.Hour
+
+// The shortest account password allowed.
+const minAccountPasswordLength = 8
+
+// A rotationCode lets a user bind a new certificate. Codes are
+// single-use and are not persisted across restarts of Grumble.
+type rotationCode struct {
+	Code string
+	// The certificate the code was created with, which the new
+	// certificate replaces.
+	CertHash string
+	Expires  time.Time
+}
+
+var (
+	errRotationNotAllowed = errors.New("invalid rotation code or password")
+	errRotationThrottled  = errors.New("too many failed password attempts")
+)
+
+// rotateCertificate binds the client's certificate to user, if secret
+// is a pending rotation code or the password of user. With a code, the
+// new certificate replaces the one the code was created with; with the
+// password, it is added to the user's certificates.
+//
+// Must not be called from the server's handler goroutine.
+func (server *Server) rotateCertificate(client *Client, user *User, secret string) error {
+	// Both rotation codes and passwords can be guessed.
+	if server.passwordThrottled(client) {
+		return errRotationThrottled
+	}
+
+	var stored, old string
+	var codeOK bool
+	err := server.runSync(func() {
+		rc, ok := server.rotations[user.Id]
+		if ok && time.Now().Before(rc.Expires) {
+			codeOK = subtle.ConstantTimeCompare([]byte(rc.Code), []byte(normalizeCode(secret))) == 1
+			old = rc.CertHash
+		}
+		stored = user.Password
+	})
+	if err != nil {
+		return err
+	}
+
+	// Verifying and hashing the password is slow, so keep it off the
+	// handler goroutine.
+	via := "rotation code"
+	var rehashed string
+	if !codeOK {
+		if len(stored) == 0 || !verifyPassword(secret, stored) {
+			server.passwordFailed(client)
+			return errRotationNotAllowed
+		}
+		via = "password"
+		if password.NeedsRehash(stored, server.passwordParams()) {
+			rehashed = server.hashConfigPassword(secret)
+		}
+	}
+
+	var bindErr error
+	err = server.runSync(func() {
+		hash := client.CertHash()
+		if other, exists := server.UserCertMap[hash]; exists && other != user {
+			bindErr = fmt.Errorf("certificate is registered to user %v", other.Id)
+			return
+		}
+		delete(server.rotations, user.Id)
+		if codeOK {
+			server.bindUserCertHash(user, old, hash, "rotation")
+			return
+		}
+		// The password may have changed while it was hashed.
+		if len(rehashed) > 0 && user.Password == stored {
+			user.Password = rehashed
+		}
+		if bindErr = server.addUserCertificate(user, hash, ""); bindErr != nil {
+			return
+		}
+		server.certificateBound(user, hash, "rotation")
+	})
+	if err != nil {
+		return err
+	}
+	if bindErr != nil {
+		return bindErr
+	}
+	client.Printf("Rotated certificate of %v using the %v", user.Name, via)
+	return nil
+}
+
+// expireRotationCodes drops expired rotation codes.
+func (server *Server) expireRotationCodes() {
+	now := time.Now()
+	for id, rc := range server.rotations {
+		if now.After(rc.Expires) {
+			delete(server.rotations, id)
+		}
+	}
+}
+
+func init() {
+	registerChatCommand("certificate", &chatCommand{
+		usage: []string{
+			"certificate rotate",
+			"certificate password [password]",
+		},
+		help:       "Move your registration to a new certificate",
+		registered: true,
+		run:        (*Server).certificateCommand,
+	})
+}
+
+// certificateCommand implements the /certificate chat command:
+//
+//	/certificate rotate
+//	/certificate password [password]
+//
+// The first creates a rotation code for the sender's registration, the
+// second sets the password of the registration, or removes it if no
+// password is given.
+func (server *Server) certificateCommand(client *Client, args []string, text string) {
+	if !server.cfg.BoolValue("CertRotation") {
+		server.sendServerText(client, "Certificate rotation is disabled on this server")
+		return
+	}
+	user := client.user
+	if client.IsSuperUser() {
+		server.sendServerText(client, "Only registered users can rotate their certificate")
+		return
+	}
+
+	switch {
+	case len(args) == 2 && args[1] == "rotate":
+		code, err := randomCode()
+		if err != nil {
+			server.Printf("Unable to create rotation code: %v", err)
+			return
+		}
+		server.rotations[user.Id] = rotationCode{
+			Code:     code,
+			CertHash: client.CertHash(),
+			Expires:  time.Now().Add(certRotationTimeout),
+		}
+		server.Printf("Created rotation code for user %v (%v)", user.Id, user.Name)
+		server.sendServerText(client, fmt.Sprintf("Your rotation code is <b>%v</b>. Within an hour, connect with your new certificate and give the code as the password.", code))
+	case len(args) >= 2 && args[1] == "password":
+		pw := chatCommandRest(text, 2)
+		if len(pw) == 0 {
+			user.Password = ""
+			server.UpdateFrozenUserRecord(user)
+			server.audit(client, auditlog.Entry{Action: "user.password.remove", Target: user.Name})
+			server.sendServerText(client, "Password removed")
+			return
+		}
+		if len(pw) < minAccountPasswordLength {
+			server.sendServerText(client, fmt.Sprintf("The password must be at least %v characters long", minAccountPasswordLength))
+			return
+		}
+		// Hashing the password is slow, so keep it off the handler
+		// goroutine.
+		go func() {
+			hash := server.hashConfigPassword(pw)
+			server.runSync(func() {
+				if server.Users[user.Id] != user {
+					return
+				}
+				user.Password = hash
+				server.UpdateFrozenUserRecord(user)
+				server.audit(client, auditlog.Entry{Action: "user.password", Target: user.Name})
+				server.sendServerText(client, "Password set. To bind a new certificate, connect with it and give this password.")
+			})
+		}()
+	default:
+		server.sendChatCommandUsage(client, args[0])
+	}
+}
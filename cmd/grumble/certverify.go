@@ -0,0 +1,72 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// peerCertificates returns the certificate chain the client sent, if
+// any.
+func (client *Client) peerCertificates() []*x509.Certificate {
+	tlsconn, ok := client.conn.(*tls.Conn)
+	if !ok {
+		return client.handedOverCerts
+	}
+	return tlsconn.ConnectionState().PeerCertificates
+}
+
+// connectedOverTLS checks whether the client connected over TLS, rather
+// than a WebSocket. Only sessions of clients connected over TLS are
+// handed over.
+func (client *Client) connectedOverTLS() bool {
+	_, ok := client.conn.(*tls.Conn)
+	return ok || client.handedOver
+}
+
+// certPool returns the CAs in the PEM file named by the config key, or
+// the system's CAs if the key is unset. Relative paths are relative to
+// the data directory.
+func (server *Server) certPool(key string) (*x509.CertPool, error) {
+	fn := server.cfg.StringValue(key)
+	if len(fn) == 0 {
+		return x509.SystemCertPool()
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, fmt.Errorf("no certificates found in %v", fn)
+	}
+	return pool, nil
+}
+
+// verifyClientCertificate reports whether the first of certs is a
+// client certificate issued by one of roots. The other certificates
+// may be used as intermediates.
+func verifyClientCertificate(certs []*x509.Certificate, roots *x509.CertPool) bool {
+	if len(certs) == 0 {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
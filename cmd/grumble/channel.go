@@ -29,6 +29,23 @@ type Channel struct {
 
 	// Blobs
 	DescriptionBlob string
+
+	// Flags. NoVoice channels are text-only: no voice is sent from or
+	// to their users. Silent channels ask clients not to play sounds
+	// when users join or leave them.
+	NoVoice bool
+	Silent  bool
+
+	// The user id of the registered user who created the channel
+	// through ChannelCreateGroup, or -1.
+	OwnerId int
+
+	// The most users allowed in the channel, or 0 for MaxChannelUsers.
+	// Only set for temporary channels created from templates.
+	MaxUsers int
+
+	// The last text messages sent to the channel, for reports
+	recentText []chatLine
 }
 
 func NewChannel(id int, name string) (channel *Channel) {
@@ -39,6 +56,7 @@ func NewChannel(id int, name string) (channel *Channel) {
 	channel.children = make(map[int]*Channel)
 	channel.ACL.Groups = make(map[string]acl.Group)
 	channel.Links = make(map[int]*Channel)
+	channel.OwnerId = -1
 	return
 }
 
@@ -0,0 +1,122 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the channel flags.
+//
+// A no-voice channel is text-only: the server drops voice sent by its
+// users, and doesn't send them voice from elsewhere, including
+// whispers. A silent channel asks clients not to play the sounds they
+// play when users join or leave it. Join sounds are played by the
+// clients, so the silent flag only takes effect in clients that know
+// it.
+//
+// The flags are sent in the Grumble-only no_voice and silent fields of
+// ChannelState. They can be set by users with WritePermission in the
+// channel, or through /servers/<id>/channelflags in the admin API.
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// apiChannelFlags is the JSON representation of a channel's flags.
+type apiChannelFlags struct {
+	Channel int   `json:"channel"`
+	NoVoice *bool `json:"no_voice,omitempty"`
+	Silent  *bool `json:"silent,omitempty"`
+}
+
+// SetChannelFlags sets the flags of channel, and tells the clients.
+// A nil flag is left as it is.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) SetChannelFlags(channel *Channel, noVoice, silent *bool) {
+	chanstate := &mumbleproto.ChannelState{
+		ChannelId: proto.Uint32(uint32(channel.Id)),
+	}
+	if noVoice != nil && *noVoice != channel.NoVoice {
+		channel.NoVoice = *noVoice
+		chanstate.NoVoice = noVoice
+	}
+	if silent != nil && *silent != channel.Silent {
+		channel.Silent = *silent
+		chanstate.Silent = silent
+	}
+	if chanstate.NoVoice == nil && chanstate.Silent == nil {
+		return
+	}
+
+	server.broadcastProtoMessage(chanstate)
+	server.channelTreeChanged()
+	if !channel.IsTemporary() {
+		server.UpdateFrozenChannel(channel, chanstate)
+	}
+}
+
+func init() {
+	registerAPIEndpoint("channelflags", handleAPIChannelFlags)
+}
+
+// handleAPIChannelFlags implements /servers/<id>/channelflags/<channel>.
+//
+//	GET  shows the channel's flags
+//	PUT  sets them: {"no_voice": true, "silent": false}
+func handleAPIChannelFlags(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 1 {
+		apiError(w, http.StatusNotFound, "expected /channelflags/<channel>")
+		return
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid channel")
+		return
+	}
+
+	var req apiChannelFlags
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if !readJSON(w, r, &req) {
+			return
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		channel, ok := server.Channels[id]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such channel"}
+			return
+		}
+		if r.Method != http.MethodGet {
+			server.SetChannelFlags(channel, req.NoVoice, req.Silent)
+			server.auditAPI(auditlog.Entry{
+				Action:  "channel.flags",
+				Target:  channel.Name,
+				Details: fmt.Sprintf("channel %v: no-voice %v, silent %v", channel.Id, channel.NoVoice, channel.Silent),
+			})
+		}
+		reply = apiChannelFlags{
+			Channel: channel.Id,
+			NoVoice: proto.Bool(channel.NoVoice),
+			Silent:  proto.Bool(channel.Silent),
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the channel and user lists sent to clients
+// when they connect.
+//
+// The lists are sent in batches of ChannelSyncBatch messages, each
+// written to the connection at once. A client has channelSyncTimeout
+// to take each batch, and is disconnected if it doesn't, so that a
+// slow client can't hold up the server. Channel links are sent once
+// all channels are known to the client. Clients too old to ask for
+// channel descriptions are streamed them once the list is sent.
+//
+// Clients can leave parts of the channel tree out of the lists by
+// adding access tokens of the form nosync:<channel id>. The channel,
+// its subchannels and the users in them are then not sent.
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/features"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The time a client has to take a batch of the channel or user list.
+const channelSyncTimeout = 10 * time.Second
+
+// The prefix of access tokens that leave channels out of the lists.
+const syncSuppressPrefix = "nosync:"
+
+// A messageBatch collects messages, and splits them into batches of
+// ChannelSyncBatch messages.
+type messageBatch struct {
+	size    int
+	buf     bytes.Buffer
+	n       int
+	batches [][]byte
+}
+
+// newMessageBatch creates a messageBatch for the server's batch size.
+func (server *Server) newMessageBatch() *messageBatch {
+	size := server.cfg.IntValue("ChannelSyncBatch")
+	if size < 1 {
+		size = 1
+	}
+	return &messageBatch{size: size}
+}
+
+// add adds msg to the current batch, and starts a new one if it is full.
+func (b *messageBatch) add(msg interface{}) error {
+	if err := encodeMessage(&b.buf, msg); err != nil {
+		return err
+	}
+	b.n++
+	if b.n >= b.size {
+		b.finish()
+	}
+	return nil
+}
+
+// finish ends the current batch, and returns all batches.
+func (b *messageBatch) finish() [][]byte {
+	if b.n > 0 {
+		b.batches = append(b.batches, append([]byte(nil), b.buf.Bytes()...))
+		b.buf.Reset()
+		b.n = 0
+	}
+	return b.batches
+}
+
+// sendBatches writes batches of encoded messages to the client. The
+// client has channelSyncTimeout to take each batch.
+func (client *Client) sendBatches(batches [][]byte) error {
+	client.sendLock.Lock()
+	defer client.sendLock.Unlock()
+	conn := client.conn
+	defer conn.SetWriteDeadline(time.Time{})
+	for _, batch := range batches {
+		conn.SetWriteDeadline(time.Now().Add(channelSyncTimeout))
+		n, err := conn.Write(batch)
+		client.traffic.addOut(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A channelTreeCache holds the encoded channel list, so that it isn't
+// encoded again for each client that connects. Clients that support
+// description hashes get a different list than older clients.
+//
+// Must only be used from the server's handler goroutine.
+type channelTreeCache struct {
+	size    int
+	batches map[bool][][]byte
+}
+
+// channelTreeChanged drops the cached channel list. It must be called
+// whenever a channel is added, removed or changed.
+func (server *Server) channelTreeChanged() {
+	server.channelTree.batches = nil
+}
+
+// syncSuppressed returns the ids of the channels the client asked to
+// leave out of the lists.
+func (client *Client) syncSuppressed() map[int]bool {
+	suppressed := make(map[int]bool)
+	for _, token := range client.tokens {
+		if len(token) <= len(syncSuppressPrefix) || !strings.EqualFold(token[:len(syncSuppressPrefix)], syncSuppressPrefix) {
+			continue
+		}
+		if id, err := strconv.Atoi(token[len(syncSuppressPrefix):]); err == nil && id != 0 {
+			suppressed[id] = true
+		}
+	}
+	return suppressed
+}
+
+// channelSynced checks whether channel is sent to a client that left
+// out the channels in suppressed.
+func channelSynced(channel *Channel, suppressed map[int]bool) bool {
+	for iter := channel; iter != nil; iter = iter.parent {
+		if suppressed[iter.Id] {
+			return false
+		}
+	}
+	return true
+}
+
+// channelState describes channel to the client, without its links or,
+// for clients that don't support description hashes, its description.
+func (client *Client) channelState(channel *Channel) *mumbleproto.ChannelState {
+	chanstate := &mumbleproto.ChannelState{
+		ChannelId: proto.Uint32(uint32(channel.Id)),
+		Name:      proto.String(channel.Name),
+	}
+	if channel.parent != nil {
+		chanstate.Parent = proto.Uint32(uint32(channel.parent.Id))
+	}
+
+	// Older clients can't ask for descriptions, and are sent them by
+	// sendChannelDescriptions instead.
+	if channel.HasDescription() && client.supports(features.DescBlobHash) {
+		chanstate.DescriptionHash = channel.DescriptionBlobHashBytes()
+	}
+
+	if channel.IsTemporary() {
+		chanstate.Temporary = proto.Bool(true)
+	}
+
+	chanstate.Position = proto.Int32(int32(channel.Position))
+
+	if channel.NoVoice {
+		chanstate.NoVoice = proto.Bool(true)
+	}
+	if channel.Silent {
+		chanstate.Silent = proto.Bool(true)
+	}
+	if channel.MaxUsers > 0 {
+		chanstate.MaxUsers = proto.Uint32(uint32(channel.MaxUsers))
+	}
+	return chanstate
+}
+
+// encodeChannelList encodes the channel tree for the client, parents
+// before their children, followed by the channel links.
+func (client *Client) encodeChannelList(suppressed map[int]bool) ([][]byte, error) {
+	batch := client.server.newMessageBatch()
+
+	var linked []*Channel
+	stack := []*Channel{client.server.RootChannel()}
+	for len(stack) > 0 {
+		channel := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if channel.parent != nil && suppressed[channel.Id] {
+			continue
+		}
+
+		if err := batch.add(client.channelState(channel)); err != nil {
+			return nil, err
+		}
+		if len(channel.Links) > 0 {
+			linked = append(linked, channel)
+		}
+		for _, subchannel := range channel.children {
+			stack = append(stack, subchannel)
+		}
+	}
+
+	for _, channel := range linked {
+		links := []uint32{}
+		for cid, link := range channel.Links {
+			if channelSynced(link, suppressed) {
+				links = append(links, uint32(cid))
+			}
+		}
+		if len(links) == 0 {
+			continue
+		}
+		err := batch.add(&mumbleproto.ChannelState{
+			ChannelId: proto.Uint32(uint32(channel.Id)),
+			Links:     links,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return batch.finish(), nil
+}
+
+// sendChannelList sends the channel tree to the client. Unless the
+// client leaves out parts of the tree, the cached list is sent.
+func (client *Client) sendChannelList() {
+	server := client.server
+	suppressed := client.syncSuppressed()
+	hashes := client.supports(features.DescBlobHash)
+
+	var batches [][]byte
+	cache := &server.channelTree
+	if len(suppressed) == 0 && cache.size == server.cfg.IntValue("ChannelSyncBatch") {
+		batches = cache.batches[hashes]
+	}
+	if batches == nil {
+		var err error
+		batches, err = client.encodeChannelList(suppressed)
+		if err != nil {
+			client.Panicf("%v", err)
+			return
+		}
+		if len(suppressed) == 0 {
+			if cache.batches == nil || cache.size != server.cfg.IntValue("ChannelSyncBatch") {
+				cache.size = server.cfg.IntValue("ChannelSyncBatch")
+				cache.batches = make(map[bool][][]byte)
+			}
+			cache.batches[hashes] = batches
+		}
+	}
+
+	if err := client.sendBatches(batches); err != nil {
+		client.Panicf("%v", err)
+		return
+	}
+	if !hashes {
+		client.sendChannelDescriptions(suppressed)
+	}
+}
+
+// sendChannelDescriptions streams the descriptions of the channels sent
+// to the client, for clients too old to ask for them.
+func (client *Client) sendChannelDescriptions(suppressed map[int]bool) {
+	for _, channel := range client.server.Channels {
+		if !channelSynced(channel, suppressed) {
+			continue
+		}
+		if err := client.sendChannelDescription(channel); err != nil {
+			client.Panicf("%v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements moving and copying channel subtrees through the
+// admin API.
+//
+// A move puts a channel, with its subchannels, users and links, under
+// a new parent. A copy creates a new channel under the new parent for
+// the channel and each of its permanent subchannels, with the same
+// name, description, position, flags, ACLs and groups. Users, links
+// and temporary subchannels are not copied. A channel can't be moved
+// or copied into its own subtree.
+//
+// With dry_run set, the changes are checked and reported but not made.
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/features"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// apiTreeChange is the JSON representation of a channel moved or
+// copied.
+type apiTreeChange struct {
+	Action  string `json:"action"`
+	Channel int    `json:"channel"`
+	Path    string `json:"path"`
+	Copy    int    `json:"copy,omitempty"`
+	ACLs    int    `json:"acls"`
+	Groups  int    `json:"groups"`
+}
+
+// path returns the names of the channel and its parents, from the
+// root down, separated by slashes.
+func (channel *Channel) path() string {
+	names := []string{}
+	for iter := channel; iter != nil; iter = iter.parent {
+		names = append([]string{iter.Name}, names...)
+	}
+	return strings.Join(names, "/")
+}
+
+// sortedChildren returns the subchannels of channel by id.
+func (channel *Channel) sortedChildren() []*Channel {
+	children := make([]*Channel, 0, len(channel.children))
+	for _, child := range channel.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Id < children[j].Id })
+	return children
+}
+
+// checkTreeTarget checks whether channel, or a copy of it, can be put
+// under parent with the given name.
+func (server *Server) checkTreeTarget(channel, parent *Channel, name string, move bool) error {
+	for iter := parent; iter != nil; iter = iter.parent {
+		if iter == channel {
+			return errors.New("the new parent is in the channel's subtree")
+		}
+	}
+	if parent.IsTemporary() {
+		return errors.New("the new parent is a temporary channel")
+	}
+	if reason := server.checkChannelName(name); len(reason) > 0 {
+		return errors.New(reason)
+	}
+	for _, sibling := range parent.children {
+		if sibling.Name == name && !(move && sibling == channel) {
+			return fmt.Errorf("the new parent already has a channel named %v", name)
+		}
+	}
+	return nil
+}
+
+// moveChannelTree puts channel under parent, renaming it to name.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) moveChannelTree(channel, parent *Channel, name string) {
+	chanstate := &mumbleproto.ChannelState{
+		ChannelId: proto.Uint32(uint32(channel.Id)),
+		Parent:    proto.Uint32(uint32(parent.Id)),
+	}
+	if name != channel.Name {
+		channel.Name = name
+		chanstate.Name = proto.String(name)
+	}
+	channel.parent.RemoveChild(channel)
+	parent.AddChild(channel)
+	server.ClearCaches()
+	server.channelTreeChanged()
+
+	server.broadcastProtoMessage(chanstate)
+	server.pushPermissions()
+	if !channel.IsTemporary() {
+		server.UpdateFrozenChannel(channel, chanstate)
+	}
+}
+
+// copyChannelTree copies src and its permanent subchannels under
+// parent, naming the copy of src name. parentPath is the path of
+// parent. If dryRun is set, nothing is copied, and parent may be nil.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) copyChannelTree(src, parent *Channel, parentPath, name string, dryRun bool) []apiTreeChange {
+	change := apiTreeChange{
+		Action:  "copy",
+		Channel: src.Id,
+		Path:    parentPath + "/" + name,
+		ACLs:    len(src.ACL.ACLs),
+		Groups:  len(src.ACL.Groups),
+	}
+	var dst *Channel
+	if !dryRun {
+		dst = server.AddChannel(name)
+		dst.Position = src.Position
+		dst.DescriptionBlob = src.DescriptionBlob
+		dst.NoVoice = src.NoVoice
+		dst.Silent = src.Silent
+		dst.ACL.InheritACL = src.ACL.InheritACL
+		dst.ACL.ACLs = append([]acl.ACL(nil), src.ACL.ACLs...)
+		for groupName, group := range src.ACL.Groups {
+			grp := acl.EmptyGroupWithName(groupName)
+			grp.Inherit = group.Inherit
+			grp.Inheritable = group.Inheritable
+			for id, v := range group.Add {
+				grp.Add[id] = v
+			}
+			for id, v := range group.Remove {
+				grp.Remove[id] = v
+			}
+			dst.ACL.Groups[groupName] = grp
+		}
+		parent.AddChild(dst)
+		change.Copy = dst.Id
+
+		server.UpdateFrozenChannelRecord(dst)
+		for _, client := range server.clients {
+			if client.state == StateClientReady {
+				client.sendMessage(client.channelState(dst))
+				if !client.supports(features.DescBlobHash) {
+					client.sendChannelDescription(dst)
+				}
+			}
+		}
+	}
+
+	changes := []apiTreeChange{change}
+	for _, child := range src.sortedChildren() {
+		if child.IsTemporary() {
+			continue
+		}
+		changes = append(changes, server.copyChannelTree(child, dst, change.Path, child.Name, dryRun)...)
+	}
+	return changes
+}
+
+func init() {
+	registerAPIEndpoint("channeltree", handleAPIChannelTree)
+}
+
+// handleAPIChannelTree implements /servers/<id>/channeltree/<channel>.
+//
+//	POST  moves or copies the channel and its subchannels:
+//	      {"op": "move", "parent": 3, "name": "New name", "dry_run": true}
+func handleAPIChannelTree(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 1 {
+		apiError(w, http.StatusNotFound, "expected /channeltree/<channel>")
+		return
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid channel")
+		return
+	}
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req struct {
+		Op     string `json:"op"`
+		Parent int    `json:"parent"`
+		Name   string `json:"name"`
+		DryRun bool   `json:"dry_run"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Op != "move" && req.Op != "copy" {
+		apiError(w, http.StatusBadRequest, "op must be move or copy")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		channel, ok := server.Channels[id]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such channel"}
+			return
+		}
+		parent, ok := server.Channels[req.Parent]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such parent channel"}
+			return
+		}
+		if channel.parent == nil && req.Op == "move" {
+			status, reply = http.StatusBadRequest, map[string]string{"error": "the root channel can't be moved"}
+			return
+		}
+		name := req.Name
+		if len(name) == 0 {
+			name = channel.Name
+		}
+		if err := server.checkTreeTarget(channel, parent, name, req.Op == "move"); err != nil {
+			status, reply = http.StatusConflict, map[string]string{"error": err.Error()}
+			return
+		}
+
+		var changes []apiTreeChange
+		if req.Op == "move" {
+			if !req.DryRun {
+				server.moveChannelTree(channel, parent, name)
+			}
+			// Every channel in the subtree gets a new path.
+			prefix := parent.path() + "/" + name
+			var walk func(c *Channel, path string)
+			walk = func(c *Channel, path string) {
+				changes = append(changes, apiTreeChange{
+					Action:  "move",
+					Channel: c.Id,
+					Path:    path,
+					ACLs:    len(c.ACL.ACLs),
+					Groups:  len(c.ACL.Groups),
+				})
+				for _, child := range c.sortedChildren() {
+					walk(child, path+"/"+child.Name)
+				}
+			}
+			walk(channel, prefix)
+		} else {
+			changes = server.copyChannelTree(channel, parent, parent.path(), name, req.DryRun)
+		}
+
+		if !req.DryRun {
+			server.auditAPI(auditlog.Entry{
+				Action:  "channel." + req.Op,
+				Target:  channel.Name,
+				Details: fmt.Sprintf("channel %v to %v, %v channels", channel.Id, parent.path(), len(changes)),
+			})
+		}
+		reply = map[string]interface{}{"dry_run": req.DryRun, "changes": changes}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements templates for temporary channels.
+//
+// ChannelTemplates names a templates file (see pkg/chantemplate).
+// When a user creates a temporary channel whose name starts with the
+// name of a template and a colon, such as "squad:Alpha", the template
+// is left out of the name, and the channel gets the template's
+// description, position, user limit and ACLs. Clients are also offered
+// a context action on channels for each template, which creates a
+// temporary channel from the template in the channel.
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/chantemplate"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The prefix of the context actions that create channels from
+// templates.
+const templateActionPrefix = "template:"
+
+// loadChannelTemplates reads the templates file named by the
+// ChannelTemplates key. Relative paths are relative to the data
+// directory. If no file is configured, it returns nil templates.
+func (server *Server) loadChannelTemplates() (*chantemplate.Templates, error) {
+	fn := server.cfg.StringValue("ChannelTemplates")
+	if len(fn) == 0 {
+		return nil, nil
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	return chantemplate.Load(fn)
+}
+
+// setChannelTemplates replaces the server's channel templates, and
+// updates the context actions of the connected clients.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) setChannelTemplates(templates *chantemplate.Templates) {
+	old := server.chanTemplates
+	server.chanTemplates = templates
+	for _, client := range server.clients {
+		if client.state != StateClientReady {
+			continue
+		}
+		for _, name := range old.Names() {
+			client.sendMessage(&mumbleproto.ContextActionModify{
+				Action:    proto.String(templateActionPrefix + name),
+				Operation: mumbleproto.ContextActionModify_Remove.Enum(),
+			})
+		}
+		server.sendChannelTemplateActions(client)
+	}
+}
+
+// sendChannelTemplateActions offers client the context actions that
+// create channels from templates.
+func (server *Server) sendChannelTemplateActions(client *Client) {
+	for _, name := range server.chanTemplates.Names() {
+		client.sendMessage(&mumbleproto.ContextActionModify{
+			Action:    proto.String(templateActionPrefix + name),
+			Text:      proto.String(fmt.Sprintf("New %v channel", name)),
+			Context:   proto.Uint32(uint32(mumbleproto.ContextActionModify_Channel)),
+			Operation: mumbleproto.ContextActionModify_Add.Enum(),
+		})
+	}
+}
+
+// channelTemplate splits the template off the name of a new temporary
+// channel. If name doesn't start with the name of a template, it
+// returns nil and name as it is.
+func (server *Server) channelTemplate(name string) (*chantemplate.Template, string) {
+	i := strings.Index(name, ":")
+	if i <= 0 {
+		return nil, name
+	}
+	tmpl, ok := server.chanTemplates.Lookup(name[:i])
+	if !ok {
+		return nil, name
+	}
+	return tmpl, strings.TrimSpace(name[i+1:])
+}
+
+// handleContextAction handles the context actions clients pick.
+func (server *Server) handleContextAction(client *Client, msg *Message) {
+	action := &mumbleproto.ContextAction{}
+	err := proto.Unmarshal(msg.buf, action)
+	if err != nil {
+		client.Panic(err)
+		return
+	}
+
+	if action.GetAction() == reportAction {
+		server.handleReportAction(client, action)
+		return
+	}
+	if strings.HasPrefix(action.GetAction(), whisperPresetActionPrefix) {
+		server.handleWhisperPresetAction(client, action)
+		return
+	}
+	if !strings.HasPrefix(action.GetAction(), templateActionPrefix) || action.ChannelId == nil {
+		return
+	}
+	tmpl, ok := server.chanTemplates.Lookup(strings.TrimPrefix(action.GetAction(), templateActionPrefix))
+	if !ok {
+		return
+	}
+	parent, ok := server.Channels[int(action.GetChannelId())]
+	if !ok {
+		return
+	}
+
+	// Pick a name not used by the parent's other subchannels, and
+	// create the channel the way the client would have.
+	name := ""
+	for n := 1; ; n++ {
+		name = fmt.Sprintf("%v %v", tmpl.Name, n)
+		taken := false
+		for _, child := range parent.children {
+			if child.Name == name {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			break
+		}
+	}
+	buf, err := proto.Marshal(&mumbleproto.ChannelState{
+		Parent:    proto.Uint32(uint32(parent.Id)),
+		Name:      proto.String(tmpl.Name + ":" + name),
+		Temporary: proto.Bool(true),
+		Position:  proto.Int32(0),
+	})
+	if err != nil {
+		client.Panic(err)
+		return
+	}
+	server.handleChannelStateMessage(client, &Message{buf: buf, kind: mumbleproto.MessageChannelState, client: client})
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements chat commands.
+//
+// A text message starting with "/" or "!" followed by the name of a
+// command is handled by the server instead of being sent on, so that
+// common tasks don't need an external bot. Each command is registered
+// with registerChatCommand, and may require the sender to be
+// registered or to have a permission in the channel it is in. /help
+// lists the commands the sender may use. Messages naming no command,
+// or a command listed in ChatCommandsDisabled, are sent on as usual, so
+// that scripts and plugins can handle them.
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/features"
+	"mumble.info/grumble/pkg/htmlfilter"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The characters a chat command may start with.
+const chatCommandPrefixes = "/!"
+
+// chatCommandFunc runs a chat command. args holds the words of the
+// message, the first being the command name without its prefix, and
+// text the whole message as plain text.
+//
+// It is called from the server's handler goroutine.
+type chatCommandFunc func(server *Server, client *Client, args []string, text string)
+
+// A chatCommand is a command users give the server in a text message.
+type chatCommand struct {
+	// The forms of the command without the prefix, such as
+	// "watch add <user>", shown by /help and when it's used wrongly.
+	usage []string
+	// A short description for /help.
+	help string
+	// Whether only registered users may use the command.
+	registered bool
+	// The permission needed in the sender's channel, if any.
+	perm acl.Permission
+	run  chatCommandFunc
+}
+
+// chatCommands maps command names to commands.
+var chatCommands = map[string]*chatCommand{}
+
+// registerChatCommand registers cmd as the chat command name.
+func registerChatCommand(name string, cmd *chatCommand) {
+	if _, exists := chatCommands[name]; exists {
+		panic("chat command registered twice: " + name)
+	}
+	chatCommands[name] = cmd
+}
+
+// chatCommand looks up the named command, unless it is disabled.
+func (server *Server) chatCommand(name string) (*chatCommand, bool) {
+	cmd, ok := chatCommands[name]
+	if !ok {
+		return nil, false
+	}
+	for _, disabled := range splitList(server.cfg.StringValue("ChatCommandsDisabled")) {
+		if strings.EqualFold(strings.TrimLeft(disabled, chatCommandPrefixes), name) {
+			return nil, false
+		}
+	}
+	return cmd, true
+}
+
+// handleChatCommand runs the chat command in msg, a text message as
+// FilterText returned it, if there is one. It returns false if msg isn't
+// a chat command.
+func (server *Server) handleChatCommand(client *Client, msg string) bool {
+	// Without markup, FilterText leaves the client's escaping alone.
+	// Markup that can't be stripped was already stripped by FilterText,
+	// and is plain text, such as "a < b".
+	text := html.UnescapeString(msg)
+	if strings.Index(msg, "<") != -1 {
+		text = msg
+		if stripped, err := htmlfilter.Filter(msg, &htmlfilter.Options{StripHTML: true}); err == nil {
+			text = stripped
+		}
+	}
+	text = strings.TrimSpace(text)
+	if len(text) < 2 || strings.IndexByte(chatCommandPrefixes, text[0]) == -1 {
+		return false
+	}
+	args := strings.Fields(text[1:])
+	if len(args) == 0 || !strings.HasPrefix(text[1:], args[0]) {
+		return false
+	}
+	name := strings.ToLower(args[0])
+	cmd, ok := server.chatCommand(name)
+	if !ok {
+		return false
+	}
+	args[0] = name
+
+	if cmd.registered && !client.IsRegistered() {
+		server.sendServerText(client, fmt.Sprintf("Only registered users can use /%v", name))
+		return true
+	}
+	if cmd.perm != acl.NonePermission {
+		channel := client.Channel
+		if channel == nil {
+			return true
+		}
+		if !acl.HasPermission(&channel.ACL, client, cmd.perm) {
+			client.sendPermissionDenied(client, channel, cmd.perm)
+			return true
+		}
+	}
+	cmd.run(server, client, args, text)
+	return true
+}
+
+// canUseChatCommand checks whether client may use cmd.
+func (server *Server) canUseChatCommand(client *Client, cmd *chatCommand) bool {
+	if cmd.registered && !client.IsRegistered() {
+		return false
+	}
+	if cmd.perm != acl.NonePermission {
+		return client.Channel != nil && acl.HasPermission(&client.Channel.ACL, client, cmd.perm)
+	}
+	return true
+}
+
+// chatCommandUsage formats the usage of the named command as HTML.
+func chatCommandUsage(name, sep string) string {
+	var forms []string
+	for _, form := range chatCommands[name].usage {
+		forms = append(forms, html.EscapeString("/"+form))
+	}
+	return strings.Join(forms, sep)
+}
+
+// sendChatCommandUsage tells client how the named command is used.
+func (server *Server) sendChatCommandUsage(client *Client, name string) {
+	server.sendServerText(client, "Usage: "+chatCommandUsage(name, ", "))
+}
+
+// chatCommandRest returns what follows the first n words of text.
+func chatCommandRest(text string, n int) string {
+	text = strings.TrimSpace(text)
+	for i := 0; i < n; i++ {
+		end := strings.IndexAny(text, " \t\n")
+		if end == -1 {
+			return ""
+		}
+		text = strings.TrimSpace(text[end:])
+	}
+	return text
+}
+
+func init() {
+	registerChatCommand("help", &chatCommand{
+		usage: []string{"help [command]"},
+		help:  "List the commands you can use",
+		run:   (*Server).helpCommand,
+	})
+	registerChatCommand("moveme", &chatCommand{
+		usage: []string{"moveme <channel>"},
+		help:  "Move yourself to a channel, given by name or id",
+		run:   (*Server).moveMeCommand,
+	})
+}
+
+// helpCommand implements /help, which lists the commands the sender
+// may use, or describes one of them.
+func (server *Server) helpCommand(client *Client, args []string, text string) {
+	if len(args) > 2 {
+		server.sendChatCommandUsage(client, args[0])
+		return
+	}
+	if len(args) == 2 {
+		name := strings.ToLower(strings.TrimLeft(args[1], chatCommandPrefixes))
+		cmd, ok := server.chatCommand(name)
+		if !ok || !server.canUseChatCommand(client, cmd) {
+			server.sendServerText(client, fmt.Sprintf("No command named %v", html.EscapeString(args[1])))
+			return
+		}
+		server.sendServerText(client, html.EscapeString(cmd.help)+"<br />"+chatCommandUsage(name, "<br />"))
+		return
+	}
+
+	var names []string
+	for name := range chatCommands {
+		if cmd, ok := server.chatCommand(name); ok && server.canUseChatCommand(client, cmd) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	lines := []string{"Commands start with / or !:"}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("<b>/%v</b>: %v", name, html.EscapeString(chatCommands[name].help)))
+	}
+	lines = append(lines, "Use /help &lt;command&gt; for how to use a command.")
+	server.sendServerText(client, strings.Join(lines, "<br />"))
+}
+
+// findChannel looks up a channel by id, or else by name, ignoring
+// case. It returns an error to show the user if there isn't exactly
+// one such channel.
+func (server *Server) findChannel(name string) (*Channel, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		if channel, ok := server.Channels[id]; ok {
+			return channel, nil
+		}
+	}
+	var found *Channel
+	for _, channel := range server.Channels {
+		if !strings.EqualFold(channel.Name, name) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("Several channels are named %v; give the channel id instead", html.EscapeString(name))
+		}
+		found = channel
+	}
+	if found == nil {
+		return nil, fmt.Errorf("No channel named %v", html.EscapeString(name))
+	}
+	return found, nil
+}
+
+// moveMeCommand implements /moveme, which moves the sender to another
+// channel, with the same checks as when it moves itself.
+func (server *Server) moveMeCommand(client *Client, args []string, text string) {
+	name := chatCommandRest(text, 1)
+	if len(name) == 0 {
+		server.sendChatCommandUsage(client, args[0])
+		return
+	}
+	channel, err := server.findChannel(name)
+	if err != nil {
+		server.sendServerText(client, err.Error())
+		return
+	}
+	if client.queued {
+		client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_Text, "You are waiting in the queue")
+		return
+	}
+	if client.held {
+		client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_Text, "The server is under maintenance")
+		return
+	}
+	if !acl.HasPermission(&channel.ACL, client, acl.EnterPermission) {
+		client.sendPermissionDenied(client, channel, acl.EnterPermission)
+		return
+	}
+	if server.channelFull(channel) {
+		client.sendPermissionDeniedFallback(mumbleproto.PermissionDenied_ChannelFull,
+			features.ChannelFullDenial, "Channel is full")
+		return
+	}
+
+	userstate := &mumbleproto.UserState{
+		Session:   proto.Uint32(client.Session()),
+		Actor:     proto.Uint32(client.Session()),
+		ChannelId: proto.Uint32(uint32(channel.Id)),
+	}
+	server.userEnterChannel(client, channel, userstate)
+	if err := server.broadcastProtoMessage(userstate); err != nil {
+		server.Panic("Unable to broadcast UserState")
+	}
+}
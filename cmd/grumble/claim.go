@@ -0,0 +1,204 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements registering connected guests.
+//
+// A guest (an unregistered client) can be registered without
+// reconnecting: from the Mumble client's menu, with the /register chat
+// command, or by an admin through the admin API. The session carries
+// on as the new registration. The client stays in its channel, which
+// becomes its last channel, keeps the tokens it connected with and the
+// groups it was given by an invite, and the comment it set as a guest
+// becomes the comment of the registration. All clients are told the
+// new user id.
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+var errAlreadyRegistered = errors.New("client is already registered")
+
+// canRegister checks whether actor may register target, and tells
+// actor why not if it may not.
+func (server *Server) canRegister(actor, target *Client) bool {
+	// Registering oneself takes the selfregister permission in the
+	// root channel, registering others the register permission.
+	perm := acl.Permission(acl.RegisterPermission)
+	if actor == target {
+		perm = acl.Permission(acl.SelfRegisterPermission)
+	}
+
+	rootChan := server.RootChannel()
+	if target.IsRegistered() || !acl.HasPermission(&rootChan.ACL, actor, perm) {
+		actor.sendPermissionDenied(actor, rootChan, perm)
+		return false
+	}
+
+	if !target.HasCertificate() {
+		actor.sendPermissionDeniedTypeUser(mumbleproto.PermissionDenied_MissingCertificate, target)
+		return false
+	}
+
+	// Self-registration may be limited to clients with certificates
+	// issued by a trusted CA.
+	if actor == target && server.cfg.BoolValue("SelfRegisterVerified") && !target.IsVerified() {
+		actor.sendPermissionDeniedTypeUser(mumbleproto.PermissionDenied_MissingCertificate, target)
+		return false
+	}
+
+	if _, exists := server.registeredUser(target.Username); exists {
+		actor.sendPermissionDeniedTypeUser(mumbleproto.PermissionDenied_UserName, target)
+		return false
+	}
+	return true
+}
+
+// claimSession registers the guest target under its name, keeping its
+// session. actor is the client that registered target, or nil if the
+// server did.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) claimSession(actor, target *Client) (*User, error) {
+	if target.IsRegistered() {
+		return nil, errAlreadyRegistered
+	}
+	uid, err := server.RegisterClient(target)
+	if err != nil {
+		return nil, err
+	}
+
+	user := server.Users[uid]
+	target.user = user
+	target.saveModeration()
+	if target.Channel != nil {
+		user.LastChannelId = target.Channel.Id
+	}
+	if len(target.comment) > 0 {
+		key, err := server.blobs.Put([]byte(target.comment))
+		if err != nil {
+			server.Panicf("Blobstore error: %v", err)
+		} else {
+			user.CommentBlob = key
+		}
+		target.comment = ""
+	}
+	server.UpdateFrozenUserRecord(user)
+	target.Printf("Registered as user %v", uid)
+
+	userstate := &mumbleproto.UserState{
+		Session: proto.Uint32(target.Session()),
+		UserId:  proto.Uint32(uid),
+		Hash:    proto.String(target.CertHash()),
+	}
+	if actor != nil {
+		userstate.Actor = proto.Uint32(actor.Session())
+	}
+	if user.HasComment() {
+		userstate.CommentHash = user.CommentBlobHashBytes()
+	}
+
+	// Registration changes the groups the client is in.
+	server.pushPermissions()
+	if err := server.broadcastProtoMessage(userstate); err != nil {
+		server.Panic("Unable to broadcast UserState")
+	}
+	return user, nil
+}
+
+func init() {
+	registerChatCommand("register", &chatCommand{
+		usage: []string{"register"},
+		help:  "Register under your current name",
+		run:   (*Server).registerCommand,
+	})
+}
+
+// registerCommand implements the /register chat command, which
+// registers the sender under its current name.
+func (server *Server) registerCommand(client *Client, args []string, text string) {
+	if len(args) != 1 {
+		server.sendChatCommandUsage(client, args[0])
+		return
+	}
+
+	if !server.canRegister(client, client) {
+		return
+	}
+	user, err := server.claimSession(client, client)
+	if err != nil {
+		client.Printf("Unable to register: %v", err)
+		server.sendServerText(client, "Unable to register")
+		return
+	}
+	server.audit(client, auditlog.Entry{
+		Action:  "user.register",
+		Target:  user.Name,
+		Details: fmt.Sprintf("user %v", user.Id),
+	})
+	server.sendServerText(client, "You are now registered")
+}
+
+// handleAPIRegisterClient implements
+// /servers/<id>/clients/<session>/register.
+//
+//	POST  registers a connected guest under its name, keeping its
+//	      session
+func handleAPIRegisterClient(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	session, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid session")
+		return
+	}
+
+	status := http.StatusCreated
+	var reply interface{}
+	err = server.runSync(func() {
+		client, ok := server.clients[uint32(session)]
+		if !ok || client.state != StateClientReady {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such session"}
+			return
+		}
+		if !client.HasCertificate() {
+			status, reply = http.StatusBadRequest, map[string]string{"error": "client has no certificate"}
+			return
+		}
+		user, err := server.claimSession(nil, client)
+		if err != nil {
+			status, reply = http.StatusConflict, map[string]string{"error": err.Error()}
+			return
+		}
+		server.auditAPI(auditlog.Entry{
+			Action:  "user.register",
+			Target:  user.Name,
+			Details: fmt.Sprintf("user %v session %v", user.Id, session),
+		})
+		reply = apiUser{
+			Id:             user.Id,
+			Name:           user.Name,
+			Email:          user.Email,
+			HasCertificate: true,
+			Online:         true,
+			LastChannel:    user.LastChannelId,
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -7,24 +7,32 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"crypto/tls"
+	"crypto/x509"
 	"encoding/binary"
 	"errors"
 	"io"
 	"log"
 	"net"
-	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"mumble.info/grumble/pkg/acl"
 	"mumble.info/grumble/pkg/cryptstate"
+	"mumble.info/grumble/pkg/features"
+	"mumble.info/grumble/pkg/geoip"
+	"mumble.info/grumble/pkg/jitterbuf"
 	"mumble.info/grumble/pkg/mumbleproto"
 	"mumble.info/grumble/pkg/packetdata"
 )
 
 // A client connection
 type Client struct {
+	// Traffic counters. Kept first in the struct so that
+	// the 64-bit atomic counters are properly aligned.
+	traffic trafficCounter
+
 	// Logging
 	*log.Logger
 	lf *clientLogForwarder
@@ -32,22 +40,49 @@ type Client struct {
 	// Connection-related
 	tcpaddr *net.TCPAddr
 	udpaddr *net.UDPAddr
+	udpconn *net.UDPConn
+	geo     geoip.Record
 	conn    net.Conn
 	reader  *bufio.Reader
 	state   int
 	server  *Server
 
+	// Held while writing to conn, so that streamed blobs aren't
+	// interleaved with other messages.
+	sendLock sync.Mutex
+
 	udprecv chan []byte
+	udpdone chan struct{} // closed when the client disconnects
+
+	// Set while the client's session is handed over to a new process
+	// (see sessionhandover.go)
+	handoverLock sync.Mutex
+	handover     *clientHandover
+
+	// Whether the session was carried over from the previous process,
+	// and the certificate chain the client sent to it
+	handedOver      bool
+	handedOverCerts []*x509.Certificate
 
 	disconnected bool
+	connectedAt  time.Time
 
 	lastResync   int64
 	crypt        cryptstate.CryptState
+	cryptLock    sync.Mutex
+	cryptKeyTime time.Time
+	jitter       *jitterbuf.Buffer
+	jitterDelay  time.Duration
 	codecs       []int32
 	opus         bool
-	udp          bool
+	udp          int32 // 1 while voice is sent over UDP; accessed atomically
 	voiceTargets map[uint32]*VoiceTarget
 
+	// Position from the client's last positional voice packet
+	posLock sync.Mutex
+	pos     [3]float32
+	posTime time.Time
+
 	// Ping stats
 	UdpPingAvg float32
 	UdpPingVar float32
@@ -73,11 +108,15 @@ type Client struct {
 	OSName     string
 	OSVersion  string
 	CryptoMode string
+	// The protocol features the client supports, worked out from its
+	// version
+	features features.Set
 
 	// Personal
 	Username        string
 	session         uint32
 	certHash        string
+	verified        bool
 	Email           string
 	tokens          []string
 	Channel         *Channel
@@ -90,6 +129,52 @@ type Client struct {
 	Recording       bool
 	PluginContext   []byte
 	PluginIdentity  string
+
+	// When the server mute or deafen is lifted; zero if it doesn't expire
+	muteExpires time.Time
+
+	// Temporary permission grants
+	grants []permissionGrant
+
+	// The permissions last sent for each channel (see permpush.go)
+	sentPermissions map[int]acl.Permission
+
+	// The comment of a guest, kept for when it registers
+	comment string
+
+	// Access tokens and root channel groups given by an invite
+	inviteTokens []string
+	inviteGroups []string
+
+	// Waiting room state, and the channel to enter once admitted
+	queued      bool
+	queueTarget int
+
+	// Whether the client used /afk, and the channel it left for it
+	afk       bool
+	afkReturn int
+
+	// Whether the client is held in the maintenance channel, and the
+	// channel it was moved from
+	held       bool
+	heldReturn int
+
+	// Text message flood protection
+	textBucket    leakyBucket
+	textFloods    int
+	lastTextFlood time.Time
+
+	// Control message shaping
+	controlBucket       leakyBucket
+	lastControlDelayLog time.Time
+
+	// Voice packet loss statistics
+	voiceStats voiceStats
+
+	// Talking state, kept by the server's handler goroutine
+	talking      bool
+	talkingSince time.Time
+	lastVoice    time.Time
 }
 
 // Debugf implements debug-level printing for Clients.
@@ -127,8 +212,30 @@ func (client *Client) Session() uint32 {
 	return client.session
 }
 
+// Tokens gets the client's access tokens, including the tokens
+// given to it by an invite.
 func (client *Client) Tokens() []string {
-	return client.tokens
+	if len(client.inviteTokens) == 0 {
+		return client.tokens
+	}
+	tokens := make([]string, 0, len(client.tokens)+len(client.inviteTokens))
+	tokens = append(tokens, client.tokens...)
+	return append(tokens, client.inviteTokens...)
+}
+
+// usesUDP checks whether the client's voice is sent over UDP rather
+// than tunneled through its control connection.
+func (client *Client) usesUDP() bool {
+	return atomic.LoadInt32(&client.udp) == 1
+}
+
+// setUDP sets whether the client's voice is sent over UDP.
+func (client *Client) setUDP(udp bool) {
+	if udp {
+		atomic.StoreInt32(&client.udp, 1)
+	} else {
+		atomic.StoreInt32(&client.udp, 0)
+	}
 }
 
 // UserId gets the User ID of this client.
@@ -154,9 +261,7 @@ func (client *Client) ShownName() string {
 // IsVerified checks whether the client's certificate is
 // verified.
 func (client *Client) IsVerified() bool {
-	tlsconn := client.conn.(*tls.Conn)
-	state := tlsconn.ConnectionState()
-	return len(state.VerifiedChains) > 0
+	return client.verified
 }
 
 // Log a panic and disconnect the client.
@@ -177,8 +282,9 @@ func (client *Client) disconnect(kicked bool) {
 		client.disconnected = true
 		client.server.RemoveClient(client, kicked)
 
-		// Close the client's UDP reciever goroutine.
-		close(client.udprecv)
+		// Stop the client's UDP reciever goroutine. udprecv itself
+		// stays open, as UDP listeners may be about to send on it.
+		close(client.udpdone)
 
 		// If the client paniced during authentication, before reaching
 		// the ready state, the receiver goroutine will be waiting for
@@ -238,22 +344,29 @@ func (client *Client) readProtoMessage() (msg *Message, err error) {
 	)
 
 	// Read the message type (16-bit big-endian unsigned integer)
-	err = binary.Read(client.reader, binary.BigEndian, &kind)
+	// and length (32-bit big-endian unsigned integer)
+	var header [6]byte
+	err = client.readFull(header[:], false)
 	if err != nil {
 		return
 	}
-
-	// Read the message length (32-bit big-endian unsigned integer)
-	err = binary.Read(client.reader, binary.BigEndian, &length)
+	kind = binary.BigEndian.Uint16(header[0:])
+	length = binary.BigEndian.Uint32(header[2:])
+	err = client.checkMessageSize(kind, length)
 	if err != nil {
 		return
 	}
 
 	buf := make([]byte, length)
-	_, err = io.ReadFull(client.reader, buf)
+	err = client.readFull(buf, true)
 	if err != nil {
 		return
 	}
+	if kind == mumbleproto.MessageUDPTunnel {
+		client.traffic.addVoiceIn(6 + len(buf))
+	} else {
+		client.traffic.addIn(6 + len(buf))
+	}
 
 	msg = &Message{
 		buf:    buf,
@@ -264,6 +377,29 @@ func (client *Client) readProtoMessage() (msg *Message, err error) {
 	return
 }
 
+// readFull reads len(buf) bytes from the client. started tells whether
+// a message was already started. The read deadline set to stop the
+// receiver for a handover only stops it between two messages: the rest
+// of a started message is read regardless.
+func (client *Client) readFull(buf []byte, started bool) error {
+	n := 0
+	for n < len(buf) {
+		m, err := client.reader.Read(buf[n:])
+		n += m
+		if err != nil {
+			if isTimeout(err) && (started || n > 0) && client.handingOver() != nil {
+				client.conn.SetReadDeadline(time.Time{})
+				continue
+			}
+			if n > 0 && err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
 // Send permission denied by type
 func (c *Client) sendPermissionDeniedType(denyType mumbleproto.PermissionDenied_DenyType) {
 	c.sendPermissionDeniedTypeUser(denyType, nil)
@@ -292,6 +428,21 @@ func (c *Client) sendPermissionDenied(who *Client, where *Channel, what acl.Perm
 		Session:    proto.Uint32(who.Session()),
 		Type:       mumbleproto.PermissionDenied_Permission.Enum(),
 	}
+	e, on := c.server.explainPermission(who, where, what)
+	c.Debugf("Permission denied to %v in channel %v: %v", who.ShownName(), where.Id, describeExplanation(e, on))
+	err := c.sendMessage(pd)
+	if err != nil {
+		c.Panicf("%v", err.Error())
+		return
+	}
+}
+
+// Send permission denied by type, with a reason
+func (c *Client) sendPermissionDeniedReason(denyType mumbleproto.PermissionDenied_DenyType, reason string) {
+	pd := &mumbleproto.PermissionDenied{
+		Type:   denyType.Enum(),
+		Reason: proto.String(reason),
+	}
 	err := c.sendMessage(pd)
 	if err != nil {
 		c.Panicf("%v", err.Error())
@@ -300,11 +451,11 @@ func (c *Client) sendPermissionDenied(who *Client, where *Channel, what acl.Perm
 }
 
 // Send permission denied fallback
-func (client *Client) sendPermissionDeniedFallback(denyType mumbleproto.PermissionDenied_DenyType, version uint32, text string) {
+func (client *Client) sendPermissionDeniedFallback(denyType mumbleproto.PermissionDenied_DenyType, feature features.Feature, text string) {
 	pd := &mumbleproto.PermissionDenied{
 		Type: denyType.Enum(),
 	}
-	if client.Version < version {
+	if !client.supports(feature) {
 		pd.Reason = proto.String(text)
 	}
 	err := client.sendMessage(pd)
@@ -316,12 +467,23 @@ func (client *Client) sendPermissionDeniedFallback(denyType mumbleproto.Permissi
 
 // UDP receive loop
 func (client *Client) udpRecvLoop() {
-	for buf := range client.udprecv {
-		// Received a zero-valued buffer. This means that the udprecv
-		// channel was closed, so exit cleanly.
-		if len(buf) == 0 {
+	diagReceivers.Add("udp", 1)
+	defer diagReceivers.Add("udp", -1)
+	defer func() {
+		if client.jitter != nil {
+			client.jitter.Stop()
+		}
+	}()
+	for {
+		var buf []byte
+		select {
+		case buf = <-client.udprecv:
+		case <-client.udpdone:
 			return
 		}
+		if len(buf) == 0 {
+			continue
+		}
 
 		kind := (buf[0] >> 5) & 0x07
 
@@ -331,49 +493,14 @@ func (client *Client) udpRecvLoop() {
 		case mumbleproto.UDPMessageVoiceCELTAlpha:
 			fallthrough
 		case mumbleproto.UDPMessageVoiceCELTBeta:
+			// Drop legacy codec packets, but keep receiving: the
+			// UDP listener blocks until the packet is taken.
 			if client.server.Opus {
-				return
+				continue
 			}
 			fallthrough
 		case mumbleproto.UDPMessageVoiceOpus:
-			target := buf[0] & 0x1f
-			var counter uint8
-			outbuf := make([]byte, 1024)
-
-			incoming := packetdata.New(buf[1 : 1+(len(buf)-1)])
-			outgoing := packetdata.New(outbuf[1 : 1+(len(outbuf)-1)])
-			_ = incoming.GetUint32()
-
-			if kind != mumbleproto.UDPMessageVoiceOpus {
-				for {
-					counter = incoming.Next8()
-					incoming.Skip(int(counter & 0x7f))
-					if !((counter&0x80) != 0 && incoming.IsValid()) {
-						break
-					}
-				}
-			} else {
-				size := int(incoming.GetUint16())
-				incoming.Skip(size & 0x1fff)
-			}
-
-			outgoing.PutUint32(client.Session())
-			outgoing.PutBytes(buf[1 : 1+(len(buf)-1)])
-			outbuf[0] = buf[0] & 0xe0 // strip target
-
-			if target != 0x1f { // VoiceTarget
-				client.server.voicebroadcast <- &VoiceBroadcast{
-					client: client,
-					buf:    outbuf[0 : 1+outgoing.Size()],
-					target: target,
-				}
-			} else { // Server loopback
-				buf := outbuf[0 : 1+outgoing.Size()]
-				err := client.SendUDP(buf)
-				if err != nil {
-					client.Panicf("Unable to send UDP message: %v", err.Error())
-				}
-			}
+			client.receiveVoice(buf)
 
 		case mumbleproto.UDPMessagePing:
 			err := client.SendUDP(buf)
@@ -384,14 +511,104 @@ func (client *Client) udpRecvLoop() {
 	}
 }
 
+// deliverUDP hands a UDP message to the client's UDP receiver
+// goroutine, or drops it if the client has disconnected.
+func (client *Client) deliverUDP(buf []byte) {
+	select {
+	case client.udprecv <- buf:
+	case <-client.udpdone:
+	}
+}
+
+// receiveVoice counts a voice packet from the client in its voice
+// statistics and passes it on. While the client's voice is tunneled
+// through the control connection, it passes through the client's
+// jitter buffer if TunnelJitterDelay is set.
+func (client *Client) receiveVoice(buf []byte) {
+	packet, err := mumbleproto.ParseUDPPacket(buf)
+	if err != nil {
+		client.Debugf("dropping voice packet: %v", err)
+		return
+	}
+	client.voiceStats.add(time.Now(), packet.Sequence)
+
+	delay := time.Duration(client.server.cfg.IntValue("TunnelJitterDelay")) * time.Millisecond
+	if delay <= 0 || client.usesUDP() {
+		if client.jitter != nil {
+			client.jitter.Flush()
+		}
+		client.forwardVoice(buf)
+		return
+	}
+
+	if client.jitter == nil || client.jitterDelay != delay {
+		if client.jitter != nil {
+			client.jitter.Flush()
+		}
+		client.jitter = jitterbuf.New(delay, client.forwardVoice)
+		client.jitterDelay = delay
+	}
+	client.jitter.Push(packet.Sequence, buf)
+}
+
+// forwardVoice sends a voice packet from the client to its targets.
+func (client *Client) forwardVoice(buf []byte) {
+	if client.disconnected {
+		return
+	}
+	packet, err := mumbleproto.ParseUDPPacket(buf)
+	if err != nil {
+		client.Debugf("dropping voice packet: %v", err)
+		return
+	}
+	client.traffic.addVoicePacket()
+	target := packet.Target
+	if packet.HasPosition {
+		client.setPosition(packet.Position[0], packet.Position[1], packet.Position[2])
+	}
+
+	outbuf := make([]byte, 1024)
+	outgoing := packetdata.New(outbuf[1:])
+	outgoing.PutUint32(client.Session())
+	outgoing.PutBytes(buf[1:])
+	if !outgoing.IsValid() {
+		client.Debugf("dropping voice packet: too large to forward")
+		return
+	}
+	outbuf[0] = buf[0] & 0xe0 // strip target
+
+	if target != 0x1f { // VoiceTarget
+		client.server.voicebroadcast <- &VoiceBroadcast{
+			client:     client,
+			buf:        outbuf[0 : 1+outgoing.Size()],
+			target:     target,
+			terminator: packet.Terminator,
+		}
+	} else { // Server loopback
+		buf := outbuf[0 : 1+outgoing.Size()]
+		err := client.SendUDP(buf)
+		if err != nil {
+			client.Panicf("Unable to send UDP message: %v", err.Error())
+		}
+	}
+}
+
 // Send buf as a UDP message. If the client does not have
 // an established UDP connection, the datagram will be tunelled
 // through the client's control channel (TCP).
 func (client *Client) SendUDP(buf []byte) error {
-	if client.udp {
+	if client.usesUDP() {
+		client.cryptLock.Lock()
+		// The session may have been handed over meanwhile.
+		if !client.usesUDP() {
+			client.cryptLock.Unlock()
+			return client.sendMessage(buf)
+		}
 		crypted := make([]byte, len(buf)+client.crypt.Overhead())
 		client.crypt.Encrypt(crypted, buf)
-		return client.server.SendUDP(crypted, client.udpaddr)
+		client.cryptLock.Unlock()
+		client.traffic.addVoiceOut(len(crypted))
+		return client.server.SendUDP(client.udpconn, crypted, client.udpaddr)
 	} else {
 		return client.sendMessage(buf)
 	}
@@ -406,6 +623,28 @@ func (client *Client) SendUDP(buf []byte) error {
 // buffered writer.
 func (client *Client) sendMessage(msg interface{}) error {
 	buf := new(bytes.Buffer)
+	err := encodeMessage(buf, msg)
+	if err != nil {
+		return err
+	}
+
+	client.sendLock.Lock()
+	n, err := client.conn.Write(buf.Bytes())
+	client.sendLock.Unlock()
+	if _, voice := msg.([]byte); voice {
+		client.traffic.addVoiceOut(n)
+	} else {
+		client.traffic.addOut(n)
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// encodeMessage appends msg to buf, framed as it is sent on the wire.
+func encodeMessage(buf *bytes.Buffer, msg interface{}) error {
 	var (
 		kind    uint16
 		msgData []byte
@@ -435,27 +674,36 @@ func (client *Client) sendMessage(msg interface{}) error {
 		return err
 	}
 	_, err = buf.Write(msgData)
-	if err != nil {
-		return err
-	}
-
-	_, err = client.conn.Write(buf.Bytes())
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return err
 }
 
 // TLS receive loop
 func (client *Client) tlsRecvLoop() {
+	diagReceivers.Add("tls", 1)
+	defer diagReceivers.Add("tls", -1)
+	if !client.tlsHandshake() {
+		return
+	}
 	for {
 		// The version handshake is done, the client has been authenticated and it has received
 		// all necessary information regarding the server.  Now we're ready to roll!
 		if client.state == StateClientReady {
+			// Stop between two messages if the session is being
+			// handed over to a new process.
+			if h := client.handingOver(); h != nil {
+				if client.relayConnection(h) {
+					return
+				}
+				continue
+			}
+
 			// Try to read the next message in the pool
 			msg, err := client.readProtoMessage()
 			if err != nil {
+				if isTimeout(err) && client.handingOver() != nil {
+					continue
+				}
+				client.endHandover()
 				if err == io.EOF {
 					client.Disconnect()
 				} else {
@@ -466,9 +714,10 @@ func (client *Client) tlsRecvLoop() {
 			// Special case UDPTunnel messages. They're high priority and shouldn't
 			// go through our synchronous path.
 			if msg.kind == mumbleproto.MessageUDPTunnel {
-				client.udp = false
-				client.udprecv <- msg.buf
+				client.setUDP(false)
+				client.deliverUDP(msg.buf)
 			} else {
+				client.shapeControlMessage(msg.kind)
 				client.server.incoming <- msg
 			}
 		}
@@ -476,13 +725,8 @@ func (client *Client) tlsRecvLoop() {
 		// The client has responded to our version query. It will try to authenticate.
 		if client.state == StateClientSentVersion {
 			// Try to read the next message in the pool
-			msg, err := client.readProtoMessage()
-			if err != nil {
-				if err == io.EOF {
-					client.Disconnect()
-				} else {
-					client.Panicf("%v", err)
-				}
+			msg, ok := client.readHandshakeMessage("AuthTimeout")
+			if !ok {
 				return
 			}
 
@@ -505,31 +749,17 @@ func (client *Client) tlsRecvLoop() {
 		// information we must send it our version information so it knows
 		// what version of the protocol it should speak.
 		if client.state == StateClientConnected {
-			version := &mumbleproto.Version{
-				Version:     proto.Uint32(0x10205),
-				Release:     proto.String("Grumble"),
-				CryptoModes: cryptstate.SupportedModes(),
-			}
-			if client.server.cfg.BoolValue("SendOSInfo") {
-				version.Os = proto.String(runtime.GOOS)
-				version.OsVersion = proto.String("(Unknown version)")
-			}
-			client.sendMessage(version)
+			client.sendMessage(client.server.versionMessage())
 			client.state = StateServerSentVersion
 			continue
 		} else if client.state == StateServerSentVersion {
-			msg, err := client.readProtoMessage()
-			if err != nil {
-				if err == io.EOF {
-					client.Disconnect()
-				} else {
-					client.Panicf("%v", err)
-				}
+			msg, ok := client.readHandshakeMessage("VersionTimeout")
+			if !ok {
 				return
 			}
 
 			version := &mumbleproto.Version{}
-			err = proto.Unmarshal(msg.buf, version)
+			err := proto.Unmarshal(msg.buf, version)
 			if err != nil {
 				client.Panicf("%v", err)
 				return
@@ -553,88 +783,28 @@ func (client *Client) tlsRecvLoop() {
 				client.OSVersion = *version.OsVersion
 			}
 
-			// Extract the client's supported crypto mode.
-			// If the client does not pick a crypto mode
-			// itself, use an invalid mode (the empty string)
-			// as its requested mode. This is effectively
-			// a flag asking for the default crypto mode.
-			requestedMode := ""
-			if len(version.CryptoModes) > 0 {
-				requestedMode = version.CryptoModes[0]
-			}
-
-			// Check if the requested crypto mode is supported
-			// by us. If not, fall back to the default crypto
-			// mode.
-			supportedModes := cryptstate.SupportedModes()
-			ok := false
-			for _, mode := range supportedModes {
-				if requestedMode == mode {
-					ok = true
-					break
-				}
-			}
-			if !ok {
-				requestedMode = "OCB2-AES128"
+			if reason, denied := client.server.deniedClient(client); denied {
+				client.RejectAuth(mumbleproto.Reject_WrongVersion, reason)
+				return
 			}
+			client.features = client.server.clientFeatures(client)
 
-			client.CryptoMode = requestedMode
+			// Pick the crypto mode from those the client
+			// supports. Legacy clients that don't list any
+			// get the default crypto mode.
+			client.CryptoMode = cryptstate.NegotiateMode(version.CryptoModes)
+			client.Printf("Using crypto mode %v", client.CryptoMode)
 			client.state = StateClientSentVersion
 		}
 	}
 }
 
-func (client *Client) sendChannelList() {
-	client.sendChannelTree(client.server.RootChannel())
-}
-
-func (client *Client) sendChannelTree(channel *Channel) {
-	chanstate := &mumbleproto.ChannelState{
-		ChannelId: proto.Uint32(uint32(channel.Id)),
-		Name:      proto.String(channel.Name),
-	}
-	if channel.parent != nil {
-		chanstate.Parent = proto.Uint32(uint32(channel.parent.Id))
-	}
-
-	if channel.HasDescription() {
-		if client.Version >= 0x10202 {
-			chanstate.DescriptionHash = channel.DescriptionBlobHashBytes()
-		} else {
-			buf, err := blobStore.Get(channel.DescriptionBlob)
-			if err != nil {
-				panic("Blobstore error.")
-			}
-			chanstate.Description = proto.String(string(buf))
-		}
-	}
-
-	if channel.IsTemporary() {
-		chanstate.Temporary = proto.Bool(true)
-	}
-
-	chanstate.Position = proto.Int32(int32(channel.Position))
-
-	links := []uint32{}
-	for cid, _ := range channel.Links {
-		links = append(links, uint32(cid))
-	}
-	chanstate.Links = links
-
-	err := client.sendMessage(chanstate)
-	if err != nil {
-		client.Panicf("%v", err)
-	}
-
-	for _, subchannel := range channel.children {
-		client.sendChannelTree(subchannel)
-	}
-}
-
 // Try to do a crypto resync
 func (client *Client) cryptResync() {
 	client.Debugf("requesting crypt resync")
+	client.cryptLock.Lock()
 	goodElapsed := time.Now().Unix() - client.crypt.LastGoodTime
+	client.cryptLock.Unlock()
 	if goodElapsed > 5 {
 		requestElapsed := time.Now().Unix() - client.lastResync
 		if requestElapsed > 5 {
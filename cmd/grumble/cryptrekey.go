@@ -0,0 +1,70 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the rotation of the keys that encrypt voice
+// packets.
+//
+// A client's key is replaced after CryptRekeyInterval seconds, or once
+// CryptRekeyPackets packets were sent and received with it. The new key
+// is sent to the client in a CryptSetup message. Packets the client
+// sent with the old key before it got the new one are still accepted
+// for cryptRekeyGrace.
+
+import (
+	"time"
+
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The time packets encrypted with a client's old key are accepted.
+const cryptRekeyGrace = 10 * time.Second
+
+// rekeyClients rotates the keys of the clients whose keys are due.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) rekeyClients() {
+	interval := time.Duration(server.cfg.IntValue("CryptRekeyInterval")) * time.Second
+	packets := uint64(server.cfg.IntValue("CryptRekeyPackets"))
+	if interval <= 0 && packets <= 0 {
+		return
+	}
+
+	var rekeyed []*Client
+	var setups []*mumbleproto.CryptSetup
+	now := time.Now()
+
+	// The UDP receivers decrypt packets while holding the client's
+	// cryptLock.
+	for _, client := range server.clients {
+		if client.state != StateClientReady {
+			continue
+		}
+		client.cryptLock.Lock()
+		due := interval > 0 && now.Sub(client.cryptKeyTime) >= interval
+		due = due || packets > 0 && client.crypt.Packets() >= packets
+		if due {
+			if err := client.crypt.Rekey(client.CryptoMode, cryptRekeyGrace); err != nil {
+				client.Printf("Unable to rotate crypt key: %v", err)
+			} else {
+				client.cryptKeyTime = now
+				rekeyed = append(rekeyed, client)
+				setups = append(setups, &mumbleproto.CryptSetup{
+					Key:         client.crypt.Key,
+					ClientNonce: append([]byte(nil), client.crypt.DecryptIV...),
+					ServerNonce: append([]byte(nil), client.crypt.EncryptIV...),
+				})
+			}
+		}
+		client.cryptLock.Unlock()
+	}
+
+	for i, client := range rekeyed {
+		client.Debugf("Rotated crypt key")
+		if err := client.sendMessage(setups[i]); err != nil {
+			client.Panicf("%v", err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the runtime diagnostics used to debug leaks and
+// stuck goroutines in production.
+//
+// The --debug-addr argument serves net/http/pprof under /debug/pprof/,
+// expvar under /debug/vars, and Prometheus metrics (see bandwidth.go)
+// under /metrics. The diagnostics port has no
+// authentication, so it may only listen on a loopback address. Next to
+// the runtime's variables, expvar publishes the number of goroutines,
+// the number of running TLS and UDP receivers, and the number of
+// connections of each server; a receiver count that keeps growing past
+// the number of connections points at goroutines that never exit.
+//
+// The admin API's /debug/goroutines endpoint dumps the stacks of all
+// goroutines, and /servers/<id>/connections lists a server's
+// connections in any state, including those still in the handshake.
+// The hits, misses and size of the blob cache are published as
+// blobcache.
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// diagReceivers counts the running receiver goroutines by kind.
+var diagReceivers = expvar.NewMap("receivers")
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("connections", expvar.Func(func() interface{} {
+		conns := map[string]int{}
+		for id, server := range servers {
+			n := 0
+			if server.runSync(func() { n = len(server.clients) }) == nil {
+				conns[strconv.FormatInt(id, 10)] = n
+			}
+		}
+		return conns
+	}))
+
+	expvar.Publish("blobcache", expvar.Func(func() interface{} {
+		return blobCacheStats()
+	}))
+
+	apiMux.HandleFunc("/debug/goroutines", handleAPIGoroutines)
+	registerAPIEndpoint("connections", handleAPIConnections)
+}
+
+// StartDiagnostics serves pprof and expvar on the given address, which
+// must be a loopback address.
+func StartDiagnostics(addr string) error {
+	tcpaddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if tcpaddr.IP == nil || !tcpaddr.IP.IsLoopback() {
+		return fmt.Errorf("%v is not a loopback address", addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/metrics", handleMetrics)
+
+	l, err := listenTCP(tcpaddr)
+	if err != nil {
+		return err
+	}
+	// No write timeout: CPU profiles and traces take as long as
+	// the request asks for.
+	srv := &http.Server{
+		Handler:     mux,
+		ReadTimeout: 10 * time.Second,
+	}
+	go func() {
+		err := srv.Serve(l)
+		if err != nil {
+			log.Printf("Diagnostics stopped: %v", err)
+		}
+	}()
+	log.Printf("Diagnostics listening on %v", addr)
+	return nil
+}
+
+// handleAPIGoroutines implements /debug/goroutines.
+//
+//	GET  dumps the stacks of all goroutines as text
+func handleAPIGoroutines(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// apiConnection is the JSON representation of a connection in the
+// admin API.
+type apiConnection struct {
+	Session      uint32    `json:"session"`
+	Name         string    `json:"name,omitempty"`
+	State        string    `json:"state"`
+	TCPAddr      string    `json:"tcp_addr"`
+	UDPAddr      string    `json:"udp_addr,omitempty"`
+	UDP          bool      `json:"udp"`
+	Disconnected bool      `json:"disconnected"`
+	ConnectedAt  time.Time `json:"connected_at"`
+	BytesIn      uint64    `json:"bytes_in"`
+	BytesOut     uint64    `json:"bytes_out"`
+	CryptMode    string    `json:"crypt_mode,omitempty"`
+	CryptGood    uint32    `json:"crypt_good"`
+	CryptLate    uint32    `json:"crypt_late"`
+	CryptLost    uint32    `json:"crypt_lost"`
+	CryptResync  uint32    `json:"crypt_resync"`
+	Version      string    `json:"version,omitempty"`
+	Release      string    `json:"release,omitempty"`
+	Features     []string  `json:"features"`
+}
+
+// clientStateNames names the client states for the admin API.
+var clientStateNames = map[int]string{
+	StateClientConnected:     "connected",
+	StateClientSentVersion:   "sent-version",
+	StateClientAuthenticated: "authenticated",
+	StateClientReady:         "ready",
+	StateClientDead:          "dead",
+}
+
+// handleAPIConnections implements /servers/<id>/connections.
+//
+//	GET  lists the server's connections, whatever their state
+func handleAPIConnections(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	conns := []apiConnection{}
+	err := server.runSync(func() {
+		// The UDP receivers record the clients' UDP addresses in
+		// hpclients. Copy them out rather than holding hmutex while
+		// going through the clients.
+		udpaddrs := map[*Client]string{}
+		server.hmutex.Lock()
+		for addr, client := range server.hpclients {
+			udpaddrs[client] = addr
+		}
+		server.hmutex.Unlock()
+
+		for _, client := range server.clients {
+			in, out := client.traffic.load()
+			conn := apiConnection{
+				Session:      client.Session(),
+				Name:         client.Username,
+				State:        clientStateNames[client.state],
+				TCPAddr:      client.tcpaddr.String(),
+				UDP:          client.usesUDP(),
+				Disconnected: client.disconnected,
+				ConnectedAt:  client.connectedAt,
+				BytesIn:      in,
+				BytesOut:     out,
+				CryptMode:    client.CryptoMode,
+				Release:      client.ClientName,
+				Features:     client.features.Names(),
+			}
+			if client.Version > 0 {
+				conn.Version = fmt.Sprintf("%v.%v.%v", client.Version>>16, client.Version>>8&0xff, client.Version&0xff)
+			}
+			conn.UDPAddr = udpaddrs[client]
+			client.cryptLock.Lock()
+			conn.CryptGood = client.crypt.Good
+			conn.CryptLate = client.crypt.Late
+			conn.CryptLost = client.crypt.Lost
+			conn.CryptResync = client.crypt.Resync
+			client.cryptLock.Unlock()
+			conns = append(conns, conn)
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Session < conns[j].Session })
+	writeJSON(w, http.StatusOK, conns)
+}
@@ -0,0 +1,451 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file keeps ACL groups in sync with the groups of an external
+// directory.
+//
+// When DirectoryURL and DirectoryGroupMap are set, the groups are read
+// from the directory every DirectorySyncInterval seconds, and the ACL
+// groups named by the mapping file (see pkg/dirsync) are made to hold
+// exactly the registered users that are members of the directory groups
+// mapped to them: missing members are added, and others removed. The
+// directory is an LDAP server, or a JSON document, read from a file or
+// over HTTP, for identity providers such as OpenID Connect providers
+// that can export group memberships but can't be searched.
+//
+// Directory members without a registration on the server are skipped.
+// ACL groups whose directory groups can't all be found are left alone,
+// so that a typo or a partial directory doesn't empty them.
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/dirsync"
+	"mumble.info/grumble/pkg/ldap"
+)
+
+// The largest directory document accepted.
+const maxDirectoryDocumentSize = 16 << 20
+
+// Config keys of the directory sync.
+var dirSyncConfigKeys = []string{
+	"DirectoryURL", "DirectoryBindDN", "DirectoryBindPassword", "DirectoryBaseDN", "DirectoryGroupFilter",
+	"DirectoryMemberAttributes", "DirectoryToken", "DirectoryGroupMap", "DirectorySyncInterval", "DirectoryDryRun",
+}
+
+var directoryClient = &http.Client{Timeout: 30 * time.Second}
+
+// A dirSyncer periodically syncs the ACL groups with the directory.
+type dirSyncer struct {
+	server *Server
+	done   chan bool
+}
+
+// dirSyncReport is the JSON representation of the changes made, or
+// that would be made, by a sync.
+type dirSyncReport struct {
+	Time   string         `json:"time"`
+	DryRun bool           `json:"dry_run"`
+	Error  string         `json:"error,omitempty"`
+	Groups []dirSyncGroup `json:"groups"`
+}
+
+// dirSyncGroup is the part of a dirSyncReport about a single ACL
+// group.
+type dirSyncGroup struct {
+	Channel         int      `json:"channel"`
+	Group           string   `json:"group"`
+	DirectoryGroups []string `json:"directory_groups"`
+	Added           []string `json:"added"`
+	Removed         []string `json:"removed"`
+	// Directory members without a registration.
+	Unknown []string `json:"unknown,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// dirSyncEnabled reports whether ACL groups are synced with a
+// directory.
+func (server *Server) dirSyncEnabled() bool {
+	return len(server.cfg.StringValue("DirectoryURL")) > 0 && len(server.cfg.StringValue("DirectoryGroupMap")) > 0
+}
+
+// fetchDirectory reads the groups of the directory named by
+// DirectoryURL.
+//
+// It makes network requests, so should not be called from the server's
+// handler goroutine.
+func (server *Server) fetchDirectory() (dirsync.Directory, error) {
+	u, err := url.Parse(server.cfg.StringValue("DirectoryURL"))
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "ldap", "ldaps":
+		return server.fetchLDAPDirectory(u)
+	case "http", "https":
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if token := server.cfg.StringValue("DirectoryToken"); len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := directoryClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("directory returned %v", resp.Status)
+		}
+		return dirsync.ParseDocument(io.LimitReader(resp.Body, maxDirectoryDocumentSize))
+	case "", "file":
+		fn := u.Path
+		if len(u.Opaque) > 0 {
+			fn = u.Opaque
+		}
+		if !filepath.IsAbs(fn) {
+			fn = filepath.Join(Args.DataDir, fn)
+		}
+		f, err := os.Open(fn)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return dirsync.ParseDocument(f)
+	}
+	return nil, fmt.Errorf("unsupported directory scheme %q", u.Scheme)
+}
+
+// fetchLDAPDirectory searches the LDAP server at u for groups.
+func (server *Server) fetchLDAPDirectory(u *url.URL) (dirsync.Directory, error) {
+	opts := ldap.Options{Address: u.Host}
+	defaultPort := "389"
+	if u.Scheme == "ldaps" {
+		defaultPort = "636"
+		opts.TLS = &tls.Config{ServerName: u.Hostname()}
+	}
+	if len(u.Port()) == 0 {
+		opts.Address = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	conn, err := ldap.Dial(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.Bind(server.cfg.StringValue("DirectoryBindDN"), server.cfg.StringValue("DirectoryBindPassword")); err != nil {
+		return nil, err
+	}
+	var attrs []string
+	for _, attr := range strings.Split(server.cfg.StringValue("DirectoryMemberAttributes"), ",") {
+		if attr = strings.TrimSpace(attr); len(attr) > 0 {
+			attrs = append(attrs, attr)
+		}
+	}
+	entries, err := conn.Search(server.cfg.StringValue("DirectoryBaseDN"), ldap.ScopeWholeSubtree, server.cfg.StringValue("DirectoryGroupFilter"), attrs)
+	if err != nil {
+		return nil, err
+	}
+	return dirsync.FromEntries(entries, attrs), nil
+}
+
+// syncDirectory reads the mapping file and the directory, and syncs the
+// ACL groups, or only reports the changes if dryRun is set.
+//
+// It makes network requests, so should not be called from the server's
+// handler goroutine. The groups are changed through sync, which runs a
+// function on the handler goroutine, or returns an error if it can't.
+// If it can't, syncDirectory returns a nil report.
+func (server *Server) syncDirectory(dryRun bool, sync func(func()) error) (*dirSyncReport, error) {
+	report := &dirSyncReport{Time: time.Now().UTC().Format(time.RFC3339), DryRun: dryRun, Groups: []dirSyncGroup{}}
+	fn := server.cfg.StringValue("DirectoryGroupMap")
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	mappings, err := dirsync.Load(fn)
+	var dir dirsync.Directory
+	if err == nil {
+		dir, err = server.fetchDirectory()
+	}
+	if err != nil {
+		report.Error = err.Error()
+		if serr := sync(func() {
+			server.dirSyncReport = report
+		}); serr != nil {
+			return nil, serr
+		}
+		return report, err
+	}
+	err = sync(func() {
+		server.reconcileDirectoryGroups(mappings, dir, report)
+		server.dirSyncReport = report
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// reconcileDirectoryGroups makes the mapped ACL groups hold the members
+// of their directory groups, recording the changes in report. The
+// groups aren't changed if report.DryRun is set.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) reconcileDirectoryGroups(mappings []dirsync.Mapping, dir dirsync.Directory, report *dirSyncReport) {
+	// Collect the members of each ACL group, which several directory
+	// groups may be mapped to.
+	type target struct {
+		channel int
+		group   string
+	}
+	var targets []target
+	entries := map[target]*dirSyncGroup{}
+	members := map[target]map[int]bool{}
+	for _, m := range mappings {
+		t := target{m.Channel, m.Group}
+		g, ok := entries[t]
+		if !ok {
+			g = &dirSyncGroup{Channel: m.Channel, Group: m.Group, Added: []string{}, Removed: []string{}}
+			entries[t] = g
+			members[t] = map[int]bool{}
+			targets = append(targets, t)
+		}
+		g.DirectoryGroups = append(g.DirectoryGroups, m.DirectoryGroup)
+		unknown := map[string]bool{}
+		for _, name := range g.Unknown {
+			unknown[name] = true
+		}
+		names, ok := dir.Members(m.DirectoryGroup)
+		if !ok {
+			g.Error = fmt.Sprintf("directory group %q not found", m.DirectoryGroup)
+			continue
+		}
+		for _, name := range names {
+			user, ok := server.registeredUser(name)
+			if !ok || user.Id == 0 {
+				if !unknown[name] {
+					unknown[name] = true
+					g.Unknown = append(g.Unknown, name)
+				}
+				continue
+			}
+			members[t][int(user.Id)] = true
+		}
+	}
+
+	changed := false
+	for _, t := range targets {
+		g := entries[t]
+		report.Groups = append(report.Groups, *g)
+		rg := &report.Groups[len(report.Groups)-1]
+		channel, ok := server.Channels[t.channel]
+		if !ok {
+			rg.Error = "no such channel"
+			continue
+		}
+		if len(rg.Error) > 0 {
+			continue
+		}
+		group, exists := channel.ACL.Groups[t.group]
+		if !exists {
+			group = acl.EmptyGroupWithName(t.group)
+		}
+		var add, remove []int
+		for id := range members[t] {
+			if !group.AddContains(id) {
+				add = append(add, id)
+			}
+		}
+		for _, id := range group.AddUsers() {
+			if !members[t][id] {
+				remove = append(remove, id)
+			}
+		}
+		rg.Added = server.userNames(add)
+		rg.Removed = server.userNames(remove)
+		sort.Strings(rg.Unknown)
+		if report.DryRun || len(add)+len(remove) == 0 {
+			continue
+		}
+
+		for _, id := range add {
+			group.Add[id] = true
+		}
+		for _, id := range remove {
+			delete(group.Add, id)
+		}
+		channel.ACL.Groups[t.group] = group
+		if !channel.IsTemporary() {
+			server.UpdateFrozenChannelACLs(channel)
+		}
+		changed = true
+		server.Printf("directory sync: group %v of channel %v: added %v, removed %v", t.group, channel.Id, rg.Added, rg.Removed)
+		server.audit(nil, auditlog.Entry{
+			Actor:   "directory",
+			Action:  "group.sync",
+			Target:  channel.Name,
+			Details: fmt.Sprintf("channel %v, group %v: added %v, removed %v", channel.Id, t.group, rg.Added, rg.Removed),
+		})
+	}
+	if changed {
+		server.pushPermissions()
+	}
+}
+
+// userNames returns the sorted names of the registered users with the
+// given ids.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) userNames(ids []int) []string {
+	names := []string{}
+	for _, id := range ids {
+		if user, ok := server.Users[uint32(id)]; ok {
+			names = append(names, user.Name)
+		} else {
+			names = append(names, fmt.Sprintf("#%v", id))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// startDirectorySync starts the periodic directory sync, if it is
+// enabled.
+//
+// Must be called from the server's handler goroutine, or before it
+// is started.
+func (server *Server) startDirectorySync() {
+	interval := time.Duration(server.cfg.IntValue("DirectorySyncInterval")) * time.Second
+	if !server.dirSyncEnabled() || interval <= 0 {
+		return
+	}
+	s := &dirSyncer{server: server, done: make(chan bool)}
+	server.dirsync = s
+	go s.run(interval)
+}
+
+// stopDirectorySync stops the periodic directory sync.
+//
+// Must be called from the server's handler goroutine, or after it
+// has stopped.
+func (server *Server) stopDirectorySync() {
+	if server.dirsync == nil {
+		return
+	}
+	close(server.dirsync.done)
+	server.dirsync = nil
+}
+
+// run syncs the groups right away, and then every interval until the
+// syncer is stopped.
+func (s *dirSyncer) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	stopped := errors.New("stopped")
+	for {
+		report, err := s.server.syncDirectory(s.server.cfg.BoolValue("DirectoryDryRun"), func(fn func()) error {
+			if !s.sync(fn) {
+				return stopped
+			}
+			return nil
+		})
+		if report == nil {
+			return
+		}
+		if err != nil {
+			s.server.Printf("directory sync: %v", err)
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync runs fn on the server's handler goroutine, and waits for it to
+// return. It returns false if the syncer was stopped first.
+func (s *dirSyncer) sync(fn func()) bool {
+	finished := make(chan bool)
+	select {
+	case s.server.syncCalls <- func() {
+		// The syncer may have been replaced while the call was queued.
+		if s.server.dirsync == s {
+			fn()
+		}
+		close(finished)
+	}:
+	case <-s.done:
+		return false
+	}
+	<-finished
+	return true
+}
+
+func init() {
+	registerAPIEndpoint("directorysync", handleAPIDirectorySync)
+}
+
+// handleAPIDirectorySync implements /servers/<id>/directorysync.
+//
+//	GET   returns the report of the last sync
+//	POST  syncs the groups now and returns the report; with ?dry_run=true,
+//	      only reports the changes that would be made
+func handleAPIDirectorySync(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 0 {
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		var report *dirSyncReport
+		err := server.runSync(func() {
+			report = server.dirSyncReport
+		})
+		if err != nil {
+			apiError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if report == nil {
+			apiError(w, http.StatusNotFound, "no sync has run yet")
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	case http.MethodPost:
+		if !server.dirSyncEnabled() {
+			apiError(w, http.StatusConflict, "DirectoryURL and DirectoryGroupMap must be set")
+			return
+		}
+		dryRun := r.URL.Query().Get("dry_run") == "true"
+		report, err := server.syncDirectory(dryRun, server.runSync)
+		if report == nil {
+			apiError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, report)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the Discord text chat bridge.
+//
+// When DiscordToken and DiscordChannel are set, text messages sent to
+// the Mumble channel named by DiscordBridgeChannel are posted to the
+// Discord channel by the bot, and messages posted in the Discord channel
+// are shown in the Mumble channel. Messages to Discord are sent at most
+// DiscordMessageLimit per second, with bursts of DiscordMessageBurst.
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/discord"
+	"mumble.info/grumble/pkg/htmlfilter"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+const (
+	// How often the Discord channel is checked for new messages.
+	discordPollInterval = 2 * time.Second
+	// The number of messages that may wait to be sent to Discord.
+	discordQueueSize = 64
+)
+
+// Config keys of the Discord bridge.
+var discordConfigKeys = []string{"DiscordToken", "DiscordChannel", "DiscordBridgeChannel", "DiscordMessageLimit", "DiscordMessageBurst"}
+
+// A discordBridge mirrors text messages between a Mumble channel and
+// a Discord channel.
+type discordBridge struct {
+	server         *Server
+	client         *discord.Client
+	discordChannel string
+	mumbleChannel  int
+	botID          string
+
+	outgoing chan string
+	done     chan bool
+}
+
+// startDiscordBridge starts the Discord bridge, if it is configured.
+//
+// Must be called from the server's handler goroutine, or before it
+// is started.
+func (server *Server) startDiscordBridge() {
+	token := server.cfg.StringValue("DiscordToken")
+	channel := server.cfg.StringValue("DiscordChannel")
+	if len(token) == 0 || len(channel) == 0 {
+		return
+	}
+
+	b := &discordBridge{
+		server:         server,
+		client:         discord.NewClient(token),
+		discordChannel: channel,
+		mumbleChannel:  server.cfg.IntValue("DiscordBridgeChannel"),
+		outgoing:       make(chan string, discordQueueSize),
+		done:           make(chan bool),
+	}
+	server.discord = b
+	go b.run()
+	server.Printf("discord: bridging channel %v with Discord channel %v", b.mumbleChannel, channel)
+}
+
+// stopDiscordBridge stops the Discord bridge, if it is running.
+//
+// Must be called from the server's handler goroutine, or after it
+// has stopped.
+func (server *Server) stopDiscordBridge() {
+	if server.discord == nil {
+		return
+	}
+	close(server.discord.done)
+	server.discord = nil
+}
+
+// bridgeToDiscord passes a text message sent by client to the Discord
+// bridge, if it was sent to the bridged channel.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) bridgeToDiscord(client *Client, txtmsg *mumbleproto.TextMessage) {
+	b := server.discord
+	if b == nil {
+		return
+	}
+	bridged := false
+	for _, id := range txtmsg.ChannelId {
+		bridged = bridged || int(id) == b.mumbleChannel
+	}
+	for _, id := range txtmsg.TreeId {
+		bridged = bridged || int(id) == b.mumbleChannel
+	}
+	if !bridged {
+		return
+	}
+
+	text, err := htmlfilter.Filter(txtmsg.GetMessage(), &htmlfilter.Options{StripHTML: true})
+	if err != nil || len(text) == 0 {
+		return
+	}
+	select {
+	case b.outgoing <- "**" + escapeDiscordMarkdown(client.ShownName()) + "**: " + text:
+	default:
+		server.Printf("discord: send queue full, dropped message from %v", client.ShownName())
+	}
+}
+
+// escapeDiscordMarkdown escapes the characters Discord interprets as
+// formatting.
+func escapeDiscordMarkdown(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("\\*_~`|>", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// run sends queued messages to Discord, and polls the Discord channel
+// for new messages, until the bridge is stopped.
+func (b *discordBridge) run() {
+	me, err := b.client.Me()
+	if err != nil {
+		b.server.Printf("discord: unable to start bridge: %v", err)
+		return
+	}
+	b.botID = me.ID
+
+	go b.sendLoop()
+
+	// Only messages posted after the bridge was started are shown.
+	var last string
+	msgs, err := b.client.Messages(b.discordChannel, "")
+	if err != nil {
+		b.server.Printf("discord: unable to read channel: %v", err)
+	} else if len(msgs) > 0 {
+		last = msgs[len(msgs)-1].ID
+	}
+
+	ticker := time.NewTicker(discordPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case <-ticker.C:
+		}
+
+		msgs, err := b.client.Messages(b.discordChannel, last)
+		if rl, ok := err.(*discord.RateLimitError); ok {
+			time.Sleep(rl.RetryAfter)
+			continue
+		} else if err != nil {
+			b.server.Printf("discord: unable to read channel: %v", err)
+			continue
+		}
+		for _, msg := range msgs {
+			last = msg.ID
+			if msg.Author.ID == b.botID || len(strings.TrimSpace(msg.Content)) == 0 {
+				continue
+			}
+			b.deliver(msg)
+		}
+	}
+}
+
+// deliver shows a message from Discord to the users in the bridged
+// Mumble channel.
+func (b *discordBridge) deliver(msg discord.Message) {
+	text := "<b>[Discord] " + html.EscapeString(msg.Author.DisplayName()) + ":</b> " + html.EscapeString(msg.Content)
+	fn := func() {
+		server := b.server
+		// The bridge may have been replaced while the call was queued.
+		if server.discord != b {
+			return
+		}
+		channel, ok := server.Channels[b.mumbleChannel]
+		if !ok {
+			return
+		}
+		for _, client := range channel.clients {
+			client.sendMessage(&mumbleproto.TextMessage{
+				ChannelId: []uint32{uint32(channel.Id)},
+				Message:   proto.String(text),
+			})
+		}
+	}
+	select {
+	case b.server.syncCalls <- fn:
+	case <-b.done:
+	}
+}
+
+// sendLoop posts queued messages to Discord, keeping to the configured
+// rate.
+func (b *discordBridge) sendLoop() {
+	var bucket leakyBucket
+	for {
+		var text string
+		select {
+		case <-b.done:
+			return
+		case text = <-b.outgoing:
+		}
+
+		rate := float64(b.server.cfg.IntValue("DiscordMessageLimit"))
+		burst := float64(b.server.cfg.IntValue("DiscordMessageBurst"))
+		for rate > 0 && burst > 0 && !bucket.allow(time.Now(), rate, burst) {
+			select {
+			case <-b.done:
+				return
+			case <-time.After(time.Duration(float64(time.Second) / rate)):
+			}
+		}
+
+		err := b.client.Send(b.discordChannel, text)
+		if rl, ok := err.(*discord.RateLimitError); ok {
+			time.Sleep(rl.RetryAfter)
+			err = b.client.Send(b.discordChannel, text)
+		}
+		if err != nil {
+			b.server.Printf("discord: unable to send message: %v", err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the dispatch of control channel messages to
+// their handlers.
+//
+// Handlers are registered by message kind with registerMessageHandler.
+// Middleware registered with registerMessageMiddleware runs around
+// every handler: its before hooks run in the order they were
+// registered and can drop a message, and its after hooks run in the
+// reverse order once the message was handled or dropped. The server
+// keeps per-kind message statistics this way, and limits the rate of
+// text messages (see floodlimit.go).
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// A messageHandler handles a message of one kind.
+type messageHandler func(server *Server, client *Client, msg *Message)
+
+// A messageMiddleware runs around the handling of every message. Either
+// hook may be nil.
+type messageMiddleware struct {
+	// before is called before the message is handled. If it returns
+	// false, the message is dropped.
+	before func(server *Server, client *Client, msg *Message) bool
+	// after is called once the message was handled, or dropped.
+	after func(server *Server, client *Client, msg *Message, handled bool, elapsed time.Duration)
+}
+
+// A registeredHandler is a messageHandler and the name of the message
+// kind it handles.
+type registeredHandler struct {
+	name    string
+	handler messageHandler
+}
+
+var messageHandlers = map[uint16]registeredHandler{}
+var messageMiddlewares []messageMiddleware
+
+// registerMessageHandler registers fn as the handler for messages of
+// the given kind.
+func registerMessageHandler(kind uint16, name string, fn messageHandler) {
+	if _, exists := messageHandlers[kind]; exists {
+		panic("dispatch: handler registered twice: " + name)
+	}
+	messageHandlers[kind] = registeredHandler{name: name, handler: fn}
+}
+
+// registerMessageMiddleware adds m to the middleware run around every
+// handler.
+func registerMessageMiddleware(m messageMiddleware) {
+	messageMiddlewares = append(messageMiddlewares, m)
+}
+
+func init() {
+	registerMessageHandler(mumbleproto.MessageAuthenticate, "Authenticate", (*Server).handleAuthenticate)
+	registerMessageHandler(mumbleproto.MessagePing, "Ping", (*Server).handlePingMessage)
+	registerMessageHandler(mumbleproto.MessageChannelRemove, "ChannelRemove", (*Server).handleChannelRemoveMessage)
+	registerMessageHandler(mumbleproto.MessageChannelState, "ChannelState", (*Server).handleChannelStateMessage)
+	registerMessageHandler(mumbleproto.MessageUserState, "UserState", (*Server).handleUserStateMessage)
+	registerMessageHandler(mumbleproto.MessageUserRemove, "UserRemove", (*Server).handleUserRemoveMessage)
+	registerMessageHandler(mumbleproto.MessageBanList, "BanList", (*Server).handleBanListMessage)
+	registerMessageHandler(mumbleproto.MessageTextMessage, "TextMessage", (*Server).handleTextMessage)
+	registerMessageHandler(mumbleproto.MessageACL, "ACL", (*Server).handleAclMessage)
+	registerMessageHandler(mumbleproto.MessageQueryUsers, "QueryUsers", (*Server).handleQueryUsers)
+	registerMessageHandler(mumbleproto.MessageCryptSetup, "CryptSetup", (*Server).handleCryptSetup)
+	registerMessageHandler(mumbleproto.MessageContextAction, "ContextAction", (*Server).handleContextAction)
+	registerMessageHandler(mumbleproto.MessageUserList, "UserList", (*Server).handleUserList)
+	registerMessageHandler(mumbleproto.MessageVoiceTarget, "VoiceTarget", (*Server).handleVoiceTarget)
+	registerMessageHandler(mumbleproto.MessagePermissionQuery, "PermissionQuery", (*Server).handlePermissionQuery)
+	registerMessageHandler(mumbleproto.MessageUserStats, "UserStats", (*Server).handleUserStatsMessage)
+	registerMessageHandler(mumbleproto.MessageRequestBlob, "RequestBlob", (*Server).handleRequestBlob)
+
+	registerMessageMiddleware(messageMiddleware{after: countMessage})
+	registerAPIEndpoint("messagestats", handleAPIMessageStats)
+}
+
+// handleIncomingMessage dispatches msg to the handler for its kind,
+// running the middleware around it. Messages of kinds without a
+// handler are ignored.
+func (server *Server) handleIncomingMessage(client *Client, msg *Message) {
+	registered, ok := messageHandlers[msg.kind]
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	handled := true
+	for _, m := range messageMiddlewares {
+		if m.before != nil && !m.before(server, client, msg) {
+			handled = false
+			break
+		}
+	}
+	if handled {
+		registered.handler(server, client, msg)
+	}
+	elapsed := time.Since(start)
+	for i := len(messageMiddlewares) - 1; i >= 0; i-- {
+		if after := messageMiddlewares[i].after; after != nil {
+			after(server, client, msg, handled, elapsed)
+		}
+	}
+}
+
+// messageStat holds the statistics of one message kind.
+type messageStat struct {
+	Kind      uint16        `json:"kind"`
+	Name      string        `json:"name"`
+	Handled   uint64        `json:"handled"`
+	Dropped   uint64        `json:"dropped"`
+	Oversized uint64        `json:"oversized"`
+	Delayed   uint64        `json:"delayed"`
+	Time      time.Duration `json:"time_ns"`
+}
+
+// countMessage is the middleware that keeps the message statistics.
+func countMessage(server *Server, client *Client, msg *Message, handled bool, elapsed time.Duration) {
+	if server.messageStats == nil {
+		server.messageStats = make(map[uint16]*messageStat)
+	}
+	stat, ok := server.messageStats[msg.kind]
+	if !ok {
+		stat = &messageStat{Kind: msg.kind, Name: messageHandlers[msg.kind].name}
+		server.messageStats[msg.kind] = stat
+	}
+	if handled {
+		stat.Handled++
+	} else {
+		stat.Dropped++
+	}
+	stat.Time += elapsed
+}
+
+// handleAPIMessageStats implements /servers/<id>/messagestats.
+func handleAPIMessageStats(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	stats := []messageStat{}
+	err := server.runSync(func() {
+		server.recvStatsLock.Lock()
+		defer server.recvStatsLock.Unlock()
+		for kind, stat := range server.messageStats {
+			stat := *stat
+			stat.Oversized = server.oversized[kind]
+			stat.Delayed = server.delayed[kind]
+			stats = append(stats, stat)
+		}
+		for kind, n := range server.oversized {
+			if _, ok := server.messageStats[kind]; !ok {
+				stats = append(stats, messageStat{Kind: kind, Name: messageHandlers[kind].name, Oversized: n})
+			}
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Kind < stats[j].Kind
+	})
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,527 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the client certificate enrollment portal.
+//
+// When EnrollEnabled is set, the web listener serves a page at /enroll
+// where users can obtain a client certificate for a registration that
+// an admin has created beforehand. Users prove who they are either with
+// a one-time enrollment code (created through the admin API), or by
+// signing in with an OpenID Connect provider, in which case the claim
+// named by EnrollOIDCUserClaim must match the registration's name.
+//
+// The generated certificate and key are returned as a PKCS#12 file
+// without a password, ready to be imported into Mumble.
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"math/big"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"mumble.info/grumble/pkg/oidc"
+	"mumble.info/grumble/pkg/pkcs12"
+)
+
+// How long a user has to complete an OpenID Connect sign-in.
+const enrollOIDCTimeout = 10 * time.Minute
+
+// An enrollCode allows the holder to enroll a certificate
+// for a registered user. Codes are single-use and are not
+// persisted across restarts of Grumble.
+type enrollCode struct {
+	UserId  uint32
+	Expires time.Time
+}
+
+// oidcLogin is an OpenID Connect sign-in in progress.
+type oidcLogin struct {
+	nonce   string
+	expires time.Time
+}
+
+// enrollState holds a server's enrollment portal state.
+// It must only be accessed from the server's handler goroutine,
+// except for the provider fields, which are guarded by providerMutex.
+type enrollState struct {
+	codes  map[string]enrollCode
+	logins map[string]oidcLogin
+
+	providerMutex sync.Mutex
+	provider      *oidc.Provider
+}
+
+func newEnrollState() *enrollState {
+	return &enrollState{
+		codes:  make(map[string]enrollCode),
+		logins: make(map[string]oidcLogin),
+	}
+}
+
+var errEnrollNotAllowed = errors.New("invalid or expired enrollment code")
+
+// randomCode returns a random, case-insensitive code that is
+// reasonably easy to type.
+func randomCode() (string, error) {
+	buf := make([]byte, 10)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(buf), nil
+}
+
+// normalizeCode canonicalizes a code as typed by a user.
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+}
+
+// CreateEnrollCode creates an enrollment code for user that is valid
+// for the given duration.
+func (server *Server) CreateEnrollCode(user *User, duration time.Duration) (string, error) {
+	code, err := randomCode()
+	if err != nil {
+		return "", err
+	}
+	server.enroll.codes[code] = enrollCode{UserId: user.Id, Expires: time.Now().Add(duration)}
+	server.Printf("Created enrollment code for user %v (%v)", user.Id, user.Name)
+	return code, nil
+}
+
+// expireEnrollState drops expired codes and sign-ins.
+func (server *Server) expireEnrollState() {
+	now := time.Now()
+	for code, ec := range server.enroll.codes {
+		if now.After(ec.Expires) {
+			delete(server.enroll.codes, code)
+		}
+	}
+	for state, login := range server.enroll.logins {
+		if now.After(login.expires) {
+			delete(server.enroll.logins, state)
+		}
+	}
+}
+
+// bindUserCertificate adds cert to the certificates of user, next to
+// those of the user's other devices.
+func (server *Server) bindUserCertificate(user *User, cert *x509.Certificate) error {
+	sum := sha1.Sum(cert.Raw)
+	hash := hex.EncodeToString(sum[:])
+	if err := server.addUserCertificate(user, hash, ""); err != nil {
+		return err
+	}
+	server.certificateBound(user, hash, "enrollment")
+	return nil
+}
+
+// generateClientCert creates a self-signed client certificate for user.
+func generateClientCert(name, email string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: name,
+		},
+		NotBefore: now.Add(-300 * time.Second),
+		// Valid for 20 years, like certificates created by Mumble itself.
+		NotAfter: now.Add(24 * time.Hour * 365 * 20),
+
+		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if len(email) > 0 {
+		tmpl.EmailAddresses = []string{email}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, priv, nil
+}
+
+// oidcProvider returns the server's OpenID Connect provider, or nil
+// if none is configured. The provider is discovered on first use, and
+// again whenever its configuration changes.
+func (server *Server) oidcProvider() (*oidc.Provider, error) {
+	issuer := server.cfg.StringValue("EnrollOIDCIssuer")
+	if len(issuer) == 0 {
+		return nil, nil
+	}
+	clientID := server.cfg.StringValue("EnrollOIDCClientID")
+	secret := server.cfg.StringValue("EnrollOIDCClientSecret")
+	redirect := server.cfg.StringValue("EnrollOIDCRedirectURL")
+
+	es := server.enroll
+	es.providerMutex.Lock()
+	defer es.providerMutex.Unlock()
+
+	p := es.provider
+	if p != nil && p.Issuer == strings.TrimSuffix(issuer, "/") && p.ClientID == clientID && p.ClientSecret == secret && p.RedirectURL == redirect {
+		return p, nil
+	}
+	p, err := oidc.Discover(issuer, clientID, secret, redirect)
+	if err != nil {
+		return nil, err
+	}
+	es.provider = p
+	return p, nil
+}
+
+var enrollTmpl = template.Must(template.New("enroll").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Certificate enrollment</title>
+</head>
+<body>
+<h1>Certificate enrollment</h1>
+{{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+<p>Enter the enrollment code you were given to download your personal certificate.
+Import the downloaded file in Mumble's certificate wizard; it is not password protected.</p>
+<form method="post" action="/enroll">
+<input type="text" name="code" autocomplete="off" autofocus>
+<input type="submit" value="Download certificate">
+</form>
+{{if .OIDC}}<p>Or <a href="/enroll/oidc">sign in</a> to download your certificate.</p>{{end}}
+</body>
+</html>
+`))
+
+// registerEnrollHandlers adds the enrollment portal to mux.
+func (server *Server) registerEnrollHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/enroll", server.handleEnroll)
+	mux.HandleFunc("/enroll/oidc", server.handleEnrollOIDC)
+	mux.HandleFunc("/enroll/callback", server.handleEnrollCallback)
+}
+
+func (server *Server) enrollPage(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	enrollTmpl.Execute(w, struct {
+		Error string
+		OIDC  bool
+	}{msg, len(server.cfg.StringValue("EnrollOIDCIssuer")) > 0})
+}
+
+// handleEnroll serves the enrollment page, and handles
+// enrollment using a code.
+func (server *Server) handleEnroll(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		server.enrollPage(w, http.StatusOK, "")
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	code := normalizeCode(r.PostFormValue("code"))
+	var name, email string
+	err := server.runSync(func() {
+		ec, ok := server.enroll.codes[code]
+		if !ok || time.Now().After(ec.Expires) {
+			return
+		}
+		if user, ok := server.Users[ec.UserId]; ok {
+			name, email = user.Name, user.Email
+		}
+	})
+	if err != nil {
+		server.enrollPage(w, http.StatusServiceUnavailable, "The server is not running.")
+		return
+	}
+	if len(name) == 0 {
+		server.Printf("Enrollment attempt with invalid code from %v", r.RemoteAddr)
+		server.enrollPage(w, http.StatusForbidden, errEnrollNotAllowed.Error())
+		return
+	}
+
+	// The code is only consumed once the certificate has been generated,
+	// and it is checked again in case it was used in the meantime.
+	server.enrollCertificate(w, name, email, func(user *User) bool {
+		ec, ok := server.enroll.codes[code]
+		if !ok || ec.UserId != user.Id {
+			return false
+		}
+		delete(server.enroll.codes, code)
+		return true
+	})
+}
+
+// enrollCertificate generates a certificate for the user called name,
+// binds it to the registration if allowed returns true, and sends it
+// to the user.
+func (server *Server) enrollCertificate(w http.ResponseWriter, name, email string, allowed func(user *User) bool) {
+	// Generating a key is slow, so only do it for users who may enroll.
+	// SuperUser signs in with a password, never a certificate.
+	registered := false
+	err := server.runSync(func() {
+		user, ok := server.UserNameMap[name]
+		registered = ok && user.Id != 0
+	})
+	if err != nil {
+		server.enrollPage(w, http.StatusServiceUnavailable, "The server is not running.")
+		return
+	}
+	if !registered {
+		server.enrollPage(w, http.StatusForbidden, errEnrollNotAllowed.Error())
+		return
+	}
+
+	cert, priv, err := generateClientCert(name, email)
+	if err != nil {
+		server.Printf("Unable to generate client certificate: %v", err)
+		server.enrollPage(w, http.StatusInternalServerError, "Unable to generate certificate.")
+		return
+	}
+	pfx, err := pkcs12.Encode(cert, priv, name, "")
+	if err != nil {
+		server.Printf("Unable to encode client certificate: %v", err)
+		server.enrollPage(w, http.StatusInternalServerError, "Unable to generate certificate.")
+		return
+	}
+
+	bound := false
+	err = server.runSync(func() {
+		// The registration may have changed while the key was
+		// generated.
+		user, ok := server.UserNameMap[name]
+		if !ok || user.Id == 0 || !allowed(user) {
+			return
+		}
+		if err := server.bindUserCertificate(user, cert); err != nil {
+			server.Printf("Unable to bind enrolled certificate: %v", err)
+			return
+		}
+		bound = true
+	})
+	if err != nil {
+		server.enrollPage(w, http.StatusServiceUnavailable, "The server is not running.")
+		return
+	}
+	if !bound {
+		server.enrollPage(w, http.StatusForbidden, errEnrollNotAllowed.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pkcs12")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".p12"}))
+	w.Write(pfx)
+}
+
+// handleEnrollOIDC starts an OpenID Connect sign-in.
+func (server *Server) handleEnrollOIDC(w http.ResponseWriter, r *http.Request) {
+	provider, err := server.oidcProvider()
+	if err != nil {
+		server.Printf("Unable to contact OpenID Connect provider: %v", err)
+		server.enrollPage(w, http.StatusBadGateway, "Sign-in is currently unavailable.")
+		return
+	}
+	if provider == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := randomCode()
+	if err != nil {
+		server.enrollPage(w, http.StatusInternalServerError, "Unable to start sign-in.")
+		return
+	}
+	nonce, err := randomCode()
+	if err != nil {
+		server.enrollPage(w, http.StatusInternalServerError, "Unable to start sign-in.")
+		return
+	}
+	err = server.runSync(func() {
+		server.enroll.logins[state] = oidcLogin{nonce: nonce, expires: time.Now().Add(enrollOIDCTimeout)}
+	})
+	if err != nil {
+		server.enrollPage(w, http.StatusServiceUnavailable, "The server is not running.")
+		return
+	}
+
+	// Tie the sign-in to this browser.
+	http.SetCookie(w, &http.Cookie{
+		Name:     "grumble_enroll",
+		Value:    state,
+		Path:     "/enroll",
+		MaxAge:   int(enrollOIDCTimeout / time.Second),
+		Secure:   true,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+// handleEnrollCallback completes an OpenID Connect sign-in.
+func (server *Server) handleEnrollCallback(w http.ResponseWriter, r *http.Request) {
+	provider, err := server.oidcProvider()
+	if err != nil || provider == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	state := r.FormValue("state")
+	cookie, err := r.Cookie("grumble_enroll")
+	if err != nil || cookie.Value != state {
+		server.enrollPage(w, http.StatusForbidden, "Sign-in failed. Please try again.")
+		return
+	}
+
+	var login oidcLogin
+	found := false
+	err = server.runSync(func() {
+		login, found = server.enroll.logins[state]
+		delete(server.enroll.logins, state)
+	})
+	if err != nil {
+		server.enrollPage(w, http.StatusServiceUnavailable, "The server is not running.")
+		return
+	}
+	if !found || time.Now().After(login.expires) {
+		server.enrollPage(w, http.StatusForbidden, "Sign-in expired. Please try again.")
+		return
+	}
+
+	claims, err := provider.Exchange(r.FormValue("code"), login.nonce)
+	if err != nil {
+		server.Printf("OpenID Connect sign-in failed: %v", err)
+		server.enrollPage(w, http.StatusForbidden, "Sign-in failed. Please try again.")
+		return
+	}
+	name, _ := claims[server.cfg.StringValue("EnrollOIDCUserClaim")].(string)
+	email, _ := claims["email"].(string)
+	if len(name) == 0 {
+		server.enrollPage(w, http.StatusForbidden, "Your account has no user name.")
+		return
+	}
+
+	server.enrollCertificate(w, name, email, func(user *User) bool {
+		return true
+	})
+}
+
+// apiEnrollCode is the JSON representation of an enrollment code.
+type apiEnrollCode struct {
+	Code     string `json:"code"`
+	User     uint32 `json:"user"`
+	Expires  string `json:"expires,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+func init() {
+	registerAPIEndpoint("enrollments", handleAPIEnrollments)
+}
+
+// handleAPIEnrollments implements /servers/<id>/enrollments.
+//
+//	GET                  lists the unused enrollment codes
+//	POST                 creates a code: {"user": 3, "duration": "24h"}
+//	DELETE /<code>       revokes a code
+func handleAPIEnrollments(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var req apiEnrollCode
+	var duration time.Duration
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !readJSON(w, r, &req) {
+			return
+		}
+		var err error
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			apiError(w, http.StatusBadRequest, "invalid duration")
+			return
+		}
+	case http.MethodDelete:
+		if len(args) != 1 {
+			apiError(w, http.StatusNotFound, "expected /enrollments/<code>")
+			return
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		switch r.Method {
+		case http.MethodPost:
+			user, ok := server.Users[req.User]
+			if !ok {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such user"}
+				return
+			}
+			if user.Id == 0 {
+				status, reply = http.StatusBadRequest, map[string]string{"error": "SuperUser signs in with a password"}
+				return
+			}
+			code, err := server.CreateEnrollCode(user, duration)
+			if err != nil {
+				status, reply = http.StatusInternalServerError, map[string]string{"error": err.Error()}
+				return
+			}
+			ec := server.enroll.codes[code]
+			status, reply = http.StatusCreated, apiEnrollCode{
+				Code:    code,
+				User:    ec.UserId,
+				Expires: ec.Expires.UTC().Format(time.RFC3339),
+			}
+			return
+		case http.MethodDelete:
+			code := normalizeCode(args[0])
+			if _, ok := server.enroll.codes[code]; !ok {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such code"}
+				return
+			}
+			delete(server.enroll.codes, code)
+		}
+
+		server.expireEnrollState()
+		codes := []apiEnrollCode{}
+		for code, ec := range server.enroll.codes {
+			codes = append(codes, apiEnrollCode{
+				Code:    code,
+				User:    ec.UserId,
+				Expires: ec.Expires.UTC().Format(time.RFC3339),
+			})
+		}
+		reply = codes
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
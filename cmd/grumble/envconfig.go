@@ -0,0 +1,161 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the configuration through environment
+// variables, for containerized deployments.
+//
+// Command line arguments can be given as GRUMBLE_<ARGUMENT>, such as
+// GRUMBLE_DATADIR or GRUMBLE_API_ADDR, except those that perform a
+// one-off task and exit. Arguments on the command line take precedence.
+// Configuration keys can be given as GRUMBLE_<KEY>, such as
+// GRUMBLE_MAX_USERS, or GRUMBLE_SERVER<ID>_<KEY> for a single virtual
+// server, and take precedence over the configuration file (see
+// serverconf.ConfigFile.AddEnv). Underscores and case don't matter in
+// the names of arguments and keys.
+//
+// The effective arguments and configuration are logged at startup,
+// along with where each value was set, with passwords and other
+// secrets left out.
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"mumble.info/grumble/pkg/serverconf"
+)
+
+// The arguments that can't be given in the environment, since they
+// perform a one-off task instead of Grumble running as usual.
+var oneOffArgs = map[string]bool{
+	"help":             true,
+	"regen-keys":       true,
+	"setsuperuserpw":   true,
+	"migrate":          true,
+	"migrate-to":       true,
+	"verify-blobstore": true,
+	"quarantine-blobs": true,
+	"restore-backup":   true,
+	"import-murmurdb":  true,
+	"cleanup":          true,
+}
+
+// argSources maps the names of the arguments that were set to where
+// they were set: "command line" or the name of a variable.
+var argSources = map[string]string{}
+
+// applyEnvArgs sets the arguments that weren't given on the command
+// line from their GRUMBLE_ variables. It must be called after
+// flag.Parse.
+func applyEnvArgs() error {
+	flag.Visit(func(f *flag.Flag) {
+		argSources[f.Name] = "command line"
+	})
+
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		eq := strings.Index(kv, "=")
+		if eq != -1 && strings.HasPrefix(kv[:eq], serverconf.EnvPrefix) {
+			env[serverconf.NormalizeEnvName(kv[len(serverconf.EnvPrefix):eq])] = kv[:eq]
+		}
+	}
+
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		name, ok := env[serverconf.NormalizeEnvName(f.Name)]
+		if !ok || err != nil || oneOffArgs[f.Name] {
+			return
+		}
+		if _, set := argSources[f.Name]; set {
+			return
+		}
+		if serr := flag.Set(f.Name, os.Getenv(name)); serr != nil {
+			err = fmt.Errorf("%v: %v", name, serr)
+			return
+		}
+		argSources[f.Name] = name
+	})
+	return err
+}
+
+// isEnvArg checks whether the variable called name sets an argument,
+// or is used by Grumble otherwise.
+func isEnvArg(name string) bool {
+	if name == handoverEnv {
+		return true
+	}
+	normalized := serverconf.NormalizeEnvName(name[len(serverconf.EnvPrefix):])
+	found := false
+	flag.VisitAll(func(f *flag.Flag) {
+		if !oneOffArgs[f.Name] && serverconf.NormalizeEnvName(f.Name) == normalized {
+			found = true
+		}
+	})
+	return found
+}
+
+// addEnvConfig adds the configuration values set in the environment to
+// cf, and warns about GRUMBLE_ variables that Grumble doesn't use.
+func addEnvConfig(cf *serverconf.ConfigFile) error {
+	for _, name := range cf.AddEnv(os.Environ()) {
+		if !isEnvArg(name) {
+			log.Printf("Ignoring unknown environment variable %v", name)
+		}
+	}
+	return cf.Validate()
+}
+
+// logValue formats the value of a configuration key for the log.
+func logValue(key, value string) string {
+	if serverconf.IsSecret(key) && len(value) > 0 {
+		return "<redacted>"
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+// logEffectiveConfig logs the arguments and each server's configuration
+// values that differ from the defaults, and where they were set.
+func logEffectiveConfig(cf *serverconf.ConfigFile) {
+	log.Printf("Effective configuration:")
+	flag.VisitAll(func(f *flag.Flag) {
+		if source, ok := argSources[f.Name]; ok {
+			log.Printf("  --%v=%q (%v)", f.Name, f.Value.String(), source)
+		}
+	})
+
+	ids := []int64{}
+	for id := range servers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		server := servers[id]
+		values := cf.ValuesForServer(id)
+		keys := []string{}
+		for key := range values {
+			keys = append(keys, key)
+		}
+		for key := range server.cfg.GetAll() {
+			if _, ok := values[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			source := "set on the server"
+			if !server.cfg.IsSet(key) {
+				source = cf.Source(id, key)
+				if !strings.HasPrefix(source, serverconf.EnvPrefix) {
+					source = configFilePath() + ", " + source
+				}
+			}
+			log.Printf("  [server %v] %v = %v (%v)", id, key, logValue(key, server.cfg.StringValue(key)), source)
+		}
+	}
+}
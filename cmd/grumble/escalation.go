@@ -0,0 +1,237 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file applies the escalation rules (see pkg/escalation) to
+// repeat offenders.
+//
+// The rules file is named by the EscalationRules configuration key, and
+// is re-read whenever the configuration is reloaded. Whenever a user is
+// kicked, banned or muted, their recent offenses of that kind are
+// counted, by the name they are shown under, and the punishment of a
+// matching rule is applied. The offenses are read from the audit log
+// when the rules are loaded, and kept in memory as they are audited. It is recorded in the audit log with
+// "escalation" as the actor, and reported to the users allowed to kick
+// in the root channel. Punishments count as offenses too, so a ban
+// given by one rule may lead to a harsher ban by another.
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/escalation"
+)
+
+// loadEscalationPolicy reads the rules file named by the
+// EscalationRules key. Relative paths are relative to the data
+// directory. If no file is configured, it returns a nil policy.
+func (server *Server) loadEscalationPolicy() (*escalation.Policy, error) {
+	fn := server.cfg.StringValue("EscalationRules")
+	if len(fn) == 0 {
+		return nil, nil
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	return escalation.Load(fn)
+}
+
+// setEscalationPolicy applies policy, and reads the offenses it counts
+// from the audit log.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) setEscalationPolicy(policy *escalation.Policy) {
+	server.escalationPolicy = policy
+	var offenses *offenseIndex
+	if policy != nil {
+		offenses = newOffenseIndex(policy)
+		l, err := server.auditLog()
+		var entries []auditlog.Entry
+		if err == nil {
+			entries, err = l.Query(auditlog.Query{Since: time.Now().Add(-policy.MaxWindow())})
+		}
+		if err != nil {
+			server.Printf("Unable to read offenses from audit log: %v", err)
+		}
+		for _, entry := range entries {
+			offenses.add(entry.Target, entry.Action, entry.Time)
+		}
+	}
+	server.auditLock.Lock()
+	server.offenses = offenses
+	server.auditLock.Unlock()
+}
+
+// An offenseIndex holds the times of recent offenses, by the name of
+// the user they were given to and their kind. It only keeps the
+// offenses counted by a policy, for as long as its rules count them.
+type offenseIndex struct {
+	lock    sync.Mutex
+	windows map[string]time.Duration
+	times   map[string]map[string][]time.Time
+}
+
+func newOffenseIndex(policy *escalation.Policy) *offenseIndex {
+	idx := &offenseIndex{
+		windows: make(map[string]time.Duration),
+		times:   make(map[string]map[string][]time.Time),
+	}
+	for _, rule := range policy.Rules {
+		idx.windows[rule.Offense] = policy.Window(rule.Offense)
+	}
+	return idx
+}
+
+// add records that the user shown as name was given offense at t.
+// Actions that aren't counted offenses are ignored.
+func (idx *offenseIndex) add(name, offense string, t time.Time) {
+	window, ok := idx.windows[offense]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.expire(now)
+	if now.Sub(t) >= window {
+		return
+	}
+	key := strings.ToLower(name)
+	if idx.times[key] == nil {
+		idx.times[key] = make(map[string][]time.Time)
+	}
+	idx.times[key][offense] = append(idx.times[key][offense], t)
+}
+
+// expire drops the offenses that no rule counts anymore. The lock must
+// be held.
+func (idx *offenseIndex) expire(now time.Time) {
+	for name, byOffense := range idx.times {
+		for offense, times := range byOffense {
+			kept := times[:0]
+			for _, t := range times {
+				if now.Sub(t) < idx.windows[offense] {
+					kept = append(kept, t)
+				}
+			}
+			if len(kept) == 0 {
+				delete(byOffense, offense)
+			} else {
+				byOffense[offense] = kept
+			}
+		}
+		if len(byOffense) == 0 {
+			delete(idx.times, name)
+		}
+	}
+}
+
+// since returns the times of the offenses of the user shown as name
+// since t.
+func (idx *offenseIndex) since(name, offense string, t time.Time) []time.Time {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	times := []time.Time{}
+	for _, at := range idx.times[strings.ToLower(name)][offense] {
+		if !at.Before(t) {
+			times = append(times, at)
+		}
+	}
+	return times
+}
+
+// notifyModerators sends text to the users allowed to kick in the
+// root channel.
+func (server *Server) notifyModerators(text string) {
+	root := server.RootChannel()
+	for _, client := range server.clients {
+		if client.state == StateClientReady && acl.HasPermission(&root.ACL, client, acl.KickPermission) {
+			server.sendServerText(client, text)
+		}
+	}
+}
+
+// escalate applies the escalation rules after client was given an
+// offense ("kick", "ban" or "mute"), which must already be in the
+// audit log. Each rule is applied at most once.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) escalate(client *Client, offense string) {
+	policy := server.escalationPolicy
+	if policy == nil {
+		return
+	}
+	applied := make(map[*escalation.Rule]bool)
+	for {
+		rule := server.checkEscalation(policy, client.ShownName(), offense)
+		if rule == nil || applied[rule] || !server.punish(client, rule) {
+			return
+		}
+		applied[rule] = true
+		offense = rule.Punishment.String()
+	}
+}
+
+// checkEscalation returns the rule that applies to the user shown as
+// name after an offense, or nil if none does.
+func (server *Server) checkEscalation(policy *escalation.Policy, name, offense string) *escalation.Rule {
+	window := policy.Window(offense)
+	if window == 0 || server.offenses == nil {
+		return nil
+	}
+	now := time.Now()
+	return policy.Check(offense, server.offenses.since(name, offense, now.Add(-window)), now)
+}
+
+// punish gives client the punishment of rule. It returns false if
+// there was nothing to do: a mute isn't given to a client that has
+// left, nor to one that is muted for longer already.
+func (server *Server) punish(client *Client, rule *escalation.Rule) bool {
+	reason := rule.Describe()
+	span := "for good"
+	if rule.Duration > 0 {
+		span = fmt.Sprintf("for %v", rule.Duration)
+	}
+
+	var done string
+	switch rule.Punishment {
+	case escalation.Ban:
+		server.banClient(client, reason, uint32(rule.Duration/time.Second))
+		if !client.disconnected {
+			if err := server.broadcastUserRemove(client, nil, true, reason); err != nil {
+				server.Printf("Unable to broadcast UserRemove: %v", err)
+			}
+			client.ForceDisconnect()
+		}
+		done = "banned"
+	case escalation.Mute:
+		if client.disconnected {
+			return false
+		}
+		if client.Mute {
+			expires := time.Now().Add(rule.Duration)
+			if client.muteExpires.IsZero() || (rule.Duration > 0 && client.muteExpires.After(expires)) {
+				return false
+			}
+		}
+		server.timedMute(client, client.Deaf, rule.Duration)
+		server.sendServerText(client, fmt.Sprintf("You have been muted %v: %v.", span, reason))
+		done = "muted"
+	}
+
+	entry := auditlog.Entry{Action: rule.Punishment.String(), Actor: "escalation", Target: client.ShownName(), Reason: reason}
+	if rule.Duration > 0 {
+		entry.Details = span
+	}
+	server.audit(nil, entry)
+	server.notifyModerators(fmt.Sprintf("%v was %v %v (%v).", html.EscapeString(client.ShownName()), done, span, reason))
+	return true
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the queue of server events delivered to Lua
+// scripts, plugins and the MQTT publisher.
+//
+// Events are queued where they happen, and delivered from the server's
+// handler goroutine once the action that caused them has completed.
+
+import (
+	"mumble.info/grumble/pkg/plugin"
+)
+
+// The number of events that may be queued.
+const eventQueueSize = 256
+
+// pluginUser returns the plugins' view of client.
+func pluginUser(client *Client) plugin.User {
+	user := plugin.User{
+		Session:  client.Session(),
+		Name:     client.ShownName(),
+		UserId:   client.UserId(),
+		Channel:  -1,
+		CertHash: client.CertHash(),
+	}
+	if client.Channel != nil {
+		user.Channel = client.Channel.Id
+	}
+	if client.tcpaddr != nil {
+		user.Address = client.tcpaddr.IP
+	}
+	return user
+}
+
+// emitEvent queues an event for delivery to the server's scripts,
+// plugins and MQTT publisher. Nothing is queued if there is no one to
+// deliver it to.
+func (server *Server) emitEvent(ev plugin.Event) {
+	if len(server.cfg.StringValue("Scripts")) == 0 && len(server.plugins) == 0 && server.mqtt == nil {
+		return
+	}
+	select {
+	case server.events <- ev:
+	default:
+		server.Printf("Event queue full, dropped %v event", ev.Type)
+	}
+}
+
+// dispatchEvent delivers a queued event.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) dispatchEvent(ev plugin.Event) {
+	server.dispatchScriptEvent(ev)
+	server.publishPresenceEvent(ev)
+	for _, p := range server.plugins {
+		for _, h := range p.eventHandlers {
+			h.HandleEvent(ev)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file decides which protocol features each client supports (see
+// pkg/features), and which clients are turned away.
+//
+// The features are worked out when a client sends its version, from
+// the version and the release string it reports, and the overrides file
+// named by the ClientFeatures configuration key. Clients listed in the
+// denylist file named by ClientDenylist are rejected at that point,
+// with the message of their line or else ClientDenylistMessage. Both
+// files are re-read whenever the configuration is reloaded, and apply
+// to clients that connect afterwards.
+
+import (
+	"path/filepath"
+
+	"mumble.info/grumble/pkg/features"
+)
+
+// loadFeatureOverrides reads the overrides file named by the
+// ClientFeatures key. Relative paths are relative to the data
+// directory. If no file is configured, it returns nil overrides.
+func (server *Server) loadFeatureOverrides() (*features.Overrides, error) {
+	fn := server.cfg.StringValue("ClientFeatures")
+	if len(fn) == 0 {
+		return nil, nil
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	return features.Load(fn)
+}
+
+// loadClientDenylist reads the denylist file named by the
+// ClientDenylist key. Relative paths are relative to the data
+// directory. If no file is configured, it returns a nil denylist.
+func (server *Server) loadClientDenylist() (*features.Denylist, error) {
+	fn := server.cfg.StringValue("ClientDenylist")
+	if len(fn) == 0 {
+		return nil, nil
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	return features.LoadDenylist(fn)
+}
+
+// setFeatureOverrides replaces the server's client feature overrides.
+func (server *Server) setFeatureOverrides(overrides *features.Overrides) {
+	server.featureLock.Lock()
+	server.featureOverrides = overrides
+	server.featureLock.Unlock()
+}
+
+// setClientDenylist replaces the server's client denylist.
+func (server *Server) setClientDenylist(denylist *features.Denylist) {
+	server.featureLock.Lock()
+	server.clientDenylist = denylist
+	server.featureLock.Unlock()
+}
+
+// deniedClient checks whether client is listed in the denylist, and
+// returns the message to reject it with.
+func (server *Server) deniedClient(client *Client) (string, bool) {
+	server.featureLock.Lock()
+	denylist := server.clientDenylist
+	server.featureLock.Unlock()
+
+	entry := denylist.Check(client.Version, client.ClientName)
+	if entry == nil {
+		return "", false
+	}
+	client.Printf("Client %q (%#x) is denied by line %v of the denylist", client.ClientName, client.Version, entry.Line)
+	if len(entry.Message) > 0 {
+		return entry.Message, true
+	}
+	return server.cfg.StringValue("ClientDenylistMessage"), true
+}
+
+// clientFeatures works out the features client supports from the
+// version it sent.
+func (server *Server) clientFeatures(client *Client) features.Set {
+	server.featureLock.Lock()
+	overrides := server.featureOverrides
+	server.featureLock.Unlock()
+
+	set := overrides.Resolve(client.Version, client.ClientName)
+	if set != features.ForVersion(client.Version) {
+		client.Printf("Feature overrides apply to %q (%#x): %v", client.ClientName, client.Version, set.Names())
+	}
+	return set
+}
+
+// supports checks whether the client supports a protocol feature.
+func (client *Client) supports(feature features.Feature) bool {
+	return client.features.Has(feature)
+}
@@ -0,0 +1,742 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the experimental channel federation, which links
+// channels of different Grumble servers (see pkg/federation).
+//
+// The links are read from the file named by the FederationLinks key.
+// Servers that other servers connect to listen on FederationAddress.
+// Both servers of a link authenticate each other by the fingerprints of
+// their certificates, which are logged when federation starts.
+//
+// The users of a linked channel are shown in the channel it is linked
+// to, under their name and that of their server, as "Name@Server". They
+// are checked every second, so that joins and leaves are passed on
+// within a second. Voice sent to a linked channel, and text written to
+// it, is relayed to the users in the channel it is linked to. Whispers
+// and shouts aren't relayed. A link that breaks is made again every
+// 10 seconds by the server that connects.
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/federation"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/packetdata"
+)
+
+const (
+	// The number of frames that may wait to be sent to a peer.
+	federationQueueSize = 256
+	// How long to wait before connecting to a peer again.
+	federationRetryInterval = 10 * time.Second
+	// The time a peer has to complete the handshake, or to take a
+	// frame.
+	federationTimeout = 10 * time.Second
+	// The most users of a peer shown in a linked channel.
+	federationMaxUsers = 256
+	// The longest name of a peer or a peer's user, in characters.
+	federationMaxName = 64
+)
+
+// Config keys of channel federation.
+var federationConfigKeys = []string{"FederationAddress", "FederationLinks"}
+
+// A federationHub holds the links of a server.
+type federationHub struct {
+	server   *Server
+	cert     tls.Certificate
+	links    []*federationLink
+	listener net.Listener
+	done     chan bool
+}
+
+// A federationLink links a local channel to a channel of a peer.
+type federationLink struct {
+	federation.Link
+	hub *federationHub
+
+	// The connection to the peer, while the link is up.
+	conn     *federationConn
+	peerName string
+	// The local users announced to the peer, and their names.
+	announced map[uint32]string
+	// The peer's users, by their id on the peer.
+	remote map[uint32]*remoteUser
+}
+
+// A remoteUser is a peer's user shown in a linked channel.
+type remoteUser struct {
+	session uint32
+	name    string
+}
+
+// A federationConn is the connection of a link.
+type federationConn struct {
+	net.Conn
+	outgoing  chan federationFrame
+	closed    chan bool
+	closeOnce sync.Once
+}
+
+type federationFrame struct {
+	typ     byte
+	payload []byte
+}
+
+// startFederation loads the federation links and starts making them,
+// if they are configured.
+//
+// Must be called from the server's handler goroutine, or before it
+// is started.
+func (server *Server) startFederation() {
+	fn := server.cfg.StringValue("FederationLinks")
+	if len(fn) == 0 {
+		return
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	links, err := federation.Load(fn)
+	if err != nil {
+		server.Printf("federation: unable to load links: %v", err)
+		return
+	}
+
+	hub := &federationHub{
+		server: server,
+		cert:   server.tlscfg.Certificates[0],
+		done:   make(chan bool),
+	}
+	for _, link := range links {
+		hub.links = append(hub.links, &federationLink{Link: link, hub: hub})
+	}
+	if addr := server.cfg.StringValue("FederationAddress"); len(addr) > 0 {
+		tcpaddr, err := net.ResolveTCPAddr("tcp", addr)
+		if err == nil {
+			hub.listener, err = listenTCP(tcpaddr)
+		}
+		if err != nil {
+			server.Printf("federation: unable to listen on %v: %v", addr, err)
+			return
+		}
+		go hub.acceptLoop()
+	}
+	server.federation = hub
+	for _, link := range hub.links {
+		if len(link.Peer) > 0 {
+			go link.dialLoop()
+		}
+	}
+
+	if leaf, err := x509.ParseCertificate(hub.cert.Certificate[0]); err == nil {
+		server.Printf("federation: %v links, certificate fingerprint %v", len(links), federation.Fingerprint(leaf))
+	}
+}
+
+// stopFederation breaks the federation links, if there are any.
+//
+// Must be called from the server's handler goroutine, or after it
+// has stopped.
+func (server *Server) stopFederation() {
+	hub := server.federation
+	if hub == nil {
+		return
+	}
+	close(hub.done)
+	if hub.listener != nil {
+		hub.listener.Close()
+	}
+	for _, link := range hub.links {
+		if link.conn != nil {
+			link.detach(link.conn)
+		}
+	}
+	server.federation = nil
+}
+
+// tlsConfig returns the TLS configuration of the hub's connections.
+func (hub *federationHub) tlsConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{hub.cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// Peers are checked against the fingerprints of the links
+		// instead.
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+	}
+}
+
+// peerFingerprint returns the fingerprint of the certificate the peer
+// of conn presented, or "" if there is none.
+func peerFingerprint(conn *tls.Conn) string {
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return ""
+	}
+	return federation.Fingerprint(certs[0])
+}
+
+// hello returns the Hello sent over link.
+func (link *federationLink) hello() federation.Hello {
+	return federation.Hello{
+		Version:     federation.Version,
+		Name:        link.hub.server.cfg.StringValue("RegisterName"),
+		Channel:     link.Channel,
+		PeerChannel: link.RemoteChannel,
+	}
+}
+
+// readHello reads the peer's Hello from conn, and checks that it
+// matches link, if given.
+func readHello(conn net.Conn, link *federationLink) (federation.Hello, error) {
+	hello := federation.Hello{}
+	typ, payload, err := federation.ReadFrame(conn)
+	if err != nil {
+		return hello, err
+	}
+	if typ != federation.FrameHello {
+		return hello, errors.New("expected hello")
+	}
+	if err := json.Unmarshal(payload, &hello); err != nil {
+		return hello, err
+	}
+	if hello.Version != federation.Version {
+		return hello, fmt.Errorf("unsupported protocol version %v", hello.Version)
+	}
+	if link != nil && (hello.Channel != link.RemoteChannel || hello.PeerChannel != link.Channel) {
+		return hello, fmt.Errorf("peer links channel %v to %v, expected %v to %v", hello.Channel, hello.PeerChannel, link.RemoteChannel, link.Channel)
+	}
+	return hello, nil
+}
+
+// dialLoop connects to the peer of link, and again whenever the
+// connection breaks, until federation is stopped.
+func (link *federationLink) dialLoop() {
+	hub := link.hub
+	for {
+		if err := link.dial(); err != nil {
+			hub.server.Printf("federation: link of channel %v to %v: %v", link.Channel, link.Peer, err)
+		}
+		select {
+		case <-hub.done:
+			return
+		case <-time.After(federationRetryInterval):
+		}
+	}
+}
+
+// dial connects to the peer of link, and serves the connection until
+// it breaks.
+func (link *federationLink) dial() error {
+	dialer := &net.Dialer{Timeout: federationTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", link.Peer, link.hub.tlsConfig())
+	if err != nil {
+		return err
+	}
+	if fp := peerFingerprint(conn); fp != link.Fingerprint {
+		conn.Close()
+		return fmt.Errorf("unexpected certificate fingerprint %v", fp)
+	}
+
+	conn.SetDeadline(time.Now().Add(federationTimeout))
+	err = federation.WriteJSON(conn, federation.FrameHello, link.hello())
+	var hello federation.Hello
+	if err == nil {
+		hello, err = readHello(conn, link)
+	}
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+
+	peerName := hello.Name
+	if len(peerName) == 0 {
+		peerName, _, _ = net.SplitHostPort(link.Peer)
+	}
+	return link.hub.serve(link, conn, peerName)
+}
+
+// acceptLoop accepts connections from peers until federation is
+// stopped.
+func (hub *federationHub) acceptLoop() {
+	for {
+		conn, err := hub.listener.Accept()
+		if err != nil {
+			select {
+			case <-hub.done:
+				return
+			default:
+			}
+			hub.server.Printf("federation: unable to accept connection: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		go hub.accept(tls.Server(conn, hub.tlsConfig()))
+	}
+}
+
+// accept finds the link a peer connected for, and serves the
+// connection until it breaks.
+func (hub *federationHub) accept(conn *tls.Conn) {
+	conn.SetDeadline(time.Now().Add(federationTimeout))
+	hello, err := readHello(conn, nil)
+	if err != nil {
+		hub.server.Printf("federation: rejected %v: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	fp := peerFingerprint(conn)
+	var link *federationLink
+	for _, l := range hub.links {
+		if len(l.Peer) == 0 && l.Fingerprint == fp && l.Channel == hello.PeerChannel && l.RemoteChannel == hello.Channel {
+			link = l
+			break
+		}
+	}
+	if link == nil {
+		hub.server.Printf("federation: rejected %v: no link of channel %v to %v for certificate %v", conn.RemoteAddr(), hello.PeerChannel, hello.Channel, fp)
+		conn.Close()
+		return
+	}
+	if err := federation.WriteJSON(conn, federation.FrameHello, link.hello()); err != nil {
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	peerName := hello.Name
+	if len(peerName) == 0 {
+		peerName, _, _ = net.SplitHostPort(conn.RemoteAddr().String())
+	}
+	if err := hub.serve(link, conn, peerName); err != nil {
+		hub.server.Printf("federation: link of channel %v to %v: %v", link.Channel, conn.RemoteAddr(), err)
+	}
+}
+
+// sync runs fn on the server's handler goroutine, unless federation
+// was stopped or restarted. It returns false if fn wasn't queued.
+func (hub *federationHub) sync(fn func()) bool {
+	call := func() {
+		// The hub may have been replaced while the call was queued.
+		if hub.server.federation == hub {
+			fn()
+		}
+	}
+	select {
+	case hub.server.syncCalls <- call:
+		return true
+	case <-hub.done:
+		return false
+	}
+}
+
+// serve attaches conn to link, and passes the frames the peer sends
+// to the handler goroutine until the connection breaks.
+func (hub *federationHub) serve(link *federationLink, conn net.Conn, peerName string) error {
+	fc := &federationConn{
+		Conn:     conn,
+		outgoing: make(chan federationFrame, federationQueueSize),
+		closed:   make(chan bool),
+	}
+	defer fc.close()
+	if !hub.sync(func() { link.attach(fc, peerName) }) {
+		return nil
+	}
+	go fc.writeLoop()
+
+	for {
+		typ, payload, err := federation.ReadFrame(fc)
+		if err != nil {
+			hub.sync(func() { link.detach(fc) })
+			select {
+			case <-fc.closed:
+				return nil
+			default:
+				return err
+			}
+		}
+		if !hub.sync(func() { link.handleFrame(fc, typ, payload) }) {
+			return nil
+		}
+	}
+}
+
+// writeLoop writes the queued frames to the peer, until the connection
+// is closed.
+func (fc *federationConn) writeLoop() {
+	for {
+		select {
+		case <-fc.closed:
+			return
+		case f := <-fc.outgoing:
+			fc.SetWriteDeadline(time.Now().Add(federationTimeout))
+			if err := federation.WriteFrame(fc, f.typ, f.payload); err != nil {
+				fc.close()
+				return
+			}
+		}
+	}
+}
+
+// close closes the connection. It may be called more than once.
+func (fc *federationConn) close() {
+	fc.closeOnce.Do(func() {
+		close(fc.closed)
+		fc.Conn.Close()
+	})
+}
+
+// send queues a frame for the peer. Voice frames are dropped if the
+// queue is full. Since the peer's view of the channel would go out of
+// sync if other frames were, the connection is closed instead, and is
+// made again.
+func (fc *federationConn) send(typ byte, payload []byte) {
+	select {
+	case fc.outgoing <- federationFrame{typ: typ, payload: payload}:
+	default:
+		if typ != federation.FrameVoice {
+			fc.close()
+		}
+	}
+}
+
+// sendJSON queues a frame with v as its payload.
+func (fc *federationConn) sendJSON(typ byte, v interface{}) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fc.send(typ, payload)
+}
+
+// trimName shortens and cleans a name sent by a peer.
+func trimName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if runes := []rune(name); len(runes) > federationMaxName {
+		name = string(runes[:federationMaxName])
+	}
+	return name
+}
+
+// attach makes fc the connection of link, replacing the connection
+// made before, if any.
+//
+// Must be called from the server's handler goroutine.
+func (link *federationLink) attach(fc *federationConn, peerName string) {
+	if link.conn != nil {
+		link.detach(link.conn)
+	}
+	link.conn = fc
+	link.peerName = trimName(peerName)
+	if len(link.peerName) == 0 {
+		link.peerName = "remote"
+	}
+	link.announced = make(map[uint32]string)
+	link.remote = make(map[uint32]*remoteUser)
+	link.hub.server.Printf("federation: linked channel %v to channel %v of %v", link.Channel, link.RemoteChannel, link.peerName)
+	link.hub.server.syncFederatedUsers(link)
+}
+
+// detach closes fc and removes the peer's users, if fc is the
+// connection of link.
+//
+// Must be called from the server's handler goroutine.
+func (link *federationLink) detach(fc *federationConn) {
+	fc.close()
+	if link.conn != fc {
+		return
+	}
+	server := link.hub.server
+	for id := range link.remote {
+		server.removeRemoteUser(link, id)
+	}
+	link.conn = nil
+	link.announced = nil
+	server.Printf("federation: unlinked channel %v from channel %v of %v", link.Channel, link.RemoteChannel, link.peerName)
+}
+
+// handleFrame handles a frame the peer of link sent over fc.
+//
+// Must be called from the server's handler goroutine.
+func (link *federationLink) handleFrame(fc *federationConn, typ byte, payload []byte) {
+	if link.conn != fc {
+		return
+	}
+	server := link.hub.server
+	switch typ {
+	case federation.FrameJoin:
+		user := federation.User{}
+		if json.Unmarshal(payload, &user) == nil {
+			server.addRemoteUser(link, user)
+		}
+	case federation.FrameLeave:
+		user := federation.User{}
+		if json.Unmarshal(payload, &user) == nil {
+			server.removeRemoteUser(link, user.Id)
+		}
+	case federation.FrameText:
+		text := federation.Text{}
+		if json.Unmarshal(payload, &text) == nil {
+			server.deliverFederatedText(link, text)
+		}
+	case federation.FrameVoice:
+		id, packet, err := federation.ParseVoicePayload(payload)
+		if err == nil {
+			server.deliverFederatedVoice(link, id, packet)
+		}
+	}
+}
+
+// remoteUserState returns the UserState of a peer's user.
+func remoteUserState(link *federationLink, user *remoteUser) *mumbleproto.UserState {
+	return &mumbleproto.UserState{
+		Session:   proto.Uint32(user.session),
+		Name:      proto.String(user.name),
+		ChannelId: proto.Uint32(uint32(link.Channel)),
+	}
+}
+
+// addRemoteUser shows a peer's user in the linked channel.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) addRemoteUser(link *federationLink, user federation.User) {
+	channel, ok := server.Channels[link.Channel]
+	if !ok || len(link.remote) >= federationMaxUsers {
+		return
+	}
+	if _, exists := link.remote[user.Id]; exists {
+		server.removeRemoteUser(link, user.Id)
+	}
+	name := trimName(user.Name)
+	if len(name) == 0 {
+		name = "?"
+	}
+	ru := &remoteUser{
+		session: server.pool.Get(),
+		name:    name + "@" + link.peerName,
+	}
+	link.remote[user.Id] = ru
+
+	userstate := remoteUserState(link, ru)
+	server.flushPresence()
+	for _, client := range server.clients {
+		if client.state == StateClientReady && channelSynced(channel, client.syncSuppressed()) {
+			client.sendMessage(client.userStateFor(userstate))
+		}
+	}
+}
+
+// removeRemoteUser removes a peer's user from the linked channel.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) removeRemoteUser(link *federationLink, id uint32) {
+	ru, ok := link.remote[id]
+	if !ok {
+		return
+	}
+	delete(link.remote, id)
+	server.broadcastProtoMessageWithPredicate(&mumbleproto.UserRemove{
+		Session: proto.Uint32(ru.session),
+	}, func(client *Client) bool {
+		return client.state == StateClientReady
+	})
+	server.pool.Reclaim(ru.session)
+}
+
+// remoteUserStates returns the UserStates of the peers' users shown in
+// the channels that aren't suppressed, for the user list.
+func (server *Server) remoteUserStates(suppressed map[int]bool) []*mumbleproto.UserState {
+	if server.federation == nil {
+		return nil
+	}
+	userstates := []*mumbleproto.UserState{}
+	for _, link := range server.federation.links {
+		channel, ok := server.Channels[link.Channel]
+		if !ok || !channelSynced(channel, suppressed) {
+			continue
+		}
+		for _, ru := range link.remote {
+			userstates = append(userstates, remoteUserState(link, ru))
+		}
+	}
+	return userstates
+}
+
+// checkFederation tells the peers about the users who joined or left
+// their linked channels.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) checkFederation() {
+	if server.federation == nil {
+		return
+	}
+	for _, link := range server.federation.links {
+		server.syncFederatedUsers(link)
+	}
+}
+
+// syncFederatedUsers tells the peer of link about the users who joined
+// or left the linked channel, or were renamed, since it was last told.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) syncFederatedUsers(link *federationLink) {
+	if link.conn == nil {
+		return
+	}
+	current := make(map[uint32]string)
+	if channel, ok := server.Channels[link.Channel]; ok {
+		for _, client := range channel.clients {
+			if client.state == StateClientReady {
+				current[client.Session()] = client.ShownName()
+			}
+		}
+	}
+	for session, name := range link.announced {
+		if current[session] != name {
+			link.conn.sendJSON(federation.FrameLeave, federation.User{Id: session})
+			delete(link.announced, session)
+		}
+	}
+	for session, name := range current {
+		if _, ok := link.announced[session]; !ok {
+			link.conn.sendJSON(federation.FrameJoin, federation.User{Id: session, Name: name})
+			link.announced[session] = name
+		}
+	}
+}
+
+// relayVoice passes a voice packet sent to the talker's current channel
+// on to the peers of its links.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) relayVoice(vb *VoiceBroadcast) {
+	if server.federation == nil {
+		return
+	}
+	var payload []byte
+	for _, link := range server.federation.links {
+		if link.conn == nil || link.Channel != vb.client.Channel.Id {
+			continue
+		}
+		if payload == nil {
+			// Take the talker's session out of the packet.
+			pds := packetdata.New(vb.buf[1:])
+			pds.GetUint32()
+			if !pds.IsValid() {
+				return
+			}
+			packet := append([]byte{vb.buf[0]}, vb.buf[1+pds.Size():]...)
+			payload = federation.VoicePayload(vb.client.Session(), packet)
+		}
+		link.conn.send(federation.FrameVoice, payload)
+	}
+}
+
+// deliverFederatedVoice sends a voice packet of a peer's user to the
+// users in the linked channel.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) deliverFederatedVoice(link *federationLink, id uint32, packet []byte) {
+	ru, ok := link.remote[id]
+	channel, chok := server.Channels[link.Channel]
+	if !ok || !chok || channel.NoVoice {
+		return
+	}
+	outbuf := make([]byte, 1024)
+	outgoing := packetdata.New(outbuf[1:])
+	outgoing.PutUint32(ru.session)
+	outgoing.PutBytes(packet[1:])
+	if !outgoing.IsValid() {
+		return
+	}
+	outbuf[0] = packet[0] & 0xe0
+	buf := outbuf[0 : 1+outgoing.Size()]
+	for _, client := range channel.clients {
+		if client.state == StateClientReady && !client.Deaf && !client.SelfDeaf {
+			if err := client.queueUDP(server.udpout, buf); err != nil {
+				client.Panicf("Unable to send UDP: %v", err)
+			}
+		}
+	}
+	server.udpout.flush()
+}
+
+// relayText passes a text message the client wrote on to the peers of
+// the links of the channels it was written to.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) relayText(client *Client, txtmsg *mumbleproto.TextMessage) {
+	if server.federation == nil {
+		return
+	}
+	for _, link := range server.federation.links {
+		if link.conn == nil {
+			continue
+		}
+		linked := false
+		for _, id := range txtmsg.ChannelId {
+			linked = linked || int(id) == link.Channel
+		}
+		for _, id := range txtmsg.TreeId {
+			linked = linked || int(id) == link.Channel
+		}
+		if linked {
+			link.conn.sendJSON(federation.FrameText, federation.Text{
+				User:    client.Session(),
+				Name:    client.ShownName(),
+				Message: txtmsg.GetMessage(),
+			})
+		}
+	}
+}
+
+// deliverFederatedText shows a text message of a peer's user to the
+// users in the linked channel, filtered by this server's rules.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) deliverFederatedText(link *federationLink, text federation.Text) {
+	channel, ok := server.Channels[link.Channel]
+	if !ok {
+		return
+	}
+	filtered, err := server.FilterText(text.Message)
+	if err != nil || len(filtered) == 0 {
+		return
+	}
+	msg := &mumbleproto.TextMessage{
+		ChannelId: []uint32{uint32(channel.Id)},
+		Message:   proto.String(filtered),
+	}
+	if ru, ok := link.remote[text.User]; ok {
+		msg.Actor = proto.Uint32(ru.session)
+	} else {
+		name := trimName(text.Name) + "@" + link.peerName
+		msg.Message = proto.String("<b>" + html.EscapeString(name) + ":</b> " + filtered)
+	}
+	for _, client := range channel.clients {
+		if client.state == StateClientReady {
+			client.sendMessage(msg)
+		}
+	}
+}
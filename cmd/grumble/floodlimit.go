@@ -0,0 +1,99 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements text message flood protection.
+//
+// Like Murmur, each client gets a leaky bucket that drains at
+// MessageLimit messages per second and holds up to MessageBurst
+// messages. Messages that would overflow the bucket are dropped, and
+// the sender is warned. A client that keeps flooding after
+// MessageFloodKick dropped messages is kicked. The check runs as
+// message middleware, before the text message handler.
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The time after which a client's dropped message count is reset.
+const floodForgiveTime = 60 * time.Second
+
+// A leakyBucket limits the rate of events.
+type leakyBucket struct {
+	level float64
+	last  time.Time
+}
+
+// allow drains the bucket at rate events per second, and checks
+// whether another event fits in a bucket of size burst.
+func (b *leakyBucket) allow(now time.Time, rate, burst float64) bool {
+	if !b.last.IsZero() {
+		b.level -= now.Sub(b.last).Seconds() * rate
+		if b.level < 0 {
+			b.level = 0
+		}
+	}
+	b.last = now
+	if b.level+1 > burst {
+		return false
+	}
+	b.level++
+	return true
+}
+
+func init() {
+	registerMessageMiddleware(messageMiddleware{before: limitTextMessages})
+}
+
+// limitTextMessages is the middleware that drops text messages from
+// flooding clients.
+func limitTextMessages(server *Server, client *Client, msg *Message) bool {
+	if msg.kind != mumbleproto.MessageTextMessage {
+		return true
+	}
+	return server.checkTextFlood(client)
+}
+
+// checkTextFlood checks whether client may send another text message.
+// If not, the client is warned, or kicked if it has been warned too
+// often.
+func (server *Server) checkTextFlood(client *Client) bool {
+	rate := float64(server.cfg.IntValue("MessageLimit"))
+	burst := float64(server.cfg.IntValue("MessageBurst"))
+	if rate <= 0 || burst <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if client.textBucket.allow(now, rate, burst) {
+		return true
+	}
+
+	if now.Sub(client.lastTextFlood) > floodForgiveTime {
+		client.textFloods = 0
+	}
+	client.lastTextFlood = now
+	client.textFloods++
+
+	kick := server.cfg.IntValue("MessageFloodKick")
+	if kick > 0 && client.textFloods >= kick {
+		client.Printf("Kicked for text message flooding")
+		server.KickClient(client, "Flooding")
+		return false
+	}
+
+	client.Printf("Dropped text message (flood)")
+	err := client.sendMessage(&mumbleproto.PermissionDenied{
+		Type:   mumbleproto.PermissionDenied_Text.Enum(),
+		Reason: proto.String("You are sending messages too quickly. Please slow down."),
+	})
+	if err != nil {
+		client.Panicf("%v", err.Error())
+	}
+	return false
+}
@@ -5,18 +5,19 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"mumble.info/grumble/pkg/acl"
 	"mumble.info/grumble/pkg/ban"
+	"mumble.info/grumble/pkg/cron"
 	"mumble.info/grumble/pkg/freezer"
 	"mumble.info/grumble/pkg/mumbleproto"
 	"mumble.info/grumble/pkg/serverconf"
@@ -30,8 +31,9 @@ func (server *Server) FreezeToFile() error {
 	if err != nil {
 		return err
 	}
+	server.replicateSnapshot()
 
-	if server.running {
+	if server.isRunning() {
 		// Re-open the freeze log.
 		err = server.openFreezeLog()
 		if err != nil {
@@ -52,7 +54,7 @@ func (server *Server) openFreezeLog() error {
 		server.freezelog = nil
 	}
 
-	logfn := filepath.Join(Args.DataDir, "servers", strconv.FormatInt(server.Id, 10), "log.fz")
+	logfn := filepath.Join(server.dir, "log.fz")
 	err := os.Remove(logfn)
 	if os.IsNotExist(err) {
 		// fallthrough
@@ -60,10 +62,13 @@ func (server *Server) openFreezeLog() error {
 		return err
 	}
 
-	server.freezelog, err = freezer.NewLogFile(logfn)
+	// The log's records are also sent to the standbys, if there are
+	// any (see replication.go).
+	f, err := os.Create(logfn)
 	if err != nil {
 		return err
 	}
+	server.freezelog = freezer.NewLog(replicatedLog{f, server.Id})
 
 	return nil
 }
@@ -113,38 +118,204 @@ func (server *Server) Freeze() (fs *freezer.Server, err error) {
 	}
 	fs.Users = users
 
+	// Freeze all invites
+	invites := []*freezer.Invite{}
+	for _, invite := range server.Invites {
+		invites = append(invites, invite.Freeze())
+	}
+	fs.Invites = invites
+
+	// Freeze all reports
+	reports := []*freezer.Report{}
+	for _, report := range server.Reports {
+		reports = append(reports, report.Freeze())
+	}
+	fs.Reports = reports
+
+	// Freeze all announcements
+	announcements := []*freezer.Announcement{}
+	for _, announcement := range server.Announcements {
+		announcements = append(announcements, announcement.Freeze())
+	}
+	fs.Announcements = announcements
+
+	// Freeze all whisper presets
+	presets := []*freezer.WhisperPreset{}
+	for _, preset := range server.WhisperPresets {
+		presets = append(presets, preset.Freeze())
+	}
+	fs.WhisperPresets = presets
+
 	return fs, nil
 }
 
+// Freeze an invite into a flattened protobuf-based structure
+// ready to be persisted to disk.
+func (invite *Invite) Freeze() *freezer.Invite {
+	return &freezer.Invite{
+		Id:        proto.Uint32(invite.Id),
+		TokenHash: proto.String(invite.TokenHash),
+		MaxUses:   proto.Uint32(invite.MaxUses),
+		Uses:      proto.Uint32(invite.Uses),
+		Expires:   proto.Int64(invite.Expires),
+		Groups:    invite.Groups,
+		Tokens:    invite.Tokens,
+		Note:      proto.String(invite.Note),
+	}
+}
+
+// Merge the contents of a frozen invite into an invite.
+// Only the use count may change after an invite has been created,
+// so the lists are only replaced if the frozen invite has a token hash.
+func (invite *Invite) Unfreeze(fi *freezer.Invite) {
+	if fi.TokenHash != nil {
+		invite.TokenHash = *fi.TokenHash
+		invite.Groups = fi.Groups
+		invite.Tokens = fi.Tokens
+	}
+	if fi.MaxUses != nil {
+		invite.MaxUses = *fi.MaxUses
+	}
+	if fi.Uses != nil {
+		invite.Uses = *fi.Uses
+	}
+	if fi.Expires != nil {
+		invite.Expires = *fi.Expires
+	}
+	if fi.Note != nil {
+		invite.Note = *fi.Note
+	}
+}
+
+// Freeze a report into a flattened protobuf-based structure ready to
+// be persisted to disk.
+func (report *Report) Freeze() *freezer.Report {
+	fr := &freezer.Report{
+		Id:           proto.Uint32(report.Id),
+		Created:      proto.Int64(report.Created),
+		ReporterId:   proto.Int32(int32(report.ReporterId)),
+		ReporterName: proto.String(report.ReporterName),
+		TargetId:     proto.Int32(int32(report.TargetId)),
+		TargetName:   proto.String(report.TargetName),
+		TargetHash:   proto.String(report.TargetHash),
+		ChannelId:    proto.Uint32(uint32(report.ChannelId)),
+		ChannelName:  proto.String(report.ChannelName),
+		Reason:       proto.String(report.Reason),
+		Closed:       proto.Int64(report.Closed),
+		ClosedBy:     proto.String(report.ClosedBy),
+		Note:         proto.String(report.Note),
+	}
+	for _, line := range report.Excerpt {
+		fr.Excerpt = append(fr.Excerpt, &freezer.ChatLine{
+			Time:   proto.Int64(line.Time),
+			Sender: proto.String(line.Sender),
+			Text:   proto.String(line.Text),
+		})
+	}
+	return fr
+}
+
+// Freeze an announcement into a flattened protobuf-based structure
+// ready to be persisted to disk.
+func (announcement *Announcement) Freeze() *freezer.Announcement {
+	fa := &freezer.Announcement{
+		Id:       proto.Uint32(announcement.Id),
+		Schedule: proto.String(announcement.Schedule),
+		Text:     proto.String(announcement.Text),
+		Enabled:  proto.Bool(announcement.Enabled),
+		Created:  proto.Int64(announcement.Created),
+	}
+	for _, id := range announcement.Channels {
+		fa.ChannelIds = append(fa.ChannelIds, uint32(id))
+	}
+	return fa
+}
+
+// Freeze a whisper preset into a flattened protobuf-based structure
+// ready to be persisted to disk.
+func (preset *WhisperPreset) Freeze() *freezer.WhisperPreset {
+	return &freezer.WhisperPreset{
+		Id:       proto.Uint32(preset.Id),
+		Name:     proto.String(preset.Name),
+		Slot:     proto.Uint32(preset.Slot),
+		Channels: freezeVoiceTargetChannels(preset.Channels),
+		UserIds:  append([]uint32(nil), preset.Users...),
+		Created:  proto.Int64(preset.Created),
+	}
+}
+
+// freezeVoiceTargetChannels flattens the channels of a voice target or
+// whisper preset.
+func freezeVoiceTargetChannels(channels []voiceTargetChannel) []*freezer.VoiceTargetChannel {
+	var fcs []*freezer.VoiceTargetChannel
+	for _, vtc := range channels {
+		fcs = append(fcs, &freezer.VoiceTargetChannel{
+			ChannelId: proto.Uint32(vtc.id),
+			Children:  proto.Bool(vtc.subChannels),
+			Links:     proto.Bool(vtc.links),
+			Group:     proto.String(vtc.onlyGroup),
+		})
+	}
+	return fcs
+}
+
+// unfreezeVoiceTargetChannels is the inverse of
+// freezeVoiceTargetChannels.
+func unfreezeVoiceTargetChannels(fcs []*freezer.VoiceTargetChannel) []voiceTargetChannel {
+	var channels []voiceTargetChannel
+	for _, fc := range fcs {
+		channels = append(channels, voiceTargetChannel{
+			id:          fc.GetChannelId(),
+			subChannels: fc.GetChildren(),
+			links:       fc.GetLinks(),
+			onlyGroup:   fc.GetGroup(),
+		})
+	}
+	return channels
+}
+
 // Merge the contents of a freezer.BanList into the server's
 // ban list.
 func (s *Server) UnfreezeBanList(fblist *freezer.BanList) {
 	s.Bans = nil
 	for _, fb := range fblist.Bans {
-		ban := ban.Ban{}
+		s.Bans = append(s.Bans, s.unfreezeBan(fb))
+	}
+}
 
-		ban.IP = fb.Ip
-		if fb.Mask != nil {
-			ban.Mask = int(*fb.Mask)
-		}
-		if fb.Username != nil {
-			ban.Username = *fb.Username
-		}
-		if fb.CertHash != nil {
-			ban.CertHash = *fb.CertHash
-		}
-		if fb.Reason != nil {
-			ban.Reason = *fb.Reason
-		}
-		if fb.Start != nil {
-			ban.Start = *fb.Start
-		}
-		if fb.Duration != nil {
-			ban.Duration = *fb.Duration
-		}
+// unfreezeBan converts a frozen ban. Bans frozen before bans had ids
+// are given a new one.
+func (s *Server) unfreezeBan(fb *freezer.Ban) ban.Ban {
+	ban := ban.Ban{}
 
-		s.Bans = append(s.Bans, ban)
+	if fb.Id != nil {
+		ban.Id = *fb.Id
+	} else {
+		ban.Id = s.nextBanId
 	}
+	if ban.Id >= s.nextBanId {
+		s.nextBanId = ban.Id + 1
+	}
+	ban.IP = fb.Ip
+	if fb.Mask != nil {
+		ban.Mask = int(*fb.Mask)
+	}
+	if fb.Username != nil {
+		ban.Username = *fb.Username
+	}
+	if fb.CertHash != nil {
+		ban.CertHash = *fb.CertHash
+	}
+	if fb.Reason != nil {
+		ban.Reason = *fb.Reason
+	}
+	if fb.Start != nil {
+		ban.Start = *fb.Start
+	}
+	if fb.Duration != nil {
+		ban.Duration = *fb.Duration
+	}
+	return ban
 }
 
 // Freeze a ban into a flattened protobuf-based struct
@@ -152,6 +323,7 @@ func (s *Server) UnfreezeBanList(fblist *freezer.BanList) {
 func FreezeBan(ban ban.Ban) (fb *freezer.Ban) {
 	fb = new(freezer.Ban)
 
+	fb.Id = proto.Uint32(ban.Id)
 	fb.Ip = ban.IP
 	fb.Mask = proto.Uint32(uint32(ban.Mask))
 	fb.Username = proto.String(ban.Username)
@@ -207,6 +379,15 @@ func (channel *Channel) Freeze() (fc *freezer.Channel, err error) {
 	// Blobstore reference to the channel's description.
 	fc.DescriptionBlob = proto.String(channel.DescriptionBlob)
 
+	// Channel flags
+	fc.NoVoice = proto.Bool(channel.NoVoice)
+	fc.Silent = proto.Bool(channel.Silent)
+
+	// The user who owns the channel, if any.
+	if channel.OwnerId >= 0 {
+		fc.OwnerId = proto.Uint32(uint32(channel.OwnerId))
+	}
+
 	return
 }
 
@@ -225,6 +406,15 @@ func (c *Channel) Unfreeze(fc *freezer.Channel) {
 	if fc.DescriptionBlob != nil {
 		c.DescriptionBlob = *fc.DescriptionBlob
 	}
+	if fc.NoVoice != nil {
+		c.NoVoice = *fc.NoVoice
+	}
+	if fc.Silent != nil {
+		c.Silent = *fc.Silent
+	}
+	if fc.OwnerId != nil {
+		c.OwnerId = int(*fc.OwnerId)
+	}
 
 	// Update ACLs
 	if fc.Acl != nil {
@@ -262,7 +452,7 @@ func (c *Channel) Unfreeze(fc *freezer.Channel) {
 			if fgrp.Name == nil {
 				continue
 			}
-			g := acl.Group{}
+			g := acl.EmptyGroupWithName(*fgrp.Name)
 			if fgrp.Inherit != nil {
 				g.Inherit = *fgrp.Inherit
 			}
@@ -297,12 +487,56 @@ func (user *User) Freeze() (fu *freezer.User, err error) {
 
 	fu.Id = proto.Uint32(user.Id)
 	fu.Name = proto.String(user.Name)
-	fu.CertHash = proto.String(user.CertHash)
+	fu.Password = proto.String(user.Password)
+	// Older versions of Grumble only know the first certificate.
+	if len(user.Certificates) > 0 {
+		fu.CertHash = proto.String(user.Certificates[0].Hash)
+	} else {
+		fu.CertHash = proto.String("")
+	}
 	fu.Email = proto.String(user.Email)
 	fu.TextureBlob = proto.String(user.TextureBlob)
 	fu.CommentBlob = proto.String(user.CommentBlob)
 	fu.LastChannelId = proto.Uint32(uint32(user.LastChannelId))
 	fu.LastActive = proto.Uint64(user.LastActive)
+	fu.Mute = proto.Bool(user.Mute)
+	fu.Deaf = proto.Bool(user.Deaf)
+	fu.PrioritySpeaker = proto.Bool(user.PrioritySpeaker)
+	fu.MuteExpires = proto.Int64(user.MuteExpires)
+	fu.LastAddress = proto.String(user.LastAddress)
+	fu.ConnectedTime = proto.Uint64(user.ConnectedTime)
+	for _, token := range user.AccessTokens {
+		fu.AccessTokens = append(fu.AccessTokens, &freezer.AccessToken{
+			Token:     proto.String(token.Token),
+			ChannelId: proto.Uint32(uint32(token.ChannelId)),
+			Expires:   proto.Int64(token.Expires),
+		})
+	}
+	for _, cert := range user.Certificates {
+		fu.Certificates = append(fu.Certificates, &freezer.Certificate{
+			Hash:     proto.String(cert.Hash),
+			Name:     proto.String(cert.Name),
+			Added:    proto.Int64(cert.Added),
+			LastSeen: proto.Int64(cert.LastSeen),
+		})
+	}
+	fu.Watches = append([]uint32(nil), user.Watches...)
+	for _, msg := range user.OfflineMessages {
+		fu.OfflineMessages = append(fu.OfflineMessages, &freezer.OfflineMessage{
+			SenderId:   proto.Uint32(msg.SenderId),
+			SenderName: proto.String(msg.SenderName),
+			Text:       proto.String(msg.Text),
+			Sent:       proto.Int64(msg.Sent),
+		})
+	}
+	for _, vt := range user.VoiceTargets {
+		fu.VoiceTargets = append(fu.VoiceTargets, &freezer.VoiceTarget{
+			Id:       proto.Uint32(vt.Id),
+			PresetId: proto.Uint32(vt.Preset),
+			Channels: freezeVoiceTargetChannels(vt.Channels),
+			UserIds:  append([]uint32(nil), vt.Users...),
+		})
+	}
 
 	return
 }
@@ -312,8 +546,8 @@ func (u *User) Unfreeze(fu *freezer.User) {
 	if fu.Name != nil {
 		u.Name = *fu.Name
 	}
-	if fu.CertHash != nil {
-		u.CertHash = *fu.CertHash
+	if fu.Password != nil {
+		u.Password = *fu.Password
 	}
 	if fu.Email != nil {
 		u.Email = *fu.Email
@@ -330,6 +564,70 @@ func (u *User) Unfreeze(fu *freezer.User) {
 	if fu.LastActive != nil {
 		u.LastActive = *fu.LastActive
 	}
+	if fu.Mute != nil {
+		u.Mute = *fu.Mute
+	}
+	if fu.Deaf != nil {
+		u.Deaf = *fu.Deaf
+	}
+	if fu.PrioritySpeaker != nil {
+		u.PrioritySpeaker = *fu.PrioritySpeaker
+	}
+	if fu.MuteExpires != nil {
+		u.MuteExpires = *fu.MuteExpires
+	}
+	if fu.LastAddress != nil {
+		u.LastAddress = *fu.LastAddress
+	}
+	if fu.ConnectedTime != nil {
+		u.ConnectedTime = *fu.ConnectedTime
+	}
+	// Only full user records carry the access tokens, the
+	// certificates, the watch list, the offline messages and the
+	// voice targets.
+	if fu.Name != nil {
+		u.AccessTokens = nil
+		for _, token := range fu.AccessTokens {
+			u.AccessTokens = append(u.AccessTokens, AccessToken{
+				Token:     token.GetToken(),
+				ChannelId: int(token.GetChannelId()),
+				Expires:   token.GetExpires(),
+			})
+		}
+		u.Certificates = nil
+		for _, cert := range fu.Certificates {
+			u.Certificates = append(u.Certificates, UserCertificate{
+				Hash:     cert.GetHash(),
+				Name:     cert.GetName(),
+				Added:    cert.GetAdded(),
+				LastSeen: cert.GetLastSeen(),
+			})
+		}
+		// Records written before users could have several
+		// certificates only hold the one.
+		if len(fu.Certificates) == 0 && len(fu.GetCertHash()) > 0 {
+			u.Certificates = []UserCertificate{{Hash: fu.GetCertHash()}}
+		}
+		u.Watches = append([]uint32(nil), fu.Watches...)
+		u.OfflineMessages = nil
+		for _, msg := range fu.OfflineMessages {
+			u.OfflineMessages = append(u.OfflineMessages, OfflineMessage{
+				SenderId:   msg.GetSenderId(),
+				SenderName: msg.GetSenderName(),
+				Text:       msg.GetText(),
+				Sent:       msg.GetSent(),
+			})
+		}
+		u.VoiceTargets = nil
+		for _, vt := range fu.VoiceTargets {
+			u.VoiceTargets = append(u.VoiceTargets, UserVoiceTarget{
+				Id:       vt.GetId(),
+				Preset:   vt.GetPresetId(),
+				Channels: unfreezeVoiceTargetChannels(vt.Channels),
+				Users:    append([]uint32(nil), vt.UserIds...),
+			})
+		}
+	}
 }
 
 // Freeze a ChannelACL into it a flattened protobuf-based structure
@@ -374,42 +672,39 @@ func FreezeGroup(group acl.Group) (*freezer.Group, error) {
 // Once both the full server and the log file has been merged together
 // in memory, a new full seralized server will be written and synced to
 // disk, and the existing log file will be removed.
-func NewServerFromFrozen(name string) (s *Server, err error) {
-	id, err := strconv.ParseInt(name, 10, 64)
-	if err != nil {
-		return nil, err
-	}
-
-	path := filepath.Join(Args.DataDir, "servers", name)
-	mainFile := filepath.Join(path, "main.fz")
-	backupFile := filepath.Join(path, "backup.fz")
-	logFn := filepath.Join(path, "log.fz")
+//
+// If 'main.fz' is damaged, it is moved aside and the previous snapshot,
+// 'backup.fz', is read instead. The log holds the changes made since
+// the damaged snapshot, not since the backup, so it isn't replayed and
+// is kept as 'log.fz.orphaned'. Otherwise, the log is replayed up to
+// the first damaged transaction group, such as one whose write was cut
+// short by a crash, and the damaged log is kept as 'log.fz.damaged'.
+func NewServerFromFrozen(id int64, dir string) (s *Server, err error) {
+	mainFile := filepath.Join(dir, "main.fz")
+	backupFile := filepath.Join(dir, "backup.fz")
+	logFn := filepath.Join(dir, "log.fz")
 
-	r, err := os.Open(mainFile)
-	if os.IsNotExist(err) {
-		err = os.Rename(backupFile, mainFile)
-		if err != nil {
-			return nil, err
+	// Unmarshal the server from it's frozen state
+	fromBackup := false
+	fs, err := readFrozenServer(mainFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Unable to read %v: %v", mainFile, err)
+			// Keep the damaged snapshot from replacing the backup
+			// once the server is frozen again.
+			if rerr := os.Rename(mainFile, mainFile+".damaged"); rerr != nil {
+				return nil, rerr
+			}
 		}
-		r, err = os.Open(mainFile)
-		if err != nil {
+		var berr error
+		fs, berr = readFrozenServer(backupFile)
+		if berr != nil {
 			return nil, err
 		}
-	} else if err != nil {
-		return nil, err
-	}
-	defer r.Close()
-
-	buf, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-
-	// Unmarshal the server from it's frozen state
-	fs := freezer.Server{}
-	err = proto.Unmarshal(buf, &fs)
-	if err != nil {
-		return nil, err
+		fromBackup = true
+		if !os.IsNotExist(err) {
+			log.Printf("Loaded server %v from %v. Changes made before the last snapshot may be lost.", id, backupFile)
+		}
 	}
 
 	// Create a config map from the frozen server.
@@ -424,6 +719,7 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 	if err != nil {
 		return nil, err
 	}
+	s.dir = dir
 	s.cfg = serverconf.New(cfgMap)
 
 	// Unfreeze the server's frozen bans.
@@ -463,6 +759,38 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 		}
 	}
 
+	// Add all invites
+	for _, fi := range fs.Invites {
+		if fi.Id == nil {
+			continue
+		}
+		s.unfreezeInvite(fi)
+	}
+
+	// Add all reports
+	for _, fr := range fs.Reports {
+		if fr.Id == nil {
+			continue
+		}
+		s.unfreezeReport(fr)
+	}
+
+	// Add all announcements
+	for _, fa := range fs.Announcements {
+		if fa.Id == nil {
+			continue
+		}
+		s.unfreezeAnnouncement(fa)
+	}
+
+	// Add all whisper presets
+	for _, fp := range fs.WhisperPresets {
+		if fp.Id == nil {
+			continue
+		}
+		s.unfreezeWhisperPreset(fp)
+	}
+
 	// Add all users
 	for _, fu := range fs.Users {
 		if fu.Id == nil && fu.Name == nil {
@@ -484,25 +812,44 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 		// to the new user.
 		s.Users[u.Id] = u
 		s.UserNameMap[u.Name] = u
-		if len(u.CertHash) > 0 {
-			s.UserCertMap[u.CertHash] = u
+		for _, cert := range u.Certificates {
+			s.UserCertMap[cert.Hash] = u
+		}
+	}
+
+	// A log that followed a lost snapshot can't be replayed on top of
+	// the backup.
+	if fromBackup {
+		orphaned := logFn + ".orphaned"
+		err = os.Rename(logFn, orphaned)
+		if err == nil {
+			log.Printf("Skipped log %v, which doesn't follow %v. It is kept as %v.", logFn, backupFile, orphaned)
+		} else if !os.IsNotExist(err) {
+			return nil, err
 		}
 	}
 
 	// Attempt to walk the stored log file
+	var journal io.Reader = bytes.NewReader(nil)
 	logFile, err := os.Open(logFn)
-	walker, err := freezer.NewReaderWalker(logFile)
+	if err == nil {
+		journal = logFile
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	walker, err := freezer.NewReaderWalker(journal)
 	if err != nil {
 		return nil, err
 	}
 
-	for {
+	damaged := false
+	for groups := 0; ; groups++ {
 		values, err := walker.Next()
 		if err == io.EOF {
-			err = logFile.Close()
-			if err != nil {
-				return nil, err
-			}
+			break
+		} else if freezer.IsCorrupt(err) {
+			log.Printf("Log %v is damaged after %v transaction groups (%v). Later changes are lost.", logFn, groups, err)
+			damaged = true
 			break
 		} else if err != nil {
 			return nil, err
@@ -547,15 +894,19 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 				}
 
 				// Merge the contents of the frozen.User into the
-				// user struct.
+				// user struct. Certificates that the record no
+				// longer holds must not keep matching the user.
+				for _, cert := range user.Certificates {
+					delete(s.UserCertMap, cert.Hash)
+				}
 				user.Unfreeze(fu)
 
 				// Update the various user maps in the server to
 				// be able to correctly look up the user.
 				s.Users[user.Id] = user
 				s.UserNameMap[user.Name] = user
-				if len(user.CertHash) > 0 {
-					s.UserCertMap[user.CertHash] = user
+				for _, cert := range user.Certificates {
+					s.UserCertMap[cert.Hash] = user
 				}
 
 			case *freezer.UserRemove:
@@ -575,9 +926,10 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 					// Clear the server maps. That should do it.
 					delete(s.Users, userId)
 					delete(s.UserNameMap, user.Name)
-					if len(user.CertHash) > 0 {
-						delete(s.UserCertMap, user.CertHash)
+					for _, cert := range user.Certificates {
+						delete(s.UserCertMap, cert.Hash)
 					}
+					s.removeFrozenUserReferences(userId)
 				} else {
 					log.Printf("Skipped UserRemove log entry: No user for given id.")
 					continue
@@ -629,13 +981,108 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 					log.Printf("Skipped ChannelRemove log entry: No id given.")
 					continue
 				}
-				s.Channels[int(*fc.Id)] = nil
-				delete(parents, *fc.Id)
+				s.removeFrozenChannel(*fc.Id, parents)
+
+			case *freezer.Invite:
+				fi := val.(*freezer.Invite)
+				if fi.Id == nil {
+					log.Printf("Skipped Invite log entry: No id given.")
+					continue
+				}
+				s.unfreezeInvite(fi)
+
+			case *freezer.InviteRemove:
+				fi := val.(*freezer.InviteRemove)
+				if fi.Id == nil {
+					log.Printf("Skipped InviteRemove log entry: No id given.")
+					continue
+				}
+				delete(s.Invites, *fi.Id)
+
+			case *freezer.Report:
+				fr := val.(*freezer.Report)
+				if fr.Id == nil {
+					log.Printf("Skipped Report log entry: No id given.")
+					continue
+				}
+				s.unfreezeReport(fr)
+
+			case *freezer.ReportRemove:
+				fr := val.(*freezer.ReportRemove)
+				if fr.Id == nil {
+					log.Printf("Skipped ReportRemove log entry: No id given.")
+					continue
+				}
+				delete(s.Reports, *fr.Id)
+
+			case *freezer.Announcement:
+				fa := val.(*freezer.Announcement)
+				if fa.Id == nil {
+					log.Printf("Skipped Announcement log entry: No id given.")
+					continue
+				}
+				s.unfreezeAnnouncement(fa)
+
+			case *freezer.AnnouncementRemove:
+				fa := val.(*freezer.AnnouncementRemove)
+				if fa.Id == nil {
+					log.Printf("Skipped AnnouncementRemove log entry: No id given.")
+					continue
+				}
+				delete(s.Announcements, *fa.Id)
+
+			case *freezer.WhisperPreset:
+				fp := val.(*freezer.WhisperPreset)
+				if fp.Id == nil {
+					log.Printf("Skipped WhisperPreset log entry: No id given.")
+					continue
+				}
+				s.unfreezeWhisperPreset(fp)
+
+			case *freezer.WhisperPresetRemove:
+				fp := val.(*freezer.WhisperPresetRemove)
+				if fp.Id == nil {
+					log.Printf("Skipped WhisperPresetRemove log entry: No id given.")
+					continue
+				}
+				delete(s.WhisperPresets, *fp.Id)
 
 			case *freezer.BanList:
 				fbl := val.(*freezer.BanList)
 				s.UnfreezeBanList(fbl)
 
+			case *freezer.Ban:
+				fb := val.(*freezer.Ban)
+				if fb.Id == nil {
+					log.Printf("Skipped Ban log entry: No id given.")
+					continue
+				}
+				ban := s.unfreezeBan(fb)
+				replaced := false
+				for i := range s.Bans {
+					if s.Bans[i].Id == ban.Id {
+						s.Bans[i] = ban
+						replaced = true
+						break
+					}
+				}
+				if !replaced {
+					s.Bans = append(s.Bans, ban)
+				}
+
+			case *freezer.BanRemove:
+				fb := val.(*freezer.BanRemove)
+				if fb.Id == nil {
+					log.Printf("Skipped BanRemove log entry: No id given.")
+					continue
+				}
+				for i := range s.Bans {
+					if s.Bans[i].Id == *fb.Id {
+						s.Bans = append(s.Bans[:i], s.Bans[i+1:]...)
+						break
+					}
+				}
+
 			case *freezer.ConfigKeyValuePair:
 				fcfg := val.(*freezer.ConfigKeyValuePair)
 				if fcfg.Key != nil {
@@ -651,6 +1098,22 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 		}
 	}
 
+	if logFile != nil {
+		err = logFile.Close()
+		if err != nil {
+			return nil, err
+		}
+		if damaged {
+			err = os.Rename(logFn, logFn+".damaged")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Drop references to channels and users that don't exist.
+	s.checkIntegrity(parents)
+
 	// Hook up children with their parents
 	for chanId, parentId := range parents {
 		childChan, exists := s.Channels[int(chanId)]
@@ -681,12 +1144,32 @@ func NewServerFromFrozen(name string) (s *Server, err error) {
 	return s, nil
 }
 
+// readFrozenServer reads a full serialized server from fn.
+func readFrozenServer(fn string) (*freezer.Server, error) {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	// A snapshot holds at least the root channel, so an empty one
+	// was cut short.
+	if len(buf) == 0 {
+		return nil, errors.New("empty snapshot")
+	}
+	fs := &freezer.Server{}
+	err = proto.Unmarshal(buf, fs)
+	if err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
 // Update the datastore with the user's current state.
 func (server *Server) UpdateFrozenUser(client *Client, state *mumbleproto.UserState) {
 	// Full sync If there's no userstate messgae provided, or if there is one, and
 	// it includes a registration operation.
 	user := client.user
 	nanos := time.Now().Unix()
+	user.LastActive = uint64(nanos)
 	if state == nil || state.UserId != nil {
 		fu, err := user.Freeze()
 		if err != nil {
@@ -709,6 +1192,12 @@ func (server *Server) UpdateFrozenUser(client *Client, state *mumbleproto.UserSt
 		if state.CommentHash != nil {
 			fu.CommentBlob = proto.String(user.CommentBlob)
 		}
+		if state.Mute != nil || state.Deaf != nil || state.PrioritySpeaker != nil {
+			fu.Mute = proto.Bool(user.Mute)
+			fu.Deaf = proto.Bool(user.Deaf)
+			fu.PrioritySpeaker = proto.Bool(user.PrioritySpeaker)
+			fu.MuteExpires = proto.Int64(user.MuteExpires)
+		}
 		fu.LastActive = proto.Uint64(uint64(nanos))
 		err := server.freezelog.Put(fu)
 		if err != nil {
@@ -718,15 +1207,46 @@ func (server *Server) UpdateFrozenUser(client *Client, state *mumbleproto.UserSt
 	server.numLogOps += 1
 }
 
+// Write the full state of user to the datastore.
+func (server *Server) UpdateFrozenUserRecord(user *User) {
+	fu, err := user.Freeze()
+	if err != nil {
+		server.Fatal(err)
+	}
+	err = server.freezelog.Put(fu)
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// Update a user's server mute, deafen and priority speaker flags
+func (server *Server) UpdateFrozenUserModeration(user *User) {
+	fu := &freezer.User{}
+	fu.Id = proto.Uint32(user.Id)
+	fu.Mute = proto.Bool(user.Mute)
+	fu.Deaf = proto.Bool(user.Deaf)
+	fu.PrioritySpeaker = proto.Bool(user.PrioritySpeaker)
+	fu.MuteExpires = proto.Int64(user.MuteExpires)
+	err := server.freezelog.Put(fu)
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
 // Update a user's last active channel
 func (server *Server) UpdateFrozenUserLastChannel(client *Client) {
 	if client.IsRegistered() {
 		user := client.user
 
+		user.LastChannelId = client.Channel.Id
+		user.LastActive = uint64(time.Now().Unix())
+
 		fu := &freezer.User{}
 		fu.Id = proto.Uint32(user.Id)
-		fu.LastChannelId = proto.Uint32(uint32(client.Channel.Id))
-		fu.LastActive = proto.Uint64(uint64(time.Now().Unix()))
+		fu.LastChannelId = proto.Uint32(uint32(user.LastChannelId))
+		fu.LastActive = proto.Uint64(user.LastActive)
 
 		err := server.freezelog.Put(fu)
 		if err != nil {
@@ -737,6 +1257,21 @@ func (server *Server) UpdateFrozenUserLastChannel(client *Client) {
 	}
 }
 
+// Update a user's connection history
+func (server *Server) UpdateFrozenUserHistory(user *User) {
+	fu := &freezer.User{}
+	fu.Id = proto.Uint32(user.Id)
+	fu.LastChannelId = proto.Uint32(uint32(user.LastChannelId))
+	fu.LastActive = proto.Uint64(user.LastActive)
+	fu.LastAddress = proto.String(user.LastAddress)
+	fu.ConnectedTime = proto.Uint64(user.ConnectedTime)
+	err := server.freezelog.Put(fu)
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
 // Mark a user as deleted in the datstore.
 func (server *Server) DeleteFrozenUser(user *User) {
 	err := server.freezelog.Put(&freezer.UserRemove{Id: proto.Uint32(user.Id)})
@@ -771,6 +1306,37 @@ func (server *Server) UpdateFrozenChannel(channel *Channel, state *mumbleproto.C
 	if len(state.DescriptionHash) > 0 {
 		fc.DescriptionBlob = proto.String(channel.DescriptionBlob)
 	}
+	if state.NoVoice != nil {
+		fc.NoVoice = state.NoVoice
+	}
+	if state.Silent != nil {
+		fc.Silent = state.Silent
+	}
+	err := server.freezelog.Put(fc)
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// Write the full state of channel to the datastore.
+func (server *Server) UpdateFrozenChannelRecord(channel *Channel) {
+	fc, err := channel.Freeze()
+	if err != nil {
+		server.Fatal(err)
+	}
+	err = server.freezelog.Put(fc)
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// UpdateFrozenChannelOwner writes the owner of a channel to disk.
+func (server *Server) UpdateFrozenChannelOwner(channel *Channel) {
+	fc := &freezer.Channel{}
+	fc.Id = proto.Uint32(uint32(channel.Id))
+	fc.OwnerId = proto.Uint32(uint32(channel.OwnerId))
 	err := server.freezelog.Put(fc)
 	if err != nil {
 		server.Fatal(err)
@@ -823,13 +1389,181 @@ func (server *Server) DeleteFrozenChannel(channel *Channel) {
 	server.numLogOps += 1
 }
 
-// UpdateFrozenBans writes the server's banlist to the datastore.
-func (server *Server) UpdateFrozenBans(bans []ban.Ban) {
-	fbl := &freezer.BanList{}
-	for _, ban := range server.Bans {
-		fbl.Bans = append(fbl.Bans, FreezeBan(ban))
+// UpdateFrozenBan writes a new or changed ban to the datastore.
+func (server *Server) UpdateFrozenBan(ban ban.Ban) {
+	err := server.freezelog.Put(FreezeBan(ban))
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// DeleteFrozenBan marks a ban as deleted in the datastore.
+func (server *Server) DeleteFrozenBan(id uint32) {
+	err := server.freezelog.Put(&freezer.BanRemove{Id: proto.Uint32(id)})
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// unfreezeInvite creates or updates an invite from a frozen invite.
+func (s *Server) unfreezeInvite(fi *freezer.Invite) {
+	invite, ok := s.Invites[*fi.Id]
+	if !ok {
+		invite = &Invite{Id: *fi.Id}
+		s.Invites[invite.Id] = invite
+		if invite.Id >= s.nextInviteId {
+			s.nextInviteId = invite.Id + 1
+		}
+	}
+	invite.Unfreeze(fi)
+}
+
+// unfreezeReport creates or replaces a report from a frozen report.
+func (s *Server) unfreezeReport(fr *freezer.Report) {
+	report := &Report{
+		Id:           fr.GetId(),
+		Created:      fr.GetCreated(),
+		ReporterId:   int(fr.GetReporterId()),
+		ReporterName: fr.GetReporterName(),
+		TargetId:     int(fr.GetTargetId()),
+		TargetName:   fr.GetTargetName(),
+		TargetHash:   fr.GetTargetHash(),
+		ChannelId:    int(fr.GetChannelId()),
+		ChannelName:  fr.GetChannelName(),
+		Reason:       fr.GetReason(),
+		Closed:       fr.GetClosed(),
+		ClosedBy:     fr.GetClosedBy(),
+		Note:         fr.GetNote(),
+	}
+	for _, line := range fr.Excerpt {
+		report.Excerpt = append(report.Excerpt, chatLine{
+			Time:   line.GetTime(),
+			Sender: line.GetSender(),
+			Text:   line.GetText(),
+		})
+	}
+	s.Reports[report.Id] = report
+	if report.Id >= s.nextReportId {
+		s.nextReportId = report.Id + 1
+	}
+}
+
+// UpdateFrozenReport writes the full state of a report to the
+// datastore.
+func (server *Server) UpdateFrozenReport(report *Report) {
+	err := server.freezelog.Put(report.Freeze())
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// DeleteFrozenReport marks a report as deleted in the datastore.
+func (server *Server) DeleteFrozenReport(id uint32) {
+	err := server.freezelog.Put(&freezer.ReportRemove{Id: proto.Uint32(id)})
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// unfreezeAnnouncement creates or replaces an announcement from a
+// frozen announcement. Announcements whose schedule no longer parses
+// are kept, but never sent.
+func (s *Server) unfreezeAnnouncement(fa *freezer.Announcement) {
+	announcement := &Announcement{
+		Id:       fa.GetId(),
+		Schedule: fa.GetSchedule(),
+		Text:     fa.GetText(),
+		Enabled:  fa.GetEnabled(),
+		Created:  fa.GetCreated(),
+	}
+	for _, id := range fa.ChannelIds {
+		announcement.Channels = append(announcement.Channels, int(id))
+	}
+	schedule, err := cron.Parse(announcement.Schedule)
+	if err != nil {
+		s.Printf("Announcement %v has an invalid schedule: %v", announcement.Id, err)
+	}
+	announcement.schedule = schedule
+	announcement.reschedule(time.Now())
+	s.Announcements[announcement.Id] = announcement
+	if announcement.Id >= s.nextAnnouncementId {
+		s.nextAnnouncementId = announcement.Id + 1
+	}
+}
+
+// UpdateFrozenAnnouncement writes the full state of an announcement to
+// the datastore.
+func (server *Server) UpdateFrozenAnnouncement(announcement *Announcement) {
+	err := server.freezelog.Put(announcement.Freeze())
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// DeleteFrozenAnnouncement marks an announcement as deleted in the
+// datastore.
+func (server *Server) DeleteFrozenAnnouncement(id uint32) {
+	err := server.freezelog.Put(&freezer.AnnouncementRemove{Id: proto.Uint32(id)})
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// unfreezeWhisperPreset creates or replaces a whisper preset from a
+// frozen preset.
+func (s *Server) unfreezeWhisperPreset(fp *freezer.WhisperPreset) {
+	preset := &WhisperPreset{
+		Id:       fp.GetId(),
+		Name:     fp.GetName(),
+		Slot:     fp.GetSlot(),
+		Channels: unfreezeVoiceTargetChannels(fp.Channels),
+		Users:    append([]uint32(nil), fp.UserIds...),
+		Created:  fp.GetCreated(),
 	}
-	err := server.freezelog.Put(fbl)
+	s.WhisperPresets[preset.Id] = preset
+	if preset.Id >= s.nextWhisperPresetId {
+		s.nextWhisperPresetId = preset.Id + 1
+	}
+}
+
+// UpdateFrozenWhisperPreset writes the full state of a whisper preset
+// to the datastore.
+func (server *Server) UpdateFrozenWhisperPreset(preset *WhisperPreset) {
+	err := server.freezelog.Put(preset.Freeze())
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// DeleteFrozenWhisperPreset marks a whisper preset as deleted in the
+// datastore.
+func (server *Server) DeleteFrozenWhisperPreset(id uint32) {
+	err := server.freezelog.Put(&freezer.WhisperPresetRemove{Id: proto.Uint32(id)})
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// UpdateFrozenInvite writes the full state of an invite to the datastore.
+func (server *Server) UpdateFrozenInvite(invite *Invite) {
+	err := server.freezelog.Put(invite.Freeze())
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+}
+
+// DeleteFrozenInvite marks an invite as deleted in the datastore.
+func (server *Server) DeleteFrozenInvite(id uint32) {
+	err := server.freezelog.Put(&freezer.InviteRemove{Id: proto.Uint32(id)})
 	if err != nil {
 		server.Fatal(err)
 	}
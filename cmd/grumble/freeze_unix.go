@@ -10,7 +10,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/golang/protobuf/proto"
 )
@@ -30,7 +29,7 @@ func (server *Server) freezeToFile() (err error) {
 	if err != nil {
 		return err
 	}
-	f, err := ioutil.TempFile(filepath.Join(Args.DataDir, "servers", strconv.FormatInt(server.Id, 10)), ".main.fz_")
+	f, err := ioutil.TempFile(server.dir, ".main.fz_")
 	if err != nil {
 		return err
 	}
@@ -50,7 +49,19 @@ func (server *Server) freezeToFile() (err error) {
 	if err != nil {
 		return err
 	}
-	err = os.Rename(f.Name(), filepath.Join(Args.DataDir, "servers", strconv.FormatInt(server.Id, 10), "main.fz"))
+
+	dst := filepath.Join(server.dir, "main.fz")
+	backup := filepath.Join(server.dir, "backup.fz")
+
+	// Keep the previous snapshot as backup.fz, which is read if
+	// main.fz turns out to be damaged.
+	os.Remove(backup)
+	err = os.Link(dst, backup)
+	if err != nil && !os.IsNotExist(err) {
+		server.Printf("Unable to keep backup snapshot: %v", err)
+	}
+
+	err = os.Rename(f.Name(), dst)
 	if err != nil {
 		return err
 	}
@@ -57,7 +57,7 @@ func GenerateSelfSignedCert(certpath, keypath string) (err error) {
 	}
 
 	certfn := filepath.Join(Args.DataDir, "cert.pem")
-	file, err := os.OpenFile(certfn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0700)
+	file, err := os.OpenFile(certfn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
@@ -68,7 +68,7 @@ func GenerateSelfSignedCert(certpath, keypath string) (err error) {
 	}
 
 	keyfn := filepath.Join(Args.DataDir, "key.pem")
-	file, err = os.OpenFile(keyfn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0700)
+	file, err = os.OpenFile(keyfn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,254 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements per-country and per-ASN connection statistics.
+//
+// When a GeoIP database is loaded (see the --geoip argument), every
+// incoming connection is looked up and its traffic is accounted to its
+// country and autonomous system. The totals are available through the
+// admin API and the metrics, and are periodically written to the server
+// log.
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"mumble.info/grumble/pkg/geoip"
+)
+
+// How often the GeoIP statistics are written to the log.
+const geoStatsReportInterval = time.Hour
+
+// The number of entries included in the periodic log report.
+const geoStatsReportTop = 10
+
+// geoStat holds the aggregated statistics of a country or ASN.
+type geoStat struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+	Connections uint64 `json:"connections"`
+	Active      int    `json:"active"`
+	BytesIn     uint64 `json:"bytes_in"`
+	BytesOut    uint64 `json:"bytes_out"`
+}
+
+// geoStats aggregates connection statistics for a server.
+// Traffic of connected clients is only folded into the totals
+// when they disconnect; see Server.geoStatsSnapshot for a live view.
+type geoStats struct {
+	mutex     sync.Mutex
+	countries map[string]*geoStat
+	asns      map[uint32]*geoStat
+}
+
+func newGeoStats() *geoStats {
+	return &geoStats{
+		countries: make(map[string]*geoStat),
+		asns:      make(map[uint32]*geoStat),
+	}
+}
+
+// entries returns the country and ASN entries for rec,
+// creating them if necessary. The mutex must be held.
+func (gs *geoStats) entries(rec geoip.Record) (country *geoStat, asn *geoStat) {
+	country, ok := gs.countries[rec.Country]
+	if !ok {
+		country = &geoStat{Key: rec.Country}
+		gs.countries[rec.Country] = country
+	}
+	asn, ok = gs.asns[rec.ASN]
+	if !ok {
+		asn = &geoStat{Key: "AS" + strconv.FormatUint(uint64(rec.ASN), 10), Description: rec.ASDescription}
+		gs.asns[rec.ASN] = asn
+	}
+	return
+}
+
+// connected records a new connection from rec.
+func (gs *geoStats) connected(rec geoip.Record) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	country, asn := gs.entries(rec)
+	for _, stat := range []*geoStat{country, asn} {
+		stat.Connections++
+		stat.Active++
+	}
+}
+
+// disconnected folds the traffic of a closed connection from rec
+// into the totals.
+func (gs *geoStats) disconnected(rec geoip.Record, in, out uint64) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	country, asn := gs.entries(rec)
+	for _, stat := range []*geoStat{country, asn} {
+		stat.Active--
+		stat.BytesIn += in
+		stat.BytesOut += out
+	}
+}
+
+// geoStatsSnapshot returns the server's per-country and per-ASN
+// statistics, including the traffic of currently connected clients.
+// The entries are sorted by total traffic, highest first.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) geoStatsSnapshot() (countries []geoStat, asns []geoStat) {
+	gs := server.geoStats
+	gs.mutex.Lock()
+	cmap := make(map[string]*geoStat, len(gs.countries))
+	for k, v := range gs.countries {
+		stat := *v
+		cmap[k] = &stat
+	}
+	amap := make(map[uint32]*geoStat, len(gs.asns))
+	for k, v := range gs.asns {
+		stat := *v
+		amap[k] = &stat
+	}
+	gs.mutex.Unlock()
+
+	for _, client := range server.clients {
+		in, out := client.traffic.load()
+		if stat, ok := cmap[client.geo.Country]; ok {
+			stat.BytesIn += in
+			stat.BytesOut += out
+		}
+		if stat, ok := amap[client.geo.ASN]; ok {
+			stat.BytesIn += in
+			stat.BytesOut += out
+		}
+	}
+
+	countries = []geoStat{}
+	for _, stat := range cmap {
+		countries = append(countries, *stat)
+	}
+	asns = []geoStat{}
+	for _, stat := range amap {
+		asns = append(asns, *stat)
+	}
+	sortGeoStats(countries)
+	sortGeoStats(asns)
+	return
+}
+
+func sortGeoStats(stats []geoStat) {
+	sort.Slice(stats, func(i, j int) bool {
+		ti := stats[i].BytesIn + stats[i].BytesOut
+		tj := stats[j].BytesIn + stats[j].BytesOut
+		if ti != tj {
+			return ti > tj
+		}
+		return stats[i].Key < stats[j].Key
+	})
+}
+
+// reportGeoStats writes a summary of the busiest countries and
+// autonomous systems to the server log.
+func (server *Server) reportGeoStats() {
+	if geoDB == nil {
+		return
+	}
+	countries, asns := server.geoStatsSnapshot()
+	if len(countries) == 0 {
+		return
+	}
+	server.Printf("GeoIP statistics (top %v by traffic):", geoStatsReportTop)
+	for i, stat := range countries {
+		if i == geoStatsReportTop {
+			break
+		}
+		server.Printf("  %v: %v connections (%v active), %v bytes in, %v bytes out",
+			stat.Key, stat.Connections, stat.Active, stat.BytesIn, stat.BytesOut)
+	}
+	for i, stat := range asns {
+		if i == geoStatsReportTop {
+			break
+		}
+		server.Printf("  %v (%v): %v connections (%v active), %v bytes in, %v bytes out",
+			stat.Key, stat.Description, stat.Connections, stat.Active, stat.BytesIn, stat.BytesOut)
+	}
+}
+
+// geoMetrics holds a server's GeoIP statistics for the metrics.
+type geoMetrics struct {
+	countries, asns []geoStat
+}
+
+// writeGeoMetrics writes the servers' per-country and per-ASN
+// statistics in the Prometheus text format.
+func writeGeoMetrics(w io.Writer, ids []int64, geo map[int64]geoMetrics) {
+	for _, dim := range []struct {
+		label, name string
+		stats       func(m geoMetrics) []geoStat
+	}{
+		{"country", "country", func(m geoMetrics) []geoStat { return m.countries }},
+		{"asn", "autonomous system", func(m geoMetrics) []geoStat { return m.asns }},
+	} {
+		prefix := "grumble_" + dim.label + "_"
+		fmt.Fprintf(w, "# HELP %vconnections_total Connections accepted, by the %v they came from.\n", prefix, dim.name)
+		fmt.Fprintf(w, "# TYPE %vconnections_total counter\n", prefix)
+		for _, id := range ids {
+			for _, stat := range dim.stats(geo[id]) {
+				writeMetric(w, prefix+"connections_total", stat.Connections,
+					"server", strconv.FormatInt(id, 10), dim.label, stat.Key)
+			}
+		}
+		fmt.Fprintf(w, "# HELP %vactive_connections Open connections, by the %v they came from.\n", prefix, dim.name)
+		fmt.Fprintf(w, "# TYPE %vactive_connections gauge\n", prefix)
+		for _, id := range ids {
+			for _, stat := range dim.stats(geo[id]) {
+				writeMetric(w, prefix+"active_connections", stat.Active,
+					"server", strconv.FormatInt(id, 10), dim.label, stat.Key)
+			}
+		}
+		fmt.Fprintf(w, "# HELP %vtraffic_bytes_total Bytes sent and received, by the %v of the connection.\n", prefix, dim.name)
+		fmt.Fprintf(w, "# TYPE %vtraffic_bytes_total counter\n", prefix)
+		for _, id := range ids {
+			for _, stat := range dim.stats(geo[id]) {
+				writeMetric(w, prefix+"traffic_bytes_total", stat.BytesIn,
+					"server", strconv.FormatInt(id, 10), dim.label, stat.Key, "direction", "in")
+				writeMetric(w, prefix+"traffic_bytes_total", stat.BytesOut,
+					"server", strconv.FormatInt(id, 10), dim.label, stat.Key, "direction", "out")
+			}
+		}
+	}
+}
+
+func init() {
+	registerAPIEndpoint("geostats", handleAPIGeoStats)
+}
+
+// handleAPIGeoStats implements /servers/<id>/geostats.
+func handleAPIGeoStats(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if geoDB == nil {
+		apiError(w, http.StatusNotFound, "no GeoIP database loaded")
+		return
+	}
+
+	var reply struct {
+		Countries []geoStat `json:"countries"`
+		ASNs      []geoStat `json:"asns"`
+	}
+	err := server.runSync(func() {
+		reply.Countries, reply.ASNs = server.geoStatsSnapshot()
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, reply)
+}
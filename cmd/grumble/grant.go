@@ -0,0 +1,228 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements session-scoped temporary permission grants.
+//
+// A grant gives a connected client extra permissions in a channel
+// (and optionally its subchannels) for a limited amount of time. Grants
+// are never persisted: they are dropped when they expire, or when the
+// client disconnects.
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// A permissionGrant is a temporary ACL overlay held by a single session.
+type permissionGrant struct {
+	ChannelId   int
+	Permission  acl.Permission
+	Subchannels bool
+	Expires     time.Time
+}
+
+// appliesTo checks whether the grant applies to the channel with
+// the given ACL context.
+func (grant *permissionGrant) appliesTo(server *Server, ctx *acl.Context) bool {
+	channel, ok := server.Channels[grant.ChannelId]
+	if !ok {
+		return false
+	}
+	if &channel.ACL == ctx {
+		return true
+	}
+	if grant.Subchannels {
+		for iter := ctx.Parent; iter != nil; iter = iter.Parent {
+			if iter == &channel.ACL {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GrantedPermissions implements acl.GrantHolder. It returns the permissions
+// granted to the client in ctx by its unexpired temporary grants.
+func (client *Client) GrantedPermissions(ctx *acl.Context) acl.Permission {
+	perm := acl.Permission(acl.NonePermission)
+	now := time.Now()
+	for i := range client.grants {
+		grant := &client.grants[i]
+		if now.Before(grant.Expires) && grant.appliesTo(client.server, ctx) {
+			perm |= grant.Permission
+		}
+	}
+	return perm
+}
+
+// GrantPermission gives client perm in channel for the given duration.
+func (server *Server) GrantPermission(client *Client, channel *Channel, perm acl.Permission, subchannels bool, duration time.Duration) {
+	client.grants = append(client.grants, permissionGrant{
+		ChannelId:   channel.Id,
+		Permission:  perm,
+		Subchannels: subchannels,
+		Expires:     time.Now().Add(duration),
+	})
+	client.Printf("Granted %v in channel %v for %v", perm, channel.Id, duration)
+	server.grantsChanged(client)
+}
+
+// RevokeGrants removes all temporary grants held by client.
+func (server *Server) RevokeGrants(client *Client) {
+	if len(client.grants) == 0 {
+		return
+	}
+	client.grants = nil
+	client.Printf("Revoked temporary grants")
+	server.grantsChanged(client)
+}
+
+// expireGrants drops expired grants from all clients.
+func (server *Server) expireGrants() {
+	now := time.Now()
+	for _, client := range server.clients {
+		if len(client.grants) == 0 {
+			continue
+		}
+		kept := client.grants[:0]
+		for _, grant := range client.grants {
+			if now.Before(grant.Expires) {
+				kept = append(kept, grant)
+			}
+		}
+		if len(kept) != len(client.grants) {
+			client.grants = kept
+			client.Printf("Temporary grant expired")
+			server.grantsChanged(client)
+		}
+	}
+}
+
+// grantsChanged updates the server's view of a client whose grants
+// have changed: its suppression state is re-evaluated, and it is sent
+// the permissions that changed.
+func (server *Server) grantsChanged(client *Client) {
+	server.ClearCaches()
+
+	channel := client.Channel
+	if channel == nil || client.state != StateClientReady {
+		return
+	}
+
+	canspeak := acl.HasPermission(&channel.ACL, client, acl.SpeakPermission)
+	if canspeak == client.Suppress {
+		client.Suppress = !canspeak
+		userstate := &mumbleproto.UserState{
+			Session:  proto.Uint32(client.Session()),
+			Suppress: proto.Bool(client.Suppress),
+		}
+		if err := server.broadcastProtoMessage(userstate); err != nil {
+			server.Printf("Unable to broadcast UserState: %v", err)
+		}
+	}
+
+	server.pushClientPermissions(client)
+}
+
+// apiGrant is the JSON representation of a permissionGrant.
+type apiGrant struct {
+	Channel     int      `json:"channel"`
+	Permissions []string `json:"permissions"`
+	Subchannels bool     `json:"subchannels"`
+	Expires     string   `json:"expires,omitempty"`
+	Duration    string   `json:"duration,omitempty"`
+}
+
+func init() {
+	registerAPIEndpoint("grants", handleAPIGrants)
+}
+
+// handleAPIGrants implements /servers/<id>/grants/<session>.
+//
+//	GET     lists the session's grants
+//	POST    adds a grant: {"channel": 1, "permissions": ["speak"], "duration": "10m"}
+//	DELETE  revokes all of the session's grants
+func handleAPIGrants(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 1 {
+		apiError(w, http.StatusNotFound, "expected /grants/<session>")
+		return
+	}
+	session, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid session")
+		return
+	}
+
+	var req apiGrant
+	var perm acl.Permission
+	var duration time.Duration
+	if r.Method == http.MethodPost {
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for _, name := range req.Permissions {
+			p, err := acl.ParsePermission(name)
+			if err != nil {
+				apiError(w, http.StatusBadRequest, "unknown permission: "+name)
+				return
+			}
+			perm |= p
+		}
+		if perm == acl.NonePermission {
+			apiError(w, http.StatusBadRequest, "no permissions given")
+			return
+		}
+		duration, err = time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			apiError(w, http.StatusBadRequest, "invalid duration")
+			return
+		}
+	} else if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		client, ok := server.clients[uint32(session)]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such session"}
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			channel, ok := server.Channels[req.Channel]
+			if !ok {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such channel"}
+				return
+			}
+			server.GrantPermission(client, channel, perm, req.Subchannels, duration)
+		case http.MethodDelete:
+			server.RevokeGrants(client)
+		}
+		grants := []apiGrant{}
+		for _, grant := range client.grants {
+			grants = append(grants, apiGrant{
+				Channel:     grant.ChannelId,
+				Permissions: grant.Permission.Names(),
+				Subchannels: grant.Subchannels,
+				Expires:     grant.Expires.UTC().Format(time.RFC3339),
+			})
+		}
+		reply = grants
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -5,24 +5,38 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"strings"
 
 	"mumble.info/grumble/pkg/blobstore"
+	"mumble.info/grumble/pkg/geoip"
 	"mumble.info/grumble/pkg/logtarget"
 )
 
 var servers map[int64]*Server
 var blobStore blobstore.BlobStore
+var geoDB *geoip.Database
+
+// The default size of the blob cache, in MiB.
+const defaultBlobCacheSize = 16
 
 func main() {
 	var err error
 
 	flag.Parse()
+	if err := applyEnvArgs(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid environment variable %v\n", err)
+		os.Exit(2)
+	}
 	if Args.ShowHelp == true {
 		Usage()
 		return
@@ -48,18 +62,40 @@ func main() {
 	log.Printf("Grumble")
 	log.Printf("Using data directory: %s", Args.DataDir)
 
+	// Report or run data directory migrations?
+	if Args.Migrate {
+		if err := reportMigrations(); err != nil {
+			log.Fatalf("Unable to check migrations: %v", err)
+		}
+		return
+	}
+	if len(Args.MigrateTo) > 0 {
+		if err := migrateDataDirTo(Args.MigrateTo); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Restore the data directory from a backup?
+	if len(Args.RestoreBackup) > 0 {
+		if err := restoreBackup(Args.RestoreBackup); err != nil {
+			log.Fatalf("Unable to restore backup: %v", err)
+		}
+		return
+	}
+
 	// Open the blobstore.  If the directory doesn't
 	// already exist, create the directory and open
 	// the blobstore.
 	// The Open method of the blobstore performs simple
 	// sanity checking of content of the blob directory,
 	// and will return an error if something's amiss.
+	// Servers may have blob stores of their own (see tenants.go).
 	blobDir := filepath.Join(Args.DataDir, "blob")
-	err = os.Mkdir(blobDir, 0700)
-	if err != nil && !os.IsExist(err) {
-		log.Fatalf("Unable to create blob directory (%v): %v", blobDir, err)
+	blobStore, err = openBlobStore(blobDir)
+	if err != nil {
+		log.Fatalf("Unable to open blob directory (%v): %v", blobDir, err)
 	}
-	blobStore = blobstore.Open(blobDir)
 
 	// Check whether we should regenerate the default global keypair
 	// and corresponding certificate.
@@ -110,6 +146,15 @@ func main() {
 		log.Printf("Private key output to %v", keyFn)
 	}
 
+	// Load the GeoIP database, if one was given.
+	if len(Args.GeoIPDB) > 0 {
+		geoDB, err = geoip.Open(Args.GeoIPDB)
+		if err != nil {
+			log.Fatalf("Unable to load GeoIP database: %v", err)
+		}
+		log.Printf("Loaded %v GeoIP ranges from %v", geoDB.Len(), Args.GeoIPDB)
+	}
+
 	// Should we import data from a Murmur SQLite file?
 	if SQLiteSupport && len(Args.SQLiteDB) > 0 {
 		f, err := os.Open(Args.DataDir)
@@ -136,7 +181,7 @@ func main() {
 		}
 
 		log.Printf("Importing Murmur data from '%s'", Args.SQLiteDB)
-		if err = MurmurImport(Args.SQLiteDB); err != nil {
+		if err = MurmurImport(context.Background(), Args.SQLiteDB, Args.ImportTimeout, Args.ImportRetries); err != nil {
 			log.Fatalf("Murmur import failed: %s", err.Error())
 		}
 
@@ -146,6 +191,11 @@ func main() {
 		return
 	}
 
+	// Bring the data directory up to date.
+	if err := migrateDataDir(); err != nil {
+		log.Fatalf("Unable to migrate data directory: %v", err)
+	}
+
 	// Create the servers directory if it doesn't already
 	// exist.
 	serversDirPath := filepath.Join(Args.DataDir, "servers")
@@ -154,6 +204,19 @@ func main() {
 		log.Fatalf("Unable to create servers directory: %v", err)
 	}
 
+	// Read the configuration file, which may place servers'
+	// state and blobs outside the data directory.
+	cf, err := loadConfigFile()
+	if err != nil {
+		log.Fatalf("Unable to load configuration file: %v", err)
+	}
+
+	// As a standby, keep a copy of the primary's servers until
+	// promoted, and then load them.
+	if len(Args.ReplicateFrom) > 0 {
+		runStandby(cf)
+	}
+
 	// Read all entries of the servers directory.
 	// We need these to load our virtual servers.
 	serversDir, err := os.Open(serversDirPath)
@@ -172,39 +235,110 @@ func main() {
 	}
 
 	// Look through the list of files in the data directory, and
-	// load all virtual servers from disk.
+	// load all virtual servers from disk, along with the servers
+	// kept in their own ServerDir.
 	servers = make(map[int64]*Server)
 	for _, name := range names {
 		if matched, _ := regexp.MatchString("^[0-9]+$", name); matched {
-			log.Printf("Loading server %v", name)
-			s, err := NewServerFromFrozen(name)
+			id, err := strconv.ParseInt(name, 10, 64)
 			if err != nil {
 				log.Fatalf("Unable to load server: %v", err.Error())
 			}
-			err = s.FreezeToFile()
+			s, err := loadServer(cf, id, false)
 			if err != nil {
-				log.Fatalf("Unable to freeze server to disk: %v", err.Error())
+				log.Fatalf("Unable to load server: %v", err.Error())
 			}
 			servers[s.Id] = s
 		}
 	}
+	for _, id := range tenantServerIds(cf) {
+		if _, ok := servers[id]; ok {
+			continue
+		}
+		s, err := loadServer(cf, id, true)
+		if err != nil {
+			log.Fatalf("Unable to load server: %v", err.Error())
+		}
+		servers[s.Id] = s
+	}
 
 	// If no servers were found, create the default virtual server.
 	if len(servers) == 0 {
-		s, err := NewServer(1)
+		s, err := loadServer(cf, 1, true)
 		if err != nil {
 			log.Fatalf("Couldn't start server: %s", err.Error())
 		}
-
 		servers[s.Id] = s
-		os.Mkdir(filepath.Join(serversDirPath, fmt.Sprintf("%v", 1)), 0750)
+	}
+
+	for _, s := range servers {
 		err = s.FreezeToFile()
 		if err != nil {
-			log.Fatalf("Unable to freeze newly created server to disk: %v", err.Error())
+			log.Fatalf("Unable to freeze server to disk: %v", err.Error())
+		}
+	}
+
+	// Should we verify the blob store?
+	if Args.VerifyBlobStore {
+		problems, err := verifyBlobStore(Args.QuarantineBlobs)
+		if err != nil {
+			log.Fatalf("Unable to verify blob store: %v", err)
+		}
+		if problems > 0 {
+			log.Fatalf("Blob store verification found %v problems", problems)
+		}
+		log.Printf("Blob store verified")
+		return
+	}
+
+	// Should we set a server's SuperUser password?
+	if len(Args.SetSUPW) > 0 {
+		id, err := strconv.ParseInt(Args.SetSUPW, 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid server id: %v", Args.SetSUPW)
+		}
+		s, ok := servers[id]
+		if !ok {
+			log.Fatalf("No server with id %v", id)
+		}
+
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			log.Fatalf("Unable to read password: %v", err)
+		}
+		password = strings.TrimRight(password, "\r\n")
+		if len(password) == 0 {
+			log.Fatalf("Refusing to set an empty SuperUser password")
+		}
+
+		s.SetSuperUserPassword(password)
+		err = s.FreezeToFile()
+		if err != nil {
+			log.Fatalf("Unable to freeze server to disk: %v", err.Error())
+		}
+		log.Printf("SuperUser password set for server %v", id)
+		return
+	}
+
+	// Apply the configuration file to the servers.
+	for _, server := range servers {
+		server.applyConfigFile(cf)
+	}
+	logEffectiveConfig(cf)
+
+	// Launch the servers we found during launch, taking over the
+	// sockets of the process we replace, if any.
+	loadInheritedSockets()
+
+	// Launch the health checks first, so that they report the servers
+	// as starting up.
+	if len(Args.HealthAddr) > 0 {
+		err = StartHealth(Args.HealthAddr)
+		if err != nil {
+			log.Fatalf("Unable to start health checks: %v", err)
 		}
 	}
 
-	// Launch the servers we found during launch...
 	for _, server := range servers {
 		err = server.Start()
 		if err != nil {
@@ -212,6 +346,62 @@ func main() {
 		}
 	}
 
+	// Launch the admin API, if requested.
+	if len(Args.APIAddr) > 0 {
+		err = StartAPI(Args.APIAddr)
+		if err != nil {
+			log.Fatalf("Unable to start admin API: %v", err)
+		}
+	}
+
+	// Stream the servers' state to standbys, if requested.
+	if len(Args.ReplicationAddr) > 0 {
+		err = StartReplication(Args.ReplicationAddr)
+		if err != nil {
+			log.Fatalf("Unable to start replication: %v", err)
+		}
+	}
+
+	// Launch the diagnostics endpoint, if requested.
+	if len(Args.DebugAddr) > 0 {
+		err = StartDiagnostics(Args.DebugAddr)
+		if err != nil {
+			log.Fatalf("Unable to start diagnostics: %v", err)
+		}
+	}
+
+	// Launch the Ice endpoint, if requested.
+	if len(Args.IceAddr) > 0 {
+		err = StartIce(Args.IceAddr)
+		if err != nil {
+			log.Fatalf("Unable to start Ice endpoint: %v", err)
+		}
+	}
+
+	// Schedule backups, if requested.
+	if len(Args.BackupTarget) > 0 {
+		err = StartBackups()
+		if err != nil {
+			log.Fatalf("Unable to schedule backups: %v", err)
+		}
+	}
+
+	// Move blobs stored by older versions into the sharded layout.
+	for dir, bs := range blobStores {
+		go func(dir string, bs blobstore.BlobStore) {
+			moved, err := bs.Reshard()
+			if err != nil {
+				log.Printf("Unable to reshard blob directory (%v): %v", dir, err)
+			} else if moved > 0 {
+				log.Printf("Moved %v blobs into the sharded blob directory layout of %v", moved, dir)
+			}
+		}(dir, bs)
+	}
+
+	closeInheritedSockets()
+	setReady(true)
+	notifyServiceManager("READY=1")
+
 	// If any servers were loaded, launch the signal
 	// handler goroutine and sleep...
 	if len(servers) > 0 {
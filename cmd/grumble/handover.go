@@ -0,0 +1,299 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements restarts that hand the listening sockets over
+// to a new process, so that a new binary can be started without
+// turning away clients that connect meanwhile.
+//
+// On Restart, the servers are shut down and the executable is started
+// again, inheriting the listening TCP and UDP sockets. Their keys are
+// passed in the handoverEnv environment variable, in the order of the
+// inherited file descriptors. The new process takes the sockets over
+// instead of listening anew, and connections made while it starts up
+// wait in the sockets' backlog.
+//
+// The sessions of connected clients are handed over as well, with
+// the old process relaying their TLS connections to the new one (see
+// sessionhandover.go). The old process exits once the last of these
+// connections closes.
+//
+// Under a service manager that supports the sd_notify protocol, such as
+// systemd with Type=notify, the old process tells it the new process'
+// PID before exiting, and each process reports when it is ready.
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The environment variable listing the inherited sockets.
+const handoverEnv = "GRUMBLE_LISTEN_FDS"
+
+// The environment variable naming the service manager's notification
+// socket.
+const notifySocketEnv = "NOTIFY_SOCKET"
+
+// A fileSocket is a listening socket that can be handed over.
+type fileSocket interface {
+	File() (*os.File, error)
+	Close() error
+}
+
+var (
+	handoverLock sync.Mutex
+	// Sockets inherited from the previous process, by key
+	inherited map[string]*os.File
+	// Open listening sockets, by key
+	sockets = map[string]fileSocket{}
+)
+
+// loadInheritedSockets picks up the sockets handed over by the
+// previous process, if any.
+func loadInheritedSockets() {
+	keys := os.Getenv(handoverEnv)
+	if len(keys) == 0 {
+		return
+	}
+	os.Unsetenv(handoverEnv)
+
+	handoverLock.Lock()
+	defer handoverLock.Unlock()
+	inherited = make(map[string]*os.File)
+	for i, key := range strings.Split(keys, ",") {
+		inherited[key] = os.NewFile(uintptr(3+i), key)
+	}
+	loadHandedOverSessions()
+	log.Printf("Inherited %v listening sockets and %v sessions", countListeningSockets(inherited), countSessions(handedOverSessions))
+}
+
+// countListeningSockets counts the listening sockets among the
+// inherited files.
+func countListeningSockets(files map[string]*os.File) int {
+	n := 0
+	for key := range files {
+		if !isSessionKey(key) {
+			n++
+		}
+	}
+	return n
+}
+
+// countSessions counts the sessions handed over for all servers.
+func countSessions(sessions map[int64]*serverHandover) int {
+	n := 0
+	for _, sh := range sessions {
+		n += len(sh.Sessions)
+	}
+	return n
+}
+
+// closeInheritedSockets closes the inherited sockets that weren't
+// taken over, such as those for addresses no longer configured, and
+// those of sessions of servers that weren't started.
+func closeInheritedSockets() {
+	handoverLock.Lock()
+	defer handoverLock.Unlock()
+	for key, f := range inherited {
+		log.Printf("Closing unused inherited socket %v", key)
+		f.Close()
+	}
+	inherited = nil
+	closeHandedOverSessions()
+}
+
+// takeInherited removes the inherited socket with the given key, and
+// returns it. It returns nil if there is no such socket.
+func takeInherited(key string) *os.File {
+	f, ok := inherited[key]
+	if !ok {
+		return nil
+	}
+	delete(inherited, key)
+	return f
+}
+
+// listenTCP listens on addr, or takes over the inherited socket
+// listening there.
+func listenTCP(addr *net.TCPAddr) (*net.TCPListener, error) {
+	handoverLock.Lock()
+	defer handoverLock.Unlock()
+
+	key := "tcp/" + addr.String()
+	var tcpl *net.TCPListener
+	if f := takeInherited(key); f != nil {
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if tcpl, ok = l.(*net.TCPListener); !ok {
+			l.Close()
+			return nil, fmt.Errorf("inherited socket %v is not a TCP listener", key)
+		}
+	} else {
+		var err error
+		if tcpl, err = net.ListenTCP("tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	sockets[key] = tcpl
+	return tcpl, nil
+}
+
+// listenUDP listens on addr, or takes over the inherited socket
+// listening there. Sockets with an index above 0 are further sockets
+// sharing the port, which is only possible if reuse was set for all
+// of them (see udpsockets.go).
+func listenUDP(addr *net.UDPAddr, index int, reuse bool) (*net.UDPConn, error) {
+	handoverLock.Lock()
+	defer handoverLock.Unlock()
+
+	key := "udp/" + addr.String()
+	if index > 0 {
+		key += "#" + strconv.Itoa(index+1)
+	}
+	var conn *net.UDPConn
+	if f := takeInherited(key); f != nil {
+		pc, err := net.FilePacketConn(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if conn, ok = pc.(*net.UDPConn); !ok {
+			pc.Close()
+			return nil, fmt.Errorf("inherited socket %v is not a UDP socket", key)
+		}
+	} else {
+		var err error
+		if conn, err = listenUDPReusable(addr, reuse); err != nil {
+			return nil, err
+		}
+	}
+	sockets[key] = conn
+	return conn, nil
+}
+
+// Restart shuts the servers down and starts the executable again,
+// handing over the listening sockets and the clients' sessions. If the
+// new process can't be started, the servers are started again in this
+// one, and the clients handed over are disconnected. Once the new
+// process is started, the caller waits for the relayed connections of
+// the clients handed over with waitRelays before exiting.
+func Restart() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	sessions, relayFiles := handOverSessions()
+
+	handoverLock.Lock()
+	var keys []string
+	var files []*os.File
+	for key, socket := range sockets {
+		f, err := socket.File()
+		if err != nil {
+			// Sockets of stopped servers are closed.
+			continue
+		}
+		keys = append(keys, key)
+		files = append(files, f)
+	}
+	listening := len(files)
+	handoverLock.Unlock()
+
+	ShutdownServers(true)
+
+	// The new process takes the blobstore lock over.
+	unlockBlobStores()
+
+	sessionFile, err := writeSessions(sessions)
+	if err != nil {
+		log.Printf("Unable to hand sessions over: %v", err)
+		for _, f := range relayFiles {
+			f.Close()
+		}
+	} else {
+		keys = append(keys, sessionsKey)
+		files = append(files, sessionFile)
+		for key, f := range relayFiles {
+			keys = append(keys, key)
+			files = append(files, f)
+		}
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), handoverEnv+"="+strings.Join(keys, ","))
+	cmd.ExtraFiles = files
+	err = cmd.Start()
+	for _, f := range files[listening:] {
+		f.Close()
+	}
+	if err == nil {
+		log.Printf("Handed %v listening sockets and %v sessions over to process %v", listening, countSessions(sessions), cmd.Process.Pid)
+		for _, f := range files[:listening] {
+			f.Close()
+		}
+		// Sockets that aren't servers', such as the admin API's,
+		// are left to the new process.
+		handoverLock.Lock()
+		for key, socket := range sockets {
+			socket.Close()
+			delete(sockets, key)
+		}
+		handoverLock.Unlock()
+		notifyServiceManager(fmt.Sprintf("MAINPID=%v", cmd.Process.Pid))
+		return nil
+	}
+
+	log.Printf("Unable to start new process: %v", err)
+	if lerr := lockBlobStores(); lerr != nil {
+		log.Fatalf("Unable to lock blob directory: %v", lerr)
+	}
+	handoverLock.Lock()
+	inherited = make(map[string]*os.File)
+	for i, key := range keys[:listening] {
+		inherited[key] = files[i]
+	}
+	handoverLock.Unlock()
+	for _, server := range servers {
+		if serr := server.Start(); serr != nil {
+			log.Printf("Unable to start server %v: %v", server.Id, serr)
+		}
+	}
+	closeInheritedSockets()
+	setReady(true)
+	return err
+}
+
+// notifyServiceManager sends state to the service manager that started
+// Grumble, if it asked for notifications.
+func notifyServiceManager(state string) {
+	name := os.Getenv(notifySocketEnv)
+	if len(name) == 0 {
+		return
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		log.Printf("Unable to notify service manager: %v", err)
+		return
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		log.Printf("Unable to notify service manager: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// relayPair returns a connected pair of Unix sockets: the local end, and
+// the remote end to be inherited by the new process.
+func relayPair() (net.Conn, *os.File, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	syscall.CloseOnExec(fds[0])
+	syscall.CloseOnExec(fds[1])
+	localFile := os.NewFile(uintptr(fds[0]), "relay")
+	local, err := net.FileConn(localFile)
+	localFile.Close()
+	if err != nil {
+		syscall.Close(fds[1])
+		return nil, nil, err
+	}
+	return local, os.NewFile(uintptr(fds[1]), "relay"), nil
+}
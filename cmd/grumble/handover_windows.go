@@ -0,0 +1,17 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// relayPair is not supported on Windows, where sockets can't be
+// inherited.
+func relayPair() (net.Conn, *os.File, error) {
+	return nil, nil, errors.New("session handover is not supported on Windows")
+}
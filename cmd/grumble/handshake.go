@@ -0,0 +1,100 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the deadlines of the connection handshake.
+//
+// A client has VersionTimeout seconds (default 10) to finish the TLS
+// handshake and send its Version message, and then AuthTimeout seconds
+// (default 30) to send its Authenticate message; 0 means no deadline.
+// Connections that miss a deadline are dropped, so sockets that
+// connect and never speak don't hold on to goroutines and file
+// descriptors. The TLS handshake runs in the client's own receiver
+// goroutine, so slow clients don't hold up the accept loop.
+
+import (
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
+	"io"
+	"time"
+)
+
+// setHandshakeDeadline sets the deadline for the next handshake step
+// to the number of seconds given by the config key, or clears it if
+// key is empty.
+func (client *Client) setHandshakeDeadline(key string) {
+	var deadline time.Time
+	if len(key) > 0 {
+		if timeout := client.server.cfg.IntValue(key); timeout > 0 {
+			deadline = time.Now().Add(time.Duration(timeout) * time.Second)
+		}
+	}
+	client.conn.SetDeadline(deadline)
+}
+
+// readHandshakeMessage reads the message of a handshake step with the
+// deadline given by the config key. If it cannot be read, the client
+// is disconnected.
+func (client *Client) readHandshakeMessage(key string) (*Message, bool) {
+	client.setHandshakeDeadline(key)
+	msg, err := client.readProtoMessage()
+	if err != nil {
+		if err == io.EOF {
+			client.Disconnect()
+		} else if isTimeout(err) {
+			client.Printf("Handshake timed out")
+			client.Disconnect()
+		} else {
+			client.Panicf("%v", err)
+		}
+		return nil, false
+	}
+	client.setHandshakeDeadline("")
+	return msg, true
+}
+
+// tlsHandshake performs the TLS handshake of a direct connection, and
+// extracts the client's certificate hash. WebSocket connections don't
+// support TLS-level client certificates. If the handshake fails, or
+// the certificate is banned, the client is disconnected.
+func (client *Client) tlsHandshake() bool {
+	tlsconn, ok := client.conn.(*tls.Conn)
+	if !ok {
+		return true
+	}
+	server := client.server
+
+	client.setHandshakeDeadline("VersionTimeout")
+	err := tlsconn.Handshake()
+	if err != nil {
+		client.Printf("TLS handshake failed: %v", err)
+		client.Disconnect()
+		return false
+	}
+
+	state := tlsconn.ConnectionState()
+	if len(state.PeerCertificates) > 0 {
+		hash := sha1.New()
+		hash.Write(state.PeerCertificates[0].Raw)
+		sum := hash.Sum(nil)
+		client.certHash = hex.EncodeToString(sum)
+
+		roots, err := server.certPool("CertCAFile")
+		if err != nil {
+			client.Printf("Unable to load CAs to verify certificate: %v", err)
+		} else {
+			client.verified = verifyClientCertificate(state.PeerCertificates, roots)
+		}
+	}
+
+	// Check whether the client's cert hash is banned
+	if server.IsCertHashBanned(client.CertHash()) {
+		client.Printf("Certificate hash is banned")
+		client.Disconnect()
+		return false
+	}
+	return true
+}
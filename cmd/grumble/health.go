@@ -0,0 +1,247 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the health checks used by orchestrators such
+// as Kubernetes.
+//
+// The --health-addr argument serves /healthz and /readyz, without
+// authentication, so that they can be probed from outside the host.
+// /healthz is the liveness probe: it fails only if the handler
+// goroutine of a running server stops responding, in which case
+// Grumble should be restarted. /readyz is the readiness probe: it
+// also fails while Grumble starts up or shuts down, if a server isn't
+// running, if it lost its TCP or UDP listeners or its freeze log, or
+// if the servers' directories or the blob store can't be written to.
+//
+// Both answer with status 200 or 503, and a JSON report of each check.
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The time a server's handler goroutine has to respond to a probe.
+const healthTimeout = 5 * time.Second
+
+// healthReady is 1 while the servers are up, and 0 while they start
+// up or shut down.
+var healthReady int32
+
+// setReady marks Grumble as ready to take clients, or not.
+func setReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&healthReady, 1)
+	} else {
+		atomic.StoreInt32(&healthReady, 0)
+	}
+}
+
+// healthServer is the JSON representation of a virtual server's
+// health.
+type healthServer struct {
+	Id           int64  `json:"id"`
+	State        string `json:"state"`
+	TCPListeners int    `json:"tcp_listeners"`
+	UDPListeners int    `json:"udp_listeners"`
+	FreezeLog    bool   `json:"freeze_log"`
+	Error        string `json:"error,omitempty"`
+
+	responsive bool
+}
+
+// healthReport is the JSON body of a probe's response.
+type healthReport struct {
+	Status  string            `json:"status"`
+	Checks  map[string]string `json:"checks"`
+	Servers []healthServer    `json:"servers"`
+}
+
+// checkServerHealth reports the state of the server, as seen from its
+// handler goroutine.
+func (server *Server) checkServerHealth() healthServer {
+	stopped := healthServer{Id: server.Id, State: "stopped", Error: "not running", responsive: true}
+	if !server.isRunning() {
+		return stopped
+	}
+	hs := healthServer{Id: server.Id, State: "running"}
+
+	// A stuck handler goroutine may still run the call once the probe
+	// gave up, so its results are passed back on a channel.
+	type result struct {
+		hs  healthServer
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res := result{hs: hs}
+		res.err = server.runSync(func() {
+			res.hs.TCPListeners = len(server.tcpls)
+			res.hs.UDPListeners = len(server.udpconns)
+			res.hs.FreezeLog = server.freezelog != nil
+		})
+		done <- res
+	}()
+	var err error
+	select {
+	case res := <-done:
+		hs, err = res.hs, res.err
+	case <-time.After(healthTimeout):
+		err = errAPITimeout
+	}
+	if err != nil {
+		if !server.isRunning() {
+			// Stopped in the meantime.
+			return stopped
+		}
+		hs.State = "unresponsive"
+		hs.Error = err.Error()
+		return hs
+	}
+	hs.responsive = true
+
+	switch {
+	case hs.TCPListeners == 0:
+		hs.Error = "no TCP listeners"
+	case hs.UDPListeners == 0:
+		hs.Error = "no UDP listeners"
+	case !hs.FreezeLog:
+		hs.Error = "freeze log not open"
+	default:
+		if err := checkDirWritable(server.dir); err != nil {
+			hs.Error = err.Error()
+		}
+	}
+	return hs
+}
+
+// checkDirWritable checks that files can be written to dir, by
+// creating and removing one.
+func checkDirWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".check_")
+	if err != nil {
+		return err
+	}
+	err = f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// checkHealth runs the checks of a probe. Liveness only requires the
+// running servers to respond.
+func checkHealth(readiness bool) (report healthReport, ok bool) {
+	report = healthReport{Status: "ok", Checks: map[string]string{}}
+	ok = true
+	fail := func(check string, err error) {
+		report.Checks[check] = err.Error()
+		ok = false
+	}
+
+	ids := []int64{}
+	for id := range servers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	report.Servers = make([]healthServer, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, server *Server) {
+			defer wg.Done()
+			report.Servers[i] = server.checkServerHealth()
+		}(i, servers[id])
+	}
+	wg.Wait()
+	for _, hs := range report.Servers {
+		if !hs.responsive || (readiness && len(hs.Error) > 0) {
+			ok = false
+		}
+	}
+
+	if readiness {
+		report.Checks["startup"] = "ok"
+		if atomic.LoadInt32(&healthReady) == 0 {
+			fail("startup", errors.New("starting or shutting down"))
+		}
+		report.Checks["blobstore"] = "ok"
+		for _, dir := range sortedBlobDirs() {
+			if err := blobStores[dir].CheckWritable(); err != nil {
+				fail("blobstore", err)
+				break
+			}
+		}
+	}
+
+	if !ok {
+		report.Status = "fail"
+	}
+	return report, ok
+}
+
+// handleHealthz implements /healthz.
+//
+//	GET  reports whether the running servers respond
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	handleProbe(w, r, false)
+}
+
+// handleReadyz implements /readyz.
+//
+//	GET  reports whether Grumble is ready to take clients
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	handleProbe(w, r, true)
+}
+
+func handleProbe(w http.ResponseWriter, r *http.Request, readiness bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	report, ok := checkHealth(readiness)
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// StartHealth serves the health checks on the given address.
+func StartHealth(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", handleReadyz)
+
+	tcpaddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return err
+	}
+	l, err := listenTCP(tcpaddr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 2 * healthTimeout,
+	}
+	go func() {
+		err := srv.Serve(l)
+		if err != nil {
+			log.Printf("Health checks stopped: %v", err)
+		}
+	}()
+	log.Printf("Health checks listening on %v", addr)
+	return nil
+}
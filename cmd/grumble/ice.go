@@ -0,0 +1,423 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements a subset of Murmur's Ice interface (Murmur.ice,
+// as shipped with Murmur 1.3), so that existing Murmur web interfaces
+// and bots can manage Grumble.
+//
+// The Ice endpoint is served on the address given by the --ice-addr
+// argument. Clients must pass the secret stored in $DATADIR/ice.secret
+// in the "secret" context key, like Murmur's icesecretwrite.
+//
+// The Meta object supports getServer, getAllServers, getBootedServers,
+// getVersion and getUptime. Server objects support isRunning, id,
+// getUptime, getConf, getAllConf, setConf, getUsers, getState,
+// kickUser, sendMessage, sendMessageChannel, getChannels,
+// getChannelState and getRegisteredUsers. Other operations fail with
+// OperationNotExistException.
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/ice"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/serverconf"
+)
+
+// The Murmur version reported by Meta.getVersion.
+const (
+	iceVersionMajor = 1
+	iceVersionMinor = 3
+	iceVersionPatch = 0
+)
+
+// The time Grumble was started, for Meta.getUptime.
+var processStart = time.Now()
+
+// Murmur's exceptions, most derived type first.
+var (
+	iceInvalidSession = &ice.UserException{TypeIds: []string{"::Murmur::InvalidSessionException", "::Murmur::MurmurException"}}
+	iceInvalidChannel = &ice.UserException{TypeIds: []string{"::Murmur::InvalidChannelException", "::Murmur::MurmurException"}}
+	iceServerBooted   = &ice.UserException{TypeIds: []string{"::Murmur::ServerBootedException", "::Murmur::MurmurException"}}
+	iceInvalidSecret  = &ice.UserException{TypeIds: []string{"::Murmur::InvalidSecretException", "::Murmur::MurmurException"}}
+	iceInvalidInput   = &ice.UserException{TypeIds: []string{"::Murmur::InvalidInputDataException", "::Murmur::MurmurException"}}
+)
+
+// iceHandler dispatches Ice requests to the Meta and Server objects.
+type iceHandler struct {
+	secret string
+}
+
+// StartIce launches the Ice endpoint on the given address.
+func StartIce(addr string) error {
+	secret, err := loadTokenFile("ice.secret", "Ice secret")
+	if err != nil {
+		return err
+	}
+	tcpaddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return err
+	}
+	l, err := listenTCP(tcpaddr)
+	if err != nil {
+		return err
+	}
+	srv := &ice.Server{
+		Handler:  &iceHandler{secret: secret},
+		ErrorLog: log.New(log.Writer(), log.Prefix(), log.Flags()),
+	}
+	go func() {
+		err := srv.Serve(l)
+		log.Printf("Ice endpoint stopped: %v", err)
+	}()
+	log.Printf("Ice endpoint listening on %v", addr)
+	return nil
+}
+
+func (h *iceHandler) ServeIce(req *ice.Request, out *ice.Encoder) error {
+	switch {
+	case req.Identity.Name == "Meta" && len(req.Identity.Category) == 0:
+		if ice.ServeObject(req, out, "::Murmur::Meta") {
+			return nil
+		}
+		if err := h.checkSecret(req); err != nil {
+			return err
+		}
+		return serveIceMeta(req, out)
+	case req.Identity.Category == "s":
+		id, err := strconv.ParseInt(req.Identity.Name, 10, 64)
+		if err != nil {
+			return ice.ErrObjectNotExist
+		}
+		server, ok := servers[id]
+		if !ok {
+			return ice.ErrObjectNotExist
+		}
+		if ice.ServeObject(req, out, "::Murmur::Server") {
+			return nil
+		}
+		if err := h.checkSecret(req); err != nil {
+			return err
+		}
+		return server.serveIce(req, out)
+	}
+	return ice.ErrObjectNotExist
+}
+
+func (h *iceHandler) checkSecret(req *ice.Request) error {
+	if subtle.ConstantTimeCompare([]byte(req.Context["secret"]), []byte(h.secret)) != 1 {
+		return iceInvalidSecret
+	}
+	return nil
+}
+
+// iceServerProxy returns a proxy for the Server object of the virtual
+// server with the given id, reachable through the connection req
+// arrived on.
+func iceServerProxy(req *ice.Request, id int64) ice.Proxy {
+	p := ice.Proxy{Identity: ice.Identity{Name: strconv.FormatInt(id, 10), Category: "s"}}
+	if addr, ok := req.Conn.LocalAddr().(*net.TCPAddr); ok {
+		p.Endpoints = []ice.Endpoint{{Host: addr.IP.String(), Port: int32(addr.Port), Timeout: -1}}
+	}
+	return p
+}
+
+func serveIceMeta(req *ice.Request, out *ice.Encoder) error {
+	switch req.Operation {
+	case "getServer":
+		id := int64(req.Params.ReadInt())
+		if _, ok := servers[id]; ok {
+			out.WriteProxy(iceServerProxy(req, id))
+		} else {
+			out.WriteProxy(ice.Proxy{})
+		}
+	case "getAllServers", "getBootedServers":
+		ids := []int64{}
+		for id, server := range servers {
+			if req.Operation == "getAllServers" || server.isRunning() {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		out.WriteSize(len(ids))
+		for _, id := range ids {
+			out.WriteProxy(iceServerProxy(req, id))
+		}
+	case "getVersion":
+		out.WriteInt(iceVersionMajor)
+		out.WriteInt(iceVersionMinor)
+		out.WriteInt(iceVersionPatch)
+		out.WriteString("Grumble " + version)
+	case "getUptime":
+		out.WriteInt(int32(time.Since(processStart).Seconds()))
+	default:
+		return ice.ErrOperationNotExist
+	}
+	return req.Params.Err()
+}
+
+// serveIce handles an operation on the server's Server object.
+func (server *Server) serveIce(req *ice.Request, out *ice.Encoder) error {
+	// Operations that do not need the server to be running.
+	switch req.Operation {
+	case "isRunning":
+		out.WriteBool(server.isRunning())
+		return nil
+	case "id":
+		out.WriteInt(int32(server.Id))
+		return nil
+	case "getConf":
+		key, ok := serverconf.MurmurKey(req.Params.ReadString())
+		if req.Params.Err() != nil {
+			return req.Params.Err()
+		}
+		if !ok {
+			return iceInvalidInput
+		}
+		out.WriteString(server.cfg.StringValue(key))
+		return nil
+	case "setConf":
+		return server.iceSetConf(req.Params)
+	case "getAllConf":
+		out.WriteStringMap(server.cfg.GetAll())
+		return nil
+	}
+
+	var opErr error
+	err := server.runSync(func() {
+		opErr = server.serveIceSync(req, out)
+	})
+	if err != nil {
+		return iceServerBooted
+	}
+	return opErr
+}
+
+// iceSetConf implements setConf. Keys may be given by their Murmur or
+// Grumble names, and values are checked like those of the
+// configuration file. Passwords are stored hashed. As in Murmur, an
+// empty value resets the key to its default.
+func (server *Server) iceSetConf(p *ice.Decoder) error {
+	name := p.ReadString()
+	value := p.ReadString()
+	if p.Err() != nil {
+		return p.Err()
+	}
+	key, ok := serverconf.MurmurKey(name)
+	if !ok {
+		server.Printf("Ice: rejected setConf of unknown key %v", name)
+		return iceInvalidInput
+	}
+	if len(value) > 0 {
+		if err := serverconf.CheckValue(key, value); err != nil {
+			server.Printf("Ice: rejected setConf: %v", err)
+			return iceInvalidInput
+		}
+		if key == "ServerPassword" {
+			// Hashing the password is slow, so keep it off the
+			// handler goroutine.
+			value = server.hashConfigPassword(value)
+		}
+	}
+
+	err := server.runSync(func() {
+		old := configSnapshot(server.cfg)
+		if len(value) == 0 {
+			server.cfg.Reset(key)
+			server.ResetConfig(key)
+		} else {
+			server.cfg.Set(key, value)
+			server.UpdateConfig(key, value)
+		}
+		server.configChanged(old)
+		server.auditIce(auditlog.Entry{Action: "config.set", Target: key})
+	})
+	if err != nil {
+		return iceServerBooted
+	}
+	return nil
+}
+
+// serveIceSync handles the operations that need access to the server's
+// state.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) serveIceSync(req *ice.Request, out *ice.Encoder) error {
+	p := req.Params
+	switch req.Operation {
+	case "getUptime":
+		out.WriteInt(int32(time.Since(server.startTime).Seconds()))
+	case "getUsers":
+		sessions := []uint32{}
+		for session, client := range server.clients {
+			if client.state == StateClientReady {
+				sessions = append(sessions, session)
+			}
+		}
+		sort.Slice(sessions, func(i, j int) bool { return sessions[i] < sessions[j] })
+		out.WriteSize(len(sessions))
+		for _, session := range sessions {
+			out.WriteInt(int32(session))
+			writeIceUser(out, server.clients[session])
+		}
+	case "getState":
+		client, err := server.iceClient(p.ReadInt())
+		if err != nil {
+			return err
+		}
+		writeIceUser(out, client)
+	case "kickUser":
+		client, err := server.iceClient(p.ReadInt())
+		if err != nil {
+			return err
+		}
+		reason := p.ReadString()
+		server.KickClient(client, reason)
+	case "sendMessage":
+		client, err := server.iceClient(p.ReadInt())
+		if err != nil {
+			return err
+		}
+		client.sendMessage(&mumbleproto.TextMessage{
+			Session: []uint32{client.Session()},
+			Message: proto.String(p.ReadString()),
+		})
+	case "sendMessageChannel":
+		channel, ok := server.Channels[int(p.ReadInt())]
+		if !ok {
+			return iceInvalidChannel
+		}
+		tree := p.ReadBool()
+		text := p.ReadString()
+		channels := []*Channel{channel}
+		if tree {
+			for _, sub := range channel.AllSubChannels() {
+				channels = append(channels, sub)
+			}
+		}
+		for _, c := range channels {
+			for _, client := range c.clients {
+				client.sendMessage(&mumbleproto.TextMessage{
+					ChannelId: []uint32{uint32(c.Id)},
+					Message:   proto.String(text),
+				})
+			}
+		}
+	case "getChannels":
+		ids := []int{}
+		for id := range server.Channels {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		out.WriteSize(len(ids))
+		for _, id := range ids {
+			out.WriteInt(int32(id))
+			writeIceChannel(out, server, server.Channels[id])
+		}
+	case "getChannelState":
+		channel, ok := server.Channels[int(p.ReadInt())]
+		if !ok {
+			return iceInvalidChannel
+		}
+		writeIceChannel(out, server, channel)
+	case "getRegisteredUsers":
+		users, _, err := server.searchUsers(userQuery{Name: p.ReadString()})
+		if err != nil {
+			return err
+		}
+		out.WriteSize(len(users))
+		for _, user := range users {
+			out.WriteInt(int32(user.Id))
+			out.WriteString(user.Name)
+		}
+	default:
+		return ice.ErrOperationNotExist
+	}
+	return p.Err()
+}
+
+// iceClient looks up a connected client by session.
+func (server *Server) iceClient(session int32) (*Client, error) {
+	client, ok := server.clients[uint32(session)]
+	if !ok || client.state != StateClientReady {
+		return nil, iceInvalidSession
+	}
+	return client, nil
+}
+
+// auditIce records an administrative action taken through Ice.
+func (server *Server) auditIce(entry auditlog.Entry) {
+	entry.Actor = "ice"
+	server.audit(nil, entry)
+}
+
+// writeIceUser writes a client as a Murmur::User struct.
+func writeIceUser(out *ice.Encoder, client *Client) {
+	out.WriteInt(int32(client.Session()))
+	out.WriteInt(int32(client.UserId()))
+	out.WriteBool(client.Mute)
+	out.WriteBool(client.Deaf)
+	out.WriteBool(client.Suppress)
+	out.WriteBool(client.PrioritySpeaker)
+	out.WriteBool(client.SelfMute)
+	out.WriteBool(client.SelfDeaf)
+	out.WriteBool(client.Recording)
+	out.WriteInt(int32(client.Channel.Id))
+	out.WriteString(client.ShownName())
+	out.WriteInt(int32(time.Since(client.connectedAt).Seconds()))
+	out.WriteInt(0) // bytespersec
+	out.WriteInt(int32(client.Version))
+	out.WriteString(client.ClientName)
+	out.WriteString(client.OSName)
+	out.WriteString(client.OSVersion)
+	out.WriteString(client.PluginIdentity)
+	out.WriteString(string(client.PluginContext))
+	comment := ""
+	if client.IsRegistered() && client.user.HasComment() {
+		if buf, err := client.server.blobs.Get(client.user.CommentBlob); err == nil {
+			comment = string(buf)
+		}
+	}
+	out.WriteString(comment)
+	out.WriteBytes(client.tcpaddr.IP.To16())
+	out.WriteBool(!client.usesUDP())
+	out.WriteInt(0) // idlesecs
+	out.WriteFloat(client.UdpPingAvg)
+	out.WriteFloat(client.TcpPingAvg)
+}
+
+// writeIceChannel writes a channel as a Murmur::Channel struct.
+func writeIceChannel(out *ice.Encoder, server *Server, channel *Channel) {
+	out.WriteInt(int32(channel.Id))
+	out.WriteString(channel.Name)
+	parent := int32(-1)
+	if channel.parent != nil {
+		parent = int32(channel.parent.Id)
+	}
+	out.WriteInt(parent)
+	links := []int32{}
+	for id := range channel.Links {
+		links = append(links, int32(id))
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i] < links[j] })
+	out.WriteIntSeq(links)
+	description := ""
+	if channel.HasDescription() {
+		if buf, err := server.blobs.Get(channel.DescriptionBlob); err == nil {
+			description = string(buf)
+		}
+	}
+	out.WriteString(description)
+	out.WriteBool(channel.IsTemporary())
+	out.WriteInt(int32(channel.Position))
+}
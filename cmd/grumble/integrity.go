@@ -0,0 +1,95 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file keeps the references between frozen channels, ACLs, groups
+// and users consistent.
+//
+// The datastore records a removed channel or user as a single log
+// entry. When the log is replayed, the removal cascades the same way
+// it did on the running server: a removed channel takes its
+// subchannels and the links to it along, and a removed user their ACL
+// entries and group memberships. Once a server is loaded, references
+// that still point nowhere, such as those left behind by older
+// versions, are dropped and logged.
+
+import (
+	"log"
+)
+
+// removeFrozenChannel removes a channel read from the datastore along
+// with its subchannels and the links to it. The channel tree is not
+// hooked up yet, so parents maps the ids of channels to those of their
+// parents.
+func (s *Server) removeFrozenChannel(id uint32, parents map[uint32]uint32) {
+	delete(s.Channels, int(id))
+	delete(parents, id)
+	for child, parent := range parents {
+		if parent == id {
+			s.removeFrozenChannel(child, parents)
+		}
+	}
+	for _, channel := range s.Channels {
+		delete(channel.Links, int(id))
+	}
+}
+
+// removeFrozenUserReferences removes the ACL entries and group
+// memberships of a user removed from the datastore.
+func (s *Server) removeFrozenUserReferences(uid uint32) {
+	for _, channel := range s.Channels {
+		s.removeRegisteredUserFromACL(uid, channel)
+	}
+}
+
+// checkIntegrity drops the references of a freshly loaded server to
+// channels and users that don't exist, before the channel tree is
+// hooked up. Channels whose parent is missing are dropped with their
+// subchannels.
+func (s *Server) checkIntegrity(parents map[uint32]uint32) {
+	for id, parent := range parents {
+		if _, ok := s.Channels[int(parent)]; ok {
+			continue
+		}
+		if _, ok := parents[id]; !ok {
+			// Already removed as the subchannel of another orphan.
+			continue
+		}
+		log.Printf("[%v] Dropped channel %v: Parent channel %v doesn't exist.", s.Id, id, parent)
+		s.removeFrozenChannel(id, parents)
+	}
+
+	for _, channel := range s.Channels {
+		for link := range channel.Links {
+			if _, ok := s.Channels[link]; !ok {
+				log.Printf("[%v] Dropped link from channel %v: Channel %v doesn't exist.", s.Id, channel.Id, link)
+				delete(channel.Links, link)
+			}
+		}
+
+		acls := channel.ACL.ACLs[:0]
+		for _, chanacl := range channel.ACL.ACLs {
+			if chanacl.IsUserACL() {
+				if _, ok := s.Users[uint32(chanacl.UserId)]; !ok {
+					log.Printf("[%v] Dropped ACL entry of channel %v: User %v doesn't exist.", s.Id, channel.Id, chanacl.UserId)
+					continue
+				}
+			}
+			acls = append(acls, chanacl)
+		}
+		channel.ACL.ACLs = acls
+
+		for _, grp := range channel.ACL.Groups {
+			for _, members := range []map[int]bool{grp.Add, grp.Remove} {
+				for uid := range members {
+					if _, ok := s.Users[uint32(uid)]; !ok {
+						log.Printf("[%v] Dropped member %v of group %v in channel %v: User doesn't exist.", s.Id, uid, grp.Name, channel.Id)
+						delete(members, uid)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements invites.
+//
+// An invite is a secret token that lets unregistered users into a
+// closed server, that is, a server with a server password or with
+// InviteOnly set. The token is given in place of the server password,
+// either in the password field or in a mumble:// URL. Invites can be
+// limited in how often and for how long they can be used, and can put
+// the users that join through them into groups of the root channel
+// and give them access tokens.
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/freezer"
+)
+
+// An Invite allows unregistered users into a closed server.
+type Invite struct {
+	Id        uint32
+	TokenHash string
+	// The number of times the invite may be used. Zero means unlimited.
+	MaxUses uint32
+	Uses    uint32
+	// Unix time after which the invite can no longer be used.
+	// Zero means never.
+	Expires int64
+	// Groups of the root channel that users joining through
+	// the invite are temporarily added to.
+	Groups []string
+	// Access tokens given to users joining through the invite.
+	Tokens []string
+	Note   string
+}
+
+// IsUsable checks whether the invite can still be used.
+func (invite *Invite) IsUsable() bool {
+	if invite.MaxUses > 0 && invite.Uses >= invite.MaxUses {
+		return false
+	}
+	if invite.Expires > 0 && time.Now().Unix() > invite.Expires {
+		return false
+	}
+	return true
+}
+
+// hashInviteToken returns the hash under which a token is stored.
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// isClosed checks whether unregistered users need a password
+// or an invite to connect.
+func (server *Server) isClosed() bool {
+	return server.hasServerPassword() || server.cfg.BoolValue("InviteOnly")
+}
+
+// CreateInvite creates a new invite and returns it along with its token.
+// The token itself is not stored.
+func (server *Server) CreateInvite(maxUses uint32, duration time.Duration, groups, tokens []string, note string) (*Invite, string, error) {
+	raw := make([]byte, 16)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	invite := &Invite{
+		Id:        server.nextInviteId,
+		TokenHash: hashInviteToken(token),
+		MaxUses:   maxUses,
+		Groups:    groups,
+		Tokens:    tokens,
+		Note:      note,
+	}
+	if duration > 0 {
+		invite.Expires = time.Now().Add(duration).Unix()
+	}
+	server.nextInviteId += 1
+	server.Invites[invite.Id] = invite
+	server.UpdateFrozenInvite(invite)
+	server.Printf("Created invite %v", invite.Id)
+	return invite, token, nil
+}
+
+// RevokeInvite removes an invite.
+func (server *Server) RevokeInvite(id uint32) bool {
+	if _, ok := server.Invites[id]; !ok {
+		return false
+	}
+	delete(server.Invites, id)
+	server.DeleteFrozenInvite(id)
+	server.Printf("Revoked invite %v", id)
+	return true
+}
+
+// findInvite looks up a usable invite matching one of the
+// given secrets.
+func (server *Server) findInvite(secrets ...string) *Invite {
+	for _, secret := range secrets {
+		if len(secret) == 0 {
+			continue
+		}
+		hash := hashInviteToken(secret)
+		for _, invite := range server.Invites {
+			if invite.TokenHash == hash && invite.IsUsable() {
+				return invite
+			}
+		}
+	}
+	return nil
+}
+
+// useInvite records that client has joined the server using invite,
+// and gives the client the invite's groups and access tokens.
+func (server *Server) useInvite(client *Client, invite *Invite) {
+	invite.Uses += 1
+	err := server.freezelog.Put(&freezer.Invite{
+		Id:   proto.Uint32(invite.Id),
+		Uses: proto.Uint32(invite.Uses),
+	})
+	if err != nil {
+		server.Fatal(err)
+	}
+	server.numLogOps += 1
+
+	client.Printf("Joined using invite %v (%v/%v uses)", invite.Id, invite.Uses, invite.MaxUses)
+	client.inviteTokens = invite.Tokens
+
+	root := server.RootChannel()
+	for _, name := range invite.Groups {
+		group, ok := root.ACL.Groups[name]
+		if !ok {
+			client.Printf("Invite group %v does not exist in the root channel", name)
+			continue
+		}
+		group.Temporary[-int(client.Session())] = true
+		client.inviteGroups = append(client.inviteGroups, name)
+	}
+	server.ClearCaches()
+}
+
+// releaseInviteGroups removes a disconnecting client from the groups
+// it was added to by an invite.
+func (server *Server) releaseInviteGroups(client *Client) {
+	root := server.RootChannel()
+	for _, name := range client.inviteGroups {
+		if group, ok := root.ACL.Groups[name]; ok {
+			delete(group.Temporary, -int(client.Session()))
+		}
+	}
+	client.inviteGroups = nil
+	server.ClearCaches()
+}
+
+// apiInvite is the JSON representation of an invite.
+type apiInvite struct {
+	Id       uint32   `json:"id"`
+	Token    string   `json:"token,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	MaxUses  uint32   `json:"max_uses"`
+	Uses     uint32   `json:"uses"`
+	Expires  string   `json:"expires,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Tokens   []string `json:"tokens,omitempty"`
+	Note     string   `json:"note,omitempty"`
+}
+
+func (invite *Invite) apiInvite() apiInvite {
+	ai := apiInvite{
+		Id:      invite.Id,
+		MaxUses: invite.MaxUses,
+		Uses:    invite.Uses,
+		Groups:  invite.Groups,
+		Tokens:  invite.Tokens,
+		Note:    invite.Note,
+	}
+	if invite.Expires > 0 {
+		ai.Expires = time.Unix(invite.Expires, 0).UTC().Format(time.RFC3339)
+	}
+	return ai
+}
+
+// inviteURL returns a mumble:// URL for the invite token, if the
+// server's public host name is known.
+func (server *Server) inviteURL(token string) string {
+	host := server.cfg.StringValue("RegisterHost")
+	if len(host) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("mumble://:%v@%v/?version=1.2.0", token, net.JoinHostPort(host, strconv.Itoa(server.Port())))
+}
+
+func init() {
+	registerAPIEndpoint("invites", handleAPIInvites)
+}
+
+// handleAPIInvites implements /servers/<id>/invites.
+//
+//	GET           lists the invites
+//	POST          creates an invite: {"max_uses": 1, "duration": "72h", "groups": ["guests"]}
+//	DELETE /<id>  revokes an invite
+//
+// The invite's token is only included in the response to the POST request.
+func handleAPIInvites(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var req apiInvite
+	var duration time.Duration
+	var id uint64
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if len(req.Duration) > 0 {
+			var err error
+			duration, err = time.ParseDuration(req.Duration)
+			if err != nil || duration <= 0 {
+				apiError(w, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+	case http.MethodDelete:
+		var err error
+		if len(args) == 1 {
+			id, err = strconv.ParseUint(args[0], 10, 32)
+		}
+		if len(args) != 1 || err != nil {
+			apiError(w, http.StatusNotFound, "expected /invites/<id>")
+			return
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		switch r.Method {
+		case http.MethodPost:
+			invite, token, err := server.CreateInvite(req.MaxUses, duration, req.Groups, req.Tokens, req.Note)
+			if err != nil {
+				status, reply = http.StatusInternalServerError, map[string]string{"error": err.Error()}
+				return
+			}
+			ai := invite.apiInvite()
+			ai.Token = token
+			ai.URL = server.inviteURL(token)
+			status, reply = http.StatusCreated, ai
+			return
+		case http.MethodDelete:
+			if !server.RevokeInvite(uint32(id)) {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such invite"}
+				return
+			}
+		}
+
+		invites := []apiInvite{}
+		for _, invite := range server.Invites {
+			invites = append(invites, invite.apiInvite())
+		}
+		sort.Slice(invites, func(i, j int) bool { return invites[i].Id < invites[j].Id })
+		reply = invites
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
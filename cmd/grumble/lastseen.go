@@ -0,0 +1,137 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the connection history of registered users.
+//
+// When a registered user connects, the time and the address they
+// connect from are recorded, as well as the time for the certificate
+// they connect with. When they disconnect, the time, the
+// channel they were in and the time they spent connected are added.
+// The history is shown in clients' registered user lists and by the
+// admin API.
+//
+// With HashUserAddresses set, addresses are stored as keyed hashes, so
+// that an administrator can tell whether two users connected from the
+// same address without the address itself being kept. The key is
+// generated once per server and stored with its configuration.
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"mumble.info/grumble/pkg/ban"
+)
+
+// The configuration key under which the address hash key is stored.
+const addressHashKeyConfig = "AddressHashKey"
+
+// addressHashKey returns the key addresses are hashed with, generating
+// it if there is none yet.
+func (server *Server) addressHashKey() []byte {
+	key, err := hex.DecodeString(server.cfg.StringValue(addressHashKeyConfig))
+	if err == nil && len(key) == sha256.Size {
+		return key
+	}
+	key = make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		server.Panicf("Unable to generate address hash key: %v", err)
+	}
+	val := hex.EncodeToString(key)
+	server.cfg.Set(addressHashKeyConfig, val)
+	server.UpdateConfig(addressHashKeyConfig, val)
+	return key
+}
+
+// userAddress returns the address recorded for client: the address
+// itself, or its hash if HashUserAddresses is set.
+func (server *Server) userAddress(client *Client) string {
+	addr := client.tcpaddr.IP.String()
+	if !server.cfg.BoolValue("HashUserAddresses") {
+		return addr
+	}
+	mac := hmac.New(sha256.New, server.addressHashKey())
+	mac.Write([]byte(addr))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// recordConnect records that a registered client connected.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) recordConnect(client *Client) {
+	if !client.IsRegistered() {
+		return
+	}
+	user := client.user
+	now := time.Now().Unix()
+	user.LastActive = uint64(now)
+	user.LastAddress = server.userAddress(client)
+	// Only full records carry the certificates, each of which
+	// records when it was last seen.
+	if cert := user.Certificate(client.CertHash()); cert != nil {
+		cert.LastSeen = now
+		server.UpdateFrozenUserRecord(user)
+		return
+	}
+	server.UpdateFrozenUserHistory(user)
+}
+
+// recordDisconnect records that a registered client disconnected.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) recordDisconnect(client *Client) {
+	if !client.IsRegistered() {
+		return
+	}
+	user := client.user
+	// A user whose registration was removed while connected has
+	// no history to keep.
+	if server.Users[user.Id] != user {
+		return
+	}
+	now := time.Now()
+	user.LastActive = uint64(now.Unix())
+	if client.Channel != nil {
+		user.LastChannelId = client.Channel.Id
+	}
+	user.ConnectedTime += uint64(now.Sub(client.connectedAt) / time.Second)
+	server.UpdateFrozenUserHistory(user)
+}
+
+// onlineUsers maps the registered users that are connected to their
+// clients.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) onlineUsers() map[*User]*Client {
+	online := make(map[*User]*Client)
+	for _, client := range server.clients {
+		if client.IsRegistered() && client.state == StateClientReady {
+			online[client.user] = client
+		}
+	}
+	return online
+}
+
+// userConnectedTime returns the time user has spent connected in all,
+// including the current connection of client, if the user is online.
+func userConnectedTime(user *User, client *Client) time.Duration {
+	total := time.Duration(user.ConnectedTime) * time.Second
+	if client != nil {
+		total += time.Since(client.connectedAt)
+	}
+	return total.Truncate(time.Second)
+}
+
+// userLastSeen returns when user was last seen, in the format of
+// Mumble's registered user list, or an empty string if never.
+func userLastSeen(user *User) string {
+	if user.LastActive == 0 {
+		return ""
+	}
+	return time.Unix(int64(user.LastActive), 0).UTC().Format(ban.ISODate)
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements maintenance mode.
+//
+// While Maintenance is set, only admins may connect: SuperUser and the
+// members of the root channel's admin group. Everyone else is turned
+// away with MaintenanceMessage. Users who are already connected stay,
+// but if MaintenanceChannel is set, those who aren't admins are moved
+// to that channel and can't leave it by themselves. When maintenance
+// ends, they are moved back to the channels they came from, if they may
+// still enter them. Admins turn maintenance mode on and off through the
+// admin API, or by changing the configuration and reloading it.
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The configuration keys that start or end maintenance.
+var maintenanceConfigKeys = []string{"Maintenance", "MaintenanceChannel"}
+
+// maintenanceChannel returns the channel users are held in during
+// maintenance, or nil if they stay where they are or there is no
+// maintenance.
+func (server *Server) maintenanceChannel() *Channel {
+	if !server.cfg.BoolValue("Maintenance") {
+		return nil
+	}
+	id := server.cfg.IntValue("MaintenanceChannel")
+	if id <= 0 {
+		return nil
+	}
+	return server.Channels[id]
+}
+
+// moveHeldClient moves a client held by, or released from, maintenance
+// to channel.
+func (server *Server) moveHeldClient(client *Client, channel *Channel) {
+	userstate := &mumbleproto.UserState{
+		Session:   proto.Uint32(client.Session()),
+		ChannelId: proto.Uint32(uint32(channel.Id)),
+	}
+	server.userEnterChannel(client, channel, userstate)
+	if err := server.broadcastProtoMessage(userstate); err != nil {
+		server.Printf("Unable to broadcast UserState: %v", err)
+	}
+}
+
+// applyMaintenance moves the connected users who aren't admins into the
+// maintenance channel, or back out of it, after maintenance started,
+// ended or its channel changed.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) applyMaintenance() {
+	maintenance := server.cfg.BoolValue("Maintenance")
+	holding := server.maintenanceChannel()
+	for _, client := range server.clients {
+		if client.state != StateClientReady || client.queued || client.Channel == nil {
+			continue
+		}
+
+		if holding != nil && !server.isAdmin(client) {
+			if !client.held {
+				client.held = true
+				client.heldReturn = client.Channel.Id
+				server.sendServerText(client, server.cfg.StringValue("MaintenanceMessage"))
+			}
+			if client.Channel != holding {
+				server.moveHeldClient(client, holding)
+			}
+			continue
+		}
+
+		if !client.held {
+			continue
+		}
+		client.held = false
+		back, ok := server.Channels[client.heldReturn]
+		if ok && back != client.Channel && acl.HasPermission(&back.ACL, client, acl.EnterPermission) && !server.channelFull(back) {
+			server.moveHeldClient(client, back)
+		}
+		if !maintenance {
+			server.sendServerText(client, "Maintenance is over")
+		}
+	}
+}
+
+func init() {
+	registerAPIEndpoint("maintenance", handleAPIMaintenance)
+}
+
+// handleAPIMaintenance implements /servers/<id>/maintenance.
+//
+//	GET  shows whether maintenance mode is on
+//	PUT  turns it on or off, and may change the message and the channel
+//	     users are held in:
+//	     {"enabled": true, "message": "Back at 10pm", "channel": 5}
+func handleAPIMaintenance(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var req struct {
+		Enabled *bool   `json:"enabled"`
+		Message *string `json:"message"`
+		Channel *int    `json:"channel"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if req.Channel != nil && *req.Channel < 0 {
+			apiError(w, http.StatusBadRequest, "invalid channel")
+			return
+		}
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	type apiMaintenance struct {
+		Enabled bool   `json:"enabled"`
+		Message string `json:"message"`
+		Channel int    `json:"channel"`
+		Held    int    `json:"held"`
+	}
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		if r.Method != http.MethodGet {
+			if req.Channel != nil && *req.Channel > 0 {
+				if _, ok := server.Channels[*req.Channel]; !ok {
+					status, reply = http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("no such channel: %v", *req.Channel)}
+					return
+				}
+			}
+			set := map[string]string{}
+			if req.Enabled != nil {
+				set["Maintenance"] = fmt.Sprintf("%v", *req.Enabled)
+			}
+			if req.Message != nil {
+				set["MaintenanceMessage"] = *req.Message
+			}
+			if req.Channel != nil {
+				set["MaintenanceChannel"] = fmt.Sprintf("%v", *req.Channel)
+			}
+			old := configSnapshot(server.cfg)
+			for key, val := range set {
+				server.cfg.Set(key, val)
+				server.UpdateConfig(key, val)
+			}
+			server.configChanged(old)
+			server.auditAPI(auditlog.Entry{
+				Action:  "config.maintenance",
+				Details: fmt.Sprintf("enabled %v channel %v", server.cfg.BoolValue("Maintenance"), server.cfg.IntValue("MaintenanceChannel")),
+			})
+		}
+
+		held := 0
+		for _, client := range server.clients {
+			if client.held {
+				held++
+			}
+		}
+		reply = apiMaintenance{
+			Enabled: server.cfg.BoolValue("Maintenance"),
+			Message: server.cfg.StringValue("MaintenanceMessage"),
+			Channel: server.cfg.IntValue("MaintenanceChannel"),
+			Held:    held,
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"mumble.info/grumble/pkg/mdns"
+)
+
+// The mDNS responder is shared by all virtual servers. It is started
+// when the first server is advertised, and stopped with the last one.
+var (
+	mdnsLock      sync.Mutex
+	mdnsResponder *mdns.Responder
+	mdnsServices  int
+)
+
+// startMDNS advertises the server on the local network, so that it
+// shows up in the LAN section of Mumble's server browser.
+func (server *Server) startMDNS() {
+	if !server.cfg.BoolValue("Bonjour") {
+		return
+	}
+
+	addrs, err := server.ListenAddresses()
+	if err != nil {
+		return
+	}
+
+	mdnsLock.Lock()
+	defer mdnsLock.Unlock()
+
+	if server.mdnsService != nil {
+		return
+	}
+	if mdnsResponder == nil {
+		r, err := mdns.NewResponder()
+		if err != nil {
+			server.Printf("mdns: unable to start responder: %v", err)
+			return
+		}
+		mdnsResponder = r
+	}
+
+	name := server.cfg.StringValue("RegisterName")
+	if len(name) == 0 {
+		name = fmt.Sprintf("Grumble Server %v", server.Id)
+	}
+	svc := &mdns.Service{
+		Instance: name,
+		Type:     "_mumble._tcp",
+		Port:     uint16(addrs[0].Port),
+	}
+	// Advertise the addresses the server listens on, unless it
+	// listens on all interfaces.
+	for _, addr := range addrs {
+		if addr.IP == nil || addr.IP.IsUnspecified() {
+			svc.IPs = nil
+			break
+		}
+		svc.IPs = append(svc.IPs, addr.IP)
+	}
+	err = mdnsResponder.Register(svc)
+	if err != nil {
+		server.Printf("mdns: unable to advertise server: %v", err)
+		return
+	}
+	server.mdnsService = svc
+	mdnsServices++
+	server.Printf("mdns: advertising as %q", name)
+}
+
+// stopMDNS stops advertising the server on the local network.
+func (server *Server) stopMDNS() {
+	mdnsLock.Lock()
+	defer mdnsLock.Unlock()
+
+	if server.mdnsService == nil {
+		return
+	}
+	mdnsResponder.Unregister(server.mdnsService)
+	server.mdnsService = nil
+	mdnsServices--
+	if mdnsServices == 0 {
+		mdnsResponder.Close()
+		mdnsResponder = nil
+	}
+}
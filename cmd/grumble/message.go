@@ -6,16 +6,19 @@ package main
 
 import (
 	"crypto/aes"
-	"crypto/tls"
 	"fmt"
-	"net"
+	"strings"
 	"time"
 
 	"github.com/golang/protobuf/proto"
 	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
 	"mumble.info/grumble/pkg/ban"
+	"mumble.info/grumble/pkg/chantemplate"
+	"mumble.info/grumble/pkg/features"
 	"mumble.info/grumble/pkg/freezer"
 	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/plugin"
 )
 
 type Message struct {
@@ -31,6 +34,35 @@ type VoiceBroadcast struct {
 	target byte
 	// The voice packet itself.
 	buf []byte
+	// Whether the packet ends the client's talk spurt.
+	terminator bool
+	// The packet as sent to listeners in each context, made as they
+	// are first needed.
+	variants [voiceContextWhisper + 1][]byte
+}
+
+// The contexts in which a listener hears a voice packet, sent in the
+// target bits of its header.
+const (
+	// Talking in the listener's channel
+	voiceContextNormal = 0
+	// Whispering to the listener's channel
+	voiceContextShout = 1
+	// Whispering to the listener directly
+	voiceContextWhisper = 2
+)
+
+// packet returns the voice packet as it is sent to listeners in context.
+// Each context's packet is made once, and shared by all its listeners.
+func (vb *VoiceBroadcast) packet(context byte) []byte {
+	if vb.variants[context] == nil && vb.buf[0]&0x1f == context {
+		vb.variants[context] = vb.buf
+	} else if vb.variants[context] == nil {
+		buf := append([]byte(nil), vb.buf...)
+		buf[0] = buf[0]&0xe0 | context
+		vb.variants[context] = buf
+	}
+	return vb.variants[context]
 }
 
 func (server *Server) handleCryptSetup(client *Client, msg *Message) {
@@ -46,7 +78,10 @@ func (server *Server) handleCryptSetup(client *Client, msg *Message) {
 	if len(cs.ClientNonce) == 0 {
 		client.Printf("Requested crypt-nonce resync")
 		cs.ClientNonce = make([]byte, aes.BlockSize)
-		if copy(cs.ClientNonce, client.crypt.EncryptIV[0:]) != aes.BlockSize {
+		client.cryptLock.Lock()
+		n := copy(cs.ClientNonce, client.crypt.EncryptIV[0:])
+		client.cryptLock.Unlock()
+		if n != aes.BlockSize {
 			return
 		}
 		client.sendMessage(cs)
@@ -56,8 +91,11 @@ func (server *Server) handleCryptSetup(client *Client, msg *Message) {
 			return
 		}
 
+		client.cryptLock.Lock()
 		client.crypt.Resync += 1
-		if copy(client.crypt.DecryptIV[0:], cs.ClientNonce) != aes.BlockSize {
+		n := copy(client.crypt.DecryptIV[0:], cs.ClientNonce)
+		client.cryptLock.Unlock()
+		if n != aes.BlockSize {
 			return
 		}
 		client.Printf("Crypt re-sync successful")
@@ -105,13 +143,16 @@ func (server *Server) handlePingMessage(client *Client, msg *Message) {
 		client.TcpPackets = *ping.TcpPackets
 	}
 
-	client.sendMessage(&mumbleproto.Ping{
+	client.cryptLock.Lock()
+	reply := &mumbleproto.Ping{
 		Timestamp: ping.Timestamp,
 		Good:      proto.Uint32(uint32(client.crypt.Good)),
 		Late:      proto.Uint32(uint32(client.crypt.Late)),
 		Lost:      proto.Uint32(uint32(client.crypt.Lost)),
 		Resync:    proto.Uint32(uint32(client.crypt.Resync)),
-	})
+	}
+	client.cryptLock.Unlock()
+	client.sendMessage(reply)
 }
 
 func (server *Server) handleChannelRemoveMessage(client *Client, msg *Message) {
@@ -139,6 +180,11 @@ func (server *Server) handleChannelRemoveMessage(client *Client, msg *Message) {
 	// Update datastore
 	if !channel.IsTemporary() {
 		server.DeleteFrozenChannel(channel)
+		server.audit(client, auditlog.Entry{
+			Action:  "channel.remove",
+			Target:  channel.Name,
+			Details: fmt.Sprintf("channel %v", channel.Id),
+		})
 	}
 
 	server.RemoveChannel(channel)
@@ -183,6 +229,7 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 
 	var name string
 	var description string
+	var tmpl *chantemplate.Template
 
 	// Extract the description and perform sanity checks.
 	if chanstate.Description != nil {
@@ -201,6 +248,17 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 	if chanstate.Name != nil {
 		name = *chanstate.Name
 
+		// New temporary channels may be created from a template.
+		if channel == nil && chanstate.GetTemporary() {
+			tmpl, name = server.channelTemplate(name)
+			chanstate.Name = proto.String(name)
+		}
+
+		if reason := server.checkChannelName(name); len(reason) > 0 {
+			client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_ChannelName, reason)
+			return
+		}
+
 		// We don't allow renames for the root channel.
 		if channel != nil && channel.Id != 0 {
 			// Pick a parent. If the name change is part of a re-parent (a channel move),
@@ -220,6 +278,10 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 		}
 	}
 
+	// Whether the channel is created by a member of ChannelCreateGroup,
+	// who becomes its owner.
+	owned := false
+
 	// If the channel does not exist already, the ChannelState message is a create operation.
 	if channel == nil {
 		if parent == nil || len(name) == 0 {
@@ -234,8 +296,15 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 			perm = acl.Permission(acl.MakeChannelPermission)
 		}
 		if !acl.HasPermission(&parent.ACL, client, perm) {
-			client.sendPermissionDenied(client, parent, perm)
-			return
+			if *chanstate.Temporary || !server.isChannelCreator(client, parent) {
+				client.sendPermissionDenied(client, parent, perm)
+				return
+			}
+			if reason := server.checkChannelQuota(client); len(reason) > 0 {
+				client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_ChannelCountLimit, reason)
+				return
+			}
+			owned = true
 		}
 
 		// Only registered users can create channels.
@@ -250,9 +319,20 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 			return
 		}
 
+		if tmpl != nil {
+			if len(description) == 0 && len(tmpl.Description) > 0 {
+				description = tmpl.Description
+				chanstate.Description = proto.String(description)
+			}
+			chanstate.Position = proto.Int32(int32(tmpl.Position))
+			if tmpl.MaxUsers > 0 {
+				chanstate.MaxUsers = proto.Uint32(uint32(tmpl.MaxUsers))
+			}
+		}
+
 		key := ""
 		if len(description) > 0 {
-			key, err = blobStore.Put([]byte(description))
+			key, err = server.blobs.Put([]byte(description))
 			if err != nil {
 				server.Panicf("Blobstore error: %v", err)
 			}
@@ -263,6 +343,8 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 		channel.DescriptionBlob = key
 		channel.temporary = *chanstate.Temporary
 		channel.Position = int(*chanstate.Position)
+		channel.NoVoice = chanstate.GetNoVoice()
+		channel.Silent = chanstate.GetSilent()
 		parent.AddChild(channel)
 
 		// Add the creator to the channel's admin group
@@ -272,6 +354,16 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 			channel.ACL.Groups["admin"] = grp
 		}
 
+		if owned {
+			server.grantChannelOwnership(channel, client)
+		}
+
+		if tmpl != nil {
+			channel.MaxUsers = tmpl.MaxUsers
+			channel.ACL.ACLs = append(channel.ACL.ACLs, tmpl.ACLs...)
+			server.ClearCaches()
+		}
+
 		// If the client wouldn't have WritePermission in the just-created channel,
 		// add a +write ACL for the user's hash.
 		if !acl.HasPermission(&channel.ACL, client, acl.WritePermission) {
@@ -293,9 +385,21 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 
 		chanstate.ChannelId = proto.Uint32(uint32(channel.Id))
 
+		if !channel.IsTemporary() {
+			details := fmt.Sprintf("channel %v in %v", channel.Id, parent.Name)
+			if owned {
+				details += ", owned by its creator"
+			}
+			server.audit(client, auditlog.Entry{
+				Action:  "channel.create",
+				Target:  channel.Name,
+				Details: details,
+			})
+		}
+
 		// Broadcast channel add
 		server.broadcastProtoMessageWithPredicate(chanstate, func(client *Client) bool {
-			return client.Version < 0x10202
+			return !client.supports(features.DescBlobHash)
 		})
 
 		// Remove description if client knows how to handle blobs.
@@ -304,7 +408,7 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 			chanstate.DescriptionHash = channel.DescriptionBlobHashBytes()
 		}
 		server.broadcastProtoMessageWithPredicate(chanstate, func(client *Client) bool {
-			return client.Version >= 0x10202
+			return client.supports(features.DescBlobHash)
 		})
 
 		// If it's a temporary channel, move the creator in there.
@@ -345,6 +449,14 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 			}
 		}
 
+		// Flag change
+		if chanstate.NoVoice != nil || chanstate.Silent != nil {
+			if !acl.HasPermission(&channel.ACL, client, acl.WritePermission) {
+				client.sendPermissionDenied(client, channel, acl.WritePermission)
+				return
+			}
+		}
+
 		// Parent change (channel move)
 		if parent != nil {
 			// No-op?
@@ -417,15 +529,18 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 		}
 
 		// Permission checks done!
+		changes := []string{}
 
 		// Channel move
 		if parent != nil {
+			changes = append(changes, fmt.Sprintf("moved from %v to %v", channel.parent.Name, parent.Name))
 			channel.parent.RemoveChild(channel)
 			parent.AddChild(channel)
 		}
 
 		// Rename
 		if chanstate.Name != nil {
+			changes = append(changes, fmt.Sprintf("renamed from %v", channel.Name))
 			channel.Name = *chanstate.Name
 		}
 
@@ -434,7 +549,7 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 			if len(description) == 0 {
 				channel.DescriptionBlob = ""
 			} else {
-				key, err := blobStore.Put([]byte(description))
+				key, err := server.blobs.Put([]byte(description))
 				if err != nil {
 					server.Panicf("Blobstore error: %v", err)
 				}
@@ -447,19 +562,42 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 			channel.Position = int(*chanstate.Position)
 		}
 
+		// Flag change
+		if chanstate.NoVoice != nil && *chanstate.NoVoice != channel.NoVoice {
+			channel.NoVoice = *chanstate.NoVoice
+			changes = append(changes, fmt.Sprintf("no-voice set to %v", channel.NoVoice))
+		}
+		if chanstate.Silent != nil && *chanstate.Silent != channel.Silent {
+			channel.Silent = *chanstate.Silent
+			changes = append(changes, fmt.Sprintf("silent set to %v", channel.Silent))
+		}
+
 		// Add links
 		for _, iter := range linkadd {
 			server.LinkChannels(channel, iter)
+			changes = append(changes, "linked to "+iter.Name)
 		}
 
 		// Remove links
 		for _, iter := range linkremove {
 			server.UnlinkChannels(channel, iter)
+			changes = append(changes, "unlinked from "+iter.Name)
+		}
+
+		if chanstate.Description != nil {
+			changes = append(changes, "description changed")
+		}
+		if len(changes) > 0 && !channel.IsTemporary() {
+			server.audit(client, auditlog.Entry{
+				Action:  "channel.edit",
+				Target:  channel.Name,
+				Details: fmt.Sprintf("channel %v: %v", channel.Id, strings.Join(changes, ", ")),
+			})
 		}
 
 		// Broadcast the update
 		server.broadcastProtoMessageWithPredicate(chanstate, func(client *Client) bool {
-			return client.Version < 0x10202
+			return !client.supports(features.DescBlobHash)
 		})
 
 		// Remove description blob when sending to 1.2.2 >= users. Only send the blob hash.
@@ -469,13 +607,24 @@ func (server *Server) handleChannelStateMessage(client *Client, msg *Message) {
 		}
 		chanstate.DescriptionHash = channel.DescriptionBlobHashBytes()
 		server.broadcastProtoMessageWithPredicate(chanstate, func(client *Client) bool {
-			return client.Version >= 0x10202
+			return client.supports(features.DescBlobHash)
 		})
+
+		// A moved channel inherits other ACLs and groups.
+		if parent != nil {
+			server.pushPermissions()
+		}
 	}
 
+	server.channelTreeChanged()
+
 	// Update channel in datastore
 	if !channel.IsTemporary() {
 		server.UpdateFrozenChannel(channel, chanstate)
+		if owned {
+			server.UpdateFrozenChannelOwner(channel)
+			server.UpdateFrozenChannelACLs(channel)
+		}
 	}
 }
 
@@ -512,26 +661,27 @@ func (server *Server) handleUserRemoveMessage(client *Client, msg *Message) {
 		return
 	}
 
+	reason := userremove.GetReason()
+	if len(reason) == 0 {
+		reason = defaultRemoveReason
+	}
+
+	entry := auditlog.Entry{
+		Action: "kick",
+		Target: removeClient.ShownName(),
+		Reason: reason,
+	}
 	if isBan {
-		ban := ban.Ban{}
-		ban.IP = removeClient.conn.RemoteAddr().(*net.TCPAddr).IP
-		ban.Mask = 128
-		if userremove.Reason != nil {
-			ban.Reason = *userremove.Reason
+		duration := userremove.GetBanDuration()
+		server.banClient(removeClient, reason, duration)
+		entry.Action = "ban"
+		if duration > 0 {
+			entry.Details = fmt.Sprintf("for %v", time.Duration(duration)*time.Second)
 		}
-		ban.Username = removeClient.ShownName()
-		ban.CertHash = removeClient.CertHash()
-		ban.Start = time.Now().Unix()
-		ban.Duration = 0
-
-		server.banlock.Lock()
-		server.Bans = append(server.Bans, ban)
-		server.UpdateFrozenBans(server.Bans)
-		server.banlock.Unlock()
 	}
+	server.audit(client, entry)
 
-	userremove.Actor = proto.Uint32(uint32(client.Session()))
-	if err = server.broadcastProtoMessage(userremove); err != nil {
+	if err = server.broadcastUserRemove(removeClient, client, isBan, reason); err != nil {
 		server.Panicf("Unable to broadcast UserRemove message")
 		return
 	}
@@ -543,6 +693,7 @@ func (server *Server) handleUserRemoveMessage(client *Client, msg *Message) {
 	}
 
 	removeClient.ForceDisconnect()
+	server.escalate(removeClient, entry.Action)
 }
 
 // Handle user state changes
@@ -579,6 +730,18 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 			return
 		}
 
+		// Clients in the waiting room can't leave it by themselves.
+		if actor == target && target.queued {
+			client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_Text, "You are waiting in the queue")
+			return
+		}
+
+		// Neither can clients held during maintenance.
+		if actor == target && target.held {
+			client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_Text, "The server is under maintenance")
+			return
+		}
+
 		// If the user and the actor aren't the same, check whether the actor has MovePermission on
 		// the user's curent channel.
 		if actor != target && !acl.HasPermission(&target.Channel.ACL, actor, acl.MovePermission) {
@@ -593,10 +756,9 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 			return
 		}
 
-		maxChannelUsers := server.cfg.IntValue("MaxChannelUsers")
-		if maxChannelUsers != 0 && len(dstChan.clients) >= maxChannelUsers {
+		if server.channelFull(dstChan) {
 			client.sendPermissionDeniedFallback(mumbleproto.PermissionDenied_ChannelFull,
-				0x010201, "Channel is full")
+				features.ChannelFullDenial, "Channel is full")
 			return
 		}
 	}
@@ -649,6 +811,9 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 		}
 
 		userstate.Comment = proto.String(filtered)
+		if target != actor {
+			server.audit(actor, auditlog.Entry{Action: "comment.clear", Target: target.ShownName()})
+		}
 	}
 
 	// Texture change
@@ -661,24 +826,8 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 	}
 
 	// Registration
-	if userstate.UserId != nil {
-		// If user == actor, check for SelfRegisterPermission on root channel.
-		// If user != actor, check for RegisterPermission permission on root channel.
-		perm := acl.Permission(acl.RegisterPermission)
-		if actor == target {
-			perm = acl.Permission(acl.SelfRegisterPermission)
-		}
-
-		rootChan := server.RootChannel()
-		if target.IsRegistered() || !acl.HasPermission(&rootChan.ACL, actor, perm) {
-			client.sendPermissionDenied(actor, rootChan, perm)
-			return
-		}
-
-		if !target.HasCertificate() {
-			client.sendPermissionDeniedTypeUser(mumbleproto.PermissionDenied_MissingCertificate, target)
-			return
-		}
+	if userstate.UserId != nil && !server.canRegister(actor, target) {
+		return
 	}
 
 	// Prevent self-targetting state changes to be applied to other users
@@ -700,7 +849,7 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 	broadcast := false
 
 	if userstate.Texture != nil && target.user != nil {
-		key, err := blobStore.Put(userstate.Texture)
+		key, err := server.blobs.Put(userstate.Texture)
 		if err != nil {
 			server.Panicf("Blobstore error: %v", err)
 			return
@@ -729,6 +878,7 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 		if !target.SelfMute {
 			userstate.SelfDeaf = proto.Bool(false)
 			target.SelfDeaf = false
+			target.afk = false
 		}
 	}
 
@@ -741,7 +891,7 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 	}
 
 	if userstate.Comment != nil && target.user != nil {
-		key, err := blobStore.Put([]byte(*userstate.Comment))
+		key, err := server.blobs.Put([]byte(*userstate.Comment))
 		if err != nil {
 			server.Panicf("Blobstore error: %v", err)
 		}
@@ -755,6 +905,11 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 		broadcast = true
 	}
 
+	// Guests keep their comment for when they register.
+	if userstate.Comment != nil && target.user == nil {
+		target.comment = *userstate.Comment
+	}
+
 	if userstate.Mute != nil || userstate.Deaf != nil || userstate.Suppress != nil || userstate.PrioritySpeaker != nil {
 		if userstate.Deaf != nil {
 			target.Deaf = *userstate.Deaf
@@ -775,7 +930,38 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 		if userstate.PrioritySpeaker != nil {
 			target.PrioritySpeaker = *userstate.PrioritySpeaker
 		}
+		details := ""
+		if userstate.Mute != nil || userstate.Deaf != nil {
+			target.muteExpires = time.Time{}
+			if duration := userstate.GetMuteDuration(); target.Mute && duration > 0 {
+				target.muteExpires = time.Now().Add(time.Duration(duration) * time.Second)
+				details = fmt.Sprintf("for %v", time.Duration(duration)*time.Second)
+			}
+			// The duration is only meant for the server.
+			userstate.MuteDuration = nil
+		}
+		if target.IsRegistered() {
+			target.saveModeration()
+		}
 		broadcast = true
+
+		for _, change := range []struct {
+			value      *bool
+			set, clear string
+		}{
+			{userstate.Mute, "mute", "unmute"},
+			{userstate.Deaf, "deafen", "undeafen"},
+			{userstate.PrioritySpeaker, "priorityspeaker", "priorityspeaker.remove"},
+		} {
+			if change.value == nil {
+				continue
+			}
+			entry := auditlog.Entry{Action: change.set, Target: target.ShownName(), Details: details}
+			if !*change.value {
+				entry.Action, entry.Details = change.clear, ""
+			}
+			server.audit(actor, entry)
+		}
 	}
 
 	if userstate.Recording != nil && *userstate.Recording != target.Recording {
@@ -790,29 +976,33 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 		}
 
 		server.broadcastProtoMessageWithPredicate(txtmsg, func(client *Client) bool {
-			return client.Version < 0x10203
+			return !client.supports(features.Recording)
 		})
 
 		broadcast = true
 	}
 
-	userRegistrationChanged := false
+	// Registration is told to all clients by claimSession.
 	if userstate.UserId != nil {
-		uid, err := server.RegisterClient(target)
+		userstate.UserId = nil
+		user, err := server.claimSession(actor, target)
 		if err != nil {
 			client.Printf("Unable to register: %v", err)
-			userstate.UserId = nil
 		} else {
-			userstate.UserId = proto.Uint32(uid)
-			client.user = server.Users[uid]
-			userRegistrationChanged = true
+			server.audit(actor, auditlog.Entry{
+				Action:  "user.register",
+				Target:  target.ShownName(),
+				Details: fmt.Sprintf("user %v", user.Id),
+			})
 		}
-		broadcast = true
 	}
 
 	if userstate.ChannelId != nil {
 		channel, ok := server.Channels[int(*userstate.ChannelId)]
 		if ok {
+			// Moving a waiting or held client lets it in.
+			target.queued = false
+			target.held = false
 			server.userEnterChannel(target, channel, userstate)
 			broadcast = true
 		}
@@ -833,7 +1023,7 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 			// we send to pre-1.2.2 clients.
 			userstate.Texture = nil
 			err := server.broadcastProtoMessageWithPredicate(userstate, func(client *Client) bool {
-				return client.Version < 0x10202
+				return !client.supports(features.NewTextures)
 			})
 			if err != nil {
 				server.Panic("Unable to broadcast UserState")
@@ -843,7 +1033,7 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 		} else {
 			// Old style texture.  We can send the message as-is.
 			err := server.broadcastProtoMessageWithPredicate(userstate, func(client *Client) bool {
-				return client.Version < 0x10202
+				return !client.supports(features.NewTextures)
 			})
 			if err != nil {
 				server.Panic("Unable to broadcast UserState")
@@ -870,12 +1060,8 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 			userstate.CommentHash = nil
 		}
 
-		if userRegistrationChanged {
-			server.ClearCaches()
-		}
-
 		err := server.broadcastProtoMessageWithPredicate(userstate, func(client *Client) bool {
-			return client.Version >= 0x10203
+			return client.supports(features.BlobHash)
 		})
 		if err != nil {
 			server.Panic("Unable to broadcast UserState")
@@ -885,6 +1071,10 @@ func (server *Server) handleUserStateMessage(client *Client, msg *Message) {
 	if target.IsRegistered() {
 		server.UpdateFrozenUser(target, userstate)
 	}
+
+	if userstate.GetMute() {
+		server.escalate(target, "mute")
+	}
 }
 
 func (server *Server) handleBanListMessage(client *Client, msg *Message) {
@@ -925,7 +1115,8 @@ func (server *Server) handleBanListMessage(client *Client, msg *Message) {
 		server.banlock.Lock()
 		defer server.banlock.Unlock()
 
-		server.Bans = server.Bans[0:0]
+		old := append([]ban.Ban(nil), server.Bans...)
+		updated := []ban.Ban{}
 		for _, entry := range banlist.Bans {
 			ban := ban.Ban{}
 			ban.IP = entry.Address
@@ -945,10 +1136,11 @@ func (server *Server) handleBanListMessage(client *Client, msg *Message) {
 			if entry.Duration != nil {
 				ban.Duration = *entry.Duration
 			}
-			server.Bans = append(server.Bans, ban)
+			updated = append(updated, ban)
 		}
 
-		server.UpdateFrozenBans(server.Bans)
+		server.setBans(updated)
+		server.auditBanListChanges(client, old, server.Bans)
 
 		client.Printf("Banlist updated")
 	}
@@ -963,25 +1155,36 @@ func (server *Server) handleTextMessage(client *Client, msg *Message) {
 		return
 	}
 
-	filtered, err := server.FilterText(*txtmsg.Message)
+	filtered, err := server.FilterText(txtmsg.GetMessage())
 	if err != nil {
 		client.sendPermissionDeniedType(mumbleproto.PermissionDenied_TextTooLong)
 		return
 	}
 
-	if len(filtered) == 0 {
+	// Chat commands such as /tell pass text on, so they only see
+	// messages the length limit and the word filter let through.
+	filtered, ok := server.applyWordFilter(client, filtered)
+	if !ok {
+		return
+	}
+	if server.handleChatCommand(client, filtered) {
+		return
+	}
+
+	filtered, ok = server.applyPluginMessageFilters(client, filtered)
+	if !ok || len(filtered) == 0 {
 		return
 	}
 
 	txtmsg.Message = proto.String(filtered)
+	perms := textMessagePermissions(filtered)
 
 	clients := make(map[uint32]*Client)
 
 	// Tree
 	for _, chanid := range txtmsg.TreeId {
 		if channel, ok := server.Channels[int(chanid)]; ok {
-			if !acl.HasPermission(&channel.ACL, client, acl.TextMessagePermission) {
-				client.sendPermissionDenied(client, channel, acl.TextMessagePermission)
+			if !client.checkTextPermissions(channel, perms) {
 				return
 			}
 			for _, target := range channel.clients {
@@ -993,8 +1196,7 @@ func (server *Server) handleTextMessage(client *Client, msg *Message) {
 	// Direct-to-channel
 	for _, chanid := range txtmsg.ChannelId {
 		if channel, ok := server.Channels[int(chanid)]; ok {
-			if !acl.HasPermission(&channel.ACL, client, acl.TextMessagePermission) {
-				client.sendPermissionDenied(client, channel, acl.TextMessagePermission)
+			if !client.checkTextPermissions(channel, perms) {
 				return
 			}
 			for _, target := range channel.clients {
@@ -1006,8 +1208,7 @@ func (server *Server) handleTextMessage(client *Client, msg *Message) {
 	// Direct-to-clients
 	for _, session := range txtmsg.Session {
 		if target, ok := server.clients[session]; ok {
-			if !acl.HasPermission(&target.Channel.ACL, client, acl.TextMessagePermission) {
-				client.sendPermissionDenied(client, target.Channel, acl.TextMessagePermission)
+			if !client.checkTextPermissions(target.Channel, perms) {
 				return
 			}
 			clients[session] = target
@@ -1017,12 +1218,25 @@ func (server *Server) handleTextMessage(client *Client, msg *Message) {
 	// Remove ourselves
 	delete(clients, client.Session())
 
+	server.flushJoinOf(client)
 	for _, target := range clients {
 		target.sendMessage(&mumbleproto.TextMessage{
 			Actor:   proto.Uint32(client.Session()),
 			Message: txtmsg.Message,
 		})
 	}
+
+	for _, ids := range [][]uint32{txtmsg.TreeId, txtmsg.ChannelId} {
+		for _, chanid := range ids {
+			if channel, ok := server.Channels[int(chanid)]; ok {
+				channel.recordText(client.ShownName(), filtered)
+			}
+		}
+	}
+
+	server.bridgeToDiscord(client, txtmsg)
+	server.relayText(client, txtmsg)
+	server.emitEvent(plugin.Event{Type: plugin.Message, User: pluginUser(client), Text: filtered})
 }
 
 // ACL set/query
@@ -1242,6 +1456,14 @@ func (server *Server) handleAclMessage(client *Client, msg *Message) {
 
 		// Update freezer
 		server.UpdateFrozenChannelACLs(channel)
+
+		server.audit(client, auditlog.Entry{
+			Action:  "acl.edit",
+			Target:  channel.Name,
+			Details: fmt.Sprintf("channel %v: %v ACL entries, %v groups", channel.Id, len(channel.ACL.ACLs), len(channel.ACL.Groups)),
+		})
+
+		server.pushPermissions()
 	}
 }
 
@@ -1331,16 +1553,17 @@ func (server *Server) handleUserStatsMessage(client *Client, msg *Message) {
 	if details {
 		// Only consider client certificates for direct connections, not WebSocket connections.
 		// We do not support TLS-level client certificates for WebSocket client.
-		if tlsconn, ok := target.conn.(*tls.Conn); ok {
-			state := tlsconn.ConnectionState()
-			for i := len(state.PeerCertificates) - 1; i >= 0; i-- {
-				stats.Certificates = append(stats.Certificates, state.PeerCertificates[i].Raw)
+		if target.connectedOverTLS() {
+			certs := target.peerCertificates()
+			for i := len(certs) - 1; i >= 0; i-- {
+				stats.Certificates = append(stats.Certificates, certs[i].Raw)
 			}
 			stats.StrongCertificate = proto.Bool(target.IsVerified())
 		}
 	}
 
 	if local {
+		target.cryptLock.Lock()
 		fromClient := &mumbleproto.UserStats_Stats{}
 		fromClient.Good = proto.Uint32(target.crypt.Good)
 		fromClient.Late = proto.Uint32(target.crypt.Late)
@@ -1354,6 +1577,7 @@ func (server *Server) handleUserStatsMessage(client *Client, msg *Message) {
 		fromServer.Lost = proto.Uint32(target.crypt.RemoteLost)
 		fromServer.Resync = proto.Uint32(target.crypt.RemoteResync)
 		stats.FromServer = fromServer
+		target.cryptLock.Unlock()
 	}
 
 	stats.UdpPackets = proto.Uint32(target.UdpPackets)
@@ -1369,19 +1593,28 @@ func (server *Server) handleUserStatsMessage(client *Client, msg *Message) {
 		if len(target.ClientName) > 0 {
 			version.Release = proto.String(target.ClientName)
 		}
-		if len(target.OSName) > 0 {
+		if len(target.OSName) > 0 && server.showClientOS(client, target) {
 			version.Os = proto.String(target.OSName)
 			if len(target.OSVersion) > 0 {
 				version.OsVersion = proto.String(target.OSVersion)
 			}
 		}
+		// Report the crypto mode the client's voice packets use.
+		version.CryptoModes = []string{target.CryptoMode}
 		stats.Version = version
 		stats.CeltVersions = target.codecs
 		stats.Opus = proto.Bool(target.opus)
 		stats.Address = target.tcpaddr.IP
 	}
 
-	// fixme(mkrautz): we don't do bandwidth tracking yet
+	stats.Bandwidth = proto.Uint32(target.bandwidth())
+
+	voice := target.voiceStats.snapshot()
+	stats.VoiceGood = proto.Uint32(voice.good)
+	stats.VoiceLate = proto.Uint32(voice.late)
+	stats.VoiceLost = proto.Uint32(voice.lost)
+	stats.VoiceLostBursts = proto.Uint32(voice.lostBursts)
+	stats.VoiceJitter = proto.Float32(float32(voice.jitter))
 
 	if err := client.sendMessage(stats); err != nil {
 		client.Panic(err)
@@ -1407,12 +1640,9 @@ func (server *Server) handleVoiceTarget(client *Client, msg *Message) {
 		return
 	}
 
-	if len(vt.Targets) == 0 {
-		delete(client.voiceTargets, id)
-	}
-
+	// All the targets in the message make up one voice target.
+	newTarget := &VoiceTarget{}
 	for _, target := range vt.Targets {
-		newTarget := &VoiceTarget{}
 		for _, session := range target.Session {
 			newTarget.AddSession(session)
 		}
@@ -1432,12 +1662,13 @@ func (server *Server) handleVoiceTarget(client *Client, msg *Message) {
 			}
 			newTarget.AddChannel(chanid, subchannels, links, group)
 		}
-		if newTarget.IsEmpty() {
-			delete(client.voiceTargets, id)
-		} else {
-			client.voiceTargets[id] = newTarget
-		}
 	}
+	if newTarget.IsEmpty() {
+		delete(client.voiceTargets, id)
+	} else {
+		client.voiceTargets[id] = newTarget
+	}
+	server.saveVoiceTargets(client)
 }
 
 // Permission query
@@ -1466,78 +1697,36 @@ func (server *Server) handleRequestBlob(client *Client, msg *Message) {
 		return
 	}
 
-	userstate := &mumbleproto.UserState{}
-
 	// Request for user textures
-	if len(blobreq.SessionTexture) > 0 {
-		for _, sid := range blobreq.SessionTexture {
-			if target, ok := server.clients[sid]; ok {
-				if target.user == nil {
-					continue
-				}
-				if target.user.HasTexture() {
-					buf, err := blobStore.Get(target.user.TextureBlob)
-					if err != nil {
-						server.Panicf("Blobstore error: %v", err)
-						return
-					}
-					userstate.Reset()
-					userstate.Session = proto.Uint32(uint32(target.Session()))
-					userstate.Texture = buf
-					if err := client.sendMessage(userstate); err != nil {
-						client.Panic(err)
-						return
-					}
-				}
+	for _, sid := range blobreq.SessionTexture {
+		if target, ok := server.clients[sid]; ok && target.user != nil && target.user.HasTexture() {
+			session := proto.Uint32(target.Session())
+			err := client.sendBlobMessage(&mumbleproto.UserState{Session: session}, userTextureField, target.user.TextureBlob)
+			if err != nil {
+				client.Panic(err)
+				return
 			}
 		}
 	}
 
 	// Request for user comments
-	if len(blobreq.SessionComment) > 0 {
-		for _, sid := range blobreq.SessionComment {
-			if target, ok := server.clients[sid]; ok {
-				if target.user == nil {
-					continue
-				}
-				if target.user.HasComment() {
-					buf, err := blobStore.Get(target.user.CommentBlob)
-					if err != nil {
-						server.Panicf("Blobstore error: %v", err)
-						return
-					}
-					userstate.Reset()
-					userstate.Session = proto.Uint32(uint32(target.Session()))
-					userstate.Comment = proto.String(string(buf))
-					if err := client.sendMessage(userstate); err != nil {
-						client.Panic(err)
-						return
-					}
-				}
+	for _, sid := range blobreq.SessionComment {
+		if target, ok := server.clients[sid]; ok && target.user != nil && target.user.HasComment() {
+			session := proto.Uint32(target.Session())
+			err := client.sendBlobMessage(&mumbleproto.UserState{Session: session}, userCommentField, target.user.CommentBlob)
+			if err != nil {
+				client.Panic(err)
+				return
 			}
 		}
 	}
 
-	chanstate := &mumbleproto.ChannelState{}
-
 	// Request for channel descriptions
-	if len(blobreq.ChannelDescription) > 0 {
-		for _, cid := range blobreq.ChannelDescription {
-			if channel, ok := server.Channels[int(cid)]; ok {
-				if channel.HasDescription() {
-					chanstate.Reset()
-					buf, err := blobStore.Get(channel.DescriptionBlob)
-					if err != nil {
-						server.Panicf("Blobstore error: %v", err)
-						return
-					}
-					chanstate.ChannelId = proto.Uint32(uint32(channel.Id))
-					chanstate.Description = proto.String(string(buf))
-					if err := client.sendMessage(chanstate); err != nil {
-						client.Panic(err)
-						return
-					}
-				}
+	for _, cid := range blobreq.ChannelDescription {
+		if channel, ok := server.Channels[int(cid)]; ok {
+			if err := client.sendChannelDescription(channel); err != nil {
+				client.Panic(err)
+				return
 			}
 		}
 	}
@@ -1565,10 +1754,15 @@ func (server *Server) handleUserList(client *Client, msg *Message) {
 			if uid == 0 {
 				continue
 			}
-			userlist.Users = append(userlist.Users, &mumbleproto.UserList_User{
-				UserId: proto.Uint32(uid),
-				Name:   proto.String(user.Name),
-			})
+			entry := &mumbleproto.UserList_User{
+				UserId:      proto.Uint32(uid),
+				Name:        proto.String(user.Name),
+				LastChannel: proto.Uint32(uint32(user.LastChannelId)),
+			}
+			if lastSeen := userLastSeen(user); len(lastSeen) > 0 {
+				entry.LastSeen = proto.String(lastSeen)
+			}
+			userlist.Users = append(userlist.Users, entry)
 		}
 		if err := client.sendMessage(userlist); err != nil {
 			client.Panic(err)
@@ -1587,6 +1781,11 @@ func (server *Server) handleUserList(client *Client, msg *Message) {
 				if ok {
 					if listUser.Name == nil {
 						// De-register
+						server.audit(client, auditlog.Entry{
+							Action:  "user.unregister",
+							Target:  user.Name,
+							Details: fmt.Sprintf("user %v", uid),
+						})
 						server.RemoveRegistration(uid)
 						err := tx.Put(&freezer.UserRemove{Id: listUser.UserId})
 						if err != nil {
@@ -1594,8 +1793,22 @@ func (server *Server) handleUserList(client *Client, msg *Message) {
 						}
 					} else {
 						// Rename user
-						// todo(mkrautz): Validate name.
+						if reason := server.checkUsername(*listUser.Name, false); len(reason) > 0 {
+							client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_UserName, reason)
+							continue
+						}
+						if other, exists := server.registeredUser(*listUser.Name); exists && other != user {
+							client.sendPermissionDeniedReason(mumbleproto.PermissionDenied_UserName, "The name is already registered")
+							continue
+						}
+						server.audit(client, auditlog.Entry{
+							Action:  "user.rename",
+							Target:  user.Name,
+							Details: fmt.Sprintf("user %v renamed to %v", uid, *listUser.Name),
+						})
+						delete(server.UserNameMap, user.Name)
 						user.Name = *listUser.Name
+						server.UserNameMap[user.Name] = user
 						err := tx.Put(&freezer.User{Id: listUser.UserId, Name: listUser.Name})
 						if err != nil {
 							server.Fatal(err)
@@ -0,0 +1,152 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file holds the migrations of the data directory's layout.
+//
+// Pending migrations are applied when Grumble starts, and Grumble
+// refuses to start on a data directory written by a newer version.
+// --migrate reports the data directory's schema version and the
+// pending migrations without applying them, and --migrate-to moves the
+// data directory up or down to a given version.
+//
+// New migrations are appended to dataDirMigrations with the next
+// version number. Their Up and Down functions must be safe to run
+// again after an interruption.
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"mumble.info/grumble/pkg/blobstore"
+	"mumble.info/grumble/pkg/migrate"
+)
+
+var dataDirMigrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "servers directory",
+		Up: func(dir string) error {
+			err := os.Mkdir(filepath.Join(dir, "servers"), 0700)
+			if err != nil && !os.IsExist(err) {
+				return err
+			}
+			return nil
+		},
+		Down: func(dir string) error {
+			// Only an empty servers directory is removed;
+			// servers that were created since are kept.
+			os.Remove(filepath.Join(dir, "servers"))
+			return nil
+		},
+	},
+	{
+		Version: 2,
+		Name:    "certificate permissions",
+		Up: func(dir string) error {
+			return chmodIfExists(map[string]os.FileMode{
+				filepath.Join(dir, "cert.pem"): 0644,
+				filepath.Join(dir, "key.pem"):  0600,
+			})
+		},
+		Down: func(dir string) error {
+			return chmodIfExists(map[string]os.FileMode{
+				filepath.Join(dir, "cert.pem"): 0700,
+				filepath.Join(dir, "key.pem"):  0700,
+			})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "sharded blob directories",
+		Up: func(dir string) error {
+			// Blobs in the old layout are still read, and are moved
+			// in the background once the servers have started.
+			return nil
+		},
+		Down: func(dir string) error {
+			_, err := blobstore.Open(filepath.Join(dir, "blob")).Unshard()
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		},
+	},
+}
+
+// chmodIfExists changes the modes of the given files that exist.
+func chmodIfExists(modes map[string]os.FileMode) error {
+	for fn, mode := range modes {
+		err := os.Chmod(fn, mode)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataDirMigrator returns the migrator of the data directory.
+func dataDirMigrator() *migrate.Migrator {
+	m, err := migrate.New(Args.DataDir, dataDirMigrations)
+	if err != nil {
+		log.Fatalf("Invalid data directory migrations: %v", err)
+	}
+	return m
+}
+
+// migrateDataDir applies the pending migrations of the data directory.
+func migrateDataDir() error {
+	m := dataDirMigrator()
+	ran, err := m.Up()
+	for _, mig := range ran {
+		log.Printf("Migrated data directory to version %v (%v)", mig.Version, mig.Name)
+	}
+	return err
+}
+
+// reportMigrations prints the data directory's schema version and its
+// pending migrations.
+func reportMigrations() error {
+	m := dataDirMigrator()
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Data directory %v is at schema version %v of %v.\n", Args.DataDir, version, m.Latest())
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("No pending migrations.")
+		return nil
+	}
+	fmt.Println("Pending migrations, applied when Grumble starts:")
+	for _, mig := range pending {
+		fmt.Printf("  %v  %v\n", mig.Version, mig.Name)
+	}
+	return nil
+}
+
+// migrateDataDirTo moves the data directory to the given version.
+func migrateDataDirTo(arg string) error {
+	target, err := strconv.Atoi(arg)
+	if err != nil {
+		return fmt.Errorf("invalid version %q", arg)
+	}
+	m := dataDirMigrator()
+	ran, err := m.Migrate(target)
+	for _, mig := range ran {
+		fmt.Printf("Ran migration %v (%v)\n", mig.Version, mig.Name)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Data directory %v is at schema version %v.\n", Args.DataDir, target)
+	return nil
+}
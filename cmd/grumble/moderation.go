@@ -0,0 +1,61 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the persistence of server mutes.
+//
+// The server mute, deafen and priority speaker flags of registered
+// users are stored with their registration, so they survive
+// reconnects and restarts. Suppression isn't stored: it follows from
+// whether the user may speak in their channel, and is worked out anew
+// whenever they enter one. Timed mutes keep their expiry, and one that
+// ran out while the user was away is lifted once they connect.
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// saveModeration copies the client's server mute, deafen and priority
+// speaker flags to its registration. The caller writes them to disk.
+func (client *Client) saveModeration() {
+	if client.user == nil {
+		return
+	}
+	client.user.Mute = client.Mute
+	client.user.Deaf = client.Deaf
+	client.user.PrioritySpeaker = client.PrioritySpeaker
+	client.user.MuteExpires = 0
+	if !client.muteExpires.IsZero() {
+		client.user.MuteExpires = client.muteExpires.Unix()
+	}
+}
+
+// restoreModeration sets the client's server mute, deafen and priority
+// speaker flags from its registration, and adds those that are set to
+// userstate.
+func (client *Client) restoreModeration(userstate *mumbleproto.UserState) {
+	if client.user == nil {
+		return
+	}
+	client.Mute = client.user.Mute || client.user.Deaf
+	client.Deaf = client.user.Deaf
+	client.PrioritySpeaker = client.user.PrioritySpeaker
+	client.muteExpires = time.Time{}
+	if client.Mute && client.user.MuteExpires != 0 {
+		client.muteExpires = time.Unix(client.user.MuteExpires, 0)
+	}
+	if client.Mute {
+		userstate.Mute = proto.Bool(true)
+	}
+	if client.Deaf {
+		userstate.Deaf = proto.Bool(true)
+	}
+	if client.PrioritySpeaker {
+		userstate.PrioritySpeaker = proto.Bool(true)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file publishes presence information to an MQTT broker.
+//
+// When MQTTBroker is set, every connect, disconnect, channel move and
+// talk spurt is published to <prefix>/<server id>/events, and the
+// users in each channel are published as a retained message to
+// <prefix>/<server id>/channels/<channel id>.
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+
+	"mumble.info/grumble/pkg/mqtt"
+	"mumble.info/grumble/pkg/plugin"
+)
+
+const (
+	// The number of messages that may wait to be published.
+	mqttQueueSize = 256
+	// The time to wait before reconnecting to the broker.
+	mqttRetryInterval = 10 * time.Second
+)
+
+// Config keys of the MQTT publisher.
+var mqttConfigKeys = []string{"MQTTBroker", "MQTTTopicPrefix", "MQTTClientID", "MQTTUsername", "MQTTPassword"}
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+	retain  bool
+}
+
+// An mqttPublisher publishes a server's presence information.
+type mqttPublisher struct {
+	server *Server
+	opts   mqtt.Options
+	prefix string
+
+	queue chan mqttMessage
+	done  chan bool
+}
+
+// mqttEvent is the JSON representation of a presence event.
+type mqttEvent struct {
+	Event       string   `json:"event"`
+	Session     uint32   `json:"session"`
+	Name        string   `json:"name"`
+	UserId      int      `json:"user_id"`
+	Channel     int      `json:"channel"`
+	PrevChannel *int     `json:"prev_channel,omitempty"`
+	Duration    *float64 `json:"duration,omitempty"`
+	Time        int64    `json:"time"`
+}
+
+// mqttOccupancy is the JSON representation of a channel's users.
+type mqttOccupancy struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+// mqttOptions parses the MQTTBroker key, a URL with the scheme tcp or
+// tls (or mqtt and mqtts), or a plain host:port.
+func (server *Server) mqttOptions() (mqtt.Options, error) {
+	opts := mqtt.Options{
+		ClientID:  server.cfg.StringValue("MQTTClientID"),
+		Username:  server.cfg.StringValue("MQTTUsername"),
+		Password:  server.cfg.StringValue("MQTTPassword"),
+		KeepAlive: time.Minute,
+	}
+	if len(opts.ClientID) == 0 {
+		opts.ClientID = fmt.Sprintf("grumble-%v", server.Id)
+	}
+
+	broker := server.cfg.StringValue("MQTTBroker")
+	u, err := url.Parse(broker)
+	if err != nil || len(u.Host) == 0 {
+		u = &url.URL{Scheme: "tcp", Host: broker}
+	}
+	defaultPort := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		defaultPort = "8883"
+		opts.TLS = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return opts, fmt.Errorf("unsupported MQTT broker scheme %q", u.Scheme)
+	}
+	opts.Address = u.Host
+	if len(u.Port()) == 0 {
+		opts.Address = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return opts, nil
+}
+
+// startMQTT starts publishing presence information, if a broker is
+// configured.
+//
+// Must be called from the server's handler goroutine, or before it
+// is started.
+func (server *Server) startMQTT() {
+	if len(server.cfg.StringValue("MQTTBroker")) == 0 {
+		return
+	}
+	opts, err := server.mqttOptions()
+	if err != nil {
+		server.Printf("mqtt: %v", err)
+		return
+	}
+	p := &mqttPublisher{
+		server: server,
+		opts:   opts,
+		prefix: fmt.Sprintf("%v/%v", server.cfg.StringValue("MQTTTopicPrefix"), server.Id),
+		queue:  make(chan mqttMessage, mqttQueueSize),
+		done:   make(chan bool),
+	}
+	server.mqtt = p
+	go p.run()
+
+	for _, channel := range server.Channels {
+		server.publishOccupancy(channel.Id)
+	}
+}
+
+// stopMQTT stops publishing presence information.
+//
+// Must be called from the server's handler goroutine, or after it
+// has stopped.
+func (server *Server) stopMQTT() {
+	if server.mqtt == nil {
+		return
+	}
+	close(server.mqtt.done)
+	server.mqtt = nil
+}
+
+// publish queues a message for publishing.
+func (p *mqttPublisher) publish(topic string, v interface{}, retain bool) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	select {
+	case p.queue <- mqttMessage{topic: p.prefix + "/" + topic, payload: payload, retain: retain}:
+	default:
+		p.server.Printf("mqtt: queue full, dropped message for %v", topic)
+	}
+}
+
+// publishPresenceEvent publishes a connect, disconnect or channel move,
+// and the occupancy of the channels involved, or a user starting or
+// stopping to talk.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) publishPresenceEvent(ev plugin.Event) {
+	if server.mqtt == nil {
+		return
+	}
+	msg := mqttEvent{
+		Event:   ev.Type.String(),
+		Session: ev.User.Session,
+		Name:    ev.User.Name,
+		UserId:  ev.User.UserId,
+		Channel: ev.User.Channel,
+		Time:    time.Now().Unix(),
+	}
+	switch ev.Type {
+	case plugin.Connect, plugin.Disconnect:
+		server.mqtt.publish("events", msg, false)
+		server.publishOccupancy(ev.User.Channel)
+	case plugin.ChannelJoin:
+		// The user's channel is the one the user was in when the
+		// event was queued, which may not be the one joined.
+		msg.Channel = ev.Channel
+		msg.PrevChannel = &ev.PrevChannel
+		server.mqtt.publish("events", msg, false)
+		server.publishOccupancy(ev.Channel)
+		server.publishOccupancy(ev.PrevChannel)
+	case plugin.TalkingStart:
+		server.mqtt.publish("events", msg, false)
+	case plugin.TalkingStop:
+		duration := ev.Duration.Seconds()
+		msg.Duration = &duration
+		server.mqtt.publish("events", msg, false)
+	}
+}
+
+// publishOccupancy publishes the users in a channel.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) publishOccupancy(id int) {
+	channel, ok := server.Channels[id]
+	if !ok {
+		return
+	}
+	occupancy := mqttOccupancy{Name: channel.Name, Users: []string{}}
+	for _, client := range channel.clients {
+		if client.state == StateClientReady {
+			occupancy.Users = append(occupancy.Users, client.ShownName())
+		}
+	}
+	sort.Strings(occupancy.Users)
+	occupancy.Count = len(occupancy.Users)
+	server.mqtt.publish(fmt.Sprintf("channels/%v", id), occupancy, true)
+}
+
+// run connects to the broker and publishes queued messages until the
+// publisher is stopped, reconnecting whenever the connection is lost.
+func (p *mqttPublisher) run() {
+	for {
+		client, err := mqtt.Dial(p.opts)
+		if err != nil {
+			p.server.Printf("mqtt: unable to connect to %v: %v", p.opts.Address, err)
+		} else {
+			p.server.Printf("mqtt: connected to %v", p.opts.Address)
+			err = p.publishLoop(client)
+			client.Close()
+			if err == nil {
+				return
+			}
+			p.server.Printf("mqtt: connection lost: %v", err)
+		}
+
+		select {
+		case <-p.done:
+			return
+		case <-time.After(mqttRetryInterval):
+		}
+	}
+}
+
+// publishLoop publishes queued messages on client. It returns nil once
+// the publisher is stopped, or the error that ended the connection.
+func (p *mqttPublisher) publishLoop(client *mqtt.Client) error {
+	for {
+		select {
+		case <-p.done:
+			return nil
+		case <-client.Done():
+			return client.Err()
+		case msg := <-p.queue:
+			err := client.Publish(msg.topic, msg.payload, msg.retain)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the limits on the size of control messages.
+//
+// A message's length is sent ahead of it, so without limits a client
+// could make the server allocate up to 4 GiB for a single message.
+// Messages of kinds that are always small are limited to a few
+// kilobytes, messages sent before the client is authenticated to
+// 64 KiB, and all others to MaxMessageSize bytes (default 1 MiB).
+// Clients that send a larger message are disconnected, and the
+// attempts are counted by kind in /servers/<id>/messagestats.
+
+import (
+	"fmt"
+
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The largest message a client may send before it is authenticated.
+const handshakeMessageLimit = 64 * 1024
+
+// The largest messages of the kinds that are always small.
+var messageSizeLimits = map[uint16]uint32{
+	mumbleproto.MessageVersion:         4096,
+	mumbleproto.MessageUDPTunnel:       UDPPacketSize,
+	mumbleproto.MessagePing:            1024,
+	mumbleproto.MessageCryptSetup:      1024,
+	mumbleproto.MessageCodecVersion:    1024,
+	mumbleproto.MessagePermissionQuery: 1024,
+	mumbleproto.MessageUserStats:       1024,
+	mumbleproto.MessageChannelRemove:   1024,
+	mumbleproto.MessageUserRemove:      4096,
+	mumbleproto.MessageContextAction:   4096,
+	mumbleproto.MessageVoiceTarget:     16 * 1024,
+	mumbleproto.MessageRequestBlob:     64 * 1024,
+	mumbleproto.MessageQueryUsers:      64 * 1024,
+}
+
+// messageSizeLimit returns the size of the largest message of the
+// given kind that client may send.
+func (client *Client) messageSizeLimit(kind uint16) uint32 {
+	limit := client.server.cfg.Uint32Value("MaxMessageSize")
+	if client.state < StateClientReady && limit > handshakeMessageLimit {
+		limit = handshakeMessageLimit
+	}
+	if small, ok := messageSizeLimits[kind]; ok && small < limit {
+		limit = small
+	}
+	return limit
+}
+
+// checkMessageSize checks whether a message of the given kind and
+// length is within client's limits, and counts it if it isn't.
+func (client *Client) checkMessageSize(kind uint16, length uint32) error {
+	limit := client.messageSizeLimit(kind)
+	if length <= limit {
+		return nil
+	}
+	server := client.server
+	server.recvStatsLock.Lock()
+	if server.oversized == nil {
+		server.oversized = make(map[uint16]uint64)
+	}
+	server.oversized[kind]++
+	server.recvStatsLock.Unlock()
+	return fmt.Errorf("Message of kind %v is too large (%v bytes, at most %v allowed)", kind, length, limit)
+}
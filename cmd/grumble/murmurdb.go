@@ -10,6 +10,7 @@ package main
 // SQLite datbase into a format that Grumble can understand.
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"log"
@@ -17,6 +18,8 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"mumble.info/grumble/pkg/acl"
 	"mumble.info/grumble/pkg/ban"
@@ -38,26 +41,123 @@ const (
 
 const SQLiteSupport = true
 
-// Import the structure of an existing Murmur SQLite database.
-func MurmurImport(filename string) (err error) {
-	db, err := sql.Open("sqlite", filename)
+// The default timeout of a query to a Murmur database, and the default
+// number of times a query is retried while the database is busy.
+const (
+	defaultMurmurQueryTimeout = 30 * time.Second
+	defaultMurmurBusyRetries  = 5
+)
+
+// The backoff before the first retry of a busy query. It doubles with
+// every retry.
+const murmurBusyBackoff = 100 * time.Millisecond
+
+// A murmurDB reads a Murmur database within one read-only transaction,
+// so that a Murmur that is still running can't change it half-way
+// through an import.
+type murmurDB struct {
+	ctx     context.Context
+	tx      *sql.Tx
+	timeout time.Duration
+	retries int
+}
+
+// isBusy checks whether err means that the database is locked by
+// another process, in which case the query can be retried.
+func isBusy(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked") ||
+		strings.Contains(msg, "sqlite_busy")
+}
+
+// retry runs fn until it succeeds, fails with an error other than a
+// busy database, or has been retried db.retries times. The backoff
+// between attempts doubles every time.
+func (db *murmurDB) retry(fn func() error) error {
+	backoff := murmurBusyBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isBusy(err) || attempt >= db.retries {
+			return err
+		}
+		log.Printf("Murmur database is busy, retrying in %v", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-db.ctx.Done():
+			return db.ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// each runs query and calls fn for every row it returns. The query,
+// including the reading of its rows, must finish within the query
+// timeout. A query that finds the database busy is retried, unless
+// rows were already passed to fn.
+func (db *murmurDB) each(fn func(rows *sql.Rows) error, query string, args ...interface{}) error {
+	var rowErr error
+	err := db.retry(func() error {
+		ctx, cancel := context.WithTimeout(db.ctx, db.timeout)
+		defer cancel()
+
+		rows, err := db.tx.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		started := false
+		for rows.Next() {
+			started = true
+			if rowErr = fn(rows); rowErr != nil {
+				return nil
+			}
+		}
+		err = rows.Err()
+		if err != nil && started {
+			rowErr = err
+			return nil
+		}
+		return err
+	})
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
+	return rowErr
+}
 
-	rows, err := db.Query("SELECT server_id FROM servers")
+// Import the structure of an existing Murmur SQLite database. Each
+// query must finish within timeout, and is retried up to retries times
+// while Murmur holds a lock on the database.
+func MurmurImport(ctx context.Context, filename string, timeout time.Duration, retries int) (err error) {
+	sqldb, err := sql.Open("sqlite", filename)
 	if err != nil {
 		panic(err.Error())
 	}
+	defer sqldb.Close()
+
+	db := &murmurDB{ctx: ctx, timeout: timeout, retries: retries}
+	err = db.retry(func() (err error) {
+		db.tx, err = sqldb.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer db.tx.Rollback()
 
 	var serverids []int64
-	var sid int64
-	for rows.Next() {
-		err = rows.Scan(&sid)
-		if err != nil {
+	err = db.each(func(rows *sql.Rows) error {
+		var sid int64
+		if err := rows.Scan(&sid); err != nil {
 			return err
 		}
 		serverids = append(serverids, sid)
+		return nil
+	}, "SELECT server_id FROM servers")
+	if err != nil {
+		return err
 	}
 
 	log.Printf("Found servers: %v (%v servers)", serverids, len(serverids))
@@ -85,7 +185,7 @@ func MurmurImport(filename string) (err error) {
 }
 
 // Create a new Server from a Murmur SQLite database
-func NewServerFromSQLite(id int64, db *sql.DB) (s *Server, err error) {
+func NewServerFromSQLite(id int64, db *murmurDB) (s *Server, err error) {
 	s, err = NewServer(id)
 	if err != nil {
 		return nil, err
@@ -130,21 +230,13 @@ func NewServerFromSQLite(id int64, db *sql.DB) (s *Server, err error) {
 }
 
 // Add channel metadata (channel_info table from SQLite) by reading the SQLite database.
-func populateChannelInfoFromDatabase(server *Server, c *Channel, db *sql.DB) error {
-	stmt, err := db.Prepare("SELECT value FROM channel_info WHERE server_id=? AND channel_id=? AND key=?")
-	if err != nil {
-		return err
-	}
+func populateChannelInfoFromDatabase(server *Server, c *Channel, db *murmurDB) error {
+	const query = "SELECT value FROM channel_info WHERE server_id=? AND channel_id=? AND key=?"
 
 	// Fetch description
-	rows, err := stmt.Query(server.Id, c.Id, ChannelInfoDescription)
-	if err != nil {
-		return err
-	}
-	for rows.Next() {
+	err := db.each(func(rows *sql.Rows) error {
 		var description string
-		err = rows.Scan(&description)
-		if err != nil {
+		if err := rows.Scan(&description); err != nil {
 			return err
 		}
 
@@ -155,38 +247,27 @@ func populateChannelInfoFromDatabase(server *Server, c *Channel, db *sql.DB) err
 			}
 			c.DescriptionBlob = key
 		}
-	}
-
-	// Fetch position
-	rows, err = stmt.Query(server.Id, c.Id, ChannelInfoPosition)
+		return nil
+	}, query, server.Id, c.Id, ChannelInfoDescription)
 	if err != nil {
 		return err
 	}
-	for rows.Next() {
+
+	// Fetch position
+	return db.each(func(rows *sql.Rows) error {
 		var pos int
 		if err := rows.Scan(&pos); err != nil {
 			return err
 		}
 
 		c.Position = pos
-	}
-
-	return nil
+		return nil
+	}, query, server.Id, c.Id, ChannelInfoPosition)
 }
 
 // Populate channel with its ACLs by reading the SQLite databse.
-func populateChannelACLFromDatabase(server *Server, c *Channel, db *sql.DB) error {
-	stmt, err := db.Prepare("SELECT user_id, group_name, apply_here, apply_sub, grantpriv, revokepriv FROM acl WHERE server_id=? AND channel_id=? ORDER BY priority")
-	if err != nil {
-		return err
-	}
-
-	rows, err := stmt.Query(server.Id, c.Id)
-	if err != nil {
-		return err
-	}
-
-	for rows.Next() {
+func populateChannelACLFromDatabase(server *Server, c *Channel, db *murmurDB) error {
+	return db.each(func(rows *sql.Rows) error {
 		var (
 			UserId    string
 			Group     string
@@ -203,6 +284,7 @@ func populateChannelACLFromDatabase(server *Server, c *Channel, db *sql.DB) erro
 		aclEntry.ApplyHere = ApplyHere
 		aclEntry.ApplySubs = ApplySub
 		if len(UserId) > 0 {
+			var err error
 			aclEntry.UserId, err = strconv.Atoi(UserId)
 			if err != nil {
 				return err
@@ -216,26 +298,15 @@ func populateChannelACLFromDatabase(server *Server, c *Channel, db *sql.DB) erro
 		aclEntry.Deny = acl.Permission(Deny)
 		aclEntry.Allow = acl.Permission(Allow)
 		c.ACL.ACLs = append(c.ACL.ACLs, aclEntry)
-	}
-
-	return nil
+		return nil
+	}, "SELECT user_id, group_name, apply_here, apply_sub, grantpriv, revokepriv FROM acl WHERE server_id=? AND channel_id=? ORDER BY priority", server.Id, c.Id)
 }
 
 // Populate channel with groups by reading the SQLite database.
-func populateChannelGroupsFromDatabase(server *Server, c *Channel, db *sql.DB) error {
-	stmt, err := db.Prepare("SELECT group_id, name, inherit, inheritable FROM groups WHERE server_id=? AND channel_id=?")
-	if err != nil {
-		return err
-	}
-
-	rows, err := stmt.Query(server.Id, c.Id)
-	if err != nil {
-		return err
-	}
-
+func populateChannelGroupsFromDatabase(server *Server, c *Channel, db *murmurDB) error {
 	groups := make(map[int64]acl.Group)
 
-	for rows.Next() {
+	err := db.each(func(rows *sql.Rows) error {
 		var (
 			GroupId     int64
 			Name        string
@@ -252,20 +323,14 @@ func populateChannelGroupsFromDatabase(server *Server, c *Channel, db *sql.DB) e
 		g.Inheritable = Inheritable
 		c.ACL.Groups[g.Name] = g
 		groups[GroupId] = g
-	}
-
-	stmt, err = db.Prepare("SELECT user_id, addit FROM group_members WHERE server_id=? AND group_id=?")
+		return nil
+	}, "SELECT group_id, name, inherit, inheritable FROM groups WHERE server_id=? AND channel_id=?", server.Id, c.Id)
 	if err != nil {
 		return err
 	}
 
 	for gid, grp := range groups {
-		rows, err = stmt.Query(server.Id, gid)
-		if err != nil {
-			return err
-		}
-
-		for rows.Next() {
+		err = db.each(func(rows *sql.Rows) error {
 			var (
 				UserId int64
 				Add    bool
@@ -280,6 +345,10 @@ func populateChannelGroupsFromDatabase(server *Server, c *Channel, db *sql.DB) e
 			} else {
 				grp.Remove[int(UserId)] = true
 			}
+			return nil
+		}, "SELECT user_id, addit FROM group_members WHERE server_id=? AND group_id=?", server.Id, gid)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -287,30 +356,19 @@ func populateChannelGroupsFromDatabase(server *Server, c *Channel, db *sql.DB) e
 }
 
 // Populate the Server with Channels from the database.
-func populateChannelsFromDatabase(server *Server, db *sql.DB, parentId int) error {
+func populateChannelsFromDatabase(server *Server, db *murmurDB, parentId int) error {
 	parent, exists := server.Channels[parentId]
 	if !exists {
 		return errors.New("Non-existant parent")
 	}
 
-	stmt, err := db.Prepare("SELECT channel_id, name, inheritacl FROM channels WHERE server_id=? AND parent_id=?")
-	if err != nil {
-		return err
-	}
-
-	rows, err := stmt.Query(server.Id, parentId)
-	if err != nil {
-		return err
-	}
-
-	for rows.Next() {
+	err := db.each(func(rows *sql.Rows) error {
 		var (
 			name    string
 			chanid  int
 			inherit bool
 		)
-		err = rows.Scan(&chanid, &name, &inherit)
-		if err != nil {
+		if err := rows.Scan(&chanid, &name, &inherit); err != nil {
 			return err
 		}
 
@@ -318,6 +376,10 @@ func populateChannelsFromDatabase(server *Server, db *sql.DB, parentId int) erro
 		server.Channels[c.Id] = c
 		c.ACL.InheritACL = inherit
 		parent.AddChild(c)
+		return nil
+	}, "SELECT channel_id, name, inheritacl FROM channels WHERE server_id=? AND parent_id=?", server.Id, parentId)
+	if err != nil {
+		return err
 	}
 
 	// Add channel_info
@@ -356,18 +418,8 @@ func populateChannelsFromDatabase(server *Server, db *sql.DB, parentId int) erro
 }
 
 // Link a Server's channels together
-func populateChannelLinkInfo(server *Server, db *sql.DB) (err error) {
-	stmt, err := db.Prepare("SELECT channel_id, link_id FROM channel_links WHERE server_id=?")
-	if err != nil {
-		return err
-	}
-
-	rows, err := stmt.Query(server.Id)
-	if err != nil {
-		return err
-	}
-
-	for rows.Next() {
+func populateChannelLinkInfo(server *Server, db *murmurDB) (err error) {
+	return db.each(func(rows *sql.Rows) error {
 		var (
 			ChannelId int
 			LinkId    int
@@ -387,24 +439,13 @@ func populateChannelLinkInfo(server *Server, db *sql.DB) (err error) {
 		}
 
 		server.LinkChannels(channel, other)
-	}
-
-	return nil
+		return nil
+	}, "SELECT channel_id, link_id FROM channel_links WHERE server_id=?", server.Id)
 }
 
-func populateUsers(server *Server, db *sql.DB) (err error) {
+func populateUsers(server *Server, db *murmurDB) (err error) {
 	// Populate the server with regular user data
-	stmt, err := db.Prepare("SELECT user_id, name, pw, lastchannel, texture, strftime('%s', last_active) FROM users WHERE server_id=?")
-	if err != nil {
-		return
-	}
-
-	rows, err := stmt.Query(server.Id)
-	if err != nil {
-		return
-	}
-
-	for rows.Next() {
+	err = db.each(func(rows *sql.Rows) error {
 		var (
 			UserId       int64
 			UserName     string
@@ -414,9 +455,9 @@ func populateUsers(server *Server, db *sql.DB) (err error) {
 			LastActive   int64
 		)
 
-		err = rows.Scan(&UserId, &UserName, &SHA1Password, &LastChannel, &Texture, &LastActive)
+		err := rows.Scan(&UserId, &UserName, &SHA1Password, &LastChannel, &Texture, &LastActive)
 		if err != nil {
-			continue
+			return nil
 		}
 
 		if UserId == 0 {
@@ -440,28 +481,21 @@ func populateUsers(server *Server, db *sql.DB) (err error) {
 		user.LastChannelId = LastChannel
 
 		server.Users[user.Id] = user
-	}
-
-	stmt, err = db.Prepare("SELECT key, value FROM user_info WHERE server_id=? AND user_id=?")
+		return nil
+	}, "SELECT user_id, name, pw, lastchannel, texture, strftime('%s', last_active) FROM users WHERE server_id=?", server.Id)
 	if err != nil {
 		return
 	}
 
 	// Populate users with any new-style UserInfo records
 	for uid, user := range server.Users {
-		rows, err = stmt.Query(server.Id, uid)
-		if err != nil {
-			return err
-		}
-
-		for rows.Next() {
+		err = db.each(func(rows *sql.Rows) error {
 			var (
 				Key   int
 				Value string
 			)
 
-			err = rows.Scan(&Key, &Value)
-			if err != nil {
+			if err := rows.Scan(&Key, &Value); err != nil {
 				return err
 			}
 
@@ -475,7 +509,7 @@ func populateUsers(server *Server, db *sql.DB) (err error) {
 				}
 				user.CommentBlob = key
 			case UserInfoHash:
-				user.CertHash = Value
+				user.Certificates = []UserCertificate{{Hash: Value}}
 			case UserInfoLastActive:
 				// not a kv-pair (trigger)
 			case UserInfoPassword:
@@ -483,6 +517,10 @@ func populateUsers(server *Server, db *sql.DB) (err error) {
 			case UserInfoName:
 				// not a kv-pair
 			}
+			return nil
+		}, "SELECT key, value FROM user_info WHERE server_id=? AND user_id=?", server.Id, uid)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -490,18 +528,8 @@ func populateUsers(server *Server, db *sql.DB) (err error) {
 }
 
 // Populate bans
-func populateBans(server *Server, db *sql.DB) (err error) {
-	stmt, err := db.Prepare("SELECT base, mask, name, hash, reason, start, duration FROM bans WHERE server_id=?")
-	if err != nil {
-		return
-	}
-
-	rows, err := stmt.Query(server.Id)
-	if err != nil {
-		return err
-	}
-
-	for rows.Next() {
+func populateBans(server *Server, db *murmurDB) (err error) {
+	return db.each(func(rows *sql.Rows) error {
 		var (
 			Ban       ban.Ban
 			IP        []byte
@@ -509,7 +537,7 @@ func populateBans(server *Server, db *sql.DB) (err error) {
 			Duration  int64
 		)
 
-		err = rows.Scan(&IP, &Ban.Mask, &Ban.Username, &Ban.CertHash, &Ban.Reason, &StartDate, &Duration)
+		err := rows.Scan(&IP, &Ban.Mask, &Ban.Username, &Ban.CertHash, &Ban.Reason, &StartDate, &Duration)
 		if err != nil {
 			return err
 		}
@@ -522,9 +550,10 @@ func populateBans(server *Server, db *sql.DB) (err error) {
 
 		Ban.SetISOStartDate(StartDate)
 		Ban.Duration = uint32(Duration)
+		Ban.Id = server.nextBanId
+		server.nextBanId += 1
 
 		server.Bans = append(server.Bans, Ban)
-	}
-
-	return
+		return nil
+	}, "SELECT base, mask, name, hash, reason, start, duration FROM bans WHERE server_id=?", server.Id)
 }
@@ -0,0 +1,90 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the name policy for usernames and channel names.
+//
+// UsernameRegex and ChannelNameRegex must match a name as a whole.
+// UsernameMaxLength and ChannelNameMaxLength limit the number of
+// characters in a name. ReservedNamePrefixes lists prefixes, separated
+// by commas, that unregistered users may not use.
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// A regexpCache holds the compiled name patterns.
+type regexpCache struct {
+	mutex    sync.Mutex
+	compiled map[string]*regexp.Regexp
+}
+
+// matches checks whether pattern matches s as a whole.
+func (c *regexpCache) matches(pattern, s string) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	re, ok := c.compiled[pattern]
+	if !ok {
+		var err error
+		re, err = regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return false, err
+		}
+		if c.compiled == nil {
+			c.compiled = make(map[string]*regexp.Regexp)
+		}
+		c.compiled[pattern] = re
+	}
+	return re.MatchString(s), nil
+}
+
+// checkName checks name against the pattern and maximum length given by
+// the config keys regexKey and lengthKey. It returns why the name isn't
+// allowed, or an empty string if it is.
+func (server *Server) checkName(name, regexKey, lengthKey string) string {
+	if max := server.cfg.IntValue(lengthKey); max > 0 && utf8.RuneCountInString(name) > max {
+		return fmt.Sprintf("Names may be at most %v characters long", max)
+	}
+	if pattern := server.cfg.StringValue(regexKey); len(pattern) > 0 {
+		ok, err := server.namePatterns.matches(pattern, name)
+		if err != nil {
+			server.Printf("Invalid %v: %v", regexKey, err)
+			return ""
+		}
+		if !ok {
+			return "The name contains characters that are not allowed"
+		}
+	}
+	return ""
+}
+
+// checkUsername checks name against the username policy. Reserved
+// prefixes are only checked if reserved is set. It returns why the name
+// isn't allowed, or an empty string if it is.
+func (server *Server) checkUsername(name string, reserved bool) string {
+	if reason := server.checkName(name, "UsernameRegex", "UsernameMaxLength"); len(reason) > 0 {
+		return reason
+	}
+	if reserved {
+		for _, prefix := range strings.Split(server.cfg.StringValue("ReservedNamePrefixes"), ",") {
+			prefix = strings.TrimSpace(prefix)
+			if len(prefix) > 0 && strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+				return fmt.Sprintf("Names starting with %q are reserved for registered users", prefix)
+			}
+		}
+	}
+	return ""
+}
+
+// checkChannelName checks name against the channel name policy. It
+// returns why the name isn't allowed, or an empty string if it is.
+func (server *Server) checkChannelName(name string) string {
+	return server.checkName(name, "ChannelNameRegex", "ChannelNameMaxLength")
+}
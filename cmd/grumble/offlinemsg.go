@@ -0,0 +1,167 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements offline messages.
+//
+// A registered user can leave a text message for a registered user who
+// is offline with the /tell chat command. The message is stored with
+// the recipient's registration and delivered when it next connects.
+// A user holds at most OfflineMessageQuota messages, and can have at
+// most as many waiting for others. Messages that aren't delivered
+// within OfflineMessageExpiry days are dropped.
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// An OfflineMessage is a text message waiting for a registered user.
+type OfflineMessage struct {
+	SenderId   uint32
+	SenderName string
+	// The message, filtered like other text messages
+	Text string
+	// When the message was sent, in Unix time
+	Sent int64
+}
+
+func init() {
+	registerChatCommand("tell", &chatCommand{
+		usage:      []string{"tell <user> <message>"},
+		help:       "Leave a message for a user who is offline",
+		registered: true,
+		perm:       acl.TextMessagePermission,
+		run:        (*Server).tellCommand,
+	})
+}
+
+// offlineMessageExpired checks whether msg is too old to deliver.
+func (server *Server) offlineMessageExpired(msg OfflineMessage, now time.Time) bool {
+	days := server.cfg.IntValue("OfflineMessageExpiry")
+	return days > 0 && now.Sub(time.Unix(msg.Sent, 0)) > time.Duration(days)*24*time.Hour
+}
+
+// expireOfflineMessages drops the offline messages that are too old to
+// deliver.
+func (server *Server) expireOfflineMessages() {
+	now := time.Now()
+	for _, user := range server.Users {
+		if len(user.OfflineMessages) == 0 {
+			continue
+		}
+		kept := user.OfflineMessages[:0]
+		for _, msg := range user.OfflineMessages {
+			if !server.offlineMessageExpired(msg, now) {
+				kept = append(kept, msg)
+			}
+		}
+		if len(kept) != len(user.OfflineMessages) {
+			user.OfflineMessages = kept
+			server.UpdateFrozenUserRecord(user)
+		}
+	}
+}
+
+// pendingOfflineMessages counts the messages sender has waiting for
+// other users.
+func (server *Server) pendingOfflineMessages(sender *User) int {
+	pending := 0
+	for _, user := range server.Users {
+		for _, msg := range user.OfflineMessages {
+			if msg.SenderId == sender.Id {
+				pending++
+			}
+		}
+	}
+	return pending
+}
+
+// deliverOfflineMessages sends the registered user of client the
+// messages left for it.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) deliverOfflineMessages(client *Client) {
+	user := client.user
+	if user == nil || len(user.OfflineMessages) == 0 {
+		return
+	}
+	now := time.Now()
+	for _, msg := range user.OfflineMessages {
+		if server.offlineMessageExpired(msg, now) {
+			continue
+		}
+		sent := time.Unix(msg.Sent, 0).UTC().Format("2006-01-02 15:04 MST")
+		server.sendServerText(client, fmt.Sprintf("Message from <b>%v</b>, sent %v: %v",
+			html.EscapeString(msg.SenderName), sent, msg.Text))
+	}
+	client.Printf("Delivered %v offline messages", len(user.OfflineMessages))
+	user.OfflineMessages = nil
+	server.UpdateFrozenUserRecord(user)
+}
+
+// tellCommand implements the /tell chat command, which leaves a message
+// for a registered user. If the user is online, it is sent at once.
+func (server *Server) tellCommand(client *Client, args []string, text string) {
+	quota := server.cfg.IntValue("OfflineMessageQuota")
+	if quota == 0 {
+		server.sendServerText(client, "Offline messages are disabled on this server")
+		return
+	}
+	body := chatCommandRest(text, 2)
+	if len(args) < 3 || len(body) == 0 {
+		server.sendChatCommandUsage(client, args[0])
+		return
+	}
+	recipient, ok := server.registeredUser(args[1])
+	if !ok {
+		server.sendServerText(client, fmt.Sprintf("No registered user named %v", html.EscapeString(args[1])))
+		return
+	}
+	if max := server.cfg.IntValue("MaxTextMessageLength"); max > 0 && len(body) > max {
+		client.sendPermissionDeniedType(mumbleproto.PermissionDenied_TextTooLong)
+		return
+	}
+	filtered, ok := server.applyWordFilter(client, html.EscapeString(body))
+	if ok {
+		filtered, ok = server.applyPluginMessageFilters(client, filtered)
+	}
+	if !ok || len(filtered) == 0 {
+		return
+	}
+
+	if target := server.onlineUsers()[recipient]; target != nil {
+		target.sendMessage(&mumbleproto.TextMessage{
+			Actor:   proto.Uint32(client.Session()),
+			Session: []uint32{target.Session()},
+			Message: proto.String(filtered),
+		})
+		server.sendServerText(client, fmt.Sprintf("%v is online; your message was sent", html.EscapeString(recipient.Name)))
+		return
+	}
+
+	if len(recipient.OfflineMessages) >= quota {
+		server.sendServerText(client, fmt.Sprintf("%v can't receive more messages until they connect", html.EscapeString(recipient.Name)))
+		return
+	}
+	if server.pendingOfflineMessages(client.user) >= quota {
+		server.sendServerText(client, fmt.Sprintf("You already have %v messages waiting to be delivered", quota))
+		return
+	}
+	recipient.OfflineMessages = append(recipient.OfflineMessages, OfflineMessage{
+		SenderId:   client.user.Id,
+		SenderName: client.user.Name,
+		Text:       filtered,
+		Sent:       time.Now().Unix(),
+	})
+	server.UpdateFrozenUserRecord(recipient)
+	client.Printf("Left an offline message for user %v", recipient.Id)
+	server.sendServerText(client, fmt.Sprintf("Your message will be delivered when %v next connects", html.EscapeString(recipient.Name)))
+}
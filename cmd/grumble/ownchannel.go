@@ -0,0 +1,73 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements channel creation by trusted users.
+//
+// Registered members of the group named by ChannelCreateGroup may
+// create permanent channels where they lack MakeChannelPermission.
+// The group is evaluated in the parent channel, so it may be a group
+// defined in the ACLs, or a special group such as "auth". Each member
+// may own at most ChannelCreateQuota channels (default 3; 0 means no
+// limit). The creator owns the channel: they are given an ACL entry
+// that lets them edit it, move and mute its users, and link it, and
+// the channel counts against their quota until it is removed.
+
+import (
+	"fmt"
+
+	"mumble.info/grumble/pkg/acl"
+)
+
+// The permissions the owner of a channel is given in it.
+const ownerPermissions = acl.WritePermission | acl.TraversePermission | acl.EnterPermission |
+	acl.SpeakPermission | acl.MuteDeafenPermission | acl.MovePermission |
+	acl.LinkChannelPermission | acl.WhisperPermission | acl.TextMessagePermission |
+	acl.HTMLMessagePermission | acl.LinkMessagePermission
+
+// isChannelCreator reports whether client is a registered member of
+// ChannelCreateGroup in parent.
+func (server *Server) isChannelCreator(client *Client, parent *Channel) bool {
+	group := server.cfg.StringValue("ChannelCreateGroup")
+	if len(group) == 0 || !client.IsRegistered() {
+		return false
+	}
+	return acl.GroupMemberCheck(&parent.ACL, &parent.ACL, group, client)
+}
+
+// ownedChannels returns the number of channels owned by the user with
+// the given id.
+func (server *Server) ownedChannels(userId int) int {
+	n := 0
+	for _, channel := range server.Channels {
+		if channel.OwnerId == userId {
+			n++
+		}
+	}
+	return n
+}
+
+// checkChannelQuota returns the reason client may not create another
+// channel of its own, or an empty string if it may.
+func (server *Server) checkChannelQuota(client *Client) string {
+	quota := server.cfg.IntValue("ChannelCreateQuota")
+	if quota > 0 && server.ownedChannels(client.UserId()) >= quota {
+		return fmt.Sprintf("You already own %v channels, the most allowed", quota)
+	}
+	return ""
+}
+
+// grantChannelOwnership makes client the owner of channel.
+func (server *Server) grantChannelOwnership(channel *Channel, client *Client) {
+	channel.OwnerId = client.UserId()
+	channel.ACL.ACLs = append(channel.ACL.ACLs, acl.ACL{
+		UserId:    client.UserId(),
+		ApplyHere: true,
+		ApplySubs: true,
+		Allow:     acl.Permission(ownerPermissions),
+		Deny:      acl.Permission(acl.NonePermission),
+	})
+	server.ClearCaches()
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the limits on checking passwords.
+//
+// Checking an argon2id hash takes PasswordHashMemory of memory and
+// a noticeable amount of CPU time, and clients can ask for a check
+// before they have authenticated. At most GOMAXPROCS checks run at
+// once, across all virtual servers; others wait for their turn. Each
+// address may also only fail a few checks in a row: after
+// passwordFailureBurst failures, its checks fail without the password
+// being looked at until it has waited long enough.
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"mumble.info/grumble/pkg/password"
+)
+
+// Failed password checks drain from an address' bucket at this rate
+// per second, and the bucket holds this many.
+const (
+	passwordFailureRate  = 1.0 / 30
+	passwordFailureBurst = 10
+)
+
+// The number of addresses whose failures are tracked. When more
+// addresses fail, those whose bucket has drained are forgotten.
+const passwordHostsKept = 16384
+
+// passwordSlots bounds the number of password checks run at once.
+var passwordSlots = make(chan struct{}, runtime.GOMAXPROCS(0))
+
+// passwordThrottle holds the failed password checks of each address.
+type passwordThrottle struct {
+	mutex sync.Mutex
+	hosts map[string]*leakyBucket
+}
+
+// full checks whether another event fits in the bucket, without
+// adding one.
+func (b *leakyBucket) full(now time.Time, rate, burst float64) bool {
+	level := b.level - now.Sub(b.last).Seconds()*rate
+	return level+1 > burst
+}
+
+// throttled checks whether host failed too many password checks.
+func (throttle *passwordThrottle) throttled(host string, now time.Time) bool {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	bucket, ok := throttle.hosts[host]
+	return ok && bucket.full(now, passwordFailureRate, passwordFailureBurst)
+}
+
+// failed records a failed password check from host.
+func (throttle *passwordThrottle) failed(host string, now time.Time) {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+	if throttle.hosts == nil {
+		throttle.hosts = make(map[string]*leakyBucket)
+	}
+	bucket, ok := throttle.hosts[host]
+	if !ok {
+		if len(throttle.hosts) >= passwordHostsKept {
+			throttle.prune(now)
+		}
+		bucket = &leakyBucket{}
+		throttle.hosts[host] = bucket
+	}
+	bucket.allow(now, passwordFailureRate, passwordFailureBurst)
+}
+
+// prune forgets the addresses whose bucket has drained, or all of
+// them if that isn't enough.
+func (throttle *passwordThrottle) prune(now time.Time) {
+	drained := time.Duration(passwordFailureBurst / passwordFailureRate * float64(time.Second))
+	for host, bucket := range throttle.hosts {
+		if now.Sub(bucket.last) > drained {
+			delete(throttle.hosts, host)
+		}
+	}
+	if len(throttle.hosts) >= passwordHostsKept {
+		throttle.hosts = make(map[string]*leakyBucket)
+	}
+}
+
+// passwordThrottled checks whether client's address failed too many
+// password checks to have another one checked.
+func (server *Server) passwordThrottled(client *Client) bool {
+	return server.passwords.throttled(client.tcpaddr.IP.String(), time.Now())
+}
+
+// passwordFailed records a failed password check from client's
+// address.
+func (server *Server) passwordFailed(client *Client) {
+	server.passwords.failed(client.tcpaddr.IP.String(), time.Now())
+}
+
+// verifyPassword checks pw against a stored hash, waiting for a free
+// slot first.
+//
+// Must not be called from the server's handler goroutine.
+func verifyPassword(pw, stored string) bool {
+	passwordSlots <- struct{}{}
+	defer func() { <-passwordSlots }()
+	return password.Verify(pw, stored)
+}
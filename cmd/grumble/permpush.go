@@ -0,0 +1,57 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file pushes permission changes to clients.
+//
+// Clients ask for their permissions in a channel with a PermissionQuery,
+// and grey out the actions they aren't allowed to take. Each client's
+// answers are remembered, and when ACLs, groups, grants or access tokens
+// change, the permissions in the channels the client asked about are
+// evaluated again. Those that changed are sent right away, so that the
+// client's UI doesn't wait for its next query to catch up.
+
+import (
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// pushClientPermissions sends client its permissions in the channels it
+// was sent permissions for before, if they changed.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) pushClientPermissions(client *Client) {
+	if client.state != StateClientReady || client.IsSuperUser() {
+		return
+	}
+	for id, sent := range client.sentPermissions {
+		channel, ok := server.Channels[id]
+		if !ok {
+			delete(client.sentPermissions, id)
+			continue
+		}
+		perm := acl.EffectivePermissions(&channel.ACL, client)
+		if perm == sent {
+			continue
+		}
+		client.sentPermissions[id] = perm
+		client.sendMessage(&mumbleproto.PermissionQuery{
+			ChannelId:   proto.Uint32(uint32(id)),
+			Permissions: proto.Uint32(uint32(perm)),
+		})
+	}
+}
+
+// pushPermissions clears the server's caches, and sends every client the
+// permissions that changed.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) pushPermissions() {
+	server.ClearCaches()
+	for _, client := range server.clients {
+		server.pushClientPermissions(client)
+	}
+}
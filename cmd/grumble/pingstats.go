@@ -0,0 +1,134 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the reporting of the ping times clients report
+// in their Ping messages.
+//
+// The ping times of all connected clients are listed at
+// /servers/<id>/clients in the admin API. If PingSummaryInterval is
+// set, a summary naming the clients with the highest ping times is
+// also sent every PingSummaryInterval seconds to the users allowed to
+// kick in the root channel.
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The number of clients named in the ping summary.
+const pingSummaryTop = 5
+
+// apiClient is the JSON representation of a connected client.
+type apiClient struct {
+	Session    uint32  `json:"session"`
+	Name       string  `json:"name"`
+	UserId     int     `json:"user_id"`
+	Channel    int     `json:"channel"`
+	UDP        bool    `json:"udp"`
+	TcpPingAvg float32 `json:"tcp_ping_avg"`
+	TcpPingVar float32 `json:"tcp_ping_var"`
+	UdpPingAvg float32 `json:"udp_ping_avg"`
+	UdpPingVar float32 `json:"udp_ping_var"`
+}
+
+// ping returns the ping time of the path the client's voice takes,
+// in milliseconds.
+func (client *Client) ping() float32 {
+	if client.usesUDP() && client.UdpPingAvg > 0 {
+		return client.UdpPingAvg
+	}
+	return client.TcpPingAvg
+}
+
+// sendPingSummary sends the ping summary to the users allowed to kick,
+// if it is due.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) sendPingSummary() {
+	interval := time.Duration(server.cfg.IntValue("PingSummaryInterval")) * time.Second
+	if interval <= 0 || time.Since(server.lastPingSummary) < interval {
+		return
+	}
+	server.lastPingSummary = time.Now()
+
+	var clients []*Client
+	var total float32
+	for _, client := range server.clients {
+		if client.state == StateClientReady && client.ping() > 0 {
+			clients = append(clients, client)
+			total += client.ping()
+		}
+	}
+	if len(clients) == 0 {
+		return
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ping() > clients[j].ping()
+	})
+
+	highest := []string{}
+	for i, client := range clients {
+		if i == pingSummaryTop {
+			break
+		}
+		highest = append(highest, fmt.Sprintf("%v (%.0f ms)", html.EscapeString(client.ShownName()), client.ping()))
+	}
+	text := fmt.Sprintf("Ping summary: %v users, %.0f ms on average. Highest: %v.",
+		len(clients), total/float32(len(clients)), strings.Join(highest, ", "))
+
+	server.notifyModerators(text)
+}
+
+func init() {
+	registerAPIEndpoint("clients", handleAPIClients)
+}
+
+// handleAPIClients implements /servers/<id>/clients.
+//
+//	GET  lists the connected clients and their ping times
+//
+// /servers/<id>/clients/<session>/register registers a guest; see
+// handleAPIRegisterClient.
+func handleAPIClients(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) == 2 && args[1] == "register" {
+		handleAPIRegisterClient(server, w, r, args)
+		return
+	}
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	clients := []apiClient{}
+	err := server.runSync(func() {
+		for _, client := range server.clients {
+			if client.state != StateClientReady {
+				continue
+			}
+			clients = append(clients, apiClient{
+				Session:    client.Session(),
+				Name:       client.ShownName(),
+				UserId:     client.UserId(),
+				Channel:    client.Channel.Id,
+				UDP:        client.usesUDP(),
+				TcpPingAvg: client.TcpPingAvg,
+				TcpPingVar: client.TcpPingVar,
+				UdpPingAvg: client.UdpPingAvg,
+				UdpPingVar: client.UdpPingVar,
+			})
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Session < clients[j].Session })
+	writeJSON(w, http.StatusOK, clients)
+}
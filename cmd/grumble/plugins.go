@@ -0,0 +1,184 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file runs the compiled-in plugins (see pkg/plugin).
+//
+// The plugins named by the Plugins configuration key are started with
+// the server, and stopped with it. Plugins are added to the binary by
+// importing them from a file in this directory, usually one guarded by
+// a build tag; see the documentation of pkg/plugin.
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/plugin"
+)
+
+// A pluginHost is a server's instance of a plugin, along with the
+// capabilities the plugin has registered. It implements plugin.Server.
+type pluginHost struct {
+	server *Server
+	name   string
+	plugin plugin.Plugin
+
+	authenticators []plugin.Authenticator
+	messageFilters []plugin.MessageFilter
+	eventHandlers  []plugin.EventHandler
+}
+
+// startPlugins starts the plugins named by the Plugins key. If one of
+// them cannot be started, the ones already started are stopped again.
+func (server *Server) startPlugins() error {
+	hosts := []*pluginHost{}
+	for _, name := range splitList(server.cfg.StringValue("Plugins")) {
+		p, err := plugin.New(name)
+		if err == nil {
+			host := &pluginHost{server: server, name: name, plugin: p}
+			err = p.Start(host)
+			if err == nil {
+				hosts = append(hosts, host)
+				server.Printf("Started plugin %v", name)
+				continue
+			}
+		}
+		for _, host := range hosts {
+			host.plugin.Stop()
+		}
+		return fmt.Errorf("plugin %v: %v", name, err)
+	}
+	server.plugins = hosts
+	return nil
+}
+
+// stopPlugins stops the server's plugins.
+func (server *Server) stopPlugins() {
+	for _, host := range server.plugins {
+		host.plugin.Stop()
+	}
+	server.plugins = nil
+}
+
+// applyPluginAuthenticators asks the plugins' authenticators about
+// a connecting client. The first decision other than plugin.Pass wins.
+//
+// Called from the client's goroutine.
+func (server *Server) applyPluginAuthenticators(client *Client, auth *mumbleproto.Authenticate) plugin.AuthResult {
+	if len(server.plugins) == 0 {
+		return plugin.AuthResult{Decision: plugin.Pass}
+	}
+	req := &plugin.AuthRequest{
+		Username: client.Username,
+		Password: auth.GetPassword(),
+		Tokens:   auth.Tokens,
+		CertHash: client.CertHash(),
+		UserId:   client.UserId(),
+	}
+	if client.tcpaddr != nil {
+		req.Address = client.tcpaddr.IP
+	}
+	for _, host := range server.plugins {
+		for _, a := range host.authenticators {
+			result := a.Authenticate(req)
+			if result.Decision != plugin.Pass {
+				return result
+			}
+		}
+	}
+	return plugin.AuthResult{Decision: plugin.Pass}
+}
+
+// applyPluginMessageFilters runs a text message through the plugins'
+// message filters. It returns the text to pass on, and false if the
+// message is to be dropped.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) applyPluginMessageFilters(client *Client, text string) (string, bool) {
+	if len(server.plugins) == 0 {
+		return text, true
+	}
+	user := pluginUser(client)
+	for _, host := range server.plugins {
+		for _, f := range host.messageFilters {
+			var ok bool
+			text, ok = f.FilterMessage(user, text)
+			if !ok {
+				return "", false
+			}
+		}
+	}
+	return text, true
+}
+
+func (host *pluginHost) Id() int64 {
+	return host.server.Id
+}
+
+func (host *pluginHost) Config(key string) string {
+	return host.server.cfg.StringValue(key)
+}
+
+func (host *pluginHost) Printf(format string, v ...interface{}) {
+	host.server.Printf("plugin %v: %v", host.name, fmt.Sprintf(format, v...))
+}
+
+func (host *pluginHost) Do(fn func()) error {
+	return host.server.runSync(fn)
+}
+
+func (host *pluginHost) client(session uint32) (*Client, error) {
+	client, ok := host.server.clients[session]
+	if !ok || client.state != StateClientReady {
+		return nil, errors.New("no such session")
+	}
+	return client, nil
+}
+
+func (host *pluginHost) Users() []plugin.User {
+	users := []plugin.User{}
+	for _, client := range host.server.clients {
+		if client.state == StateClientReady {
+			users = append(users, pluginUser(client))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Session < users[j].Session })
+	return users
+}
+
+func (host *pluginHost) SendMessage(session uint32, text string) error {
+	client, err := host.client(session)
+	if err != nil {
+		return err
+	}
+	return client.sendMessage(&mumbleproto.TextMessage{
+		Session: []uint32{session},
+		Message: proto.String(text),
+	})
+}
+
+func (host *pluginHost) Kick(session uint32, reason string) error {
+	client, err := host.client(session)
+	if err != nil {
+		return err
+	}
+	host.server.KickClient(client, reason)
+	return nil
+}
+
+func (host *pluginHost) RegisterAuthenticator(a plugin.Authenticator) {
+	host.authenticators = append(host.authenticators, a)
+}
+
+func (host *pluginHost) RegisterMessageFilter(f plugin.MessageFilter) {
+	host.messageFilters = append(host.messageFilters, f)
+}
+
+func (host *pluginHost) RegisterEventHandler(h plugin.EventHandler) {
+	host.eventHandlers = append(host.eventHandlers, h)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements distance-based routing of voice.
+//
+// Clients with positional audio enabled append their position in the
+// game to their voice packets. If PositionalRadius is set, voice sent
+// to a channel is only forwarded to the clients in the channel that
+// are within PositionalRadius of the speaker. A client's position is
+// only known from its own voice packets, so clients whose position is
+// unknown, or older than positionMaxAge, hear everyone, as do clients
+// in another game than the speaker's. Whispers to voice targets are
+// forwarded regardless of distance.
+
+import (
+	"bytes"
+	"time"
+)
+
+// The time a client's position is trusted after its last voice packet.
+const positionMaxAge = 30 * time.Second
+
+// setPosition records the position the client sent with its voice.
+func (client *Client) setPosition(x, y, z float32) {
+	client.posLock.Lock()
+	defer client.posLock.Unlock()
+	client.pos = [3]float32{x, y, z}
+	client.posTime = time.Now()
+}
+
+// position returns the client's last known position, or false if it
+// isn't known.
+func (client *Client) position() ([3]float32, bool) {
+	client.posLock.Lock()
+	defer client.posLock.Unlock()
+	if client.posTime.IsZero() || time.Since(client.posTime) > positionMaxAge {
+		return [3]float32{}, false
+	}
+	return client.pos, true
+}
+
+// inHearingRange checks whether listener should be sent the voice of
+// speaker in the same channel.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) inHearingRange(speaker, listener *Client) bool {
+	radius := float32(server.cfg.IntValue("PositionalRadius"))
+	if radius <= 0 {
+		return true
+	}
+	if len(speaker.PluginContext) == 0 || !bytes.Equal(speaker.PluginContext, listener.PluginContext) {
+		return true
+	}
+	from, ok := speaker.position()
+	if !ok {
+		return true
+	}
+	to, ok := listener.position()
+	if !ok {
+		return true
+	}
+	dx, dy, dz := from[0]-to[0], from[1]-to[1], from[2]-to[2]
+	return dx*dx+dy*dy+dz*dz <= radius*radius
+}
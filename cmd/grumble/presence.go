@@ -0,0 +1,218 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the batching of join and leave broadcasts.
+//
+// On a large server, every client that connects or disconnects costs a
+// message to every other client. With PresenceBatchInterval set, these
+// messages are collected for that many milliseconds and then written
+// to each client at once. A client that leaves before its join was sent
+// is never announced at all, which takes the edge off reconnect storms.
+// Any other broadcast, and any voice or text message from a user whose
+// join is still collected, sends the collected messages first, so that
+// clients never hear of a user they don't know yet.
+//
+// Clients that add the access token reducedstate are told only the
+// name, channel, registration and mute and deaf state of other users,
+// without certificate hashes, comments, avatars or plugin data. User
+// state broadcasts that change nothing else aren't sent to them at all.
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The access token that asks for reduced user states.
+const reducedStateToken = "reducedstate"
+
+// The interval at which the presence batch is checked.
+const presenceCheckInterval = 50 * time.Millisecond
+
+// A presenceUpdate is a join or leave that waits to be broadcast.
+type presenceUpdate struct {
+	session uint32
+	join    *mumbleproto.UserState
+	remove  *mumbleproto.UserRemove
+}
+
+// A presenceBatch collects join and leave broadcasts.
+//
+// Must only be used from the server's handler goroutine.
+type presenceBatch struct {
+	started time.Time
+	updates []presenceUpdate
+}
+
+// wantsReducedState checks whether the client asked for reduced user
+// states.
+func (client *Client) wantsReducedState() bool {
+	for _, token := range client.tokens {
+		if strings.EqualFold(token, reducedStateToken) {
+			return true
+		}
+	}
+	return false
+}
+
+// reducedUserState returns the part of userstate sent to clients that
+// asked for reduced user states.
+func reducedUserState(userstate *mumbleproto.UserState) *mumbleproto.UserState {
+	return &mumbleproto.UserState{
+		Session:         userstate.Session,
+		Actor:           userstate.Actor,
+		Name:            userstate.Name,
+		UserId:          userstate.UserId,
+		ChannelId:       userstate.ChannelId,
+		Mute:            userstate.Mute,
+		Deaf:            userstate.Deaf,
+		Suppress:        userstate.Suppress,
+		SelfMute:        userstate.SelfMute,
+		SelfDeaf:        userstate.SelfDeaf,
+		PrioritySpeaker: userstate.PrioritySpeaker,
+		Recording:       userstate.Recording,
+	}
+}
+
+// userStateFor returns the version of userstate sent to client, or nil
+// if none of it is.
+func (client *Client) userStateFor(userstate *mumbleproto.UserState) *mumbleproto.UserState {
+	if !client.wantsReducedState() {
+		return userstate
+	}
+	reduced := reducedUserState(userstate)
+	unchanged := &mumbleproto.UserState{Session: reduced.Session, Actor: reduced.Actor}
+	if proto.Equal(reduced, unchanged) {
+		return nil
+	}
+	return reduced
+}
+
+// broadcastJoin tells the clients about a client that joined. The
+// client itself is told at once.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) broadcastJoin(joined *Client, userstate *mumbleproto.UserState) {
+	if err := joined.sendMessage(userstate); err != nil {
+		joined.Printf("Unable to send own user state: %v", err)
+	}
+	if server.cfg.IntValue("PresenceBatchInterval") <= 0 {
+		server.flushPresence()
+		server.sendPresence([]presenceUpdate{{session: joined.Session(), join: userstate}})
+		return
+	}
+	server.queuePresence(presenceUpdate{session: joined.Session(), join: userstate})
+}
+
+// broadcastLeave tells the clients about a client that left.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) broadcastLeave(left *Client) {
+	update := presenceUpdate{
+		session: left.Session(),
+		remove:  &mumbleproto.UserRemove{Session: proto.Uint32(left.Session())},
+	}
+	if server.cfg.IntValue("PresenceBatchInterval") <= 0 {
+		server.flushPresence()
+		server.sendPresence([]presenceUpdate{update})
+		return
+	}
+
+	// A client that leaves before its join was sent needn't be
+	// announced at all.
+	for i, pending := range server.presence.updates {
+		if pending.session == update.session && pending.join != nil {
+			server.presence.updates = append(server.presence.updates[:i], server.presence.updates[i+1:]...)
+			return
+		}
+	}
+	server.queuePresence(update)
+}
+
+// queuePresence adds an update to the batch.
+func (server *Server) queuePresence(update presenceUpdate) {
+	if len(server.presence.updates) == 0 {
+		server.presence.started = time.Now()
+	}
+	server.presence.updates = append(server.presence.updates, update)
+}
+
+// flushJoinOf sends the batch if it holds the join of client, which is
+// about to be heard from.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) flushJoinOf(client *Client) {
+	for _, pending := range server.presence.updates {
+		if pending.session == client.Session() && pending.join != nil {
+			server.flushPresence()
+			return
+		}
+	}
+}
+
+// checkPresence sends the batch once it is due.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) checkPresence() {
+	if len(server.presence.updates) == 0 {
+		return
+	}
+	interval := time.Duration(server.cfg.IntValue("PresenceBatchInterval")) * time.Millisecond
+	if time.Since(server.presence.started) >= interval {
+		server.flushPresence()
+	}
+}
+
+// flushPresence sends the batched updates, if any.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) flushPresence() {
+	if len(server.presence.updates) == 0 {
+		return
+	}
+	updates := server.presence.updates
+	server.presence.updates = nil
+	server.sendPresence(updates)
+}
+
+// sendPresence writes updates to every client in one go. Clients are
+// not told about their own join again.
+func (server *Server) sendPresence(updates []presenceUpdate) {
+	for _, client := range server.clients {
+		if client.state < StateClientAuthenticated {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		for _, update := range updates {
+			var err error
+			switch {
+			case update.join != nil && update.session != client.Session():
+				err = encodeMessage(buf, client.userStateFor(update.join))
+			case update.remove != nil:
+				err = encodeMessage(buf, update.remove)
+			}
+			if err != nil {
+				server.Panicf("Unable to encode presence update: %v", err)
+				return
+			}
+		}
+		if buf.Len() == 0 {
+			continue
+		}
+		client.sendLock.Lock()
+		n, err := client.conn.Write(buf.Bytes())
+		client.sendLock.Unlock()
+		client.traffic.addOut(n)
+		if err != nil {
+			// A client whose connection broke is disconnected by
+			// its own receiver.
+			client.Printf("Unable to send presence updates: %v", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements what the server tells clients about itself and
+// about other clients' systems.
+//
+// The Version message a client is sent when it connects carries the
+// protocol version, and unless SendVersion is off, the ServerRelease
+// string and, with SendOSInfo, the server's operating system. The
+// version details of UserStats carry the client's release and
+// operating system; with HideClientOS, the operating system is only
+// shown to the client itself and to admins.
+
+import (
+	"runtime"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/cryptstate"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// versionMessage returns the Version message sent to clients as they
+// connect.
+func (server *Server) versionMessage() *mumbleproto.Version {
+	version := &mumbleproto.Version{
+		Version:     proto.Uint32(0x10205),
+		CryptoModes: cryptstate.SupportedModes(),
+	}
+	if !server.cfg.BoolValue("SendVersion") {
+		return version
+	}
+	if release := server.cfg.StringValue("ServerRelease"); len(release) > 0 {
+		version.Release = proto.String(release)
+	}
+	if server.cfg.BoolValue("SendOSInfo") {
+		version.Os = proto.String(runtime.GOOS)
+		version.OsVersion = proto.String("(Unknown version)")
+	}
+	return version
+}
+
+// showClientOS checks whether client may see target's operating system
+// in its user statistics.
+func (server *Server) showClientOS(client *Client, target *Client) bool {
+	if !server.cfg.BoolValue("HideClientOS") || client == target {
+		return true
+	}
+	return server.isAdmin(client)
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements configuration hot reloading.
+//
+// The configuration file (see the --config argument) is read at startup,
+// and again whenever Grumble receives SIGHUP or a reload request through
+// the admin API. Changed values take effect immediately. Values that
+// clients need to know about are re-broadcast to them.
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/logtarget"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/serverconf"
+)
+
+// Config keys whose changes require a restart of the virtual server.
+var restartConfigKeys = []string{"Address", "Port", "WebPort", "NoWebServer", "ProxyAddress", "ProxyTrustedNetworks", "Plugins", "UDPSockets"}
+
+// Config keys used for public server registration.
+var registerConfigKeys = []string{"RegisterName", "RegisterHost", "RegisterPassword", "RegisterWebUrl", "RegisterLocation"}
+
+// The configuration file names looked for in the data directory
+// if no --config argument is given.
+var defaultConfigFiles = []string{"grumble.toml", "grumble.yaml", "grumble.yml", "grumble.ini", "murmur.ini"}
+
+// configFilePath returns the path of the configuration file.
+func configFilePath() string {
+	if len(Args.ConfigPath) > 0 {
+		return Args.ConfigPath
+	}
+	for _, name := range defaultConfigFiles {
+		fn := filepath.Join(Args.DataDir, name)
+		if _, err := os.Stat(fn); err == nil {
+			return fn
+		}
+	}
+	return filepath.Join(Args.DataDir, "grumble.ini")
+}
+
+// loadConfigFile reads the configuration file, and adds the values set
+// in the environment. A missing file is only an error if it was
+// explicitly given on the command line.
+func loadConfigFile() (*serverconf.ConfigFile, error) {
+	fn := configFilePath()
+	cf, err := serverconf.LoadFile(fn)
+	if os.IsNotExist(err) && len(Args.ConfigPath) == 0 {
+		cf, err = serverconf.NewConfigFile(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range cf.Warnings {
+		log.Printf("%v: %v", fn, warning)
+	}
+	if err := addEnvConfig(cf); err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// ReloadConfig re-reads the configuration file, re-opens the log file
+// and applies the new configuration to all virtual servers.
+func ReloadConfig() error {
+	cf, err := loadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	err = logtarget.Default.Rotate()
+	if err != nil {
+		log.Printf("Unable to re-open log file: %v", err)
+	}
+
+	log.Printf("Reloading configuration from %v", configFilePath())
+	for _, server := range servers {
+		server.applyConfigFile(cf)
+	}
+	return nil
+}
+
+// applyConfigFile applies the values in cf to the server. If the server
+// is running, its clients are informed of any relevant changes.
+func (server *Server) applyConfigFile(cf *serverconf.ConfigFile) {
+	values := cf.ValuesForServer(server.Id)
+	if !server.isRunning() {
+		server.cfg.SetFileValues(values)
+		return
+	}
+
+	err := server.runSync(func() {
+		old := configSnapshot(server.cfg)
+		server.cfg.SetFileValues(values)
+		// The word filter's rules file, the message and channel
+		// templates, the escalation rules, the client feature
+		// overrides and denylist, and the scripts are re-read along
+		// with the configuration file. If they cannot be read, the
+		// old ones stay.
+		filter, err := server.loadWordFilter()
+		if err != nil {
+			server.Printf("Unable to reload word filter: %v", err)
+		} else {
+			server.wordFilter = filter
+		}
+		templates, err := server.loadMessageTemplates()
+		if err != nil {
+			server.Printf("Unable to reload message templates: %v", err)
+		} else {
+			server.msgTemplates = templates
+		}
+		chanTemplates, err := server.loadChannelTemplates()
+		if err != nil {
+			server.Printf("Unable to reload channel templates: %v", err)
+		} else {
+			server.setChannelTemplates(chanTemplates)
+		}
+		policy, err := server.loadEscalationPolicy()
+		if err != nil {
+			server.Printf("Unable to reload escalation rules: %v", err)
+		} else {
+			server.setEscalationPolicy(policy)
+		}
+		overrides, err := server.loadFeatureOverrides()
+		if err != nil {
+			server.Printf("Unable to reload client feature overrides: %v", err)
+		} else {
+			server.setFeatureOverrides(overrides)
+		}
+		denylist, err := server.loadClientDenylist()
+		if err != nil {
+			server.Printf("Unable to reload client denylist: %v", err)
+		} else {
+			server.setClientDenylist(denylist)
+		}
+		scripts, err := server.loadScripts()
+		if err != nil {
+			server.Printf("Unable to reload scripts: %v", err)
+		} else {
+			server.setScripts(scripts)
+		}
+		server.configChanged(old)
+	})
+	if err != nil {
+		// The server is shutting down; the new values will be used
+		// on its next launch.
+		server.cfg.SetFileValues(values)
+	}
+}
+
+// configSnapshot returns the effective values of the keys that
+// are acted upon by configChanged.
+func configSnapshot(cfg *serverconf.Config) map[string]string {
+	keys := []string{"MaxBandwidth", "WelcomeText", "AllowHTML", "MaxTextMessageLength", "MaxImageMessageLength", "MaxUsers", "Bonjour"}
+	keys = append(keys, restartConfigKeys...)
+	keys = append(keys, tenantConfigKeys...)
+	keys = append(keys, registerConfigKeys...)
+	keys = append(keys, discordConfigKeys...)
+	keys = append(keys, federationConfigKeys...)
+	keys = append(keys, mqttConfigKeys...)
+	keys = append(keys, revocationConfigKeys...)
+	keys = append(keys, dirSyncConfigKeys...)
+	keys = append(keys, maintenanceConfigKeys...)
+
+	snapshot := make(map[string]string)
+	for _, key := range keys {
+		snapshot[key] = cfg.StringValue(key)
+	}
+	return snapshot
+}
+
+// configChanged compares the server's configuration to the old
+// effective values and acts on the differences.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) configChanged(old map[string]string) {
+	changed := func(key string) bool {
+		return old[key] != server.cfg.StringValue(key)
+	}
+
+	msg := &mumbleproto.ServerConfig{}
+	send := false
+	if changed("MaxBandwidth") {
+		msg.MaxBandwidth = proto.Uint32(server.cfg.Uint32Value("MaxBandwidth"))
+		send = true
+	}
+	if changed("WelcomeText") {
+		msg.WelcomeText = proto.String(server.cfg.StringValue("WelcomeText"))
+		send = true
+	}
+	if changed("AllowHTML") {
+		msg.AllowHtml = proto.Bool(server.cfg.BoolValue("AllowHTML"))
+		send = true
+	}
+	if changed("MaxTextMessageLength") {
+		msg.MessageLength = proto.Uint32(server.cfg.Uint32Value("MaxTextMessageLength"))
+		send = true
+	}
+	if changed("MaxImageMessageLength") {
+		msg.ImageMessageLength = proto.Uint32(server.cfg.Uint32Value("MaxImageMessageLength"))
+		send = true
+	}
+	if changed("MaxUsers") {
+		msg.MaxUsers = proto.Uint32(server.cfg.Uint32Value("MaxUsers"))
+		send = true
+	}
+	if send {
+		server.Printf("Configuration changed, sending ServerConfig to clients")
+		err := server.broadcastProtoMessageWithPredicate(msg, func(client *Client) bool {
+			return client.state == StateClientReady
+		})
+		if err != nil {
+			server.Printf("Unable to broadcast ServerConfig: %v", err)
+		}
+	}
+
+	for _, key := range restartConfigKeys {
+		if changed(key) {
+			server.Printf("Configuration key %v changed; restart the server to apply it", key)
+		}
+	}
+
+	for _, key := range tenantConfigKeys {
+		if changed(key) {
+			server.Printf("Configuration key %v changed; restart Grumble to apply it", key)
+		}
+	}
+
+	for _, key := range registerConfigKeys {
+		if changed(key) {
+			go server.RegisterPublicServer()
+			break
+		}
+	}
+
+	if changed("Bonjour") || changed("RegisterName") {
+		server.stopMDNS()
+		server.startMDNS()
+	}
+
+	for _, key := range discordConfigKeys {
+		if changed(key) {
+			server.stopDiscordBridge()
+			server.startDiscordBridge()
+			break
+		}
+	}
+
+	for _, key := range federationConfigKeys {
+		if changed(key) {
+			server.stopFederation()
+			server.startFederation()
+			break
+		}
+	}
+
+	for _, key := range mqttConfigKeys {
+		if changed(key) {
+			server.stopMQTT()
+			server.startMQTT()
+			break
+		}
+	}
+
+	for _, key := range revocationConfigKeys {
+		if changed(key) {
+			server.stopRevocationSweep()
+			server.startRevocationSweep()
+			break
+		}
+	}
+
+	for _, key := range dirSyncConfigKeys {
+		if changed(key) {
+			server.stopDirectorySync()
+			server.startDirectorySync()
+			break
+		}
+	}
+
+	for _, key := range maintenanceConfigKeys {
+		if changed(key) {
+			server.applyMaintenance()
+			break
+		}
+	}
+}
+
+func init() {
+	apiMux.HandleFunc("/reload", handleAPIReload)
+}
+
+// handleAPIReload implements /reload. A POST request reloads
+// the configuration file.
+func handleAPIReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	err := ReloadConfig()
+	if err != nil {
+		apiError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+}
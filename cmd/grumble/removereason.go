@@ -0,0 +1,101 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the reasons sent to clients when a client is
+// kicked or banned.
+//
+// A reason is always sent: when none is given, a default is used. If
+// MessageTemplates names a templates file (see pkg/msgtemplate), each
+// client is sent the reason through the kick or ban template of its
+// locale. The Mumble protocol has no field for the client's locale, so
+// clients report it with an access token of the form locale:<tag>.
+// Other clients use DefaultLocale.
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/msgtemplate"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The reason given for kicks and bans without one.
+const defaultRemoveReason = "No reason given"
+
+// The prefix of access tokens that report a client's locale.
+const localeTokenPrefix = "locale:"
+
+// loadMessageTemplates reads the templates file named by the
+// MessageTemplates key. Relative paths are relative to the data
+// directory. If no file is configured, it returns nil templates.
+func (server *Server) loadMessageTemplates() (*msgtemplate.Templates, error) {
+	fn := server.cfg.StringValue("MessageTemplates")
+	if len(fn) == 0 {
+		return nil, nil
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	return msgtemplate.Load(fn)
+}
+
+// Locale gets the locale reported by the client, or the server's
+// default locale if it didn't report one.
+func (client *Client) Locale() string {
+	for _, token := range client.tokens {
+		if len(token) > len(localeTokenPrefix) && strings.EqualFold(token[:len(localeTokenPrefix)], localeTokenPrefix) {
+			return token[len(localeTokenPrefix):]
+		}
+	}
+	return client.server.cfg.StringValue("DefaultLocale")
+}
+
+// broadcastUserRemove tells the clients that target was kicked or
+// banned by actor, which is nil for the server itself. Each client is
+// sent the reason in its own locale.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) broadcastUserRemove(target, actor *Client, ban bool, reason string) error {
+	if len(reason) == 0 {
+		reason = defaultRemoveReason
+	}
+	kind := "kick"
+	if ban {
+		kind = "ban"
+	}
+	vars := map[string]string{
+		"user":   target.ShownName(),
+		"actor":  "the server",
+		"reason": reason,
+	}
+	if actor != nil {
+		vars["actor"] = actor.ShownName()
+	}
+
+	server.flushPresence()
+	for _, client := range server.clients {
+		if client.state < StateClientAuthenticated {
+			continue
+		}
+		text, ok := server.msgTemplates.Render(kind, client.Locale(), vars)
+		if !ok {
+			text = reason
+		}
+		userremove := &mumbleproto.UserRemove{
+			Session: proto.Uint32(target.Session()),
+			Reason:  proto.String(text),
+			Ban:     proto.Bool(ban),
+		}
+		if actor != nil {
+			userremove.Actor = proto.Uint32(actor.Session())
+		}
+		if err := client.sendMessage(userremove); err != nil {
+			return err
+		}
+	}
+	return nil
+}
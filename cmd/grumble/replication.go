@@ -0,0 +1,420 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the replication of the servers' state to a
+// standby instance, for high-availability deployments (see
+// pkg/replication).
+//
+// A primary started with --replication-addr streams the state of all
+// its virtual servers to the standbys that connect to it: a snapshot of
+// each server, followed by every change written to the server's freeze
+// log. A standby started with --replicate-from writes the stream to its
+// own server directories instead of starting its servers. Once it is
+// promoted, by SIGUSR1, or by --failover-after when the primary has been
+// gone for that long after a complete sync, it loads the replicated
+// state and starts as usual, with the primary's channel tree,
+// registrations and bans.
+//
+// Both sides authenticate each other with the certificates in their
+// data directories, whose fingerprints are passed to the other side
+// with --replication-peer. The blob stores and the configuration file
+// aren't replicated.
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/federation"
+	"mumble.info/grumble/pkg/replication"
+	"mumble.info/grumble/pkg/serverconf"
+)
+
+const (
+	// The number of frames that may wait to be sent to a standby.
+	replicationQueueSize = 4096
+	// How often the primary pings a standby that is sent nothing else.
+	replicationPingInterval = 5 * time.Second
+	// The time a standby has to complete the handshake, or to take a
+	// frame, and the time the primary may be silent before a standby
+	// gives up on the connection.
+	replicationTimeout = 15 * time.Second
+	// How long a standby waits before connecting to the primary again.
+	replicationRetryInterval = 5 * time.Second
+)
+
+// A replica is the connection of a standby to the primary.
+type replica struct {
+	net.Conn
+	outgoing  chan replicationFrame
+	closed    chan bool
+	closeOnce sync.Once
+	// The servers whose snapshots were sent to the standby, and whose
+	// changes are therefore sent too.
+	servers map[int64]bool
+}
+
+type replicationFrame struct {
+	typ     byte
+	id      int64
+	payload []byte
+}
+
+// The connected standbys, guarded by replicasMutex.
+var (
+	replicas      = map[*replica]bool{}
+	replicasMutex sync.Mutex
+)
+
+// replicationTLSConfig returns the TLS configuration of replication
+// connections, which use the global certificate.
+func replicationTLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(Args.DataDir, "cert.pem"), filepath.Join(Args.DataDir, "key.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(Args.ReplicationPeer) == 0 {
+		return nil, errors.New("no --replication-peer given")
+	}
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		log.Printf("Replication certificate fingerprint %v", federation.Fingerprint(leaf))
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// The peer is checked against --replication-peer instead.
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS12,
+	}, nil
+}
+
+// checkReplicationPeer checks that the peer of conn presented the
+// certificate given by --replication-peer.
+func checkReplicationPeer(conn *tls.Conn) error {
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	if fp := peerFingerprint(conn); fp != federation.NormalizeFingerprint(Args.ReplicationPeer) {
+		return fmt.Errorf("unexpected certificate fingerprint %v", fp)
+	}
+	return nil
+}
+
+// StartReplication serves standbys on addr.
+func StartReplication(addr string) error {
+	config, err := replicationTLSConfig()
+	if err != nil {
+		return err
+	}
+	tcpaddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return err
+	}
+	l, err := listenTCP(tcpaddr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				// The listener is closed once handed over to
+				// a new process.
+				if ne, ok := err.(net.Error); !ok || !ne.Temporary() {
+					log.Printf("Replication stopped: %v", err)
+					return
+				}
+				log.Printf("Replication: unable to accept connection: %v", err)
+				time.Sleep(time.Second)
+				continue
+			}
+			go acceptReplica(tls.Server(conn, config))
+		}
+	}()
+	log.Printf("Replication listening on %v", addr)
+	return nil
+}
+
+// acceptReplica sends the state of all servers to a standby, and keeps
+// sending their changes until the connection breaks.
+func acceptReplica(conn *tls.Conn) {
+	conn.SetDeadline(time.Now().Add(replicationTimeout))
+	if err := checkReplicationPeer(conn); err != nil {
+		log.Printf("Replication: rejected standby %v: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	r := &replica{
+		Conn:     conn,
+		outgoing: make(chan replicationFrame, replicationQueueSize),
+		closed:   make(chan bool),
+		servers:  map[int64]bool{},
+	}
+	defer r.close()
+
+	ids := []int64{}
+	for id := range servers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	hello, err := json.Marshal(replication.Hello{Version: replication.Version, Servers: ids})
+	if err != nil {
+		return
+	}
+	r.send(replicationFrame{typ: replication.FrameHello, payload: hello})
+
+	replicasMutex.Lock()
+	replicas[r] = true
+	replicasMutex.Unlock()
+	defer func() {
+		replicasMutex.Lock()
+		delete(replicas, r)
+		replicasMutex.Unlock()
+	}()
+	go r.writeLoop()
+
+	for _, id := range ids {
+		if err := servers[id].attachReplica(r); err != nil {
+			log.Printf("Replication: unable to send server %v to standby %v: %v", id, conn.RemoteAddr(), err)
+			return
+		}
+	}
+	r.send(replicationFrame{typ: replication.FrameSynced})
+	log.Printf("Replication: standby %v synced", conn.RemoteAddr())
+
+	// The standby sends nothing; reading only notices that it's gone.
+	ioutil.ReadAll(conn)
+	log.Printf("Replication: standby %v disconnected", conn.RemoteAddr())
+}
+
+// attachReplica sends a snapshot of the server's state to r, after
+// which r is sent the server's changes too.
+func (server *Server) attachReplica(r *replica) error {
+	attach := func() error {
+		fs, err := server.Freeze()
+		if err != nil {
+			return err
+		}
+		snapshot, err := proto.Marshal(fs)
+		if err != nil {
+			return err
+		}
+		replicasMutex.Lock()
+		r.servers[server.Id] = true
+		replicasMutex.Unlock()
+		r.send(replicationFrame{typ: replication.FrameSnapshot, id: server.Id, payload: snapshot})
+		return nil
+	}
+	if !server.isRunning() {
+		return attach()
+	}
+	var err error
+	if serr := server.runSync(func() { err = attach() }); serr != nil {
+		return serr
+	}
+	return err
+}
+
+// replicate sends a frame for the server with the given id to the
+// standbys that were sent its snapshot.
+func replicate(typ byte, id int64, payload []byte) {
+	replicasMutex.Lock()
+	defer replicasMutex.Unlock()
+	for r := range replicas {
+		if r.servers[id] {
+			r.send(replicationFrame{typ: typ, id: id, payload: payload})
+		}
+	}
+}
+
+// replicateSnapshot sends the snapshot of the server's state just
+// written to main.fz to the standbys.
+func (server *Server) replicateSnapshot() {
+	replicasMutex.Lock()
+	n := len(replicas)
+	replicasMutex.Unlock()
+	if n == 0 {
+		return
+	}
+	snapshot, err := ioutil.ReadFile(filepath.Join(server.dir, "main.fz"))
+	if err != nil {
+		server.Printf("Replication: unable to read snapshot: %v", err)
+		return
+	}
+	replicate(replication.FrameSnapshot, server.Id, snapshot)
+}
+
+// A replicatedLog is a freeze log file whose records are also sent to
+// the standbys.
+type replicatedLog struct {
+	*os.File
+	id int64
+}
+
+func (rl replicatedLog) Write(p []byte) (int, error) {
+	n, err := rl.File.Write(p)
+	if err == nil {
+		replicate(replication.FrameLog, rl.id, append([]byte(nil), p...))
+	}
+	return n, err
+}
+
+// writeLoop writes the queued frames to the standby, and pings it when
+// there are none, until the connection is closed.
+func (r *replica) writeLoop() {
+	ping := time.NewTicker(replicationPingInterval)
+	defer ping.Stop()
+	for {
+		f := replicationFrame{typ: replication.FramePing}
+		select {
+		case <-r.closed:
+			return
+		case f = <-r.outgoing:
+		case <-ping.C:
+		}
+		r.SetWriteDeadline(time.Now().Add(replicationTimeout))
+		if err := replication.WriteFrame(r, f.typ, f.id, f.payload); err != nil {
+			r.close()
+			return
+		}
+	}
+}
+
+// close closes the connection. It may be called more than once.
+func (r *replica) close() {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		r.Conn.Close()
+	})
+}
+
+// send queues a frame for the standby. If the queue is full, the
+// standby would miss changes, so the connection is closed instead. The
+// standby connects again, and is sent fresh snapshots.
+func (r *replica) send(f replicationFrame) {
+	select {
+	case r.outgoing <- f:
+	default:
+		r.close()
+	}
+}
+
+// runStandby keeps a copy of the primary's state until this instance
+// is promoted.
+func runStandby(cf *serverconf.ConfigFile) {
+	config, err := replicationTLSConfig()
+	if err != nil {
+		log.Fatalf("Unable to start replication: %v", err)
+	}
+	store := replication.NewStore(func(id int64) string {
+		return tenantDir(cf, id, "ServerDir", filepath.Join(Args.DataDir, "servers", strconv.FormatInt(id, 10)))
+	})
+	defer store.Close()
+
+	promote := promoteSignal()
+	defer stopPromoteSignal(promote)
+
+	log.Printf("Standby of %v", Args.ReplicateFrom)
+	synced := false
+	lastContact := time.Now()
+	for {
+		conn, err := dialPrimary(config)
+		if err != nil {
+			log.Printf("Replication: unable to connect to primary %v: %v", Args.ReplicateFrom, err)
+		} else {
+			log.Printf("Replication: connected to primary %v", Args.ReplicateFrom)
+			done := make(chan error, 1)
+			go func() { done <- followPrimary(conn, store, &synced) }()
+			select {
+			case err = <-done:
+				log.Printf("Replication: lost primary %v: %v", Args.ReplicateFrom, err)
+			case <-promote:
+				conn.Close()
+				<-done
+				log.Printf("Promoted to primary")
+				return
+			}
+			lastContact = time.Now()
+		}
+
+		if Args.FailoverAfter > 0 && time.Since(lastContact) >= Args.FailoverAfter {
+			if synced {
+				log.Printf("Primary unreachable for %v, taking over", Args.FailoverAfter)
+				return
+			}
+			log.Printf("Primary unreachable for %v, but not taking over before a complete sync", Args.FailoverAfter)
+		}
+		select {
+		case <-promote:
+			log.Printf("Promoted to primary")
+			return
+		case <-time.After(replicationRetryInterval):
+		}
+	}
+}
+
+// dialPrimary connects to the primary.
+func dialPrimary(config *tls.Config) (*tls.Conn, error) {
+	dialer := &net.Dialer{Timeout: replicationTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", Args.ReplicateFrom, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReplicationPeer(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// followPrimary writes the state streamed by the primary to store
+// until the connection breaks. synced is set once the snapshots of all
+// the primary's servers have been written.
+func followPrimary(conn net.Conn, store *replication.Store, synced *bool) error {
+	defer conn.Close()
+	for {
+		conn.SetReadDeadline(time.Now().Add(replicationTimeout))
+		typ, id, payload, err := replication.ReadFrame(conn)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case replication.FrameHello:
+			hello := replication.Hello{}
+			if err := json.Unmarshal(payload, &hello); err != nil {
+				return err
+			}
+			if hello.Version != replication.Version {
+				return fmt.Errorf("unsupported protocol version %v", hello.Version)
+			}
+			log.Printf("Replication: primary has servers %v", hello.Servers)
+		case replication.FrameSnapshot:
+			err = store.Snapshot(id, payload)
+		case replication.FrameLog:
+			err = store.Append(id, payload)
+		case replication.FrameSynced:
+			*synced = true
+			log.Printf("Replication: synced")
+		}
+		if err != nil {
+			return fmt.Errorf("server %v: %v", id, err)
+		}
+	}
+}
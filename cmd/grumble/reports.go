@@ -0,0 +1,354 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements user reports.
+//
+// Users report other users with the /report chat command, or with the
+// "Report user" action in a user's context menu. Each report is kept
+// as a ticket holding the reporter, the reported user, the channel the
+// reported user was in, the reason, and the last text messages sent to
+// that channel. The moderators that are online, the users allowed to
+// kick in the root channel, are told about it. Admins list, close and
+// delete tickets through the admin API.
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/htmlfilter"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The number of text messages kept for each channel, and put into
+// reports.
+const chatHistoryLength = 20
+
+// The context action that reports a user.
+const reportAction = "report"
+
+// A chatLine is a text message sent to a channel, as plain text.
+type chatLine struct {
+	Time   int64
+	Sender string
+	Text   string
+}
+
+// A Report is a ticket filed by a user about another user.
+type Report struct {
+	Id      uint32
+	Created int64
+	// The user ids of the reporter and the reported user, or -1 for
+	// guests.
+	ReporterId   int
+	ReporterName string
+	TargetId     int
+	TargetName   string
+	TargetHash   string
+	// The channel the reported user was in
+	ChannelId   int
+	ChannelName string
+	Reason      string
+	Excerpt     []chatLine
+	// When and by whom the report was closed; Closed is zero while
+	// the report is open.
+	Closed   int64
+	ClosedBy string
+	Note     string
+}
+
+// recordText adds a text message sent to the channel to its history.
+func (channel *Channel) recordText(sender, text string) {
+	plain, err := htmlfilter.Filter(text, &htmlfilter.Options{StripHTML: true})
+	if err != nil || len(strings.TrimSpace(plain)) == 0 {
+		return
+	}
+	channel.recentText = append(channel.recentText, chatLine{
+		Time:   time.Now().Unix(),
+		Sender: sender,
+		Text:   html.UnescapeString(plain),
+	})
+	if n := len(channel.recentText); n > chatHistoryLength {
+		channel.recentText = append([]chatLine(nil), channel.recentText[n-chatHistoryLength:]...)
+	}
+}
+
+// openReport returns the open report by reporter about target, or nil
+// if there is none.
+func (server *Server) openReport(reporter, target *Client) *Report {
+	for _, report := range server.Reports {
+		if report.Closed == 0 && report.ReporterName == reporter.ShownName() && report.TargetName == target.ShownName() {
+			return report
+		}
+	}
+	return nil
+}
+
+// fileReport files a report by reporter about target, and tells the
+// moderators.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) fileReport(reporter, target *Client, reason string) {
+	if len(reason) == 0 {
+		reason = "No reason given"
+	}
+
+	report := &Report{
+		Id:           server.nextReportId,
+		Created:      time.Now().Unix(),
+		ReporterId:   reporter.UserId(),
+		ReporterName: reporter.ShownName(),
+		TargetId:     target.UserId(),
+		TargetName:   target.ShownName(),
+		TargetHash:   target.CertHash(),
+		Reason:       reason,
+	}
+	server.nextReportId++
+	if channel := target.Channel; channel != nil {
+		report.ChannelId = channel.Id
+		report.ChannelName = channel.Name
+		report.Excerpt = append([]chatLine(nil), channel.recentText...)
+	}
+	server.Reports[report.Id] = report
+	server.UpdateFrozenReport(report)
+
+	server.audit(reporter, auditlog.Entry{
+		Action:  "user.report",
+		Target:  report.TargetName,
+		Reason:  reason,
+		Details: fmt.Sprintf("report %v session %v channel %v", report.Id, target.Session(), report.ChannelId),
+	})
+	server.notifyModerators(fmt.Sprintf("%v reported %v (in %v): %v. This is report %v.",
+		html.EscapeString(report.ReporterName), html.EscapeString(report.TargetName),
+		html.EscapeString(report.ChannelName), html.EscapeString(reason), report.Id))
+}
+
+// sendReportAction offers client the context action that reports a
+// user.
+func (server *Server) sendReportAction(client *Client) {
+	client.sendMessage(&mumbleproto.ContextActionModify{
+		Action:    proto.String(reportAction),
+		Text:      proto.String("Report user"),
+		Context:   proto.Uint32(uint32(mumbleproto.ContextActionModify_User)),
+		Operation: mumbleproto.ContextActionModify_Add.Enum(),
+	})
+}
+
+// handleReportAction handles the context action that reports a user.
+func (server *Server) handleReportAction(client *Client, action *mumbleproto.ContextAction) {
+	target, ok := server.clients[action.GetSession()]
+	if action.Session == nil || !ok || target == client || target.state != StateClientReady {
+		return
+	}
+	if server.openReport(client, target) != nil {
+		server.sendServerText(client, fmt.Sprintf("You already reported %v", html.EscapeString(target.ShownName())))
+		return
+	}
+	server.fileReport(client, target, "")
+	server.sendServerText(client, "Thank you, the moderators have been told. Use /report to give a reason.")
+}
+
+func init() {
+	registerChatCommand("report", &chatCommand{
+		usage: []string{"report <user> <reason>"},
+		help:  "Report a user to the moderators",
+		run:   (*Server).reportCommand,
+	})
+	registerAPIEndpoint("reports", handleAPIReports)
+}
+
+// reportCommand implements the /report chat command, which files a
+// report about a connected user.
+func (server *Server) reportCommand(client *Client, args []string, text string) {
+	reason := chatCommandRest(text, 2)
+	if len(args) < 3 || len(reason) == 0 {
+		server.sendChatCommandUsage(client, args[0])
+		return
+	}
+	var target *Client
+	for _, other := range server.clients {
+		if other.state == StateClientReady && strings.EqualFold(other.ShownName(), args[1]) {
+			target = other
+			break
+		}
+	}
+	if target == nil {
+		server.sendServerText(client, fmt.Sprintf("No user named %v is connected", html.EscapeString(args[1])))
+		return
+	}
+
+	// Reporting a user again gives the open report a new reason.
+	if report := server.openReport(client, target); report != nil {
+		report.Reason = reason
+		server.UpdateFrozenReport(report)
+		server.sendServerText(client, "Your report was updated")
+		return
+	}
+	server.fileReport(client, target, reason)
+	server.sendServerText(client, "Thank you, the moderators have been told")
+}
+
+// apiChatLine is the JSON representation of a chatLine.
+type apiChatLine struct {
+	Time   string `json:"time"`
+	Sender string `json:"sender"`
+	Text   string `json:"text"`
+}
+
+// apiReport is the JSON representation of a Report.
+type apiReport struct {
+	Id          uint32        `json:"id"`
+	Created     string        `json:"created"`
+	Reporter    string        `json:"reporter"`
+	ReporterId  int           `json:"reporter_id"`
+	Target      string        `json:"target"`
+	TargetId    int           `json:"target_id"`
+	TargetHash  string        `json:"target_hash,omitempty"`
+	Channel     int           `json:"channel"`
+	ChannelName string        `json:"channel_name"`
+	Reason      string        `json:"reason"`
+	Open        bool          `json:"open"`
+	Closed      string        `json:"closed,omitempty"`
+	ClosedBy    string        `json:"closed_by,omitempty"`
+	Note        string        `json:"note,omitempty"`
+	Excerpt     []apiChatLine `json:"excerpt,omitempty"`
+}
+
+// apiReport returns the JSON representation of report, with the chat
+// excerpt if excerpt is set.
+func (report *Report) apiReport(excerpt bool) apiReport {
+	ar := apiReport{
+		Id:          report.Id,
+		Created:     time.Unix(report.Created, 0).UTC().Format(time.RFC3339),
+		Reporter:    report.ReporterName,
+		ReporterId:  report.ReporterId,
+		Target:      report.TargetName,
+		TargetId:    report.TargetId,
+		TargetHash:  report.TargetHash,
+		Channel:     report.ChannelId,
+		ChannelName: report.ChannelName,
+		Reason:      report.Reason,
+		Open:        report.Closed == 0,
+		ClosedBy:    report.ClosedBy,
+		Note:        report.Note,
+	}
+	if report.Closed > 0 {
+		ar.Closed = time.Unix(report.Closed, 0).UTC().Format(time.RFC3339)
+	}
+	if excerpt {
+		ar.Excerpt = []apiChatLine{}
+		for _, line := range report.Excerpt {
+			ar.Excerpt = append(ar.Excerpt, apiChatLine{
+				Time:   time.Unix(line.Time, 0).UTC().Format(time.RFC3339),
+				Sender: line.Sender,
+				Text:   line.Text,
+			})
+		}
+	}
+	return ar
+}
+
+// handleAPIReports implements /servers/<id>/reports.
+//
+//	GET     lists the reports, newest first; ?open=true lists only
+//	        the open ones
+//	GET     .../reports/<id> shows a report with its chat excerpt
+//	POST    .../reports/<id>/close closes a report: {"note": "warned"}
+//	DELETE  .../reports/<id> deletes a report
+func handleAPIReports(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var id uint64
+	if len(args) > 0 {
+		var err error
+		id, err = strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			apiError(w, http.StatusBadRequest, "invalid report")
+			return
+		}
+	}
+	var req struct {
+		Note string `json:"note"`
+	}
+	switch {
+	case r.Method == http.MethodGet && len(args) <= 1:
+	case r.Method == http.MethodPost && len(args) == 2 && args[1] == "close":
+		if r.ContentLength != 0 && !readJSON(w, r, &req) {
+			return
+		}
+	case r.Method == http.MethodDelete && len(args) == 1:
+	case len(args) > 2 || len(args) == 2 && args[1] != "close":
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	openOnly := r.URL.Query().Get("open") == "true"
+
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		if len(args) == 0 {
+			reports := []apiReport{}
+			for _, report := range server.Reports {
+				if !openOnly || report.Closed == 0 {
+					reports = append(reports, report.apiReport(false))
+				}
+			}
+			sort.Slice(reports, func(i, j int) bool { return reports[i].Id > reports[j].Id })
+			reply = reports
+			return
+		}
+
+		report, ok := server.Reports[uint32(id)]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such report"}
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			if report.Closed > 0 {
+				status, reply = http.StatusConflict, map[string]string{"error": "report already closed"}
+				return
+			}
+			report.Closed = time.Now().Unix()
+			report.ClosedBy = "api"
+			report.Note = req.Note
+			server.UpdateFrozenReport(report)
+			server.auditAPI(auditlog.Entry{
+				Action:  "report.close",
+				Target:  report.TargetName,
+				Reason:  req.Note,
+				Details: fmt.Sprintf("report %v", report.Id),
+			})
+		case http.MethodDelete:
+			delete(server.Reports, report.Id)
+			server.DeleteFrozenReport(report.Id)
+			server.auditAPI(auditlog.Entry{
+				Action:  "report.delete",
+				Target:  report.TargetName,
+				Details: fmt.Sprintf("report %v", report.Id),
+			})
+			status = http.StatusNoContent
+			return
+		}
+		reply = report.apiReport(true)
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"syscall"
+)
+
+const udpLoadBalancing = false
+
+// reusePort is a no-op on systems that don't spread datagrams over
+// sockets sharing a port.
+func reusePort(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements revocation checks of client certificates.
+//
+// When CertCRLFile or CertOCSPResponder is set, client certificates
+// are checked when the client authenticates, and again every
+// CertRecheckInterval seconds while it is connected. Clients with
+// revoked certificates are rejected or kicked. Checks that fail, for
+// example because the OCSP responder can't be reached, are logged and
+// let the client in.
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// The time allowed for an OCSP request.
+	ocspTimeout = 10 * time.Second
+	// The largest OCSP response accepted.
+	maxOCSPResponseSize = 1 << 20
+)
+
+// Config keys of the revocation checks.
+var revocationConfigKeys = []string{"CertCRLFile", "CertOCSPResponder", "CertRecheckInterval"}
+
+var ocspClient = &http.Client{Timeout: ocspTimeout}
+
+// A crlCache holds the parsed CRL file, and reloads it when the file
+// changes.
+type crlCache struct {
+	mutex   sync.Mutex
+	fn      string
+	modTime time.Time
+	crl     *pkix.CertificateList
+}
+
+// load returns the CRL in fn.
+func (c *crlCache) load(fn string) (*pkix.CertificateList, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	fi, err := os.Stat(fn)
+	if err != nil {
+		return nil, err
+	}
+	if c.crl != nil && c.fn == fn && fi.ModTime().Equal(c.modTime) {
+		return c.crl, nil
+	}
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	crl, err := x509.ParseCRL(buf)
+	if err != nil {
+		return nil, err
+	}
+	c.fn, c.modTime, c.crl = fn, fi.ModTime(), crl
+	return crl, nil
+}
+
+// A revocationSweeper periodically checks the certificates of the
+// connected clients.
+type revocationSweeper struct {
+	server *Server
+	done   chan bool
+}
+
+// revocationEnabled reports whether client certificates are checked
+// for revocation.
+func (server *Server) revocationEnabled() bool {
+	return len(server.cfg.StringValue("CertCRLFile")) > 0 || len(server.cfg.StringValue("CertOCSPResponder")) > 0
+}
+
+// issuerOf returns the certificate in certs that issued the first one,
+// or nil if the client didn't send it.
+func issuerOf(certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs[1:] {
+		if certs[0].CheckSignatureFrom(cert) == nil {
+			return cert
+		}
+	}
+	return nil
+}
+
+// certRevoked checks whether the first of certs, a client's
+// certificate chain, has been revoked according to the configured CRL
+// and OCSP responder.
+//
+// It makes network requests, so should not be called from the
+// server's handler goroutine.
+func (server *Server) certRevoked(certs []*x509.Certificate) (bool, error) {
+	if len(certs) == 0 {
+		return false, nil
+	}
+	leaf := certs[0]
+	issuer := issuerOf(certs)
+
+	if fn := server.cfg.StringValue("CertCRLFile"); len(fn) > 0 {
+		if !filepath.IsAbs(fn) {
+			fn = filepath.Join(Args.DataDir, fn)
+		}
+		revoked, err := server.crlRevoked(fn, leaf, issuer)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if responder := server.cfg.StringValue("CertOCSPResponder"); len(responder) > 0 {
+		if responder == "auto" {
+			if len(leaf.OCSPServer) == 0 {
+				return false, nil
+			}
+			responder = leaf.OCSPServer[0]
+		}
+		return ocspRevoked(responder, leaf, issuer)
+	}
+	return false, nil
+}
+
+// crlRevoked checks leaf against the CRL in fn. CRLs of other issuers
+// are ignored. If the issuer is known, the CRL's signature is checked.
+func (server *Server) crlRevoked(fn string, leaf, issuer *x509.Certificate) (bool, error) {
+	crl, err := server.crl.load(fn)
+	if err != nil {
+		return false, err
+	}
+	if crl.TBSCertList.Issuer.String() != leaf.Issuer.ToRDNSequence().String() {
+		return false, nil
+	}
+	if issuer != nil {
+		if err := issuer.CheckCRLSignature(crl); err != nil {
+			return false, fmt.Errorf("CRL signature: %v", err)
+		}
+	}
+	for _, rc := range crl.TBSCertList.RevokedCertificates {
+		if rc.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ocspRevoked asks the OCSP responder whether leaf has been revoked.
+func ocspRevoked(responder string, leaf, issuer *x509.Certificate) (bool, error) {
+	if issuer == nil {
+		return false, errors.New("OCSP: the client did not send its certificate's issuer")
+	}
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := ocspClient.Post(responder, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("OCSP: responder returned %v", resp.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxOCSPResponseSize))
+	if err != nil {
+		return false, err
+	}
+	status, err := ocsp.ParseResponseForCert(body, leaf, issuer)
+	if err != nil {
+		return false, err
+	}
+	return status.Status == ocsp.Revoked, nil
+}
+
+// startRevocationSweep starts the periodic revocation checks, if they
+// are enabled.
+//
+// Must be called from the server's handler goroutine, or before it
+// is started.
+func (server *Server) startRevocationSweep() {
+	interval := time.Duration(server.cfg.IntValue("CertRecheckInterval")) * time.Second
+	if !server.revocationEnabled() || interval <= 0 {
+		return
+	}
+	s := &revocationSweeper{server: server, done: make(chan bool)}
+	server.revocation = s
+	go s.run(interval)
+}
+
+// stopRevocationSweep stops the periodic revocation checks.
+//
+// Must be called from the server's handler goroutine, or after it
+// has stopped.
+func (server *Server) stopRevocationSweep() {
+	if server.revocation == nil {
+		return
+	}
+	close(server.revocation.done)
+	server.revocation = nil
+}
+
+// run checks the certificates of the connected clients every interval
+// until the sweeper is stopped.
+func (s *revocationSweeper) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		var clients []*Client
+		if !s.sync(func() {
+			for _, client := range s.server.clients {
+				if client.HasCertificate() {
+					clients = append(clients, client)
+				}
+			}
+		}) {
+			return
+		}
+
+		for _, client := range clients {
+			revoked, err := s.server.certRevoked(client.peerCertificates())
+			if err != nil {
+				client.Printf("Unable to check certificate revocation: %v", err)
+				continue
+			}
+			if !revoked {
+				continue
+			}
+			client := client
+			if !s.sync(func() {
+				if s.server.clients[client.Session()] == client {
+					client.Printf("Certificate revoked")
+					s.server.KickClient(client, "Certificate revoked")
+				}
+			}) {
+				return
+			}
+		}
+	}
+}
+
+// sync runs fn on the server's handler goroutine, and waits for it to
+// return. It returns false if the sweeper was stopped first.
+func (s *revocationSweeper) sync(fn func()) bool {
+	finished := make(chan bool)
+	select {
+	case s.server.syncCalls <- func() {
+		// The sweeper may have been replaced while the call was queued.
+		if s.server.revocation == s {
+			fn()
+		}
+		close(finished)
+	}:
+	case <-s.done:
+		return false
+	}
+	<-finished
+	return true
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file hooks Lua scripts (see pkg/scripting) into the server.
+//
+// The scripts named by the Scripts configuration key are loaded when
+// the server starts, and again whenever the configuration is reloaded.
+// Server events reach the scripts through the event queue (see
+// events.go).
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/plugin"
+	"mumble.info/grumble/pkg/scripting"
+)
+
+// loadScripts loads the scripts named by the Scripts key. Relative
+// paths are relative to the data directory.
+func (server *Server) loadScripts() ([]*scripting.Script, error) {
+	scripts := []*scripting.Script{}
+	for _, fn := range splitList(server.cfg.StringValue("Scripts")) {
+		if !filepath.IsAbs(fn) {
+			fn = filepath.Join(Args.DataDir, fn)
+		}
+		name := filepath.Base(fn)
+		script, err := scripting.Load(name, fn, &scriptHost{server: server, name: name})
+		if err != nil {
+			for _, script := range scripts {
+				script.Close()
+			}
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// setScripts replaces the server's scripts.
+func (server *Server) setScripts(scripts []*scripting.Script) {
+	for _, script := range server.scripts {
+		script.Close()
+	}
+	server.scripts = scripts
+}
+
+// scriptUser returns the scripts' view of user.
+func scriptUser(user plugin.User) scripting.User {
+	return scripting.User{
+		Session: user.Session,
+		Name:    user.Name,
+		UserId:  user.UserId,
+		Channel: user.Channel,
+	}
+}
+
+// dispatchScriptEvent delivers a queued event to the server's scripts.
+// Message events pass the text, channel_join events the channel
+// entered and the one left, and talking_stop events the length of the
+// talk spurt in seconds, as extra arguments.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) dispatchScriptEvent(ev plugin.Event) {
+	var args []interface{}
+	switch ev.Type {
+	case plugin.Message:
+		args = []interface{}{ev.Text}
+	case plugin.ChannelJoin:
+		args = []interface{}{ev.Channel, ev.PrevChannel}
+	case plugin.TalkingStop:
+		args = []interface{}{ev.Duration.Seconds()}
+	}
+	user := scriptUser(ev.User)
+	for _, script := range server.scripts {
+		script.Emit(ev.Type.String(), user, args...)
+	}
+}
+
+// A scriptHost carries out the actions of a single script.
+// Its methods are called from the server's handler goroutine.
+type scriptHost struct {
+	server *Server
+	name   string
+}
+
+func (host *scriptHost) client(session uint32) (*Client, error) {
+	client, ok := host.server.clients[session]
+	if !ok || client.state != StateClientReady {
+		return nil, errors.New("no such session")
+	}
+	return client, nil
+}
+
+func (host *scriptHost) SendMessage(session uint32, text string) error {
+	client, err := host.client(session)
+	if err != nil {
+		return err
+	}
+	return client.sendMessage(&mumbleproto.TextMessage{
+		Session: []uint32{session},
+		Message: proto.String(text),
+	})
+}
+
+func (host *scriptHost) SendChannelMessage(id int, text string) error {
+	channel, ok := host.server.Channels[id]
+	if !ok {
+		return errors.New("no such channel")
+	}
+	msg := &mumbleproto.TextMessage{
+		ChannelId: []uint32{uint32(id)},
+		Message:   proto.String(text),
+	}
+	for _, client := range channel.clients {
+		err := client.sendMessage(msg)
+		if err != nil {
+			client.Panicf("%v", err)
+		}
+	}
+	return nil
+}
+
+func (host *scriptHost) MoveUser(session uint32, id int) error {
+	client, err := host.client(session)
+	if err != nil {
+		return err
+	}
+	channel, ok := host.server.Channels[id]
+	if !ok {
+		return errors.New("no such channel")
+	}
+	userstate := &mumbleproto.UserState{
+		Session:   proto.Uint32(session),
+		ChannelId: proto.Uint32(uint32(id)),
+	}
+	host.server.userEnterChannel(client, channel, userstate)
+	return host.server.broadcastProtoMessage(userstate)
+}
+
+func (host *scriptHost) SetMute(session uint32, mute bool) error {
+	client, err := host.client(session)
+	if err != nil {
+		return err
+	}
+	if client.IsSuperUser() {
+		return errors.New("cannot mute SuperUser")
+	}
+	if client.Mute == mute {
+		return nil
+	}
+	action := "mute"
+	if !mute {
+		action = "unmute"
+	}
+	host.server.audit(nil, auditlog.Entry{Action: action, Actor: "script " + host.name, Target: client.ShownName()})
+	host.server.SetClientMute(client, mute)
+	return nil
+}
+
+func (host *scriptHost) Users() []scripting.User {
+	users := []scripting.User{}
+	for _, client := range host.server.clients {
+		if client.state == StateClientReady {
+			users = append(users, scriptUser(pluginUser(client)))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Session < users[j].Session })
+	return users
+}
+
+func (host *scriptHost) Log(text string) {
+	host.server.Printf("script %v: %v", host.name, text)
+}
@@ -8,32 +8,44 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"crypto/rand"
-	"crypto/sha1"
 	"crypto/tls"
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"hash"
 	"log"
 	"net"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/ipv4"
 	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/auditlog"
 	"mumble.info/grumble/pkg/ban"
+	"mumble.info/grumble/pkg/blobstore"
+	"mumble.info/grumble/pkg/chantemplate"
+	"mumble.info/grumble/pkg/escalation"
+	"mumble.info/grumble/pkg/features"
 	"mumble.info/grumble/pkg/freezer"
 	"mumble.info/grumble/pkg/htmlfilter"
 	"mumble.info/grumble/pkg/logtarget"
+	"mumble.info/grumble/pkg/mdns"
+	"mumble.info/grumble/pkg/msgtemplate"
 	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/password"
+	"mumble.info/grumble/pkg/plugin"
+	"mumble.info/grumble/pkg/proxyproto"
+	"mumble.info/grumble/pkg/scripting"
 	"mumble.info/grumble/pkg/serverconf"
 	"mumble.info/grumble/pkg/sessionpool"
 	"mumble.info/grumble/pkg/web"
+	"mumble.info/grumble/pkg/wordfilter"
 )
 
 // The default port a Murmur server listens on
@@ -62,22 +74,28 @@ type KeyValuePair struct {
 type Server struct {
 	Id int64
 
-	tcpl      *net.TCPListener
-	tlsl      net.Listener
-	udpconn   *net.UDPConn
+	tcpls     []*net.TCPListener
+	tlsls     []net.Listener
+	udpconns  []*net.UDPConn
+	proxyls   []*proxyproto.Listener
 	tlscfg    *tls.Config
 	webwsl    *web.Listener
 	webtlscfg *tls.Config
 	webhttp   *http.Server
 	bye       chan bool
 	netwg     sync.WaitGroup
-	running   bool
+	running   int32 // 1 while started; accessed atomically
+	startTime time.Time
 
 	incoming       chan *Message
 	voicebroadcast chan *VoiceBroadcast
 	cfgUpdate      chan *KeyValuePair
 	tempRemove     chan *Channel
 
+	// Functions to be run on the server's synchronous handler
+	// goroutine. Used by the admin API.
+	syncCalls chan func()
+
 	// Signals to the server that a client has been successfully
 	// authenticated.
 	clientAuthenticated chan *Client
@@ -93,6 +111,15 @@ type Server struct {
 	hclients  map[string][]*Client
 	hpclients map[string]*Client
 
+	// Voice datagrams queued by the handler goroutine
+	udpout *udpBatch
+
+	// Limits on answering UDP pings
+	udpPings udpPingLimiter
+
+	// Failed password checks of each address
+	passwords passwordThrottle
+
 	// Codec information
 	AlphaCodec       int32
 	BetaCodec        int32
@@ -116,9 +143,116 @@ type Server struct {
 	numLogOps int
 	freezelog *freezer.Log
 
+	// The directory of the server's state, and the blob store of its
+	// textures, comments and descriptions (see tenants.go).
+	dir   string
+	blobs blobstore.BlobStore
+
 	// Bans
-	banlock sync.RWMutex
-	Bans    []ban.Ban
+	banlock   sync.RWMutex
+	Bans      []ban.Ban
+	nextBanId uint32
+
+	// Per-country and per-ASN statistics
+	geoStats *geoStats
+
+	// Certificate enrollment portal
+	enroll *enrollState
+
+	// Pending certificate rotations, by user id
+	rotations map[uint32]rotationCode
+
+	// Invites
+	Invites      map[uint32]*Invite
+	nextInviteId uint32
+
+	// User reports
+	Reports      map[uint32]*Report
+	nextReportId uint32
+
+	// Scheduled announcements
+	Announcements      map[uint32]*Announcement
+	nextAnnouncementId uint32
+
+	// Whisper presets
+	WhisperPresets      map[uint32]*WhisperPreset
+	nextWhisperPresetId uint32
+
+	// Local network advertisement
+	mdnsService *mdns.Service
+
+	// Text message word filter
+	wordFilter     *wordfilter.Filter
+	msgTemplates   *msgtemplate.Templates
+	chanTemplates  *chantemplate.Templates
+	wordFilterHits []wordFilterHit
+
+	// Punishments for repeat offenders
+	escalationPolicy *escalation.Policy
+
+	// Client feature overrides and denylist, also read by the
+	// clients' goroutines
+	featureLock      sync.Mutex
+	featureOverrides *features.Overrides
+	clientDenylist   *features.Denylist
+
+	// Audit log of administrative actions, and the recent offenses
+	// in it that the escalation rules count
+	auditLock sync.Mutex
+	audits    *auditlog.Log
+	offenses  *offenseIndex
+
+	// Lua scripts and plugins, and the events delivered to them
+	scripts []*scripting.Script
+	plugins []*pluginHost
+	events  chan plugin.Event
+
+	// Discord text chat bridge
+	discord *discordBridge
+
+	// Channel federation links
+	federation *federationHub
+
+	// MQTT presence publisher
+	mqtt *mqttPublisher
+
+	// Clients in the waiting room, in the order they arrived
+	queue []*Client
+
+	// Encoded channel list sent to connecting clients
+	channelTree channelTreeCache
+
+	// Client certificate revocation checks
+	crl        crlCache
+	revocation *revocationSweeper
+
+	// Directory group sync, and the report of its last run
+	dirsync       *dirSyncer
+	dirSyncReport *dirSyncReport
+
+	// Compiled name policy patterns
+	namePatterns regexpCache
+
+	// Statistics of the handled control channel messages
+	messageStats map[uint16]*messageStat
+
+	// Number of oversized and delayed messages by kind. Counted by
+	// the clients' receiver goroutines.
+	recvStatsLock sync.Mutex
+	oversized     map[uint16]uint64
+	delayed       map[uint16]uint64
+
+	// Number of times a client's UDP path was found dead
+	udpFallbacks uint64
+
+	// Clients that are talking, by session
+	talkers map[uint32]*Client
+
+	// Join and leave broadcasts waiting to be sent
+	presence presenceBatch
+
+	// When the last ping summary was sent
+	lastPingSummary time.Time
 
 	// Logging
 	*log.Logger
@@ -142,6 +276,8 @@ func NewServer(id int64) (s *Server, err error) {
 	s = new(Server)
 
 	s.Id = id
+	s.dir = filepath.Join(Args.DataDir, "servers", strconv.FormatInt(id, 10))
+	s.blobs = blobStore
 
 	s.cfg = serverconf.New(nil)
 
@@ -156,6 +292,20 @@ func NewServer(id int64) (s *Server, err error) {
 	s.Channels[0] = NewChannel(0, "Root")
 	s.nextChanId = 1
 
+	s.geoStats = newGeoStats()
+	s.enroll = newEnrollState()
+	s.rotations = make(map[uint32]rotationCode)
+
+	s.Invites = make(map[uint32]*Invite)
+	s.nextInviteId = 1
+	s.Reports = make(map[uint32]*Report)
+	s.nextReportId = 1
+	s.Announcements = make(map[uint32]*Announcement)
+	s.nextAnnouncementId = 1
+	s.WhisperPresets = make(map[uint32]*WhisperPreset)
+	s.nextWhisperPresetId = 1
+	s.nextBanId = 1
+
 	s.Logger = log.New(logtarget.Default, fmt.Sprintf("[%v] ", s.Id), log.LstdFlags|log.Lmicroseconds)
 
 	return
@@ -175,21 +325,40 @@ func (server *Server) RootChannel() *Channel {
 	return root
 }
 
-func (server *Server) setConfigPassword(key, password string) {
-	saltBytes := make([]byte, 24)
-	_, err := rand.Read(saltBytes)
-	if err != nil {
-		server.Fatalf("Unable to read from crypto/rand: %v", err)
+// isAdmin checks whether client is SuperUser or a member of the root
+// channel's admin group. It doesn't rely on the client's channel, which
+// isn't set while it connects.
+func (server *Server) isAdmin(client *Client) bool {
+	if client.IsSuperUser() {
+		return true
 	}
+	root := server.RootChannel()
+	return acl.GroupMemberCheck(&root.ACL, &root.ACL, "admin", client)
+}
 
-	salt := hex.EncodeToString(saltBytes)
-	hasher := sha1.New()
-	hasher.Write(saltBytes)
-	hasher.Write([]byte(password))
-	digest := hex.EncodeToString(hasher.Sum(nil))
+// passwordParams returns the argon2id parameters used for new password
+// hashes.
+func (server *Server) passwordParams() password.Params {
+	return password.Params{
+		Time:    uint32(server.cfg.IntValue("PasswordHashTime")),
+		Memory:  uint32(server.cfg.IntValue("PasswordHashMemory")),
+		Threads: uint8(server.cfg.IntValue("PasswordHashThreads")),
+	}
+}
 
+// hashConfigPassword returns the salted hash of pw that is stored
+// in password config keys.
+func (server *Server) hashConfigPassword(pw string) string {
+	val, err := password.Hash(pw, server.passwordParams())
+	if err != nil {
+		server.Fatalf("Unable to hash password: %v", err)
+	}
+	return val
+}
+
+func (server *Server) setConfigPassword(key, password string) {
 	// Could be racy, but shouldn't really matter...
-	val := "sha1$" + salt + "$" + digest
+	val := server.hashConfigPassword(password)
 	server.cfg.Set(key, val)
 
 	if server.cfgUpdate != nil {
@@ -207,43 +376,21 @@ func (server *Server) SetServerPassword(password string) {
 	server.setConfigPassword("ServerPassword", password)
 }
 
-func (server *Server) checkConfigPassword(key, password string) bool {
-	parts := strings.Split(server.cfg.StringValue(key), "$")
-	if len(parts) != 3 {
-		return false
-	}
-
-	if len(parts[2]) == 0 {
+// checkConfigPassword checks pw against the hash stored in a password
+// config key. Legacy hashes, and hashes made with other parameters than
+// the configured ones, are replaced once pw has been checked. Hashes
+// from the configuration file are left alone.
+//
+// Must not be called from the server's handler goroutine.
+func (server *Server) checkConfigPassword(key, pw string) bool {
+	stored := server.cfg.StringValue(key)
+	if !verifyPassword(pw, stored) {
 		return false
 	}
-
-	var h hash.Hash
-	switch parts[0] {
-	case "sha1":
-		h = sha1.New()
-	default:
-		// no such hash
-		return false
+	if server.cfg.IsSet(key) && password.NeedsRehash(stored, server.passwordParams()) {
+		server.setConfigPassword(key, pw)
 	}
-
-	// salt
-	if len(parts[1]) > 0 {
-		saltBytes, err := hex.DecodeString(parts[1])
-		if err != nil {
-			server.Fatalf("Unable to decode salt: %v", err)
-		}
-		h.Write(saltBytes)
-	}
-
-	// password
-	h.Write([]byte(password))
-
-	sum := hex.EncodeToString(h.Sum(nil))
-	if parts[2] == sum {
-		return true
-	}
-
-	return false
+	return true
 }
 
 // CheckSuperUserPassword checks whether password matches the set SuperUser password.
@@ -276,6 +423,11 @@ func (server *Server) handleIncomingClient(conn net.Conn) (err error) {
 	client.Printf("New connection: %v (%v)", conn.RemoteAddr(), client.Session())
 
 	client.tcpaddr = addr.(*net.TCPAddr)
+	client.connectedAt = time.Now()
+	if geoDB != nil {
+		client.geo, _ = geoDB.Lookup(client.tcpaddr.IP)
+		server.geoStats.connected(client.geo)
+	}
 	client.server = server
 	client.conn = conn
 	client.reader = bufio.NewReader(client.conn)
@@ -283,37 +435,11 @@ func (server *Server) handleIncomingClient(conn net.Conn) (err error) {
 	client.state = StateClientConnected
 
 	client.udprecv = make(chan []byte)
+	client.udpdone = make(chan struct{})
 	client.voiceTargets = make(map[uint32]*VoiceTarget)
 
 	client.user = nil
 
-	// Extract user's cert hash
-	// Only consider client certificates for direct connections, not WebSocket connections.
-	// We do not support TLS-level client certificates for WebSocket client.
-	if tlsconn, ok := client.conn.(*tls.Conn); ok {
-		err = tlsconn.Handshake()
-		if err != nil {
-			client.Printf("TLS handshake failed: %v", err)
-			client.Disconnect()
-			return
-		}
-
-		state := tlsconn.ConnectionState()
-		if len(state.PeerCertificates) > 0 {
-			hash := sha1.New()
-			hash.Write(state.PeerCertificates[0].Raw)
-			sum := hash.Sum(nil)
-			client.certHash = hex.EncodeToString(sum)
-		}
-
-		// Check whether the client's cert hash is banned
-		if server.IsCertHashBanned(client.CertHash()) {
-			client.Printf("Certificate hash is banned")
-			client.Disconnect()
-			return
-		}
-	}
-
 	// Launch network readers
 	go client.tlsRecvLoop()
 	go client.udpRecvLoop()
@@ -324,7 +450,45 @@ func (server *Server) handleIncomingClient(conn net.Conn) (err error) {
 // RemoveClient removes a disconnected client from the server's
 // internal representation.
 func (server *Server) RemoveClient(client *Client, kicked bool) {
+	server.forgetClientAddresses(client)
+
+	delete(server.clients, client.Session())
+	server.pool.Reclaim(client.Session())
+
+	if len(client.inviteGroups) > 0 {
+		server.releaseInviteGroups(client)
+	}
+
+	if geoDB != nil {
+		in, out := client.traffic.load()
+		server.geoStats.disconnected(client.geo, in, out)
+	}
+
+	server.stopTalking(client, time.Now())
+	if client.state == StateClientReady {
+		server.recordDisconnect(client)
+		server.emitEvent(plugin.Event{Type: plugin.Disconnect, User: pluginUser(client)})
+	}
+
+	// Remove client from channel
+	channel := client.Channel
+	if channel != nil {
+		channel.RemoveClient(client)
+	}
+
+	// If the user was not kicked, broadcast a UserRemove message.
+	// If the user is disconnect via a kick, the UserRemove message has already been sent
+	// at this point.
+	if !kicked && client.state > StateClientAuthenticated {
+		server.broadcastLeave(client)
+	}
+}
+
+// forgetClientAddresses removes client from the host and host/port
+// mappings, so that no more UDP packets are matched to it.
+func (server *Server) forgetClientAddresses(client *Client) {
 	server.hmutex.Lock()
+	defer server.hmutex.Unlock()
 	host := client.tcpaddr.IP.String()
 	oldclients := server.hclients[host]
 	newclients := []*Client{}
@@ -337,27 +501,45 @@ func (server *Server) RemoveClient(client *Client, kicked bool) {
 	if client.udpaddr != nil {
 		delete(server.hpclients, client.udpaddr.String())
 	}
-	server.hmutex.Unlock()
-
-	delete(server.clients, client.Session())
-	server.pool.Reclaim(client.Session())
+}
 
-	// Remove client from channel
-	channel := client.Channel
-	if channel != nil {
-		channel.RemoveClient(client)
+// KickClient removes a client from the server on the server's own
+// behalf, telling the other clients why.
+func (server *Server) KickClient(client *Client, reason string) {
+	if len(reason) == 0 {
+		reason = defaultRemoveReason
+	}
+	err := server.broadcastUserRemove(client, nil, false, reason)
+	if err != nil {
+		server.Panicf("Unable to broadcast UserRemove message")
+		return
 	}
+	server.audit(nil, auditlog.Entry{Action: "kick", Target: client.ShownName(), Reason: reason})
+	client.ForceDisconnect()
+	server.escalate(client, "kick")
+}
 
-	// If the user was not kicked, broadcast a UserRemove message.
-	// If the user is disconnect via a kick, the UserRemove message has already been sent
-	// at this point.
-	if !kicked && client.state > StateClientAuthenticated {
-		err := server.broadcastProtoMessage(&mumbleproto.UserRemove{
-			Session: proto.Uint32(client.Session()),
-		})
-		if err != nil {
-			server.Panic("Unable to broadcast UserRemove message for disconnected client.")
-		}
+// SetClientMute mutes or unmutes a client on the server's own behalf.
+// Unmuting a client also undeafens it. Either way, a timed mute no
+// longer expires.
+func (server *Server) SetClientMute(client *Client, mute bool) {
+	client.Mute = mute
+	client.muteExpires = time.Time{}
+	userstate := &mumbleproto.UserState{
+		Session: proto.Uint32(client.Session()),
+		Mute:    proto.Bool(mute),
+	}
+	if !mute && client.Deaf {
+		client.Deaf = false
+		userstate.Deaf = proto.Bool(false)
+	}
+	if client.IsRegistered() {
+		client.saveModeration()
+		server.UpdateFrozenUserModeration(client.user)
+	}
+	err := server.broadcastProtoMessage(userstate)
+	if err != nil {
+		server.Printf("Unable to broadcast UserState: %v", err)
 	}
 }
 
@@ -366,6 +548,7 @@ func (server *Server) AddChannel(name string) (channel *Channel) {
 	channel = NewChannel(server.nextChanId, name)
 	server.Channels[channel.Id] = channel
 	server.nextChanId += 1
+	server.channelTreeChanged()
 
 	return
 }
@@ -384,12 +567,14 @@ func (server *Server) RemoveChanel(channel *Channel) {
 func (server *Server) LinkChannels(channel *Channel, other *Channel) {
 	channel.Links[other.Id] = other
 	other.Links[channel.Id] = channel
+	server.channelTreeChanged()
 }
 
 // Unlink two channels
 func (server *Server) UnlinkChannels(channel *Channel, other *Channel) {
 	delete(channel.Links, other.Id)
 	delete(other.Links, channel.Id)
+	server.channelTreeChanged()
 }
 
 // This is the synchronous handler goroutine.
@@ -397,6 +582,10 @@ func (server *Server) UnlinkChannels(channel *Channel, other *Channel) {
 // to keep server state synchronized.
 func (server *Server) handlerLoop() {
 	regtick := time.Tick(time.Hour)
+	granttick := time.Tick(time.Second)
+	geotick := time.Tick(geoStatsReportInterval)
+	talktick := time.Tick(talkingCheckInterval)
+	presencetick := time.Tick(presenceCheckInterval)
 	for {
 		select {
 		// We're done. Stop the server's event handler
@@ -406,26 +595,20 @@ func (server *Server) handlerLoop() {
 		case msg := <-server.incoming:
 			client := msg.client
 			server.handleIncomingMessage(client, msg)
-		// Voice broadcast
+		// Voice broadcast. The voice packets waiting are relayed
+		// together, and the datagrams sent in a batch.
 		case vb := <-server.voicebroadcast:
-			if vb.target == 0 { // Current channel
-				channel := vb.client.Channel
-				for _, client := range channel.clients {
-					if client != vb.client {
-						err := client.SendUDP(vb.buf)
-						if err != nil {
-							client.Panicf("Unable to send UDP: %v", err)
-						}
-					}
-				}
-			} else {
-				target, ok := vb.client.voiceTargets[uint32(vb.target)]
-				if !ok {
-					continue
+			server.handleVoiceBroadcast(vb)
+		voice:
+			for i := 1; i < udpBatchSize; i++ {
+				select {
+				case vb := <-server.voicebroadcast:
+					server.handleVoiceBroadcast(vb)
+				default:
+					break voice
 				}
-
-				target.SendVoiceBroadcast(vb)
 			}
+			server.udpout.flush()
 		// Remove a temporary channel
 		case tempChannel := <-server.tempRemove:
 			if tempChannel.IsEmpty() {
@@ -444,10 +627,49 @@ func (server *Server) handlerLoop() {
 				server.ResetConfig(kvp.Key)
 			}
 
-		// Server registration update
+		// Admin API calls
+		case fn := <-server.syncCalls:
+			fn()
+
+		// Events for Lua scripts and plugins
+		case ev := <-server.events:
+			server.dispatchEvent(ev)
+
+		// Server registration update, and audit log pruning
 		// Tick every hour + a minute offset based on the server id.
 		case <-regtick:
 			server.RegisterPublicServer()
+			server.pruneAuditLog()
+
+		// Drop expired temporary permission grants and enrollment state,
+		// rotate due crypt keys, check UDP paths, and report ping times
+		case <-granttick:
+			server.expireGrants()
+			server.expireEnrollState()
+			server.expireRotationCodes()
+			server.expireAccessTokens()
+			server.expireOfflineMessages()
+			server.expireMutes()
+			server.RemoveExpiredBans()
+			server.admitQueued()
+			server.rekeyClients()
+			server.checkUDPPaths()
+			server.checkVoiceLoss()
+			server.sendPingSummary()
+			server.checkFederation()
+			server.sendAnnouncements()
+
+		// End the talk spurts of clients whose voice paused
+		case <-talktick:
+			server.checkTalking()
+
+		// Send batched join and leave broadcasts
+		case <-presencetick:
+			server.checkPresence()
+
+		// Periodic GeoIP statistics report
+		case <-geotick:
+			server.reportGeoStats()
 		}
 
 		// Check if its time to sync the server state and re-open the log
@@ -463,6 +685,39 @@ func (server *Server) handlerLoop() {
 	}
 }
 
+// handleVoiceBroadcast queues a client's voice packet for its
+// listeners in server.udpout.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) handleVoiceBroadcast(vb *VoiceBroadcast) {
+	// Users in no-voice channels can't talk, and can't be
+	// heard through whispers either.
+	if vb.client.Channel.NoVoice {
+		return
+	}
+	server.flushJoinOf(vb.client)
+	server.noteVoice(vb.client, vb.terminator)
+	if vb.target == 0 { // Current channel
+		channel := vb.client.Channel
+		for _, client := range channel.clients {
+			if client != vb.client && server.inHearingRange(vb.client, client) {
+				err := client.queueUDP(server.udpout, vb.packet(voiceContextNormal))
+				if err != nil {
+					client.Panicf("Unable to send UDP: %v", err)
+				}
+			}
+		}
+		server.relayVoice(vb)
+	} else {
+		target, ok := vb.client.voiceTargets[uint32(vb.target)]
+		if !ok {
+			return
+		}
+
+		target.SendVoiceBroadcast(vb)
+	}
+}
+
 // Handle an Authenticate protobuf message.  This is handled in a separate
 // goroutine to allow for remote authenticators that are slow to respond.
 //
@@ -490,6 +745,7 @@ func (server *Server) handleAuthenticate(client *Client, msg *Message) {
 	server.ClearCaches()
 
 	if client.state >= StateClientAuthenticated {
+		server.pushClientPermissions(client)
 		return
 	}
 
@@ -501,10 +757,31 @@ func (server *Server) handleAuthenticate(client *Client, msg *Message) {
 
 	client.Username = *auth.Username
 
+	if server.cfg.BoolValue("CertRequired") && !client.HasCertificate() {
+		client.RejectAuth(mumbleproto.Reject_NoCertificate, "A certificate is required to connect to this server")
+		return
+	}
+	if server.cfg.BoolValue("CertRequireVerified") && !client.IsVerified() {
+		client.RejectAuth(mumbleproto.Reject_NoCertificate, "A certificate issued by a CA trusted by this server is required to connect")
+		return
+	}
+	if client.HasCertificate() && server.revocationEnabled() {
+		revoked, err := server.certRevoked(client.peerCertificates())
+		if err != nil {
+			client.Printf("Unable to check certificate revocation: %v", err)
+		} else if revoked {
+			client.RejectAuth(mumbleproto.Reject_NoCertificate, "Your certificate has been revoked")
+			return
+		}
+	}
+
 	if client.Username == "SuperUser" {
 		if auth.Password == nil {
 			client.RejectAuth(mumbleproto.Reject_WrongUserPW, "")
 			return
+		} else if server.passwordThrottled(client) {
+			client.RejectAuth(mumbleproto.Reject_WrongUserPW, "Too many failed password attempts, try again later")
+			return
 		} else {
 			if server.CheckSuperUserPassword(*auth.Password) {
 				ok := false
@@ -514,15 +791,25 @@ func (server *Server) handleAuthenticate(client *Client, msg *Message) {
 					return
 				}
 			} else {
+				server.passwordFailed(client)
 				client.RejectAuth(mumbleproto.Reject_WrongUserPW, "")
 				return
 			}
 		}
 	} else {
 		// First look up registration by name.
-		user, exists := server.UserNameMap[client.Username]
+		user, exists := server.registeredUser(client.Username)
 		if exists {
-			if client.HasCertificate() && user.CertHash == client.CertHash() {
+			if client.HasCertificate() && user.Certificate(client.CertHash()) != nil {
+				client.user = user
+			} else if client.HasCertificate() && auth.Password != nil && server.cfg.BoolValue("CertRotation") {
+				// The user may bind a new certificate by proving
+				// the old credential.
+				if err := server.rotateCertificate(client, user, *auth.Password); err != nil {
+					client.Printf("Certificate rotation for %v failed: %v", user.Name, err)
+					client.RejectAuth(mumbleproto.Reject_WrongUserPW, "Wrong certificate hash")
+					return
+				}
 				client.user = user
 			} else {
 				client.RejectAuth(mumbleproto.Reject_WrongUserPW, "Wrong certificate hash")
@@ -537,13 +824,63 @@ func (server *Server) handleAuthenticate(client *Client, msg *Message) {
 				client.user = user
 			}
 		}
+
+		// Register clients with strong certificates, if enabled.
+		if client.user == nil && server.cfg.BoolValue("CertAutoRegister") {
+			if cert := server.strongCertificate(client); cert != nil {
+				var regErr error
+				err = server.runSync(func() {
+					client.user, regErr = server.autoRegister(client, cert)
+				})
+				if err == nil && regErr != nil {
+					client.Printf("Unable to register automatically: %v", regErr)
+				}
+			}
+		}
+
+		// Unregistered users must pick a name allowed by the name policy.
+		if client.user == nil {
+			if reason := server.checkUsername(client.Username, true); len(reason) > 0 {
+				client.RejectAuth(mumbleproto.Reject_InvalidUsername, reason)
+				return
+			}
+		}
+	}
+
+	// Plugins may let users in without the server password, or keep
+	// them out.
+	pluginAuth := plugin.AuthResult{Decision: plugin.Pass}
+	if client.Username != "SuperUser" {
+		pluginAuth = server.applyPluginAuthenticators(client, auth)
+	}
+	if pluginAuth.Decision == plugin.Deny {
+		client.RejectAuth(mumbleproto.Reject_WrongUserPW, pluginAuth.Reason)
+		return
 	}
 
-	if client.user == nil && server.hasServerPassword() {
-		if auth.Password == nil || !server.CheckServerPassword(*auth.Password) {
-			client.RejectAuth(mumbleproto.Reject_WrongServerPW, "Invalid server password")
+	if client.user == nil && server.isClosed() && pluginAuth.Decision != plugin.Allow {
+		// An invite may be given in place of the server password,
+		// either as the password or as one of the access tokens.
+		if server.passwordThrottled(client) {
+			client.RejectAuth(mumbleproto.Reject_WrongServerPW, "Too many failed password attempts, try again later")
 			return
 		}
+		passwordOK := auth.Password != nil && server.hasServerPassword() && server.CheckServerPassword(*auth.Password)
+		if !passwordOK {
+			var invited bool
+			err = server.runSync(func() {
+				invite := server.findInvite(append([]string{auth.GetPassword()}, auth.Tokens...)...)
+				if invite != nil {
+					server.useInvite(client, invite)
+					invited = true
+				}
+			})
+			if err != nil || !invited {
+				server.passwordFailed(client)
+				client.RejectAuth(mumbleproto.Reject_WrongServerPW, "Invalid server password")
+				return
+			}
+		}
 	}
 
 	// Setup the cryptstate for the client.
@@ -552,6 +889,7 @@ func (server *Server) handleAuthenticate(client *Client, msg *Message) {
 		client.Panicf("%v", err)
 		return
 	}
+	client.cryptKeyTime = time.Now()
 
 	// Send CryptState information to the client so it can establish an UDP connection,
 	// if it wishes.
@@ -600,6 +938,26 @@ func (server *Server) finishAuthenticate(client *Client) {
 		// No, that user isn't already connected. Move along.
 	}
 
+	// During maintenance, only admins may connect.
+	if server.cfg.BoolValue("Maintenance") && !server.isAdmin(client) {
+		client.RejectAuth(mumbleproto.Reject_None, server.cfg.StringValue("MaintenanceMessage"))
+		return
+	}
+
+	// Clients over the user limits wait in the queue channel, if there
+	// is one. Otherwise, a full server turns them away.
+	channel := server.entryChannel(client)
+	queueChannel := server.queueChannel()
+	if !client.IsSuperUser() && (server.serverFull() || server.channelFull(channel)) {
+		if queueChannel != nil {
+			server.enqueue(client, channel)
+			channel = queueChannel
+		} else if server.serverFull() {
+			client.RejectAuth(mumbleproto.Reject_ServerFull, "The server is full")
+			return
+		}
+	}
+
 	// Add the client to the connected list
 	server.clients[client.Session()] = client
 
@@ -627,14 +985,6 @@ func (server *Server) finishAuthenticate(client *Client) {
 	server.hclients[host] = append(server.hclients[host], client)
 	server.hmutex.Unlock()
 
-	channel := server.RootChannel()
-	if client.IsRegistered() {
-		lastChannel := server.Channels[client.user.LastChannelId]
-		if lastChannel != nil {
-			channel = lastChannel
-		}
-	}
-
 	userstate := &mumbleproto.UserState{
 		Session:   proto.Uint32(client.Session()),
 		Name:      proto.String(client.ShownName()),
@@ -647,37 +997,29 @@ func (server *Server) finishAuthenticate(client *Client) {
 
 	if client.IsRegistered() {
 		userstate.UserId = proto.Uint32(uint32(client.UserId()))
+		client.restoreModeration(userstate)
 
+		// Clients too old to ask for blobs are streamed their own
+		// texture and comment below.
 		if client.user.HasTexture() {
-			// Does the client support blobs?
-			if client.Version >= 0x10203 {
-				userstate.TextureHash = client.user.TextureBlobHashBytes()
-			} else {
-				buf, err := blobStore.Get(client.user.TextureBlob)
-				if err != nil {
-					server.Panicf("Blobstore error: %v", err.Error())
-				}
-				userstate.Texture = buf
-			}
+			userstate.TextureHash = client.user.TextureBlobHashBytes()
 		}
-
 		if client.user.HasComment() {
-			// Does the client support blobs?
-			if client.Version >= 0x10203 {
-				userstate.CommentHash = client.user.CommentBlobHashBytes()
-			} else {
-				buf, err := blobStore.Get(client.user.CommentBlob)
-				if err != nil {
-					server.Panicf("Blobstore error: %v", err.Error())
-				}
-				userstate.Comment = proto.String(string(buf))
-			}
+			userstate.CommentHash = client.user.CommentBlobHashBytes()
 		}
 	}
 
 	server.userEnterChannel(client, channel, userstate)
-	if err := server.broadcastProtoMessage(userstate); err != nil {
-		// Server panic?
+	if client.queued {
+		client.Suppress = true
+		userstate.Suppress = proto.Bool(true)
+	}
+	server.broadcastJoin(client, userstate)
+	if !client.supports(features.BlobHash) {
+		if err := client.sendUserBlobs(client); err != nil {
+			client.Panicf("%v", err)
+			return
+		}
 	}
 
 	server.sendUserList(client)
@@ -711,8 +1053,21 @@ func (server *Server) finishAuthenticate(client *Client) {
 		return
 	}
 
+	server.sendChannelTemplateActions(client)
+	server.sendReportAction(client)
+	server.sendWhisperPresetActions(client)
+	server.restoreVoiceTargets(client)
+
 	client.state = StateClientReady
 	client.clientReady <- true
+	server.recordConnect(client)
+	server.notifyWatchers(client)
+	server.deliverOfflineMessages(client)
+	server.emitEvent(plugin.Event{Type: plugin.Connect, User: pluginUser(client)})
+
+	if client.queued {
+		server.sendQueuePosition(client)
+	}
 }
 
 func (server *Server) updateCodecVersions(connecting *Client) {
@@ -811,6 +1166,10 @@ func (server *Server) updateCodecVersions(connecting *Client) {
 }
 
 func (server *Server) sendUserList(client *Client) {
+	suppressed := client.syncSuppressed()
+	batch := server.newMessageBatch()
+	streamBlobs := !client.supports(features.BlobHash) && !client.wantsReducedState()
+	var withBlobs []*Client
 	for _, connectedClient := range server.clients {
 		if connectedClient.state != StateClientReady {
 			continue
@@ -818,6 +1177,9 @@ func (server *Server) sendUserList(client *Client) {
 		if connectedClient == client {
 			continue
 		}
+		if !channelSynced(connectedClient.Channel, suppressed) {
+			continue
+		}
 
 		userstate := &mumbleproto.UserState{
 			Session:   proto.Uint32(connectedClient.Session()),
@@ -832,29 +1194,16 @@ func (server *Server) sendUserList(client *Client) {
 		if connectedClient.IsRegistered() {
 			userstate.UserId = proto.Uint32(uint32(connectedClient.UserId()))
 
-			if connectedClient.user.HasTexture() {
-				// Does the client support blobs?
-				if client.Version >= 0x10203 {
+			// Clients too old to ask for blobs are streamed them
+			// once the list is sent.
+			if streamBlobs {
+				withBlobs = append(withBlobs, connectedClient)
+			} else {
+				if connectedClient.user.HasTexture() {
 					userstate.TextureHash = connectedClient.user.TextureBlobHashBytes()
-				} else {
-					buf, err := blobStore.Get(connectedClient.user.TextureBlob)
-					if err != nil {
-						server.Panicf("Blobstore error: %v", err.Error())
-					}
-					userstate.Texture = buf
 				}
-			}
-
-			if connectedClient.user.HasComment() {
-				// Does the client support blobs?
-				if client.Version >= 0x10203 {
+				if connectedClient.user.HasComment() {
 					userstate.CommentHash = connectedClient.user.CommentBlobHashBytes()
-				} else {
-					buf, err := blobStore.Get(connectedClient.user.CommentBlob)
-					if err != nil {
-						server.Panicf("Blobstore error: %v", err.Error())
-					}
-					userstate.Comment = proto.String(string(buf))
 				}
 			}
 		}
@@ -862,6 +1211,9 @@ func (server *Server) sendUserList(client *Client) {
 		if connectedClient.Mute {
 			userstate.Mute = proto.Bool(true)
 		}
+		if connectedClient.Deaf {
+			userstate.Deaf = proto.Bool(true)
+		}
 		if connectedClient.Suppress {
 			userstate.Suppress = proto.Bool(true)
 		}
@@ -884,10 +1236,25 @@ func (server *Server) sendUserList(client *Client) {
 			userstate.PluginIdentity = proto.String(connectedClient.PluginIdentity)
 		}
 
-		err := client.sendMessage(userstate)
-		if err != nil {
-			// Server panic?
-			continue
+		if err := batch.add(client.userStateFor(userstate)); err != nil {
+			client.Panicf("%v", err)
+			return
+		}
+	}
+	for _, userstate := range server.remoteUserStates(suppressed) {
+		if err := batch.add(client.userStateFor(userstate)); err != nil {
+			client.Panicf("%v", err)
+			return
+		}
+	}
+	if err := client.sendBatches(batch.finish()); err != nil {
+		client.Panicf("%v", err)
+		return
+	}
+	for _, connectedClient := range withBlobs {
+		if err := client.sendUserBlobs(connectedClient); err != nil {
+			client.Panicf("%v", err)
+			return
 		}
 	}
 }
@@ -899,19 +1266,23 @@ func (server *Server) sendClientPermissions(client *Client, channel *Channel) {
 		return
 	}
 
-	// fixme(mkrautz): re-add when we have ACL caching
-	return
-
-	perm := acl.Permission(acl.NonePermission)
+	perm := acl.EffectivePermissions(&channel.ACL, client)
 	client.sendMessage(&mumbleproto.PermissionQuery{
 		ChannelId:   proto.Uint32(uint32(channel.Id)),
 		Permissions: proto.Uint32(uint32(perm)),
 	})
+	if client.sentPermissions == nil {
+		client.sentPermissions = make(map[int]acl.Permission)
+	}
+	client.sentPermissions[channel.Id] = perm
 }
 
 type ClientPredicate func(client *Client) bool
 
 func (server *Server) broadcastProtoMessageWithPredicate(msg interface{}, clientcheck ClientPredicate) error {
+	// Clients must know of all users the message may refer to.
+	server.flushPresence()
+	userstate, isUserState := msg.(*mumbleproto.UserState)
 	for _, client := range server.clients {
 		if !clientcheck(client) {
 			continue
@@ -919,9 +1290,18 @@ func (server *Server) broadcastProtoMessageWithPredicate(msg interface{}, client
 		if client.state < StateClientAuthenticated {
 			continue
 		}
-		err := client.sendMessage(msg)
-		if err != nil {
-			return err
+		sent := msg
+		if isUserState {
+			reduced := client.userStateFor(userstate)
+			if reduced == nil {
+				continue
+			}
+			sent = reduced
+		}
+		// A client whose connection broke is disconnected by its
+		// own receiver; it must not keep the message from the others.
+		if err := client.sendMessage(sent); err != nil {
+			client.Printf("Unable to send broadcast message: %v", err)
 		}
 	}
 
@@ -933,58 +1313,25 @@ func (server *Server) broadcastProtoMessage(msg interface{}) (err error) {
 	return
 }
 
-func (server *Server) handleIncomingMessage(client *Client, msg *Message) {
-	switch msg.kind {
-	case mumbleproto.MessageAuthenticate:
-		server.handleAuthenticate(msg.client, msg)
-	case mumbleproto.MessagePing:
-		server.handlePingMessage(msg.client, msg)
-	case mumbleproto.MessageChannelRemove:
-		server.handleChannelRemoveMessage(msg.client, msg)
-	case mumbleproto.MessageChannelState:
-		server.handleChannelStateMessage(msg.client, msg)
-	case mumbleproto.MessageUserState:
-		server.handleUserStateMessage(msg.client, msg)
-	case mumbleproto.MessageUserRemove:
-		server.handleUserRemoveMessage(msg.client, msg)
-	case mumbleproto.MessageBanList:
-		server.handleBanListMessage(msg.client, msg)
-	case mumbleproto.MessageTextMessage:
-		server.handleTextMessage(msg.client, msg)
-	case mumbleproto.MessageACL:
-		server.handleAclMessage(msg.client, msg)
-	case mumbleproto.MessageQueryUsers:
-		server.handleQueryUsers(msg.client, msg)
-	case mumbleproto.MessageCryptSetup:
-		server.handleCryptSetup(msg.client, msg)
-	case mumbleproto.MessageContextAction:
-		server.Printf("MessageContextAction from client")
-	case mumbleproto.MessageUserList:
-		server.handleUserList(msg.client, msg)
-	case mumbleproto.MessageVoiceTarget:
-		server.handleVoiceTarget(msg.client, msg)
-	case mumbleproto.MessagePermissionQuery:
-		server.handlePermissionQuery(msg.client, msg)
-	case mumbleproto.MessageUserStats:
-		server.handleUserStatsMessage(msg.client, msg)
-	case mumbleproto.MessageRequestBlob:
-		server.handleRequestBlob(msg.client, msg)
-	}
-}
-
-// Send the content of buf as a UDP packet to addr.
-func (s *Server) SendUDP(buf []byte, addr *net.UDPAddr) (err error) {
-	_, err = s.udpconn.WriteTo(buf, addr)
+// Send the content of buf as a UDP packet to addr, using the
+// socket conn.
+func (s *Server) SendUDP(conn *net.UDPConn, buf []byte, addr *net.UDPAddr) (err error) {
+	_, err = conn.WriteTo(buf, addr)
 	return
 }
 
-// Listen for and handle UDP packets.
-func (server *Server) udpListenLoop() {
+// Listen for and handle UDP packets arriving on conn.
+func (server *Server) udpListenLoop(conn *net.UDPConn) {
 	defer server.netwg.Done()
 
+	if udpBatchRead {
+		server.udpBatchListenLoop(conn)
+		return
+	}
+
 	buf := make([]byte, UDPPacketSize)
 	for {
-		nread, remote, err := server.udpconn.ReadFrom(buf)
+		nread, remote, err := conn.ReadFrom(buf)
 		if err != nil {
 			if isTimeout(err) {
 				continue
@@ -999,35 +1346,73 @@ func (server *Server) udpListenLoop() {
 			return
 		}
 
-		// Length 12 is for ping datagrams from the ConnectDialog.
-		if nread == 12 {
-			readbuf := bytes.NewBuffer(buf)
-			var (
-				tmp32 uint32
-				rand  uint64
-			)
-			_ = binary.Read(readbuf, binary.BigEndian, &tmp32)
-			_ = binary.Read(readbuf, binary.BigEndian, &rand)
-
-			buffer := bytes.NewBuffer(make([]byte, 0, 24))
-			_ = binary.Write(buffer, binary.BigEndian, uint32((1<<16)|(2<<8)|2))
-			_ = binary.Write(buffer, binary.BigEndian, rand)
-			_ = binary.Write(buffer, binary.BigEndian, uint32(len(server.clients)))
-			_ = binary.Write(buffer, binary.BigEndian, server.cfg.Uint32Value("MaxUsers"))
-			_ = binary.Write(buffer, binary.BigEndian, server.cfg.Uint32Value("MaxBandwidth"))
-
-			err = server.SendUDP(buffer.Bytes(), udpaddr)
-			if err != nil {
+		if !server.handleDatagram(conn, udpaddr, buf[0:nread]) {
+			return
+		}
+	}
+}
+
+// Listen for UDP packets arriving on conn, reading up to udpBatchSize
+// of them at a time (see udpbatch.go).
+func (server *Server) udpBatchListenLoop(conn *net.UDPConn) {
+	reader := newUDPReadBatcher(conn)
+	msgs := make([]ipv4.Message, udpBatchSize)
+	for i := range msgs {
+		msgs[i].Buffers = [][]byte{make([]byte, UDPPacketSize)}
+	}
+	for {
+		n, err := reader.ReadBatch(msgs, 0)
+		if err != nil {
+			if isTimeout(err) {
+				continue
+			} else {
+				return
+			}
+		}
+		for _, msg := range msgs[:n] {
+			udpaddr, ok := msg.Addr.(*net.UDPAddr)
+			if !ok {
+				continue
+			}
+			if !server.handleDatagram(conn, udpaddr, msg.Buffers[0][:msg.N]) {
 				return
 			}
+		}
+	}
+}
 
-		} else {
-			server.handleUdpPacket(udpaddr, buf[0:nread])
+// handleDatagram answers a ping datagram, or handles a voice datagram.
+// It returns false if conn can no longer be written to.
+func (server *Server) handleDatagram(conn *net.UDPConn, udpaddr *net.UDPAddr, buf []byte) bool {
+	// Length 12 is for ping datagrams from the ConnectDialog.
+	if len(buf) == 12 {
+		if !server.allowUDPPing(udpaddr) {
+			return true
 		}
+		readbuf := bytes.NewBuffer(buf)
+		var (
+			tmp32 uint32
+			rand  uint64
+		)
+		_ = binary.Read(readbuf, binary.BigEndian, &tmp32)
+		_ = binary.Read(readbuf, binary.BigEndian, &rand)
+
+		buffer := bytes.NewBuffer(make([]byte, 0, 24))
+		_ = binary.Write(buffer, binary.BigEndian, uint32((1<<16)|(2<<8)|2))
+		_ = binary.Write(buffer, binary.BigEndian, rand)
+		_ = binary.Write(buffer, binary.BigEndian, uint32(len(server.clients)))
+		_ = binary.Write(buffer, binary.BigEndian, server.cfg.Uint32Value("MaxUsers"))
+		_ = binary.Write(buffer, binary.BigEndian, server.cfg.Uint32Value("MaxBandwidth"))
+
+		err := server.SendUDP(conn, buffer.Bytes(), udpaddr)
+		return err == nil
 	}
+
+	server.handleUdpPacket(conn, udpaddr, buf)
+	return true
 }
 
-func (server *Server) handleUdpPacket(udpaddr *net.UDPAddr, buf []byte) {
+func (server *Server) handleUdpPacket(conn *net.UDPConn, udpaddr *net.UDPAddr, buf []byte) {
 	var match *Client
 	plain := make([]byte, len(buf))
 
@@ -1038,34 +1423,67 @@ func (server *Server) handleUdpPacket(udpaddr *net.UDPAddr, buf []byte) {
 	// If we don't find any matches, we look in the 'hclients',
 	// which maps a host address to a slice of clients.
 	server.hmutex.Lock()
-	defer server.hmutex.Unlock()
 	client, ok := server.hpclients[udpaddr.String()]
+	server.hmutex.Unlock()
 	if ok {
+		// Only the client's crypt state is locked while decrypting,
+		// so that the goroutines of several UDP sockets can decrypt
+		// at once (see udpsockets.go).
+		client.cryptLock.Lock()
 		err := client.crypt.Decrypt(plain, buf)
+		client.cryptLock.Unlock()
 		if err != nil {
 			client.Debugf("unable to decrypt incoming packet, requesting resync: %v", err)
 			client.cryptResync()
 			return
 		}
 		match = client
+	}
+
+	server.hmutex.Lock()
+	if match != nil {
+		// The client may have disconnected while the packet was
+		// decrypted.
+		if server.hpclients[udpaddr.String()] != match {
+			server.hmutex.Unlock()
+			return
+		}
 	} else {
 		host := udpaddr.IP.String()
 		hostclients := server.hclients[host]
+		// Other clients on the same host fail to decrypt the
+		// packet, so only give up once none of them can.
 		for _, client := range hostclients {
+			client.cryptLock.Lock()
 			err := client.crypt.Decrypt(plain[0:], buf)
-			if err != nil {
-				client.Debugf("unable to decrypt incoming packet, requesting resync: %v", err)
-				client.cryptResync()
-				return
-			} else {
+			client.cryptLock.Unlock()
+			if err == nil {
 				match = client
+				break
 			}
 		}
+		if match == nil && len(hostclients) == 1 {
+			// The packet can only have come from this client.
+			client := hostclients[0]
+			server.hmutex.Unlock()
+			client.Debugf("unable to decrypt incoming packet, requesting resync")
+			client.cryptResync()
+			return
+		}
 		if match != nil {
+			// Replies go out through the socket the client's
+			// datagrams arrive on, which is the same for all
+			// datagrams from its address.
 			match.udpaddr = udpaddr
+			match.udpconn = conn
 			server.hpclients[udpaddr.String()] = match
 		}
 	}
+	// hmutex must not be held while the packet is handed to the
+	// client below: that blocks until the client's UDP receiver is
+	// done with its previous packet, which may wait for the handler
+	// goroutine.
+	server.hmutex.Unlock()
 
 	if match == nil {
 		return
@@ -1073,10 +1491,14 @@ func (server *Server) handleUdpPacket(udpaddr *net.UDPAddr, buf []byte) {
 
 	// Resize the plaintext slice now that we know
 	// the true encryption overhead.
-	plain = plain[:len(plain)-match.crypt.Overhead()]
-
-	match.udp = true
-	match.udprecv <- plain
+	match.cryptLock.Lock()
+	overhead := match.crypt.Overhead()
+	match.cryptLock.Unlock()
+	plain = plain[:len(plain)-overhead]
+
+	match.setUDP(true)
+	match.traffic.addVoiceIn(len(buf))
+	match.deliverUDP(plain)
 }
 
 // ClearCaches clears the Server's caches
@@ -1115,6 +1537,14 @@ func (server *Server) userEnterChannel(client *Client, channel *Channel, usersta
 	if channel.parent != nil {
 		server.sendClientPermissions(client, channel.parent)
 	}
+
+	if client.state == StateClientReady {
+		from := -1
+		if oldchan != nil {
+			from = oldchan.Id
+		}
+		server.emitEvent(plugin.Event{Type: plugin.ChannelJoin, User: pluginUser(client), Channel: channel.Id, PrevChannel: from})
+	}
 }
 
 // Register a client on the server.
@@ -1135,9 +1565,15 @@ func (s *Server) RegisterClient(client *Client) (uid uint32, err error) {
 	if !client.HasCertificate() {
 		return 0, errors.New("no cert hash")
 	}
+	if _, exists := s.registeredUser(client.Username); exists {
+		return 0, errors.New("name already registered")
+	}
+	if _, exists := s.UserCertMap[client.CertHash()]; exists {
+		return 0, errors.New("certificate already registered")
+	}
 
 	user.Email = client.Email
-	user.CertHash = client.CertHash()
+	user.Certificates = []UserCertificate{{Hash: client.CertHash(), Added: time.Now().Unix()}}
 
 	uid = s.nextUserId
 	s.Users[uid] = user
@@ -1147,6 +1583,42 @@ func (s *Server) RegisterClient(client *Client) (uid uint32, err error) {
 	return uid, nil
 }
 
+// registeredUser looks up the registered user that holds name. If
+// NamesCaseInsensitive is set, names that only differ in case match.
+func (server *Server) registeredUser(name string) (*User, bool) {
+	if user, exists := server.UserNameMap[name]; exists {
+		return user, true
+	}
+	if server.cfg.BoolValue("NamesCaseInsensitive") {
+		for _, user := range server.Users {
+			if strings.EqualFold(user.Name, name) {
+				return user, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// CreateRegistration registers a new user that has no certificate yet.
+// The user can only connect once a certificate has been bound to it,
+// for example through the certificate enrollment portal.
+func (server *Server) CreateRegistration(name, email string) (*User, error) {
+	if _, exists := server.registeredUser(name); exists {
+		return nil, errors.New("name already registered")
+	}
+	user, err := NewUser(server.nextUserId, name)
+	if err != nil {
+		return nil, err
+	}
+	user.Email = email
+
+	server.nextUserId += 1
+	server.Users[user.Id] = user
+	server.UserNameMap[user.Name] = user
+	server.UpdateFrozenUserRecord(user)
+	return user, nil
+}
+
 // RemoveRegistration removes a registered user.
 func (s *Server) RemoveRegistration(uid uint32) (err error) {
 	user, ok := s.Users[uid]
@@ -1156,7 +1628,9 @@ func (s *Server) RemoveRegistration(uid uint32) (err error) {
 
 	// Remove from user maps
 	delete(s.Users, uid)
-	delete(s.UserCertMap, user.CertHash)
+	for _, cert := range user.Certificates {
+		delete(s.UserCertMap, cert.Hash)
+	}
 	delete(s.UserNameMap, user.Name)
 
 	// Remove from groups and ACLs.
@@ -1167,7 +1641,16 @@ func (s *Server) RemoveRegistration(uid uint32) (err error) {
 
 // Remove references for user id uid from channel. Traverses subchannels.
 func (s *Server) removeRegisteredUserFromChannel(uid uint32, channel *Channel) {
+	s.removeRegisteredUserFromACL(uid, channel)
 
+	for _, subChan := range channel.children {
+		s.removeRegisteredUserFromChannel(uid, subChan)
+	}
+}
+
+// Remove the ACL entries and group memberships of user id uid from
+// channel alone.
+func (s *Server) removeRegisteredUserFromACL(uid uint32, channel *Channel) {
 	newACL := []acl.ACL{}
 	for _, chanacl := range channel.ACL.ACLs {
 		if chanacl.UserId == int(uid) {
@@ -1188,10 +1671,6 @@ func (s *Server) removeRegisteredUserFromChannel(uid uint32, channel *Channel) {
 			delete(grp.Temporary, int(uid))
 		}
 	}
-
-	for _, subChan := range channel.children {
-		s.removeRegisteredUserFromChannel(uid, subChan)
-	}
 }
 
 // RemoveChannel removes a channel
@@ -1200,6 +1679,7 @@ func (server *Server) RemoveChannel(channel *Channel) {
 	if channel == server.RootChannel() {
 		return
 	}
+	server.channelTreeChanged()
 
 	// Remove all links
 	for _, linkedChannel := range channel.Links {
@@ -1239,7 +1719,8 @@ func (server *Server) RemoveChannel(channel *Channel) {
 	}
 }
 
-// RemoveExpiredBans removes expired bans
+// RemoveExpiredBans removes expired bans. It is called once a second,
+// and whenever a client connects.
 func (server *Server) RemoveExpiredBans() {
 	server.banlock.Lock()
 	defer server.banlock.Unlock()
@@ -1251,15 +1732,83 @@ func (server *Server) RemoveExpiredBans() {
 			newBans = append(newBans, ban)
 		} else {
 			update = true
+			server.DeleteFrozenBan(ban.Id)
+			server.audit(nil, auditlog.Entry{
+				Action:  "ban.expire",
+				Target:  banTarget(ban),
+				Details: fmt.Sprintf("%v/%v", ban.IP, ban.Mask),
+			})
 		}
 	}
 
 	if update {
 		server.Bans = newBans
-		server.UpdateFrozenBans(server.Bans)
 	}
 }
 
+// addBan gives b an id, adds it to the ban list and writes it to the
+// datastore. The caller must hold banlock.
+func (server *Server) addBan(b ban.Ban) {
+	b.Id = server.nextBanId
+	server.nextBanId += 1
+	server.Bans = append(server.Bans, b)
+	server.UpdateFrozenBan(b)
+}
+
+// setBans replaces the ban list with updated, and writes only the bans
+// that were added, changed or removed to the datastore. A ban keeps its
+// id if it is in updated unchanged, or else if updated has a ban of
+// the same address and mask. The caller must hold banlock.
+func (server *Server) setBans(updated []ban.Ban) {
+	addrKey := func(b ban.Ban) string {
+		return fmt.Sprintf("%v/%v", b.IP, b.Mask)
+	}
+	old := make(map[string][]ban.Ban)
+	for _, b := range server.Bans {
+		old[addrKey(b)] = append(old[addrKey(b)], b)
+	}
+
+	// take removes and returns the first ban of the same address and
+	// mask as b that matches.
+	take := func(b ban.Ban, match func(ban.Ban) bool) (ban.Ban, bool) {
+		k := addrKey(b)
+		for i, o := range old[k] {
+			if match(o) {
+				old[k] = append(old[k][:i], old[k][i+1:]...)
+				return o, true
+			}
+		}
+		return ban.Ban{}, false
+	}
+
+	changed := make([]bool, len(updated))
+	for i := range updated {
+		if o, ok := take(updated[i], updated[i].Equal); ok {
+			updated[i].Id = o.Id
+		} else {
+			changed[i] = true
+		}
+	}
+	for i := range updated {
+		if !changed[i] {
+			continue
+		}
+		if o, ok := take(updated[i], func(ban.Ban) bool { return true }); ok {
+			updated[i].Id = o.Id
+		} else {
+			updated[i].Id = server.nextBanId
+			server.nextBanId += 1
+		}
+		server.UpdateFrozenBan(updated[i])
+	}
+	for _, bans := range old {
+		for _, b := range bans {
+			server.DeleteFrozenBan(b.Id)
+		}
+	}
+	server.Bans = updated
+}
+
 // IsConnectionBanned Is the incoming connection conn banned?
 func (server *Server) IsConnectionBanned(conn net.Conn) bool {
 	server.banlock.RLock()
@@ -1352,13 +1901,17 @@ func (server *Server) initPerLaunchData() {
 	server.clients = make(map[uint32]*Client)
 	server.hclients = make(map[string][]*Client)
 	server.hpclients = make(map[string]*Client)
+	server.talkers = make(map[uint32]*Client)
 
 	server.bye = make(chan bool)
 	server.incoming = make(chan *Message)
 	server.voicebroadcast = make(chan *VoiceBroadcast)
+	server.udpout = newUDPBatch()
 	server.cfgUpdate = make(chan *KeyValuePair)
 	server.tempRemove = make(chan *Channel, 1)
+	server.syncCalls = make(chan func())
 	server.clientAuthenticated = make(chan *Client)
+	server.events = make(chan plugin.Event, eventQueueSize)
 }
 
 // Clean per-launch data
@@ -1367,13 +1920,17 @@ func (server *Server) cleanPerLaunchData() {
 	server.clients = nil
 	server.hclients = nil
 	server.hpclients = nil
+	server.talkers = nil
 
 	server.bye = nil
 	server.incoming = nil
 	server.voicebroadcast = nil
+	server.udpout = nil
 	server.cfgUpdate = nil
 	server.tempRemove = nil
+	server.syncCalls = nil
 	server.clientAuthenticated = nil
+	server.events = nil
 }
 
 // Port returns the port the native server will listen on when it is
@@ -1402,79 +1959,192 @@ func (server *Server) WebPort() int {
 	return port
 }
 
+// isRunning checks whether the server is started. Unlike most of the
+// server's state, it may be read from any goroutine.
+func (server *Server) isRunning() bool {
+	return atomic.LoadInt32(&server.running) == 1
+}
+
 // CurrentPort returns the port the native server is currently listening
 // on.  If called when the server is not running,
 // this function returns -1.
 func (server *Server) CurrentPort() int {
-	if !server.running {
+	if !server.isRunning() {
 		return -1
 	}
-	tcpaddr := server.tcpl.Addr().(*net.TCPAddr)
+	tcpaddr := server.tcpls[0].Addr().(*net.TCPAddr)
 	return tcpaddr.Port
 }
 
-// HostAddress returns the host address the server will listen on when
-// it is started. This must be an IP address, either IPv4
-// or IPv6.
-func (server *Server) HostAddress() string {
-	host := server.cfg.StringValue("Address")
-	if host == "" {
-		return "0.0.0.0"
+// ListenAddresses returns the addresses the server will listen on when
+// it is started. The Address config key holds a list of IPv4 or IPv6
+// addresses, separated by spaces or commas. Each address may include a
+// port; addresses without one use the server's port. If no addresses
+// are set, the server listens on all interfaces.
+func (server *Server) ListenAddresses() ([]*net.TCPAddr, error) {
+	addrs, err := parseListenAddresses(server.cfg.StringValue("Address"), server.Port())
+	if err != nil || len(addrs) > 0 {
+		return addrs, err
+	}
+	return []*net.TCPAddr{{Port: server.Port()}}, nil
+}
+
+// ProxyListenAddresses returns the addresses on which the server accepts
+// connections from a load balancer, which start with a PROXY protocol
+// header. They are set by the ProxyAddress config key, in the same
+// format as Address.
+func (server *Server) ProxyListenAddresses() ([]*net.TCPAddr, error) {
+	return parseListenAddresses(server.cfg.StringValue("ProxyAddress"), server.Port())
+}
+
+// proxyTrustedNetworks parses the ProxyTrustedNetworks config key, a list
+// of networks in CIDR notation that are allowed to send PROXY headers.
+func (server *Server) proxyTrustedNetworks() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, field := range splitList(server.cfg.StringValue("ProxyTrustedNetworks")) {
+		// Single addresses are taken as networks of one.
+		if ip := net.ParseIP(field); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			field = fmt.Sprintf("%v/%v", field, bits)
+		}
+		_, network, err := net.ParseCIDR(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy network %q", field)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// splitList splits a config value holding a list separated
+// by spaces or commas.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// parseListenAddresses parses a list of listen addresses. Addresses
+// without a port use defaultPort.
+func parseListenAddresses(value string, defaultPort int) ([]*net.TCPAddr, error) {
+	addrs := []*net.TCPAddr{}
+	for _, field := range splitList(value) {
+		host, port := field, defaultPort
+		if h, p, err := net.SplitHostPort(field); err == nil {
+			host = h
+			port, err = strconv.Atoi(p)
+			if err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid port in listen address %q", field)
+			}
+		} else {
+			host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid listen address %q: not an IP address", field)
+		}
+		addrs = append(addrs, &net.TCPAddr{IP: ip, Port: port})
 	}
-	return host
+	return addrs, nil
 }
 
 // Start the server.
 func (server *Server) Start() (err error) {
-	if server.running {
+	if server.isRunning() {
 		return errors.New("already running")
 	}
 
-	host := server.HostAddress()
-	port := server.Port()
+	addrs, err := server.ListenAddresses()
+	if err != nil {
+		return err
+	}
 	webport := server.WebPort()
 	shouldListenWeb := server.ListenWebPort()
 
-	// Setup our UDP listener
-	server.udpconn, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP(host), Port: port})
+	certFn := filepath.Join(Args.DataDir, "cert.pem")
+	keyFn := filepath.Join(Args.DataDir, "key.pem")
+	cert, err := tls.LoadX509KeyPair(certFn, keyFn)
 	if err != nil {
 		return err
 	}
-	/*
-		err = server.udpconn.SetReadTimeout(1e9)
-		if err != nil {
-			return err
-		}
-	*/
+	server.tlscfg = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequestClientCert,
+	}
 
-	// Set up our TCP connection
-	server.tcpl, err = net.ListenTCP("tcp", &net.TCPAddr{IP: net.ParseIP(host), Port: port})
+	proxyAddrs, err := server.ProxyListenAddresses()
+	if err != nil {
+		return err
+	}
+	server.wordFilter, err = server.loadWordFilter()
+	if err != nil {
+		return err
+	}
+	server.msgTemplates, err = server.loadMessageTemplates()
+	if err != nil {
+		return err
+	}
+	server.chanTemplates, err = server.loadChannelTemplates()
+	if err != nil {
+		return err
+	}
+	policy, err := server.loadEscalationPolicy()
+	if err != nil {
+		return err
+	}
+	server.setEscalationPolicy(policy)
+	server.pruneAuditLog()
+	overrides, err := server.loadFeatureOverrides()
+	if err != nil {
+		return err
+	}
+	server.setFeatureOverrides(overrides)
+	denylist, err := server.loadClientDenylist()
+	if err != nil {
+		return err
+	}
+	server.setClientDenylist(denylist)
+	scripts, err := server.loadScripts()
+	if err != nil {
+		return err
+	}
+	server.setScripts(scripts)
+	trusted, err := server.proxyTrustedNetworks()
+	if err != nil {
+		return err
+	}
+	if len(proxyAddrs) > 0 && len(trusted) == 0 {
+		return errors.New("ProxyAddress is set, but ProxyTrustedNetworks is empty")
+	}
+	err = server.startPlugins()
 	if err != nil {
 		return err
 	}
-	/*
-		err = server.tcpl.SetTimeout(1e9)
+
+	// Set up a UDP socket and a TCP listener for each address.
+	server.tcpls, server.tlsls, server.udpconns, server.proxyls = nil, nil, nil, nil
+	for _, addr := range addrs {
+		err = server.listen(addr)
 		if err != nil {
+			server.closeListeners()
 			return err
 		}
-	*/
-
-	// Wrap a TLS listener around the TCP connection
-	certFn := filepath.Join(Args.DataDir, "cert.pem")
-	keyFn := filepath.Join(Args.DataDir, "key.pem")
-	cert, err := tls.LoadX509KeyPair(certFn, keyFn)
-	if err != nil {
-		return err
 	}
-	server.tlscfg = &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		ClientAuth:   tls.RequestClientCert,
+	for _, addr := range proxyAddrs {
+		err = server.listenProxy(addr, trusted)
+		if err != nil {
+			server.closeListeners()
+			return err
+		}
 	}
-	server.tlsl = tls.NewListener(server.tcpl, server.tlscfg)
 
 	if shouldListenWeb {
-		// Create HTTP server and WebSocket "listener"
-		webaddr := &net.TCPAddr{IP: net.ParseIP(host), Port: webport}
+		// Create HTTP server and WebSocket "listener". It only
+		// listens on the first address.
+		webaddr := &net.TCPAddr{IP: addrs[0].IP, Port: webport}
 		server.webtlscfg = &tls.Config{
 			Certificates: []tls.Certificate{cert},
 			ClientAuth:   tls.NoClientCert,
@@ -1483,6 +2153,9 @@ func (server *Server) Start() (err error) {
 		server.webwsl = web.NewListener(webaddr, server.Logger)
 		mux := http.NewServeMux()
 		mux.Handle("/", server.webwsl)
+		if server.cfg.BoolValue("EnrollEnabled") {
+			server.registerEnrollHandlers(mux)
+		}
 		server.webhttp = &http.Server{
 			Addr:      webaddr.String(),
 			Handler:   mux,
@@ -1496,19 +2169,25 @@ func (server *Server) Start() (err error) {
 			WriteTimeout: 10 * time.Second,
 			IdleTimeout:  2 * time.Minute,
 		}
+		webl, err := listenTCP(webaddr)
+		if err != nil {
+			server.closeListeners()
+			return err
+		}
 		go func() {
-			err := server.webhttp.ListenAndServeTLS("", "")
+			err := server.webhttp.ServeTLS(webl, "", "")
 			if err != http.ErrServerClosed {
 				server.Fatalf("Fatal HTTP server error: %v", err)
 			}
 		}()
 
-		server.Printf("Started: listening on %v and %v", server.tcpl.Addr(), server.webwsl.Addr())
+		server.Printf("Started: listening on %v and %v", server.listenAddrs(), server.webwsl.Addr())
 	} else {
-		server.Printf("Started: listening on %v", server.tcpl.Addr())
+		server.Printf("Started: listening on %v", server.listenAddrs())
 	}
 
-	server.running = true
+	atomic.StoreInt32(&server.running, 1)
+	server.startTime = time.Now()
 
 	// Open a fresh freezer log
 	err = server.openFreezeLog()
@@ -1520,6 +2199,16 @@ func (server *Server) Start() (err error) {
 	// a clean state.
 	server.initPerLaunchData()
 
+	// Take over the sessions handed over by the previous process, if
+	// any, before any messages are handled.
+	server.adoptSessions()
+
+	server.startDiscordBridge()
+	server.startFederation()
+	server.startMQTT()
+	server.startRevocationSweep()
+	server.startDirectorySync()
+
 	// Launch the event handler goroutine
 	go server.handlerLoop()
 
@@ -1530,18 +2219,24 @@ func (server *Server) Start() (err error) {
 	// for the servers. Each network goroutine defers a call to
 	// netwg.Done(). In the Stop() we close all the connections
 	// and call netwg.Wait() to wait for the goroutines to end.
-	numWG := 2
+	numWG := len(server.udpconns) + len(server.tlsls)
 	if shouldListenWeb {
 		numWG++
 	}
 
 	server.netwg.Add(numWG)
-	go server.udpListenLoop()
-	go server.acceptLoop(server.tlsl)
+	for _, conn := range server.udpconns {
+		go server.udpListenLoop(conn)
+	}
+	for _, l := range server.tlsls {
+		go server.acceptLoop(l)
+	}
 	if shouldListenWeb {
 		go server.acceptLoop(server.webwsl)
 	}
 
+	server.startMDNS()
+
 	// Schedule a server registration update (if needed)
 	go func() {
 		time.Sleep(1 * time.Minute)
@@ -1551,28 +2246,99 @@ func (server *Server) Start() (err error) {
 	return nil
 }
 
-// Stop the server.
-func (server *Server) Stop() (err error) {
-	if !server.running {
+// listen sets up the UDP sockets and a TLS listener on addr.
+func (server *Server) listen(addr *net.TCPAddr) error {
+	udpaddr := &net.UDPAddr{IP: addr.IP, Port: addr.Port, Zone: addr.Zone}
+	udpconns, err := server.listenUDPSockets(udpaddr, server.udpSocketCount())
+	if err != nil {
+		return err
+	}
+	tcpl, err := listenTCP(addr)
+	if err != nil {
+		for _, conn := range udpconns {
+			conn.Close()
+		}
+		return err
+	}
+	server.udpconns = append(server.udpconns, udpconns...)
+	server.tcpls = append(server.tcpls, tcpl)
+	server.tlsls = append(server.tlsls, tls.NewListener(tcpl, server.tlscfg))
+	return nil
+}
+
+// listenProxy sets up a TLS listener on addr for connections that
+// start with a PROXY protocol header. Only the networks in trusted
+// may connect.
+func (server *Server) listenProxy(addr *net.TCPAddr, trusted []*net.IPNet) error {
+	tcpl, err := listenTCP(addr)
+	if err != nil {
+		return err
+	}
+	proxyl := proxyproto.NewListener(tcpl)
+	proxyl.Trusted = trusted
+	proxyl.ErrorLog = server.Logger
+	server.proxyls = append(server.proxyls, proxyl)
+	server.tlsls = append(server.tlsls, tls.NewListener(proxyl, server.tlscfg))
+	return nil
+}
+
+// closeListeners closes the server's TLS listeners and UDP sockets.
+func (server *Server) closeListeners() (err error) {
+	for _, l := range server.tlsls {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for _, conn := range server.udpconns {
+		if cerr := conn.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// listenAddrs returns the addresses of the server's listeners, for logging.
+func (server *Server) listenAddrs() string {
+	addrs := []string{}
+	for _, l := range server.tcpls {
+		addrs = append(addrs, l.Addr().String())
+	}
+	for _, l := range server.proxyls {
+		addrs = append(addrs, l.Addr().String()+" (PROXY)")
+	}
+	return strings.Join(addrs, ", ")
+}
+
+// Stop the server. Open HTTP connections are given until ctx is done
+// to finish.
+func (server *Server) Stop(ctx context.Context) (err error) {
+	if !server.isRunning() {
 		return errors.New("server not running")
 	}
 
+	// Say goodbye on the local network
+	server.stopMDNS()
+
 	// Stop the handler goroutine and disconnect all
 	// clients
 	server.bye <- true
+	server.stopDiscordBridge()
+	server.stopFederation()
+	server.stopMQTT()
+	server.stopRevocationSweep()
+	server.stopDirectorySync()
 	for _, client := range server.clients {
 		client.Disconnect()
 	}
 
 	// Wait for the HTTP server to shutdown gracefully
 	// A client could theoretically block the server from ever stopping by
-	// never letting the HTTP connection go idle, so we give 15 seconds of grace time.
+	// never letting the HTTP connection go idle, so it is only given until
+	// ctx's deadline.
 	// This does not apply to opened WebSockets, which were forcibly closed when
 	// all clients were disconnected.
-	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(15*time.Second))
 	if server.ListenWebPort() {
 		err = server.webhttp.Shutdown(ctx)
-		cancel()
 		if err == context.DeadlineExceeded {
 			server.Println("Forcibly shutdown HTTP server while stopping")
 		} else if err != nil {
@@ -1585,14 +2351,8 @@ func (server *Server) Stop() (err error) {
 		}
 	}
 
-	// Close the listeners
-	err = server.tlsl.Close()
-	if err != nil {
-		return err
-	}
-
-	// Close the UDP connection
-	err = server.udpconn.Close()
+	// Close the listeners and UDP sockets
+	err = server.closeListeners()
 	if err != nil {
 		return err
 	}
@@ -1610,7 +2370,9 @@ func (server *Server) Stop() (err error) {
 	server.netwg.Wait()
 
 	server.cleanPerLaunchData()
-	server.running = false
+	server.setScripts(nil)
+	server.stopPlugins()
+	atomic.StoreInt32(&server.running, 0)
 	server.Printf("Stopped")
 
 	return nil
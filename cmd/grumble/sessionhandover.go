@@ -0,0 +1,730 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file carries the sessions of connected clients over to the new
+// process on a Restart (see handover.go), so that clients stay
+// connected, and keep talking, through a binary upgrade.
+//
+// A TLS connection can't be moved to another process, so the old
+// process keeps the clients' TLS connections, and relays their control
+// channels, decrypted, to the new process over Unix socket pairs. The
+// old process exits once the last relayed connection closes. The rest
+// of each session moves: the new process takes over the session's id,
+// user, channel and state, and its voice crypt state. The UDP sockets
+// are handed over too, so voice goes on directly with the new process.
+//
+// Before the servers shut down, the receiver goroutines of the clients
+// are stopped between two messages, and the clients are taken off the
+// servers without telling anyone. Their records are passed to the new
+// process in an inherited file, along with the relay sockets. The new
+// process adopts the sessions before its servers take any connections.
+//
+// Only clients that finished connecting are carried over, and only
+// those connected over TLS. WebSocket connections can't be stopped
+// between two messages, and are disconnected as before.
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/cryptstate"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The time the clients' receivers have to stop for a handover.
+const handoverStopTimeout = 5 * time.Second
+
+// The key of the inherited file holding the handed over sessions.
+const sessionsKey = "sessions"
+
+// A clientHandover is the state of a client's session being handed over.
+type clientHandover struct {
+	// Closed once the client's receiver stopped reading. Whether it
+	// stopped to be handed over, rather than because the client
+	// disconnected, is in ready.
+	stopped chan struct{}
+	ready   bool
+
+	// Receives the relay to the new process, or is closed if the
+	// handover is called off.
+	relay chan net.Conn
+}
+
+// A sessionRecord is the state of a client's session, as it is handed
+// over to the new process.
+type sessionRecord struct {
+	Session uint32
+
+	// The client's addresses, and the local address of the UDP
+	// socket it uses
+	TCPAddr      string
+	UDPAddr      string
+	UDPLocalAddr string
+	UDP          bool
+	ConnectedAt  time.Time
+	Crypt        cryptstate.Snapshot
+	CryptKeyTime time.Time
+
+	// Registered user id, or -1
+	UserId       int
+	Username     string
+	CertHash     string
+	Verified     bool
+	Certificates [][]byte
+
+	Version    uint32
+	ClientName string
+	OSName     string
+	OSVersion  string
+	Codecs     []int32
+	Opus       bool
+
+	Tokens       []string
+	InviteTokens []string
+	InviteGroups []string
+	Grants       []permissionGrant
+
+	ChannelId       int
+	SelfMute        bool
+	SelfDeaf        bool
+	Mute            bool
+	Deaf            bool
+	Suppress        bool
+	PrioritySpeaker bool
+	Recording       bool
+	MuteExpires     time.Time
+	PluginContext   []byte
+	PluginIdentity  string
+	Comment         string
+	VoiceTargets    []handedOverVoiceTarget
+
+	// Waiting room, /afk and maintenance state. QueueIndex is the
+	// client's place in the queue, or -1.
+	Queued      bool
+	QueueIndex  int
+	QueueTarget int
+	Afk         bool
+	AfkReturn   int
+	Held        bool
+	HeldReturn  int
+}
+
+// A handedOverVoiceTarget is a client's voice target, as it is handed
+// over to the new process.
+type handedOverVoiceTarget struct {
+	Id       uint32
+	Sessions []uint32
+	Channels []handedOverTargetChannel
+	Users    []uint32
+	Preset   uint32
+}
+
+type handedOverTargetChannel struct {
+	Id          uint32
+	SubChannels bool
+	Links       bool
+	OnlyGroup   string
+}
+
+// A serverHandover holds the sessions of a server handed over to the
+// new process.
+type serverHandover struct {
+	Sessions []*sessionRecord
+	// Channels that are temporary. Channels are saved without
+	// the flag.
+	TemporaryChannels []int
+}
+
+var (
+	// The sessions handed over by the previous process, by server
+	// id. Guarded by handoverLock.
+	handedOverSessions map[int64]*serverHandover
+
+	// Relayed connections of clients handed over to the new process
+	relays sync.WaitGroup
+)
+
+// relayKey returns the key of the inherited relay socket of the given
+// session.
+func relayKey(id int64, session uint32) string {
+	return fmt.Sprintf("session/%v/%v", id, session)
+}
+
+// handingOver returns the client's pending handover, if any.
+func (client *Client) handingOver() *clientHandover {
+	client.handoverLock.Lock()
+	defer client.handoverLock.Unlock()
+	return client.handover
+}
+
+// endHandover tells a pending handover that the client's receiver
+// stopped because the client disconnected.
+func (client *Client) endHandover() {
+	client.handoverLock.Lock()
+	h := client.handover
+	client.handover = nil
+	client.handoverLock.Unlock()
+	if h != nil {
+		close(h.stopped)
+	}
+}
+
+// relayConnection is called by the client's receiver once it stopped
+// for handover h. When the relay arrives, the client's connection is
+// relayed until either side closes it, and relayConnection returns
+// true. If the handover is called off, it returns false, and the
+// receiver goes on.
+func (client *Client) relayConnection(h *clientHandover) bool {
+	h.ready = true
+	close(h.stopped)
+	relay, ok := <-h.relay
+	if !ok {
+		client.handoverLock.Lock()
+		client.handover = nil
+		client.handoverLock.Unlock()
+		client.conn.SetReadDeadline(time.Time{})
+		return false
+	}
+
+	client.conn.SetReadDeadline(time.Time{})
+	client.Printf("Relaying connection to the new process")
+	done := make(chan struct{})
+	go func() {
+		io.Copy(lockedConnWriter{client}, relay)
+		client.conn.Close()
+		close(done)
+	}()
+	// The reader may hold the start of messages not yet read.
+	io.Copy(relay, client.reader)
+	relay.Close()
+	<-done
+	client.Printf("Relayed connection closed")
+	relays.Done()
+	return true
+}
+
+// lockedConnWriter writes to a client's connection under its sendLock.
+type lockedConnWriter struct {
+	client *Client
+}
+
+func (w lockedConnWriter) Write(buf []byte) (int, error) {
+	w.client.sendLock.Lock()
+	defer w.client.sendLock.Unlock()
+	return w.client.conn.Write(buf)
+}
+
+// beginHandover asks the receivers of the server's connected clients to
+// stop between two messages, and returns the clients asked.
+func (server *Server) beginHandover() []*Client {
+	var clients []*Client
+	err := server.runSync(func() {
+		for _, client := range server.clients {
+			if client.state != StateClientReady || client.disconnected || !client.connectedOverTLS() {
+				continue
+			}
+			h := &clientHandover{
+				stopped: make(chan struct{}),
+				relay:   make(chan net.Conn, 1),
+			}
+			client.handoverLock.Lock()
+			client.handover = h
+			client.handoverLock.Unlock()
+			// Wake the receiver if it is waiting for a message.
+			client.conn.SetReadDeadline(time.Now())
+			clients = append(clients, client)
+		}
+	})
+	if err != nil {
+		server.Printf("Unable to hand sessions over: %v", err)
+	}
+	return clients
+}
+
+// detachSessions waits until the receivers of clients stopped, and takes
+// the clients whose receivers stopped in time off the server, without
+// telling anyone. It returns the records of their sessions, and the
+// remote ends of their relays by key. The handovers of the other clients
+// are called off.
+func (server *Server) detachSessions(clients []*Client, deadline time.Time) (*serverHandover, map[string]*os.File) {
+	var stopped []*Client
+	for _, client := range clients {
+		h := client.handingOver()
+		if h == nil {
+			continue
+		}
+		select {
+		case <-h.stopped:
+			if h.ready {
+				stopped = append(stopped, client)
+			}
+		case <-time.After(time.Until(deadline)):
+			close(h.relay)
+		}
+	}
+
+	sh := &serverHandover{}
+	files := make(map[string]*os.File)
+	err := server.runSync(func() {
+		for _, client := range stopped {
+			h := client.handingOver()
+			if client.disconnected || h == nil {
+				if h != nil {
+					close(h.relay)
+				}
+				continue
+			}
+			local, remote, err := relayPair()
+			if err != nil {
+				client.Printf("Unable to set up relay: %v", err)
+				close(h.relay)
+				continue
+			}
+			sh.Sessions = append(sh.Sessions, server.sessionRecord(client))
+			server.detachClient(client)
+			files[relayKey(server.Id, client.Session())] = remote
+			relays.Add(1)
+			h.relay <- local
+		}
+		for id, channel := range server.Channels {
+			if channel.IsTemporary() {
+				sh.TemporaryChannels = append(sh.TemporaryChannels, id)
+			}
+		}
+	})
+	if err != nil {
+		server.Printf("Unable to hand sessions over: %v", err)
+		for _, client := range stopped {
+			if h := client.handingOver(); h != nil {
+				close(h.relay)
+			}
+		}
+	}
+	return sh, files
+}
+
+// sessionRecord returns the record of client's session.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) sessionRecord(client *Client) *sessionRecord {
+	rec := &sessionRecord{
+		Session:         client.Session(),
+		TCPAddr:         client.tcpaddr.String(),
+		ConnectedAt:     client.connectedAt,
+		CryptKeyTime:    client.cryptKeyTime,
+		UserId:          client.UserId(),
+		Username:        client.Username,
+		CertHash:        client.certHash,
+		Verified:        client.verified,
+		Version:         client.Version,
+		ClientName:      client.ClientName,
+		OSName:          client.OSName,
+		OSVersion:       client.OSVersion,
+		Codecs:          client.codecs,
+		Opus:            client.opus,
+		Tokens:          client.tokens,
+		InviteTokens:    client.inviteTokens,
+		InviteGroups:    client.inviteGroups,
+		Grants:          client.grants,
+		ChannelId:       client.Channel.Id,
+		SelfMute:        client.SelfMute,
+		SelfDeaf:        client.SelfDeaf,
+		Mute:            client.Mute,
+		Deaf:            client.Deaf,
+		Suppress:        client.Suppress,
+		PrioritySpeaker: client.PrioritySpeaker,
+		Recording:       client.Recording,
+		MuteExpires:     client.muteExpires,
+		PluginContext:   client.PluginContext,
+		PluginIdentity:  client.PluginIdentity,
+		Comment:         client.comment,
+		Queued:          client.queued,
+		QueueIndex:      -1,
+		QueueTarget:     client.queueTarget,
+		Afk:             client.afk,
+		AfkReturn:       client.afkReturn,
+		Held:            client.held,
+		HeldReturn:      client.heldReturn,
+	}
+	if client.udpaddr != nil && client.udpconn != nil {
+		rec.UDPAddr = client.udpaddr.String()
+		rec.UDPLocalAddr = client.udpconn.LocalAddr().String()
+	}
+	for _, cert := range client.peerCertificates() {
+		rec.Certificates = append(rec.Certificates, cert.Raw)
+	}
+	for i, queued := range server.queue {
+		if queued == client {
+			rec.QueueIndex = i
+		}
+	}
+	for id, vt := range client.voiceTargets {
+		hvt := handedOverVoiceTarget{
+			Id:       id,
+			Sessions: vt.sessions,
+			Users:    vt.users,
+			Preset:   vt.preset,
+		}
+		for _, vtc := range vt.channels {
+			hvt.Channels = append(hvt.Channels, handedOverTargetChannel{
+				Id:          vtc.id,
+				SubChannels: vtc.subChannels,
+				Links:       vtc.links,
+				OnlyGroup:   vtc.onlyGroup,
+			})
+		}
+		rec.VoiceTargets = append(rec.VoiceTargets, hvt)
+	}
+
+	// From here on, voice to the client is tunneled through the
+	// control channel, so that the crypt state doesn't move on.
+	client.cryptLock.Lock()
+	rec.UDP = client.usesUDP()
+	client.setUDP(false)
+	rec.Crypt = client.crypt.Snapshot(client.CryptoMode)
+	client.cryptLock.Unlock()
+	return rec
+}
+
+// detachClient takes client off the server without telling anyone, and
+// without freeing its session id.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) detachClient(client *Client) {
+	server.forgetClientAddresses(client)
+	delete(server.clients, client.Session())
+	delete(server.talkers, client.Session())
+	for i, queued := range server.queue {
+		if queued == client {
+			server.queue = append(server.queue[:i], server.queue[i+1:]...)
+			break
+		}
+	}
+	if client.Channel != nil {
+		client.Channel.RemoveClient(client)
+	}
+	client.disconnected = true
+	close(client.udpdone)
+}
+
+// handOverSessions detaches the handed over sessions of all running
+// servers. It returns the sessions by server id, and the remote ends of
+// their relays by key.
+func handOverSessions() (map[int64]*serverHandover, map[string]*os.File) {
+	deadline := time.Now().Add(handoverStopTimeout)
+	asked := make(map[*Server][]*Client)
+	for _, server := range servers {
+		if server.isRunning() {
+			asked[server] = server.beginHandover()
+		}
+	}
+
+	sessions := make(map[int64]*serverHandover)
+	files := make(map[string]*os.File)
+	for server, clients := range asked {
+		sh, serverFiles := server.detachSessions(clients, deadline)
+		sessions[server.Id] = sh
+		for key, f := range serverFiles {
+			files[key] = f
+		}
+		if len(sh.Sessions) > 0 {
+			server.Printf("Handing %v sessions over", len(sh.Sessions))
+		}
+	}
+	return sessions, files
+}
+
+// writeSessions writes sessions to an unnamed temporary file, to be
+// inherited by the new process.
+func writeSessions(sessions map[int64]*serverHandover) (*os.File, error) {
+	f, err := ioutil.TempFile("", "grumble-sessions-")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	err = json.NewEncoder(f).Encode(sessions)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// loadHandedOverSessions reads the sessions handed over by the previous
+// process, if any.
+//
+// Must be called with handoverLock held.
+func loadHandedOverSessions() {
+	f := takeInherited(sessionsKey)
+	if f == nil {
+		return
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(&handedOverSessions); err != nil {
+		log.Printf("Unable to read handed over sessions: %v", err)
+		handedOverSessions = nil
+	}
+}
+
+// adoptSessions takes over the sessions of the server handed over by
+// the previous process, if any.
+//
+// Must be called before the server's goroutines are started.
+func (server *Server) adoptSessions() {
+	handoverLock.Lock()
+	sh := handedOverSessions[server.Id]
+	delete(handedOverSessions, server.Id)
+	conns := make(map[uint32]net.Conn)
+	if sh != nil {
+		for _, rec := range sh.Sessions {
+			f := takeInherited(relayKey(server.Id, rec.Session))
+			if f == nil {
+				continue
+			}
+			conn, err := net.FileConn(f)
+			f.Close()
+			if err != nil {
+				server.Printf("Unable to take session %v over: %v", rec.Session, err)
+				continue
+			}
+			conns[rec.Session] = conn
+		}
+	}
+	handoverLock.Unlock()
+	if sh == nil {
+		return
+	}
+
+	for _, id := range sh.TemporaryChannels {
+		if channel, ok := server.Channels[id]; ok {
+			channel.temporary = true
+		}
+	}
+
+	var adopted, queued, moved []*Client
+	queueIndex := make(map[*Client]int)
+	for _, rec := range sh.Sessions {
+		conn, ok := conns[rec.Session]
+		if !ok {
+			continue
+		}
+		client, err := server.adoptSession(rec, conn)
+		if err != nil {
+			server.Printf("Unable to take session %v over: %v", rec.Session, err)
+			conn.Close()
+			continue
+		}
+		adopted = append(adopted, client)
+		if rec.QueueIndex >= 0 {
+			queued = append(queued, client)
+			queueIndex[client] = rec.QueueIndex
+		}
+		if client.Channel.Id != rec.ChannelId {
+			moved = append(moved, client)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		return queueIndex[queued[i]] < queueIndex[queued[j]]
+	})
+	server.queue = append(server.queue, queued...)
+	server.ClearCaches()
+	server.updateCodecVersions(nil)
+
+	// Clients in channels that are gone were put in the root channel.
+	for _, client := range moved {
+		server.broadcastProtoMessage(&mumbleproto.UserState{
+			Session:   proto.Uint32(client.Session()),
+			ChannelId: proto.Uint32(uint32(client.Channel.Id)),
+		})
+	}
+
+	for _, client := range adopted {
+		go client.tlsRecvLoop()
+		go client.udpRecvLoop()
+	}
+	if len(adopted) > 0 {
+		server.Printf("Took %v sessions over from the previous process", len(adopted))
+	}
+}
+
+// adoptSession sets up a connected client from rec, relayed over conn.
+//
+// Must be called before the server's goroutines are started.
+func (server *Server) adoptSession(rec *sessionRecord, conn net.Conn) (*Client, error) {
+	tcpaddr, err := net.ResolveTCPAddr("tcp", rec.TCPAddr)
+	if err != nil {
+		return nil, err
+	}
+	// The UDP sockets were handed over too. Of sockets sharing a port,
+	// any will do.
+	var udpaddr *net.UDPAddr
+	var udpconn *net.UDPConn
+	for _, conn := range server.udpconns {
+		if conn.LocalAddr().String() == rec.UDPLocalAddr {
+			udpconn = conn
+			break
+		}
+	}
+	if udpconn != nil {
+		udpaddr, err = net.ResolveUDPAddr("udp", rec.UDPAddr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var certs []*x509.Certificate
+	for _, raw := range rec.Certificates {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if _, inUse := server.clients[rec.Session]; inUse {
+		return nil, fmt.Errorf("session %v is in use", rec.Session)
+	}
+
+	client := new(Client)
+	client.lf = &clientLogForwarder{client, server.Logger}
+	client.Logger = log.New(client.lf, "", 0)
+	client.server = server
+	client.session = rec.Session
+	client.conn = conn
+	client.reader = bufio.NewReader(conn)
+	client.tcpaddr = tcpaddr
+	client.udpaddr = udpaddr
+	client.udpconn = udpconn
+	client.connectedAt = rec.ConnectedAt
+	client.handedOver = true
+	client.handedOverCerts = certs
+
+	err = client.crypt.Restore(rec.Crypt)
+	if err != nil {
+		return nil, err
+	}
+	client.CryptoMode = rec.Crypt.Mode
+	client.cryptKeyTime = rec.CryptKeyTime
+	client.setUDP(rec.UDP && udpaddr != nil)
+
+	if rec.UserId >= 0 {
+		client.user = server.Users[uint32(rec.UserId)]
+	}
+	client.Username = rec.Username
+	client.certHash = rec.CertHash
+	client.verified = rec.Verified
+	client.Version = rec.Version
+	client.ClientName = rec.ClientName
+	client.OSName = rec.OSName
+	client.OSVersion = rec.OSVersion
+	client.features = server.clientFeatures(client)
+	client.codecs = rec.Codecs
+	client.opus = rec.Opus
+
+	client.tokens = rec.Tokens
+	client.inviteTokens = rec.InviteTokens
+	client.grants = rec.Grants
+	root := server.RootChannel()
+	for _, name := range rec.InviteGroups {
+		if group, ok := root.ACL.Groups[name]; ok {
+			group.Temporary[-int(client.Session())] = true
+			client.inviteGroups = append(client.inviteGroups, name)
+		}
+	}
+
+	client.SelfMute = rec.SelfMute
+	client.SelfDeaf = rec.SelfDeaf
+	client.Mute = rec.Mute
+	client.Deaf = rec.Deaf
+	client.Suppress = rec.Suppress
+	client.PrioritySpeaker = rec.PrioritySpeaker
+	client.Recording = rec.Recording
+	client.muteExpires = rec.MuteExpires
+	client.PluginContext = rec.PluginContext
+	client.PluginIdentity = rec.PluginIdentity
+	client.comment = rec.Comment
+	client.queued = rec.Queued
+	client.queueTarget = rec.QueueTarget
+	client.afk = rec.Afk
+	client.afkReturn = rec.AfkReturn
+	client.held = rec.Held
+	client.heldReturn = rec.HeldReturn
+
+	client.voiceTargets = make(map[uint32]*VoiceTarget)
+	for _, hvt := range rec.VoiceTargets {
+		vt := &VoiceTarget{
+			sessions: hvt.Sessions,
+			users:    hvt.Users,
+			preset:   hvt.Preset,
+		}
+		for _, vtc := range hvt.Channels {
+			vt.AddChannel(vtc.Id, vtc.SubChannels, vtc.Links, vtc.OnlyGroup)
+		}
+		client.voiceTargets[hvt.Id] = vt
+	}
+
+	client.udprecv = make(chan []byte)
+	client.udpdone = make(chan struct{})
+	client.state = StateClientReady
+
+	server.pool.Reserve(client.Session())
+	server.clients[client.Session()] = client
+	channel, ok := server.Channels[rec.ChannelId]
+	if !ok {
+		channel = root
+	}
+	channel.AddClient(client)
+
+	host := tcpaddr.IP.String()
+	server.hmutex.Lock()
+	server.hclients[host] = append(server.hclients[host], client)
+	if udpaddr != nil {
+		server.hpclients[udpaddr.String()] = client
+	}
+	server.hmutex.Unlock()
+
+	if geoDB != nil {
+		client.geo, _ = geoDB.Lookup(tcpaddr.IP)
+		server.geoStats.connected(client.geo)
+	}
+	return client, nil
+}
+
+// closeHandedOverSessions drops the handed over sessions of servers that
+// weren't started.
+//
+// Must be called with handoverLock held.
+func closeHandedOverSessions() {
+	for id, sh := range handedOverSessions {
+		log.Printf("Dropping %v sessions of server %v, which wasn't started", len(sh.Sessions), id)
+	}
+	handedOverSessions = nil
+}
+
+// isSessionKey checks whether an inherited file's key belongs to the
+// handed over sessions, rather than to a listening socket.
+func isSessionKey(key string) bool {
+	return key == sessionsKey || strings.HasPrefix(key, "session/")
+}
+
+// waitRelays waits until the relayed connections of the clients handed
+// over to the new process have closed.
+func waitRelays() {
+	relays.Wait()
+}
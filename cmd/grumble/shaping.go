@@ -0,0 +1,83 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the traffic shaping of control messages.
+//
+// Every client gets a token bucket for the control messages it sends,
+// other than voice, which refills at ControlMessageLimit messages per
+// second and holds up to ControlMessageBurst messages. A message that
+// finds the bucket empty isn't dropped: the client's receiver waits
+// for the next token before passing it to the server. A client that
+// floods the server with UserState changes, permission queries or
+// channel edits is thus slowed down to the sustained rate, without
+// holding up the server or other clients. Text messages are counted
+// too, on top of their own flood limit (see floodlimit.go).
+
+import (
+	"time"
+)
+
+// The longest a client's receiver waits for a single message.
+const maxControlDelay = 10 * time.Second
+
+// The time between two log messages about a client's delayed
+// messages.
+const controlDelayLogInterval = time.Minute
+
+// delay reserves room for another event in a bucket of size burst
+// that drains at rate events per second, and returns how long the
+// event must wait until it fits.
+func (b *leakyBucket) delay(now time.Time, rate, burst float64) time.Duration {
+	if !b.last.IsZero() {
+		b.level -= now.Sub(b.last).Seconds() * rate
+		if b.level < 0 {
+			b.level = 0
+		}
+	}
+	b.last = now
+	b.level++
+	if b.level <= burst {
+		return 0
+	}
+	return time.Duration((b.level - burst) / rate * float64(time.Second))
+}
+
+// shapeControlMessage waits until client may send another control
+// message of the given kind.
+//
+// Must be called from the client's receiver goroutine.
+func (client *Client) shapeControlMessage(kind uint16) {
+	server := client.server
+	rate := float64(server.cfg.IntValue("ControlMessageLimit"))
+	burst := float64(server.cfg.IntValue("ControlMessageBurst"))
+	if rate <= 0 || burst <= 0 {
+		return
+	}
+
+	now := time.Now()
+	wait := client.controlBucket.delay(now, rate, burst)
+	if wait <= 0 {
+		return
+	}
+	if wait > maxControlDelay {
+		// Don't let a flood build up an endless backlog.
+		client.controlBucket.level = burst + maxControlDelay.Seconds()*rate
+		wait = maxControlDelay
+	}
+
+	server.recvStatsLock.Lock()
+	if server.delayed == nil {
+		server.delayed = make(map[uint16]uint64)
+	}
+	server.delayed[kind]++
+	server.recvStatsLock.Unlock()
+
+	if now.Sub(client.lastControlDelayLog) > controlDelayLogInterval {
+		client.lastControlDelayLog = now
+		client.Printf("Delaying control messages (flood)")
+	}
+	time.Sleep(wait)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the graceful shutdown of servers.
+//
+// Before a server stops, its clients are sent ShutdownMessage, and
+// the server state is written to disk. On a restart (see handover.go),
+// the sessions handed over to the new process have already been taken
+// off the server, and only the clients left behind, such as those
+// still connecting, are sent RestartMessage. The server then stops as usual.
+// Messages are written to clients directly rather than queued, so the
+// notices are written at once, each with a short deadline, to keep a
+// stalled client from holding up the shutdown or the notices of
+// others. Each server gets an equal share of the time left to shut
+// down, so a slow server doesn't use up the time of those after it.
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The time servers have to shut down before they are stopped anyway.
+const shutdownTimeout = 15 * time.Second
+
+// The time a client has to take the shutdown notice.
+const shutdownNoticeTimeout = 2 * time.Second
+
+// Shutdown tells the server's clients it is shutting down or
+// restarting, saves its state, and stops it. The server must stop by
+// ctx's deadline.
+func (server *Server) Shutdown(ctx context.Context, restarting bool) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(shutdownTimeout)
+	}
+	err := server.runSync(func() {
+		server.notifyShutdown(deadline, restarting)
+		if err := server.FreezeToFile(); err != nil {
+			server.Printf("Unable to save server state: %v", err)
+		}
+	})
+	if err != nil {
+		server.Printf("Unable to notify clients of shutdown: %v", err)
+	}
+	return server.Stop(ctx)
+}
+
+// notifyShutdown sends ShutdownMessage, or RestartMessage, to the
+// connected clients, all at once. A write that doesn't complete within
+// shutdownNoticeTimeout, or by deadline, is abandoned.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) notifyShutdown(deadline time.Time, restarting bool) {
+	text := server.cfg.StringValue("ShutdownMessage")
+	if restarting {
+		text = server.cfg.StringValue("RestartMessage")
+	}
+	if len(text) == 0 {
+		return
+	}
+	if noticeDeadline := time.Now().Add(shutdownNoticeTimeout); noticeDeadline.Before(deadline) {
+		deadline = noticeDeadline
+	}
+	var wg sync.WaitGroup
+	for _, client := range server.clients {
+		if client.state < StateClientAuthenticated {
+			continue
+		}
+		msg := &mumbleproto.TextMessage{
+			Session: []uint32{client.Session()},
+			Message: proto.String(text),
+		}
+		wg.Add(1)
+		go func(client *Client) {
+			defer wg.Done()
+			client.conn.SetWriteDeadline(deadline)
+			if err := client.sendMessage(msg); err != nil {
+				client.Printf("Unable to send shutdown notice: %v", err)
+			}
+			client.conn.SetWriteDeadline(time.Time{})
+		}(client)
+	}
+	wg.Wait()
+}
+
+// ShutdownServers shuts down all running servers, giving them
+// shutdownTimeout to finish. Readiness probes fail from then on.
+func ShutdownServers(restarting bool) {
+	setReady(false)
+	running := []*Server{}
+	for _, server := range servers {
+		if server.isRunning() {
+			running = append(running, server)
+		}
+	}
+	deadline := time.Now().Add(shutdownTimeout)
+	for i, server := range running {
+		// Time a server doesn't need is left to those after it.
+		share := time.Until(deadline) / time.Duration(len(running)-i)
+		ctx, cancel := context.WithTimeout(context.Background(), share)
+		log.Printf("Stopping server %v", server.Id)
+		if err := server.Shutdown(ctx, restarting); err != nil {
+			log.Printf("Server err %v", err)
+		}
+		cancel()
+	}
+}
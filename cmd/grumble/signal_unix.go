@@ -18,7 +18,8 @@ import (
 
 func SignalHandler() {
 	sigchan := make(chan os.Signal, 10)
-	signal.Notify(sigchan, syscall.SIGUSR2, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(sigchan, syscall.SIGUSR1, syscall.SIGUSR2, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+	restarted := false
 	for sig := range sigchan {
 		if sig == syscall.SIGUSR2 {
 			err := logtarget.Default.Rotate()
@@ -27,16 +28,55 @@ func SignalHandler() {
 			}
 			continue
 		}
-		if sig == syscall.SIGINT || sig == syscall.SIGTERM {
-			for _, server := range servers {
-				log.Printf("Stopping server %v", server.Id)
-				err := server.Stop()
-				if err != nil {
-					log.Printf("Server err %v", err)
-				}
+		// Once restarted, this process only relays the connections
+		// of the clients handed over to the new process.
+		if restarted {
+			if sig == syscall.SIGINT || sig == syscall.SIGTERM {
+				log.Print("Closing relayed connections. Exiting.")
+				os.Exit(0)
+			}
+			continue
+		}
+		if sig == syscall.SIGUSR1 {
+			err := Restart()
+			if err != nil {
+				log.Printf("Unable to restart: %v", err)
+				continue
+			}
+			restarted = true
+			log.Print("Restarted. Exiting once the relayed connections close.")
+			go func() {
+				waitRelays()
+				log.Print("Relayed connections closed. Exiting.")
+				os.Exit(0)
+			}()
+			continue
+		}
+		if sig == syscall.SIGHUP {
+			err := ReloadConfig()
+			if err != nil {
+				log.Printf("Unable to reload configuration: %v", err)
 			}
+			continue
+		}
+		if sig == syscall.SIGINT || sig == syscall.SIGTERM {
+			ShutdownServers(false)
+			unlockBlobStores()
 			log.Print("All servers stopped. Exiting.")
 			os.Exit(0)
 		}
 	}
 }
+
+// promoteSignal returns the channel on which SIGUSR1 promotes a
+// standby.
+func promoteSignal() chan os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1)
+	return c
+}
+
+// stopPromoteSignal stops the signals sent on c.
+func stopPromoteSignal(c chan os.Signal) {
+	signal.Stop(c)
+}
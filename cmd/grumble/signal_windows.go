@@ -4,5 +4,16 @@
 
 package main
 
+import "os"
+
 func SignalHandler() {
 }
+
+// promoteSignal returns a channel that is never sent on, since Windows
+// has no SIGUSR1. A standby is only promoted by --failover-after.
+func promoteSignal() chan os.Signal {
+	return nil
+}
+
+func stopPromoteSignal(c chan os.Signal) {
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the server-side detection of who is talking.
+//
+// A client starts talking with the first voice packet the server
+// passes on, and stops with a packet that ends its talk spurt, or when
+// its voice pauses for talkingTimeout, in case that packet was lost.
+// Both are delivered as talking_start and talking_stop events to
+// scripts, plugins and the MQTT publisher, and the admin API lists
+// the clients talking right now at /servers/<id>/talking, so that "now
+// speaking" dashboards work without client plugins.
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"mumble.info/grumble/pkg/plugin"
+)
+
+// The pause in a client's voice after which it stops talking.
+const talkingTimeout = 500 * time.Millisecond
+
+// The interval at which paused talkers are looked for.
+const talkingCheckInterval = 100 * time.Millisecond
+
+// noteVoice updates client's talking state for a voice packet it sent.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) noteVoice(client *Client, terminator bool) {
+	now := time.Now()
+	client.lastVoice = now
+	if !client.talking {
+		if terminator {
+			// A lone terminator ends a spurt that has been
+			// timed out already.
+			return
+		}
+		client.talking = true
+		client.talkingSince = now
+		server.talkers[client.Session()] = client
+		server.emitEvent(plugin.Event{Type: plugin.TalkingStart, User: pluginUser(client)})
+	}
+	if terminator {
+		server.stopTalking(client, now)
+	}
+}
+
+// stopTalking ends client's talk spurt, if it is talking.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) stopTalking(client *Client, now time.Time) {
+	if !client.talking {
+		return
+	}
+	client.talking = false
+	delete(server.talkers, client.Session())
+	server.emitEvent(plugin.Event{
+		Type:     plugin.TalkingStop,
+		User:     pluginUser(client),
+		Duration: now.Sub(client.talkingSince),
+	})
+}
+
+// checkTalking stops the talkers whose voice has paused.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) checkTalking() {
+	now := time.Now()
+	for _, client := range server.talkers {
+		if now.Sub(client.lastVoice) >= talkingTimeout {
+			server.stopTalking(client, client.lastVoice)
+		}
+	}
+}
+
+func init() {
+	registerAPIEndpoint("talking", handleAPITalking)
+}
+
+// apiTalking is the JSON representation of a talking client in the
+// admin API.
+type apiTalking struct {
+	Session uint32    `json:"session"`
+	Name    string    `json:"name"`
+	Channel int       `json:"channel"`
+	Since   time.Time `json:"since"`
+}
+
+// handleAPITalking implements /servers/<id>/talking.
+//
+//	GET  lists the clients that are talking, longest first
+func handleAPITalking(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	talking := []apiTalking{}
+	err := server.runSync(func() {
+		for _, client := range server.talkers {
+			talking = append(talking, apiTalking{
+				Session: client.Session(),
+				Name:    client.ShownName(),
+				Channel: client.Channel.Id,
+				Since:   client.talkingSince,
+			})
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	sort.Slice(talking, func(i, j int) bool {
+		if !talking[i].Since.Equal(talking[j].Since) {
+			return talking[i].Since.Before(talking[j].Since)
+		}
+		return talking[i].Session < talking[j].Session
+	})
+	writeJSON(w, http.StatusOK, talking)
+}
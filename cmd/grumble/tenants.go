@@ -0,0 +1,191 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the isolation of tenants, for hosting providers
+// that run a virtual server per customer.
+//
+// A virtual server keeps its state in $DATADIR/servers/<id>, and its
+// textures, comments and channel descriptions in the blob store shared
+// by all servers, $DATADIR/blob. The ServerDir and BlobDir keys of a
+// [server N] section move either elsewhere, such as onto a volume of
+// its own, so that a tenant's data is kept apart from the others' and
+// can be moved to another host by copying the two directories and the
+// section. Relative paths are relative to the data directory. Both
+// keys are only read when Grumble starts.
+//
+// Servers that share a BlobDir share its blob store. Each blob store is
+// locked, and has a blob cache of its own.
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"mumble.info/grumble/pkg/blobstore"
+	"mumble.info/grumble/pkg/serverconf"
+)
+
+// Config keys that are only read at startup.
+var tenantConfigKeys = []string{"ServerDir", "BlobDir"}
+
+// The open blob stores and their locks, by directory.
+var (
+	blobStores = map[string]blobstore.BlobStore{}
+	blobLocks  = map[string]*blobstore.Lock{}
+)
+
+// openBlobStore opens and locks the blob store in dir, creating the
+// directory if necessary. Opening a blob store twice returns the same
+// one.
+func openBlobStore(dir string) (blobstore.BlobStore, error) {
+	dir = filepath.Clean(dir)
+	if bs, ok := blobStores[dir]; ok {
+		return bs, nil
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return blobstore.BlobStore{}, err
+	}
+	bs := blobstore.Open(dir).WithCache(Args.BlobCacheSize << 20)
+
+	// Lock the blobstore, so that a second Grumble started on the
+	// same data directory doesn't write to it alongside us.
+	lock, err := bs.Lock()
+	if err != nil {
+		return blobstore.BlobStore{}, err
+	}
+	blobStores[dir] = bs
+	blobLocks[dir] = lock
+	return bs, nil
+}
+
+// sortedBlobDirs returns the directories of the open blob stores.
+func sortedBlobDirs() []string {
+	dirs := []string{}
+	for dir := range blobStores {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// unlockBlobStores releases the locks of all blob stores.
+func unlockBlobStores() {
+	for _, dir := range sortedBlobDirs() {
+		if err := blobLocks[dir].Unlock(); err != nil {
+			log.Printf("Unable to unlock blob directory (%v): %v", dir, err)
+		}
+	}
+}
+
+// lockBlobStores takes the locks of all blob stores again, after
+// unlockBlobStores.
+func lockBlobStores() error {
+	for _, dir := range sortedBlobDirs() {
+		lock, err := blobStores[dir].Lock()
+		if err != nil {
+			return fmt.Errorf("%v: %v", dir, err)
+		}
+		blobLocks[dir] = lock
+	}
+	return nil
+}
+
+// blobCacheStats returns the statistics of the caches of all blob
+// stores, added up.
+func blobCacheStats() blobstore.CacheStats {
+	total := blobstore.CacheStats{}
+	for _, bs := range blobStores {
+		stats := bs.CacheStats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Evictions += stats.Evictions
+		total.Blobs += stats.Blobs
+		total.Bytes += stats.Bytes
+		total.MaxBytes += stats.MaxBytes
+	}
+	return total
+}
+
+// tenantDir returns the directory set by key for the server with the
+// given id in cf, or def if it isn't set.
+func tenantDir(cf *serverconf.ConfigFile, id int64, key string, def string) string {
+	dir := cf.Servers[id][key]
+	if len(dir) == 0 {
+		return def
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(Args.DataDir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// tenantServerIds returns the ids of the servers given a ServerDir in
+// cf.
+func tenantServerIds(cf *serverconf.ConfigFile) []int64 {
+	ids := []int64{}
+	for id, section := range cf.Servers {
+		if len(section["ServerDir"]) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// loadServer loads the server with the given id from its directory,
+// and opens its blob store. A server whose directory holds no state
+// yet is created if create is set, and always if it was given a
+// ServerDir.
+func loadServer(cf *serverconf.ConfigFile, id int64, create bool) (*Server, error) {
+	defDir := filepath.Join(Args.DataDir, "servers", strconv.FormatInt(id, 10))
+	dir := tenantDir(cf, id, "ServerDir", defDir)
+	if dir != defDir {
+		// A server moved to its ServerDir must not leave its old
+		// state behind, or it would be unclear which one is current.
+		if _, err := os.Stat(defDir); err == nil {
+			return nil, fmt.Errorf("server %v has a ServerDir (%v), but %v exists too", id, dir, defDir)
+		}
+		create = true
+	}
+
+	var s *Server
+	var err error
+	if create && !hasFrozenServer(dir) {
+		if err = os.MkdirAll(dir, 0750); err != nil {
+			return nil, err
+		}
+		log.Printf("Creating server %v in %v", id, dir)
+		if s, err = NewServer(id); err == nil {
+			s.dir = dir
+		}
+	} else {
+		log.Printf("Loading server %v from %v", id, dir)
+		s, err = NewServerFromFrozen(id, dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	blobDir := tenantDir(cf, id, "BlobDir", filepath.Join(Args.DataDir, "blob"))
+	if s.blobs, err = openBlobStore(blobDir); err != nil {
+		return nil, fmt.Errorf("unable to open blob directory (%v): %v", blobDir, err)
+	}
+	return s, nil
+}
+
+// hasFrozenServer checks whether dir holds the state of a server.
+func hasFrozenServer(dir string) bool {
+	for _, name := range []string{"main.fz", "backup.fz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the permissions for the content of text
+// messages.
+//
+// Sending a text message takes TextMessagePermission. Messages with
+// HTML markup, such as formatting or images, also take
+// HTMLMessagePermission, and messages with links also take
+// LinkMessagePermission. Both are granted by default, and can be
+// denied, for example in public channels, to allow chatting but not
+// image spam.
+
+import (
+	"mumble.info/grumble/pkg/acl"
+	"mumble.info/grumble/pkg/htmlfilter"
+)
+
+// textMessagePermissions returns the permissions it takes to send
+// text, a filtered message.
+func textMessagePermissions(text string) []acl.Permission {
+	perms := []acl.Permission{acl.TextMessagePermission}
+	content, err := htmlfilter.Classify(text)
+	if err != nil {
+		// Filtered text parses, so this shouldn't happen; treat
+		// it as HTML to be safe.
+		content.HTML = true
+	}
+	if content.HTML {
+		perms = append(perms, acl.HTMLMessagePermission)
+	}
+	if content.Links {
+		perms = append(perms, acl.LinkMessagePermission)
+	}
+	return perms
+}
+
+// checkTextPermissions checks whether client holds perms in channel.
+// If it doesn't, it is told which permission it lacks.
+func (client *Client) checkTextPermissions(channel *Channel, perms []acl.Permission) bool {
+	for _, perm := range perms {
+		if !acl.HasPermission(&channel.ACL, client, perm) {
+			client.sendPermissionDenied(client, channel, perm)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements timed server mutes and bans.
+//
+// A server mute or deafen may be given a duration, either through the
+// Grumble-only mute_duration field of UserState, or through the admin
+// API. Once it runs out, the user is unmuted and told so. Bans made by
+// kicking a user may likewise be given a duration through the
+// Grumble-only ban_duration field of UserRemove, or the admin API, and
+// are removed once they run out. Both are checked once a second, and
+// both survive restarts.
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/ban"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// maxBanDuration is the longest timed ban. Bans store their duration
+// in seconds as a uint32.
+const maxBanDuration = math.MaxUint32 * time.Second
+
+// timedMute server mutes client, and deafens it too if deaf is set.
+// The mute is lifted after duration, or never if duration is 0.
+func (server *Server) timedMute(client *Client, deaf bool, duration time.Duration) {
+	client.Mute = true
+	client.Deaf = deaf
+	client.muteExpires = time.Time{}
+	if duration > 0 {
+		client.muteExpires = time.Now().Add(duration)
+	}
+	if client.IsRegistered() {
+		client.saveModeration()
+		server.UpdateFrozenUserModeration(client.user)
+	}
+	err := server.broadcastProtoMessage(&mumbleproto.UserState{
+		Session: proto.Uint32(client.Session()),
+		Mute:    proto.Bool(true),
+		Deaf:    proto.Bool(deaf),
+	})
+	if err != nil {
+		server.Printf("Unable to broadcast UserState: %v", err)
+	}
+}
+
+// expireMutes lifts the timed mutes that have run out.
+func (server *Server) expireMutes() {
+	now := time.Now()
+	for _, client := range server.clients {
+		if client.muteExpires.IsZero() || now.Before(client.muteExpires) {
+			continue
+		}
+		server.SetClientMute(client, false)
+		server.sendServerText(client, "Your server mute has run out. You may speak again.")
+		server.audit(nil, auditlog.Entry{Action: "unmute", Target: client.ShownName(), Details: "mute ran out"})
+	}
+}
+
+// banClient bans client's address and certificate for duration
+// seconds, or for good if duration is 0. The caller kicks the client.
+func (server *Server) banClient(client *Client, reason string, duration uint32) {
+	ban := ban.Ban{}
+	ban.IP = client.tcpaddr.IP
+	ban.Mask = 128
+	ban.Reason = reason
+	ban.Username = client.ShownName()
+	ban.CertHash = client.CertHash()
+	ban.Start = time.Now().Unix()
+	ban.Duration = duration
+
+	server.banlock.Lock()
+	server.addBan(ban)
+	server.banlock.Unlock()
+}
+
+// apiMute is the JSON representation of a client's server mute.
+type apiMute struct {
+	Mute     bool   `json:"mute"`
+	Deaf     bool   `json:"deaf"`
+	Expires  string `json:"expires,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+// apiBan is the JSON representation of a ban.
+type apiBan struct {
+	Id       uint32 `json:"id,omitempty"`
+	Session  uint32 `json:"session,omitempty"`
+	Address  string `json:"address,omitempty"`
+	Mask     int    `json:"mask,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Start    string `json:"start,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	Duration string `json:"duration,omitempty"`
+}
+
+func init() {
+	registerAPIEndpoint("mute", handleAPIMute)
+	registerAPIEndpoint("bans", handleAPIBans)
+}
+
+// handleAPIMute implements /servers/<id>/mute/<session>.
+//
+//	GET     shows the session's server mute
+//	POST    mutes it: {"deaf": false, "duration": "10m"}; without a duration, the mute doesn't run out
+//	DELETE  unmutes it
+func handleAPIMute(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 1 {
+		apiError(w, http.StatusNotFound, "expected /mute/<session>")
+		return
+	}
+	session, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid session")
+		return
+	}
+
+	var req apiMute
+	var duration time.Duration
+	if r.Method == http.MethodPost {
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if len(req.Duration) > 0 {
+			duration, err = time.ParseDuration(req.Duration)
+			if err != nil || duration <= 0 {
+				apiError(w, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+	} else if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		client, ok := server.clients[uint32(session)]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such session"}
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			server.timedMute(client, req.Deaf, duration)
+			entry := auditlog.Entry{Action: "mute", Target: client.ShownName()}
+			if req.Deaf {
+				entry.Action = "deafen"
+			}
+			if duration > 0 {
+				entry.Details = fmt.Sprintf("for %v", duration)
+			}
+			server.auditAPI(entry)
+			server.escalate(client, "mute")
+			if client.disconnected {
+				reply = map[string]string{"result": "banned by the escalation rules"}
+				return
+			}
+		case http.MethodDelete:
+			server.SetClientMute(client, false)
+			server.auditAPI(auditlog.Entry{Action: "unmute", Target: client.ShownName()})
+		}
+		mute := apiMute{Mute: client.Mute, Deaf: client.Deaf}
+		if !client.muteExpires.IsZero() {
+			mute.Expires = client.muteExpires.UTC().Format(time.RFC3339)
+		}
+		reply = mute
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
+
+// handleAPIBans implements /servers/<id>/bans.
+//
+//	GET   lists the bans
+//	POST  bans and kicks a session: {"session": 5, "reason": "spam", "duration": "24h"};
+//	      without a duration, the ban doesn't run out
+func handleAPIBans(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if len(args) != 0 {
+		apiError(w, http.StatusNotFound, "expected /bans")
+		return
+	}
+
+	var req apiBan
+	var duration time.Duration
+	var err error
+	if r.Method == http.MethodPost {
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if len(req.Duration) > 0 {
+			duration, err = time.ParseDuration(req.Duration)
+			if err != nil || duration < time.Second || duration > maxBanDuration {
+				apiError(w, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+		if len(req.Reason) == 0 {
+			req.Reason = defaultRemoveReason
+		}
+	} else if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		if r.Method == http.MethodPost {
+			client, ok := server.clients[req.Session]
+			if !ok {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such session"}
+				return
+			}
+			server.banClient(client, req.Reason, uint32(duration/time.Second))
+			entry := auditlog.Entry{Action: "ban", Target: client.ShownName(), Reason: req.Reason}
+			if duration > 0 {
+				entry.Details = fmt.Sprintf("for %v", duration)
+			}
+			server.auditAPI(entry)
+			if err := server.broadcastUserRemove(client, nil, true, req.Reason); err != nil {
+				server.Printf("Unable to broadcast UserRemove: %v", err)
+			}
+			client.ForceDisconnect()
+			server.escalate(client, "ban")
+		}
+
+		server.banlock.RLock()
+		defer server.banlock.RUnlock()
+		bans := []apiBan{}
+		for _, b := range server.Bans {
+			entry := apiBan{
+				Id:      b.Id,
+				Address: b.IP.String(),
+				Mask:    b.Mask,
+				Name:    b.Username,
+				Hash:    b.CertHash,
+				Reason:  b.Reason,
+				Start:   time.Unix(b.Start, 0).UTC().Format(time.RFC3339),
+			}
+			if b.Duration > 0 {
+				entry.Expires = time.Unix(b.Start+int64(b.Duration), 0).UTC().Format(time.RFC3339)
+			}
+			bans = append(bans, entry)
+		}
+		reply = bans
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, status, reply)
+}
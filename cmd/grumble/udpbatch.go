@@ -0,0 +1,78 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements batching in the UDP voice path, which cuts the
+// number of system calls per audio frame.
+//
+// The handler goroutine relays the voice packets waiting for it, up to
+// udpBatchSize of them, before sending any datagram. The datagrams for
+// the listeners are queued in a udpBatch, and sent with one sendmmsg
+// call per socket on Linux. Likewise, the UDP sockets are read with
+// recvmmsg, up to udpBatchSize datagrams at a time. Elsewhere,
+// datagrams are sent and read one at a time.
+
+import (
+	"net"
+)
+
+// The most voice packets relayed, and datagrams read, at a time.
+const udpBatchSize = 32
+
+// A udpDatagram is an encrypted datagram queued for a client.
+type udpDatagram struct {
+	buf    []byte
+	addr   *net.UDPAddr
+	client *Client
+}
+
+// A udpBatch holds the datagrams queued on each UDP socket. It is only
+// used by the server's handler goroutine.
+type udpBatch struct {
+	pending map[*net.UDPConn][]udpDatagram
+}
+
+func newUDPBatch() *udpBatch {
+	return &udpBatch{pending: make(map[*net.UDPConn][]udpDatagram)}
+}
+
+// queueUDP queues buf for the client in batch. Like SendUDP, it
+// tunnels buf through the control channel if the client doesn't use
+// UDP.
+func (client *Client) queueUDP(batch *udpBatch, buf []byte) error {
+	if !client.usesUDP() {
+		return client.sendMessage(buf)
+	}
+	client.cryptLock.Lock()
+	crypted := make([]byte, len(buf)+client.crypt.Overhead())
+	client.crypt.Encrypt(crypted, buf)
+	client.cryptLock.Unlock()
+	client.traffic.addVoiceOut(len(crypted))
+	conn := client.udpconn
+	batch.pending[conn] = append(batch.pending[conn], udpDatagram{buf: crypted, addr: client.udpaddr, client: client})
+	return nil
+}
+
+// flush sends the queued datagrams. Clients whose datagram can't be
+// sent are disconnected.
+func (batch *udpBatch) flush() {
+	for conn, dgrams := range batch.pending {
+		queued := dgrams
+		for len(dgrams) > 0 {
+			n, err := writeUDPBatch(conn, dgrams)
+			if err == nil {
+				break
+			}
+			dgrams[n].client.Panicf("Unable to send UDP: %v", err)
+			dgrams = dgrams[n+1:]
+		}
+		// Keep the slice for the next batch, without holding on to
+		// the datagrams.
+		for i := range queued {
+			queued[i] = udpDatagram{}
+		}
+		batch.pending[conn] = queued[:0]
+	}
+}
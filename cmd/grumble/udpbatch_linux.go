@@ -0,0 +1,129 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+import (
+	"net"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+)
+
+// Datagrams are read with recvmmsg.
+const udpBatchRead = true
+
+// An mmsghdr is the argument of sendmmsg for a single datagram.
+type mmsghdr struct {
+	hdr unix.Msghdr
+	len uint32
+}
+
+// udpReadBatcher reads several datagrams with one recvmmsg call.
+type udpReadBatcher interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+// newUDPReadBatcher returns a reader of batches of datagrams from conn.
+func newUDPReadBatcher(conn *net.UDPConn) udpReadBatcher {
+	if isIPv4Socket(conn) {
+		return ipv4.NewPacketConn(conn)
+	}
+	return ipv6.NewPacketConn(conn)
+}
+
+// isIPv4Socket checks whether conn is an AF_INET socket, rather than an
+// AF_INET6 socket that may also talk to IPv4 addresses.
+func isIPv4Socket(conn *net.UDPConn) bool {
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	return ok && addr.IP.To4() != nil
+}
+
+// writeUDPBatch sends dgrams on conn with as few sendmmsg calls as
+// possible. If a datagram can't be sent, it returns its index and the
+// error.
+//
+// The sockaddrs are built here rather than by ipv4.PacketConn's
+// WriteBatch, which addresses IPv4 clients with AF_INET sockaddrs that
+// dual-stack sockets reject.
+func writeUDPBatch(conn *net.UDPConn, dgrams []udpDatagram) (int, error) {
+	v4 := isIPv4Socket(conn)
+	hdrs := make([]mmsghdr, len(dgrams))
+	iovs := make([]unix.Iovec, len(dgrams))
+	var addrs4 []unix.RawSockaddrInet4
+	var addrs6 []unix.RawSockaddrInet6
+	if v4 {
+		addrs4 = make([]unix.RawSockaddrInet4, len(dgrams))
+	} else {
+		addrs6 = make([]unix.RawSockaddrInet6, len(dgrams))
+	}
+	for i, d := range dgrams {
+		iovs[i].Base = &d.buf[0]
+		iovs[i].SetLen(len(d.buf))
+		hdrs[i].hdr.Iov = &iovs[i]
+		hdrs[i].hdr.SetIovlen(1)
+		if v4 {
+			sa := &addrs4[i]
+			sa.Family = unix.AF_INET
+			putPort(&sa.Port, d.addr.Port)
+			copy(sa.Addr[:], d.addr.IP.To4())
+			hdrs[i].hdr.Name = (*byte)(unsafe.Pointer(sa))
+			hdrs[i].hdr.Namelen = unix.SizeofSockaddrInet4
+		} else {
+			sa := &addrs6[i]
+			sa.Family = unix.AF_INET6
+			putPort(&sa.Port, d.addr.Port)
+			copy(sa.Addr[:], d.addr.IP.To16())
+			if len(d.addr.Zone) > 0 {
+				if ifi, err := net.InterfaceByName(d.addr.Zone); err == nil {
+					sa.Scope_id = uint32(ifi.Index)
+				}
+			}
+			hdrs[i].hdr.Name = (*byte)(unsafe.Pointer(sa))
+			hdrs[i].hdr.Namelen = unix.SizeofSockaddrInet6
+		}
+	}
+
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	var serr error
+	err = rc.Write(func(fd uintptr) bool {
+		for sent < len(hdrs) {
+			n, _, errno := unix.Syscall6(unix.SYS_SENDMMSG, fd, uintptr(unsafe.Pointer(&hdrs[sent])), uintptr(len(hdrs)-sent), 0, 0, 0)
+			if errno == unix.EAGAIN {
+				return false
+			}
+			if errno == unix.EINTR {
+				continue
+			}
+			if errno != 0 {
+				serr = errno
+				return true
+			}
+			sent += int(n)
+		}
+		return true
+	})
+	runtime.KeepAlive(iovs)
+	runtime.KeepAlive(addrs4)
+	runtime.KeepAlive(addrs6)
+	runtime.KeepAlive(dgrams)
+	if err == nil {
+		err = serr
+	}
+	return sent, err
+}
+
+// putPort stores port in a sockaddr's port field, in network byte
+// order.
+func putPort(field *uint16, port int) {
+	b := (*[2]byte)(unsafe.Pointer(field))
+	b[0], b[1] = byte(port>>8), byte(port)
+}
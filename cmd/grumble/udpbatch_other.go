@@ -0,0 +1,36 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+)
+
+// Datagrams are read one at a time.
+const udpBatchRead = false
+
+type udpReadBatcher interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+func newUDPReadBatcher(conn *net.UDPConn) udpReadBatcher {
+	return nil
+}
+
+// writeUDPBatch sends dgrams on conn one at a time. If a datagram
+// can't be sent, it returns its index and the error.
+func writeUDPBatch(conn *net.UDPConn, dgrams []udpDatagram) (int, error) {
+	for i, d := range dgrams {
+		if _, err := conn.WriteTo(d.buf, d.addr); err != nil {
+			return i, err
+		}
+	}
+	return len(dgrams), nil
+}
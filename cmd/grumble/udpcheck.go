@@ -0,0 +1,96 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the detection of clients whose UDP path has
+// died.
+//
+// A client that sent voice or pings over UDP is sent its voice over
+// UDP as well. If no packet from it decrypts for UDPTimeout seconds,
+// the server assumes the UDP path is gone, tunnels the client's voice
+// through the control connection instead, and tells the client. Once
+// UDP packets arrive again, the server goes back to UDP.
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// checkUDPPaths switches clients whose UDP path seems dead over to the
+// control connection.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) checkUDPPaths() {
+	timeout := int64(server.cfg.IntValue("UDPTimeout"))
+	if timeout <= 0 {
+		return
+	}
+
+	var fallen []*Client
+	now := time.Now().Unix()
+
+	// The UDP receivers update a client's UDP state atomically and its
+	// crypt state under cryptLock, so hmutex isn't needed here.
+	for _, client := range server.clients {
+		if client.state != StateClientReady || !client.usesUDP() {
+			continue
+		}
+		client.cryptLock.Lock()
+		lastGood := client.crypt.LastGoodTime
+		client.cryptLock.Unlock()
+		if now-lastGood >= timeout && atomic.CompareAndSwapInt32(&client.udp, 1, 0) {
+			fallen = append(fallen, client)
+		}
+	}
+
+	for _, client := range fallen {
+		server.udpFallbacks++
+		client.Printf("No UDP packets for %v seconds, falling back to TCP", timeout)
+		server.sendServerText(client, "Your voice connection over UDP appears to be down. Voice is now sent over TCP, which may add latency. Check your firewall or router if this keeps happening.")
+	}
+}
+
+func init() {
+	registerAPIEndpoint("transport", handleAPITransport)
+}
+
+// handleAPITransport implements /servers/<id>/transport.
+func handleAPITransport(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var reply struct {
+		Clients   int     `json:"clients"`
+		UDP       int     `json:"udp"`
+		TCP       int     `json:"tcp"`
+		UDPRatio  float64 `json:"udp_ratio"`
+		Fallbacks uint64  `json:"fallbacks"`
+	}
+	err := server.runSync(func() {
+		for _, client := range server.clients {
+			if client.state != StateClientReady {
+				continue
+			}
+			reply.Clients++
+			if client.usesUDP() {
+				reply.UDP++
+			} else {
+				reply.TCP++
+			}
+		}
+		reply.Fallbacks = server.udpFallbacks
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if reply.Clients > 0 {
+		reply.UDPRatio = float64(reply.UDP) / float64(reply.Clients)
+	}
+	writeJSON(w, http.StatusOK, reply)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the limits on answering UDP pings.
+//
+// Clients ping servers over UDP to show their user count and ping time
+// in the server list. The reply is twice as large as the ping, and the
+// ping's source address is easily forged, so unlimited replies would
+// make the server an amplification reflector. Pings from addresses
+// with a connected client are always answered. Others are answered at
+// most UDPPingHostRate times a second per address, and UDPPingRate
+// times a second in total (0 means no limit), each with a burst of
+// twice that. If UDPPingSessionOnly is set, only pings from addresses
+// with a connected client are answered at all.
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// The number of addresses whose ping rate is tracked. When more
+// addresses ping, those that haven't pinged for a minute are forgotten.
+const udpPingHostsKept = 16384
+
+// udpPingLimiter holds the token buckets of unauthenticated pings.
+type udpPingLimiter struct {
+	mutex sync.Mutex
+	total leakyBucket
+	hosts map[string]*leakyBucket
+}
+
+// allow checks whether a ping from host may be answered.
+func (limiter *udpPingLimiter) allow(host string, now time.Time, rate, hostRate float64) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if hostRate > 0 {
+		if limiter.hosts == nil {
+			limiter.hosts = make(map[string]*leakyBucket)
+		}
+		bucket, ok := limiter.hosts[host]
+		if !ok {
+			if len(limiter.hosts) >= udpPingHostsKept {
+				limiter.prune(now)
+			}
+			bucket = &leakyBucket{}
+			limiter.hosts[host] = bucket
+		}
+		if !bucket.allow(now, hostRate, 2*hostRate) {
+			return false
+		}
+	}
+	if rate > 0 && !limiter.total.allow(now, rate, 2*rate) {
+		return false
+	}
+	return true
+}
+
+// prune forgets the addresses that haven't pinged for a minute, or
+// all of them if that isn't enough.
+func (limiter *udpPingLimiter) prune(now time.Time) {
+	for host, bucket := range limiter.hosts {
+		if now.Sub(bucket.last) > time.Minute {
+			delete(limiter.hosts, host)
+		}
+	}
+	if len(limiter.hosts) >= udpPingHostsKept {
+		limiter.hosts = make(map[string]*leakyBucket)
+	}
+}
+
+// hasSession checks whether a client is connected from addr's host.
+func (server *Server) hasSession(addr *net.UDPAddr) bool {
+	server.hmutex.Lock()
+	defer server.hmutex.Unlock()
+	return len(server.hclients[addr.IP.String()]) > 0
+}
+
+// allowUDPPing checks whether a UDP ping from addr may be answered.
+func (server *Server) allowUDPPing(addr *net.UDPAddr) bool {
+	if server.hasSession(addr) {
+		return true
+	}
+	if server.cfg.BoolValue("UDPPingSessionOnly") {
+		return false
+	}
+	rate := float64(server.cfg.IntValue("UDPPingRate"))
+	hostRate := float64(server.cfg.IntValue("UDPPingHostRate"))
+	return server.udpPings.allow(addr.IP.String(), time.Now(), rate, hostRate)
+}
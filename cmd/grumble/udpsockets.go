@@ -0,0 +1,72 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements spreading a server's UDP traffic over several
+// sockets, so that thousands of simultaneous speakers can be decrypted
+// on more than one core.
+//
+// The UDPSockets key sets the number of UDP sockets opened on each
+// address. They share the port with SO_REUSEPORT, and the kernel picks
+// the socket of each datagram by a hash of its source address and
+// port, so all datagrams of a client arrive on the same socket, and
+// are read and decrypted in order by that socket's goroutine. The
+// decryption itself only holds the client's cryptLock.
+//
+// Only Linux spreads datagrams over sockets this way; elsewhere, and
+// for the default of 1, a single socket is opened on each address.
+
+import (
+	"context"
+	"net"
+)
+
+// udpSocketCount returns the number of UDP sockets to open on each of
+// the server's addresses.
+func (server *Server) udpSocketCount() int {
+	n := server.cfg.IntValue("UDPSockets")
+	if n > 1 && !udpLoadBalancing {
+		server.Printf("UDPSockets = %v needs SO_REUSEPORT load balancing, which this platform lacks; using 1", n)
+		return 1
+	}
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// listenUDPSockets opens n UDP sockets sharing addr. If only some of
+// them can be opened, such as when the first socket was inherited from
+// a process that opened it without SO_REUSEPORT, those are used.
+func (server *Server) listenUDPSockets(addr *net.UDPAddr, n int) ([]*net.UDPConn, error) {
+	conn, err := listenUDP(addr, 0, n > 1)
+	if err != nil {
+		return nil, err
+	}
+	conns := []*net.UDPConn{conn}
+	for i := 1; i < n; i++ {
+		conn, err := listenUDP(addr, i, true)
+		if err != nil {
+			server.Printf("Unable to open UDP socket %v of %v on %v: %v", i+1, n, addr, err)
+			break
+		}
+		conns = append(conns, conn)
+	}
+	return conns, nil
+}
+
+// listenUDPReusable listens on addr, allowing other sockets to share
+// the port if reuse is set.
+func listenUDPReusable(addr *net.UDPAddr, reuse bool) (*net.UDPConn, error) {
+	if !reuse {
+		return net.ListenUDP("udp", addr)
+	}
+	lc := net.ListenConfig{Control: reusePort}
+	pc, err := lc.ListenPacket(context.Background(), "udp", addr.String())
+	if err != nil {
+		return nil, err
+	}
+	return pc.(*net.UDPConn), nil
+}
@@ -17,12 +17,65 @@ type User struct {
 	Id            uint32
 	Name          string
 	Password      string
-	CertHash      string
 	Email         string
 	TextureBlob   string
 	CommentBlob   string
 	LastChannelId int
 	LastActive    uint64
+	AccessTokens  []AccessToken
+
+	// Server mute, deafen and priority speaker flags, which are
+	// restored when the user connects.
+	Mute            bool
+	Deaf            bool
+	PrioritySpeaker bool
+	// When the server mute or deafen is lifted, in Unix time, or 0
+	// if it doesn't expire.
+	MuteExpires int64
+
+	// The address the user last connected from, or its hash if
+	// HashUserAddresses is set, and the seconds the user has spent
+	// connected in all.
+	LastAddress   string
+	ConnectedTime uint64
+
+	// The certificates bound to the user, one for each device the
+	// user connects from.
+	Certificates []UserCertificate
+
+	// The ids of the users this user is told about when they connect
+	Watches []uint32
+
+	// Text messages sent to the user while it was offline, delivered
+	// when it next connects
+	OfflineMessages []OfflineMessage
+
+	// The user's voice targets, restored when it connects
+	VoiceTargets []UserVoiceTarget
+}
+
+// A UserVoiceTarget is a voice target of a registered user, as it is
+// stored. Sessions of registered users are stored as their user ids;
+// other sessions are left out.
+type UserVoiceTarget struct {
+	Id uint32
+	// The whisper preset the target is bound to, or 0. Targets bound
+	// to a preset follow its changes, and store no targets of their
+	// own.
+	Preset   uint32
+	Channels []voiceTargetChannel
+	Users    []uint32
+}
+
+// A UserCertificate is a certificate bound to a registered user.
+type UserCertificate struct {
+	Hash string
+	// A name for the device, such as "phone", or empty.
+	Name string
+	// When the certificate was bound and when a client last connected
+	// with it, in Unix time, or 0 if not known.
+	Added    int64
+	LastSeen int64
 }
 
 // Create a new User
@@ -40,6 +93,17 @@ func NewUser(id uint32, name string) (user *User, err error) {
 	}, nil
 }
 
+// Certificate returns the certificate with the given hash that is
+// bound to the user, or nil if there is none.
+func (user *User) Certificate(hash string) *UserCertificate {
+	for i := range user.Certificates {
+		if user.Certificates[i].Hash == hash {
+			return &user.Certificates[i]
+		}
+	}
+	return nil
+}
+
 // HasComment Does the channel have comment?
 func (user *User) HasComment() bool {
 	return len(user.CommentBlob) > 0
@@ -0,0 +1,243 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the certificates of registered users.
+//
+// A registered user may have several certificates, one for each device
+// the user connects from, such as a desktop and a phone. A client
+// presenting any of them is the user. Each certificate has an optional
+// device name, and records when it was added and when a client last
+// connected with it. Admins manage the certificates through the admin
+// API.
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"mumble.info/grumble/pkg/auditlog"
+)
+
+// bindUserCertHash binds the certificate with the given hash to user.
+// It replaces the certificate with the hash old, keeping its device
+// name, or all of the user's certificates if old is empty. The actor
+// names what bound it in the audit log.
+func (server *Server) bindUserCertHash(user *User, old, hash string, actor string) {
+	bound := UserCertificate{Hash: hash, Added: time.Now().Unix()}
+	certs := []UserCertificate{}
+	replaced := false
+	for _, cert := range user.Certificates {
+		switch {
+		case cert.Hash == hash:
+		case len(old) == 0:
+			delete(server.UserCertMap, cert.Hash)
+		case cert.Hash == old:
+			delete(server.UserCertMap, cert.Hash)
+			bound.Name = cert.Name
+			certs = append(certs, bound)
+			replaced = true
+		default:
+			certs = append(certs, cert)
+		}
+	}
+	if !replaced {
+		certs = append(certs, bound)
+	}
+	user.Certificates = certs
+	server.UserCertMap[hash] = user
+	server.UpdateFrozenUserRecord(user)
+	server.certificateBound(user, hash, actor)
+}
+
+// certificateBound logs and audits that a user bound the certificate
+// with the given hash.
+func (server *Server) certificateBound(user *User, hash string, actor string) {
+	server.Printf("Bound certificate %v to user %v (%v) by %v", hash, user.Id, user.Name, actor)
+	server.audit(nil, auditlog.Entry{
+		Action:  "user.certificate",
+		Actor:   actor,
+		Target:  user.Name,
+		Details: fmt.Sprintf("user %v certificate %v", user.Id, hash),
+	})
+}
+
+// addUserCertificate binds another certificate to user.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) addUserCertificate(user *User, hash, name string) error {
+	if other, exists := server.UserCertMap[hash]; exists {
+		if other == user {
+			return errors.New("certificate already bound to the user")
+		}
+		return fmt.Errorf("certificate is registered to user %v", other.Id)
+	}
+	user.Certificates = append(user.Certificates, UserCertificate{
+		Hash:  hash,
+		Name:  name,
+		Added: time.Now().Unix(),
+	})
+	server.UserCertMap[hash] = user
+	server.UpdateFrozenUserRecord(user)
+	return nil
+}
+
+// removeUserCertificate unbinds a certificate from user, and kicks the
+// clients that connected with it. It returns false if the certificate
+// isn't bound to user.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) removeUserCertificate(user *User, hash string) bool {
+	certs := []UserCertificate{}
+	for _, cert := range user.Certificates {
+		if cert.Hash != hash {
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) == len(user.Certificates) {
+		return false
+	}
+	user.Certificates = certs
+	delete(server.UserCertMap, hash)
+	server.UpdateFrozenUserRecord(user)
+
+	for _, client := range server.clients {
+		if client.user == user && client.CertHash() == hash {
+			server.KickClient(client, "Certificate removed")
+		}
+	}
+	return true
+}
+
+// normalizeCertHash canonicalizes a certificate hash, the hex-encoded
+// SHA-1 hash Mumble shows for certificates. It returns an empty string
+// if hash isn't one.
+func normalizeCertHash(hash string) string {
+	hash = strings.ToLower(strings.Replace(strings.TrimSpace(hash), ":", "", -1))
+	if buf, err := hex.DecodeString(hash); err != nil || len(buf) != 20 {
+		return ""
+	}
+	return hash
+}
+
+// apiCertificate is the JSON representation of a certificate bound to
+// a registered user.
+type apiCertificate struct {
+	Hash     string `json:"hash"`
+	Name     string `json:"name,omitempty"`
+	Added    string `json:"added,omitempty"`
+	LastSeen string `json:"last_seen,omitempty"`
+}
+
+func newAPICertificate(cert UserCertificate) apiCertificate {
+	a := apiCertificate{Hash: cert.Hash, Name: cert.Name}
+	if cert.Added > 0 {
+		a.Added = time.Unix(cert.Added, 0).UTC().Format(time.RFC3339)
+	}
+	if cert.LastSeen > 0 {
+		a.LastSeen = time.Unix(cert.LastSeen, 0).UTC().Format(time.RFC3339)
+	}
+	return a
+}
+
+// handleAPIUserCertificates implements
+// /servers/<id>/users/<user>/certificates.
+//
+//	GET     lists the certificates bound to the user, with when each
+//	        was added and when a client last connected with it
+//	POST    binds another certificate: {"hash": "<SHA-1 hash>", "name": "phone"}
+//	DELETE  .../certificates/<hash> unbinds a certificate, and kicks
+//	        the clients connected with it
+func handleAPIUserCertificates(server *Server, w http.ResponseWriter, r *http.Request, uid string, args []string) {
+	id, err := strconv.ParseUint(uid, 10, 32)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, "invalid user")
+		return
+	}
+	var req apiCertificate
+	var hash string
+	switch {
+	case r.Method == http.MethodGet && len(args) == 0:
+	case r.Method == http.MethodPost && len(args) == 0:
+		if !readJSON(w, r, &req) {
+			return
+		}
+		hash = normalizeCertHash(req.Hash)
+		if len(hash) == 0 {
+			apiError(w, http.StatusBadRequest, "invalid certificate hash")
+			return
+		}
+	case r.Method == http.MethodDelete && len(args) == 1:
+		hash = normalizeCertHash(args[0])
+		if len(hash) == 0 {
+			apiError(w, http.StatusBadRequest, "invalid certificate hash")
+			return
+		}
+	case len(args) > 1:
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err = server.runSync(func() {
+		user, ok := server.Users[uint32(id)]
+		if !ok {
+			status, reply = http.StatusNotFound, map[string]string{"error": "no such user"}
+			return
+		}
+		if user.Id == 0 {
+			status, reply = http.StatusBadRequest, map[string]string{"error": "SuperUser signs in with a password"}
+			return
+		}
+
+		switch r.Method {
+		case http.MethodPost:
+			if err := server.addUserCertificate(user, hash, req.Name); err != nil {
+				status, reply = http.StatusConflict, map[string]string{"error": err.Error()}
+				return
+			}
+			server.auditAPI(auditlog.Entry{
+				Action:  "user.certificate.add",
+				Target:  user.Name,
+				Details: fmt.Sprintf("user %v certificate %v", user.Id, hash),
+			})
+			status, reply = http.StatusCreated, newAPICertificate(*user.Certificate(hash))
+		case http.MethodDelete:
+			if !server.removeUserCertificate(user, hash) {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such certificate"}
+				return
+			}
+			server.auditAPI(auditlog.Entry{
+				Action:  "user.certificate.remove",
+				Target:  user.Name,
+				Details: fmt.Sprintf("user %v certificate %v", user.Id, hash),
+			})
+			status = http.StatusNoContent
+		default:
+			certs := []apiCertificate{}
+			for _, cert := range user.Certificates {
+				certs = append(certs, newAPICertificate(cert))
+			}
+			reply = certs
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, reply)
+}
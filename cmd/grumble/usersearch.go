@@ -0,0 +1,97 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the search of registered users, for servers
+// with too many registrations to list them all at once.
+//
+// Users are matched by a case-insensitive substring or prefix of their
+// name, sorted by id, name or the time they were last seen, and
+// returned a page at a time.
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// A userQuery selects, sorts and pages registered users.
+type userQuery struct {
+	// Only users whose name contains Name, or starts with Prefix,
+	// ignoring case, are matched.
+	Name   string
+	Prefix string
+	// Sort is id (the default), name or last_seen. Desc reverses
+	// the order.
+	Sort string
+	Desc bool
+	// The number of matching users skipped, and the number returned
+	// at most, or 0 for all of them.
+	Offset int
+	Limit  int
+}
+
+var errInvalidUserSort = errors.New("invalid sort")
+
+// userLess returns the ordering of users for a sort key.
+func userLess(key string) (func(a, b *User) bool, error) {
+	switch key {
+	case "", "id":
+		return func(a, b *User) bool { return a.Id < b.Id }, nil
+	case "name":
+		return func(a, b *User) bool {
+			an, bn := strings.ToLower(a.Name), strings.ToLower(b.Name)
+			if an != bn {
+				return an < bn
+			}
+			return a.Id < b.Id
+		}, nil
+	case "last_seen":
+		return func(a, b *User) bool {
+			if a.LastActive != b.LastActive {
+				return a.LastActive < b.LastActive
+			}
+			return a.Id < b.Id
+		}, nil
+	}
+	return nil, errInvalidUserSort
+}
+
+// searchUsers returns a page of the registered users matching q, and
+// the number of matching users in all.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) searchUsers(q userQuery) (users []*User, total int, err error) {
+	less, err := userLess(q.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	name := strings.ToLower(q.Name)
+	prefix := strings.ToLower(q.Prefix)
+
+	for _, user := range server.Users {
+		lower := strings.ToLower(user.Name)
+		if !strings.Contains(lower, name) || !strings.HasPrefix(lower, prefix) {
+			continue
+		}
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if q.Desc {
+			return less(users[j], users[i])
+		}
+		return less(users[i], users[j])
+	})
+
+	total = len(users)
+	if q.Offset >= total {
+		return nil, total, nil
+	}
+	users = users[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(users) {
+		users = users[:q.Limit]
+	}
+	return users, total, nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"testing"
+
+	"mumble.info/grumble/pkg/logtarget"
+)
+
+// The number of listeners in the benchmarks' channels.
+var benchListeners = []int{1, 10, 100}
+
+// A 20ms Opus frame, as sent by a client: header, session, sequence
+// number, size and payload.
+var benchVoice = append([]byte{0x80, 0x01, 0x01, 0x48}, make([]byte, 72)...)
+
+// newBenchServer sets up a server with a speaker and listeners in the
+// root channel. Their voice is queued in the server's udpBatch, which
+// is never flushed, so no sockets are needed.
+func newBenchServer(b *testing.B, listeners int) (*Server, *Client) {
+	server, err := NewServer(1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	server.Logger = log.New(logtarget.Default, "", 0)
+	server.clients = make(map[uint32]*Client)
+	server.talkers = make(map[uint32]*Client)
+	server.udpout = newUDPBatch()
+
+	conn := &net.UDPConn{}
+	var speaker *Client
+	for i := 0; i <= listeners; i++ {
+		client := &Client{
+			Logger:       log.New(logtarget.Default, "", 0),
+			server:       server,
+			session:      uint32(i + 1),
+			udpconn:      conn,
+			udpaddr:      &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 10000 + i},
+			voiceTargets: make(map[uint32]*VoiceTarget),
+			state:        StateClientReady,
+		}
+		if err := client.crypt.GenerateKey("OCB2-AES128"); err != nil {
+			b.Fatal(err)
+		}
+		client.setUDP(true)
+		server.clients[client.Session()] = client
+		server.RootChannel().AddClient(client)
+		if speaker == nil {
+			speaker = client
+		}
+	}
+	return server, speaker
+}
+
+// resetBatch drops the datagrams queued by the previous iteration,
+// and returns how many there were.
+func resetBatch(server *Server) int {
+	queued := 0
+	for conn, dgrams := range server.udpout.pending {
+		queued += len(dgrams)
+		server.udpout.pending[conn] = dgrams[:0]
+	}
+	return queued
+}
+
+// checkListeners sends one packet with send, and fails b unless it
+// reached every listener.
+func checkListeners(b *testing.B, server *Server, listeners int, send func()) {
+	send()
+	if queued := resetBatch(server); queued != listeners {
+		b.Fatalf("voice reached %v listeners, want %v", queued, listeners)
+	}
+}
+
+// BenchmarkHandleVoiceBroadcast measures relaying a voice packet to
+// the speaker's channel, including encrypting it for each listener.
+func BenchmarkHandleVoiceBroadcast(b *testing.B) {
+	for _, n := range benchListeners {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			server, speaker := newBenchServer(b, n)
+			checkListeners(b, server, n, func() {
+				server.handleVoiceBroadcast(&VoiceBroadcast{client: speaker, buf: benchVoice})
+			})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				server.handleVoiceBroadcast(&VoiceBroadcast{client: speaker, buf: benchVoice})
+				resetBatch(server)
+			}
+		})
+	}
+}
+
+// BenchmarkSendVoiceBroadcast measures whispering to a voice target,
+// both with the target's cached recipients and when they must be
+// worked out again, as after every channel or ACL change.
+func BenchmarkSendVoiceBroadcast(b *testing.B) {
+	targets := []struct {
+		name string
+		add  func(vt *VoiceTarget, server *Server)
+	}{
+		{"sessions", func(vt *VoiceTarget, server *Server) {
+			for session := range server.RootChannel().clients {
+				vt.AddSession(session)
+			}
+		}},
+		{"channel", func(vt *VoiceTarget, server *Server) {
+			vt.AddChannel(0, false, false, "")
+		}},
+	}
+	for _, target := range targets {
+		for _, n := range benchListeners {
+			for _, cached := range []bool{true, false} {
+				name := fmt.Sprintf("%s/%d/uncached", target.name, n)
+				if cached {
+					name = fmt.Sprintf("%s/%d/cached", target.name, n)
+				}
+				b.Run(name, func(b *testing.B) {
+					server, speaker := newBenchServer(b, n)
+					vt := &VoiceTarget{}
+					target.add(vt, server)
+					speaker.voiceTargets[1] = vt
+					checkListeners(b, server, n, func() {
+						vt.SendVoiceBroadcast(&VoiceBroadcast{client: speaker, target: 1, buf: benchVoice})
+					})
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						if !cached {
+							vt.ClearCache()
+						}
+						vt.SendVoiceBroadcast(&VoiceBroadcast{client: speaker, target: 1, buf: benchVoice})
+						resetBatch(server)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the packet loss statistics of clients' voice.
+//
+// The crypt state's good, late and lost counts only see UDP packets,
+// and count every packet alike. Here the server instead follows the
+// sequence numbers of the audio frames a client sends, over UDP or
+// tunneled, and counts the frames that went missing or arrived out of
+// order within a talk spurt. A single lost packet is covered up by the
+// decoder (Opus' in-band FEC and packet loss concealment); two or more
+// in a row are heard, so those gaps are counted separately as lost
+// bursts. The interarrival jitter is estimated as in RFC 3550.
+//
+// The statistics are sent in the Grumble-only fields of UserStats, and
+// served as metrics. If VoiceLossWarning is set, a client that loses
+// more than that percentage of its voice is told so, now and then.
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// The duration of one step of a voice packet's sequence number. Mumble
+// clients count sequence numbers in 10 ms frames.
+const voiceFrameDuration = 10 * time.Millisecond
+
+// A pause in a client's voice longer than this starts a new talk spurt.
+const voiceSpurtGap = time.Second
+
+// Gaps of more packets than this in the sequence numbers of a talk
+// spurt are taken for a restart of the client's counter, not for loss.
+const maxVoiceGap = 50
+
+// The number of packets a client's loss is judged on for the warning.
+const voiceLossSample = 500
+
+// The shortest time between two warnings about a client's voice loss.
+const voiceLossWarningInterval = 10 * time.Minute
+
+// voiceStats follows the sequence numbers of a client's voice packets.
+// It is updated from the client's receiver goroutines, and read from
+// the server's handler goroutine, so its fields must only be accessed
+// with mutex held.
+type voiceStats struct {
+	mutex sync.Mutex
+
+	good       uint32
+	late       uint32
+	lost       uint32
+	lostBursts uint32
+	// The interarrival jitter, in seconds.
+	jitter float64
+
+	// The state of the current talk spurt.
+	lastSeq     uint64
+	lastArrival time.Time
+	// The smallest step between sequence numbers seen, which is the
+	// number of frames per packet.
+	step uint64
+
+	// The counts since the loss was last judged for the warning.
+	sampleGood  uint32
+	sampleLost  uint32
+	lastWarning time.Time
+}
+
+// add counts a voice packet with sequence number seq arriving at now.
+func (vs *voiceStats) add(now time.Time, seq uint64) {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	if vs.lastArrival.IsZero() || now.Sub(vs.lastArrival) > voiceSpurtGap {
+		vs.startSpurt(now, seq)
+		return
+	}
+	if seq <= vs.lastSeq {
+		// A packet that was counted as lost came in after all.
+		vs.late++
+		if vs.lost > 0 {
+			vs.lost--
+		}
+		if vs.sampleLost > 0 {
+			vs.sampleLost--
+		}
+		return
+	}
+
+	delta := seq - vs.lastSeq
+	if vs.step == 0 || delta < vs.step {
+		vs.step = delta
+	}
+	missing := delta/vs.step - 1
+	if missing > maxVoiceGap {
+		vs.startSpurt(now, seq)
+		return
+	}
+	vs.lost += uint32(missing)
+	vs.sampleLost += uint32(missing)
+	if missing >= 2 {
+		vs.lostBursts++
+	}
+
+	transit := now.Sub(vs.lastArrival) - time.Duration(delta)*voiceFrameDuration
+	vs.jitter += (math.Abs(transit.Seconds()) - vs.jitter) / 16
+
+	vs.lastSeq = seq
+	vs.lastArrival = now
+	vs.good++
+	vs.sampleGood++
+}
+
+// startSpurt starts a new talk spurt with the packet seq.
+func (vs *voiceStats) startSpurt(now time.Time, seq uint64) {
+	vs.lastSeq = seq
+	vs.lastArrival = now
+	vs.good++
+	vs.sampleGood++
+}
+
+// voiceStatsSnapshot is a copy of a client's voice statistics.
+type voiceStatsSnapshot struct {
+	good       uint32
+	late       uint32
+	lost       uint32
+	lostBursts uint32
+	// The jitter in milliseconds.
+	jitter float64
+}
+
+func (vs *voiceStats) snapshot() voiceStatsSnapshot {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+	return voiceStatsSnapshot{
+		good:       vs.good,
+		late:       vs.late,
+		lost:       vs.lost,
+		lostBursts: vs.lostBursts,
+		jitter:     vs.jitter * 1000,
+	}
+}
+
+// sampleLoss returns the percentage of packets lost since it last
+// returned ok, once there are enough packets to tell. A warning is due
+// if it wasn't given within voiceLossWarningInterval.
+func (vs *voiceStats) sampleLoss(now time.Time) (loss float64, warn bool, ok bool) {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+	total := vs.sampleGood + vs.sampleLost
+	if total < voiceLossSample {
+		return 0, false, false
+	}
+	loss = float64(vs.sampleLost) * 100 / float64(total)
+	vs.sampleGood = 0
+	vs.sampleLost = 0
+	warn = now.Sub(vs.lastWarning) >= voiceLossWarningInterval
+	return loss, warn, true
+}
+
+// checkVoiceLoss warns clients that lose more than VoiceLossWarning
+// percent of their voice packets.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) checkVoiceLoss() {
+	threshold := float64(server.cfg.IntValue("VoiceLossWarning"))
+	if threshold <= 0 {
+		return
+	}
+	now := time.Now()
+	for _, client := range server.clients {
+		if client.state != StateClientReady {
+			continue
+		}
+		loss, warn, ok := client.voiceStats.sampleLoss(now)
+		if !ok || loss <= threshold || !warn {
+			continue
+		}
+		client.voiceStats.mutex.Lock()
+		client.voiceStats.lastWarning = now
+		client.voiceStats.mutex.Unlock()
+		client.Printf("Losing %.1f%% of voice packets", loss)
+		server.sendServerText(client, fmt.Sprintf("The server is missing %.0f%% of your voice packets, so others may hear you break up. Check your network connection, or lower your audio quality.", loss))
+	}
+}
@@ -11,6 +11,11 @@ import "mumble.info/grumble/pkg/acl"
 type VoiceTarget struct {
 	sessions []uint32
 	channels []voiceTargetChannel
+	// Registered users, reached in whichever session they are
+	// connected with.
+	users []uint32
+	// The whisper preset the target was bound from, or 0.
+	preset uint32
 
 	directCache       map[uint32]*Client
 	fromChannelsCache map[uint32]*Client
@@ -42,9 +47,14 @@ func (vt *VoiceTarget) AddChannel(id uint32, subchannels bool, links bool, group
 	})
 }
 
+// AddUser adds a registered user to the VoiceTarget.
+func (vt *VoiceTarget) AddUser(id uint32) {
+	vt.users = append(vt.users, id)
+}
+
 // IsEmpty checks whether the VoiceTarget is empty (has no targets)
 func (vt *VoiceTarget) IsEmpty() bool {
-	return len(vt.sessions) == 0 && len(vt.channels) == 0
+	return len(vt.sessions) == 0 && len(vt.channels) == 0 && len(vt.users) == 0
 }
 
 // ClearCache clears the VoiceTarget's cache.
@@ -56,7 +66,6 @@ func (vt *VoiceTarget) ClearCache() {
 // Send the contents of the VoiceBroadcast to all targets specified in the
 // VoiceTarget.
 func (vt *VoiceTarget) SendVoiceBroadcast(vb *VoiceBroadcast) {
-	buf := vb.buf
 	client := vb.client
 	server := client.server
 
@@ -113,6 +122,20 @@ func (vt *VoiceTarget) SendVoiceBroadcast(vb *VoiceBroadcast) {
 				}
 			}
 		}
+		if len(vt.users) > 0 {
+			users := make(map[uint32]bool)
+			for _, id := range vt.users {
+				users[id] = true
+			}
+			for _, target := range server.clients {
+				if !target.IsRegistered() || !users[uint32(target.UserId())] {
+					continue
+				}
+				if _, alreadyInFromChannels := fromChannels[target.Session()]; !alreadyInFromChannels {
+					direct[target.Session()] = target
+				}
+			}
+		}
 
 		// Make sure we don't send to ourselves.
 		delete(direct, client.Session())
@@ -127,12 +150,12 @@ func (vt *VoiceTarget) SendVoiceBroadcast(vb *VoiceBroadcast) {
 		}
 	}
 
-	kind := buf[0] & 0xe0
-
 	if len(fromChannels) > 0 {
 		for _, target := range fromChannels {
-			buf[0] = kind | 2
-			err := target.SendUDP(buf)
+			if target.Channel.NoVoice {
+				continue
+			}
+			err := target.queueUDP(server.udpout, vb.packet(voiceContextShout))
 			if err != nil {
 				target.Panicf("Unable to send UDP packet: %v", err.Error())
 			}
@@ -141,9 +164,10 @@ func (vt *VoiceTarget) SendVoiceBroadcast(vb *VoiceBroadcast) {
 
 	if len(direct) > 0 {
 		for _, target := range direct {
-			buf[0] = kind | 2
-			target.SendUDP(buf)
-			err := target.SendUDP(buf)
+			if target.Channel.NoVoice {
+				continue
+			}
+			err := target.queueUDP(server.udpout, vb.packet(voiceContextWhisper))
 			if err != nil {
 				target.Panicf("Unable to send UDP packet: %v", err.Error())
 			}
@@ -0,0 +1,136 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements the waiting room.
+//
+// When the server holds MaxUsers clients, clients that connect are
+// rejected, or, if QueueChannel is set, put in that channel to wait.
+// With a QueueChannel, the same happens when the channel a client would
+// enter holds MaxChannelUsers clients. Waiting clients are suppressed
+// and can't leave the channel by themselves. They are admitted in the
+// order they arrived as soon as there is room for them.
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// queueChannel returns the channel clients wait in, or nil if the
+// waiting room is disabled.
+func (server *Server) queueChannel() *Channel {
+	id := server.cfg.IntValue("QueueChannel")
+	if id <= 0 {
+		return nil
+	}
+	return server.Channels[id]
+}
+
+// serverFull checks whether the server holds as many admitted clients
+// as it allows. SuperUser doesn't count towards the limit.
+func (server *Server) serverFull() bool {
+	max := server.cfg.IntValue("MaxUsers")
+	if max <= 0 {
+		return false
+	}
+	admitted := 0
+	for _, client := range server.clients {
+		if !client.queued && !client.IsSuperUser() {
+			admitted++
+		}
+	}
+	return admitted >= max
+}
+
+// channelFull checks whether channel holds as many clients as it allows.
+// SuperUser doesn't count towards the limit.
+func (server *Server) channelFull(channel *Channel) bool {
+	max := channel.MaxUsers
+	if max <= 0 {
+		max = server.cfg.IntValue("MaxChannelUsers")
+	}
+	if max <= 0 {
+		return false
+	}
+	occupants := 0
+	for _, client := range channel.clients {
+		if !client.IsSuperUser() {
+			occupants++
+		}
+	}
+	return occupants >= max
+}
+
+// entryChannel returns the channel client enters when it connects:
+// its last channel if it is registered, or the root channel.
+func (server *Server) entryChannel(client *Client) *Channel {
+	if client.IsRegistered() {
+		lastChannel := server.Channels[client.user.LastChannelId]
+		if lastChannel != nil && lastChannel != server.queueChannel() {
+			return lastChannel
+		}
+	}
+	return server.RootChannel()
+}
+
+// enqueue puts client in the waiting room, to enter target once it is
+// admitted.
+func (server *Server) enqueue(client *Client, target *Channel) {
+	client.queued = true
+	client.queueTarget = target.Id
+	server.queue = append(server.queue, client)
+	client.Printf("Waiting for room in channel %v", target.Id)
+}
+
+// sendQueuePosition tells a waiting client its place in the queue.
+func (server *Server) sendQueuePosition(client *Client) {
+	position := 0
+	for _, queued := range server.queue {
+		if queued.queued {
+			position++
+		}
+		if queued == client {
+			break
+		}
+	}
+	server.sendServerText(client, fmt.Sprintf("The server is full. You are number %v in the queue, and will be let in when there is room.", position))
+}
+
+// admitQueued admits the waiting clients there is room for, in the
+// order they arrived.
+func (server *Server) admitQueued() {
+	if len(server.queue) == 0 {
+		return
+	}
+	kept := server.queue[:0]
+	for _, client := range server.queue {
+		if !client.queued || server.clients[client.Session()] != client {
+			continue
+		}
+		channel, ok := server.Channels[client.queueTarget]
+		if !ok {
+			channel = server.RootChannel()
+		}
+		if server.serverFull() || server.channelFull(channel) {
+			kept = append(kept, client)
+			continue
+		}
+
+		client.queued = false
+		userstate := &mumbleproto.UserState{
+			Session:   proto.Uint32(client.Session()),
+			ChannelId: proto.Uint32(uint32(channel.Id)),
+		}
+		server.userEnterChannel(client, channel, userstate)
+		if err := server.broadcastProtoMessage(userstate); err != nil {
+			server.Printf("Unable to broadcast UserState: %v", err)
+		}
+		client.Printf("Admitted from the queue")
+		server.sendServerText(client, "Welcome! You have been let in.")
+	}
+	server.queue = kept
+}
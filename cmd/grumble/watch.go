@@ -0,0 +1,133 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements watch lists.
+//
+// Registered users can keep a list of other registered users they want
+// to hear about, stored with their registration. When a user on the
+// list connects, the server tells each watcher that is connected with
+// a text message. The list is managed with the /watch chat command.
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+// The most users a user can watch.
+const maxWatches = 100
+
+// watches checks whether user watches the user with the given id.
+func (user *User) watches(id uint32) bool {
+	for _, watched := range user.Watches {
+		if watched == id {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyWatchers tells the connected users that watch the user of
+// client that it connected.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) notifyWatchers(client *Client) {
+	if !client.IsRegistered() {
+		return
+	}
+	text := fmt.Sprintf("%v is now online", html.EscapeString(client.user.Name))
+	for _, watcher := range server.clients {
+		if watcher == client || watcher.user == nil || watcher.state != StateClientReady {
+			continue
+		}
+		if watcher.user.watches(client.user.Id) {
+			server.sendServerText(watcher, text)
+		}
+	}
+}
+
+func init() {
+	registerChatCommand("watch", &chatCommand{
+		usage: []string{
+			"watch add <user>",
+			"watch remove <user>",
+			"watch list",
+		},
+		help:       "Be told when other users connect",
+		registered: true,
+		run:        (*Server).watchCommand,
+	})
+}
+
+// watchCommand implements the /watch chat command:
+//
+//	/watch add <user>
+//	/watch remove <user>
+//	/watch list
+//
+// The command manages the watch list of the sender.
+func (server *Server) watchCommand(client *Client, args []string, text string) {
+	user := client.user
+
+	switch {
+	case len(args) == 3 && args[1] == "add":
+		watched, ok := server.registeredUser(args[2])
+		if !ok {
+			server.sendServerText(client, fmt.Sprintf("No registered user named %v", html.EscapeString(args[2])))
+			return
+		}
+		if watched == user || user.watches(watched.Id) {
+			server.sendServerText(client, fmt.Sprintf("Already watching %v", html.EscapeString(watched.Name)))
+			return
+		}
+		if len(user.Watches) >= maxWatches {
+			server.sendServerText(client, fmt.Sprintf("You can watch at most %v users", maxWatches))
+			return
+		}
+		user.Watches = append(user.Watches, watched.Id)
+		server.UpdateFrozenUserRecord(user)
+		server.sendServerText(client, fmt.Sprintf("Watching %v", html.EscapeString(watched.Name)))
+	case len(args) == 3 && args[1] == "remove":
+		watched, ok := server.registeredUser(args[2])
+		if !ok || !user.watches(watched.Id) {
+			server.sendServerText(client, fmt.Sprintf("Not watching %v", html.EscapeString(args[2])))
+			return
+		}
+		watches := []uint32{}
+		for _, id := range user.Watches {
+			if id != watched.Id {
+				watches = append(watches, id)
+			}
+		}
+		user.Watches = watches
+		server.UpdateFrozenUserRecord(user)
+		server.sendServerText(client, fmt.Sprintf("No longer watching %v", html.EscapeString(watched.Name)))
+	case len(args) == 1 || len(args) == 2 && args[1] == "list":
+		online := server.onlineUsers()
+		var lines []string
+		for _, id := range user.Watches {
+			// Registrations may have been removed since.
+			watched, ok := server.Users[id]
+			if !ok {
+				continue
+			}
+			line := html.EscapeString(watched.Name)
+			if online[watched] != nil {
+				line += " (online)"
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) == 0 {
+			server.sendServerText(client, "You aren't watching anyone")
+			return
+		}
+		sort.Strings(lines)
+		server.sendServerText(client, strings.Join(lines, "<br />"))
+	default:
+		server.sendChatCommandUsage(client, args[0])
+	}
+}
@@ -0,0 +1,489 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file implements whisper presets, and keeps registered users'
+// voice targets across reconnects.
+//
+// A whisper preset is a voice target defined by the admins, such as
+// "all moderators": the members of a group in a channel tree, or some
+// registered users. Clients are offered a server context action for
+// each preset, which binds it to the preset's voice target slot, and
+// the /whisper chat command binds a preset to any slot. A slot bound
+// to a preset follows the preset's changes, and is cleared when the
+// preset is deleted. Whispering through a preset still takes whisper
+// permission in the channels it reaches.
+//
+// The voice targets of registered users, their own and those bound
+// to presets, are stored with their registration and restored when
+// they connect again. Direct targets are stored as the user ids of
+// registered users, and so reach those users in whichever session
+// they are connected with; direct targets that aren't registered are
+// not stored.
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The prefix of the context actions that bind whisper presets.
+const whisperPresetActionPrefix = "whisperpreset:"
+
+// The highest voice target slot; 0x1f is the server loopback.
+const maxVoiceTargetSlot = 0x1e
+
+// A WhisperPreset is a voice target defined by the admins.
+type WhisperPreset struct {
+	Id   uint32
+	Name string
+	// The voice target slot the preset's context action binds it to
+	Slot     uint32
+	Channels []voiceTargetChannel
+	// The registered users whispered to directly
+	Users   []uint32
+	Created int64
+}
+
+// voiceTarget returns a voice target that whispers to the preset's
+// targets.
+func (preset *WhisperPreset) voiceTarget() *VoiceTarget {
+	return &VoiceTarget{
+		channels: append([]voiceTargetChannel(nil), preset.Channels...),
+		users:    append([]uint32(nil), preset.Users...),
+		preset:   preset.Id,
+	}
+}
+
+// whisperPreset looks up a preset by name, ignoring case.
+func (server *Server) whisperPreset(name string) (*WhisperPreset, bool) {
+	for _, preset := range server.WhisperPresets {
+		if strings.EqualFold(preset.Name, name) {
+			return preset, true
+		}
+	}
+	return nil, false
+}
+
+// bindWhisperPreset binds preset to the client's voice target slot.
+func (server *Server) bindWhisperPreset(client *Client, preset *WhisperPreset, slot uint32) {
+	client.voiceTargets[slot] = preset.voiceTarget()
+	server.saveVoiceTargets(client)
+	server.sendServerText(client, fmt.Sprintf("Voice target %v now whispers to %v", slot, html.EscapeString(preset.Name)))
+}
+
+// rebindWhisperPreset updates the voice target slots bound to the preset
+// with the given id after it was changed, or clears them if it was
+// deleted.
+func (server *Server) rebindWhisperPreset(id uint32) {
+	preset, exists := server.WhisperPresets[id]
+	for _, client := range server.clients {
+		changed := false
+		for slot, vt := range client.voiceTargets {
+			if vt.preset != id {
+				continue
+			}
+			if exists {
+				client.voiceTargets[slot] = preset.voiceTarget()
+			} else {
+				delete(client.voiceTargets, slot)
+			}
+			changed = true
+		}
+		if changed {
+			server.saveVoiceTargets(client)
+		}
+	}
+}
+
+// saveVoiceTargets stores the voice targets of a registered client
+// with its registration, if they changed.
+func (server *Server) saveVoiceTargets(client *Client) {
+	user := client.user
+	if user == nil {
+		return
+	}
+	var saved []UserVoiceTarget
+	for slot, vt := range client.voiceTargets {
+		uvt := UserVoiceTarget{Id: slot, Preset: vt.preset}
+		if vt.preset == 0 {
+			uvt.Channels = append([]voiceTargetChannel(nil), vt.channels...)
+			uvt.Users = append([]uint32(nil), vt.users...)
+			for _, session := range vt.sessions {
+				if target, ok := server.clients[session]; ok && target.IsRegistered() {
+					uvt.Users = append(uvt.Users, uint32(target.UserId()))
+				}
+			}
+		}
+		saved = append(saved, uvt)
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].Id < saved[j].Id })
+	if reflect.DeepEqual(saved, user.VoiceTargets) {
+		return
+	}
+	user.VoiceTargets = saved
+	server.UpdateFrozenUserRecord(user)
+}
+
+// restoreVoiceTargets gives a registered client the voice targets
+// stored with its registration. Targets bound to presets that were
+// deleted since are left out.
+func (server *Server) restoreVoiceTargets(client *Client) {
+	if client.user == nil {
+		return
+	}
+	for _, uvt := range client.user.VoiceTargets {
+		if uvt.Preset != 0 {
+			if preset, ok := server.WhisperPresets[uvt.Preset]; ok {
+				client.voiceTargets[uvt.Id] = preset.voiceTarget()
+			}
+			continue
+		}
+		client.voiceTargets[uvt.Id] = &VoiceTarget{
+			channels: append([]voiceTargetChannel(nil), uvt.Channels...),
+			users:    append([]uint32(nil), uvt.Users...),
+		}
+	}
+}
+
+// whisperPresetAction returns the name of the context action that
+// binds the preset with the given id.
+func whisperPresetAction(id uint32) string {
+	return whisperPresetActionPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+// sendWhisperPresetAction offers client the context action that binds
+// preset.
+func (server *Server) sendWhisperPresetAction(client *Client, preset *WhisperPreset) {
+	client.sendMessage(&mumbleproto.ContextActionModify{
+		Action:    proto.String(whisperPresetAction(preset.Id)),
+		Text:      proto.String(fmt.Sprintf("Whisper to %v", preset.Name)),
+		Context:   proto.Uint32(uint32(mumbleproto.ContextActionModify_Server)),
+		Operation: mumbleproto.ContextActionModify_Add.Enum(),
+	})
+}
+
+// sendWhisperPresetActions offers client the context actions that bind
+// whisper presets.
+func (server *Server) sendWhisperPresetActions(client *Client) {
+	for _, preset := range server.WhisperPresets {
+		server.sendWhisperPresetAction(client, preset)
+	}
+}
+
+// updateWhisperPresetActions replaces the context action of the preset
+// with the given id in the connected clients.
+func (server *Server) updateWhisperPresetActions(id uint32) {
+	preset, exists := server.WhisperPresets[id]
+	for _, client := range server.clients {
+		if client.state != StateClientReady {
+			continue
+		}
+		client.sendMessage(&mumbleproto.ContextActionModify{
+			Action:    proto.String(whisperPresetAction(id)),
+			Operation: mumbleproto.ContextActionModify_Remove.Enum(),
+		})
+		if exists {
+			server.sendWhisperPresetAction(client, preset)
+		}
+	}
+}
+
+// handleWhisperPresetAction handles the context actions that bind
+// whisper presets.
+func (server *Server) handleWhisperPresetAction(client *Client, action *mumbleproto.ContextAction) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(action.GetAction(), whisperPresetActionPrefix), 10, 32)
+	if err != nil {
+		return
+	}
+	preset, ok := server.WhisperPresets[uint32(id)]
+	if !ok {
+		return
+	}
+	server.bindWhisperPreset(client, preset, preset.Slot)
+}
+
+func init() {
+	registerChatCommand("whisper", &chatCommand{
+		usage: []string{"whisper", "whisper <preset> [slot]"},
+		help:  "List the whisper presets, or bind one to a voice target",
+		run:   (*Server).whisperCommand,
+	})
+	registerAPIEndpoint("whisperpresets", handleAPIWhisperPresets)
+}
+
+// whisperCommand implements the /whisper chat command, which lists the
+// whisper presets, or binds one to a voice target slot. The slot
+// defaults to the preset's.
+func (server *Server) whisperCommand(client *Client, args []string, text string) {
+	if len(args) == 1 {
+		var presets []*WhisperPreset
+		for _, preset := range server.WhisperPresets {
+			presets = append(presets, preset)
+		}
+		if len(presets) == 0 {
+			server.sendServerText(client, "There are no whisper presets")
+			return
+		}
+		sort.Slice(presets, func(i, j int) bool { return presets[i].Slot < presets[j].Slot })
+		lines := []string{"Whisper presets:"}
+		for _, preset := range presets {
+			lines = append(lines, fmt.Sprintf("<b>%v</b> (voice target %v)", html.EscapeString(preset.Name), preset.Slot))
+		}
+		server.sendServerText(client, strings.Join(lines, "<br />"))
+		return
+	}
+
+	// Preset names may contain spaces; a number at the end is the
+	// slot.
+	name := chatCommandRest(text, 1)
+	preset, ok := server.whisperPreset(name)
+	slot := uint64(0)
+	if !ok && len(args) > 2 {
+		var err error
+		slot, err = strconv.ParseUint(args[len(args)-1], 10, 32)
+		if err == nil {
+			name = strings.TrimSpace(strings.TrimSuffix(name, args[len(args)-1]))
+			preset, ok = server.whisperPreset(name)
+			if ok && (slot < 1 || slot > maxVoiceTargetSlot) {
+				server.sendServerText(client, fmt.Sprintf("Voice targets go from 1 to %v", maxVoiceTargetSlot))
+				return
+			}
+		}
+	}
+	if !ok {
+		server.sendServerText(client, fmt.Sprintf("No whisper preset named %v", html.EscapeString(name)))
+		return
+	}
+	if slot == 0 {
+		slot = uint64(preset.Slot)
+	}
+	server.bindWhisperPreset(client, preset, uint32(slot))
+}
+
+// apiWhisperChannel is the JSON representation of a voiceTargetChannel.
+type apiWhisperChannel struct {
+	Channel  uint32 `json:"channel"`
+	Children bool   `json:"children"`
+	Links    bool   `json:"links"`
+	Group    string `json:"group,omitempty"`
+}
+
+// apiWhisperPreset is the JSON representation of a WhisperPreset.
+type apiWhisperPreset struct {
+	Id       uint32              `json:"id"`
+	Name     string              `json:"name"`
+	Slot     uint32              `json:"slot"`
+	Channels []apiWhisperChannel `json:"channels"`
+	Users    []uint32            `json:"users"`
+	Created  string              `json:"created"`
+}
+
+func (preset *WhisperPreset) apiWhisperPreset() apiWhisperPreset {
+	ap := apiWhisperPreset{
+		Id:       preset.Id,
+		Name:     preset.Name,
+		Slot:     preset.Slot,
+		Channels: []apiWhisperChannel{},
+		Users:    append([]uint32{}, preset.Users...),
+		Created:  time.Unix(preset.Created, 0).UTC().Format(time.RFC3339),
+	}
+	for _, vtc := range preset.Channels {
+		ap.Channels = append(ap.Channels, apiWhisperChannel{
+			Channel:  vtc.id,
+			Children: vtc.subChannels,
+			Links:    vtc.links,
+			Group:    vtc.onlyGroup,
+		})
+	}
+	return ap
+}
+
+// handleAPIWhisperPresets implements /servers/<id>/whisperpresets.
+//
+//	GET     lists the presets; .../whisperpresets/<id> shows one
+//	POST    creates a preset:
+//	        {"name": "all moderators", "slot": 5,
+//	         "channels": [{"channel": 0, "children": true, "group": "moderators"}],
+//	         "users": [3]}
+//	PUT     .../whisperpresets/<id> changes the fields given
+//	DELETE  .../whisperpresets/<id> deletes a preset
+func handleAPIWhisperPresets(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	var id uint64
+	if len(args) > 0 {
+		var err error
+		id, err = strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			apiError(w, http.StatusBadRequest, "invalid preset")
+			return
+		}
+	}
+	var req struct {
+		Name     *string              `json:"name"`
+		Slot     *uint32              `json:"slot"`
+		Channels *[]apiWhisperChannel `json:"channels"`
+		Users    *[]uint32            `json:"users"`
+	}
+	switch {
+	case len(args) > 1:
+		apiError(w, http.StatusNotFound, "not found")
+		return
+	case r.Method == http.MethodGet:
+	case r.Method == http.MethodPost && len(args) == 0:
+		if !readJSON(w, r, &req) {
+			return
+		}
+		if req.Name == nil || req.Slot == nil {
+			apiError(w, http.StatusBadRequest, "name and slot are required")
+			return
+		}
+	case r.Method == http.MethodPut && len(args) == 1:
+		if !readJSON(w, r, &req) {
+			return
+		}
+	case r.Method == http.MethodDelete && len(args) == 1:
+	default:
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if req.Name != nil && len(strings.TrimSpace(*req.Name)) == 0 {
+		apiError(w, http.StatusBadRequest, "name must not be empty")
+		return
+	}
+	if req.Slot != nil && (*req.Slot < 1 || *req.Slot > maxVoiceTargetSlot) {
+		apiError(w, http.StatusBadRequest, fmt.Sprintf("slot must be between 1 and %v", maxVoiceTargetSlot))
+		return
+	}
+
+	status := http.StatusOK
+	var reply interface{}
+	err := server.runSync(func() {
+		if r.Method == http.MethodGet && len(args) == 0 {
+			presets := []apiWhisperPreset{}
+			for _, preset := range server.WhisperPresets {
+				presets = append(presets, preset.apiWhisperPreset())
+			}
+			sort.Slice(presets, func(i, j int) bool { return presets[i].Id < presets[j].Id })
+			reply = presets
+			return
+		}
+
+		var preset *WhisperPreset
+		if r.Method == http.MethodPost {
+			preset = &WhisperPreset{Created: time.Now().Unix()}
+		} else {
+			var ok bool
+			preset, ok = server.WhisperPresets[uint32(id)]
+			if !ok {
+				status, reply = http.StatusNotFound, map[string]string{"error": "no such preset"}
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			reply = preset.apiWhisperPreset()
+			return
+		case http.MethodDelete:
+			delete(server.WhisperPresets, preset.Id)
+			server.DeleteFrozenWhisperPreset(preset.Id)
+			server.rebindWhisperPreset(preset.Id)
+			server.updateWhisperPresetActions(preset.Id)
+			server.auditAPI(auditlog.Entry{
+				Action:  "whisperpreset.delete",
+				Target:  preset.Name,
+				Details: fmt.Sprintf("preset %v", preset.Id),
+			})
+			status = http.StatusNoContent
+			return
+		}
+
+		if req.Name != nil {
+			name := strings.TrimSpace(*req.Name)
+			if other, ok := server.whisperPreset(name); ok && other != preset {
+				status, reply = http.StatusConflict, map[string]string{"error": "a preset with that name exists"}
+				return
+			}
+		}
+		var channels []voiceTargetChannel
+		if req.Channels != nil {
+			for _, ac := range *req.Channels {
+				if _, ok := server.Channels[int(ac.Channel)]; !ok {
+					status, reply = http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("no such channel: %v", ac.Channel)}
+					return
+				}
+				channels = append(channels, voiceTargetChannel{
+					id:          ac.Channel,
+					subChannels: ac.Children,
+					links:       ac.Links,
+					onlyGroup:   ac.Group,
+				})
+			}
+		}
+		if req.Users != nil {
+			for _, userId := range *req.Users {
+				if _, ok := server.Users[userId]; !ok {
+					status, reply = http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("no such user: %v", userId)}
+					return
+				}
+			}
+		}
+		emptied := (req.Channels != nil && len(channels) == 0 || req.Channels == nil && len(preset.Channels) == 0) &&
+			(req.Users != nil && len(*req.Users) == 0 || req.Users == nil && len(preset.Users) == 0)
+		if emptied {
+			status, reply = http.StatusBadRequest, map[string]string{"error": "a preset needs channels or users"}
+			return
+		}
+
+		action := "whisperpreset.update"
+		if r.Method == http.MethodPost {
+			preset.Id = server.nextWhisperPresetId
+			server.nextWhisperPresetId++
+			server.WhisperPresets[preset.Id] = preset
+			action = "whisperpreset.create"
+			status = http.StatusCreated
+		}
+		if req.Name != nil {
+			preset.Name = strings.TrimSpace(*req.Name)
+		}
+		if req.Slot != nil {
+			preset.Slot = *req.Slot
+		}
+		if req.Channels != nil {
+			preset.Channels = channels
+		}
+		if req.Users != nil {
+			preset.Users = append([]uint32(nil), *req.Users...)
+		}
+		server.UpdateFrozenWhisperPreset(preset)
+		server.rebindWhisperPreset(preset.Id)
+		server.updateWhisperPresetActions(preset.Id)
+		server.auditAPI(auditlog.Entry{
+			Action:  action,
+			Target:  preset.Name,
+			Details: fmt.Sprintf("preset %v slot %v", preset.Id, preset.Slot),
+		})
+		reply = preset.apiWhisperPreset()
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, reply)
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package main
+
+// This file hooks the word filter (see pkg/wordfilter) into text chat.
+//
+// The rules file is named by the WordFilter configuration key, and is
+// re-read whenever the configuration is reloaded. Every triggered rule
+// is logged, and the most recent hits are kept for the admin API.
+
+import (
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/auditlog"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/wordfilter"
+)
+
+// The number of word filter hits kept for the admin API.
+const wordFilterHitsKept = 100
+
+// A wordFilterHit records a message that triggered the word filter.
+type wordFilterHit struct {
+	Time       time.Time
+	Session    uint32
+	Name       string
+	UserId     int
+	Line       int
+	Pattern    string
+	Action     wordfilter.Action
+	Punishment wordfilter.Punishment
+	Message    string
+}
+
+// loadWordFilter reads the rules file named by the WordFilter key.
+// Relative paths are relative to the data directory. If no file is
+// configured, it returns a nil filter.
+func (server *Server) loadWordFilter() (*wordfilter.Filter, error) {
+	fn := server.cfg.StringValue("WordFilter")
+	if len(fn) == 0 {
+		return nil, nil
+	}
+	if !filepath.IsAbs(fn) {
+		fn = filepath.Join(Args.DataDir, fn)
+	}
+	return wordfilter.Load(fn)
+}
+
+// applyWordFilter runs a text message through the word filter, and
+// punishes its sender if needed. It returns the text to pass on, and
+// false if the message is to be dropped.
+//
+// Must be called from the server's handler goroutine.
+func (server *Server) applyWordFilter(client *Client, text string) (string, bool) {
+	if server.wordFilter == nil {
+		return text, true
+	}
+	result := server.wordFilter.Check(text)
+	if len(result.Matched) == 0 {
+		return text, true
+	}
+
+	for _, rule := range result.Matched {
+		client.Printf("Word filter rule on line %v (%v, %v) matched message %q", rule.Line, rule.Action, rule.Punishment, text)
+		server.wordFilterHits = append(server.wordFilterHits, wordFilterHit{
+			Time:       time.Now(),
+			Session:    client.Session(),
+			Name:       client.ShownName(),
+			UserId:     client.UserId(),
+			Line:       rule.Line,
+			Pattern:    rule.Pattern.String(),
+			Action:     rule.Action,
+			Punishment: rule.Punishment,
+			Message:    text,
+		})
+	}
+	if n := len(server.wordFilterHits); n > wordFilterHitsKept {
+		server.wordFilterHits = append([]wordFilterHit(nil), server.wordFilterHits[n-wordFilterHitsKept:]...)
+	}
+
+	switch result.Punishment {
+	case wordfilter.Warn:
+		err := client.sendMessage(&mumbleproto.PermissionDenied{
+			Type:   mumbleproto.PermissionDenied_Text.Enum(),
+			Reason: proto.String("Your message violates the rules of this server."),
+		})
+		if err != nil {
+			client.Panicf("%v", err.Error())
+		}
+	case wordfilter.Mute:
+		if !client.Mute {
+			server.audit(nil, auditlog.Entry{Action: "mute", Actor: "wordfilter", Target: client.ShownName(), Reason: text})
+			server.SetClientMute(client, true)
+			server.escalate(client, "mute")
+			if client.disconnected {
+				return "", false
+			}
+		}
+	case wordfilter.Kick:
+		server.KickClient(client, "Your message violates the rules of this server.")
+		return "", false
+	}
+
+	if result.Drop {
+		return "", false
+	}
+	return result.Text, true
+}
+
+// apiWordFilterHit is the JSON representation of a wordFilterHit.
+type apiWordFilterHit struct {
+	Time       string `json:"time"`
+	Session    uint32 `json:"session"`
+	Name       string `json:"name"`
+	UserId     int    `json:"user_id"`
+	Line       int    `json:"line"`
+	Pattern    string `json:"pattern"`
+	Action     string `json:"action"`
+	Punishment string `json:"punishment"`
+	Message    string `json:"message"`
+}
+
+func init() {
+	registerAPIEndpoint("wordfilter", handleAPIWordFilter)
+}
+
+// handleAPIWordFilter implements /servers/<id>/wordfilter. A GET
+// request lists the most recent messages that triggered the filter.
+func handleAPIWordFilter(server *Server, w http.ResponseWriter, r *http.Request, args []string) {
+	if r.Method != http.MethodGet {
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	hits := []apiWordFilterHit{}
+	err := server.runSync(func() {
+		for _, hit := range server.wordFilterHits {
+			hits = append(hits, apiWordFilterHit{
+				Time:       hit.Time.UTC().Format(time.RFC3339),
+				Session:    hit.Session,
+				Name:       hit.Name,
+				UserId:     hit.UserId,
+				Line:       hit.Line,
+				Pattern:    hit.Pattern,
+				Action:     hit.Action.String(),
+				Punishment: hit.Punishment.String(),
+				Message:    hit.Message,
+			})
+		}
+	})
+	if err != nil {
+		apiError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, hits)
+}
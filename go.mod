@@ -3,8 +3,12 @@ module mumble.info/grumble
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/golang/protobuf v1.5.4
 	github.com/gorilla/websocket v1.5.1
+	github.com/yuin/gopher-lua v1.1.1
 	golang.org/x/crypto v0.21.0
-	golang.org/x/net v0.22.0 // indirect
+	golang.org/x/net v0.22.0
+	golang.org/x/sys v0.18.0
+	gopkg.in/yaml.v3 v3.0.1
 )
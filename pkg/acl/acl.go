@@ -19,6 +19,12 @@ const (
 	TextMessagePermission = 0x200
 	TempChannelPermission = 0x400
 
+	// Grumble-only per-channel permissions. Sending text messages
+	// with HTML (including images) or links takes these on top of
+	// TextMessagePermission.
+	HTMLMessagePermission = 0x1000
+	LinkMessagePermission = 0x2000
+
 	// Root channel only
 	KickPermission         = 0x10000
 	BanPermission          = 0x20000
@@ -27,7 +33,7 @@ const (
 
 	// Extra flags
 	CachedPermission = 0x8000000
-	AllPermissions   = 0xf07ff
+	AllPermissions   = 0xf37ff
 )
 
 // Permission represents a permission in Mumble's ACL system.
@@ -100,8 +106,39 @@ func HasPermission(ctx *Context, user User, perm Permission) bool {
 		return true
 	}
 
+	granted := EffectivePermissions(ctx, user)
+
+	// The +write permission implies all permissions except for +speak and +whisper.
+	// This means that if the user has WritePermission, we should return true for all
+	// permissions exccept SpeakPermission and WhisperPermission.
+	if perm != SpeakPermission && perm != WhisperPermission {
+		return (granted & (perm | WritePermission)) != NonePermission
+	}
+	return (granted & perm) != NonePermission
+}
+
+// EffectivePermissions calculates the full set of permissions that the given
+// user has in the given context. Any temporary grants held by the user (see
+// GrantHolder) are applied on top of the permissions granted by the ACLs.
+func EffectivePermissions(ctx *Context, user User) Permission {
+	// We can't check permissions on a nil ctx.
+	if ctx == nil {
+		panic("acl: EffectivePermissions got nil context")
+	}
+	return evaluate(ctx, user, nil)
+}
+
+// evaluate calculates the permissions of user in ctx. If tr isn't nil,
+// it records the ACL entries that decided them (see Explain).
+func evaluate(ctx *Context, user User, tr *tracer) Permission {
+	// SuperUser can't speak or whisper, but everything else is OK
+	if user.UserId() == 0 {
+		return Permission(AllPermissions) &^ Permission(SpeakPermission|WhisperPermission)
+	}
+
 	// Default permissions
-	defaults := Permission(TraversePermission | EnterPermission | SpeakPermission | WhisperPermission | TextMessagePermission)
+	defaults := Permission(TraversePermission | EnterPermission | SpeakPermission | WhisperPermission | TextMessagePermission |
+		HTMLMessagePermission | LinkMessagePermission)
 	granted := defaults
 	contexts := buildChain(ctx)
 	origCtx := ctx
@@ -113,11 +150,12 @@ func HasPermission(ctx *Context, user User, perm Permission) bool {
 		// If the context does not inherit any ACLs, use the default permissions.
 		if !ctx.InheritACL {
 			granted = defaults
+			tr.reset(ctx)
 		}
 		// Iterate through ACLs that are defined on ctx. Note: this does not include
 		// ACLs that iter has inherited from a parent (unless there is also a group on
 		// iter with the same name, that changes the permissions a bit!)
-		for _, acl := range ctx.ACLs {
+		for i, acl := range ctx.ACLs {
 			// Determine whether the ACL applies to user.
 			// If it is a user ACL and the user id of the ACL
 			// matches user's id, we're good to go.
@@ -140,9 +178,11 @@ func HasPermission(ctx *Context, user User, perm Permission) bool {
 				if acl.Deny.isSet(WritePermission) {
 					write = false
 				}
+				tr.traversal(ctx, i)
 				if (origCtx == ctx && acl.ApplyHere) || (origCtx != ctx && acl.ApplySubs) {
 					granted |= acl.Allow
 					granted &= ^acl.Deny
+					tr.apply(ctx, i)
 				}
 			}
 		}
@@ -152,18 +192,18 @@ func HasPermission(ctx *Context, user User, perm Permission) bool {
 		// all permissions.
 		if !traverse && !write {
 			granted = NonePermission
+			tr.noTraverse()
 			break
 		}
 	}
 
-	// The +write permission implies all permissions except for +speak and +whisper.
-	// This means that if the user has WritePermission, we should return true for all
-	// permissions exccept SpeakPermission and WhisperPermission.
-	if perm != SpeakPermission && perm != WhisperPermission {
-		return (granted & (perm | WritePermission)) != NonePermission
-	} else {
-		return (granted & perm) != NonePermission
+	// Temporary grants are overlaid on top of whatever the ACLs
+	// evaluated to.
+	if holder, ok := user.(GrantHolder); ok {
+		grants := holder.GrantedPermissions(origCtx) & AllPermissions
+		tr.grant(grants &^ granted)
+		granted |= grants
 	}
 
-	return false
+	return granted
 }
@@ -0,0 +1,150 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package acl
+
+import (
+	"testing"
+)
+
+type testUser struct {
+	id      int
+	ctx     *Context
+	granted Permission
+}
+
+func (u *testUser) Session() uint32      { return 1 }
+func (u *testUser) UserId() int          { return u.id }
+func (u *testUser) CertHash() string     { return "" }
+func (u *testUser) Tokens() []string     { return nil }
+func (u *testUser) ACLContext() *Context { return u.ctx }
+
+func (u *testUser) GrantedPermissions(ctx *Context) Permission {
+	return u.granted
+}
+
+func moderatedContext() *Context {
+	return &Context{
+		InheritACL: true,
+		ACLs: []ACL{
+			{UserId: -1, Group: "all", ApplyHere: true, Deny: SpeakPermission},
+		},
+	}
+}
+
+func TestEffectivePermissionsDeny(t *testing.T) {
+	ctx := moderatedContext()
+	user := &testUser{id: -1, ctx: ctx}
+	if HasPermission(ctx, user, SpeakPermission) {
+		t.Errorf("Expected speak to be denied")
+	}
+	if !HasPermission(ctx, user, EnterPermission) {
+		t.Errorf("Expected enter to be allowed")
+	}
+}
+
+func TestEffectivePermissionsGrant(t *testing.T) {
+	ctx := moderatedContext()
+	user := &testUser{id: -1, ctx: ctx, granted: SpeakPermission}
+	if !HasPermission(ctx, user, SpeakPermission) {
+		t.Errorf("Expected speak to be granted")
+	}
+	if EffectivePermissions(ctx, user)&WritePermission != 0 {
+		t.Errorf("Grant leaked write permission")
+	}
+}
+
+type tokenUser struct {
+	testUser
+	scope  *Context
+	tokens []string
+}
+
+func (u *tokenUser) ScopedTokens(ctx *Context) []string {
+	for iter := ctx; iter != nil; iter = iter.Parent {
+		if iter == u.scope {
+			return u.tokens
+		}
+	}
+	return nil
+}
+
+func TestScopedTokens(t *testing.T) {
+	root := &Context{InheritACL: true}
+	sub := &Context{Parent: root, InheritACL: true}
+	other := &Context{Parent: root, InheritACL: true}
+	user := &tokenUser{testUser: testUser{id: -1}, scope: sub, tokens: []string{"Secret"}}
+
+	if !GroupMemberCheck(sub, sub, "#secret", user) {
+		t.Errorf("Expected token to apply in its channel")
+	}
+	if GroupMemberCheck(other, other, "#secret", user) {
+		t.Errorf("Token applied outside its channel")
+	}
+	if GroupMemberCheck(root, root, "#secret", user) {
+		t.Errorf("Token applied in the parent of its channel")
+	}
+}
+
+func TestParsePermission(t *testing.T) {
+	perm, err := ParsePermission("speak, whisper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm != SpeakPermission|WhisperPermission {
+		t.Errorf("Unexpected permission %v", perm)
+	}
+	if perm.String() != "speak,whisper" {
+		t.Errorf("Unexpected string %q", perm.String())
+	}
+	if _, err := ParsePermission("fly"); err != ErrUnknownPermission {
+		t.Errorf("Expected ErrUnknownPermission, got %v", err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	root := &Context{
+		InheritACL: true,
+		ACLs: []ACL{
+			{UserId: -1, Group: "all", ApplyHere: true, ApplySubs: true, Allow: TextMessagePermission},
+			{UserId: -1, Group: "all", ApplyHere: false, ApplySubs: true, Deny: SpeakPermission},
+		},
+	}
+	ctx := &Context{Parent: root, InheritACL: true}
+	user := &testUser{id: -1, ctx: ctx}
+
+	e := Explain(ctx, user, SpeakPermission)
+	if e.Granted || e.Reason != ReasonACL || e.Context != root || e.Index != 1 || !e.Inherited {
+		t.Errorf("Unexpected explanation for speak: %+v", e)
+	}
+	if e.String() != "speak denied by inherited entry #1 for @all" {
+		t.Errorf("Unexpected description %q", e.String())
+	}
+	if e := Explain(ctx, user, EnterPermission); !e.Granted || e.Reason != ReasonDefault || e.Entry != nil {
+		t.Errorf("Unexpected explanation for enter: %+v", e)
+	}
+	if e := Explain(ctx, user, MovePermission); e.Granted || e.Reason != ReasonDefault {
+		t.Errorf("Unexpected explanation for move: %+v", e)
+	}
+
+	user.granted = SpeakPermission
+	if e := Explain(ctx, user, SpeakPermission); !e.Granted || e.Reason != ReasonGrant {
+		t.Errorf("Unexpected explanation for granted speak: %+v", e)
+	}
+	user.granted = NonePermission
+
+	ctx.ACLs = []ACL{{UserId: 3, ApplyHere: true, Allow: WritePermission}}
+	if e := Explain(ctx, &testUser{id: 3, ctx: ctx}, MovePermission); !e.Granted || e.Reason != ReasonWrite || e.Context != ctx || e.Inherited {
+		t.Errorf("Unexpected explanation for move with write: %+v", e)
+	}
+
+	ctx.ACLs = []ACL{{UserId: -1, Group: "all", ApplyHere: true, Deny: TraversePermission}}
+	if e := Explain(ctx, user, EnterPermission); e.Granted || e.Reason != ReasonTraverse || e.Index != 0 {
+		t.Errorf("Unexpected explanation for enter without traverse: %+v", e)
+	}
+
+	if e := Explain(ctx, &testUser{id: 0, ctx: ctx}, SpeakPermission); e.Granted || e.Reason != ReasonSuperUser {
+		t.Errorf("Unexpected explanation for SuperUser: %+v", e)
+	}
+}
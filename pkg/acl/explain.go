@@ -0,0 +1,217 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package acl
+
+import (
+	"fmt"
+)
+
+// Reason tells why a permission was granted or denied.
+type Reason string
+
+const (
+	// The user is the SuperUser.
+	ReasonSuperUser Reason = "superuser"
+	// No ACL entry changed the default permissions.
+	ReasonDefault Reason = "default"
+	// An ACL entry allowed or denied the permission.
+	ReasonACL Reason = "acl"
+	// An ACL entry allowed the write permission, which implies
+	// the permission.
+	ReasonWrite Reason = "write"
+	// An ACL entry denied traverse on the way to the context,
+	// which removes all permissions.
+	ReasonTraverse Reason = "traverse"
+	// The permission is held as a temporary grant.
+	ReasonGrant Reason = "grant"
+)
+
+// An Explanation tells which ACL entry decided whether a user has a
+// permission in a context.
+type Explanation struct {
+	// The permission that was checked.
+	Permission Permission
+	// Granted is the result of HasPermission.
+	Granted bool
+	// Why the permission was granted or denied.
+	Reason Reason
+
+	// The context that holds the deciding entry, or, for
+	// ReasonDefault, the context that stopped inheriting ACLs (nil
+	// if none did).
+	Context *Context
+	// The index of the deciding entry in Context.ACLs, and the entry
+	// itself. Index is -1 and Entry nil if no entry decided.
+	Index int
+	Entry *ACL
+	// Inherited is set if Context is an ancestor of the context that
+	// was checked.
+	Inherited bool
+}
+
+// String describes the explanation in a sentence.
+func (e Explanation) String() string {
+	verdict := "denied"
+	if e.Granted {
+		verdict = "granted"
+	}
+	switch e.Reason {
+	case ReasonSuperUser:
+		return fmt.Sprintf("%v %v to SuperUser", e.Permission, verdict)
+	case ReasonGrant:
+		return fmt.Sprintf("%v granted temporarily", e.Permission)
+	case ReasonDefault:
+		if e.Context != nil {
+			return fmt.Sprintf("%v %v by default, as ACLs are not inherited", e.Permission, verdict)
+		}
+		return fmt.Sprintf("%v %v by default", e.Permission, verdict)
+	}
+	entry := "no entry"
+	if e.Entry != nil {
+		who := "@" + e.Entry.Group
+		if e.Entry.IsUserACL() {
+			who = fmt.Sprintf("user %v", e.Entry.UserId)
+		}
+		entry = fmt.Sprintf("entry #%v for %v", e.Index, who)
+		if e.Inherited {
+			entry = "inherited " + entry
+		}
+	}
+	switch e.Reason {
+	case ReasonWrite:
+		return fmt.Sprintf("%v granted by write, from %v", e.Permission, entry)
+	case ReasonTraverse:
+		return fmt.Sprintf("%v denied as traverse is denied by %v", e.Permission, entry)
+	}
+	return fmt.Sprintf("%v %v by %v", e.Permission, verdict, entry)
+}
+
+// Explain checks whether the given user has permission perm in the
+// given context, like HasPermission, and tells which ACL entry decided
+// it.
+func Explain(ctx *Context, user User, perm Permission) Explanation {
+	// We can't check permissions on a nil ctx.
+	if ctx == nil {
+		panic("acl: Explain got nil context")
+	}
+
+	e := Explanation{Permission: perm, Index: -1}
+	writeImplies := perm != SpeakPermission && perm != WhisperPermission
+	if user.UserId() == 0 {
+		e.Granted = writeImplies
+		e.Reason = ReasonSuperUser
+		return e
+	}
+
+	none := step{index: -1}
+	tr := &tracer{perm: perm, last: none, write: none, traverse: none}
+	granted := evaluate(ctx, user, tr)
+	byPerm := granted&perm != NonePermission
+	byWrite := writeImplies && granted&WritePermission != NonePermission
+	e.Granted = byPerm || byWrite
+
+	var decided step
+	switch {
+	case e.Granted && (tr.grants&perm != NonePermission || !byPerm && tr.grants&WritePermission != NonePermission):
+		e.Reason = ReasonGrant
+		return e
+	case tr.blocked:
+		e.Reason = ReasonTraverse
+		decided = tr.traverse
+	case !byPerm && byWrite:
+		e.Reason = ReasonWrite
+		decided = tr.write
+	default:
+		e.Reason = ReasonACL
+		decided = tr.last
+	}
+	if decided.index < 0 {
+		e.Reason = ReasonDefault
+		e.Context = decided.ctx
+		return e
+	}
+	e.Context = decided.ctx
+	e.Index = decided.index
+	e.Entry = &decided.ctx.ACLs[decided.index]
+	e.Inherited = decided.ctx != ctx
+	return e
+}
+
+// A step is an ACL entry seen while evaluating permissions. An index
+// of -1 stands for the default permissions, restored at ctx if it
+// isn't nil.
+type step struct {
+	ctx   *Context
+	index int
+}
+
+// A tracer records the ACL entries that decide a permission while
+// evaluating permissions. Its methods do nothing on a nil tracer.
+type tracer struct {
+	perm Permission
+
+	// The last entries that changed perm and write.
+	last  step
+	write step
+	// The last entry that denied traverse or write, and whether
+	// that left the user without any permissions.
+	traverse step
+	blocked  bool
+	// Permissions held only as temporary grants.
+	grants Permission
+}
+
+// reset records that ctx restored the default permissions. The root
+// context has nothing to inherit, so it isn't recorded.
+func (tr *tracer) reset(ctx *Context) {
+	if tr == nil {
+		return
+	}
+	if ctx.Parent == nil {
+		ctx = nil
+	}
+	tr.last = step{ctx: ctx, index: -1}
+	tr.write = step{ctx: ctx, index: -1}
+}
+
+// traversal records that entry i of ctx matched the user.
+func (tr *tracer) traversal(ctx *Context, i int) {
+	if tr == nil {
+		return
+	}
+	if ctx.ACLs[i].Deny&(TraversePermission|WritePermission) != NonePermission {
+		tr.traverse = step{ctx: ctx, index: i}
+	}
+}
+
+// apply records that entry i of ctx was applied to the permissions.
+func (tr *tracer) apply(ctx *Context, i int) {
+	if tr == nil {
+		return
+	}
+	acl := ctx.ACLs[i]
+	if (acl.Allow|acl.Deny)&tr.perm != NonePermission {
+		tr.last = step{ctx: ctx, index: i}
+	}
+	if (acl.Allow|acl.Deny)&WritePermission != NonePermission {
+		tr.write = step{ctx: ctx, index: i}
+	}
+}
+
+// noTraverse records that all permissions were removed.
+func (tr *tracer) noTraverse() {
+	if tr == nil {
+		return
+	}
+	tr.blocked = true
+}
+
+// grant records the permissions held only as temporary grants.
+func (tr *tracer) grant(perm Permission) {
+	if tr == nil {
+		return
+	}
+	tr.grants = perm
+}
@@ -184,6 +184,13 @@ func GroupMemberCheck(current *Context, acl *Context, name string, user User) (o
 				return true
 			}
 		}
+		if holder, ok := user.(ScopedTokenHolder); ok {
+			for _, token := range holder.ScopedTokens(channel) {
+				if strings.ToLower(name) == strings.ToLower(token) {
+					return true
+				}
+			}
+		}
 		return false
 	} else if hash {
 		// The client is part of this group if the remaining name matches the
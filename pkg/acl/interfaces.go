@@ -17,6 +17,22 @@ type User interface {
 	ACLContext() *Context
 }
 
+// GrantHolder is an optional interface that may be implemented by a User
+// that holds temporary permission grants. The permissions returned by
+// GrantedPermissions are added to the permissions the user is granted by
+// the ACLs of the given context.
+type GrantHolder interface {
+	GrantedPermissions(ctx *Context) Permission
+}
+
+// ScopedTokenHolder is an optional interface that may be implemented by a
+// User that holds access tokens that only apply in some channels. The
+// tokens returned by ScopedTokens are checked in addition to those
+// returned by Tokens when evaluating token groups in the given context.
+type ScopedTokenHolder interface {
+	ScopedTokens(ctx *Context) []string
+}
+
 // Channel represents a Channel on a Mumble server.
 type Channel interface {
 	ChannelId() int
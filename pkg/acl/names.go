@@ -0,0 +1,84 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package acl
+
+import (
+	"errors"
+	"strings"
+)
+
+// permissionNames maps each single permission to the name used
+// for it in Murmur's ACL editor and configuration.
+var permissionNames = []struct {
+	perm Permission
+	name string
+}{
+	{WritePermission, "write"},
+	{TraversePermission, "traverse"},
+	{EnterPermission, "enter"},
+	{SpeakPermission, "speak"},
+	{MuteDeafenPermission, "mutedeafen"},
+	{MovePermission, "move"},
+	{MakeChannelPermission, "makechannel"},
+	{LinkChannelPermission, "linkchannel"},
+	{WhisperPermission, "whisper"},
+	{TextMessagePermission, "textmessage"},
+	{TempChannelPermission, "tempchannel"},
+	{HTMLMessagePermission, "htmlmessage"},
+	{LinkMessagePermission, "linkmessage"},
+	{KickPermission, "kick"},
+	{BanPermission, "ban"},
+	{RegisterPermission, "register"},
+	{SelfRegisterPermission, "selfregister"},
+}
+
+// ErrUnknownPermission is returned by ParsePermission when it encounters
+// a permission name it does not know about.
+var ErrUnknownPermission = errors.New("acl: unknown permission")
+
+// ParsePermission parses a comma-separated list of permission names
+// (for example "speak,whisper") into a Permission.
+func ParsePermission(names string) (Permission, error) {
+	perm := Permission(NonePermission)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if len(name) == 0 {
+			continue
+		}
+		found := false
+		for _, pn := range permissionNames {
+			if pn.name == name {
+				perm |= pn.perm
+				found = true
+				break
+			}
+		}
+		if !found {
+			return NonePermission, ErrUnknownPermission
+		}
+	}
+	return perm, nil
+}
+
+// Names returns the names of the individual permissions set in perm.
+func (perm Permission) Names() []string {
+	names := []string{}
+	for _, pn := range permissionNames {
+		if perm.isSet(pn.perm) {
+			names = append(names, pn.name)
+		}
+	}
+	return names
+}
+
+// String returns a comma-separated list of the names of the
+// permissions set in perm.
+func (perm Permission) String() string {
+	names := perm.Names()
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package auditlog implements an append-only log of administrative
+// actions.
+//
+// Entries are stored as JSON, one per line (JSONL), so that the log
+// file can be exported as-is and processed with standard tools.
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An Entry records a single administrative action.
+type Entry struct {
+	Time time.Time `json:"time"`
+
+	// The kind of action, such as "kick" or "channel.create".
+	Action string `json:"action"`
+
+	// Who performed the action. The actor's user id is -1 for
+	// unregistered users, and for actions taken by the server itself
+	// or through the admin API.
+	Actor   string `json:"actor"`
+	ActorId int    `json:"actor_id"`
+
+	// What the action was performed on.
+	Target string `json:"target,omitempty"`
+
+	Reason  string `json:"reason,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// A Query selects entries from a Log. Zero fields match all entries.
+type Query struct {
+	Action string
+	Actor  string
+	Target string
+	Since  time.Time
+	Until  time.Time
+
+	// The maximum number of entries to return. The most recent
+	// entries are returned.
+	Limit int
+}
+
+// Matches checks whether the entry is selected by the query. Action
+// also matches the actions below it, so that "channel" matches
+// "channel.create". Names are compared case-insensitively.
+func (q *Query) Matches(e *Entry) bool {
+	if len(q.Action) > 0 && e.Action != q.Action && !strings.HasPrefix(e.Action, q.Action+".") {
+		return false
+	}
+	if len(q.Actor) > 0 && !strings.EqualFold(e.Actor, q.Actor) {
+		return false
+	}
+	if len(q.Target) > 0 && !strings.EqualFold(e.Target, q.Target) {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// A Log is an audit log file.
+type Log struct {
+	mutex sync.Mutex
+	fn    string
+	file  *os.File
+}
+
+// Open opens the audit log at fn, creating it if needed.
+func Open(fn string) (*Log, error) {
+	f, err := os.OpenFile(fn, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the last entry was cut short, start a new line so that
+	// it does not swallow the next one.
+	fi, err := f.Stat()
+	if err == nil && fi.Size() > 0 {
+		last := make([]byte, 1)
+		_, err = f.ReadAt(last, fi.Size()-1)
+		if err == nil && last[0] != '\n' {
+			_, err = f.Write([]byte{'\n'})
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Log{fn: fn, file: f}, nil
+}
+
+// Close closes the log.
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+// Append adds an entry to the log. If the entry has no time,
+// the current time is used.
+func (l *Log) Append(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, err = l.file.Write(buf)
+	return err
+}
+
+// Query returns the entries selected by q, oldest first.
+func (l *Log) Query(q Query) ([]Entry, error) {
+	entries := []Entry{}
+	err := l.each(func(e *Entry) {
+		if !q.Matches(e) {
+			return
+		}
+		entries = append(entries, *e)
+		if q.Limit > 0 && len(entries) > 2*q.Limit {
+			entries = append(entries[:0], entries[len(entries)-q.Limit:]...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries, nil
+}
+
+// Export writes the entries selected by q to w in JSONL format.
+func (l *Log) Export(w io.Writer, q Query) error {
+	entries, err := l.Query(q)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := range entries {
+		err = enc.Encode(&entries[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prune removes the entries older than before, and returns how many it
+// removed. Lines that cannot be parsed are removed too. The log file is
+// rewritten, and replaced once the remaining entries are written.
+func (l *Log) Prune(before time.Time) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	f, err := os.Open(l.fn)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	tmp, err := os.OpenFile(l.fn+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	pruned := 0
+	writer := bufio.NewWriter(tmp)
+	reader := bufio.NewReader(f)
+	for {
+		line, rerr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var e Entry
+			if json.Unmarshal(line, &e) != nil || e.Time.Before(before) {
+				pruned++
+			} else if _, err = writer.Write(line); err != nil {
+				tmp.Close()
+				return 0, err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		} else if rerr != nil {
+			tmp.Close()
+			return 0, rerr
+		}
+	}
+	if pruned == 0 {
+		tmp.Close()
+		return 0, nil
+	}
+	if err = writer.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Open files can't be replaced on Windows.
+	f.Close()
+	l.file.Close()
+	rerr := os.Rename(tmp.Name(), l.fn)
+	l.file, err = os.OpenFile(l.fn, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if rerr != nil {
+		return 0, rerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// each calls fn for each entry in the log. Lines that cannot be
+// parsed, such as a line cut short by a crash, are skipped.
+func (l *Log) each(fn func(e *Entry)) error {
+	l.mutex.Lock()
+	f, err := os.Open(l.fn)
+	l.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var e Entry
+			if json.Unmarshal(line, &e) == nil {
+				fn(&e)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
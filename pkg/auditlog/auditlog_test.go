@@ -0,0 +1,130 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package auditlog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testLog(t *testing.T) (*Log, func()) {
+	dir, err := ioutil.TempDir("", "auditlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := Open(filepath.Join(dir, "audit.jsonl"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return l, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAppendQuery(t *testing.T) {
+	l, done := testLog(t)
+	defer done()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, e := range []Entry{
+		{Action: "kick", Actor: "alice", ActorId: 1, Target: "bob", Reason: "spam"},
+		{Action: "channel.create", Actor: "alice", ActorId: 1, Target: "Lobby"},
+		{Action: "channel.remove", Actor: "Carol", ActorId: -1, Target: "Lobby"},
+		{Action: "ban", Actor: "server", ActorId: -1, Target: "bob"},
+	} {
+		e.Time = start.Add(time.Duration(i) * time.Hour)
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A line cut short must not hide the others.
+	f, _ := os.OpenFile(l.fn, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString(`{"time": "2026`)
+	f.Close()
+	l.Close()
+	l, err := Open(l.fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.Append(Entry{Time: start.Add(4 * time.Hour), Action: "unban", Actor: "alice"})
+
+	for _, tc := range []struct {
+		query   Query
+		actions []string
+	}{
+		{Query{}, []string{"kick", "channel.create", "channel.remove", "ban", "unban"}},
+		{Query{Action: "channel"}, []string{"channel.create", "channel.remove"}},
+		{Query{Action: "chan"}, nil},
+		{Query{Actor: "carol"}, []string{"channel.remove"}},
+		{Query{Target: "bob"}, []string{"kick", "ban"}},
+		{Query{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)}, []string{"channel.create", "channel.remove"}},
+		{Query{Limit: 2}, []string{"ban", "unban"}},
+	} {
+		entries, err := l.Query(tc.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actions []string
+		for _, e := range entries {
+			actions = append(actions, e.Action)
+		}
+		if strings.Join(actions, ",") != strings.Join(tc.actions, ",") {
+			t.Errorf("Query %+v returned %v, want %v", tc.query, actions, tc.actions)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	l, done := testLog(t)
+	defer done()
+
+	l.Append(Entry{Action: "kick", Actor: "alice", Target: "bob"})
+	l.Append(Entry{Action: "ban", Actor: "alice", Target: "bob"})
+
+	buf := bytes.NewBuffer(nil)
+	if err := l.Export(buf, Query{Action: "ban"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"action":"ban"`) {
+		t.Errorf("Unexpected export %q", buf.String())
+	}
+}
+
+func TestPrune(t *testing.T) {
+	l, done := testLog(t)
+	defer done()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, action := range []string{"kick", "ban", "mute"} {
+		l.Append(Entry{Time: start.Add(time.Duration(i) * time.Hour), Action: action})
+	}
+	n, err := l.Prune(start.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Pruned %v entries, want 2", n)
+	}
+
+	// The log must still take new entries.
+	l.Append(Entry{Time: start.Add(3 * time.Hour), Action: "unban"})
+	entries, err := l.Query(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Action != "mute" || entries[1].Action != "unban" {
+		t.Errorf("Unexpected entries after pruning: %v", entries)
+	}
+}
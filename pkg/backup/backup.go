@@ -0,0 +1,177 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package backup implements backups of a Grumble data directory.
+//
+// A backup is a gzipped tar archive of the directory, stored under a
+// name that holds the time it was made:
+//
+//	grumble-20261016T030000Z.tar.gz
+//
+// Backups are written to a Target, a local directory or an S3 bucket,
+// and old backups are removed according to a Retention policy.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	namePrefix = "grumble-"
+	nameSuffix = ".tar.gz"
+	timeLayout = "20060102T150405Z"
+)
+
+var ErrInvalidPath = errors.New("backup: archive entry outside of the data directory")
+
+// Name returns the name of a backup made at t.
+func Name(t time.Time) string {
+	return namePrefix + t.UTC().Format(timeLayout) + nameSuffix
+}
+
+// ParseName returns the time a backup was made at from its name.
+func ParseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, namePrefix) || !strings.HasSuffix(name, nameSuffix) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(timeLayout, name[len(namePrefix):len(name)-len(nameSuffix)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Archive writes a gzipped tar archive of the directory root to w.
+// Paths for which skip returns true are left out, along with
+// everything below them. Files that are removed while the archive is
+// written are left out as well.
+func Archive(w io.Writer, root string, skip func(path string) bool) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(root, func(fn string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fn == root {
+			return nil
+		}
+		if skip != nil && skip(fn) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, fn)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+			return tw.WriteHeader(hdr)
+		}
+
+		f, err := os.Open(fn)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		// A file that grows while it is read, such as a server's log,
+		// is cut off at the size it had when it was found.
+		n, err := io.Copy(tw, io.LimitReader(f, hdr.Size))
+		if err != nil {
+			return err
+		}
+		if n < hdr.Size {
+			return fmt.Errorf("backup: %v shrank while it was read", fn)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore extracts an archive written by Archive into the directory
+// root, replacing existing files.
+func Restore(r io.Reader, root string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return ErrInvalidPath
+		}
+		fn := filepath.Join(root, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(fn, os.FileMode(hdr.Mode).Perm()|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(fn), 0700); err != nil {
+				return err
+			}
+			if err := restoreFile(fn, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func restoreFile(fn string, r io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(fn, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
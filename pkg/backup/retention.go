@@ -0,0 +1,68 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// A Retention policy decides which backups are kept.
+//
+// For each of the last Daily days on which backups were made, the
+// latest backup of that day is kept, and likewise for the last Weekly
+// weeks. Days and weeks are counted in UTC, and weeks start on Monday.
+// A backup may be kept for both reasons. A policy that keeps no daily
+// and no weekly backups keeps all of them.
+type Retention struct {
+	Daily  int
+	Weekly int
+}
+
+// Expired returns the names of the backups among names that the
+// policy doesn't keep. Names that aren't backup names are ignored.
+func (r Retention) Expired(names []string) []string {
+	if r.Daily <= 0 && r.Weekly <= 0 {
+		return nil
+	}
+
+	type backup struct {
+		name string
+		t    time.Time
+	}
+	backups := []backup{}
+	for _, name := range names {
+		if t, ok := ParseName(name); ok {
+			backups = append(backups, backup{name, t})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+
+	keep := make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for _, b := range backups {
+		day := b.t.Format("2006-01-02")
+		if !days[day] && len(days) < r.Daily {
+			days[day] = true
+			keep[b.name] = true
+		}
+		year, week := b.t.ISOWeek()
+		wk := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[wk] && len(weeks) < r.Weekly {
+			weeks[wk] = true
+			keep[b.name] = true
+		}
+	}
+
+	expired := []string{}
+	for _, b := range backups {
+		if !keep[b.name] {
+			expired = append(expired, b.name)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package backup
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrMissingCredentials = errors.New("backup: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for S3 targets")
+
+// S3 is a Target that stores backups in an S3 bucket, or a bucket of
+// a compatible service. Requests are signed with AWS Signature
+// Version 4.
+type S3 struct {
+	Bucket string
+	// Prefix is prepended to the names of backups, as in "grumble/".
+	Prefix string
+	Region string
+	// Endpoint is the URL of the service, such as
+	// https://s3.eu-west-1.amazonaws.com. With PathStyle set, the
+	// bucket is addressed in the path (https://host/bucket/key)
+	// rather than the host name (https://bucket.host/key), as most
+	// S3-compatible services expect.
+	Endpoint  string
+	PathStyle bool
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// The HTTP client used, or http.DefaultClient if nil.
+	Client *http.Client
+}
+
+// NewS3FromEnv returns the S3 target for a spec of the form
+// s3://bucket/prefix. Credentials are read from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, and the region from
+// AWS_REGION (default us-east-1). Set AWS_ENDPOINT_URL to use an
+// S3-compatible service, whose buckets are then addressed in the path.
+func NewS3FromEnv(spec string) (*S3, error) {
+	u, err := url.Parse(spec)
+	if err != nil || u.Scheme != "s3" || len(u.Host) == 0 {
+		return nil, fmt.Errorf("backup: invalid S3 target %q", spec)
+	}
+	s := &S3{
+		Bucket:       u.Host,
+		Prefix:       strings.TrimPrefix(u.Path, "/"),
+		Region:       os.Getenv("AWS_REGION"),
+		Endpoint:     os.Getenv("AWS_ENDPOINT_URL"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if len(s.Prefix) > 0 && !strings.HasSuffix(s.Prefix, "/") {
+		s.Prefix += "/"
+	}
+	if len(s.Region) == 0 {
+		s.Region = "us-east-1"
+	}
+	if len(s.Endpoint) > 0 {
+		s.PathStyle = true
+	} else {
+		s.Endpoint = "https://s3." + s.Region + ".amazonaws.com"
+	}
+	if len(s.AccessKey) == 0 || len(s.SecretKey) == 0 {
+		return nil, ErrMissingCredentials
+	}
+	return s, nil
+}
+
+// objectURL returns the URL of the object key, or of the bucket if
+// key is empty.
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u, nil
+}
+
+func (s *S3) do(method, key string, query url.Values, body io.ReadSeeker, size int64, payloadHash string) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = ioutil.NopCloser(body)
+		req.ContentLength = size
+	}
+	s.sign(req, payloadHash, time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("backup: S3 %v %v: %v: %s", method, key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// emptyHash is the SHA-256 hash of an empty payload.
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func (s *S3) Put(name string, r io.Reader) error {
+	// S3 needs to know the size and hash of an object up front, so
+	// the backup is spooled to a temporary file first.
+	f, err := ioutil.TempFile("", "grumble-backup-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, s.Prefix+name, nil, f, size, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *S3) Get(name string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.Prefix+name, nil, nil, 0, emptyHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *S3) List() ([]string, error) {
+	names := []string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if len(token) > 0 {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(http.MethodGet, "", query, nil, 0, emptyHash)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range result.Contents {
+			name := strings.TrimPrefix(obj.Key, s.Prefix)
+			if _, ok := ParseName(name); ok {
+				names = append(names, name)
+			}
+		}
+		if !result.IsTruncated || len(result.NextContinuationToken) == 0 {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *S3) Delete(name string) error {
+	resp, err := s.do(http.MethodDelete, s.Prefix+name, nil, nil, 0, emptyHash)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// sign adds an AWS Signature Version 4 Authorization header to req,
+// covering its host and all headers set on it.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if len(s.SessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := []string{}
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	params := []string{}
+	for _, key := range keys {
+		for _, value := range query[key] {
+			params = append(params, awsEscape(key, true)+"="+awsEscape(value, true))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, false),
+		strings.Join(params, "&"),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{date, s.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape escapes s as AWS Signature Version 4 requires: all bytes
+// but unreserved characters are percent-encoded, and slashes too if
+// escapeSlash is set.
+func awsEscape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package backup
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A Target is where backups are stored.
+type Target interface {
+	// Put stores a backup read from r under name.
+	Put(name string, r io.Reader) error
+	// Get opens the backup stored under name.
+	Get(name string) (io.ReadCloser, error)
+	// List returns the names of the stored backups, oldest first.
+	List() ([]string, error)
+	// Delete removes the backup stored under name.
+	Delete(name string) error
+}
+
+// Open returns the target for spec: an S3 bucket if spec is of the
+// form s3://bucket/prefix, and a local directory otherwise. S3
+// credentials are read from the environment, as in NewS3FromEnv.
+func Open(spec string) (Target, error) {
+	if strings.HasPrefix(spec, "s3://") {
+		return NewS3FromEnv(spec)
+	}
+	return Dir(spec), nil
+}
+
+// Dir is a Target that stores backups in a local directory.
+type Dir string
+
+func (d Dir) Put(name string, r io.Reader) error {
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a failed backup
+	// doesn't leave a partial archive behind under its real name.
+	f, err := ioutil.TempFile(string(d), ".backup_")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(string(d), filepath.Base(name)))
+}
+
+func (d Dir) Get(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), filepath.Base(name)))
+}
+
+func (d Dir) List() ([]string, error) {
+	entries, err := ioutil.ReadDir(string(d))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if _, ok := ParseName(entry.Name()); ok && entry.Mode().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d Dir) Delete(name string) error {
+	return os.Remove(filepath.Join(string(d), filepath.Base(name)))
+}
@@ -14,6 +14,7 @@ const (
 )
 
 type Ban struct {
+	Id       uint32
 	IP       net.IP
 	Mask     int
 	Username string
@@ -23,6 +24,14 @@ type Ban struct {
 	Duration uint32
 }
 
+// Equal checks whether two bans have the same contents. Their ids are
+// not compared.
+func (ban Ban) Equal(other Ban) bool {
+	return ban.IP.Equal(other.IP) && ban.Mask == other.Mask &&
+		ban.Username == other.Username && ban.CertHash == other.CertHash &&
+		ban.Reason == other.Reason && ban.Start == other.Start && ban.Duration == other.Duration
+}
+
 // Create a net.IPMask from a specified amount of mask bits
 func (ban Ban) IPMask() (mask net.IPMask) {
 	allbits := ban.Mask
@@ -136,3 +136,15 @@ func TestNotExpired(t *testing.T) {
 		t.Errorf("Should expire in 24 hours")
 	}
 }
+
+func TestEqual(t *testing.T) {
+	a := Ban{Id: 1, IP: net.ParseIP("10.0.0.1"), Mask: 128, Reason: "spam", Duration: 60}
+	b := Ban{Id: 2, IP: net.ParseIP("10.0.0.1").To4(), Mask: 128, Reason: "spam", Duration: 60}
+	if !a.Equal(b) {
+		t.Errorf("Expected bans that differ only in their ids to be equal")
+	}
+	b.Reason = "flood"
+	if a.Equal(b) {
+		t.Errorf("Expected bans with different reasons to differ")
+	}
+}
@@ -29,22 +29,27 @@ var (
 // Blobs in the blobstore are indexed by their SHA1 hash.
 //
 // The BlobStore is backed by a directory on the filesystem. This
-// directory contains subdirectories which contain keys (SHA1 hashes).
-// Each subdirectory is named according to the first hex-encoded byte
-// of the keys that subdirectory contains.
+// directory contains two levels of subdirectories which contain keys
+// (SHA1 hashes). The subdirectories are named according to the first
+// and second hex-encoded byte of the keys they contain, so that no
+// directory holds more than a small share of the blobs.
 //
 // For example, a file that has the content 'hello world' will have
 // the SHA1 hash '2aae6c35c94fcfb415dbe95f408b9ce91ee846ed'. If our
 // blobstore's backing directory is called 'blobstore', the blob with
 // only 'hello world' in it will be stored as follows:
 //
-//     blobstore/2a/2aae6c35c94fcfb415dbe95f408b9ce91ee846ed
+//     blobstore/2a/ae/2aae6c35c94fcfb415dbe95f408b9ce91ee846ed
+//
+// Older versions stored blobs one level up, as blobstore/2a/2aae...
+// Blobs in that layout are still found, and are moved by Reshard.
 //
 // The BlobStore is self-synchronizing, relying on the filesystem
 // operations to ensure atomicity. Thus, accessing a single BlobStore
 // from multiple goroutines should have no ill side effects.
 type BlobStore struct {
-	dir string
+	dir   string
+	cache *lruCache
 }
 
 // Open opens an existing BlobStore. The path parameter must
@@ -79,7 +84,13 @@ func extractKeyComponents(key string) (dir string, fn string, err error) {
 	if !isValidKey(key) {
 		return "", "", ErrBadKey
 	}
-	return key[0:2], key, nil
+	return filepath.Join(key[0:2], key[2:4]), key, nil
+}
+
+// legacyPath returns the path of the blob identified by a valid key
+// in the single-level layout of older versions.
+func (bs BlobStore) legacyPath(key string) string {
+	return filepath.Join(bs.dir, key[0:2], key)
 }
 
 // Get returns a byte slice containing the contents of
@@ -91,8 +102,21 @@ func (bs BlobStore) Get(key string) ([]byte, error) {
 		return nil, err
 	}
 
+	if bs.cache != nil {
+		if buf, ok := bs.cache.get(key); ok {
+			return buf, nil
+		}
+	}
+
 	blobfn := filepath.Join(bs.dir, dir, fn)
 	f, err := os.Open(blobfn)
+	if os.IsNotExist(err) {
+		f, err = os.Open(bs.legacyPath(key))
+		if os.IsNotExist(err) {
+			// Reshard may have moved the blob in the meantime.
+			f, err = os.Open(blobfn)
+		}
+	}
 	if os.IsNotExist(err) {
 		return nil, ErrNoSuchKey
 	} else if err != nil {
@@ -111,6 +135,10 @@ func (bs BlobStore) Get(key string) ([]byte, error) {
 		return nil, err
 	}
 
+	if bs.cache != nil {
+		bs.cache.add(key, buf)
+	}
+
 	return buf, nil
 }
 
@@ -119,6 +147,16 @@ func (bs BlobStore) Get(key string) ([]byte, error) {
 // be used to retrieve the buf from the BlobStore at a
 // later time.
 func (bs BlobStore) Put(buf []byte) (key string, err error) {
+	key, err = bs.put(buf)
+	// Blobs are usually read right after they are stored, when
+	// they are sent to other clients.
+	if err == nil && bs.cache != nil {
+		bs.cache.add(key, append([]byte(nil), buf...))
+	}
+	return key, err
+}
+
+func (bs BlobStore) put(buf []byte) (key string, err error) {
 	// Calculate the key for the blob.  We can't really delay it more than this,
 	// since we need to know the key for the blob to check whether it's already on
 	// disk.
@@ -152,8 +190,16 @@ func (bs BlobStore) Put(buf []byte) (key string, err error) {
 	}
 
 	// Ensure that blobdir exist.
-	err = os.Mkdir(blobdir, 0750)
-	if err != nil && !os.IsExist(err) {
+	err = os.MkdirAll(blobdir, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	// If the blob is stored in the old layout, move it.
+	err = os.Rename(bs.legacyPath(key), blobpath)
+	if err == nil {
+		return key, nil
+	} else if !os.IsNotExist(err) {
 		return "", err
 	}
 
@@ -197,3 +243,22 @@ func (bs BlobStore) Put(buf []byte) (key string, err error) {
 
 	return key, nil
 }
+
+// CheckWritable checks that blobs can be written to the BlobStore, by
+// creating and removing a file in its directory.
+func (bs BlobStore) CheckWritable() error {
+	f, err := ioutil.TempFile(bs.dir, ".check_")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write([]byte{0}); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Remove(f.Name())
+}
@@ -103,3 +103,21 @@ func TestReadBadKeyNonHex(t *testing.T) {
 		return
 	}
 }
+
+func TestCheckWritable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Open(dir).CheckWritable(); err != nil {
+		t.Errorf("Expected a writable blobstore, got %v", err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no leftover files, got %v", len(entries))
+	}
+	if err := Open(dir + "/missing").CheckWritable(); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats holds the statistics of a BlobStore's cache.
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Blobs     int    `json:"blobs"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+}
+
+// lruCache keeps the most recently used blobs in memory, up to a
+// total size in bytes.
+type lruCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+	stats   CacheStats
+}
+
+type cacheEntry struct {
+	key string
+	buf []byte
+}
+
+func newLRUCache(maxBytes int64) *lruCache {
+	return &lruCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		stats:   CacheStats{MaxBytes: maxBytes},
+	}
+}
+
+// get returns the cached blob identified by key, if any.
+func (c *lruCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).buf, true
+}
+
+// add caches the blob buf identified by key, evicting the least
+// recently used blobs to make room. Blobs larger than an eighth of
+// the cache aren't cached, so that a few large blobs can't push out
+// all others.
+func (c *lruCache) add(key string, buf []byte) {
+	size := int64(len(buf))
+	if size > c.stats.MaxBytes/8 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	for c.stats.Bytes+size > c.stats.MaxBytes {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key, buf})
+	c.stats.Bytes += size
+	c.stats.Blobs++
+}
+
+// remove drops the blob identified by key from the cache.
+func (c *lruCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.stats.Bytes -= int64(len(entry.buf))
+	c.stats.Blobs--
+}
+
+// WithCache returns a BlobStore for the same directory that keeps the
+// most recently read and written blobs in memory, up to maxBytes in
+// all. Blobs returned by its Get are shared with the cache and must
+// not be modified.
+func (bs BlobStore) WithCache(maxBytes int64) BlobStore {
+	if maxBytes <= 0 {
+		return BlobStore{dir: bs.dir}
+	}
+	return BlobStore{dir: bs.dir, cache: newLRUCache(maxBytes)}
+}
+
+// CacheStats returns the statistics of the BlobStore's cache, which
+// are all zero if it has none.
+func (bs BlobStore) CacheStats() CacheStats {
+	if bs.cache == nil {
+		return CacheStats{}
+	}
+	bs.cache.mu.Lock()
+	defer bs.cache.mu.Unlock()
+	return bs.cache.stats
+}
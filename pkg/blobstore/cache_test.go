@@ -0,0 +1,60 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bs := Open(dir).WithCache(80)
+
+	a, _ := bs.Put(bytes.Repeat([]byte{'a'}, 10))
+	b, _ := bs.Put(bytes.Repeat([]byte{'b'}, 10))
+	if stats := bs.CacheStats(); stats.Blobs != 2 || stats.Bytes != 20 {
+		t.Errorf("Unexpected stats after Put: %+v", stats)
+	}
+
+	// Cached blobs are served from memory, even once gone from disk.
+	os.Remove(filepath.Join(dir, a[0:2], a[2:4], a))
+	if buf, err := bs.Get(a); err != nil || len(buf) != 10 {
+		t.Errorf("Expected cached blob, got %q (%v)", buf, err)
+	}
+
+	// Reading b makes a the least recently used blob.
+	bs.Get(b)
+	for _, c := range []byte("cdefghij") {
+		if _, err := bs.Put(bytes.Repeat([]byte{c}, 10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := bs.CacheStats()
+	if stats.Bytes > 80 || stats.Evictions != 2 || stats.Hits != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if _, err := bs.Get(a); err != ErrNoSuchKey {
+		t.Errorf("Expected evicted blob to be read from disk, got %v", err)
+	}
+
+	// Blobs too large for the cache aren't cached.
+	large, _ := bs.Put(bytes.Repeat([]byte{'x'}, 11))
+	os.Remove(filepath.Join(dir, large[0:2], large[2:4], large))
+	if _, err := bs.Get(large); err != ErrNoSuchKey {
+		t.Errorf("Expected large blob not to be cached, got %v", err)
+	}
+
+	if stats := Open(dir).CacheStats(); stats != (CacheStats{}) {
+		t.Errorf("Expected empty stats without cache, got %+v", stats)
+	}
+}
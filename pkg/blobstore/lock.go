@@ -0,0 +1,164 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// lockName is the file in the BlobStore's directory that the
+	// operating system's advisory lock is taken on.
+	lockName = "lock"
+	// linkLockName is the lock file used where the file system
+	// doesn't support advisory locks, such as older NFS mounts.
+	linkLockName = "lock.link"
+)
+
+var (
+	// errLockHeld signals that another process holds an advisory lock.
+	errLockHeld = errors.New("blobstore: lock held")
+	// errLockUnsupported signals that the file system doesn't
+	// support advisory locks.
+	errLockUnsupported = errors.New("blobstore: locks not supported")
+)
+
+// LockedError signals that the BlobStore is locked by another process.
+type LockedError struct {
+	// Owner identifies the process holding the lock, as pid@host,
+	// if known.
+	Owner string
+}
+
+func (e *LockedError) Error() string {
+	if len(e.Owner) == 0 {
+		return "blobstore: locked by another process"
+	}
+	return "blobstore: locked by process " + e.Owner
+}
+
+// A Lock is an exclusive lock on a BlobStore, held by this process.
+type Lock struct {
+	f        *os.File
+	linkPath string
+}
+
+// Lock locks the BlobStore, so that only one process at a time uses
+// it. If another process holds the lock, Lock returns a *LockedError.
+//
+// The lock is an advisory lock of the operating system (flock on Unix,
+// LockFileEx on Windows), which is released when the process exits,
+// also if it crashes. Where the file system doesn't support advisory
+// locks, a lock file is created by hard-linking, which is atomic even
+// on NFS. A lock file left behind by a crashed process on the same
+// host is detected and taken over; one left behind on another host
+// must be removed by hand.
+func (bs BlobStore) Lock() (*Lock, error) {
+	f, err := os.OpenFile(filepath.Join(bs.dir, lockName), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = lockFile(f)
+	if err == nil {
+		// The owner is only recorded for the error message of
+		// processes that find the BlobStore locked.
+		if f.Truncate(0) == nil {
+			f.WriteAt([]byte(lockOwner()), 0)
+		}
+		return &Lock{f: f}, nil
+	}
+	owner, _ := ioutil.ReadAll(f)
+	f.Close()
+	if err == errLockHeld {
+		return nil, &LockedError{Owner: strings.TrimSpace(string(owner))}
+	}
+	if err != errLockUnsupported {
+		return nil, err
+	}
+	return bs.linkLock()
+}
+
+// linkLock takes the lock by hard-linking a file holding the owner
+// to linkLockName.
+func (bs BlobStore) linkLock() (*Lock, error) {
+	path := filepath.Join(bs.dir, linkLockName)
+	tmp, err := ioutil.TempFile(bs.dir, linkLockName+".")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(lockOwner())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		err = os.Link(tmp.Name(), path)
+		if err == nil {
+			return &Lock{linkPath: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		buf, rerr := ioutil.ReadFile(path)
+		if os.IsNotExist(rerr) {
+			// Unlocked in the meantime.
+			continue
+		}
+		owner := strings.TrimSpace(string(buf))
+		if !isStaleOwner(owner) {
+			return nil, &LockedError{Owner: owner}
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, &LockedError{}
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	if len(l.linkPath) > 0 {
+		return os.Remove(l.linkPath)
+	}
+	if err := unlockFile(l.f); err != nil {
+		l.f.Close()
+		return err
+	}
+	return l.f.Close()
+}
+
+// lockOwner identifies this process as pid@host.
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%v@%v\n", os.Getpid(), host)
+}
+
+// isStaleOwner reports whether owner, as written by lockOwner, is a
+// process on this host that no longer runs.
+func isStaleOwner(owner string) bool {
+	parts := strings.SplitN(owner, "@", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	pid, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	host, err := os.Hostname()
+	if err != nil || host != parts[1] {
+		return false
+	}
+	return pid != os.Getpid() && !processAlive(pid)
+}
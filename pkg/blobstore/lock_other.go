@@ -0,0 +1,28 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build !darwin && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package blobstore
+
+import (
+	"os"
+)
+
+// lockFile always falls back to lock files, as advisory locks aren't
+// implemented on this platform.
+func lockFile(f *os.File) error {
+	return errLockUnsupported
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
+
+// processAlive can't tell whether a process runs on this platform,
+// so stale lock files must be removed by hand.
+func processAlive(pid int) bool {
+	return true
+}
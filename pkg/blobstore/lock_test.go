@@ -0,0 +1,93 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bs := Open(dir)
+
+	l, err := bs.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bs.Lock()
+	lerr, ok := err.(*LockedError)
+	if !ok {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+	if !strings.HasPrefix(lerr.Owner, strconv.Itoa(os.Getpid())+"@") {
+		t.Errorf("Unexpected owner %q", lerr.Owner)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	l, err = bs.Lock()
+	if err != nil {
+		t.Fatalf("Unable to lock after unlock: %v", err)
+	}
+	l.Unlock()
+}
+
+func TestLinkLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bs := Open(dir)
+
+	l, err := bs.linkLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bs.linkLock(); err == nil {
+		t.Fatal("Expected second lock to fail")
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, linkLockName)
+	host, _ := os.Hostname()
+
+	// A lock file of a process on another host is never stale.
+	ioutil.WriteFile(path, []byte("1@some-other-host\n"), 0600)
+	_, err = bs.linkLock()
+	if lerr, ok := err.(*LockedError); !ok || lerr.Owner != "1@some-other-host" {
+		t.Errorf("Expected LockedError for another host, got %v", err)
+	}
+
+	// A lock file of a process that exited is taken over.
+	if processAlive(1 << 30) {
+		t.Skip("unable to find a pid that isn't running")
+	}
+	ioutil.WriteFile(path, []byte(fmt.Sprintf("%v@%v\n", 1<<30, host)), 0600)
+	l, err = bs.linkLock()
+	if err != nil {
+		t.Fatalf("Expected stale lock to be taken over, got %v", err)
+	}
+	l.Unlock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file to be removed on unlock")
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Unexpected files left behind: %v", len(entries))
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package blobstore
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes an exclusive advisory lock on f without waiting.
+func lockFile(f *os.File) error {
+	err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	switch err {
+	case nil:
+		return nil
+	case unix.EWOULDBLOCK:
+		return errLockHeld
+	case unix.ENOLCK, unix.EOPNOTSUPP, unix.EINVAL:
+		return errLockUnsupported
+	}
+	return err
+}
+
+// unlockFile releases the lock taken by lockFile.
+func unlockFile(f *os.File) error {
+	return unix.Flock(int(f.Fd()), unix.LOCK_UN)
+}
+
+// processAlive reports whether a process with the given pid runs.
+func processAlive(pid int) bool {
+	return unix.Kill(pid, 0) != unix.ESRCH
+}
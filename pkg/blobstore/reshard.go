@@ -0,0 +1,125 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// isPrefixDir reports whether name is the name of a subdirectory of
+// the BlobStore's layout: a hex-encoded byte.
+func isPrefixDir(name string) bool {
+	return len(name) == 2 && isHex(name)
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// walkBlobs calls fn with the key and path of each blob in the
+// BlobStore, in either layout. Files that aren't named like blobs,
+// such as those left behind by interrupted writes, are skipped.
+func (bs BlobStore) walkBlobs(fn func(key, path string, legacy bool) error) error {
+	dirs, err := ioutil.ReadDir(bs.dir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || !isPrefixDir(dir.Name()) {
+			continue
+		}
+		dirpath := filepath.Join(bs.dir, dir.Name())
+		entries, err := ioutil.ReadDir(dirpath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() && isPrefixDir(name) {
+				subpath := filepath.Join(dirpath, name)
+				blobs, err := ioutil.ReadDir(subpath)
+				if os.IsNotExist(err) {
+					continue
+				} else if err != nil {
+					return err
+				}
+				for _, blob := range blobs {
+					key := blob.Name()
+					if blob.Mode().IsRegular() && isValidKey(key) && key[0:2] == dir.Name() && key[2:4] == name {
+						if err := fn(key, filepath.Join(subpath, key), false); err != nil {
+							return err
+						}
+					}
+				}
+				continue
+			}
+			if entry.Mode().IsRegular() && isValidKey(name) && name[0:2] == dir.Name() {
+				if err := fn(name, filepath.Join(dirpath, name), true); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Reshard moves the blobs stored in the single-level layout of older
+// versions to their place in the current layout, and returns the
+// number of blobs moved. The BlobStore may be used meanwhile, and an
+// interrupted Reshard is picked up again by the next one.
+func (bs BlobStore) Reshard() (moved int, err error) {
+	err = bs.walkBlobs(func(key, path string, legacy bool) error {
+		if !legacy {
+			return nil
+		}
+		dir, fn, err := extractKeyComponents(key)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Join(bs.dir, dir), 0750)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(path, filepath.Join(bs.dir, dir, fn))
+		if os.IsNotExist(err) {
+			// Moved by Put in the meantime.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		moved++
+		return nil
+	})
+	return moved, err
+}
+
+// Unshard moves all blobs back to the single-level layout of older
+// versions, and returns the number of blobs moved. The BlobStore must
+// not be used meanwhile, since Put stores blobs in the current layout.
+func (bs BlobStore) Unshard() (moved int, err error) {
+	err = bs.walkBlobs(func(key, path string, legacy bool) error {
+		if legacy {
+			return nil
+		}
+		if err := os.Rename(path, bs.legacyPath(key)); err != nil {
+			return err
+		}
+		moved++
+		// Remove the subdirectory once it is empty.
+		os.Remove(filepath.Dir(path))
+		return nil
+	})
+	return moved, err
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// putLegacy stores buf in the single-level layout of older versions.
+func putLegacy(t *testing.T, dir string, buf []byte) string {
+	sum := sha1.Sum(buf)
+	key := hex.EncodeToString(sum[:])
+	os.MkdirAll(filepath.Join(dir, key[0:2]), 0750)
+	if err := ioutil.WriteFile(filepath.Join(dir, key[0:2], key), buf, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestReshard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bs := Open(dir)
+
+	old := putLegacy(t, dir, []byte("old blob"))
+	moved := putLegacy(t, dir, []byte("old blob, stored again"))
+	current, err := bs.Put([]byte("new blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, current[0:2], current[2:4], current)); err != nil {
+		t.Errorf("Expected new blob in the sharded layout: %v", err)
+	}
+
+	// Blobs in the old layout are still found.
+	if buf, err := bs.Get(old); err != nil || string(buf) != "old blob" {
+		t.Errorf("Unable to get blob in the old layout: %q, %v", buf, err)
+	}
+	// Storing a blob again moves it.
+	if _, err := bs.Put([]byte("old blob, stored again")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, moved[0:2], moved)); !os.IsNotExist(err) {
+		t.Errorf("Expected Put to move blob out of the old layout")
+	}
+
+	n, err := bs.Reshard()
+	if err != nil || n != 1 {
+		t.Fatalf("Expected Reshard to move 1 blob, moved %v (%v)", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, old[0:2], old[2:4], old)); err != nil {
+		t.Errorf("Expected blob in the sharded layout: %v", err)
+	}
+	if n, err := bs.Reshard(); err != nil || n != 0 {
+		t.Errorf("Expected second Reshard to move nothing, moved %v (%v)", n, err)
+	}
+	if checked, bad, err := bs.Verify(); err != nil || checked != 3 || len(bad) != 0 {
+		t.Errorf("Unexpected verification %v, %v, %v", checked, bad, err)
+	}
+
+	n, err = bs.Unshard()
+	if err != nil || n != 3 {
+		t.Fatalf("Expected Unshard to move 3 blobs, moved %v (%v)", n, err)
+	}
+	for _, key := range []string{old, moved, current} {
+		if _, err := os.Stat(filepath.Join(dir, key[0:2], key)); err != nil {
+			t.Errorf("Expected blob in the old layout: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, key[0:2], key[2:4])); !os.IsNotExist(err) {
+			t.Errorf("Expected empty subdirectory to be removed")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// GetReader opens the blob identified by key for reading, and returns
+// it along with its size, so that large blobs needn't be held in
+// memory. If the blob's content doesn't match its key, reading it
+// ends with an EOFHashMismatchError instead of io.EOF. If no such blob
+// is found, GetReader returns ErrNoSuchKey.
+func (bs BlobStore) GetReader(key string) (rc io.ReadCloser, size int64, err error) {
+	dir, fn, err := extractKeyComponents(key)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if bs.cache != nil {
+		if buf, ok := bs.cache.get(key); ok {
+			return ioutil.NopCloser(bytes.NewReader(buf)), int64(len(buf)), nil
+		}
+	}
+
+	blobfn := filepath.Join(bs.dir, dir, fn)
+	f, err := os.Open(blobfn)
+	if os.IsNotExist(err) {
+		f, err = os.Open(bs.legacyPath(key))
+		if os.IsNotExist(err) {
+			// Reshard may have moved the blob in the meantime.
+			f, err = os.Open(blobfn)
+		}
+	}
+	if os.IsNotExist(err) {
+		return nil, 0, ErrNoSuchKey
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	br, err := newBlobReader(f, key)
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return br, fi.Size(), nil
+}
+
+// PutReader stores the blob read from r in the BlobStore, without
+// holding it in memory, and returns its key.
+func (bs BlobStore) PutReader(r io.Reader) (key string, err error) {
+	// The key is only known once the blob is read, so it is written
+	// to a temporary file in the BlobStore's directory first.
+	f, err := ioutil.TempFile(bs.dir, ".put_")
+	if err != nil {
+		return "", err
+	}
+	tmpfn := f.Name()
+	defer os.Remove(tmpfn)
+
+	h := sha1.New()
+	_, err = io.Copy(io.MultiWriter(f, h), r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	key = hex.EncodeToString(h.Sum(nil))
+
+	dir, fn, err := extractKeyComponents(key)
+	if err != nil {
+		return "", err
+	}
+	blobdir := filepath.Join(bs.dir, dir)
+	blobpath := filepath.Join(blobdir, fn)
+
+	_, err = os.Stat(blobpath)
+	if err == nil {
+		return key, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	err = os.MkdirAll(blobdir, 0750)
+	if err != nil {
+		return "", err
+	}
+
+	// If the blob is stored in the old layout, move it.
+	err = os.Rename(bs.legacyPath(key), blobpath)
+	if err == nil {
+		return key, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	err = os.Rename(tmpfn, blobpath)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
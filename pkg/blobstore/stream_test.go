@@ -0,0 +1,69 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bs := Open(dir)
+
+	blob := bytes.Repeat([]byte("grumble"), 100000)
+	key, err := bs.PutReader(bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if putKey, _ := bs.Put(blob); putKey != key {
+		t.Errorf("Expected key %v, got %v", putKey, key)
+	}
+	if _, err := bs.PutReader(bytes.NewReader(blob)); err != nil {
+		t.Errorf("Unexpected error storing an existing blob: %v", err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected no leftover temporary files, got %v entries", len(entries))
+	}
+
+	rc, size, err := bs.GetReader(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ioutil.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len(blob)) || !bytes.Equal(buf, blob) {
+		t.Errorf("Unexpected blob of size %v read back", size)
+	}
+
+	// Corrupt blobs fail at the end of reading.
+	err = ioutil.WriteFile(filepath.Join(dir, key[0:2], key[2:4], key), []byte("corrupt"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, _, err = bs.GetReader(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ioutil.ReadAll(rc)
+	rc.Close()
+	if _, ok := err.(EOFHashMismatchError); !ok {
+		t.Errorf("Expected EOFHashMismatchError, got %v", err)
+	}
+
+	if _, _, err := bs.GetReader("da39a3ee5e6b4b0d3255bfef95601890afd80709"); err != ErrNoSuchKey {
+		t.Errorf("Expected ErrNoSuchKey, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// QuarantineDir is the subdirectory of the BlobStore's directory
+// that corrupt blobs are moved to by Quarantine.
+const QuarantineDir = "quarantine"
+
+// A BadBlob is a blob whose content doesn't match its key.
+type BadBlob struct {
+	Key string
+	// Sum is the hex-encoded SHA1 hash of the content found.
+	Sum string
+}
+
+// Verify re-hashes all blobs in the BlobStore, and returns the number
+// of blobs checked and those whose content doesn't match their key.
+// Files that aren't named like blobs, such as those left behind by
+// interrupted writes, are skipped.
+func (bs BlobStore) Verify() (checked int, bad []BadBlob, err error) {
+	err = bs.walkBlobs(func(key, path string, legacy bool) error {
+		sum, err := hashFile(path)
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		checked++
+		if sum != key {
+			bad = append(bad, BadBlob{Key: key, Sum: sum})
+		}
+		return nil
+	})
+	return checked, bad, err
+}
+
+// hashFile returns the hex-encoded SHA1 hash of the file fn.
+func hashFile(fn string) (string, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Quarantine moves the blob identified by key out of the BlobStore,
+// into its QuarantineDir, where it is kept for inspection. Afterwards,
+// Get returns ErrNoSuchKey for key, and Put stores the blob anew.
+func (bs BlobStore) Quarantine(key string) error {
+	dir, fn, err := extractKeyComponents(key)
+	if err != nil {
+		return err
+	}
+	if bs.cache != nil {
+		bs.cache.remove(key)
+	}
+	qdir := filepath.Join(bs.dir, QuarantineDir)
+	err = os.Mkdir(qdir, 0750)
+	if err != nil && !os.IsExist(err) {
+		return err
+	}
+	err = os.Rename(filepath.Join(bs.dir, dir, fn), filepath.Join(qdir, fn))
+	if os.IsNotExist(err) {
+		err = os.Rename(bs.legacyPath(key), filepath.Join(qdir, fn))
+	}
+	if os.IsNotExist(err) {
+		return ErrNoSuchKey
+	}
+	return err
+}
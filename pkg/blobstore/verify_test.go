@@ -0,0 +1,68 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package blobstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bs := Open(dir)
+	good, err := bs.Put([]byte("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err := bs.Put([]byte("goodbye world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, corrupt[0:2], corrupt[2:4], corrupt), []byte("goodbye worle"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A leftover of an interrupted Put.
+	err = ioutil.WriteFile(filepath.Join(dir, good[0:2], good[2:4], good+"123"), []byte("junk"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checked, bad, err := bs.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 2 {
+		t.Errorf("Expected 2 blobs checked, got %v", checked)
+	}
+	if len(bad) != 1 || bad[0].Key != corrupt || bad[0].Sum == corrupt {
+		t.Fatalf("Unexpected bad blobs %v", bad)
+	}
+
+	if err := bs.Quarantine(corrupt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bs.Get(corrupt); err != ErrNoSuchKey {
+		t.Errorf("Expected quarantined blob to be gone, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, QuarantineDir, corrupt)); err != nil {
+		t.Errorf("Expected quarantined blob to be kept: %v", err)
+	}
+	if err := bs.Quarantine(corrupt); err != ErrNoSuchKey {
+		t.Errorf("Expected ErrNoSuchKey quarantining a missing blob, got %v", err)
+	}
+
+	checked, bad, err = bs.Verify()
+	if err != nil || checked != 1 || len(bad) != 0 {
+		t.Errorf("Unexpected verification after quarantine: %v, %v, %v", checked, bad, err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package chantemplate implements templates for channels created by
+// users.
+//
+// Templates are read from a JSON file that maps template names to
+// templates:
+//
+//	{
+//	    "squad": {
+//	        "description": "A channel for a squad of five.",
+//	        "position": 10,
+//	        "max_users": 5,
+//	        "acls": [
+//	            {"group": "all", "deny": ["speak"]},
+//	            {"group": "in", "allow": ["speak"], "apply_subs": false}
+//	        ]
+//	    }
+//	}
+//
+// An ACL entry names either a group or a user_id, and the permissions
+// it allows and denies (see acl.ParsePermission). apply_here and
+// apply_subs default to true.
+package chantemplate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"mumble.info/grumble/pkg/acl"
+)
+
+// Template describes a channel.
+type Template struct {
+	Name        string
+	Description string
+	Position    int
+	// The most users allowed in the channel, or 0 for the server's
+	// limit.
+	MaxUsers int
+	ACLs     []acl.ACL
+}
+
+// Templates holds channel templates by name.
+type Templates struct {
+	templates map[string]*Template
+}
+
+type jsonACL struct {
+	UserId    *int     `json:"user_id"`
+	Group     string   `json:"group"`
+	ApplyHere *bool    `json:"apply_here"`
+	ApplySubs *bool    `json:"apply_subs"`
+	Allow     []string `json:"allow"`
+	Deny      []string `json:"deny"`
+}
+
+type jsonTemplate struct {
+	Description string    `json:"description"`
+	Position    int       `json:"position"`
+	MaxUsers    int       `json:"max_users"`
+	ACLs        []jsonACL `json:"acls"`
+}
+
+// Load reads a templates file.
+func Load(fn string) (*Templates, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads templates from r.
+func Parse(r io.Reader) (*Templates, error) {
+	var raw map[string]jsonTemplate
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	t := &Templates{templates: make(map[string]*Template)}
+	for name, jt := range raw {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("template without a name")
+		}
+		if jt.MaxUsers < 0 {
+			return nil, fmt.Errorf("template %v: negative max_users", name)
+		}
+		tmpl := &Template{
+			Name:        name,
+			Description: jt.Description,
+			Position:    jt.Position,
+			MaxUsers:    jt.MaxUsers,
+		}
+		for i, ja := range jt.ACLs {
+			entry, err := ja.toACL()
+			if err != nil {
+				return nil, fmt.Errorf("template %v, ACL %v: %v", name, i+1, err)
+			}
+			tmpl.ACLs = append(tmpl.ACLs, entry)
+		}
+		t.templates[strings.ToLower(name)] = tmpl
+	}
+	return t, nil
+}
+
+// toACL converts a JSON ACL entry.
+func (ja jsonACL) toACL() (acl.ACL, error) {
+	entry := acl.ACL{UserId: -1, ApplyHere: true, ApplySubs: true}
+	switch {
+	case ja.UserId != nil && len(ja.Group) > 0:
+		return entry, fmt.Errorf("both user_id and group given")
+	case ja.UserId != nil:
+		entry.UserId = *ja.UserId
+	case len(ja.Group) > 0:
+		entry.Group = ja.Group
+	default:
+		return entry, fmt.Errorf("neither user_id nor group given")
+	}
+	if ja.ApplyHere != nil {
+		entry.ApplyHere = *ja.ApplyHere
+	}
+	if ja.ApplySubs != nil {
+		entry.ApplySubs = *ja.ApplySubs
+	}
+	var err error
+	if entry.Allow, err = acl.ParsePermission(strings.Join(ja.Allow, ",")); err != nil {
+		return entry, fmt.Errorf("allow: %v", err)
+	}
+	if entry.Deny, err = acl.ParsePermission(strings.Join(ja.Deny, ",")); err != nil {
+		return entry, fmt.Errorf("deny: %v", err)
+	}
+	return entry, nil
+}
+
+// Lookup finds the template with the given name, ignoring case.
+func (t *Templates) Lookup(name string) (*Template, bool) {
+	if t == nil {
+		return nil, false
+	}
+	tmpl, ok := t.templates[strings.ToLower(name)]
+	return tmpl, ok
+}
+
+// Names returns the names of the templates, sorted.
+func (t *Templates) Names() []string {
+	if t == nil {
+		return nil
+	}
+	names := []string{}
+	for _, tmpl := range t.templates {
+		names = append(names, tmpl.Name)
+	}
+	sort.Strings(names)
+	return names
+}
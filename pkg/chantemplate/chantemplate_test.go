@@ -0,0 +1,75 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package chantemplate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mumble.info/grumble/pkg/acl"
+)
+
+const testTemplates = `{
+	"Squad": {
+		"description": "A channel for a squad of five.",
+		"position": 10,
+		"max_users": 5,
+		"acls": [
+			{"group": "all", "deny": ["speak"]},
+			{"group": "in", "allow": ["speak", "whisper"], "apply_subs": false},
+			{"user_id": 3, "allow": ["write"]}
+		]
+	},
+	"lounge": {}
+}`
+
+func TestParse(t *testing.T) {
+	tmpls, err := Parse(strings.NewReader(testTemplates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := tmpls.Names(); !reflect.DeepEqual(names, []string{"Squad", "lounge"}) {
+		t.Errorf("Names() = %v", names)
+	}
+
+	squad, ok := tmpls.Lookup("squad")
+	if !ok {
+		t.Fatal("squad template not found")
+	}
+	if squad.Name != "Squad" || squad.Position != 10 || squad.MaxUsers != 5 || squad.Description == "" {
+		t.Errorf("unexpected template: %+v", squad)
+	}
+	expected := []acl.ACL{
+		{UserId: -1, Group: "all", ApplyHere: true, ApplySubs: true, Deny: acl.SpeakPermission},
+		{UserId: -1, Group: "in", ApplyHere: true, Allow: acl.SpeakPermission | acl.WhisperPermission},
+		{UserId: 3, ApplyHere: true, ApplySubs: true, Allow: acl.WritePermission},
+	}
+	if !reflect.DeepEqual(squad.ACLs, expected) {
+		t.Errorf("ACLs = %+v, expected %+v", squad.ACLs, expected)
+	}
+
+	if _, ok := tmpls.Lookup("missing"); ok {
+		t.Error("found a template that doesn't exist")
+	}
+	var none *Templates
+	if _, ok := none.Lookup("squad"); ok {
+		t.Error("found a template in nil templates")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		`{"a": {"acls": [{"allow": ["speak"]}]}}`,
+		`{"a": {"acls": [{"group": "all", "user_id": 1}]}}`,
+		`{"a": {"acls": [{"group": "all", "allow": ["fly"]}]}}`,
+		`{"a": {"max_users": -1}}`,
+		`not json`,
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%q) succeeded", input)
+		}
+	}
+}
@@ -0,0 +1,592 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package client implements enough of a Mumble client to write
+// end-to-end tests against a running server, and to build bots.
+//
+// A Client connects, authenticates and keeps track of the server's
+// channels and users. It can move itself to another channel, send text
+// messages, and send and receive Opus audio. Audio is sent over UDP
+// once the server has answered a UDP ping, and tunnelled through the
+// TLS connection until then, or if UDP is disabled.
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/cryptstate"
+	"mumble.info/grumble/pkg/mumbleproto"
+)
+
+// The protocol version the client claims to speak.
+const clientVersion = 0x10205
+
+// The time between two pings, which keep the server from timing the
+// client out.
+const pingInterval = 5 * time.Second
+
+// The largest message the client accepts from the server.
+const maxMessageSize = 8 * 1024 * 1024
+
+// Config configures a Client.
+type Config struct {
+	Username string
+	// The server password or the user's password.
+	Password string
+	// Access tokens for the server's ACL groups.
+	Tokens []string
+
+	// The TLS configuration, which may hold a client certificate.
+	// If nil, the server's certificate isn't verified.
+	TLSConfig *tls.Config
+	// The crypto modes offered for UDP, most preferred first. If
+	// empty, cryptstate.DefaultMode is offered.
+	CryptoModes []string
+	// Whether audio is always tunnelled through the TLS connection.
+	DisableUDP bool
+	// How long Dial waits for the server to accept the client.
+	// Defaults to 30 seconds.
+	Timeout time.Duration
+
+	// OnTextMessage is called for each text message the client
+	// receives.
+	OnTextMessage func(*mumbleproto.TextMessage)
+	// OnAudio is called for each voice packet the client receives.
+	OnAudio func(*Audio)
+	// OnMessage is called for every control message the client
+	// receives, after the client has handled it.
+	OnMessage func(kind uint16, msg proto.Message)
+	// OnDisconnect is called once the connection is closed, with the
+	// reason. The error is nil if Close was called.
+	OnDisconnect func(error)
+}
+
+// A User is a user connected to the server.
+type User struct {
+	Session   uint32
+	UserID    int64 // -1 if the user isn't registered
+	Name      string
+	ChannelID uint32
+	Mute      bool
+	Deaf      bool
+	SelfMute  bool
+	SelfDeaf  bool
+}
+
+// A Channel is a channel on the server.
+type Channel struct {
+	ID       uint32
+	ParentID uint32
+	Name     string
+}
+
+// Audio is a voice packet received from the server.
+type Audio struct {
+	// The session of the speaker.
+	Session uint32
+	// The kind of voice packet, one of the mumbleproto.UDPMessage
+	// constants.
+	Kind byte
+	// 0 for normal talking, 1 for a whisper to the client's channel,
+	// 2 for a whisper to the client, and 0x1f for the server loopback.
+	Target   byte
+	Sequence uint64
+	// The audio. For Opus, this is one frame without its header;
+	// for the legacy codecs, the frames with their headers.
+	Data []byte
+	// Whether the speaker stopped talking.
+	Last bool
+
+	HasPosition bool
+	Position    [3]float32
+}
+
+// A Client is a connection to a Mumble server. Its methods may be
+// called from multiple goroutines.
+type Client struct {
+	config Config
+	conn   *tls.Conn
+	wmu    sync.Mutex
+
+	// The voice crypt state and the UDP socket.
+	cmu       sync.Mutex
+	crypt     cryptstate.CryptState
+	keyed     bool
+	udpconn   *net.UDPConn
+	udpClosed bool
+	udpActive bool
+	seq       uint64
+
+	mu       sync.Mutex
+	session  uint32
+	users    map[uint32]*User
+	channels map[uint32]*Channel
+	closed   bool
+
+	done chan struct{}
+	once sync.Once
+}
+
+// Dial connects to the server at addr, authenticates, and waits until
+// the server has sent its state.
+func Dial(addr string, config Config) (*Client, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if len(config.CryptoModes) == 0 {
+		config.CryptoModes = []string{cryptstate.DefaultMode}
+	}
+	tlsConfig := config.TLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: config.Timeout}, "tcp", addr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		config:   config,
+		conn:     conn,
+		users:    make(map[uint32]*User),
+		channels: make(map[uint32]*Channel),
+		done:     make(chan struct{}),
+	}
+	if err := c.handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if !config.DisableUDP {
+		if err := c.dialUDP(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	go c.tlsRecvLoop()
+	go c.pingLoop()
+	return c, nil
+}
+
+// handshake sends the client's version and credentials, and handles
+// the server's messages until it is synced.
+func (c *Client) handshake() error {
+	c.conn.SetDeadline(time.Now().Add(c.config.Timeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	err := c.Send(&mumbleproto.Version{
+		Version:     proto.Uint32(clientVersion),
+		Release:     proto.String("grumble client"),
+		CryptoModes: c.config.CryptoModes,
+	})
+	if err != nil {
+		return err
+	}
+	err = c.Send(&mumbleproto.Authenticate{
+		Username: proto.String(c.config.Username),
+		Password: proto.String(c.config.Password),
+		Tokens:   c.config.Tokens,
+		Opus:     proto.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		kind, msg, err := c.readMessage()
+		if err != nil {
+			return err
+		}
+		switch m := msg.(type) {
+		case *mumbleproto.Reject:
+			return &RejectError{Type: m.GetType(), Reason: m.GetReason()}
+		case *mumbleproto.ServerSync:
+			c.mu.Lock()
+			c.session = m.GetSession()
+			c.mu.Unlock()
+		}
+		if err := c.handleMessage(kind, msg); err != nil {
+			return err
+		}
+		if kind == mumbleproto.MessageServerSync {
+			return nil
+		}
+	}
+}
+
+// A RejectError is returned by Dial if the server rejected the client.
+type RejectError struct {
+	Type   mumbleproto.Reject_RejectType
+	Reason string
+}
+
+func (err *RejectError) Error() string {
+	return fmt.Sprintf("rejected by server: %v (%v)", err.Reason, err.Type)
+}
+
+// Session returns the client's session.
+func (c *Client) Session() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+// Self returns the client's own user.
+func (c *Client) Self() User {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if user, ok := c.users[c.session]; ok {
+		return *user
+	}
+	return User{Session: c.session, UserID: -1}
+}
+
+// Users returns the users connected to the server.
+func (c *Client) Users() []User {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	users := make([]User, 0, len(c.users))
+	for _, user := range c.users {
+		users = append(users, *user)
+	}
+	return users
+}
+
+// Channels returns the server's channels.
+func (c *Client) Channels() []Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	channels := make([]Channel, 0, len(c.channels))
+	for _, channel := range c.channels {
+		channels = append(channels, *channel)
+	}
+	return channels
+}
+
+// ChannelByName returns the first channel called name.
+func (c *Client) ChannelByName(name string) (Channel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, channel := range c.channels {
+		if channel.Name == name {
+			return *channel, true
+		}
+	}
+	return Channel{}, false
+}
+
+// Join asks the server to move the client to a channel. The server
+// answers with a UserState message if it does, or a PermissionDenied
+// message if it doesn't.
+func (c *Client) Join(channelID uint32) error {
+	return c.Send(&mumbleproto.UserState{
+		Session:   proto.Uint32(c.Session()),
+		ChannelId: proto.Uint32(channelID),
+	})
+}
+
+// SendText sends a text message to a channel.
+func (c *Client) SendText(channelID uint32, message string) error {
+	return c.Send(&mumbleproto.TextMessage{
+		ChannelId: []uint32{channelID},
+		Message:   proto.String(message),
+	})
+}
+
+// SendTextToUser sends a private text message to a user.
+func (c *Client) SendTextToUser(session uint32, message string) error {
+	return c.Send(&mumbleproto.TextMessage{
+		Session: []uint32{session},
+		Message: proto.String(message),
+	})
+}
+
+// SendAudio sends an Opus frame to target: 0 for the client's channel,
+// a voice target registered with a VoiceTarget message, or 0x1f for
+// the server loopback. last marks the end of a transmission.
+func (c *Client) SendAudio(target byte, frame []byte, last bool) error {
+	audio, err := mumbleproto.OpusAudio(frame, last)
+	if err != nil {
+		return errors.New("client: audio frame too large")
+	}
+	c.cmu.Lock()
+	seq := c.seq
+	c.seq++
+	udp := c.udpActive
+	c.cmu.Unlock()
+	packet := (&mumbleproto.UDPPacket{
+		Kind:     mumbleproto.UDPMessageVoiceOpus,
+		Target:   target,
+		Sequence: seq,
+		Audio:    audio,
+	}).Encode()
+
+	if udp {
+		return c.sendUDP(packet)
+	}
+	return c.Send(packet)
+}
+
+// UDPActive returns whether audio is sent over UDP.
+func (c *Client) UDPActive() bool {
+	c.cmu.Lock()
+	defer c.cmu.Unlock()
+	return c.udpActive
+}
+
+// Send sends a control message to the server. A []byte is sent as a
+// tunnelled voice packet.
+func (c *Client) Send(msg interface{}) error {
+	kind := mumbleproto.MessageType(msg)
+	var data []byte
+	if kind == mumbleproto.MessageUDPTunnel {
+		data = msg.([]byte)
+	} else {
+		protoMsg, ok := msg.(proto.Message)
+		if !ok {
+			return errors.New("client: expected a proto.Message")
+		}
+		var err error
+		data, err = proto.Marshal(protoMsg)
+		if err != nil {
+			return err
+		}
+	}
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.BigEndian, kind)
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	_, err := c.conn.Write(buf.Bytes())
+	return err
+}
+
+// Done returns a channel that is closed once the client is
+// disconnected.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close disconnects the client.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return c.conn.Close()
+}
+
+// disconnected cleans up after the connection was closed.
+func (c *Client) disconnected(err error) {
+	c.once.Do(func() {
+		c.conn.Close()
+		c.cmu.Lock()
+		if c.udpconn != nil {
+			c.udpClosed = true
+			c.udpconn.Close()
+		}
+		c.cmu.Unlock()
+		c.mu.Lock()
+		if c.closed {
+			err = nil
+		}
+		c.mu.Unlock()
+		close(c.done)
+		if c.config.OnDisconnect != nil {
+			c.config.OnDisconnect(err)
+		}
+	})
+}
+
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.Send(&mumbleproto.Ping{Timestamp: proto.Uint64(uint64(now.Unix()))})
+			c.sendUDPPing(now)
+		}
+	}
+}
+
+func (c *Client) tlsRecvLoop() {
+	for {
+		kind, msg, err := c.readMessage()
+		if err == nil {
+			err = c.handleMessage(kind, msg)
+		}
+		if err != nil {
+			c.disconnected(err)
+			return
+		}
+	}
+}
+
+// readMessage reads the next control message. Tunnelled voice packets
+// are handled right away, and returned as a nil message, as are
+// messages of unknown kinds.
+func (c *Client) readMessage() (uint16, proto.Message, error) {
+	var header [6]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		return 0, nil, err
+	}
+	kind := binary.BigEndian.Uint16(header[0:2])
+	length := binary.BigEndian.Uint32(header[2:6])
+	if length > maxMessageSize {
+		return 0, nil, fmt.Errorf("client: message of kind %v too large (%v bytes)", kind, length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(c.conn, buf); err != nil {
+		return 0, nil, err
+	}
+
+	if kind == mumbleproto.MessageUDPTunnel {
+		c.receiveVoice(buf)
+		return kind, nil, nil
+	}
+	msg := newMessage(kind)
+	if msg == nil {
+		return kind, nil, nil
+	}
+	if err := proto.Unmarshal(buf, msg); err != nil {
+		return 0, nil, err
+	}
+	return kind, msg, nil
+}
+
+// handleMessage updates the client's state from a control message.
+func (c *Client) handleMessage(kind uint16, msg proto.Message) error {
+	switch m := msg.(type) {
+	case *mumbleproto.CryptSetup:
+		if err := c.handleCryptSetup(m); err != nil {
+			return err
+		}
+	case *mumbleproto.ChannelState:
+		c.mu.Lock()
+		channel, ok := c.channels[m.GetChannelId()]
+		if !ok {
+			channel = &Channel{ID: m.GetChannelId()}
+			c.channels[channel.ID] = channel
+		}
+		if m.Parent != nil {
+			channel.ParentID = m.GetParent()
+		}
+		if m.Name != nil {
+			channel.Name = m.GetName()
+		}
+		c.mu.Unlock()
+	case *mumbleproto.ChannelRemove:
+		c.mu.Lock()
+		delete(c.channels, m.GetChannelId())
+		c.mu.Unlock()
+	case *mumbleproto.UserState:
+		c.mu.Lock()
+		c.applyUserState(m)
+		c.mu.Unlock()
+	case *mumbleproto.UserRemove:
+		c.mu.Lock()
+		delete(c.users, m.GetSession())
+		c.mu.Unlock()
+	case *mumbleproto.TextMessage:
+		if c.config.OnTextMessage != nil {
+			c.config.OnTextMessage(m)
+		}
+	}
+	if msg != nil && c.config.OnMessage != nil {
+		c.config.OnMessage(kind, msg)
+	}
+	return nil
+}
+
+// applyUserState applies the fields set in userstate.
+//
+// Must be called with c.mu held.
+func (c *Client) applyUserState(userstate *mumbleproto.UserState) {
+	user, ok := c.users[userstate.GetSession()]
+	if !ok {
+		user = &User{Session: userstate.GetSession(), UserID: -1}
+		c.users[user.Session] = user
+	}
+	if userstate.UserId != nil {
+		user.UserID = int64(userstate.GetUserId())
+	}
+	if userstate.Name != nil {
+		user.Name = userstate.GetName()
+	}
+	if userstate.ChannelId != nil {
+		user.ChannelID = userstate.GetChannelId()
+	}
+	if userstate.Mute != nil {
+		user.Mute = userstate.GetMute()
+	}
+	if userstate.Deaf != nil {
+		user.Deaf = userstate.GetDeaf()
+	}
+	if userstate.SelfMute != nil {
+		user.SelfMute = userstate.GetSelfMute()
+	}
+	if userstate.SelfDeaf != nil {
+		user.SelfDeaf = userstate.GetSelfDeaf()
+	}
+}
+
+// newMessage returns an empty message of the given kind, or nil if the
+// client doesn't know the kind.
+func newMessage(kind uint16) proto.Message {
+	switch kind {
+	case mumbleproto.MessageVersion:
+		return &mumbleproto.Version{}
+	case mumbleproto.MessagePing:
+		return &mumbleproto.Ping{}
+	case mumbleproto.MessageReject:
+		return &mumbleproto.Reject{}
+	case mumbleproto.MessageServerSync:
+		return &mumbleproto.ServerSync{}
+	case mumbleproto.MessageChannelRemove:
+		return &mumbleproto.ChannelRemove{}
+	case mumbleproto.MessageChannelState:
+		return &mumbleproto.ChannelState{}
+	case mumbleproto.MessageUserRemove:
+		return &mumbleproto.UserRemove{}
+	case mumbleproto.MessageUserState:
+		return &mumbleproto.UserState{}
+	case mumbleproto.MessageBanList:
+		return &mumbleproto.BanList{}
+	case mumbleproto.MessageTextMessage:
+		return &mumbleproto.TextMessage{}
+	case mumbleproto.MessagePermissionDenied:
+		return &mumbleproto.PermissionDenied{}
+	case mumbleproto.MessageACL:
+		return &mumbleproto.ACL{}
+	case mumbleproto.MessageQueryUsers:
+		return &mumbleproto.QueryUsers{}
+	case mumbleproto.MessageCryptSetup:
+		return &mumbleproto.CryptSetup{}
+	case mumbleproto.MessageContextActionModify:
+		return &mumbleproto.ContextActionModify{}
+	case mumbleproto.MessageUserList:
+		return &mumbleproto.UserList{}
+	case mumbleproto.MessagePermissionQuery:
+		return &mumbleproto.PermissionQuery{}
+	case mumbleproto.MessageCodecVersion:
+		return &mumbleproto.CodecVersion{}
+	case mumbleproto.MessageUserStats:
+		return &mumbleproto.UserStats{}
+	case mumbleproto.MessageServerConfig:
+		return &mumbleproto.ServerConfig{}
+	}
+	return nil
+}
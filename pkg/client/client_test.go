@@ -0,0 +1,244 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"mumble.info/grumble/pkg/cryptstate"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/packetdata"
+)
+
+// listen starts a TLS listener with a self-signed certificate.
+func listen(t *testing.T) net.Listener {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func writeMessage(w io.Writer, msg interface{}) error {
+	kind := mumbleproto.MessageType(msg)
+	data, ok := msg.([]byte)
+	if !ok {
+		var err error
+		data, err = proto.Marshal(msg.(proto.Message))
+		if err != nil {
+			return err
+		}
+	}
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.BigEndian, kind)
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func readMessage(r io.Reader) (uint16, []byte, error) {
+	var header [6]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint32(header[2:]))
+	_, err := io.ReadFull(r, buf)
+	return binary.BigEndian.Uint16(header[:2]), buf, err
+}
+
+// fakeServer accepts one client, syncs it, and then moves it when it
+// asks to, and echoes its text messages and voice packets.
+func fakeServer(t *testing.T, l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for _, want := range []uint16{mumbleproto.MessageVersion, mumbleproto.MessageAuthenticate} {
+		kind, buf, err := readMessage(conn)
+		if err != nil || kind != want {
+			t.Errorf("expected message of kind %v, got %v (%v)", want, kind, err)
+			return
+		}
+		if kind == mumbleproto.MessageAuthenticate {
+			auth := &mumbleproto.Authenticate{}
+			proto.Unmarshal(buf, auth)
+			if auth.GetUsername() != "bot" || !auth.GetOpus() {
+				t.Errorf("unexpected Authenticate %v", auth)
+			}
+		}
+	}
+
+	crypt := cryptstate.CryptState{}
+	crypt.GenerateKey(cryptstate.DefaultMode)
+	for _, msg := range []interface{}{
+		&mumbleproto.CryptSetup{Key: crypt.Key, ClientNonce: crypt.DecryptIV, ServerNonce: crypt.EncryptIV},
+		&mumbleproto.ChannelState{ChannelId: proto.Uint32(0), Name: proto.String("Root")},
+		&mumbleproto.ChannelState{ChannelId: proto.Uint32(1), Parent: proto.Uint32(0), Name: proto.String("Lobby")},
+		&mumbleproto.UserState{Session: proto.Uint32(3), Name: proto.String("alice"), UserId: proto.Uint32(5), ChannelId: proto.Uint32(1)},
+		&mumbleproto.UserState{Session: proto.Uint32(7), Name: proto.String("bot"), ChannelId: proto.Uint32(0)},
+		&mumbleproto.ServerSync{Session: proto.Uint32(7)},
+	} {
+		if err := writeMessage(conn, msg); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	for {
+		kind, buf, err := readMessage(conn)
+		if err != nil {
+			return
+		}
+		switch kind {
+		case mumbleproto.MessageUserState:
+			userstate := &mumbleproto.UserState{}
+			proto.Unmarshal(buf, userstate)
+			writeMessage(conn, userstate)
+		case mumbleproto.MessageTextMessage:
+			txt := &mumbleproto.TextMessage{}
+			proto.Unmarshal(buf, txt)
+			txt.Actor = proto.Uint32(3)
+			writeMessage(conn, txt)
+		case mumbleproto.MessageUDPTunnel:
+			out := make([]byte, len(buf)+5)
+			pds := packetdata.New(out[1:])
+			pds.PutUint32(3)
+			pds.PutBytes(buf[1:])
+			out[0] = buf[0] & 0xe0
+			writeMessage(conn, out[:1+pds.Size()])
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+	go fakeServer(t, l)
+
+	texts := make(chan *mumbleproto.TextMessage, 1)
+	audio := make(chan *Audio, 1)
+	moved := make(chan *mumbleproto.UserState, 1)
+	c, err := Dial(l.Addr().String(), Config{
+		Username:      "bot",
+		DisableUDP:    true,
+		Timeout:       5 * time.Second,
+		OnTextMessage: func(txt *mumbleproto.TextMessage) { texts <- txt },
+		OnAudio:       func(a *Audio) { audio <- a },
+		OnMessage: func(kind uint16, msg proto.Message) {
+			// The users' states are sent while syncing, too.
+			userstate, ok := msg.(*mumbleproto.UserState)
+			if ok && userstate.GetSession() == 7 && userstate.GetChannelId() == 1 {
+				moved <- userstate
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Session() != 7 || c.Self().Name != "bot" {
+		t.Errorf("unexpected self %+v", c.Self())
+	}
+	if len(c.Users()) != 2 || len(c.Channels()) != 2 {
+		t.Errorf("unexpected state %v %v", c.Users(), c.Channels())
+	}
+	lobby, ok := c.ChannelByName("Lobby")
+	if !ok || lobby.ID != 1 {
+		t.Fatalf("unexpected channel %+v", lobby)
+	}
+
+	if err := c.Join(lobby.ID); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-moved:
+		if c.Self().ChannelID != lobby.ID {
+			t.Errorf("expected to be in %v, in %v", lobby.ID, c.Self().ChannelID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the move")
+	}
+
+	if err := c.SendText(lobby.ID, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case txt := <-texts:
+		if txt.GetMessage() != "hello" || txt.GetActor() != 3 {
+			t.Errorf("unexpected text message %v", txt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the text message")
+	}
+
+	if err := c.SendAudio(0, []byte{1, 2, 3}, true); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case a := <-audio:
+		if a.Session != 3 || a.Kind != mumbleproto.UDPMessageVoiceOpus || !a.Last || !bytes.Equal(a.Data, []byte{1, 2, 3}) {
+			t.Errorf("unexpected audio %+v", a)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for audio")
+	}
+}
+
+func TestDialRejected(t *testing.T) {
+	l := listen(t)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		readMessage(conn)
+		readMessage(conn)
+		writeMessage(conn, &mumbleproto.Reject{
+			Type:   mumbleproto.Reject_WrongServerPW.Enum(),
+			Reason: proto.String("Invalid server password"),
+		})
+	}()
+
+	_, err := Dial(l.Addr().String(), Config{Username: "bot", Timeout: 5 * time.Second})
+	reject, ok := err.(*RejectError)
+	if !ok || reject.Type != mumbleproto.Reject_WrongServerPW {
+		t.Fatalf("expected a RejectError, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package client
+
+import (
+	"net"
+	"time"
+
+	"mumble.info/grumble/pkg/cryptstate"
+	"mumble.info/grumble/pkg/mumbleproto"
+	"mumble.info/grumble/pkg/packetdata"
+)
+
+// handleCryptSetup handles a CryptSetup message: a new key, the
+// server's nonce after a resync, or a request for the client's nonce.
+func (c *Client) handleCryptSetup(cs *mumbleproto.CryptSetup) error {
+	c.cmu.Lock()
+	defer c.cmu.Unlock()
+	switch {
+	case len(cs.Key) > 0:
+		// The server picks the mode from the client's offer the
+		// same way.
+		mode := cryptstate.NegotiateMode(c.config.CryptoModes)
+		if err := c.crypt.SetKey(mode, cs.Key, cs.ClientNonce, cs.ServerNonce); err != nil {
+			return err
+		}
+		c.keyed = true
+	case len(cs.ServerNonce) > 0 && c.keyed:
+		copy(c.crypt.DecryptIV, cs.ServerNonce)
+	case len(cs.ClientNonce) == 0 && c.keyed:
+		nonce := append([]byte(nil), c.crypt.EncryptIV...)
+		go c.Send(&mumbleproto.CryptSetup{ClientNonce: nonce})
+	}
+	return nil
+}
+
+// dialUDP opens the client's UDP socket and sends a first ping. Once
+// the server answers it, audio is sent over UDP.
+func (c *Client) dialUDP() error {
+	c.cmu.Lock()
+	keyed := c.keyed
+	c.cmu.Unlock()
+	if !keyed {
+		// The server didn't set up a crypt key; stick to TCP.
+		return nil
+	}
+	raddr, ok := c.conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return nil
+	}
+	udpconn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: raddr.IP, Port: raddr.Port, Zone: raddr.Zone})
+	if err != nil {
+		return err
+	}
+	c.cmu.Lock()
+	c.udpconn = udpconn
+	c.cmu.Unlock()
+	go c.udpRecvLoop(udpconn)
+	c.sendUDPPing(time.Now())
+	return nil
+}
+
+func (c *Client) sendUDPPing(now time.Time) {
+	c.cmu.Lock()
+	hasUDP := c.udpconn != nil
+	c.cmu.Unlock()
+	if !hasUDP {
+		return
+	}
+	ping := &mumbleproto.UDPPacket{
+		Kind:     mumbleproto.UDPMessagePing,
+		Sequence: uint64(now.UnixNano()),
+	}
+	c.sendUDP(ping.Encode())
+}
+
+func (c *Client) sendUDP(plain []byte) error {
+	c.cmu.Lock()
+	crypted := make([]byte, len(plain)+c.crypt.Overhead())
+	c.crypt.Encrypt(crypted, plain)
+	udpconn := c.udpconn
+	c.cmu.Unlock()
+	_, err := udpconn.Write(crypted)
+	return err
+}
+
+func (c *Client) udpRecvLoop(udpconn *net.UDPConn) {
+	buf := make([]byte, 2048)
+	for {
+		n, err := udpconn.Read(buf)
+		if err != nil {
+			// Reads fail on ICMP errors too, which only
+			// mean that a datagram was lost.
+			c.cmu.Lock()
+			closed := c.udpClosed
+			c.cmu.Unlock()
+			if closed {
+				return
+			}
+			continue
+		}
+		c.cmu.Lock()
+		if n <= c.crypt.Overhead() {
+			c.cmu.Unlock()
+			continue
+		}
+		plain := make([]byte, n-c.crypt.Overhead())
+		err = c.crypt.Decrypt(plain, buf[:n])
+		if err == nil {
+			c.udpActive = true
+		}
+		c.cmu.Unlock()
+		if err == nil {
+			c.receiveVoice(plain)
+		}
+	}
+}
+
+// receiveVoice handles a voice packet from the server, which sends the
+// speaker's session ahead of the packet as the speaker sent it.
+func (c *Client) receiveVoice(buf []byte) {
+	if c.config.OnAudio == nil || len(buf) == 0 {
+		return
+	}
+	kind := (buf[0] >> 5) & 0x07
+	if kind == mumbleproto.UDPMessagePing {
+		return
+	}
+	pds := packetdata.New(buf[1:])
+	session := pds.GetUint32()
+	if !pds.IsValid() {
+		return
+	}
+	rest := make([]byte, 1+pds.Left())
+	rest[0] = buf[0]
+	pds.CopyBytes(rest[1:])
+	packet, err := mumbleproto.ParseUDPPacket(rest)
+	if err != nil {
+		return
+	}
+
+	audio := &Audio{
+		Session:     session,
+		Kind:        packet.Kind,
+		Target:      packet.Target,
+		Sequence:    packet.Sequence,
+		Data:        packet.Audio,
+		Last:        packet.Terminator,
+		HasPosition: packet.HasPosition,
+		Position:    packet.Position,
+	}
+	if kind == mumbleproto.UDPMessageVoiceOpus {
+		frame := packetdata.New(packet.Audio)
+		frame.GetUint64()
+		audio.Data = packet.Audio[frame.Size():]
+	}
+	c.config.OnAudio(audio)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, good := range []string{"* * * * *", "0 20 * * fri", "*/15 9-17 * * mon-fri", "0 0 1,15 * *", "@daily", "0 12 * jan-mar 7"} {
+		if _, err := Parse(good); err != nil {
+			t.Errorf("Parse(%q): %v", good, err)
+		}
+	}
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5/2 * * * *", "* * * * foo", "10-5 * * * *", "*/0 * * * *"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	start := time.Date(2026, time.October, 16, 10, 7, 30, 0, time.UTC) // a Friday
+	for _, tc := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2026, time.October, 16, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, time.October, 16, 10, 15, 0, 0, time.UTC)},
+		{"0 20 * * fri", time.Date(2026, time.October, 16, 20, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-thu", time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1 * *", time.Date(2026, time.November, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted.
+		{"0 0 20 * fri", time.Date(2026, time.October, 20, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 feb *", time.Time{}},
+	} {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.expr, err)
+		}
+		if next := s.Next(start); !next.Equal(tc.next) {
+			t.Errorf("%q: expected %v, got %v", tc.expr, tc.next, next)
+		}
+		if !tc.next.IsZero() && !s.Matches(tc.next) {
+			t.Errorf("%q doesn't match %v", tc.expr, tc.next)
+		}
+	}
+}
@@ -41,14 +41,52 @@ type CryptState struct {
 
 	decryptHistory [decryptHistorySize]byte
 	mode           CryptoMode
+
+	// Packets encrypted and decrypted with the current key
+	encrypted uint64
+	decrypted uint64
+
+	// The decryption state of the previous key, and when it stops
+	// being accepted
+	previous       *CryptState
+	previousExpiry time.Time
 }
 
+// The CryptoMode used with clients that don't support any other.
+const DefaultMode = "OCB2-AES128"
+
+// The CryptoMode used with clients that support it, regardless of
+// their own preference.
+const PreferredMode = "AES256-GCM"
+
 // SupportedModes returns the list of supported CryptoModes.
 func SupportedModes() []string {
 	return []string{
 		"OCB2-AES128",
 		"XSalsa20-Poly1305",
+		"XChaCha20-Poly1305",
+		"AES256-GCM",
+	}
+}
+
+// NegotiateMode picks the CryptoMode to use with a client that supports
+// the modes in requested, in its order of preference. PreferredMode is
+// picked if requested holds it, and otherwise the first supported mode
+// in requested. If there is none, DefaultMode is picked.
+func NegotiateMode(requested []string) string {
+	for _, mode := range requested {
+		if mode == PreferredMode {
+			return mode
+		}
+	}
+	for _, mode := range requested {
+		for _, supported := range SupportedModes() {
+			if mode == supported {
+				return mode
+			}
+		}
 	}
+	return DefaultMode
 }
 
 // createMode creates the CryptoMode with the given mode name.
@@ -58,6 +96,10 @@ func createMode(mode string) (CryptoMode, error) {
 		return &ocb2Mode{}, nil
 	case "XSalsa20-Poly1305":
 		return &secretBoxMode{}, nil
+	case "XChaCha20-Poly1305":
+		return &xchachaMode{}, nil
+	case "AES256-GCM":
+		return &aesGCMMode{}, nil
 	}
 	return nil, errors.New("cryptstate: no such CryptoMode")
 }
@@ -77,6 +119,8 @@ func (cs *CryptState) GenerateKey(mode string) error {
 	cm.SetKey(key)
 	cs.mode = cm
 	cs.Key = key
+	cs.encrypted = 0
+	cs.decrypted = 0
 
 	cs.EncryptIV = make([]byte, cm.NonceSize())
 	_, err = io.ReadFull(rand.Reader, cs.EncryptIV)
@@ -102,6 +146,8 @@ func (cs *CryptState) SetKey(mode string, key []byte, eiv []byte, div []byte) er
 	cm.SetKey(key)
 	cs.mode = cm
 	cs.Key = key
+	cs.encrypted = 0
+	cs.decrypted = 0
 
 	cs.EncryptIV = eiv
 	cs.DecryptIV = div
@@ -109,13 +155,97 @@ func (cs *CryptState) SetKey(mode string, key []byte, eiv []byte, div []byte) er
 	return nil
 }
 
+// A Snapshot holds the state of a CryptState, so that it can be
+// carried on in another process.
+type Snapshot struct {
+	Mode           string
+	Key            []byte
+	EncryptIV      []byte
+	DecryptIV      []byte
+	DecryptHistory []byte
+	LastGoodTime   int64
+}
+
+// Snapshot returns the state of the CryptState, which was set up
+// for mode. The decryption state of a previous key is left out.
+func (cs *CryptState) Snapshot(mode string) Snapshot {
+	return Snapshot{
+		Mode:           mode,
+		Key:            append([]byte(nil), cs.Key...),
+		EncryptIV:      append([]byte(nil), cs.EncryptIV...),
+		DecryptIV:      append([]byte(nil), cs.DecryptIV...),
+		DecryptHistory: append([]byte(nil), cs.decryptHistory[:]...),
+		LastGoodTime:   cs.LastGoodTime,
+	}
+}
+
+// Restore sets the CryptState to the state in s, so that it goes on
+// encrypting and decrypting where the snapshotted one stopped.
+func (cs *CryptState) Restore(s Snapshot) error {
+	if len(s.DecryptHistory) != decryptHistorySize {
+		return errors.New("cryptstate: invalid decrypt history in snapshot")
+	}
+	err := cs.SetKey(s.Mode, append([]byte(nil), s.Key...), append([]byte(nil), s.EncryptIV...), append([]byte(nil), s.DecryptIV...))
+	if err != nil {
+		return err
+	}
+	copy(cs.decryptHistory[:], s.DecryptHistory)
+	cs.LastGoodTime = s.LastGoodTime
+	cs.previous = nil
+	return nil
+}
+
+// Rekey replaces the key and nonces with fresh ones for mode. Packets
+// encrypted with the previous key are still decrypted for grace, so
+// that packets sent before the other side learns of the new key
+// aren't lost.
+func (cs *CryptState) Rekey(mode string, grace time.Duration) error {
+	previous := &CryptState{
+		DecryptIV:      cs.DecryptIV,
+		decryptHistory: cs.decryptHistory,
+		mode:           cs.mode,
+	}
+	err := cs.GenerateKey(mode)
+	if err != nil {
+		return err
+	}
+	cs.previous = previous
+	cs.previousExpiry = time.Now().Add(grace)
+	return nil
+}
+
+// Packets returns the number of packets encrypted and decrypted with
+// the current key.
+func (cs *CryptState) Packets() uint64 {
+	return cs.encrypted + cs.decrypted
+}
+
 // Overhead returns the length, in bytes, that a ciphertext
 // is longer than a plaintext.
 func (cs *CryptState) Overhead() int {
 	return 1 + cs.mode.Overhead()
 }
 
+// Decrypt decrypts src into dst. Right after a Rekey, packets
+// encrypted with the previous key are decrypted too.
 func (cs *CryptState) Decrypt(dst, src []byte) error {
+	err := cs.decrypt(dst, src)
+	if err != nil && cs.previous != nil {
+		if time.Now().Before(cs.previousExpiry) {
+			if cs.previous.decrypt(dst, src) == nil {
+				return nil
+			}
+		} else {
+			cs.previous = nil
+		}
+	}
+	if err == nil {
+		cs.decrypted++
+	}
+	return err
+}
+
+func (cs *CryptState) decrypt(dst, src []byte) error {
 	if len(src) < cs.Overhead() {
 		return errors.New("cryptstate: crypted length too short to decrypt")
 	}
@@ -239,4 +369,5 @@ func (cs *CryptState) Encrypt(dst, src []byte) {
 
 	dst[0] = cs.EncryptIV[0]
 	cs.mode.Encrypt(dst[1:], src, cs.EncryptIV)
+	cs.encrypted++
 }
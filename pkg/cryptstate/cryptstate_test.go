@@ -9,6 +9,7 @@ import (
 	"crypto/aes"
 	"encoding/hex"
 	"testing"
+	"time"
 )
 
 func TestOCB2AES128Encrypt(t *testing.T) {
@@ -160,3 +161,194 @@ func TestXSalsa20Poly1305Decrypt(t *testing.T) {
 		t.Fatalf("mismatch! got\n%x\n, expected\n%x", dst, expected)
 	}
 }
+
+// Test that messages encrypted with the AEAD modes can be decrypted
+// by the other side, and that tampering is detected.
+func TestAEADRoundTrip(t *testing.T) {
+	for _, mode := range []string{"XChaCha20-Poly1305", "AES256-GCM"} {
+		cm, err := createMode(mode)
+		if err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		key := make([]byte, cm.KeySize())
+		eiv := make([]byte, cm.NonceSize())
+		div := make([]byte, cm.NonceSize())
+		for i := range key {
+			key[i] = byte(i)
+		}
+		for i := range eiv {
+			eiv[i] = 2
+			div[i] = 7
+		}
+
+		sender := CryptState{}
+		receiver := CryptState{}
+		sender.SetKey(mode, key, append([]byte(nil), eiv...), append([]byte(nil), div...))
+		receiver.SetKey(mode, key, append([]byte(nil), div...), append([]byte(nil), eiv...))
+
+		message := []byte("The quick brown fox jumps over the lazy dog")
+		crypted := make([]byte, len(message)+sender.Overhead())
+		sender.Encrypt(crypted, message)
+
+		dst := make([]byte, len(message))
+		if err := receiver.Decrypt(dst, crypted); err != nil {
+			t.Fatalf("%v: %v", mode, err)
+		}
+		if !bytes.Equal(dst, message) {
+			t.Fatalf("%v: mismatch! got\n%x\n, expected\n%x", mode, dst, message)
+		}
+
+		sender.Encrypt(crypted, message)
+		crypted[len(crypted)-1] ^= 1
+		if err := receiver.Decrypt(dst, crypted); err == nil {
+			t.Fatalf("%v: expected tampered message to be rejected", mode)
+		}
+	}
+}
+
+func TestNegotiateMode(t *testing.T) {
+	for _, tc := range []struct {
+		requested []string
+		expected  string
+	}{
+		{nil, "OCB2-AES128"},
+		{[]string{"Foo-Bar"}, "OCB2-AES128"},
+		{[]string{"XChaCha20-Poly1305", "OCB2-AES128"}, "XChaCha20-Poly1305"},
+		{[]string{"Foo-Bar", "XSalsa20-Poly1305"}, "XSalsa20-Poly1305"},
+		{[]string{"OCB2-AES128", "AES256-GCM"}, "AES256-GCM"},
+	} {
+		if got := NegotiateMode(tc.requested); got != tc.expected {
+			t.Errorf("NegotiateMode(%q) = %q; expected %q", tc.requested, got, tc.expected)
+		}
+	}
+}
+
+// Test that packets encrypted with the previous key are accepted for
+// the grace period after a Rekey, and rejected afterwards.
+func TestRekey(t *testing.T) {
+	client := CryptState{}
+	server := CryptState{}
+	if err := server.GenerateKey("AES256-GCM"); err != nil {
+		t.Fatal(err)
+	}
+	client.SetKey("AES256-GCM", server.Key, append([]byte(nil), server.DecryptIV...), append([]byte(nil), server.EncryptIV...))
+
+	message := []byte("voice")
+	crypted := make([]byte, len(message)+client.Overhead())
+	dst := make([]byte, len(message))
+	client.Encrypt(crypted, message)
+	if err := server.Decrypt(dst, crypted); err != nil {
+		t.Fatal(err)
+	}
+	if server.Packets() != 1 {
+		t.Errorf("expected 1 packet, got %v", server.Packets())
+	}
+
+	if err := server.Rekey("AES256-GCM", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if server.Packets() != 0 {
+		t.Errorf("expected packet count to be reset, got %v", server.Packets())
+	}
+	client.Encrypt(crypted, message)
+	if err := server.Decrypt(dst, crypted); err != nil {
+		t.Fatalf("expected packet with old key to be accepted: %v", err)
+	}
+	if !bytes.Equal(dst, message) {
+		t.Fatalf("mismatch! got\n%x\n, expected\n%x", dst, message)
+	}
+
+	server.previousExpiry = time.Now().Add(-time.Second)
+	client.Encrypt(crypted, message)
+	if err := server.Decrypt(dst, crypted); err == nil {
+		t.Fatalf("expected packet with expired key to be rejected")
+	}
+}
+
+// Test that a restored snapshot goes on where the original stopped,
+// and still rejects packets the original already decrypted.
+func TestSnapshot(t *testing.T) {
+	client := CryptState{}
+	server := CryptState{}
+	if err := server.GenerateKey("OCB2-AES128"); err != nil {
+		t.Fatal(err)
+	}
+	client.SetKey("OCB2-AES128", server.Key, append([]byte(nil), server.DecryptIV...), append([]byte(nil), server.EncryptIV...))
+
+	message := []byte("voice")
+	replayed := make([]byte, len(message)+client.Overhead())
+	dst := make([]byte, len(message))
+	client.Encrypt(replayed, message)
+	if err := server.Decrypt(dst, replayed); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := CryptState{}
+	if err := restored.Restore(server.Snapshot("OCB2-AES128")); err != nil {
+		t.Fatal(err)
+	}
+
+	crypted := make([]byte, len(message)+client.Overhead())
+	client.Encrypt(crypted, message)
+	if err := restored.Decrypt(dst, crypted); err != nil {
+		t.Fatalf("expected packet to be accepted after restore: %v", err)
+	}
+	if err := restored.Decrypt(dst, replayed); err == nil {
+		t.Fatalf("expected replayed packet to be rejected after restore")
+	}
+
+	restored.Encrypt(crypted, message)
+	if err := client.Decrypt(dst, crypted); err != nil {
+		t.Fatalf("expected packet from restored state to be accepted: %v", err)
+	}
+	if !bytes.Equal(dst, message) {
+		t.Fatalf("mismatch! got\n%x\n, expected\n%x", dst, message)
+	}
+}
+
+// A typical 20ms Opus voice packet.
+var benchPacket = make([]byte, 120)
+
+func benchmarkModes(b *testing.B, bench func(b *testing.B, sender, receiver *CryptState)) {
+	for _, mode := range SupportedModes() {
+		b.Run(mode, func(b *testing.B) {
+			sender := &CryptState{}
+			if err := sender.GenerateKey(mode); err != nil {
+				b.Fatal(err)
+			}
+			receiver := &CryptState{}
+			err := receiver.SetKey(mode, sender.Key, append([]byte(nil), sender.DecryptIV...), append([]byte(nil), sender.EncryptIV...))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(benchPacket)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			bench(b, sender, receiver)
+		})
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	benchmarkModes(b, func(b *testing.B, sender, receiver *CryptState) {
+		crypted := make([]byte, len(benchPacket)+sender.Overhead())
+		for i := 0; i < b.N; i++ {
+			sender.Encrypt(crypted, benchPacket)
+		}
+	})
+}
+
+// Decrypting needs a fresh packet each time, so this measures both
+// directions as a packet takes them through the server.
+func BenchmarkEncryptDecrypt(b *testing.B) {
+	benchmarkModes(b, func(b *testing.B, sender, receiver *CryptState) {
+		crypted := make([]byte, len(benchPacket)+sender.Overhead())
+		dst := make([]byte, len(benchPacket))
+		for i := 0; i < b.N; i++ {
+			sender.Encrypt(crypted, benchPacket)
+			if err := receiver.Decrypt(dst, crypted); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package cryptstate
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+)
+
+// aesGCMMode implements the AES256-GCM CryptoMode
+type aesGCMMode struct {
+	aead cipher.AEAD
+}
+
+// NonceSize returns the nonce size to be used with AES256-GCM.
+func (g *aesGCMMode) NonceSize() int {
+	return 12
+}
+
+// KeySize returns the key size to be used with AES256-GCM.
+func (g *aesGCMMode) KeySize() int {
+	return 32
+}
+
+// Overhead returns the overhead that a ciphertext has over a plaintext.
+// In the case of AES256-GCM the overhead is the authentication tag.
+func (g *aesGCMMode) Overhead() int {
+	return 16
+}
+
+// SetKey sets a new key. The key must have a length equal to KeySize().
+func (g *aesGCMMode) SetKey(key []byte) {
+	if len(key) != g.KeySize() {
+		panic("cryptstate: invalid key length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic("cryptstate: " + err.Error())
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic("cryptstate: " + err.Error())
+	}
+	g.aead = aead
+}
+
+// Encrypt encrypts a message using AES256-GCM and outputs it to dst.
+func (g *aesGCMMode) Encrypt(dst []byte, src []byte, nonce []byte) {
+	if len(dst) < len(src)+g.Overhead() {
+		panic("cryptstate: bad dst")
+	}
+
+	if len(nonce) != g.NonceSize() {
+		panic("cryptstate: bad nonce length")
+	}
+
+	g.aead.Seal(dst[0:0], nonce, src, nil)
+}
+
+// Decrypt decrypts a message using AES256-GCM and outputs it to dst.
+// Returns false if decryption failed (authentication tag mismatch).
+func (g *aesGCMMode) Decrypt(dst []byte, src []byte, nonce []byte) bool {
+	if len(src) <= g.Overhead() || len(dst) < len(src)-g.Overhead() {
+		panic("cryptstate: bad src")
+	}
+
+	if len(nonce) != g.NonceSize() {
+		panic("cryptstate: bad nonce length")
+	}
+
+	_, err := g.aead.Open(dst[0:0], nonce, src, nil)
+	return err == nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package cryptstate
+
+import (
+	"crypto/cipher"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// xchachaMode implements the XChaCha20-Poly1305 CryptoMode. Unlike
+// OCB2-AES128, it is fast without AES hardware acceleration.
+type xchachaMode struct {
+	aead cipher.AEAD
+}
+
+// NonceSize returns the nonce size to be used with XChaCha20-Poly1305.
+func (xc *xchachaMode) NonceSize() int {
+	return chacha20poly1305.NonceSizeX
+}
+
+// KeySize returns the key size to be used with XChaCha20-Poly1305.
+func (xc *xchachaMode) KeySize() int {
+	return chacha20poly1305.KeySize
+}
+
+// Overhead returns the overhead that a ciphertext has over a plaintext.
+// In the case of XChaCha20-Poly1305 the overhead is the authentication tag.
+func (xc *xchachaMode) Overhead() int {
+	return chacha20poly1305.Overhead
+}
+
+// SetKey sets a new key. The key must have a length equal to KeySize().
+func (xc *xchachaMode) SetKey(key []byte) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		panic("cryptstate: invalid key length")
+	}
+	xc.aead = aead
+}
+
+// Encrypt encrypts a message using XChaCha20-Poly1305 and outputs it to dst.
+func (xc *xchachaMode) Encrypt(dst []byte, src []byte, nonce []byte) {
+	if len(dst) < len(src)+xc.Overhead() {
+		panic("cryptstate: bad dst")
+	}
+
+	if len(nonce) != xc.NonceSize() {
+		panic("cryptstate: bad nonce length")
+	}
+
+	xc.aead.Seal(dst[0:0], nonce, src, nil)
+}
+
+// Decrypt decrypts a message using XChaCha20-Poly1305 and outputs it to dst.
+// Returns false if decryption failed (authentication tag mismatch).
+func (xc *xchachaMode) Decrypt(dst []byte, src []byte, nonce []byte) bool {
+	if len(src) <= xc.Overhead() || len(dst) < len(src)-xc.Overhead() {
+		panic("cryptstate: bad src")
+	}
+
+	if len(nonce) != xc.NonceSize() {
+		panic("cryptstate: bad nonce length")
+	}
+
+	_, err := xc.aead.Open(dst[0:0], nonce, src, nil)
+	return err == nil
+}
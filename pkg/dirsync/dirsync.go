@@ -0,0 +1,159 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package dirsync maps the groups of an external directory, such as an
+// LDAP server, to the ACL groups of channels.
+//
+// The mapping is read from a file with one mapping per line:
+//
+//	# channel  group       directory group
+//	0          admin       cn=grumble-admins,ou=groups,dc=example,dc=org
+//	3          developers  Development Team
+//
+// Each line makes the members of the directory group members of the ACL
+// group of the channel. The directory group is the rest of the line,
+// and matches a directory group by its full distinguished name, or by
+// the value of its first component ("grumble-admins" above), without
+// regard to case. Several directory groups may be mapped to the same
+// ACL group.
+package dirsync
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mumble.info/grumble/pkg/ldap"
+)
+
+// A Mapping is a single line of a mapping file.
+type Mapping struct {
+	Line           int
+	Channel        int
+	Group          string
+	DirectoryGroup string
+}
+
+// Load reads a mapping file.
+func Load(fn string) ([]Mapping, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads mappings from r.
+func Parse(r io.Reader) ([]Mapping, error) {
+	mappings := []Mapping{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.SplitN(strings.Join(strings.Fields(text), " "), " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %v: expected a channel, a group and a directory group", line)
+		}
+		channel, err := strconv.Atoi(fields[0])
+		if err != nil || channel < 0 {
+			return nil, fmt.Errorf("line %v: invalid channel %q", line, fields[0])
+		}
+		group := fields[1]
+		if strings.HasPrefix(group, "~") || strings.HasPrefix(group, "#") || strings.HasPrefix(group, "$") {
+			return nil, fmt.Errorf("line %v: %q is not a channel group", line, group)
+		}
+		for _, other := range mappings {
+			if other.Channel == channel && other.Group == group && strings.EqualFold(other.DirectoryGroup, fields[2]) {
+				return nil, fmt.Errorf("line %v: duplicate of line %v", line, other.Line)
+			}
+		}
+
+		mappings = append(mappings, Mapping{
+			Line:           line,
+			Channel:        channel,
+			Group:          group,
+			DirectoryGroup: fields[2],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// A Directory holds the groups read from a directory. It maps the name
+// of each group, or its distinguished name, to the user names of its
+// members.
+type Directory map[string][]string
+
+// Members returns the members of the directory group name, as matched
+// by a mapping.
+func (d Directory) Members(name string) ([]string, bool) {
+	if members, ok := d[name]; ok {
+		return members, true
+	}
+	for key, members := range d {
+		if strings.EqualFold(key, name) {
+			return members, true
+		}
+		if attr, value := ldap.FirstRDN(key); len(attr) > 0 && strings.EqualFold(value, name) {
+			return members, true
+		}
+	}
+	return nil, false
+}
+
+// ParseDocument reads a directory from a JSON document that lists the
+// members of each group:
+//
+//	{"groups": {"staff": ["alice", "bob"], "admins": ["alice"]}}
+func ParseDocument(r io.Reader) (Directory, error) {
+	var doc struct {
+		Groups map[string][]string `json:"groups"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Groups == nil {
+		return nil, fmt.Errorf("dirsync: document has no groups")
+	}
+	return Directory(doc.Groups), nil
+}
+
+// FromEntries makes a directory of the group entries returned by an
+// LDAP search. The members of a group are the values of its attributes
+// named by memberAttrs. Members given by distinguished name, as in the
+// member attribute of groupOfNames entries, are reduced to the value of
+// its first component: the user name "alice" for
+// "uid=alice,ou=people,dc=example,dc=org".
+func FromEntries(entries []ldap.Entry, memberAttrs []string) Directory {
+	d := Directory{}
+	for _, entry := range entries {
+		seen := map[string]bool{}
+		members := []string{}
+		for _, attr := range memberAttrs {
+			for _, v := range entry.Values(attr) {
+				_, name := ldap.FirstRDN(v)
+				if len(name) > 0 && !seen[name] {
+					seen[name] = true
+					members = append(members, name)
+				}
+			}
+		}
+		sort.Strings(members)
+		d[entry.DN] = members
+	}
+	return d
+}
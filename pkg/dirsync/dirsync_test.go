@@ -0,0 +1,78 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package dirsync
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mumble.info/grumble/pkg/ldap"
+)
+
+const testMap = `
+# channel  group       directory group
+0          admin       cn=grumble-admins,ou=groups,dc=example,dc=org
+3	developers	Development   Team
+`
+
+func TestParse(t *testing.T) {
+	mappings, err := Parse(strings.NewReader(testMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Mapping{
+		{Line: 3, Channel: 0, Group: "admin", DirectoryGroup: "cn=grumble-admins,ou=groups,dc=example,dc=org"},
+		{Line: 4, Channel: 3, Group: "developers", DirectoryGroup: "Development Team"},
+	}
+	if !reflect.DeepEqual(mappings, want) {
+		t.Errorf("Unexpected mappings %+v", mappings)
+	}
+
+	for _, bad := range []string{
+		"0 admin",
+		"x admin staff",
+		"-1 admin staff",
+		"0 ~sub staff",
+		"0 admin staff\n0 admin STAFF",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestDirectory(t *testing.T) {
+	d := FromEntries([]ldap.Entry{
+		{
+			DN: "cn=Development Team,ou=groups,dc=example,dc=org",
+			Attributes: map[string][]string{
+				"member":    {"uid=bob,ou=people,dc=example,dc=org", "uid=alice,ou=people,dc=example,dc=org"},
+				"memberUid": {"alice", "carol"},
+			},
+		},
+	}, []string{"member", "memberuid"})
+
+	for _, name := range []string{"development team", "CN=Development Team,ou=groups,dc=example,dc=org"} {
+		members, ok := d.Members(name)
+		if !ok || !reflect.DeepEqual(members, []string{"alice", "bob", "carol"}) {
+			t.Errorf("Unexpected members of %q: %v %v", name, members, ok)
+		}
+	}
+	if _, ok := d.Members("groups"); ok {
+		t.Errorf("Matched a group by a later component of its name")
+	}
+
+	d, err := ParseDocument(strings.NewReader(`{"groups": {"staff": ["alice"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members, ok := d.Members("Staff"); !ok || !reflect.DeepEqual(members, []string{"alice"}) {
+		t.Errorf("Unexpected members of staff: %v %v", members, ok)
+	}
+	if _, err := ParseDocument(strings.NewReader(`{"users": []}`)); err == nil {
+		t.Errorf("Expected error for a document without groups")
+	}
+}
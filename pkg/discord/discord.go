@@ -0,0 +1,164 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package discord implements the small part of Discord's bot REST API
+// needed to bridge a text channel: posting messages, and polling a
+// channel for new ones.
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// The API endpoint used by clients created by NewClient.
+const DefaultBaseURL = "https://discord.com/api/v10"
+
+// The maximum length of a message's content.
+const MaxMessageLength = 2000
+
+// A RateLimitError is returned when Discord refuses a request because
+// the bot is sending too quickly.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("discord: rate limited, retry after %v", e.RetryAfter)
+}
+
+// A Client talks to the Discord API on behalf of a bot.
+type Client struct {
+	Token   string
+	BaseURL string
+
+	client *http.Client
+}
+
+// NewClient creates a client for the bot with the given token.
+func NewClient(token string) *Client {
+	return &Client{
+		Token:   token,
+		BaseURL: DefaultBaseURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// A User is a Discord user or bot.
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+// DisplayName returns the name the user is shown as.
+func (u User) DisplayName() string {
+	if len(u.GlobalName) > 0 {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+// A Message is a message in a Discord channel.
+type Message struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Author  User   `json:"author"`
+}
+
+// Me returns the bot's own user.
+func (c *Client) Me() (*User, error) {
+	var user User
+	err := c.do(http.MethodGet, "/users/@me", nil, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Send posts a message to a channel. Content longer than
+// MaxMessageLength is truncated. Mentions in the content are not
+// turned into notifications.
+func (c *Client) Send(channelID, content string) error {
+	runes := []rune(content)
+	if len(runes) > MaxMessageLength {
+		content = string(runes[:MaxMessageLength-1]) + "…"
+	}
+	body := map[string]interface{}{
+		"content":          content,
+		"allowed_mentions": map[string][]string{"parse": {}},
+	}
+	return c.do(http.MethodPost, "/channels/"+url.PathEscape(channelID)+"/messages", body, nil)
+}
+
+// Messages returns up to 50 messages posted to a channel after the
+// message with the id after, oldest first. If after is empty, the
+// most recent messages are returned.
+func (c *Client) Messages(channelID, after string) ([]Message, error) {
+	v := url.Values{}
+	v.Set("limit", "50")
+	if len(after) > 0 {
+		v.Set("after", after)
+	}
+	var msgs []Message
+	err := c.do(http.MethodGet, "/channels/"+url.PathEscape(channelID)+"/messages?"+v.Encode(), nil, &msgs)
+	if err != nil {
+		return nil, err
+	}
+	// Discord lists messages newest first.
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+// do performs an API request. The request body is encoded from in,
+// and the response body decoded into out, if they are not nil.
+func (c *Client) do(method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+c.Token)
+	req.Header.Set("User-Agent", "DiscordBot (https://github.com/mumble-voip/grumble, 1)")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var limit struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		json.NewDecoder(resp.Body).Decode(&limit)
+		return &RateLimitError{RetryAfter: time.Duration(limit.RetryAfter * float64(time.Second))}
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New("discord: invalid bot token")
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("discord: %v %v returned %v", method, path, resp.Status)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
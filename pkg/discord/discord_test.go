@@ -0,0 +1,101 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package discord
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient("secret")
+	c.BaseURL = srv.URL
+	return c
+}
+
+func TestSend(t *testing.T) {
+	var got map[string]interface{}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/channels/42/messages" {
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"id": "1"}`))
+	})
+
+	err := c.Send("42", strings.Repeat("x", MaxMessageLength+10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := got["content"].(string)
+	if n := len([]rune(content)); n != MaxMessageLength {
+		t.Errorf("content has %v characters, expected %v", n, MaxMessageLength)
+	}
+	if _, ok := got["allowed_mentions"]; !ok {
+		t.Errorf("mentions not disabled")
+	}
+}
+
+func TestMessagesOldestFirst(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if after := r.URL.Query().Get("after"); after != "10" {
+			t.Errorf("after = %q, expected 10", after)
+		}
+		w.Write([]byte(`[
+			{"id": "12", "content": "second", "author": {"id": "7", "username": "bob"}},
+			{"id": "11", "content": "first", "author": {"id": "8", "username": "carol", "global_name": "Carol"}}
+		]`))
+	})
+
+	msgs, err := c.Messages("42", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 || msgs[0].ID != "11" || msgs[1].ID != "12" {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+	if name := msgs[0].Author.DisplayName(); name != "Carol" {
+		t.Errorf("display name %q, expected Carol", name)
+	}
+	if name := msgs[1].Author.DisplayName(); name != "bob" {
+		t.Errorf("display name %q, expected bob", name)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 1.5}`))
+	})
+
+	err := c.Send("42", "hello")
+	rl, ok := err.(*RateLimitError)
+	if !ok {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if rl.RetryAfter != 1500*time.Millisecond {
+		t.Errorf("retry after %v, expected 1.5s", rl.RetryAfter)
+	}
+}
+
+func TestInvalidToken(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	c.Token = "wrong"
+	if _, err := c.Me(); err == nil {
+		t.Fatal("expected an error for an invalid token")
+	}
+}
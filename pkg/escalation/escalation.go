@@ -0,0 +1,195 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package escalation implements policies that punish repeat offenders
+// more harshly.
+//
+// A policy is read from a rules file with one rule per line:
+//
+//	# offense  count  window  punishment  duration
+//	kick       3      24h     ban         1h
+//	ban        2      720h    ban         forever
+//
+// A rule applies when a user has received count offenses of its kind
+// (a kick, ban or mute) within window, the latest included. The user is
+// then given the punishment (a ban or a mute) for duration, or for good
+// if the duration is "forever". Windows and durations are in the
+// syntax of time.ParseDuration, and durations may be at most
+// MaxDuration, about 136 years.
+package escalation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxDuration is the longest punishment a rule may give. Bans store
+// their duration in seconds as a uint32.
+const MaxDuration = math.MaxUint32 * time.Second
+
+// The kinds of offenses that rules count.
+var offenses = []string{"kick", "ban", "mute"}
+
+// A Punishment is what a rule gives a repeat offender.
+type Punishment int
+
+const (
+	Ban Punishment = iota
+	Mute
+)
+
+var punishmentNames = []string{"ban", "mute"}
+
+func (p Punishment) String() string {
+	return punishmentNames[p]
+}
+
+// A Rule is a single line of a rules file.
+type Rule struct {
+	Line       int
+	Offense    string
+	Count      int
+	Window     time.Duration
+	Punishment Punishment
+	// Zero for a punishment that doesn't run out.
+	Duration time.Duration
+}
+
+// Describe says why the rule applies, as in "3 kicks within 24h0m0s".
+func (rule *Rule) Describe() string {
+	return fmt.Sprintf("%v %vs within %v", rule.Count, rule.Offense, rule.Window)
+}
+
+// A Policy is an ordered list of rules.
+type Policy struct {
+	Rules []*Rule
+}
+
+// Load reads a rules file.
+func Load(fn string) (*Policy, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads rules from r.
+func Parse(r io.Reader) (*Policy, error) {
+	policy := &Policy{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("line %v: expected 5 fields, got %v", line, len(fields))
+		}
+		offense, ok := lookup(offenses, fields[0])
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown offense %q", line, fields[0])
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("line %v: invalid count %q", line, fields[1])
+		}
+		window, err := time.ParseDuration(fields[2])
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("line %v: invalid window %q", line, fields[2])
+		}
+		punishment, ok := lookup(punishmentNames, fields[3])
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown punishment %q", line, fields[3])
+		}
+		var duration time.Duration
+		if !strings.EqualFold(fields[4], "forever") {
+			duration, err = time.ParseDuration(fields[4])
+			if err != nil || duration < time.Second || duration > MaxDuration {
+				return nil, fmt.Errorf("line %v: invalid duration %q", line, fields[4])
+			}
+		}
+
+		policy.Rules = append(policy.Rules, &Rule{
+			Line:       line,
+			Offense:    offenses[offense],
+			Count:      count,
+			Window:     window,
+			Punishment: Punishment(punishment),
+			Duration:   duration,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func lookup(names []string, name string) (int, bool) {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Window returns the longest window of the rules for offense, or 0 if
+// there are none. Offenses older than that never count.
+func (policy *Policy) Window(offense string) time.Duration {
+	var window time.Duration
+	for _, rule := range policy.Rules {
+		if rule.Offense == offense && rule.Window > window {
+			window = rule.Window
+		}
+	}
+	return window
+}
+
+// MaxWindow returns the longest window of all rules. Offenses older
+// than that never count.
+func (policy *Policy) MaxWindow() time.Duration {
+	var window time.Duration
+	for _, rule := range policy.Rules {
+		if rule.Window > window {
+			window = rule.Window
+		}
+	}
+	return window
+}
+
+// Check returns the rule that applies to a user who has just received
+// an offense at now, given the times of their offenses of that kind,
+// the latest included. If several rules apply, the one listed last
+// wins, so rules are best listed from the mildest to the harshest. If
+// none applies, Check returns nil.
+func (policy *Policy) Check(offense string, times []time.Time, now time.Time) *Rule {
+	var match *Rule
+	for _, rule := range policy.Rules {
+		if rule.Offense != offense {
+			continue
+		}
+		n := 0
+		for _, t := range times {
+			if !t.After(now) && now.Sub(t) < rule.Window {
+				n++
+			}
+		}
+		if n >= rule.Count {
+			match = rule
+		}
+	}
+	return match
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package escalation
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRules = `
+# offense  count  window  punishment  duration
+kick       3      24h     ban         1h
+KICK	5	24h	ban	forever
+mute       2      1h      mute        30m
+`
+
+func TestParse(t *testing.T) {
+	policy, err := Parse(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %v", len(policy.Rules))
+	}
+	rule := policy.Rules[1]
+	if rule.Line != 4 || rule.Offense != "kick" || rule.Count != 5 || rule.Window != 24*time.Hour || rule.Punishment != Ban || rule.Duration != 0 {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+	if rule := policy.Rules[2]; rule.Punishment != Mute || rule.Duration != 30*time.Minute {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+	if policy.Window("kick") != 24*time.Hour || policy.Window("ban") != 0 || policy.MaxWindow() != 24*time.Hour {
+		t.Errorf("Unexpected windows")
+	}
+
+	for _, bad := range []string{
+		"kick 3 24h ban",
+		"warn 3 24h ban 1h",
+		"kick 0 24h ban 1h",
+		"kick 3 1d ban 1h",
+		"kick 3 24h kick 1h",
+		"kick 3 24h ban 1ms",
+		"kick 3 24h ban 2000000h",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	policy, err := Parse(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	ago := func(durations ...time.Duration) []time.Time {
+		times := []time.Time{}
+		for _, d := range durations {
+			times = append(times, now.Add(-d))
+		}
+		return times
+	}
+
+	if rule := policy.Check("kick", ago(0, time.Hour), now); rule != nil {
+		t.Errorf("Expected no rule for 2 kicks, got line %v", rule.Line)
+	}
+	if rule := policy.Check("kick", ago(0, time.Hour, 25*time.Hour, 26*time.Hour), now); rule != nil {
+		t.Errorf("Expected old kicks not to count, got line %v", rule.Line)
+	}
+	if rule := policy.Check("kick", ago(0, time.Hour, 2*time.Hour), now); rule == nil || rule.Line != 3 {
+		t.Errorf("Expected line 3 for 3 kicks, got %+v", rule)
+	}
+	if rule := policy.Check("kick", ago(0, 1, 2, 3, 4, 5), now); rule == nil || rule.Line != 4 {
+		t.Errorf("Expected line 4 for 6 kicks, got %+v", rule)
+	}
+	if rule := policy.Check("ban", ago(0, 1, 2, 3, 4, 5), now); rule != nil {
+		t.Errorf("Expected no rule for bans, got line %v", rule.Line)
+	}
+	if desc := policy.Rules[0].Describe(); desc != "3 kicks within 24h0m0s" {
+		t.Errorf("Unexpected description %q", desc)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package features
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// An Entry is a single line of a denylist file.
+type Entry struct {
+	Match
+	Line int
+	// The message for rejected clients, if the line gives one
+	Message string
+}
+
+// A Denylist lists the clients to turn away.
+type Denylist struct {
+	Entries []*Entry
+}
+
+// LoadDenylist reads a denylist file.
+func LoadDenylist(fn string) (*Denylist, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseDenylist(f)
+}
+
+// ParseDenylist reads a denylist from r.
+func ParseDenylist(r io.Reader) (*Denylist, error) {
+	denylist := &Denylist{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %v: expected at least 2 fields, got %v", line, len(fields))
+		}
+		m, err := parseMatch(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		entry := &Entry{Match: m, Line: line}
+		if len(fields) > 2 {
+			// The message keeps its own spacing.
+			rest := strings.TrimSpace(text[len(fields[0]):])
+			entry.Message = strings.TrimSpace(rest[len(fields[1]):])
+		}
+		denylist.Entries = append(denylist.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return denylist, nil
+}
+
+// Check returns the first entry that picks out a client with the given
+// version and release string, or nil if there is none. denylist may be
+// nil.
+func (denylist *Denylist) Check(version uint32, release string) *Entry {
+	if denylist == nil {
+		return nil
+	}
+	for _, entry := range denylist.Entries {
+		if entry.Matches(version, release) {
+			return entry
+		}
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package features decides which protocol features a Mumble client
+// supports.
+//
+// Each feature came with a version of the Mumble protocol, and is
+// assumed for clients that report that version or a later one. Some
+// client builds get a feature wrong all the same. An overrides file
+// turns features on or off for them, with one rule per line:
+//
+//	# release   versions      features
+//	Mumble*     1.2.2         -DescBlobHash
+//	*           1.3.0-1.3.2   -BlobHash
+//	MyBot       *             +BlobHash +Recording
+//
+// A rule applies to clients whose release string matches the pattern,
+// in the syntax of path.Match, and whose version is in the range.
+// Versions are written as major.minor.patch. A single version matches
+// only itself, either end of a range may be left out, as in "-1.2.4",
+// and "*" matches all versions. Rules are applied in order, so later
+// rules win.
+//
+// A denylist file picks out clients the same way, and may give each
+// line a message for the clients it turns away:
+//
+//	# release   versions      message
+//	*           -1.2.3        Please upgrade to Mumble 1.3 or later.
+//	Mumble*     1.4.0-1.4.1
+package features
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// A Feature is part of the protocol that only some clients support.
+type Feature int
+
+const (
+	// PermissionDenied messages of type ChannelFull
+	ChannelFullDenial Feature = iota
+	// Channel descriptions sent as blob hashes
+	DescBlobHash
+	// User textures in the format of Mumble 1.2.2
+	NewTextures
+	// User textures and comments sent as blob hashes
+	BlobHash
+	// The recording flag of user states
+	Recording
+)
+
+var features = []struct {
+	name    string
+	version uint32
+}{
+	ChannelFullDenial: {"ChannelFullDenial", 0x10201},
+	DescBlobHash:      {"DescBlobHash", 0x10202},
+	NewTextures:       {"NewTextures", 0x10202},
+	BlobHash:          {"BlobHash", 0x10203},
+	Recording:         {"Recording", 0x10203},
+}
+
+func (f Feature) String() string {
+	return features[f].name
+}
+
+// Version returns the protocol version the feature came with.
+func (f Feature) Version() uint32 {
+	return features[f].version
+}
+
+// Lookup finds a feature by name, ignoring case.
+func Lookup(name string) (Feature, bool) {
+	for i, feature := range features {
+		if strings.EqualFold(feature.name, name) {
+			return Feature(i), true
+		}
+	}
+	return 0, false
+}
+
+// A Set is a set of features.
+type Set uint32
+
+// Has checks whether f is in the set.
+func (s Set) Has(f Feature) bool {
+	return s&(1<<uint(f)) != 0
+}
+
+// With returns the set with f added.
+func (s Set) With(f Feature) Set {
+	return s | 1<<uint(f)
+}
+
+// Names returns the names of the features in the set.
+func (s Set) Names() []string {
+	names := []string{}
+	for i, feature := range features {
+		if s.Has(Feature(i)) {
+			names = append(names, feature.name)
+		}
+	}
+	return names
+}
+
+// ForVersion returns the features of clients that report version.
+func ForVersion(version uint32) Set {
+	var s Set
+	for i, feature := range features {
+		if version >= feature.version {
+			s = s.With(Feature(i))
+		}
+	}
+	return s
+}
+
+// ParseVersion parses a version written as major.minor.patch, where
+// minor and patch may be left out, into its protocol encoding.
+func ParseVersion(s string) (uint32, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+	var version uint32
+	for i := 0; i < 3; i++ {
+		var n uint64
+		if i < len(parts) {
+			var err error
+			n, err = strconv.ParseUint(parts[i], 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("invalid version %q", s)
+			}
+		}
+		version = version<<8 | uint32(n)
+	}
+	return version, nil
+}
+
+// A Match picks out clients by release string and version.
+type Match struct {
+	Release string
+	// The versions matched, both included
+	MinVersion uint32
+	MaxVersion uint32
+}
+
+// Matches checks whether a client is picked out.
+func (m *Match) Matches(version uint32, release string) bool {
+	if version < m.MinVersion || version > m.MaxVersion {
+		return false
+	}
+	ok, _ := path.Match(m.Release, release)
+	return ok
+}
+
+// parseMatch parses the release pattern and version range that start
+// a line.
+func parseMatch(release, versions string) (Match, error) {
+	m := Match{Release: release, MaxVersion: ^uint32(0)}
+	if _, err := path.Match(release, ""); err != nil {
+		return m, fmt.Errorf("invalid release pattern %q", release)
+	}
+	if versions == "*" {
+		return m, nil
+	}
+	var err error
+	lo, hi := versions, versions
+	if i := strings.Index(versions, "-"); i >= 0 {
+		lo, hi = versions[:i], versions[i+1:]
+	}
+	if len(lo) > 0 {
+		if m.MinVersion, err = ParseVersion(lo); err != nil {
+			return m, err
+		}
+	}
+	if len(hi) > 0 {
+		if m.MaxVersion, err = ParseVersion(hi); err != nil {
+			return m, err
+		}
+	}
+	if m.MinVersion > m.MaxVersion {
+		return m, fmt.Errorf("invalid version range %q", versions)
+	}
+	return m, nil
+}
+
+// A Rule is a single line of an overrides file.
+type Rule struct {
+	Match
+	Line    int
+	Enable  Set
+	Disable Set
+}
+
+// Overrides is an ordered list of rules.
+type Overrides struct {
+	Rules []*Rule
+}
+
+// Load reads an overrides file.
+func Load(fn string) (*Overrides, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads rules from r.
+func Parse(r io.Reader) (*Overrides, error) {
+	overrides := &Overrides{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %v: expected at least 3 fields, got %v", line, len(fields))
+		}
+		m, err := parseMatch(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		rule := &Rule{Match: m, Line: line}
+		for _, field := range fields[2:] {
+			if len(field) < 2 || field[0] != '+' && field[0] != '-' {
+				return nil, fmt.Errorf("line %v: expected +feature or -feature, got %q", line, field)
+			}
+			feature, ok := Lookup(field[1:])
+			if !ok {
+				return nil, fmt.Errorf("line %v: unknown feature %q", line, field[1:])
+			}
+			if field[0] == '+' {
+				rule.Enable = rule.Enable.With(feature)
+			} else {
+				rule.Disable = rule.Disable.With(feature)
+			}
+		}
+		overrides.Rules = append(overrides.Rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// Resolve returns the features of a client with the given version and
+// release string. overrides may be nil.
+func (overrides *Overrides) Resolve(version uint32, release string) Set {
+	s := ForVersion(version)
+	if overrides == nil {
+		return s
+	}
+	for _, rule := range overrides.Rules {
+		if rule.Matches(version, release) {
+			s = s&^rule.Disable | rule.Enable
+		}
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package features
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestForVersion(t *testing.T) {
+	if s := ForVersion(0x10200); s != 0 {
+		t.Errorf("1.2.0 has features %v", s.Names())
+	}
+	s := ForVersion(0x10202)
+	if !s.Has(ChannelFullDenial) || !s.Has(DescBlobHash) || !s.Has(NewTextures) || s.Has(BlobHash) {
+		t.Errorf("unexpected features for 1.2.2: %v", s.Names())
+	}
+	if s := ForVersion(0x10400); len(s.Names()) != len(features) {
+		t.Errorf("1.4.0 lacks features: %v", s.Names())
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for s, expected := range map[string]uint32{"1.2.3": 0x10203, "1.4": 0x10400, "1": 0x10000} {
+		if v, err := ParseVersion(s); err != nil || v != expected {
+			t.Errorf("ParseVersion(%q) = %#x, %v", s, v, err)
+		}
+	}
+	for _, bad := range []string{"", "1.2.3.4", "1.256", "a.b"} {
+		if _, err := ParseVersion(bad); err == nil {
+			t.Errorf("ParseVersion(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	overrides, err := Parse(strings.NewReader(`
+# release   versions      features
+Mumble*     1.2.2         -DescBlobHash
+*           1.3.0-1.3.2   -blobhash
+MyBot       *             +BlobHash +Recording
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overrides.Rules) != 3 {
+		t.Fatalf("expected 3 rules, got %v", len(overrides.Rules))
+	}
+
+	for _, bad := range []string{
+		"Mumble 1.2.2",
+		"Mumble 1.2.2 DescBlobHash",
+		"Mumble 1.2.2 -Foo",
+		"Mumble 1.3-1.2 -BlobHash",
+		"Mumble x -BlobHash",
+		"[ 1.2.2 -BlobHash",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	overrides, err := Parse(strings.NewReader(`
+Mumble*     1.2.2         -DescBlobHash
+*           1.3.0-1.3.2   -BlobHash
+*           -1.2.1        +DescBlobHash
+MyBot       *             +BlobHash
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		version  uint32
+		release  string
+		feature  Feature
+		expected bool
+	}{
+		{0x10202, "Mumble 1.2.2", DescBlobHash, false},
+		{0x10202, "1.2.2", DescBlobHash, true},
+		{0x10301, "1.3.1", BlobHash, false},
+		{0x10303, "1.3.3", BlobHash, true},
+		{0x10200, "Old", DescBlobHash, true},
+		{0x10200, "MyBot", BlobHash, true},
+		{0x10200, "MyBot", Recording, false},
+	} {
+		if s := overrides.Resolve(tc.version, tc.release); s.Has(tc.feature) != tc.expected {
+			t.Errorf("%#x %q: expected %v to be %v", tc.version, tc.release, tc.feature, tc.expected)
+		}
+	}
+
+	var none *Overrides
+	if none.Resolve(0x10203, "") != ForVersion(0x10203) {
+		t.Errorf("nil overrides changed the features")
+	}
+}
+
+func TestDenylist(t *testing.T) {
+	denylist, err := ParseDenylist(strings.NewReader(`
+# release   versions      message
+*           -1.2.3        Please   upgrade to Mumble 1.3.
+Mumble*     1.4.0-1.4.1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := denylist.Check(0x10202, "1.2.2"); e == nil || e.Message != "Please   upgrade to Mumble 1.3." {
+		t.Errorf("unexpected entry for 1.2.2: %v", e)
+	}
+	if e := denylist.Check(0x10401, "Mumble 1.4.1"); e == nil || e.Line != 4 || e.Message != "" {
+		t.Errorf("unexpected entry for Mumble 1.4.1: %v", e)
+	}
+	if e := denylist.Check(0x10401, "1.4.1"); e != nil {
+		t.Errorf("1.4.1 is denied by line %v", e.Line)
+	}
+
+	var none *Denylist
+	if none.Check(0x10200, "") != nil {
+		t.Errorf("nil denylist denied a client")
+	}
+
+	for _, bad := range []string{"*", "* 1.x", "* 1.4-1.3"} {
+		if _, err := ParseDenylist(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseDenylist(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package federation implements the protocol that links a channel on
+// one Grumble server to a channel on another.
+//
+// The links of a server are read from a links file with one link per
+// line:
+//
+//	# channel  peer                     remote-channel  fingerprint
+//	3          voice.example.org:64750  5               3f2a...9c
+//	7          *                        2               b41e...07
+//
+// Each line links a local channel to a channel of the peer. The peer is
+// the federation address of the other server, which this server
+// connects to, or "*" if the other server connects to this one. The
+// fingerprint is the SHA-256 hash of the other server's certificate, as
+// printed by Fingerprint, and is checked in both directions: both
+// servers use their certificates in the TLS handshake. A link is only
+// made if both servers list it.
+//
+// Once connected, both sides send a Hello, and then frames of the other
+// types as the users of the linked channels come and go, talk and write.
+package federation
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of the protocol.
+const Version = 1
+
+// MaxFrameSize is the largest payload of a frame.
+const MaxFrameSize = 1 << 20
+
+// The types of frames.
+const (
+	// A Hello (JSON) opens the link.
+	FrameHello byte = iota + 1
+	// A User (JSON) joined the linked channel.
+	FrameJoin
+	// A User (JSON) left the linked channel.
+	FrameLeave
+	// A Text (JSON) was written to the linked channel.
+	FrameText
+	// A voice packet, as built by VoicePayload.
+	FrameVoice
+)
+
+// ErrFrameTooLarge is returned for frames larger than MaxFrameSize.
+var ErrFrameTooLarge = errors.New("federation: frame too large")
+
+// A Link is a single line of a links file.
+type Link struct {
+	Line    int
+	Channel int
+	// The address to connect to, or empty if the peer connects.
+	Peer          string
+	RemoteChannel int
+	Fingerprint   string
+}
+
+// Load reads a links file.
+func Load(fn string) ([]Link, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads links from r.
+func Parse(r io.Reader) ([]Link, error) {
+	links := []Link{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("line %v: expected 4 fields, got %v", line, len(fields))
+		}
+		channel, err := strconv.Atoi(fields[0])
+		if err != nil || channel < 0 {
+			return nil, fmt.Errorf("line %v: invalid channel %q", line, fields[0])
+		}
+		peer := fields[1]
+		if peer == "*" {
+			peer = ""
+		} else if _, _, err := net.SplitHostPort(peer); err != nil {
+			return nil, fmt.Errorf("line %v: invalid peer %q", line, fields[1])
+		}
+		remote, err := strconv.Atoi(fields[2])
+		if err != nil || remote < 0 {
+			return nil, fmt.Errorf("line %v: invalid remote channel %q", line, fields[2])
+		}
+		fingerprint := NormalizeFingerprint(fields[3])
+		if b, err := hex.DecodeString(fingerprint); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("line %v: invalid fingerprint %q", line, fields[3])
+		}
+		for _, other := range links {
+			if other.Channel == channel && other.Peer == peer && other.RemoteChannel == remote && other.Fingerprint == fingerprint {
+				return nil, fmt.Errorf("line %v: duplicate of line %v", line, other.Line)
+			}
+		}
+
+		links = append(links, Link{
+			Line:          line,
+			Channel:       channel,
+			Peer:          peer,
+			RemoteChannel: remote,
+			Fingerprint:   fingerprint,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Fingerprint returns the SHA-256 hash of cert, in hex.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeFingerprint returns fp in the form returned by Fingerprint,
+// so that fingerprints copied with colons or in upper case match.
+func NormalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.Replace(fp, ":", "", -1))
+}
+
+// Hello opens a link. Channel is the sender's channel, and PeerChannel
+// the receiver's.
+type Hello struct {
+	Version     int    `json:"version"`
+	Name        string `json:"name"`
+	Channel     int    `json:"channel"`
+	PeerChannel int    `json:"peer_channel"`
+}
+
+// User is a user in a linked channel. Id identifies the user on its own
+// server.
+type User struct {
+	Id   uint32 `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Text is a text message written by a user to a linked channel. Name
+// is the user's name, for users who aren't in the channel.
+type Text struct {
+	User    uint32 `json:"user"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// WriteFrame writes a frame of the given type to w: its type, the
+// length of its payload as a 32-bit big-endian integer, and the
+// payload.
+func WriteFrame(w io.Writer, typ byte, payload []byte) error {
+	if len(payload) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+	buf := make([]byte, 5+len(payload))
+	buf[0] = typ
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(payload)))
+	copy(buf[5:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// WriteJSON writes a frame of the given type with v as its payload.
+func WriteJSON(w io.Writer, typ byte, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return WriteFrame(w, typ, payload)
+}
+
+// ReadFrame reads a frame from r.
+func ReadFrame(r io.Reader) (typ byte, payload []byte, err error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > MaxFrameSize {
+		return 0, nil, ErrFrameTooLarge
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// VoicePayload builds the payload of a voice frame from the id of the
+// user talking and a Mumble voice packet without the sender's session:
+// the packet's header byte, followed by its sequence number, audio data
+// and position.
+func VoicePayload(user uint32, packet []byte) []byte {
+	payload := make([]byte, 4+len(packet))
+	binary.BigEndian.PutUint32(payload, user)
+	copy(payload[4:], packet)
+	return payload
+}
+
+// ParseVoicePayload splits the payload of a voice frame into the id of
+// the user talking and the voice packet.
+func ParseVoicePayload(payload []byte) (user uint32, packet []byte, err error) {
+	if len(payload) < 5 {
+		return 0, nil, errors.New("federation: short voice frame")
+	}
+	return binary.BigEndian.Uint32(payload), payload[4:], nil
+}
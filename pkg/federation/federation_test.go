@@ -0,0 +1,92 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package federation
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testFingerprint = "3F:2A:00:11:22:33:44:55:66:77:88:99:aa:bb:cc:dd:ee:ff:00:11:22:33:44:55:66:77:88:99:aa:bb:cc:dd"
+
+const testLinks = `
+# channel  peer                     remote-channel  fingerprint
+3          voice.example.org:64750  5               ` + testFingerprint + `
+7	*	2	3f2a00112233445566778899aabbccddeeff00112233445566778899aabbccdd
+`
+
+func TestParse(t *testing.T) {
+	links, err := Parse(strings.NewReader(testLinks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("Expected 2 links, got %v", len(links))
+	}
+	link := links[0]
+	if link.Line != 3 || link.Channel != 3 || link.Peer != "voice.example.org:64750" || link.RemoteChannel != 5 {
+		t.Errorf("Unexpected link %+v", link)
+	}
+	if link.Fingerprint != links[1].Fingerprint || link.Fingerprint != NormalizeFingerprint(testFingerprint) {
+		t.Errorf("Unexpected fingerprint %q", link.Fingerprint)
+	}
+	if links[1].Peer != "" {
+		t.Errorf("Expected no peer address, got %q", links[1].Peer)
+	}
+
+	for _, bad := range []string{
+		"3 voice.example.org:64750 5",
+		"x voice.example.org:64750 5 " + testFingerprint,
+		"3 voice.example.org 5 " + testFingerprint,
+		"3 voice.example.org:64750 -1 " + testFingerprint,
+		"3 voice.example.org:64750 5 3f2a",
+		"3 * 5 " + testFingerprint + "\n3 * 5 " + testFingerprint,
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, FrameHello, Hello{Version: Version, Name: "a", Channel: 3, PeerChannel: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFrame(&buf, FrameVoice, VoicePayload(42, []byte{0x80, 1, 2, 3})); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, payload, err := ReadFrame(&buf)
+	if err != nil || typ != FrameHello {
+		t.Fatalf("Unexpected frame %v: %v", typ, err)
+	}
+	if string(payload) != `{"version":1,"name":"a","channel":3,"peer_channel":5}` {
+		t.Errorf("Unexpected hello %s", payload)
+	}
+	typ, payload, err = ReadFrame(&buf)
+	if err != nil || typ != FrameVoice {
+		t.Fatalf("Unexpected frame %v: %v", typ, err)
+	}
+	user, packet, err := ParseVoicePayload(payload)
+	if err != nil || user != 42 || !bytes.Equal(packet, []byte{0x80, 1, 2, 3}) {
+		t.Errorf("Unexpected voice payload %v %v: %v", user, packet, err)
+	}
+	if _, _, err := ReadFrame(&buf); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+
+	if err := WriteFrame(&buf, FrameText, make([]byte, MaxFrameSize+1)); err != ErrFrameTooLarge {
+		t.Errorf("Expected ErrFrameTooLarge, got %v", err)
+	}
+	if _, _, err := ReadFrame(bytes.NewReader([]byte{FrameText, 0xff, 0xff, 0xff, 0xff})); err != ErrFrameTooLarge {
+		t.Errorf("Expected ErrFrameTooLarge, got %v", err)
+	}
+	if _, _, err := ReadFrame(bytes.NewReader([]byte{FrameText, 0, 0, 0, 4, 'a'})); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -19,3 +19,14 @@ var (
 	ErrRemainingBytesForRecord = errors.New("remaining bytes in record")
 	ErrRecordTooBig            = errors.New("the record in the file is too big")
 )
+
+// IsCorrupt checks whether err, returned by a Walker, means that the
+// log is damaged at this point, such as by a write that was cut short.
+// The transaction groups read before it are intact.
+func IsCorrupt(err error) bool {
+	switch err {
+	case ErrUnexpectedEndOfRecord, ErrCRC32Mismatch, ErrRemainingBytesForRecord, ErrRecordTooBig:
+		return true
+	}
+	return false
+}
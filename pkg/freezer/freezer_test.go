@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"testing"
@@ -23,6 +24,16 @@ var testValues []proto.Message = []proto.Message{
 	&UserRemove{Id: proto.Uint32(0)},
 	&Channel{Id: proto.Uint32(0), Name: proto.String("RootChannel")},
 	&ChannelRemove{Id: proto.Uint32(0)},
+	&Invite{Id: proto.Uint32(1), Groups: []string{"guests"}},
+	&InviteRemove{Id: proto.Uint32(1)},
+	&Ban{Id: proto.Uint32(1), Mask: proto.Uint32(128)},
+	&BanRemove{Id: proto.Uint32(1)},
+	&Report{Id: proto.Uint32(1), Excerpt: []*ChatLine{{Text: proto.String("hi")}}},
+	&ReportRemove{Id: proto.Uint32(1)},
+	&Announcement{Id: proto.Uint32(1), Schedule: proto.String("@daily"), ChannelIds: []uint32{0, 2}},
+	&AnnouncementRemove{Id: proto.Uint32(1)},
+	&WhisperPreset{Id: proto.Uint32(1), Name: proto.String("mods"), Channels: []*VoiceTargetChannel{{ChannelId: proto.Uint32(0), Group: proto.String("mods")}}},
+	&WhisperPresetRemove{Id: proto.Uint32(1)},
 }
 
 // Generate a byet slice representing an entry in a Tx record
@@ -178,6 +189,43 @@ func TestCRC32MismatchLog(t *testing.T) {
 	}
 }
 
+// Check that a log whose last write was cut short reads up to the
+// damaged transaction group.
+func TestTruncatedLog(t *testing.T) {
+	l, err := NewLogFile("truncated.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("truncated.log")
+
+	for _, val := range testValues[:2] {
+		if err = l.Put(val); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := ioutil.ReadFile("truncated.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker, err := NewReaderWalker(bytes.NewReader(buf[:len(buf)-3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := walker.Next()
+	if err != nil || len(entries) != 1 || !proto.Equal(entries[0].(proto.Message), testValues[0]) {
+		t.Fatalf("expected the first entry intact, got %v (%v)", entries, err)
+	}
+	_, err = walker.Next()
+	if !IsCorrupt(err) {
+		t.Errorf("expected a corrupt log, got %v", err)
+	}
+}
+
 // Test that unknown TxGroup values are not attempted to be
 // decoded.
 func TestUnknownTypeDecode(t *testing.T) {
@@ -294,3 +342,133 @@ func TestTxGroupCapacityEnforcement(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// Test that log entries are merged into a frozen server
+func TestApply(t *testing.T) {
+	fs := &Server{
+		Config:   []*ConfigKeyValuePair{{Key: proto.String("Foo"), Value: proto.String("1")}},
+		Channels: []*Channel{{Id: proto.Uint32(0), Name: proto.String("Root")}},
+		Users:    []*User{{Id: proto.Uint32(0), Name: proto.String("SuperUser")}},
+	}
+
+	Apply(fs, []interface{}{
+		&ConfigKeyValuePair{Key: proto.String("Foo")},
+		&ConfigKeyValuePair{Key: proto.String("Bar"), Value: proto.String("2")},
+		&Channel{Id: proto.Uint32(1), Name: proto.String("Lobby"), ParentId: proto.Uint32(0)},
+		&Channel{Id: proto.Uint32(1), Position: proto.Int64(3), ParentId: proto.Uint32(7)},
+		&Channel{Id: proto.Uint32(2), Position: proto.Int64(1)},
+		&ChannelRemove{Id: proto.Uint32(0)},
+		&User{Id: proto.Uint32(0), Email: proto.String("root@example.com")},
+		&User{Id: proto.Uint32(1), Name: proto.String("Alice")},
+		&UserRemove{Id: proto.Uint32(1)},
+	})
+
+	if len(fs.Config) != 1 || fs.Config[0].GetKey() != "Bar" || fs.Config[0].GetValue() != "2" {
+		t.Errorf("unexpected config: %v", fs.Config)
+	}
+	if len(fs.Channels) != 1 {
+		t.Fatalf("expected 1 channel, got %v", len(fs.Channels))
+	}
+	c := fs.Channels[0]
+	if c.GetName() != "Lobby" || c.GetPosition() != 3 || c.GetParentId() != 0 {
+		t.Errorf("unexpected channel: %v", c)
+	}
+	if len(fs.Users) != 1 || fs.Users[0].GetName() != "SuperUser" || fs.Users[0].GetEmail() != "root@example.com" {
+		t.Errorf("unexpected users: %v", fs.Users)
+	}
+}
+
+func TestApplyUserModeration(t *testing.T) {
+	fs := &Server{
+		Users: []*User{{Id: proto.Uint32(3), Name: proto.String("Alice")}},
+	}
+
+	Apply(fs, []interface{}{
+		&User{Id: proto.Uint32(3), Mute: proto.Bool(true), Deaf: proto.Bool(true), MuteExpires: proto.Int64(1700000000)},
+		&User{Id: proto.Uint32(3), Email: proto.String("alice@example.com")},
+		&User{Id: proto.Uint32(3), Deaf: proto.Bool(false), PrioritySpeaker: proto.Bool(true)},
+	})
+
+	u := fs.Users[0]
+	if !u.GetMute() || u.GetDeaf() || !u.GetPrioritySpeaker() || u.GetMuteExpires() != 1700000000 || u.GetEmail() != "alice@example.com" {
+		t.Errorf("unexpected user: %v", u)
+	}
+}
+
+func TestApplyUserCertificates(t *testing.T) {
+	fs := &Server{}
+
+	Apply(fs, []interface{}{
+		&User{Id: proto.Uint32(3), Name: proto.String("Alice"), CertHash: proto.String("aa"), Certificates: []*Certificate{
+			{Hash: proto.String("aa"), Name: proto.String("desktop")},
+			{Hash: proto.String("bb"), Name: proto.String("phone")},
+		}, Watches: []uint32{4, 5}, OfflineMessages: []*OfflineMessage{
+			{SenderId: proto.Uint32(4), Text: proto.String("hi")},
+		}, VoiceTargets: []*VoiceTarget{
+			{Id: proto.Uint32(2), UserIds: []uint32{4}},
+		}},
+		&User{Id: proto.Uint32(3), LastAddress: proto.String("192.0.2.1")},
+	})
+	u := fs.Users[0]
+	if len(u.Certificates) != 2 || u.Certificates[1].GetName() != "phone" || len(u.Watches) != 2 || len(u.OfflineMessages) != 1 || len(u.VoiceTargets) != 1 {
+		t.Errorf("partial record changed certificates, watches, messages or voice targets: %v", u)
+	}
+
+	Apply(fs, []interface{}{
+		&User{Id: proto.Uint32(3), Name: proto.String("Alice"), CertHash: proto.String("bb"), Certificates: []*Certificate{
+			{Hash: proto.String("bb"), Name: proto.String("phone"), LastSeen: proto.Int64(1700000000)},
+		}},
+	})
+	u = fs.Users[0]
+	if len(u.Certificates) != 1 || u.Certificates[0].GetLastSeen() != 1700000000 || u.GetCertHash() != "bb" || len(u.Watches) != 0 || len(u.OfflineMessages) != 0 || len(u.VoiceTargets) != 0 {
+		t.Errorf("unexpected user: %v", u)
+	}
+}
+
+func TestApplyReports(t *testing.T) {
+	fs := &Server{}
+	Apply(fs, []interface{}{
+		&Report{Id: proto.Uint32(1), Reason: proto.String("spam")},
+		&Report{Id: proto.Uint32(2), Reason: proto.String("abuse")},
+		&Report{Id: proto.Uint32(1), Reason: proto.String("spam"), Closed: proto.Int64(1700000000)},
+		&ReportRemove{Id: proto.Uint32(2)},
+	})
+	if len(fs.Reports) != 1 {
+		t.Fatalf("expected 1 report, got %v", len(fs.Reports))
+	}
+	if r := fs.Reports[0]; r.GetId() != 1 || r.GetClosed() != 1700000000 || r.GetReason() != "spam" {
+		t.Errorf("unexpected report: %v", r)
+	}
+}
+
+func TestApplyAnnouncements(t *testing.T) {
+	fs := &Server{}
+	Apply(fs, []interface{}{
+		&Announcement{Id: proto.Uint32(1), Schedule: proto.String("0 20 * * fri"), Enabled: proto.Bool(true)},
+		&Announcement{Id: proto.Uint32(2), Schedule: proto.String("@daily"), Enabled: proto.Bool(true)},
+		&Announcement{Id: proto.Uint32(1), Schedule: proto.String("0 20 * * fri"), ChannelIds: []uint32{3}},
+		&AnnouncementRemove{Id: proto.Uint32(2)},
+	})
+	if len(fs.Announcements) != 1 {
+		t.Fatalf("expected 1 announcement, got %v", len(fs.Announcements))
+	}
+	if a := fs.Announcements[0]; a.GetId() != 1 || a.GetEnabled() || len(a.ChannelIds) != 1 || a.ChannelIds[0] != 3 {
+		t.Errorf("unexpected announcement: %v", a)
+	}
+}
+
+func TestApplyWhisperPresets(t *testing.T) {
+	fs := &Server{}
+	Apply(fs, []interface{}{
+		&WhisperPreset{Id: proto.Uint32(1), Name: proto.String("mods"), Slot: proto.Uint32(5)},
+		&WhisperPreset{Id: proto.Uint32(2), Name: proto.String("leads"), Slot: proto.Uint32(6)},
+		&WhisperPreset{Id: proto.Uint32(1), Name: proto.String("moderators"), Slot: proto.Uint32(5), UserIds: []uint32{3}},
+		&WhisperPresetRemove{Id: proto.Uint32(2)},
+	})
+	if len(fs.WhisperPresets) != 1 {
+		t.Fatalf("expected 1 preset, got %v", len(fs.WhisperPresets))
+	}
+	if p := fs.WhisperPresets[0]; p.GetId() != 1 || p.GetName() != "moderators" || len(p.UserIds) != 1 {
+		t.Errorf("unexpected preset: %v", p)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package freezer
+
+// Apply merges the entries of a transaction group read from a Log
+// into fs, the way the server does when it loads its log.
+//
+// User and Channel entries are deltas: fields that are set overwrite
+// those of an existing user or channel, and a new user or channel is
+// only created if the entry has a name. User entries with a name are
+// full records, and replace the user's access tokens. Report,
+// Announcement and WhisperPreset entries always hold the whole record.
+// Entries without an id are ignored.
+func Apply(fs *Server, entries []interface{}) {
+	for _, entry := range entries {
+		switch val := entry.(type) {
+		case *Server:
+			*fs = *val
+		case *ConfigKeyValuePair:
+			applyConfig(fs, val)
+		case *BanList:
+			fs.BanList = val
+		case *User:
+			applyUser(fs, val)
+		case *UserRemove:
+			if val.Id == nil {
+				continue
+			}
+			for i, fu := range fs.Users {
+				if fu.GetId() == *val.Id {
+					fs.Users = append(fs.Users[:i], fs.Users[i+1:]...)
+					break
+				}
+			}
+		case *Channel:
+			applyChannel(fs, val)
+		case *ChannelRemove:
+			if val.Id == nil {
+				continue
+			}
+			for i, fc := range fs.Channels {
+				if fc.GetId() == *val.Id {
+					fs.Channels = append(fs.Channels[:i], fs.Channels[i+1:]...)
+					break
+				}
+			}
+		case *Invite:
+			if val.Id == nil {
+				continue
+			}
+			applyInvite(fs, val)
+		case *InviteRemove:
+			if val.Id == nil {
+				continue
+			}
+			for i, fi := range fs.Invites {
+				if fi.GetId() == *val.Id {
+					fs.Invites = append(fs.Invites[:i], fs.Invites[i+1:]...)
+					break
+				}
+			}
+		case *Report:
+			if val.Id == nil {
+				continue
+			}
+			applyReport(fs, val)
+		case *ReportRemove:
+			if val.Id == nil {
+				continue
+			}
+			for i, fr := range fs.Reports {
+				if fr.GetId() == *val.Id {
+					fs.Reports = append(fs.Reports[:i], fs.Reports[i+1:]...)
+					break
+				}
+			}
+		case *Announcement:
+			if val.Id == nil {
+				continue
+			}
+			applyAnnouncement(fs, val)
+		case *AnnouncementRemove:
+			if val.Id == nil {
+				continue
+			}
+			for i, fa := range fs.Announcements {
+				if fa.GetId() == *val.Id {
+					fs.Announcements = append(fs.Announcements[:i], fs.Announcements[i+1:]...)
+					break
+				}
+			}
+		case *WhisperPreset:
+			if val.Id == nil {
+				continue
+			}
+			applyWhisperPreset(fs, val)
+		case *WhisperPresetRemove:
+			if val.Id == nil {
+				continue
+			}
+			for i, fp := range fs.WhisperPresets {
+				if fp.GetId() == *val.Id {
+					fs.WhisperPresets = append(fs.WhisperPresets[:i], fs.WhisperPresets[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+}
+
+func applyConfig(fs *Server, kv *ConfigKeyValuePair) {
+	if kv.Key == nil {
+		return
+	}
+	for i, cfg := range fs.Config {
+		if cfg.GetKey() == *kv.Key {
+			if kv.Value == nil {
+				fs.Config = append(fs.Config[:i], fs.Config[i+1:]...)
+			} else {
+				cfg.Value = kv.Value
+			}
+			return
+		}
+	}
+	if kv.Value != nil {
+		fs.Config = append(fs.Config, kv)
+	}
+}
+
+func applyUser(fs *Server, delta *User) {
+	if delta.Id == nil {
+		return
+	}
+	var fu *User
+	for _, u := range fs.Users {
+		if u.GetId() == *delta.Id {
+			fu = u
+			break
+		}
+	}
+	if fu == nil {
+		if delta.Name == nil {
+			return
+		}
+		fu = &User{Id: delta.Id}
+		fs.Users = append(fs.Users, fu)
+	}
+
+	if delta.Name != nil {
+		fu.Name = delta.Name
+	}
+	if delta.Password != nil {
+		fu.Password = delta.Password
+	}
+	if delta.CertHash != nil {
+		fu.CertHash = delta.CertHash
+	}
+	if delta.Email != nil {
+		fu.Email = delta.Email
+	}
+	if delta.TextureBlob != nil {
+		fu.TextureBlob = delta.TextureBlob
+	}
+	if delta.CommentBlob != nil {
+		fu.CommentBlob = delta.CommentBlob
+	}
+	if delta.LastChannelId != nil {
+		fu.LastChannelId = delta.LastChannelId
+	}
+	if delta.LastActive != nil {
+		fu.LastActive = delta.LastActive
+	}
+	if delta.Mute != nil {
+		fu.Mute = delta.Mute
+	}
+	if delta.Deaf != nil {
+		fu.Deaf = delta.Deaf
+	}
+	if delta.PrioritySpeaker != nil {
+		fu.PrioritySpeaker = delta.PrioritySpeaker
+	}
+	if delta.MuteExpires != nil {
+		fu.MuteExpires = delta.MuteExpires
+	}
+	// Only full records carry the user's access tokens,
+	// certificates, watch list, offline messages and voice targets.
+	if delta.Name != nil {
+		fu.AccessTokens = delta.AccessTokens
+		fu.Certificates = delta.Certificates
+		fu.Watches = delta.Watches
+		fu.OfflineMessages = delta.OfflineMessages
+		fu.VoiceTargets = delta.VoiceTargets
+	}
+}
+
+func applyChannel(fs *Server, delta *Channel) {
+	if delta.Id == nil {
+		return
+	}
+	var fc *Channel
+	for _, c := range fs.Channels {
+		if c.GetId() == *delta.Id {
+			fc = c
+			break
+		}
+	}
+	if fc == nil {
+		if delta.Name == nil {
+			return
+		}
+		// The parent of a channel is only set when it is created.
+		fc = &Channel{Id: delta.Id, ParentId: delta.ParentId}
+		fs.Channels = append(fs.Channels, fc)
+	}
+
+	if delta.Name != nil {
+		fc.Name = delta.Name
+	}
+	if delta.Position != nil {
+		fc.Position = delta.Position
+	}
+	if delta.InheritAcl != nil {
+		fc.InheritAcl = delta.InheritAcl
+	}
+	if delta.DescriptionBlob != nil {
+		fc.DescriptionBlob = delta.DescriptionBlob
+	}
+	if delta.NoVoice != nil {
+		fc.NoVoice = delta.NoVoice
+	}
+	if delta.Silent != nil {
+		fc.Silent = delta.Silent
+	}
+	if delta.OwnerId != nil {
+		fc.OwnerId = delta.OwnerId
+	}
+	if delta.Acl != nil {
+		fc.Acl = delta.Acl
+	}
+	if delta.Groups != nil {
+		fc.Groups = delta.Groups
+	}
+	if delta.Links != nil {
+		fc.Links = delta.Links
+	}
+}
+
+func applyInvite(fs *Server, delta *Invite) {
+	var fi *Invite
+	for _, i := range fs.Invites {
+		if i.GetId() == *delta.Id {
+			fi = i
+			break
+		}
+	}
+	if fi == nil {
+		fi = &Invite{Id: delta.Id}
+		fs.Invites = append(fs.Invites, fi)
+	}
+
+	if delta.TokenHash != nil {
+		fi.TokenHash = delta.TokenHash
+		fi.Groups = delta.Groups
+		fi.Tokens = delta.Tokens
+	}
+	if delta.MaxUses != nil {
+		fi.MaxUses = delta.MaxUses
+	}
+	if delta.Uses != nil {
+		fi.Uses = delta.Uses
+	}
+	if delta.Expires != nil {
+		fi.Expires = delta.Expires
+	}
+	if delta.Note != nil {
+		fi.Note = delta.Note
+	}
+}
+
+func applyReport(fs *Server, report *Report) {
+	for i, fr := range fs.Reports {
+		if fr.GetId() == *report.Id {
+			fs.Reports[i] = report
+			return
+		}
+	}
+	fs.Reports = append(fs.Reports, report)
+}
+
+func applyAnnouncement(fs *Server, announcement *Announcement) {
+	for i, fa := range fs.Announcements {
+		if fa.GetId() == *announcement.Id {
+			fs.Announcements[i] = announcement
+			return
+		}
+	}
+	fs.Announcements = append(fs.Announcements, announcement)
+}
+
+func applyWhisperPreset(fs *Server, preset *WhisperPreset) {
+	for i, fp := range fs.WhisperPresets {
+		if fp.GetId() == *preset.Id {
+			fs.WhisperPresets[i] = preset
+			return
+		}
+	}
+	fs.WhisperPresets = append(fs.WhisperPresets, preset)
+}
@@ -14,4 +14,14 @@ const (
 	UserRemoveType
 	ChannelType
 	ChannelRemoveType
+	InviteType
+	InviteRemoveType
+	BanType
+	BanRemoveType
+	ReportType
+	ReportRemoveType
+	AnnouncementType
+	AnnouncementRemoveType
+	WhisperPresetType
+	WhisperPresetRemoveType
 )
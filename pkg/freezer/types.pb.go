@@ -18,6 +18,10 @@ type Server struct {
 	BanList          *BanList              `protobuf:"bytes,3,opt,name=ban_list" json:"ban_list,omitempty"`
 	Channels         []*Channel            `protobuf:"bytes,4,rep,name=channels" json:"channels,omitempty"`
 	Users            []*User               `protobuf:"bytes,5,rep,name=users" json:"users,omitempty"`
+	Invites          []*Invite             `protobuf:"bytes,6,rep,name=invites" json:"invites,omitempty"`
+	Reports          []*Report             `protobuf:"bytes,7,rep,name=reports" json:"reports,omitempty"`
+	Announcements    []*Announcement       `protobuf:"bytes,8,rep,name=announcements" json:"announcements,omitempty"`
+	WhisperPresets   []*WhisperPreset      `protobuf:"bytes,9,rep,name=whisper_presets" json:"whisper_presets,omitempty"`
 	XXX_unrecognized []byte                `json:"-"`
 }
 
@@ -64,6 +68,7 @@ type Ban struct {
 	Reason           *string `protobuf:"bytes,5,opt,name=reason" json:"reason,omitempty"`
 	Start            *int64  `protobuf:"varint,6,opt,name=start" json:"start,omitempty"`
 	Duration         *uint32 `protobuf:"varint,7,opt,name=duration" json:"duration,omitempty"`
+	Id               *uint32 `protobuf:"varint,8,opt,name=id" json:"id,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -120,6 +125,13 @@ func (this *Ban) GetDuration() uint32 {
 	return 0
 }
 
+func (this *Ban) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
 type BanList struct {
 	Bans             []*Ban `protobuf:"bytes,1,rep,name=bans" json:"bans,omitempty"`
 	XXX_unrecognized []byte `json:"-"`
@@ -130,16 +142,27 @@ func (this *BanList) String() string { return proto.CompactTextString(this) }
 func (*BanList) ProtoMessage()       {}
 
 type User struct {
-	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
-	Name             *string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
-	Password         *string `protobuf:"bytes,3,opt,name=password" json:"password,omitempty"`
-	CertHash         *string `protobuf:"bytes,4,opt,name=cert_hash" json:"cert_hash,omitempty"`
-	Email            *string `protobuf:"bytes,5,opt,name=email" json:"email,omitempty"`
-	TextureBlob      *string `protobuf:"bytes,6,opt,name=texture_blob" json:"texture_blob,omitempty"`
-	CommentBlob      *string `protobuf:"bytes,7,opt,name=comment_blob" json:"comment_blob,omitempty"`
-	LastChannelId    *uint32 `protobuf:"varint,8,opt,name=last_channel_id" json:"last_channel_id,omitempty"`
-	LastActive       *uint64 `protobuf:"varint,9,opt,name=last_active" json:"last_active,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Id               *uint32           `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Name             *string           `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Password         *string           `protobuf:"bytes,3,opt,name=password" json:"password,omitempty"`
+	CertHash         *string           `protobuf:"bytes,4,opt,name=cert_hash" json:"cert_hash,omitempty"`
+	Email            *string           `protobuf:"bytes,5,opt,name=email" json:"email,omitempty"`
+	TextureBlob      *string           `protobuf:"bytes,6,opt,name=texture_blob" json:"texture_blob,omitempty"`
+	CommentBlob      *string           `protobuf:"bytes,7,opt,name=comment_blob" json:"comment_blob,omitempty"`
+	LastChannelId    *uint32           `protobuf:"varint,8,opt,name=last_channel_id" json:"last_channel_id,omitempty"`
+	LastActive       *uint64           `protobuf:"varint,9,opt,name=last_active" json:"last_active,omitempty"`
+	AccessTokens     []*AccessToken    `protobuf:"bytes,10,rep,name=access_tokens" json:"access_tokens,omitempty"`
+	Mute             *bool             `protobuf:"varint,11,opt,name=mute" json:"mute,omitempty"`
+	Deaf             *bool             `protobuf:"varint,12,opt,name=deaf" json:"deaf,omitempty"`
+	PrioritySpeaker  *bool             `protobuf:"varint,13,opt,name=priority_speaker" json:"priority_speaker,omitempty"`
+	MuteExpires      *int64            `protobuf:"varint,14,opt,name=mute_expires" json:"mute_expires,omitempty"`
+	LastAddress      *string           `protobuf:"bytes,15,opt,name=last_address" json:"last_address,omitempty"`
+	ConnectedTime    *uint64           `protobuf:"varint,16,opt,name=connected_time" json:"connected_time,omitempty"`
+	Certificates     []*Certificate    `protobuf:"bytes,17,rep,name=certificates" json:"certificates,omitempty"`
+	Watches          []uint32          `protobuf:"varint,18,rep,name=watches" json:"watches,omitempty"`
+	OfflineMessages  []*OfflineMessage `protobuf:"bytes,19,rep,name=offline_messages" json:"offline_messages,omitempty"`
+	VoiceTargets     []*VoiceTarget    `protobuf:"bytes,20,rep,name=voice_targets" json:"voice_targets,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
 }
 
 func (this *User) Reset()         { *this = User{} }
@@ -209,6 +232,226 @@ func (this *User) GetLastActive() uint64 {
 	return 0
 }
 
+func (this *User) GetMute() bool {
+	if this != nil && this.Mute != nil {
+		return *this.Mute
+	}
+	return false
+}
+
+func (this *User) GetDeaf() bool {
+	if this != nil && this.Deaf != nil {
+		return *this.Deaf
+	}
+	return false
+}
+
+func (this *User) GetPrioritySpeaker() bool {
+	if this != nil && this.PrioritySpeaker != nil {
+		return *this.PrioritySpeaker
+	}
+	return false
+}
+
+func (this *User) GetMuteExpires() int64 {
+	if this != nil && this.MuteExpires != nil {
+		return *this.MuteExpires
+	}
+	return 0
+}
+
+func (this *User) GetLastAddress() string {
+	if this != nil && this.LastAddress != nil {
+		return *this.LastAddress
+	}
+	return ""
+}
+
+func (this *User) GetConnectedTime() uint64 {
+	if this != nil && this.ConnectedTime != nil {
+		return *this.ConnectedTime
+	}
+	return 0
+}
+
+type Certificate struct {
+	Hash             *string `protobuf:"bytes,1,opt,name=hash" json:"hash,omitempty"`
+	Name             *string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Added            *int64  `protobuf:"varint,3,opt,name=added" json:"added,omitempty"`
+	LastSeen         *int64  `protobuf:"varint,4,opt,name=last_seen" json:"last_seen,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *Certificate) Reset()         { *this = Certificate{} }
+func (this *Certificate) String() string { return proto.CompactTextString(this) }
+func (*Certificate) ProtoMessage()       {}
+
+func (this *Certificate) GetHash() string {
+	if this != nil && this.Hash != nil {
+		return *this.Hash
+	}
+	return ""
+}
+
+func (this *Certificate) GetName() string {
+	if this != nil && this.Name != nil {
+		return *this.Name
+	}
+	return ""
+}
+
+func (this *Certificate) GetAdded() int64 {
+	if this != nil && this.Added != nil {
+		return *this.Added
+	}
+	return 0
+}
+
+func (this *Certificate) GetLastSeen() int64 {
+	if this != nil && this.LastSeen != nil {
+		return *this.LastSeen
+	}
+	return 0
+}
+
+type VoiceTarget struct {
+	Id               *uint32               `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	PresetId         *uint32               `protobuf:"varint,2,opt,name=preset_id" json:"preset_id,omitempty"`
+	Channels         []*VoiceTargetChannel `protobuf:"bytes,3,rep,name=channels" json:"channels,omitempty"`
+	UserIds          []uint32              `protobuf:"varint,4,rep,name=user_ids" json:"user_ids,omitempty"`
+	XXX_unrecognized []byte                `json:"-"`
+}
+
+func (this *VoiceTarget) Reset()         { *this = VoiceTarget{} }
+func (this *VoiceTarget) String() string { return proto.CompactTextString(this) }
+func (*VoiceTarget) ProtoMessage()       {}
+
+func (this *VoiceTarget) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+func (this *VoiceTarget) GetPresetId() uint32 {
+	if this != nil && this.PresetId != nil {
+		return *this.PresetId
+	}
+	return 0
+}
+
+type VoiceTargetChannel struct {
+	ChannelId        *uint32 `protobuf:"varint,1,opt,name=channel_id" json:"channel_id,omitempty"`
+	Children         *bool   `protobuf:"varint,2,opt,name=children" json:"children,omitempty"`
+	Links            *bool   `protobuf:"varint,3,opt,name=links" json:"links,omitempty"`
+	Group            *string `protobuf:"bytes,4,opt,name=group" json:"group,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *VoiceTargetChannel) Reset()         { *this = VoiceTargetChannel{} }
+func (this *VoiceTargetChannel) String() string { return proto.CompactTextString(this) }
+func (*VoiceTargetChannel) ProtoMessage()       {}
+
+func (this *VoiceTargetChannel) GetChannelId() uint32 {
+	if this != nil && this.ChannelId != nil {
+		return *this.ChannelId
+	}
+	return 0
+}
+
+func (this *VoiceTargetChannel) GetChildren() bool {
+	if this != nil && this.Children != nil {
+		return *this.Children
+	}
+	return false
+}
+
+func (this *VoiceTargetChannel) GetLinks() bool {
+	if this != nil && this.Links != nil {
+		return *this.Links
+	}
+	return false
+}
+
+func (this *VoiceTargetChannel) GetGroup() string {
+	if this != nil && this.Group != nil {
+		return *this.Group
+	}
+	return ""
+}
+
+type OfflineMessage struct {
+	SenderId         *uint32 `protobuf:"varint,1,opt,name=sender_id" json:"sender_id,omitempty"`
+	SenderName       *string `protobuf:"bytes,2,opt,name=sender_name" json:"sender_name,omitempty"`
+	Text             *string `protobuf:"bytes,3,opt,name=text" json:"text,omitempty"`
+	Sent             *int64  `protobuf:"varint,4,opt,name=sent" json:"sent,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *OfflineMessage) Reset()         { *this = OfflineMessage{} }
+func (this *OfflineMessage) String() string { return proto.CompactTextString(this) }
+func (*OfflineMessage) ProtoMessage()       {}
+
+func (this *OfflineMessage) GetSenderId() uint32 {
+	if this != nil && this.SenderId != nil {
+		return *this.SenderId
+	}
+	return 0
+}
+
+func (this *OfflineMessage) GetSenderName() string {
+	if this != nil && this.SenderName != nil {
+		return *this.SenderName
+	}
+	return ""
+}
+
+func (this *OfflineMessage) GetText() string {
+	if this != nil && this.Text != nil {
+		return *this.Text
+	}
+	return ""
+}
+
+func (this *OfflineMessage) GetSent() int64 {
+	if this != nil && this.Sent != nil {
+		return *this.Sent
+	}
+	return 0
+}
+
+type AccessToken struct {
+	Token            *string `protobuf:"bytes,1,opt,name=token" json:"token,omitempty"`
+	ChannelId        *uint32 `protobuf:"varint,2,opt,name=channel_id" json:"channel_id,omitempty"`
+	Expires          *int64  `protobuf:"varint,3,opt,name=expires" json:"expires,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *AccessToken) Reset()         { *this = AccessToken{} }
+func (this *AccessToken) String() string { return proto.CompactTextString(this) }
+func (*AccessToken) ProtoMessage()       {}
+
+func (this *AccessToken) GetToken() string {
+	if this != nil && this.Token != nil {
+		return *this.Token
+	}
+	return ""
+}
+
+func (this *AccessToken) GetChannelId() uint32 {
+	if this != nil && this.ChannelId != nil {
+		return *this.ChannelId
+	}
+	return 0
+}
+
+func (this *AccessToken) GetExpires() int64 {
+	if this != nil && this.Expires != nil {
+		return *this.Expires
+	}
+	return 0
+}
+
 type UserRemove struct {
 	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
@@ -235,6 +478,9 @@ type Channel struct {
 	Acl              []*ACL   `protobuf:"bytes,7,rep,name=acl" json:"acl,omitempty"`
 	Groups           []*Group `protobuf:"bytes,8,rep,name=groups" json:"groups,omitempty"`
 	DescriptionBlob  *string  `protobuf:"bytes,9,opt,name=description_blob" json:"description_blob,omitempty"`
+	NoVoice          *bool    `protobuf:"varint,10,opt,name=no_voice" json:"no_voice,omitempty"`
+	Silent           *bool    `protobuf:"varint,11,opt,name=silent" json:"silent,omitempty"`
+	OwnerId          *uint32  `protobuf:"varint,12,opt,name=owner_id" json:"owner_id,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -284,6 +530,27 @@ func (this *Channel) GetDescriptionBlob() string {
 	return ""
 }
 
+func (this *Channel) GetNoVoice() bool {
+	if this != nil && this.NoVoice != nil {
+		return *this.NoVoice
+	}
+	return false
+}
+
+func (this *Channel) GetSilent() bool {
+	if this != nil && this.Silent != nil {
+		return *this.Silent
+	}
+	return false
+}
+
+func (this *Channel) GetOwnerId() uint32 {
+	if this != nil && this.OwnerId != nil {
+		return *this.OwnerId
+	}
+	return 0
+}
+
 type ChannelRemove struct {
 	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
@@ -390,5 +657,379 @@ func (this *Group) GetInheritable() bool {
 	return false
 }
 
+type Invite struct {
+	Id               *uint32  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	TokenHash        *string  `protobuf:"bytes,2,opt,name=token_hash" json:"token_hash,omitempty"`
+	MaxUses          *uint32  `protobuf:"varint,3,opt,name=max_uses" json:"max_uses,omitempty"`
+	Uses             *uint32  `protobuf:"varint,4,opt,name=uses" json:"uses,omitempty"`
+	Expires          *int64   `protobuf:"varint,5,opt,name=expires" json:"expires,omitempty"`
+	Groups           []string `protobuf:"bytes,6,rep,name=groups" json:"groups,omitempty"`
+	Tokens           []string `protobuf:"bytes,7,rep,name=tokens" json:"tokens,omitempty"`
+	Note             *string  `protobuf:"bytes,8,opt,name=note" json:"note,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (this *Invite) Reset()         { *this = Invite{} }
+func (this *Invite) String() string { return proto.CompactTextString(this) }
+func (*Invite) ProtoMessage()       {}
+
+func (this *Invite) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+func (this *Invite) GetTokenHash() string {
+	if this != nil && this.TokenHash != nil {
+		return *this.TokenHash
+	}
+	return ""
+}
+
+func (this *Invite) GetMaxUses() uint32 {
+	if this != nil && this.MaxUses != nil {
+		return *this.MaxUses
+	}
+	return 0
+}
+
+func (this *Invite) GetUses() uint32 {
+	if this != nil && this.Uses != nil {
+		return *this.Uses
+	}
+	return 0
+}
+
+func (this *Invite) GetExpires() int64 {
+	if this != nil && this.Expires != nil {
+		return *this.Expires
+	}
+	return 0
+}
+
+func (this *Invite) GetNote() string {
+	if this != nil && this.Note != nil {
+		return *this.Note
+	}
+	return ""
+}
+
+type InviteRemove struct {
+	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *InviteRemove) Reset()         { *this = InviteRemove{} }
+func (this *InviteRemove) String() string { return proto.CompactTextString(this) }
+func (*InviteRemove) ProtoMessage()       {}
+
+func (this *InviteRemove) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+type BanRemove struct {
+	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *BanRemove) Reset()         { *this = BanRemove{} }
+func (this *BanRemove) String() string { return proto.CompactTextString(this) }
+func (*BanRemove) ProtoMessage()       {}
+
+func (this *BanRemove) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+type Report struct {
+	Id               *uint32     `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Created          *int64      `protobuf:"varint,2,opt,name=created" json:"created,omitempty"`
+	ReporterId       *int32      `protobuf:"varint,3,opt,name=reporter_id" json:"reporter_id,omitempty"`
+	ReporterName     *string     `protobuf:"bytes,4,opt,name=reporter_name" json:"reporter_name,omitempty"`
+	TargetId         *int32      `protobuf:"varint,5,opt,name=target_id" json:"target_id,omitempty"`
+	TargetName       *string     `protobuf:"bytes,6,opt,name=target_name" json:"target_name,omitempty"`
+	TargetHash       *string     `protobuf:"bytes,7,opt,name=target_hash" json:"target_hash,omitempty"`
+	ChannelId        *uint32     `protobuf:"varint,8,opt,name=channel_id" json:"channel_id,omitempty"`
+	ChannelName      *string     `protobuf:"bytes,9,opt,name=channel_name" json:"channel_name,omitempty"`
+	Reason           *string     `protobuf:"bytes,10,opt,name=reason" json:"reason,omitempty"`
+	Excerpt          []*ChatLine `protobuf:"bytes,11,rep,name=excerpt" json:"excerpt,omitempty"`
+	Closed           *int64      `protobuf:"varint,12,opt,name=closed" json:"closed,omitempty"`
+	ClosedBy         *string     `protobuf:"bytes,13,opt,name=closed_by" json:"closed_by,omitempty"`
+	Note             *string     `protobuf:"bytes,14,opt,name=note" json:"note,omitempty"`
+	XXX_unrecognized []byte      `json:"-"`
+}
+
+func (this *Report) Reset()         { *this = Report{} }
+func (this *Report) String() string { return proto.CompactTextString(this) }
+func (*Report) ProtoMessage()       {}
+
+func (this *Report) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+func (this *Report) GetCreated() int64 {
+	if this != nil && this.Created != nil {
+		return *this.Created
+	}
+	return 0
+}
+
+func (this *Report) GetReporterId() int32 {
+	if this != nil && this.ReporterId != nil {
+		return *this.ReporterId
+	}
+	return 0
+}
+
+func (this *Report) GetReporterName() string {
+	if this != nil && this.ReporterName != nil {
+		return *this.ReporterName
+	}
+	return ""
+}
+
+func (this *Report) GetTargetId() int32 {
+	if this != nil && this.TargetId != nil {
+		return *this.TargetId
+	}
+	return 0
+}
+
+func (this *Report) GetTargetName() string {
+	if this != nil && this.TargetName != nil {
+		return *this.TargetName
+	}
+	return ""
+}
+
+func (this *Report) GetTargetHash() string {
+	if this != nil && this.TargetHash != nil {
+		return *this.TargetHash
+	}
+	return ""
+}
+
+func (this *Report) GetChannelId() uint32 {
+	if this != nil && this.ChannelId != nil {
+		return *this.ChannelId
+	}
+	return 0
+}
+
+func (this *Report) GetChannelName() string {
+	if this != nil && this.ChannelName != nil {
+		return *this.ChannelName
+	}
+	return ""
+}
+
+func (this *Report) GetReason() string {
+	if this != nil && this.Reason != nil {
+		return *this.Reason
+	}
+	return ""
+}
+
+func (this *Report) GetClosed() int64 {
+	if this != nil && this.Closed != nil {
+		return *this.Closed
+	}
+	return 0
+}
+
+func (this *Report) GetClosedBy() string {
+	if this != nil && this.ClosedBy != nil {
+		return *this.ClosedBy
+	}
+	return ""
+}
+
+func (this *Report) GetNote() string {
+	if this != nil && this.Note != nil {
+		return *this.Note
+	}
+	return ""
+}
+
+type ChatLine struct {
+	Time             *int64  `protobuf:"varint,1,opt,name=time" json:"time,omitempty"`
+	Sender           *string `protobuf:"bytes,2,opt,name=sender" json:"sender,omitempty"`
+	Text             *string `protobuf:"bytes,3,opt,name=text" json:"text,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *ChatLine) Reset()         { *this = ChatLine{} }
+func (this *ChatLine) String() string { return proto.CompactTextString(this) }
+func (*ChatLine) ProtoMessage()       {}
+
+func (this *ChatLine) GetTime() int64 {
+	if this != nil && this.Time != nil {
+		return *this.Time
+	}
+	return 0
+}
+
+func (this *ChatLine) GetSender() string {
+	if this != nil && this.Sender != nil {
+		return *this.Sender
+	}
+	return ""
+}
+
+func (this *ChatLine) GetText() string {
+	if this != nil && this.Text != nil {
+		return *this.Text
+	}
+	return ""
+}
+
+type ReportRemove struct {
+	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *ReportRemove) Reset()         { *this = ReportRemove{} }
+func (this *ReportRemove) String() string { return proto.CompactTextString(this) }
+func (*ReportRemove) ProtoMessage()       {}
+
+func (this *ReportRemove) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+type Announcement struct {
+	Id               *uint32  `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Schedule         *string  `protobuf:"bytes,2,opt,name=schedule" json:"schedule,omitempty"`
+	Text             *string  `protobuf:"bytes,3,opt,name=text" json:"text,omitempty"`
+	ChannelIds       []uint32 `protobuf:"varint,4,rep,name=channel_ids" json:"channel_ids,omitempty"`
+	Enabled          *bool    `protobuf:"varint,5,opt,name=enabled" json:"enabled,omitempty"`
+	Created          *int64   `protobuf:"varint,6,opt,name=created" json:"created,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (this *Announcement) Reset()         { *this = Announcement{} }
+func (this *Announcement) String() string { return proto.CompactTextString(this) }
+func (*Announcement) ProtoMessage()       {}
+
+func (this *Announcement) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+func (this *Announcement) GetSchedule() string {
+	if this != nil && this.Schedule != nil {
+		return *this.Schedule
+	}
+	return ""
+}
+
+func (this *Announcement) GetText() string {
+	if this != nil && this.Text != nil {
+		return *this.Text
+	}
+	return ""
+}
+
+func (this *Announcement) GetEnabled() bool {
+	if this != nil && this.Enabled != nil {
+		return *this.Enabled
+	}
+	return false
+}
+
+func (this *Announcement) GetCreated() int64 {
+	if this != nil && this.Created != nil {
+		return *this.Created
+	}
+	return 0
+}
+
+type AnnouncementRemove struct {
+	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *AnnouncementRemove) Reset()         { *this = AnnouncementRemove{} }
+func (this *AnnouncementRemove) String() string { return proto.CompactTextString(this) }
+func (*AnnouncementRemove) ProtoMessage()       {}
+
+func (this *AnnouncementRemove) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+type WhisperPreset struct {
+	Id               *uint32               `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	Name             *string               `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Slot             *uint32               `protobuf:"varint,3,opt,name=slot" json:"slot,omitempty"`
+	Channels         []*VoiceTargetChannel `protobuf:"bytes,4,rep,name=channels" json:"channels,omitempty"`
+	UserIds          []uint32              `protobuf:"varint,5,rep,name=user_ids" json:"user_ids,omitempty"`
+	Created          *int64                `protobuf:"varint,6,opt,name=created" json:"created,omitempty"`
+	XXX_unrecognized []byte                `json:"-"`
+}
+
+func (this *WhisperPreset) Reset()         { *this = WhisperPreset{} }
+func (this *WhisperPreset) String() string { return proto.CompactTextString(this) }
+func (*WhisperPreset) ProtoMessage()       {}
+
+func (this *WhisperPreset) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
+func (this *WhisperPreset) GetName() string {
+	if this != nil && this.Name != nil {
+		return *this.Name
+	}
+	return ""
+}
+
+func (this *WhisperPreset) GetSlot() uint32 {
+	if this != nil && this.Slot != nil {
+		return *this.Slot
+	}
+	return 0
+}
+
+func (this *WhisperPreset) GetCreated() int64 {
+	if this != nil && this.Created != nil {
+		return *this.Created
+	}
+	return 0
+}
+
+type WhisperPresetRemove struct {
+	Id               *uint32 `protobuf:"varint,1,opt,name=id" json:"id,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (this *WhisperPresetRemove) Reset()         { *this = WhisperPresetRemove{} }
+func (this *WhisperPresetRemove) String() string { return proto.CompactTextString(this) }
+func (*WhisperPresetRemove) ProtoMessage()       {}
+
+func (this *WhisperPresetRemove) GetId() uint32 {
+	if this != nil && this.Id != nil {
+		return *this.Id
+	}
+	return 0
+}
+
 func init() {
 }
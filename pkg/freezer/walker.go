@@ -222,6 +222,96 @@ func (walker *Walker) Next() (entries []interface{}, err error) {
 				return nil, err
 			}
 			entries = append(entries, channelRemove)
+		case InviteType:
+			invite := &Invite{}
+			err = proto.Unmarshal(buf, invite)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, invite)
+		case InviteRemoveType:
+			inviteRemove := &InviteRemove{}
+			err = proto.Unmarshal(buf, inviteRemove)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, inviteRemove)
+		case BanType:
+			ban := &Ban{}
+			err = proto.Unmarshal(buf, ban)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, ban)
+		case BanRemoveType:
+			banRemove := &BanRemove{}
+			err = proto.Unmarshal(buf, banRemove)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, banRemove)
+		case ReportType:
+			report := &Report{}
+			err = proto.Unmarshal(buf, report)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, report)
+		case ReportRemoveType:
+			reportRemove := &ReportRemove{}
+			err = proto.Unmarshal(buf, reportRemove)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, reportRemove)
+		case AnnouncementType:
+			announcement := &Announcement{}
+			err = proto.Unmarshal(buf, announcement)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, announcement)
+		case AnnouncementRemoveType:
+			announcementRemove := &AnnouncementRemove{}
+			err = proto.Unmarshal(buf, announcementRemove)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, announcementRemove)
+		case WhisperPresetType:
+			preset := &WhisperPreset{}
+			err = proto.Unmarshal(buf, preset)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, preset)
+		case WhisperPresetRemoveType:
+			presetRemove := &WhisperPresetRemove{}
+			err = proto.Unmarshal(buf, presetRemove)
+			if isEOF(err) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			entries = append(entries, presetRemove)
 		}
 
 		remainOps -= 1
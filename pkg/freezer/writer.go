@@ -64,6 +64,12 @@ func NewLogFile(fn string) (*Log, error) {
 	return log, nil
 }
 
+// Create a new log that writes to wc. Each transaction
+// record is passed to wc in a single Write call.
+func NewLog(wc io.WriteCloser) *Log {
+	return &Log{wc: wc}
+}
+
 // Close a Log
 func (log *Log) Close() error {
 	return log.wc.Close()
@@ -127,6 +133,36 @@ func (tx *LogTx) Put(value interface{}) (err error) {
 	case *ChannelRemove:
 		kind = ChannelRemoveType
 		buf, err = proto.Marshal(val)
+	case *Invite:
+		kind = InviteType
+		buf, err = proto.Marshal(val)
+	case *InviteRemove:
+		kind = InviteRemoveType
+		buf, err = proto.Marshal(val)
+	case *Ban:
+		kind = BanType
+		buf, err = proto.Marshal(val)
+	case *BanRemove:
+		kind = BanRemoveType
+		buf, err = proto.Marshal(val)
+	case *Report:
+		kind = ReportType
+		buf, err = proto.Marshal(val)
+	case *ReportRemove:
+		kind = ReportRemoveType
+		buf, err = proto.Marshal(val)
+	case *Announcement:
+		kind = AnnouncementType
+		buf, err = proto.Marshal(val)
+	case *AnnouncementRemove:
+		kind = AnnouncementRemoveType
+		buf, err = proto.Marshal(val)
+	case *WhisperPreset:
+		kind = WhisperPresetType
+		buf, err = proto.Marshal(val)
+	case *WhisperPresetRemove:
+		kind = WhisperPresetRemoveType
+		buf, err = proto.Marshal(val)
 	default:
 		panic("Attempt to put an unknown type")
 	}
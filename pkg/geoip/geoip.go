@@ -0,0 +1,136 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package geoip implements IP address to country and autonomous
+// system lookups.
+//
+// The database is read from a tab-separated file in the format
+// published by iptoasn.com (ip2asn-combined.tsv):
+//
+//	range_start	range_end	AS_number	country_code	AS_description
+//
+// Lines starting with '#' and blank lines are ignored.
+package geoip
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unknown is used as the country code for addresses that
+// are not covered by the database.
+const Unknown = "??"
+
+// Record holds the information known about an IP address range.
+type Record struct {
+	Country       string
+	ASN           uint32
+	ASDescription string
+}
+
+type ipRange struct {
+	start  net.IP
+	end    net.IP
+	record Record
+}
+
+// Database is an in-memory IP range database.
+type Database struct {
+	ranges []ipRange
+}
+
+// ErrMalformedLine is returned when a line of a database file
+// could not be parsed.
+var ErrMalformedLine = errors.New("geoip: malformed line")
+
+// Open reads the database in the file fn.
+func Open(fn string) (*Database, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a database from r.
+func Read(r io.Reader) (*Database, error) {
+	db := &Database{}
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 5)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%v (line %v)", ErrMalformedLine, lineno)
+		}
+		start := net.ParseIP(fields[0]).To16()
+		end := net.ParseIP(fields[1]).To16()
+		if start == nil || end == nil {
+			return nil, fmt.Errorf("%v (line %v)", ErrMalformedLine, lineno)
+		}
+		asn, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%v (line %v)", ErrMalformedLine, lineno)
+		}
+
+		rec := Record{
+			Country: strings.ToUpper(fields[3]),
+			ASN:     uint32(asn),
+		}
+		if len(fields) == 5 {
+			rec.ASDescription = fields[4]
+		}
+		if rec.Country == "NONE" || len(rec.Country) == 0 {
+			rec.Country = Unknown
+		}
+		db.ranges = append(db.ranges, ipRange{start, end, rec})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return bytes.Compare(db.ranges[i].start, db.ranges[j].start) < 0
+	})
+
+	return db, nil
+}
+
+// Len returns the number of ranges in the database.
+func (db *Database) Len() int {
+	return len(db.ranges)
+}
+
+// Lookup finds the record for ip. If ip is not covered by
+// the database, ok is false and the returned Record has its
+// Country set to Unknown.
+func (db *Database) Lookup(ip net.IP) (rec Record, ok bool) {
+	ip = ip.To16()
+	if db == nil || ip == nil {
+		return Record{Country: Unknown}, false
+	}
+
+	// Find the last range that starts at or before ip.
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return bytes.Compare(db.ranges[i].start, ip) > 0
+	}) - 1
+	if i < 0 || bytes.Compare(ip, db.ranges[i].end) > 0 {
+		return Record{Country: Unknown}, false
+	}
+	return db.ranges[i].record, true
+}
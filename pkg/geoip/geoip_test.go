@@ -0,0 +1,55 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package geoip
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+const testData = `# test database
+1.0.0.0	1.0.0.255	13335	US	CLOUDFLARENET
+2.16.0.0	2.16.7.255	20940	EU	AKAMAI-ASN1
+10.0.0.0	10.255.255.255	0	None	Not routed
+2001:db8::	2001:db8::ffff	64496	DK	DOCUMENTATION
+`
+
+func TestLookup(t *testing.T) {
+	db, err := Read(strings.NewReader(testData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 4 {
+		t.Fatalf("Expected 4 ranges, got %v", db.Len())
+	}
+
+	rec, ok := db.Lookup(net.ParseIP("2.16.3.4"))
+	if !ok || rec.Country != "EU" || rec.ASN != 20940 {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+
+	rec, ok = db.Lookup(net.ParseIP("2001:db8::42"))
+	if !ok || rec.Country != "DK" {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+
+	rec, ok = db.Lookup(net.ParseIP("10.1.2.3"))
+	if !ok || rec.Country != Unknown {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+
+	rec, ok = db.Lookup(net.ParseIP("1.0.1.0"))
+	if ok || rec.Country != Unknown {
+		t.Errorf("Expected no match, got %+v", rec)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	_, err := Read(strings.NewReader("1.0.0.0\tfoo\t1\tUS\n"))
+	if err == nil {
+		t.Errorf("Expected error for malformed line")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package htmlfilter
+
+import (
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Content describes what a message holds besides plain text.
+type Content struct {
+	// The message uses HTML markup. Paragraphs and line breaks,
+	// which clients use for plain text too, and links don't count.
+	HTML bool
+	// The message holds images. Messages with images also use HTML.
+	Images bool
+	// The message holds links, either as HTML or as bare URLs.
+	Links bool
+}
+
+// Elements that clients use for plain text messages.
+var plainElements = map[string]bool{
+	"html": true,
+	"p":    true,
+	"br":   true,
+}
+
+// Bare URLs that clients turn into links.
+var bareURL = regexp.MustCompile(`(?i)\b(?:(?:https?|ftp|mumble)://|www\.)\S`)
+
+// Classify finds out what text, a filtered message, holds.
+func Classify(text string) (Content, error) {
+	var c Content
+	if strings.Index(text, "<") == -1 {
+		c.Links = bareURL.MatchString(text)
+		return c, nil
+	}
+
+	parser := xml.NewDecoder(strings.NewReader("<html>" + text + "</html>"))
+	parser.Strict = false
+	parser.AutoClose = xml.HTMLAutoClose
+	parser.Entity = xml.HTMLEntity
+	for {
+		tok, err := parser.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return Content{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			if bareURL.Match(t) {
+				c.Links = true
+			}
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "a":
+				for _, attr := range t.Attr {
+					if strings.ToLower(attr.Name.Local) == "href" {
+						c.Links = true
+					}
+				}
+			case name == "img":
+				c.HTML = true
+				c.Images = true
+			case !plainElements[name]:
+				c.HTML = true
+			}
+		}
+	}
+	return c, nil
+}
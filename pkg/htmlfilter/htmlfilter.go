@@ -43,6 +43,9 @@ func Filter(text string, options *Options) (filtered string, err error) {
 	//
 	// MaxImageMessageLength:
 	//    Text length for messages with images.
+	//
+	// If StripHTML is false, HTML is sanitized: only the elements and
+	// attributes that Mumble's rich text uses are kept.
 
 	if options == nil {
 		options = &defaultOptions
@@ -58,7 +61,7 @@ func Filter(text string, options *Options) (filtered string, err error) {
 		} else {
 			// Strip away all HTML
 			out := bytes.NewBuffer(nil)
-			buf := bytes.NewBufferString(text)
+			buf := bytes.NewBufferString("<html>" + text + "</html>")
 			parser := xml.NewDecoder(buf)
 			parser.Strict = false
 			parser.AutoClose = xml.HTMLAutoClose
@@ -86,6 +89,14 @@ func Filter(text string, options *Options) (filtered string, err error) {
 			return "", ErrExceedsTextMessageLength
 		}
 	} else {
+		// Only allow a safe subset of HTML through.
+		if strings.Index(text, "<") != -1 {
+			text, err = sanitize(text, false)
+			if err != nil {
+				return "", err
+			}
+		}
+
 		// No limits
 		if max == 0 && maximg == 0 {
 			return text, nil
@@ -110,45 +121,10 @@ func Filter(text string, options *Options) (filtered string, err error) {
 		}
 
 		// Simplify the received HTML data by stripping away data URIs
-		out := bytes.NewBuffer(nil)
-		buf := bytes.NewBufferString(text)
-		parser := xml.NewDecoder(buf)
-		parser.Strict = false
-		parser.AutoClose = xml.HTMLAutoClose
-		parser.Entity = xml.HTMLEntity
-		for {
-			tok, err := parser.Token()
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				return "", err
-			}
-
-			switch t := tok.(type) {
-			case xml.CharData:
-				out.Write(t)
-			case xml.StartElement:
-				out.WriteString("<")
-				xml.Escape(out, []byte(t.Name.Local))
-				for _, attr := range t.Attr {
-					if t.Name.Local == "img" && attr.Name.Local == "src" {
-						continue
-					}
-					out.WriteString(" ")
-					xml.Escape(out, []byte(attr.Name.Local))
-					out.WriteString(`="`)
-					out.WriteString(attr.Value)
-					out.WriteString(`"`)
-				}
-				out.WriteString(">")
-			case xml.EndElement:
-				out.WriteString("</")
-				xml.Escape(out, []byte(t.Name.Local))
-				out.WriteString(">")
-			}
+		filtered, err = sanitize(text, true)
+		if err != nil {
+			return "", err
 		}
-
-		filtered = strings.TrimSpace(out.String())
 		if len(filtered) > max {
 			return "", ErrExceedsTextMessageLength
 		}
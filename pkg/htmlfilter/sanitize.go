@@ -0,0 +1,240 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package htmlfilter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Elements that may appear in sanitized HTML, along with the attributes
+// they may carry. This is roughly the subset of HTML supported by Qt's
+// rich text engine, which Mumble uses to display messages.
+var allowedElements = map[string][]string{
+	"a":          {"href", "title"},
+	"b":          nil,
+	"big":        nil,
+	"blockquote": nil,
+	"br":         nil,
+	"center":     nil,
+	"code":       nil,
+	"del":        nil,
+	"div":        {"align"},
+	"em":         nil,
+	"font":       {"color", "face", "size"},
+	"h1":         {"align"},
+	"h2":         {"align"},
+	"h3":         {"align"},
+	"h4":         {"align"},
+	"h5":         {"align"},
+	"h6":         {"align"},
+	"hr":         {"width"},
+	"i":          nil,
+	"img":        {"src", "alt", "width", "height"},
+	"li":         nil,
+	"ol":         {"type", "start"},
+	"p":          {"align"},
+	"pre":        nil,
+	"s":          nil,
+	"small":      nil,
+	"span":       nil,
+	"strike":     nil,
+	"strong":     nil,
+	"sub":        nil,
+	"sup":        nil,
+	"table":      {"border", "cellpadding", "cellspacing", "width", "bgcolor"},
+	"tbody":      nil,
+	"td":         {"align", "valign", "colspan", "rowspan", "width", "bgcolor"},
+	"th":         {"align", "valign", "colspan", "rowspan", "width", "bgcolor"},
+	"thead":      nil,
+	"tr":         {"align", "valign", "bgcolor"},
+	"tt":         nil,
+	"u":          nil,
+	"ul":         {"type"},
+}
+
+// Attributes that may appear on any allowed element.
+var globalAttributes = []string{"style"}
+
+// Elements whose content is dropped along with the element itself.
+var droppedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"applet":   true,
+	"frame":    true,
+	"frameset": true,
+	"noscript": true,
+	"template": true,
+	"head":     true,
+	"title":    true,
+	"svg":      true,
+	"math":     true,
+}
+
+// Elements that never have content, and are written without end tags.
+var voidElements = map[string]bool{
+	"br":  true,
+	"hr":  true,
+	"img": true,
+}
+
+// URL schemes that links may use.
+var allowedLinkSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"ftp":    true,
+	"mailto": true,
+	"mumble": true,
+}
+
+// sanitize rewrites text so that it only contains allowed elements and
+// attributes. Disallowed elements are removed, but their text is kept,
+// except for elements such as script whose content is dropped too.
+// If stripImages is set, the src attributes of images are removed.
+func sanitize(text string, stripImages bool) (string, error) {
+	out := bytes.NewBuffer(nil)
+	// The text is wrapped in a root element, so that void elements at
+	// the end of the text are closed before the input runs out.
+	parser := xml.NewDecoder(strings.NewReader("<html>" + text + "</html>"))
+	parser.Strict = false
+	parser.AutoClose = xml.HTMLAutoClose
+	parser.Entity = xml.HTMLEntity
+
+	// The nesting depth within a dropped element.
+	dropDepth := 0
+	for {
+		tok, err := parser.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			if dropDepth == 0 {
+				out.WriteString(html.EscapeString(string(t)))
+			}
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if dropDepth > 0 || droppedElements[name] {
+				dropDepth++
+				continue
+			}
+			attrs, ok := allowedElements[name]
+			if !ok {
+				continue
+			}
+			out.WriteString("<")
+			out.WriteString(name)
+			for _, attr := range t.Attr {
+				key := strings.ToLower(attr.Name.Local)
+				if len(attr.Name.Space) > 0 || !(contains(attrs, key) || contains(globalAttributes, key)) {
+					continue
+				}
+				value, ok := sanitizeAttribute(name, key, attr.Value, stripImages)
+				if !ok {
+					continue
+				}
+				out.WriteString(" ")
+				out.WriteString(key)
+				out.WriteString(`="`)
+				out.WriteString(html.EscapeString(value))
+				out.WriteString(`"`)
+			}
+			if voidElements[name] {
+				out.WriteString(" />")
+			} else {
+				out.WriteString(">")
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if dropDepth > 0 {
+				dropDepth--
+				continue
+			}
+			if _, ok := allowedElements[name]; ok && !voidElements[name] {
+				out.WriteString("</")
+				out.WriteString(name)
+				out.WriteString(">")
+			}
+		}
+	}
+
+	return strings.TrimSpace(out.String()), nil
+}
+
+// sanitizeAttribute checks the value of an allowed attribute.
+func sanitizeAttribute(element, key, value string, stripImages bool) (string, bool) {
+	switch {
+	case element == "a" && key == "href":
+		return value, isAllowedURL(value, false)
+	case element == "img" && key == "src":
+		if stripImages {
+			return "", false
+		}
+		return value, isAllowedURL(value, true)
+	case key == "style":
+		// Styles may load resources, or run script in some browsers.
+		lower := strings.ToLower(stripSpace(value))
+		for _, bad := range []string{"url(", "expression(", "javascript:", "@import", "behavior:", "-moz-binding"} {
+			if strings.Contains(lower, bad) {
+				return "", false
+			}
+		}
+	}
+	return value, true
+}
+
+// isAllowedURL checks whether a link or image source is safe to pass on.
+// Images may also be embedded as data URIs.
+func isAllowedURL(value string, image bool) bool {
+	value = stripSpace(value)
+	if image && strings.HasPrefix(strings.ToLower(value), "data:") {
+		mime := strings.ToLower(value[len("data:"):])
+		// SVG images may carry script.
+		return strings.HasPrefix(mime, "image/") && !strings.HasPrefix(mime, "image/svg")
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	if len(u.Scheme) == 0 {
+		// Relative links have no meaning in a message, but are harmless.
+		return !strings.Contains(value, ":")
+	}
+	schemes := allowedLinkSchemes
+	if image {
+		schemes = map[string]bool{"http": true, "https": true}
+	}
+	return schemes[strings.ToLower(u.Scheme)]
+}
+
+// stripSpace removes whitespace and control characters, which browsers
+// ignore within URL schemes.
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package htmlfilter
+
+import (
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{`<b>bold</b> and <i>italic</i>`, `<b>bold</b> and <i>italic</i>`},
+		{`<P ALIGN="center">a<br>b</P>`, `<p align="center">a<br />b</p>`},
+		{`hello<script>alert(1)</script> world`, `hello world`},
+		{`<style>body { color: red }</style>text`, `text`},
+		{`<blink>text</blink>`, `text`},
+		{`<img src="x" onerror="alert(1)">`, `<img src="x" />`},
+		{`<img src="javascript:alert(1)">`, `<img />`},
+		{`<img src="data:image/png;base64,AAAA" alt="pic">`, `<img src="data:image/png;base64,AAAA" alt="pic" />`},
+		{`<img src="data:image/svg+xml;base64,AAAA">`, `<img />`},
+		{`<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href=" java&#9;script:alert(1)">x</a>`, `<a>x</a>`},
+		{`<a href="https://example.com/?a=1&amp;b=2" onclick="x()">x</a>`, `<a href="https://example.com/?a=1&amp;b=2">x</a>`},
+		{`<span style="color: red">x</span>`, `<span style="color: red">x</span>`},
+		{`<span style="background: url(http://x)">x</span>`, `<span>x</span>`},
+		{`<a title="&quot; onmouseover=&quot;x()">x</a>`, `<a title="&#34; onmouseover=&#34;x()">x</a>`},
+		{`1 &lt; 2`, `1 &lt; 2`},
+	} {
+		out, err := sanitize(tc.in, false)
+		if err != nil {
+			t.Errorf("sanitize(%q): %v", tc.in, err)
+			continue
+		}
+		if out != tc.out {
+			t.Errorf("sanitize(%q) = %q, want %q", tc.in, out, tc.out)
+		}
+	}
+}
+
+func TestSanitizeStripImages(t *testing.T) {
+	out, err := sanitize(`<img src="data:image/png;base64,AAAA" alt="pic">text`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `<img alt="pic" />text` {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestFilterSanitizes(t *testing.T) {
+	out, err := Filter(`<b onclick="x()">hi</b><script>x()</script>`, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != `<b>hi</b>` {
+		t.Errorf("Unexpected output %q", out)
+	}
+
+	// Malformed markup is rejected rather than passed on.
+	if _, err := Filter(`<b>hi</i>`, &Options{}); err == nil {
+		t.Errorf("Expected error for malformed HTML")
+	}
+}
+
+func TestFilterStrips(t *testing.T) {
+	out, err := Filter(`<p>line</p>a<br>`, &Options{StripHTML: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "line\na" {
+		t.Errorf("Unexpected output %q", out)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		in string
+		c  Content
+	}{
+		{`hello`, Content{}},
+		{`<p>hello<br />there</p>`, Content{}},
+		{`see https://example.com`, Content{Links: true}},
+		{`www.example.com`, Content{Links: true}},
+		{`<a href="https://example.com">x</a>`, Content{Links: true}},
+		{`<p>go to http://example.com</p>`, Content{Links: true}},
+		{`<b>bold</b>`, Content{HTML: true}},
+		{`<img src="data:image/png;base64,AAAA" />`, Content{HTML: true, Images: true}},
+		{`1 &lt; 2`, Content{}},
+	} {
+		c, err := Classify(tc.in)
+		if err != nil {
+			t.Errorf("Classify(%q): %v", tc.in, err)
+			continue
+		}
+		if c != tc.c {
+			t.Errorf("Classify(%q) = %+v, want %+v", tc.in, c, tc.c)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package ice
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrMalformed is returned when decoding data that does not follow
+// the Ice encoding.
+var ErrMalformed = errors.New("ice: malformed data")
+
+// An EncodingVersion is a version of the Ice encoding.
+type EncodingVersion struct {
+	Major, Minor byte
+}
+
+var (
+	Encoding10 = EncodingVersion{1, 0}
+	Encoding11 = EncodingVersion{1, 1}
+)
+
+// An Identity names an Ice object.
+type Identity struct {
+	Name     string
+	Category string
+}
+
+// An Endpoint is the TCP address of a proxy.
+type Endpoint struct {
+	Host    string
+	Port    int32
+	Timeout int32
+}
+
+// A Proxy refers to an Ice object. The zero Proxy is the null proxy.
+type Proxy struct {
+	Identity  Identity
+	Endpoints []Endpoint
+}
+
+// An Encoder marshals values using the Ice encoding.
+type Encoder struct {
+	Encoding EncodingVersion
+	buf      []byte
+}
+
+// NewEncoder returns an encoder for the given encoding version.
+func NewEncoder(encoding EncodingVersion) *Encoder {
+	return &Encoder{Encoding: encoding}
+}
+
+// Bytes returns the encoded data.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) WriteByte(b byte) error {
+	e.buf = append(e.buf, b)
+	return nil
+}
+
+func (e *Encoder) WriteBool(b bool) {
+	if b {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *Encoder) WriteShort(v int16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], uint16(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) WriteInt(v int32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) WriteLong(v int64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *Encoder) WriteFloat(v float32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], math.Float32bits(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+// WriteSize writes the size of a sequence, dictionary or string.
+func (e *Encoder) WriteSize(n int) {
+	if n < 255 {
+		e.buf = append(e.buf, byte(n))
+		return
+	}
+	e.buf = append(e.buf, 255)
+	e.WriteInt(int32(n))
+}
+
+func (e *Encoder) WriteString(s string) {
+	e.WriteSize(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+// WriteBytes writes a sequence of bytes.
+func (e *Encoder) WriteBytes(b []byte) {
+	e.WriteSize(len(b))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *Encoder) WriteStringSeq(list []string) {
+	e.WriteSize(len(list))
+	for _, s := range list {
+		e.WriteString(s)
+	}
+}
+
+func (e *Encoder) WriteIntSeq(list []int32) {
+	e.WriteSize(len(list))
+	for _, v := range list {
+		e.WriteInt(v)
+	}
+}
+
+// WriteStringMap writes a dictionary of strings to strings.
+func (e *Encoder) WriteStringMap(m map[string]string) {
+	e.WriteSize(len(m))
+	for _, k := range sortedKeys(m) {
+		e.WriteString(k)
+		e.WriteString(m[k])
+	}
+}
+
+func (e *Encoder) WriteIdentity(id Identity) {
+	e.WriteString(id.Name)
+	e.WriteString(id.Category)
+}
+
+// WriteEncapsulation writes data encoded with the given encoding as
+// an encapsulation.
+func (e *Encoder) WriteEncapsulation(encoding EncodingVersion, data []byte) {
+	e.WriteInt(int32(len(data) + 6))
+	e.buf = append(e.buf, encoding.Major, encoding.Minor)
+	e.buf = append(e.buf, data...)
+}
+
+// WriteProxy writes a twoway proxy with TCP endpoints.
+func (e *Encoder) WriteProxy(p Proxy) {
+	e.WriteIdentity(p.Identity)
+	if len(p.Identity.Name) == 0 {
+		return
+	}
+	e.WriteSize(0)     // facet
+	e.WriteByte(0)     // mode: twoway
+	e.WriteBool(false) // secure
+	if e.Encoding != Encoding10 {
+		e.buf = append(e.buf, 1, 0) // protocol
+		e.buf = append(e.buf, e.Encoding.Major, e.Encoding.Minor)
+	}
+	e.WriteSize(len(p.Endpoints))
+	for _, ep := range p.Endpoints {
+		e.WriteShort(1) // TCP
+		inner := NewEncoder(e.Encoding)
+		inner.WriteString(ep.Host)
+		inner.WriteInt(ep.Port)
+		inner.WriteInt(ep.Timeout)
+		inner.WriteBool(false) // compress
+		e.WriteEncapsulation(e.Encoding, inner.Bytes())
+	}
+}
+
+// A Decoder unmarshals values encoded using the Ice encoding. Errors
+// are sticky: once a value could not be read, all following reads
+// return zero values, and Err returns the error.
+type Decoder struct {
+	Encoding EncodingVersion
+	buf      []byte
+	err      error
+}
+
+// NewDecoder returns a decoder reading buf.
+func NewDecoder(encoding EncodingVersion, buf []byte) *Decoder {
+	return &Decoder{Encoding: encoding, buf: buf}
+}
+
+// Err returns the first error encountered while decoding.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Remaining returns the number of bytes left to read.
+func (d *Decoder) Remaining() int {
+	return len(d.buf)
+}
+
+func (d *Decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.err = ErrMalformed
+		d.buf = nil
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *Decoder) ReadByte() (byte, error) {
+	b := d.next(1)
+	if b == nil {
+		return 0, d.err
+	}
+	return b[0], nil
+}
+
+func (d *Decoder) ReadBool() bool {
+	b, _ := d.ReadByte()
+	return b != 0
+}
+
+func (d *Decoder) ReadShort() int16 {
+	b := d.next(2)
+	if b == nil {
+		return 0
+	}
+	return int16(binary.LittleEndian.Uint16(b))
+}
+
+func (d *Decoder) ReadInt() int32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.LittleEndian.Uint32(b))
+}
+
+func (d *Decoder) ReadLong() int64 {
+	b := d.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.LittleEndian.Uint64(b))
+}
+
+func (d *Decoder) ReadFloat() float32 {
+	b := d.next(4)
+	if b == nil {
+		return 0
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+// ReadSize reads the size of a sequence, dictionary or string.
+func (d *Decoder) ReadSize() int {
+	b, _ := d.ReadByte()
+	if b < 255 {
+		return int(b)
+	}
+	n := d.ReadInt()
+	if n < 0 {
+		d.err = ErrMalformed
+		return 0
+	}
+	return int(n)
+}
+
+func (d *Decoder) ReadString() string {
+	return string(d.next(d.ReadSize()))
+}
+
+// ReadBytes reads a sequence of bytes.
+func (d *Decoder) ReadBytes() []byte {
+	b := d.next(d.ReadSize())
+	return append([]byte(nil), b...)
+}
+
+func (d *Decoder) ReadStringSeq() []string {
+	n := d.ReadSize()
+	list := []string{}
+	for i := 0; i < n && d.err == nil; i++ {
+		list = append(list, d.ReadString())
+	}
+	return list
+}
+
+func (d *Decoder) ReadIntSeq() []int32 {
+	n := d.ReadSize()
+	list := []int32{}
+	for i := 0; i < n && d.err == nil; i++ {
+		list = append(list, d.ReadInt())
+	}
+	return list
+}
+
+// ReadStringMap reads a dictionary of strings to strings.
+func (d *Decoder) ReadStringMap() map[string]string {
+	n := d.ReadSize()
+	m := make(map[string]string)
+	for i := 0; i < n && d.err == nil; i++ {
+		k := d.ReadString()
+		m[k] = d.ReadString()
+	}
+	return m
+}
+
+func (d *Decoder) ReadIdentity() Identity {
+	name := d.ReadString()
+	return Identity{Name: name, Category: d.ReadString()}
+}
+
+// ReadEncapsulation reads an encapsulation, and returns a decoder for
+// its contents.
+func (d *Decoder) ReadEncapsulation() *Decoder {
+	size := int(d.ReadInt())
+	if d.err == nil && size < 6 {
+		d.err = ErrMalformed
+	}
+	data := d.next(size - 6 + 2)
+	if data == nil {
+		return &Decoder{err: d.err}
+	}
+	return NewDecoder(EncodingVersion{data[0], data[1]}, data[2:])
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
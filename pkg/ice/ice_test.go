@@ -0,0 +1,167 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package ice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestEncodingRoundTrip(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	e := NewEncoder(Encoding11)
+	e.WriteInt(-5)
+	e.WriteString(long)
+	e.WriteBool(true)
+	e.WriteFloat(1.5)
+	e.WriteStringMap(map[string]string{"b": "2", "a": "1"})
+	e.WriteIntSeq([]int32{1, 2, 3})
+
+	d := NewDecoder(Encoding11, e.Bytes())
+	if v := d.ReadInt(); v != -5 {
+		t.Errorf("int: got %v", v)
+	}
+	if v := d.ReadString(); v != long {
+		t.Errorf("long string not decoded correctly")
+	}
+	if v := d.ReadBool(); !v {
+		t.Errorf("bool: got %v", v)
+	}
+	if v := d.ReadFloat(); v != 1.5 {
+		t.Errorf("float: got %v", v)
+	}
+	if m := d.ReadStringMap(); len(m) != 2 || m["a"] != "1" || m["b"] != "2" {
+		t.Errorf("map: got %v", m)
+	}
+	if s := d.ReadIntSeq(); len(s) != 3 || s[2] != 3 {
+		t.Errorf("sequence: got %v", s)
+	}
+	if d.Err() != nil || d.Remaining() != 0 {
+		t.Errorf("err %v, %v bytes remaining", d.Err(), d.Remaining())
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	d := NewDecoder(Encoding11, []byte{10, 'a', 'b'})
+	d.ReadString()
+	if d.Err() != ErrMalformed {
+		t.Errorf("expected ErrMalformed, got %v", d.Err())
+	}
+	if v := d.ReadInt(); v != 0 {
+		t.Errorf("read after error returned %v", v)
+	}
+}
+
+// request builds a twoway request message.
+func request(id int32, ident Identity, op string, ctx map[string]string, params []byte) []byte {
+	body := NewEncoder(Encoding10)
+	body.WriteInt(id)
+	body.WriteIdentity(ident)
+	body.WriteStringSeq(nil)
+	body.WriteString(op)
+	body.WriteByte(0)
+	body.WriteStringMap(ctx)
+	body.WriteEncapsulation(Encoding11, params)
+	return append(header(msgRequest, len(body.Bytes())), body.Bytes()...)
+}
+
+// readReply reads a reply message, returning its request id, status
+// and the rest of its body.
+func readReply(t *testing.T, r *bufio.Reader) (int32, byte, *Decoder) {
+	hdr := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		t.Fatal(err)
+	}
+	if hdr[8] != msgReply {
+		t.Fatalf("expected reply, got message type %v", hdr[8])
+	}
+	body := make([]byte, binary.LittleEndian.Uint32(hdr[10:])-headerSize)
+	if _, err := io.ReadFull(r, body); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDecoder(Encoding10, body)
+	id := d.ReadInt()
+	status, _ := d.ReadByte()
+	return id, status, d
+}
+
+func TestServer(t *testing.T) {
+	srv := &Server{Handler: HandlerFunc(func(req *Request, out *Encoder) error {
+		if req.Identity.Name != "Meta" {
+			return ErrObjectNotExist
+		}
+		if ServeObject(req, out, "::Test::Meta") {
+			return nil
+		}
+		switch req.Operation {
+		case "echo":
+			out.WriteString(req.Params.ReadString() + req.Context["suffix"])
+			return nil
+		case "fail":
+			return &UserException{TypeIds: []string{"::Test::Derived", "::Test::Base"}}
+		}
+		return ErrOperationNotExist
+	})}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go srv.serveConn(server)
+
+	r := bufio.NewReader(client)
+	validate := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, validate); err != nil || validate[8] != msgValidateConnection {
+		t.Fatalf("expected connection validation, got %x (%v)", validate, err)
+	}
+
+	params := NewEncoder(Encoding11)
+	params.WriteString("hello")
+	client.Write(request(1, Identity{Name: "Meta"}, "echo", map[string]string{"suffix": "!"}, params.Bytes()))
+	id, status, d := readReply(t, r)
+	if id != 1 || status != replyOK {
+		t.Fatalf("echo: id %v, status %v", id, status)
+	}
+	if s := d.ReadEncapsulation().ReadString(); s != "hello!" {
+		t.Errorf("echo returned %q", s)
+	}
+
+	params = NewEncoder(Encoding11)
+	params.WriteString("::Test::Meta")
+	client.Write(request(2, Identity{Name: "Meta"}, "ice_isA", nil, params.Bytes()))
+	if _, status, d = readReply(t, r); status != replyOK || !d.ReadEncapsulation().ReadBool() {
+		t.Errorf("ice_isA failed with status %v", status)
+	}
+
+	client.Write(request(3, Identity{Name: "Meta"}, "fail", nil, nil))
+	if _, status, d = readReply(t, r); status != replyUserException {
+		t.Fatalf("fail: status %v", status)
+	}
+	exc := d.ReadEncapsulation()
+	if flags, _ := exc.ReadByte(); flags != 0x01 || exc.ReadString() != "::Test::Derived" {
+		t.Errorf("unexpected first exception slice")
+	}
+
+	client.Write(request(4, Identity{Name: "Other"}, "echo", nil, nil))
+	if _, status, _ = readReply(t, r); status != replyObjectNotExist {
+		t.Errorf("unknown object: status %v", status)
+	}
+
+	client.Write(request(5, Identity{Name: "Meta"}, "nope", nil, nil))
+	if _, status, _ = readReply(t, r); status != replyOperationNotExist {
+		t.Errorf("unknown operation: status %v", status)
+	}
+}
+
+func TestNullProxy(t *testing.T) {
+	e := NewEncoder(Encoding11)
+	e.WriteProxy(Proxy{})
+	if !bytes.Equal(e.Bytes(), []byte{0, 0}) {
+		t.Errorf("null proxy encoded as %x", e.Bytes())
+	}
+}
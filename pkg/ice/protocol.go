@@ -0,0 +1,302 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package ice implements the server side of a subset of ZeroC Ice's
+// RPC protocol: twoway and oneway requests over plain TCP, with
+// parameters in the 1.0 or 1.1 encoding. Compression, classes and
+// optional parameters are not supported.
+package ice
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+)
+
+// Message types.
+const (
+	msgRequest            = 0
+	msgBatchRequest       = 1
+	msgReply              = 2
+	msgValidateConnection = 3
+	msgCloseConnection    = 4
+)
+
+// Reply status codes.
+const (
+	replyOK                    = 0
+	replyUserException         = 1
+	replyObjectNotExist        = 2
+	replyFacetNotExist         = 3
+	replyOperationNotExist     = 4
+	replyUnknownLocalException = 5
+	replyUnknownUserException  = 6
+)
+
+const headerSize = 14
+
+// The largest message accepted from a client.
+const maxMessageSize = 1 << 20
+
+var (
+	// ErrObjectNotExist is returned by a Handler for unknown identities.
+	ErrObjectNotExist = errors.New("ice: object does not exist")
+	// ErrOperationNotExist is returned by a Handler for unknown
+	// operations.
+	ErrOperationNotExist = errors.New("ice: operation does not exist")
+
+	errFacetNotExist = errors.New("ice: facet does not exist")
+)
+
+// A UserException is an exception declared in a Slice interface. Its
+// type ids list the exception's type followed by those of its base
+// exceptions, most derived first. The exceptions may not have members.
+type UserException struct {
+	TypeIds []string
+}
+
+func (e *UserException) Error() string {
+	return "ice: user exception " + e.TypeIds[0]
+}
+
+// A Request is an operation invoked on an object.
+type Request struct {
+	Identity  Identity
+	Facet     []string
+	Operation string
+	Mode      byte
+	Context   map[string]string
+	// The request's input parameters.
+	Params *Decoder
+	// The connection the request arrived on.
+	Conn net.Conn
+}
+
+// A Handler handles requests. ServeIce writes the operation's results
+// to out, which uses the encoding of the request's parameters.
+//
+// If ServeIce returns an error, the results are discarded and the
+// client receives an exception: ErrObjectNotExist and
+// ErrOperationNotExist are reported as such, UserExceptions are sent
+// to the client, and any other error is sent as an unknown local
+// exception.
+type Handler interface {
+	ServeIce(req *Request, out *Encoder) error
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(req *Request, out *Encoder) error
+
+func (f HandlerFunc) ServeIce(req *Request, out *Encoder) error {
+	return f(req, out)
+}
+
+// A Server serves Ice requests.
+type Server struct {
+	Handler  Handler
+	ErrorLog *log.Logger
+}
+
+// Serve accepts connections on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, v...)
+	}
+}
+
+func header(typ byte, size int) []byte {
+	buf := []byte{'I', 'c', 'e', 'P', 1, 0, 1, 0, typ, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(buf[10:], uint32(headerSize+size))
+	return buf
+}
+
+// serveConn handles the requests arriving on a connection. Requests
+// are handled one at a time, in order.
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	if _, err := conn.Write(header(msgValidateConnection, 0)); err != nil {
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		hdr := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return
+		}
+		if string(hdr[:4]) != "IceP" || hdr[4] != 1 {
+			s.logf("ice: %v: bad message header", conn.RemoteAddr())
+			return
+		}
+		if hdr[9] != 0 {
+			s.logf("ice: %v: compressed messages are not supported", conn.RemoteAddr())
+			return
+		}
+		size := int(binary.LittleEndian.Uint32(hdr[10:]))
+		if size < headerSize || size > maxMessageSize {
+			s.logf("ice: %v: bad message size %v", conn.RemoteAddr(), size)
+			return
+		}
+		body := make([]byte, size-headerSize)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return
+		}
+
+		d := NewDecoder(Encoding10, body)
+		switch hdr[8] {
+		case msgRequest:
+			err := s.handleRequest(conn, d, true)
+			if err != nil {
+				s.logf("ice: %v: %v", conn.RemoteAddr(), err)
+				return
+			}
+		case msgBatchRequest:
+			n := int(d.ReadInt())
+			for i := 0; i < n; i++ {
+				if err := s.handleRequest(conn, d, false); err != nil {
+					s.logf("ice: %v: %v", conn.RemoteAddr(), err)
+					return
+				}
+			}
+		case msgCloseConnection:
+			return
+		case msgValidateConnection:
+		default:
+			s.logf("ice: %v: unknown message type %v", conn.RemoteAddr(), hdr[8])
+			return
+		}
+	}
+}
+
+// handleRequest decodes a request, runs it, and sends the reply.
+// Batch requests have no request id, and get no reply.
+func (s *Server) handleRequest(conn net.Conn, d *Decoder, hasId bool) error {
+	var id int32
+	if hasId {
+		id = d.ReadInt()
+	}
+	req := &Request{Conn: conn}
+	req.Identity = d.ReadIdentity()
+	req.Facet = d.ReadStringSeq()
+	req.Operation = d.ReadString()
+	req.Mode, _ = d.ReadByte()
+	req.Context = d.ReadStringMap()
+	req.Params = d.ReadEncapsulation()
+	if err := d.Err(); err != nil {
+		return err
+	}
+
+	encoding := req.Params.Encoding
+	if encoding != Encoding10 && encoding != Encoding11 {
+		encoding = Encoding11
+	}
+	out := NewEncoder(encoding)
+	var err error
+	if len(req.Facet) > 0 {
+		err = errFacetNotExist
+	} else {
+		err = s.Handler.ServeIce(req, out)
+	}
+	if id == 0 {
+		// Oneway request
+		return nil
+	}
+
+	reply := NewEncoder(Encoding10)
+	reply.WriteInt(id)
+	switch e := err.(type) {
+	case nil:
+		reply.WriteByte(replyOK)
+		reply.WriteEncapsulation(encoding, out.Bytes())
+	case *UserException:
+		if encoding == Encoding10 {
+			reply.WriteByte(replyUnknownUserException)
+			reply.WriteString(e.TypeIds[0])
+			break
+		}
+		reply.WriteByte(replyUserException)
+		reply.WriteEncapsulation(encoding, encodeException(e))
+	default:
+		switch err {
+		case ErrObjectNotExist, ErrOperationNotExist, errFacetNotExist:
+			status := byte(replyObjectNotExist)
+			if err == ErrOperationNotExist {
+				status = replyOperationNotExist
+			} else if err == errFacetNotExist {
+				status = replyFacetNotExist
+			}
+			reply.WriteByte(status)
+			reply.WriteIdentity(req.Identity)
+			reply.WriteStringSeq(req.Facet)
+			reply.WriteString(req.Operation)
+		default:
+			reply.WriteByte(replyUnknownLocalException)
+			reply.WriteString(fmt.Sprintf("%v", err))
+		}
+	}
+
+	_, werr := conn.Write(append(header(msgReply, len(reply.Bytes())), reply.Bytes()...))
+	return werr
+}
+
+// encodeException encodes a user exception without members in the
+// compact 1.1 format: one slice per type, each with its type id.
+func encodeException(e *UserException) []byte {
+	const (
+		flagHasTypeIdString = 0x01
+		flagIsLastSlice     = 0x20
+	)
+	enc := NewEncoder(Encoding11)
+	for i, id := range e.TypeIds {
+		flags := byte(flagHasTypeIdString)
+		if i == len(e.TypeIds)-1 {
+			flags |= flagIsLastSlice
+		}
+		enc.WriteByte(flags)
+		enc.WriteString(id)
+	}
+	return enc.Bytes()
+}
+
+// ServeObject handles the operations all Ice objects support (ice_ping,
+// ice_isA, ice_id and ice_ids) for an object with the given type ids,
+// most derived first. It returns false for other operations.
+func ServeObject(req *Request, out *Encoder, typeIds ...string) bool {
+	ids := append(append([]string{}, typeIds...), "::Ice::Object")
+	switch req.Operation {
+	case "ice_ping":
+	case "ice_isA":
+		want := req.Params.ReadString()
+		found := false
+		for _, id := range ids {
+			found = found || id == want
+		}
+		out.WriteBool(found)
+	case "ice_id":
+		out.WriteString(ids[0])
+	case "ice_ids":
+		sorted := append([]string{}, ids...)
+		sort.Strings(sorted)
+		out.WriteStringSeq(sorted)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package jitterbuf implements a small reorder buffer for voice
+// packets.
+//
+// Packets are passed on in the order of their sequence numbers. A
+// packet that follows the last one passed on is passed on right away;
+// one that arrives after a gap is held until the packets in the gap
+// arrive, or until it has been held for the buffer's delay. The step
+// between sequence numbers is learned from the packets, since clients
+// number audio frames rather than packets.
+package jitterbuf
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// The number of packets held at most. When more arrive, the gap is
+// given up on.
+const maxHeld = 32
+
+// A sequence number this far behind the last one starts a new stream,
+// as when a client starts talking again.
+const resetDistance = 1000
+
+type packet struct {
+	seq uint64
+	buf []byte
+}
+
+// Buffer is a reorder buffer. It is safe for concurrent use.
+type Buffer struct {
+	mutex   sync.Mutex
+	delay   time.Duration
+	out     func(buf []byte)
+	started bool
+	last    uint64
+	step    uint64
+	held    []packet
+	timer   *time.Timer
+	stopped bool
+}
+
+// New creates a Buffer that passes packets on to out, holding them for
+// at most delay. out is called with the buffer locked, in sequence
+// order.
+func New(delay time.Duration, out func(buf []byte)) *Buffer {
+	return &Buffer{delay: delay, out: out}
+}
+
+// Push adds the packet buf with sequence number seq to the buffer.
+func (b *Buffer) Push(seq uint64, buf []byte) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.stopped {
+		return
+	}
+
+	switch {
+	case !b.started:
+		b.started = true
+		b.release(seq, buf)
+	case seq <= b.last:
+		// A late packet is passed on for the receivers to sort out,
+		// unless it starts a new stream.
+		if b.last-seq >= resetDistance {
+			b.flush()
+			b.step = 0
+			b.release(seq, buf)
+		} else {
+			b.out(buf)
+		}
+	case b.step == 0 || seq-b.last <= b.step:
+		b.step = seq - b.last
+		b.release(seq, buf)
+	default:
+		b.hold(seq, buf)
+	}
+	b.drain()
+}
+
+// Flush passes on all held packets.
+func (b *Buffer) Flush() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.flush()
+}
+
+// Stop drops all held packets. Packets pushed afterwards are dropped.
+func (b *Buffer) Stop() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.stopped = true
+	b.held = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
+
+// release passes a packet on, and makes it the last one.
+func (b *Buffer) release(seq uint64, buf []byte) {
+	b.last = seq
+	b.out(buf)
+}
+
+// hold keeps a packet that arrived after a gap.
+func (b *Buffer) hold(seq uint64, buf []byte) {
+	i := sort.Search(len(b.held), func(i int) bool { return b.held[i].seq >= seq })
+	if i < len(b.held) && b.held[i].seq == seq {
+		return
+	}
+	b.held = append(b.held, packet{})
+	copy(b.held[i+1:], b.held[i:])
+	b.held[i] = packet{seq: seq, buf: buf}
+
+	if len(b.held) > maxHeld {
+		b.flush()
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.delay, b.expire)
+	}
+}
+
+// drain passes on the held packets that follow the last one.
+func (b *Buffer) drain() {
+	for len(b.held) > 0 && (b.held[0].seq <= b.last || b.held[0].seq-b.last <= b.step) {
+		if b.held[0].seq > b.last {
+			b.release(b.held[0].seq, b.held[0].buf)
+		}
+		b.held = b.held[1:]
+	}
+	if len(b.held) == 0 && b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
+
+// flush passes on all held packets, giving up on the gaps between them.
+func (b *Buffer) flush() {
+	for _, p := range b.held {
+		b.release(p.seq, p.buf)
+	}
+	b.held = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
+
+// expire is called when the first held packet has been held for the
+// buffer's delay.
+func (b *Buffer) expire() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.timer = nil
+	if !b.stopped {
+		b.flush()
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package jitterbuf
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mutex sync.Mutex
+	seqs  []int
+}
+
+func (r *recorder) out(buf []byte) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.seqs = append(r.seqs, int(buf[0]))
+}
+
+func (r *recorder) got() []int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]int(nil), r.seqs...)
+}
+
+func push(b *Buffer, seqs ...int) {
+	for _, seq := range seqs {
+		b.Push(uint64(seq), []byte{byte(seq)})
+	}
+}
+
+func TestReorder(t *testing.T) {
+	r := &recorder{}
+	b := New(time.Hour, r.out)
+	push(b, 2, 4, 8, 10, 6, 12)
+	expected := []int{2, 4, 6, 8, 10, 12}
+	if got := r.got(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	r := &recorder{}
+	b := New(10*time.Millisecond, r.out)
+	push(b, 1, 2, 5, 4)
+	if got := r.got(); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("got %v before the delay, expected [1 2]", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := r.got(); !reflect.DeepEqual(got, []int{1, 2, 4, 5}) {
+		t.Errorf("got %v after the delay, expected [1 2 4 5]", got)
+	}
+}
+
+func TestFlushAndStop(t *testing.T) {
+	r := &recorder{}
+	b := New(time.Hour, r.out)
+	push(b, 1, 2, 4)
+	b.Flush()
+	push(b, 6)
+	b.Stop()
+	push(b, 7)
+	if got := r.got(); !reflect.DeepEqual(got, []int{1, 2, 4}) {
+		t.Errorf("got %v, expected [1 2 4]", got)
+	}
+}
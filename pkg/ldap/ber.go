@@ -0,0 +1,163 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// The BER tags used by LDAP. Only tag numbers below 31 are used, so
+// each tag is a single byte.
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+)
+
+// The largest element accepted from a server.
+const maxElementSize = 16 << 20
+
+var errMalformed = errors.New("ldap: malformed BER element")
+
+// An element is a decoded BER element.
+type element struct {
+	tag  byte
+	data []byte
+}
+
+// tlv encodes an element with the given tag and contents.
+func tlv(tag byte, contents ...[]byte) []byte {
+	n := 0
+	for _, c := range contents {
+		n += len(c)
+	}
+	buf := []byte{tag}
+	switch {
+	case n < 0x80:
+		buf = append(buf, byte(n))
+	case n < 0x100:
+		buf = append(buf, 0x81, byte(n))
+	case n < 0x10000:
+		buf = append(buf, 0x82, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	for _, c := range contents {
+		buf = append(buf, c...)
+	}
+	return buf
+}
+
+// berInt encodes an integer with the given tag.
+func berInt(tag byte, n int64) []byte {
+	var buf []byte
+	for {
+		buf = append([]byte{byte(n)}, buf...)
+		if (n >= -0x80 && n < 0x80) || len(buf) == 8 {
+			break
+		}
+		n >>= 8
+	}
+	return tlv(tag, buf)
+}
+
+func berString(tag byte, s string) []byte {
+	return tlv(tag, []byte(s))
+}
+
+func berBool(b bool) []byte {
+	if b {
+		return tlv(tagBoolean, []byte{0xff})
+	}
+	return tlv(tagBoolean, []byte{0})
+}
+
+// readElement reads a single element from r.
+func readElement(r *bufio.Reader) (element, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return element{}, err
+	}
+	if tag&0x1f == 0x1f {
+		return element{}, errMalformed
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return element{}, io.ErrUnexpectedEOF
+	}
+	size := int(b)
+	if b&0x80 != 0 {
+		n := int(b & 0x7f)
+		if n == 0 || n > 4 {
+			return element{}, errMalformed
+		}
+		size = 0
+		for i := 0; i < n; i++ {
+			b, err := r.ReadByte()
+			if err != nil {
+				return element{}, io.ErrUnexpectedEOF
+			}
+			size = size<<8 | int(b)
+		}
+	}
+	if size > maxElementSize {
+		return element{}, errMalformed
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return element{}, io.ErrUnexpectedEOF
+	}
+	return element{tag: tag, data: data}, nil
+}
+
+// children decodes the elements contained in e.
+func (e element) children() ([]element, error) {
+	var elems []element
+	data := e.data
+	for len(data) > 0 {
+		if len(data) < 2 || data[0]&0x1f == 0x1f {
+			return nil, errMalformed
+		}
+		tag, size, off := data[0], int(data[1]), 2
+		if data[1]&0x80 != 0 {
+			n := int(data[1] & 0x7f)
+			if n == 0 || n > 4 || len(data) < 2+n {
+				return nil, errMalformed
+			}
+			size = 0
+			for _, b := range data[2 : 2+n] {
+				size = size<<8 | int(b)
+			}
+			off += n
+		}
+		if size < 0 || size > len(data)-off {
+			return nil, errMalformed
+		}
+		elems = append(elems, element{tag: tag, data: data[off : off+size]})
+		data = data[off+size:]
+	}
+	return elems, nil
+}
+
+// int decodes the contents of e as an integer.
+func (e element) int() (int64, error) {
+	if len(e.data) == 0 || len(e.data) > 8 {
+		return 0, errMalformed
+	}
+	n := int64(int8(e.data[0]))
+	for _, b := range e.data[1:] {
+		n = n<<8 | int64(b)
+	}
+	return n, nil
+}
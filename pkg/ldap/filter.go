@@ -0,0 +1,169 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// The tags of the filter choices.
+const (
+	filterAnd            = classContext | constructed | 0
+	filterOr             = classContext | constructed | 1
+	filterNot            = classContext | constructed | 2
+	filterEqualityMatch  = classContext | constructed | 3
+	filterSubstrings     = classContext | constructed | 4
+	filterGreaterOrEqual = classContext | constructed | 5
+	filterLessOrEqual    = classContext | constructed | 6
+	filterPresent        = classContext | 7
+	filterApproxMatch    = classContext | constructed | 8
+
+	substringInitial = classContext | 0
+	substringAny     = classContext | 1
+	substringFinal   = classContext | 2
+)
+
+// ErrFilter is returned for search filters that can't be parsed.
+var ErrFilter = errors.New("ldap: invalid search filter")
+
+// encodeFilter encodes a search filter in the string representation of
+// RFC 4515, such as "(&(objectClass=groupOfNames)(cn=staff*))".
+// Extensible matches are not supported.
+func encodeFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if len(filter) > 0 && filter[0] != '(' {
+		filter = "(" + filter + ")"
+	}
+	buf, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, ErrFilter
+	}
+	return buf, nil
+}
+
+// parseFilter encodes the parenthesized filter at the start of s, and
+// returns the rest of s.
+func parseFilter(s string) ([]byte, string, error) {
+	if len(s) < 2 || s[0] != '(' {
+		return nil, "", ErrFilter
+	}
+	s = s[1:]
+	switch s[0] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		s = s[1:]
+		var parts [][]byte
+		for len(s) > 0 && s[0] == '(' {
+			part, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			parts = append(parts, part)
+			s = rest
+		}
+		if len(parts) == 0 || len(s) == 0 || s[0] != ')' {
+			return nil, "", ErrFilter
+		}
+		return tlv(tag, parts...), s[1:], nil
+	case '!':
+		part, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		if len(rest) == 0 || rest[0] != ')' {
+			return nil, "", ErrFilter
+		}
+		return tlv(filterNot, part), rest[1:], nil
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", ErrFilter
+	}
+	item, rest := s[:end], s[end+1:]
+	eq := strings.IndexByte(item, '=')
+	if eq < 1 {
+		return nil, "", ErrFilter
+	}
+	attr, value := item[:eq], item[eq+1:]
+	tag := byte(filterEqualityMatch)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = filterGreaterOrEqual, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLessOrEqual, attr[:len(attr)-1]
+	case '~':
+		tag, attr = filterApproxMatch, attr[:len(attr)-1]
+	case ':':
+		return nil, "", ErrFilter
+	}
+	if len(attr) == 0 {
+		return nil, "", ErrFilter
+	}
+
+	if tag == filterEqualityMatch && strings.Contains(value, "*") {
+		if value == "*" {
+			return berString(filterPresent, attr), rest, nil
+		}
+		parts := strings.Split(value, "*")
+		var subs [][]byte
+		for i, part := range parts {
+			if len(part) == 0 {
+				continue
+			}
+			v, err := unescapeValue(part)
+			if err != nil {
+				return nil, "", err
+			}
+			subTag := byte(substringAny)
+			switch i {
+			case 0:
+				subTag = substringInitial
+			case len(parts) - 1:
+				subTag = substringFinal
+			}
+			subs = append(subs, berString(subTag, v))
+		}
+		return tlv(filterSubstrings, berString(tagOctetString, attr), tlv(tagSequence, subs...)), rest, nil
+	}
+
+	v, err := unescapeValue(value)
+	if err != nil {
+		return nil, "", err
+	}
+	return tlv(tag, berString(tagOctetString, attr), berString(tagOctetString, v)), rest, nil
+}
+
+// unescapeValue decodes the \XX escapes of a filter value.
+func unescapeValue(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", ErrFilter
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", ErrFilter
+		}
+		b.Write(c)
+		i += 2
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package ldap implements a minimal LDAPv3 client that can only bind
+// with a password and search, one request at a time.
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// The tags of the protocol operations.
+const (
+	opBindRequest       = classApplication | constructed | 0
+	opBindResponse      = classApplication | constructed | 1
+	opUnbindRequest     = classApplication | 2
+	opSearchRequest     = classApplication | constructed | 3
+	opSearchResultEntry = classApplication | constructed | 4
+	opSearchResultDone  = classApplication | constructed | 5
+	opSearchResultRef   = classApplication | constructed | 19
+)
+
+// Search scopes.
+const (
+	ScopeBaseObject   = 0
+	ScopeSingleLevel  = 1
+	ScopeWholeSubtree = 2
+)
+
+// The time allowed for each request.
+const requestTimeout = 30 * time.Second
+
+// Options configure a connection to a server.
+type Options struct {
+	// The server's host:port.
+	Address string
+	// If set, the connection uses TLS.
+	TLS *tls.Config
+}
+
+// A Conn is a connection to an LDAP server.
+type Conn struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	msgId int64
+}
+
+// A ResultError is an LDAP result other than success.
+type ResultError struct {
+	Code    int
+	Message string
+}
+
+func (e *ResultError) Error() string {
+	if len(e.Message) > 0 {
+		return fmt.Sprintf("ldap: result code %v: %v", e.Code, e.Message)
+	}
+	return fmt.Sprintf("ldap: result code %v", e.Code)
+}
+
+// An Entry is an entry returned by a search.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Values returns the values of the entry's attribute attr. Attribute
+// names are matched without regard to case.
+func (e *Entry) Values(attr string) []string {
+	for name, values := range e.Attributes {
+		if strings.EqualFold(name, attr) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Dial connects to the server described by opts.
+func Dial(opts Options) (*Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if opts.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", opts.Address, opts.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", opts.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn), nil
+}
+
+// NewConn returns a Conn that uses an established connection.
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{conn: conn, r: bufio.NewReader(conn)}
+}
+
+// Bind authenticates as dn with a password. An empty dn and password
+// bind anonymously.
+func (c *Conn) Bind(dn, password string) error {
+	op := tlv(opBindRequest,
+		berInt(tagInteger, 3),
+		berString(tagOctetString, dn),
+		berString(classContext|0, password))
+	id, err := c.send(op)
+	if err != nil {
+		return err
+	}
+	resp, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if resp.tag != opBindResponse {
+		return fmt.Errorf("ldap: unexpected response %#x to bind", resp.tag)
+	}
+	return result(resp)
+}
+
+// Search returns the entries below base, in the given scope, that match
+// filter, given in the string representation of RFC 4515. Only the
+// given attributes are returned, or all of them if attrs is empty.
+// Search references are skipped.
+func (c *Conn) Search(base string, scope int, filter string, attrs []string) ([]Entry, error) {
+	f, err := encodeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	var names [][]byte
+	for _, attr := range attrs {
+		names = append(names, berString(tagOctetString, attr))
+	}
+	op := tlv(opSearchRequest,
+		berString(tagOctetString, base),
+		berInt(tagEnumerated, int64(scope)),
+		berInt(tagEnumerated, 0), // never dereference aliases
+		berInt(tagInteger, 0),    // no size limit
+		berInt(tagInteger, 0),    // no time limit
+		berBool(false),
+		f,
+		tlv(tagSequence, names...))
+	id, err := c.send(op)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for {
+		resp, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch resp.tag {
+		case opSearchResultEntry:
+			entry, err := parseEntry(resp)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchResultRef:
+		case opSearchResultDone:
+			if err := result(resp); err != nil {
+				return nil, err
+			}
+			return entries, nil
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response %#x to search", resp.tag)
+		}
+	}
+}
+
+// Close unbinds and closes the connection.
+func (c *Conn) Close() error {
+	c.send(tlv(opUnbindRequest))
+	return c.conn.Close()
+}
+
+// send sends a request with the given protocol operation, and returns
+// its message id.
+func (c *Conn) send(op []byte) (int64, error) {
+	c.msgId++
+	c.conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	_, err := c.conn.Write(tlv(tagSequence, berInt(tagInteger, c.msgId), op))
+	return c.msgId, err
+}
+
+// receive reads the next response to the request with the given
+// message id, and returns its protocol operation.
+func (c *Conn) receive(id int64) (element, error) {
+	c.conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	for {
+		msg, err := readElement(c.r)
+		if err != nil {
+			return element{}, err
+		}
+		parts, err := msg.children()
+		if err != nil {
+			return element{}, err
+		}
+		if msg.tag != tagSequence || len(parts) < 2 {
+			return element{}, errMalformed
+		}
+		msgId, err := parts[0].int()
+		if err != nil {
+			return element{}, err
+		}
+		if msgId == 0 {
+			// An unsolicited notification, such as the notice of
+			// disconnection.
+			if err := result(parts[1]); err != nil {
+				return element{}, err
+			}
+			return element{}, errors.New("ldap: unsolicited notification")
+		}
+		if msgId == id {
+			return parts[1], nil
+		}
+	}
+}
+
+// result returns the error in the LDAPResult op, if any.
+func result(op element) error {
+	parts, err := op.children()
+	if err != nil {
+		return err
+	}
+	if len(parts) < 3 {
+		return errMalformed
+	}
+	code, err := parts[0].int()
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &ResultError{Code: int(code), Message: string(parts[2].data)}
+	}
+	return nil
+}
+
+// parseEntry decodes a SearchResultEntry.
+func parseEntry(op element) (Entry, error) {
+	parts, err := op.children()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(parts) != 2 {
+		return Entry{}, errMalformed
+	}
+	entry := Entry{DN: string(parts[0].data), Attributes: map[string][]string{}}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, attr := range attrs {
+		fields, err := attr.children()
+		if err != nil {
+			return Entry{}, err
+		}
+		if len(fields) != 2 {
+			return Entry{}, errMalformed
+		}
+		values, err := fields[1].children()
+		if err != nil {
+			return Entry{}, err
+		}
+		name := string(fields[0].data)
+		for _, v := range values {
+			entry.Attributes[name] = append(entry.Attributes[name], string(v.data))
+		}
+	}
+	return entry, nil
+}
+
+// FirstRDN returns the attribute and value of the first relative
+// distinguished name of dn: "uid" and "alice" for
+// "uid=alice,ou=people,dc=example,dc=org". It returns an empty
+// attribute if dn isn't a distinguished name.
+func FirstRDN(dn string) (attr, value string) {
+	eq := strings.IndexByte(dn, '=')
+	if eq < 1 {
+		return "", dn
+	}
+	attr = strings.TrimSpace(dn[:eq])
+	var b strings.Builder
+	rest := dn[eq+1:]
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		if c == ',' || c == '+' {
+			break
+		}
+		if c == '\\' && i+1 < len(rest) {
+			i++
+			c = rest[i]
+			if i+1 < len(rest) && isHex(c) && isHex(rest[i+1]) {
+				c = unhex(c)<<4 | unhex(rest[i+1])
+				i++
+			}
+		}
+		b.WriteByte(c)
+	}
+	return attr, strings.TrimSpace(b.String())
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c <= 'F':
+		return c - 'A' + 10
+	}
+	return c - 'a' + 10
+}
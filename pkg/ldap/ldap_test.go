@@ -0,0 +1,153 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestElementLength(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 255, 256, 65536} {
+		buf := tlv(tagOctetString, make([]byte, n))
+		e, err := readElement(bufio.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			t.Fatalf("length %v: %v", n, err)
+		}
+		if e.tag != tagOctetString || len(e.data) != n {
+			t.Errorf("length %v: got tag %#x, length %v", n, e.tag, len(e.data))
+		}
+	}
+	for _, n := range []int64{0, 1, 127, 128, -1, -129, 1 << 40} {
+		buf := berInt(tagInteger, n)
+		e, err := readElement(bufio.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := e.int(); err != nil || got != n {
+			t.Errorf("Integer %v decoded as %v (%v)", n, got, err)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	buf, err := encodeFilter("(cn=a)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0xa3, 0x07, 0x04, 0x02, 'c', 'n', 0x04, 0x01, 'a'}
+	if !bytes.Equal(buf, want) {
+		t.Errorf("Unexpected encoding %x, expected %x", buf, want)
+	}
+
+	buf, err = encodeFilter("(&(objectClass=*)(!(cn=a\\2ab*c)))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = tlv(filterAnd,
+		berString(filterPresent, "objectClass"),
+		tlv(filterNot, tlv(filterSubstrings, berString(tagOctetString, "cn"), tlv(tagSequence,
+			berString(substringInitial, "a*b"),
+			berString(substringFinal, "c")))))
+	if !bytes.Equal(buf, want) {
+		t.Errorf("Unexpected encoding %x, expected %x", buf, want)
+	}
+
+	for _, bad := range []string{"", "(cn=a", "(&)", "(=a)", "(cn=a)(cn=b)", "(cn=\\2)", "(cn:dn:=a)"} {
+		if _, err := encodeFilter(bad); err != ErrFilter {
+			t.Errorf("Expected ErrFilter for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestFirstRDN(t *testing.T) {
+	tests := []struct{ dn, attr, value string }{
+		{"uid=alice,ou=people,dc=example,dc=org", "uid", "alice"},
+		{"CN=Smith\\, John+uid=js,dc=org", "CN", "Smith, John"},
+		{"cn=caf\\c3\\a9", "cn", "café"},
+		{"alice", "", "alice"},
+	}
+	for _, test := range tests {
+		attr, value := FirstRDN(test.dn)
+		if attr != test.attr || value != test.value {
+			t.Errorf("FirstRDN(%q) = %q, %q", test.dn, attr, value)
+		}
+	}
+}
+
+// message encodes an LDAP message.
+func message(id int64, op []byte) []byte {
+	return tlv(tagSequence, berInt(tagInteger, id), op)
+}
+
+func ldapResult(tag byte, code int64, msg string) []byte {
+	return tlv(tag, berInt(tagEnumerated, code), berString(tagOctetString, ""), berString(tagOctetString, msg))
+}
+
+// server runs a fake directory on conn that accepts the password
+// "secret" and answers every search with a single group.
+func server(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		msg, err := readElement(r)
+		if err != nil {
+			conn.Close()
+			return
+		}
+		parts, _ := msg.children()
+		id, _ := parts[0].int()
+		switch parts[1].tag {
+		case opBindRequest:
+			fields, _ := parts[1].children()
+			code := int64(0)
+			if string(fields[2].data) != "secret" {
+				code = 49
+			}
+			conn.Write(message(id, ldapResult(opBindResponse, code, "")))
+		case opSearchRequest:
+			conn.Write(message(id, tlv(opSearchResultEntry,
+				berString(tagOctetString, "cn=staff,ou=groups,dc=example,dc=org"),
+				tlv(tagSequence,
+					tlv(tagSequence, berString(tagOctetString, "cn"), tlv(tagSet, berString(tagOctetString, "staff"))),
+					tlv(tagSequence, berString(tagOctetString, "member"), tlv(tagSet,
+						berString(tagOctetString, "uid=alice,ou=people,dc=example,dc=org"),
+						berString(tagOctetString, "uid=bob,ou=people,dc=example,dc=org")))))))
+			conn.Write(message(id, tlv(opSearchResultRef, berString(tagOctetString, "ldap://other/"))))
+			conn.Write(message(id, ldapResult(opSearchResultDone, 0, "")))
+		case opUnbindRequest:
+			conn.Close()
+			return
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	client, srv := net.Pipe()
+	go server(srv)
+	c := NewConn(client)
+	defer c.Close()
+
+	err := c.Bind("cn=grumble,dc=example,dc=org", "wrong")
+	if rerr, ok := err.(*ResultError); !ok || rerr.Code != 49 {
+		t.Fatalf("Expected invalid credentials, got %v", err)
+	}
+	if err := c.Bind("cn=grumble,dc=example,dc=org", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := c.Search("dc=example,dc=org", ScopeWholeSubtree, "(objectClass=groupOfNames)", []string{"cn", "member"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].DN != "cn=staff,ou=groups,dc=example,dc=org" {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	members := entries[0].Values("MEMBER")
+	if !reflect.DeepEqual(members, []string{"uid=alice,ou=people,dc=example,dc=org", "uid=bob,ou=people,dc=example,dc=org"}) {
+		t.Errorf("Unexpected members %v", members)
+	}
+}
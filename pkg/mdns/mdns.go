@@ -0,0 +1,478 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package mdns implements a minimal Multicast DNS (RFC 6762) responder
+// for advertising DNS-SD (RFC 6763) services on the local network.
+//
+// The responder answers queries for the services registered with it,
+// announces them when they are registered, and sends goodbye packets
+// when they are unregistered. It does not probe for name conflicts.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	port = 5353
+
+	// The TTL of the records sent by the responder.
+	recordTTL = 120
+
+	// The highest TTL allowed in replies to legacy unicast queries.
+	legacyTTL = 10
+
+	// The top bit of the class field is the cache-flush bit in
+	// responses, and the unicast-response bit in questions.
+	classTopBit = 0x8000
+
+	servicesName = "_services._dns-sd._udp.local."
+)
+
+var (
+	ipv4Group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: port}
+	ipv6Group = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: port}
+)
+
+// A Service is a DNS-SD service instance.
+type Service struct {
+	// The user visible name of the instance, e.g. "My Server".
+	Instance string
+	// The service type, e.g. "_mumble._tcp".
+	Type string
+	// The host name, without the .local suffix. Defaults to
+	// the system's host name.
+	Host string
+	Port uint16
+	// The addresses of the host. Defaults to the addresses of
+	// all network interfaces.
+	IPs []net.IP
+	TXT []string
+}
+
+func (svc *Service) typeName() string {
+	return svc.Type + ".local."
+}
+
+func (svc *Service) instanceName() string {
+	// Dots would be taken as label separators.
+	return strings.Replace(svc.Instance, ".", " ", -1) + "." + svc.typeName()
+}
+
+func (svc *Service) hostName() string {
+	return svc.Host + ".local."
+}
+
+// A Responder answers mDNS queries for its registered services.
+type Responder struct {
+	conn4  *ipv4.PacketConn
+	conn6  *ipv6.PacketConn
+	ifaces []net.Interface
+	wg     sync.WaitGroup
+
+	mutex    sync.Mutex
+	services []*Service
+}
+
+// NewResponder joins the mDNS multicast groups on all multicast
+// capable network interfaces and starts answering queries. IPv6 is
+// used if available.
+func NewResponder() (*Responder, error) {
+	r := &Responder{}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagUp != 0 && ifi.Flags&net.FlagMulticast != 0 {
+			r.ifaces = append(r.ifaces, ifi)
+		}
+	}
+	if len(r.ifaces) == 0 {
+		return nil, errors.New("mdns: no multicast capable network interfaces")
+	}
+
+	lc := net.ListenConfig{Control: reuseAddr}
+	pc, err := lc.ListenPacket(context.Background(), "udp4", "0.0.0.0:5353")
+	if err != nil {
+		return nil, err
+	}
+	r.conn4 = ipv4.NewPacketConn(pc)
+	joined := 0
+	for i := range r.ifaces {
+		if r.conn4.JoinGroup(&r.ifaces[i], ipv4Group) == nil {
+			joined++
+		}
+	}
+	if joined == 0 {
+		pc.Close()
+		return nil, errors.New("mdns: unable to join multicast group")
+	}
+	r.conn4.SetMulticastTTL(255)
+	r.conn4.SetMulticastLoopback(true)
+	r.conn4.SetControlMessage(ipv4.FlagInterface, true)
+
+	pc6, err := lc.ListenPacket(context.Background(), "udp6", "[::]:5353")
+	if err == nil {
+		r.conn6 = ipv6.NewPacketConn(pc6)
+		joined = 0
+		for i := range r.ifaces {
+			if r.conn6.JoinGroup(&r.ifaces[i], ipv6Group) == nil {
+				joined++
+			}
+		}
+		if joined == 0 {
+			pc6.Close()
+			r.conn6 = nil
+		} else {
+			r.conn6.SetMulticastHopLimit(255)
+			r.conn6.SetMulticastLoopback(true)
+			r.conn6.SetControlMessage(ipv6.FlagInterface, true)
+		}
+	}
+
+	r.wg.Add(1)
+	go r.readLoop4()
+	if r.conn6 != nil {
+		r.wg.Add(1)
+		go r.readLoop6()
+	}
+	return r, nil
+}
+
+// Register starts advertising svc, and announces it on the network.
+func (r *Responder) Register(svc *Service) error {
+	if len(svc.Instance) == 0 || len(svc.Type) == 0 {
+		return errors.New("mdns: service instance and type must be set")
+	}
+	if len(svc.Host) == 0 {
+		host, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		svc.Host = strings.SplitN(host, ".", 2)[0]
+	}
+
+	r.mutex.Lock()
+	r.services = append(r.services, svc)
+	r.mutex.Unlock()
+
+	// Announce the service twice, one second apart.
+	r.announce(svc, recordTTL)
+	go func() {
+		time.Sleep(1 * time.Second)
+		if r.isRegistered(svc) {
+			r.announce(svc, recordTTL)
+		}
+	}()
+	return nil
+}
+
+// Unregister stops advertising svc, and tells the network that it is gone.
+func (r *Responder) Unregister(svc *Service) {
+	r.mutex.Lock()
+	found := false
+	for i, s := range r.services {
+		if s == svc {
+			r.services = append(r.services[:i], r.services[i+1:]...)
+			found = true
+			break
+		}
+	}
+	r.mutex.Unlock()
+
+	if found {
+		r.announce(svc, 0)
+	}
+}
+
+// Close unregisters all services and stops the responder.
+func (r *Responder) Close() error {
+	r.mutex.Lock()
+	services := r.services
+	r.services = nil
+	r.mutex.Unlock()
+
+	for _, svc := range services {
+		r.announce(svc, 0)
+	}
+
+	err := r.conn4.Close()
+	if r.conn6 != nil {
+		r.conn6.Close()
+	}
+	r.wg.Wait()
+	return err
+}
+
+func (r *Responder) isRegistered(svc *Service) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, s := range r.services {
+		if s == svc {
+			return true
+		}
+	}
+	return false
+}
+
+// announce sends all records of svc to the multicast groups on
+// all interfaces. A TTL of zero announces that the service is gone.
+func (r *Responder) announce(svc *Service, ttl uint32) {
+	msg := &dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}}
+	msg.Answers = append(msg.Answers, svc.ptrRecord(ttl))
+	msg.Answers = append(msg.Answers, svc.srvRecord(ttl), svc.txtRecord(ttl))
+	msg.Answers = append(msg.Answers, svc.addrRecords(ttl, true, true)...)
+	buf, err := msg.Pack()
+	if err != nil {
+		return
+	}
+	r.sendMulticast(buf, 0)
+}
+
+// sendMulticast sends buf to the multicast groups on the interface
+// with the given index, or on all interfaces if ifIndex is zero.
+func (r *Responder) sendMulticast(buf []byte, ifIndex int) {
+	for i := range r.ifaces {
+		ifi := &r.ifaces[i]
+		if ifIndex != 0 && ifi.Index != ifIndex {
+			continue
+		}
+		if r.conn4.SetMulticastInterface(ifi) == nil {
+			r.conn4.WriteTo(buf, nil, ipv4Group)
+		}
+		if r.conn6 != nil && r.conn6.SetMulticastInterface(ifi) == nil {
+			r.conn6.WriteTo(buf, nil, ipv6Group)
+		}
+	}
+}
+
+func (r *Responder) readLoop4() {
+	defer r.wg.Done()
+	buf := make([]byte, 9000)
+	for {
+		n, cm, src, err := r.conn4.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		ifIndex := 0
+		if cm != nil {
+			ifIndex = cm.IfIndex
+		}
+		r.handlePacket(buf[:n], src, ifIndex, func(b []byte, dst net.Addr) {
+			r.conn4.WriteTo(b, nil, dst)
+		})
+	}
+}
+
+func (r *Responder) readLoop6() {
+	defer r.wg.Done()
+	buf := make([]byte, 9000)
+	for {
+		n, cm, src, err := r.conn6.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		ifIndex := 0
+		if cm != nil {
+			ifIndex = cm.IfIndex
+		}
+		r.handlePacket(buf[:n], src, ifIndex, func(b []byte, dst net.Addr) {
+			r.conn6.WriteTo(b, nil, dst)
+		})
+	}
+}
+
+// handlePacket answers a query received from src.
+func (r *Responder) handlePacket(buf []byte, src net.Addr, ifIndex int, sendUnicast func([]byte, net.Addr)) {
+	udpSrc, ok := src.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	resp, unicast := r.handleQuery(buf, udpSrc.Port != port)
+	if resp == nil {
+		return
+	}
+	out, err := resp.Pack()
+	if err != nil {
+		return
+	}
+	if unicast {
+		sendUnicast(out, src)
+	} else {
+		r.sendMulticast(out, ifIndex)
+	}
+}
+
+// handleQuery builds the response to a query. Queries from ports
+// other than 5353 are legacy unicast queries (RFC 6762, section 6.7),
+// answered directly with the query's id. The returned response is nil
+// if the query is not about any registered service.
+func (r *Responder) handleQuery(buf []byte, legacy bool) (resp *dnsmessage.Message, unicast bool) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(buf)
+	if err != nil || hdr.Response || hdr.OpCode != 0 {
+		return nil, false
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, false
+	}
+
+	r.mutex.Lock()
+	services := make([]*Service, len(r.services))
+	copy(services, r.services)
+	r.mutex.Unlock()
+
+	resp = &dnsmessage.Message{Header: dnsmessage.Header{Response: true, Authoritative: true}}
+	unicast = legacy
+	for _, q := range questions {
+		if q.Class&classTopBit != 0 {
+			unicast = true
+		}
+		for _, svc := range services {
+			answers, additionals := svc.answer(q)
+			resp.Answers = append(resp.Answers, answers...)
+			resp.Additionals = append(resp.Additionals, additionals...)
+		}
+	}
+	if len(resp.Answers) == 0 {
+		return nil, false
+	}
+
+	if legacy {
+		resp.Header.ID = hdr.ID
+		resp.Questions = questions
+		for _, rrs := range [][]dnsmessage.Resource{resp.Answers, resp.Additionals} {
+			for i := range rrs {
+				rrs[i].Header.Class &^= classTopBit
+				if rrs[i].Header.TTL > legacyTTL {
+					rrs[i].Header.TTL = legacyTTL
+				}
+			}
+		}
+	}
+	return resp, unicast
+}
+
+// answer returns the records of svc that answer q, along with
+// additional records the asker is likely to need next.
+func (svc *Service) answer(q dnsmessage.Question) (answers, additionals []dnsmessage.Resource) {
+	name := strings.ToLower(q.Name.String())
+	any := q.Type == dnsmessage.TypeALL
+	switch name {
+	case servicesName:
+		if q.Type == dnsmessage.TypePTR || any {
+			answers = append(answers, svc.servicesRecord())
+		}
+	case strings.ToLower(svc.typeName()):
+		if q.Type == dnsmessage.TypePTR || any {
+			answers = append(answers, svc.ptrRecord(recordTTL))
+			additionals = append(additionals, svc.srvRecord(recordTTL), svc.txtRecord(recordTTL))
+			additionals = append(additionals, svc.addrRecords(recordTTL, true, true)...)
+		}
+	case strings.ToLower(svc.instanceName()):
+		if q.Type == dnsmessage.TypeSRV || any {
+			answers = append(answers, svc.srvRecord(recordTTL))
+			additionals = append(additionals, svc.addrRecords(recordTTL, true, true)...)
+		}
+		if q.Type == dnsmessage.TypeTXT || any {
+			answers = append(answers, svc.txtRecord(recordTTL))
+		}
+	case strings.ToLower(svc.hostName()):
+		answers = append(answers, svc.addrRecords(recordTTL, q.Type == dnsmessage.TypeA || any, q.Type == dnsmessage.TypeAAAA || any)...)
+	}
+	return answers, additionals
+}
+
+func header(name string, typ dnsmessage.Type, ttl uint32, unique bool) dnsmessage.ResourceHeader {
+	class := dnsmessage.ClassINET
+	if unique {
+		class |= classTopBit
+	}
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, Class: class, TTL: ttl}
+}
+
+func (svc *Service) servicesRecord() dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: header(servicesName, dnsmessage.TypePTR, recordTTL, false),
+		Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(svc.typeName())},
+	}
+}
+
+func (svc *Service) ptrRecord(ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: header(svc.typeName(), dnsmessage.TypePTR, ttl, false),
+		Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(svc.instanceName())},
+	}
+}
+
+func (svc *Service) srvRecord(ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: header(svc.instanceName(), dnsmessage.TypeSRV, ttl, true),
+		Body:   &dnsmessage.SRVResource{Target: dnsmessage.MustNewName(svc.hostName()), Port: svc.Port},
+	}
+}
+
+func (svc *Service) txtRecord(ttl uint32) dnsmessage.Resource {
+	txt := svc.TXT
+	if len(txt) == 0 {
+		// A TXT record must hold at least one string.
+		txt = []string{""}
+	}
+	return dnsmessage.Resource{
+		Header: header(svc.instanceName(), dnsmessage.TypeTXT, ttl, true),
+		Body:   &dnsmessage.TXTResource{TXT: txt},
+	}
+}
+
+// addrRecords returns the A and/or AAAA records of the service's host.
+func (svc *Service) addrRecords(ttl uint32, v4, v6 bool) []dnsmessage.Resource {
+	var rrs []dnsmessage.Resource
+	for _, ip := range svc.addresses() {
+		if ip4 := ip.To4(); ip4 != nil {
+			if v4 {
+				rr := dnsmessage.AResource{}
+				copy(rr.A[:], ip4)
+				rrs = append(rrs, dnsmessage.Resource{Header: header(svc.hostName(), dnsmessage.TypeA, ttl, true), Body: &rr})
+			}
+		} else if v6 {
+			rr := dnsmessage.AAAAResource{}
+			copy(rr.AAAA[:], ip.To16())
+			rrs = append(rrs, dnsmessage.Resource{Header: header(svc.hostName(), dnsmessage.TypeAAAA, ttl, true), Body: &rr})
+		}
+	}
+	return rrs
+}
+
+// addresses returns the service's addresses, or the addresses
+// of all network interfaces if none are set.
+func (svc *Service) addresses() []net.IP {
+	if len(svc.IPs) > 0 {
+		return svc.IPs
+	}
+	var ips []net.IP
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipnet.IP)
+	}
+	return ips
+}
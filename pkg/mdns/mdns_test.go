@@ -0,0 +1,117 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package mdns
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func testResponder() *Responder {
+	return &Responder{services: []*Service{{
+		Instance: "Test Server",
+		Type:     "_mumble._tcp",
+		Host:     "grumble",
+		Port:     64738,
+		IPs:      []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1")},
+	}}}
+}
+
+func query(t *testing.T, id uint16, name string, qtype dnsmessage.Type, class dnsmessage.Class) []byte {
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  qtype,
+			Class: class,
+		}},
+	}
+	buf, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestBrowse(t *testing.T) {
+	r := testResponder()
+	resp, unicast := r.handleQuery(query(t, 0, "_mumble._tcp.local.", dnsmessage.TypePTR, dnsmessage.ClassINET), false)
+	if resp == nil {
+		t.Fatal("No response to browse query")
+	}
+	if unicast {
+		t.Error("Expected multicast response")
+	}
+	if len(resp.Answers) != 1 {
+		t.Fatalf("Expected 1 answer, got %v", len(resp.Answers))
+	}
+	ptr, ok := resp.Answers[0].Body.(*dnsmessage.PTRResource)
+	if !ok || ptr.PTR.String() != "Test Server._mumble._tcp.local." {
+		t.Errorf("Unexpected answer %v", resp.Answers[0].GoString())
+	}
+
+	// The SRV, TXT, A and AAAA records are sent along.
+	types := map[dnsmessage.Type]bool{}
+	for _, rr := range resp.Additionals {
+		types[rr.Header.Type] = true
+		if srv, ok := rr.Body.(*dnsmessage.SRVResource); ok {
+			if srv.Port != 64738 || srv.Target.String() != "grumble.local." {
+				t.Errorf("Unexpected SRV record %v", srv.GoString())
+			}
+		}
+	}
+	for _, typ := range []dnsmessage.Type{dnsmessage.TypeSRV, dnsmessage.TypeTXT, dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		if !types[typ] {
+			t.Errorf("Missing additional %v record", typ)
+		}
+	}
+
+	if _, err := resp.Pack(); err != nil {
+		t.Errorf("Unable to pack response: %v", err)
+	}
+}
+
+func TestUnicastQueries(t *testing.T) {
+	r := testResponder()
+
+	// The QU bit asks for a unicast response.
+	resp, unicast := r.handleQuery(query(t, 0, "grumble.local.", dnsmessage.TypeA, dnsmessage.ClassINET|classTopBit), false)
+	if resp == nil || !unicast {
+		t.Fatal("Expected unicast response")
+	}
+	if len(resp.Answers) != 1 || resp.Answers[0].Header.Type != dnsmessage.TypeA {
+		t.Errorf("Expected a single A record, got %v", resp.Answers)
+	}
+
+	// Legacy unicast queries are answered with the query's id and
+	// question, and a short TTL.
+	resp, unicast = r.handleQuery(query(t, 1234, "test server._mumble._tcp.local.", dnsmessage.TypeSRV, dnsmessage.ClassINET), true)
+	if resp == nil || !unicast {
+		t.Fatal("Expected unicast response")
+	}
+	if resp.Header.ID != 1234 || len(resp.Questions) != 1 {
+		t.Errorf("Legacy response lacks id or question")
+	}
+	for _, rr := range resp.Answers {
+		if rr.Header.TTL > legacyTTL || rr.Header.Class != dnsmessage.ClassINET {
+			t.Errorf("Bad legacy record header %v", rr.Header.GoString())
+		}
+	}
+}
+
+func TestIgnoredQueries(t *testing.T) {
+	r := testResponder()
+	for _, buf := range [][]byte{
+		query(t, 0, "_http._tcp.local.", dnsmessage.TypePTR, dnsmessage.ClassINET),
+		query(t, 0, "other.local.", dnsmessage.TypeA, dnsmessage.ClassINET),
+		{0, 1, 2},
+	} {
+		if resp, _ := r.handleQuery(buf, false); resp != nil {
+			t.Errorf("Unexpected response %v", resp.GoString())
+		}
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build !darwin && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!freebsd,!linux,!netbsd,!openbsd
+
+package mdns
+
+import (
+	"syscall"
+)
+
+// reuseAddr is a no-op on systems where sharing the mDNS port
+// needs no socket options, or is not supported.
+func reuseAddr(network, address string, c syscall.RawConn) error {
+	return nil
+}
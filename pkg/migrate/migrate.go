@@ -0,0 +1,155 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package migrate implements versioned migrations of an on-disk data
+// directory.
+//
+// Each migration has a version, numbered from 1 without gaps, and a
+// pair of functions that move the directory's contents up to that
+// version and back down again. The version the directory is at is
+// kept in a schema_version file in the directory, which is updated
+// after each migration, so that an interrupted run resumes where it
+// stopped.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The name of the file that holds a directory's schema version.
+const VersionFile = "schema_version"
+
+// A Migration moves a data directory from one schema version to the
+// next. Down may be nil for migrations that can't be undone.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(dir string) error
+	Down    func(dir string) error
+}
+
+// ErrTooNew is returned for directories at a version newer than the
+// last known migration, written by a newer program.
+var ErrTooNew = errors.New("migrate: data directory is newer than the known migrations")
+
+// A Migrator runs migrations on a directory.
+type Migrator struct {
+	Dir        string
+	Migrations []Migration
+}
+
+// New returns a Migrator for dir. The migrations must be numbered
+// from 1 without gaps, in order.
+func New(dir string, migrations []Migration) (*Migrator, error) {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migrate: migration %q has version %v, expected %v", m.Name, m.Version, i+1)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migrate: migration %v (%v) has no up function", m.Version, m.Name)
+		}
+	}
+	return &Migrator{Dir: dir, Migrations: migrations}, nil
+}
+
+// Latest returns the version of the last known migration.
+func (m *Migrator) Latest() int {
+	return len(m.Migrations)
+}
+
+// Version returns the directory's schema version, which is 0 if it
+// has none.
+func (m *Migrator) Version() (int, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(m.Dir, VersionFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(buf)))
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("migrate: invalid %v: %q", VersionFile, strings.TrimSpace(string(buf)))
+	}
+	return version, nil
+}
+
+// setVersion records the directory's schema version.
+func (m *Migrator) setVersion(version int) error {
+	fn := filepath.Join(m.Dir, VersionFile)
+	tmp := fn + ".tmp"
+	err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(version)+"\n"), 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
+
+// Pending returns the migrations that haven't been applied to the
+// directory yet.
+func (m *Migrator) Pending() ([]Migration, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if version > m.Latest() {
+		return nil, ErrTooNew
+	}
+	return m.Migrations[version:], nil
+}
+
+// Migrate moves the directory to the given version, applying the up
+// migrations on the way up and the down migrations on the way down.
+// It returns the migrations that were run, in the order they ran.
+func (m *Migrator) Migrate(target int) ([]Migration, error) {
+	if target < 0 || target > m.Latest() {
+		return nil, fmt.Errorf("migrate: no version %v", target)
+	}
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if version > m.Latest() {
+		return nil, ErrTooNew
+	}
+
+	var ran []Migration
+	for version < target {
+		mig := m.Migrations[version]
+		if err := mig.Up(m.Dir); err != nil {
+			return ran, fmt.Errorf("migrate: %v (%v) up: %v", mig.Version, mig.Name, err)
+		}
+		if err := m.setVersion(mig.Version); err != nil {
+			return ran, err
+		}
+		ran = append(ran, mig)
+		version = mig.Version
+	}
+	for version > target {
+		mig := m.Migrations[version-1]
+		if mig.Down == nil {
+			return ran, fmt.Errorf("migrate: %v (%v) can't be undone", mig.Version, mig.Name)
+		}
+		if err := mig.Down(m.Dir); err != nil {
+			return ran, fmt.Errorf("migrate: %v (%v) down: %v", mig.Version, mig.Name, err)
+		}
+		if err := m.setVersion(mig.Version - 1); err != nil {
+			return ran, err
+		}
+		ran = append(ran, mig)
+		version = mig.Version - 1
+	}
+	return ran, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() ([]Migration, error) {
+	return m.Migrate(m.Latest())
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package migrate
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// touch returns a migration that creates the named file, and removes
+// it again on the way down.
+func touch(version int, name string) Migration {
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up: func(dir string) error {
+			return ioutil.WriteFile(filepath.Join(dir, name), nil, 0600)
+		},
+		Down: func(dir string) error {
+			return os.Remove(filepath.Join(dir, name))
+		},
+	}
+}
+
+func exists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := New(dir, []Migration{touch(1, "a"), touch(2, "b"), touch(3, "c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := m.Version(); err != nil || version != 0 {
+		t.Fatalf("Expected version 0, got %v (%v)", version, err)
+	}
+	if pending, _ := m.Pending(); len(pending) != 3 {
+		t.Fatalf("Expected 3 pending migrations, got %v", len(pending))
+	}
+
+	ran, err := m.Migrate(2)
+	if err != nil || len(ran) != 2 {
+		t.Fatalf("Unexpected result %v (%v)", ran, err)
+	}
+	if !exists(dir, "a") || !exists(dir, "b") || exists(dir, "c") {
+		t.Errorf("Unexpected files after migrating to 2")
+	}
+	if pending, _ := m.Pending(); len(pending) != 1 || pending[0].Name != "c" {
+		t.Errorf("Unexpected pending migrations %v", pending)
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := m.Version(); version != 3 || !exists(dir, "c") {
+		t.Errorf("Expected version 3, got %v", version)
+	}
+
+	ran, err = m.Migrate(1)
+	if err != nil || len(ran) != 2 || ran[0].Version != 3 || ran[1].Version != 2 {
+		t.Fatalf("Unexpected result %v (%v)", ran, err)
+	}
+	if !exists(dir, "a") || exists(dir, "b") || exists(dir, "c") {
+		t.Errorf("Unexpected files after migrating down to 1")
+	}
+	if version, _ := m.Version(); version != 1 {
+		t.Errorf("Expected version 1, got %v", version)
+	}
+}
+
+func TestMigrateFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	broken := Migration{Version: 2, Name: "broken", Up: func(string) error { return errors.New("boom") }}
+	m, err := New(dir, []Migration{touch(1, "a"), broken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran, err := m.Up()
+	if err == nil || len(ran) != 1 {
+		t.Fatalf("Expected the second migration to fail, got %v (%v)", ran, err)
+	}
+	// The first migration is kept, and not run again.
+	if version, _ := m.Version(); version != 1 {
+		t.Errorf("Expected version 1, got %v", version)
+	}
+
+	// Migrations without a down function can't be undone.
+	m.Migrations[0].Down = nil
+	if _, err := m.Migrate(0); err == nil {
+		t.Errorf("Expected an error undoing an irreversible migration")
+	}
+}
+
+func TestTooNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, VersionFile), []byte("5\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := New(dir, []Migration{touch(1, "a")})
+	if _, err := m.Pending(); err != ErrTooNew {
+		t.Errorf("Expected ErrTooNew, got %v", err)
+	}
+	if _, err := m.Up(); err != ErrTooNew {
+		t.Errorf("Expected ErrTooNew, got %v", err)
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New("", []Migration{touch(2, "a")}); err == nil {
+		t.Errorf("Expected an error for a gap in the versions")
+	}
+	if _, err := New("", []Migration{{Version: 1, Name: "a"}}); err == nil {
+		t.Errorf("Expected an error for a migration without an up function")
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package mqtt implements a minimal MQTT 3.1.1 client that can only
+// publish messages, at QoS 0.
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Control packet types.
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetPingReq    = 12
+	packetPingResp   = 13
+	packetDisconnect = 14
+)
+
+// ErrClosed is returned when publishing on a closed client.
+var ErrClosed = errors.New("mqtt: connection closed")
+
+// Options configure a connection to a broker.
+type Options struct {
+	// The broker's host:port.
+	Address  string
+	ClientID string
+	Username string
+	Password string
+	// If set, the connection uses TLS.
+	TLS *tls.Config
+	// The interval at which the broker is pinged. Zero disables
+	// keep-alive.
+	KeepAlive time.Duration
+}
+
+// A Client is a connection to an MQTT broker.
+type Client struct {
+	conn net.Conn
+
+	writeLock sync.Mutex
+	closed    bool
+	done      chan bool
+	err       error
+}
+
+// Dial connects to the broker described by opts.
+func Dial(opts Options) (*Client, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if opts.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", opts.Address, opts.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", opts.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClient(conn, opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewClient performs the MQTT handshake on an established connection.
+func NewClient(conn net.Conn, opts Options) (*Client, error) {
+	var flags byte = 0x02 // clean session
+	payload := appendString(nil, opts.ClientID)
+	if len(opts.Username) > 0 {
+		flags |= 0x80
+		payload = appendString(payload, opts.Username)
+	}
+	if len(opts.Password) > 0 {
+		flags |= 0x40
+		payload = appendString(payload, opts.Password)
+	}
+	keepAlive := uint16(opts.KeepAlive / time.Second)
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags, byte(keepAlive>>8), byte(keepAlive))
+	body = append(body, payload...)
+
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Write(packet(packetConnect<<4, body)); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	typ, ack, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if typ != packetConnAck || len(ack) != 2 {
+		return nil, errors.New("mqtt: expected CONNACK")
+	}
+	if ack[1] != 0 {
+		return nil, fmt.Errorf("mqtt: connection refused (code %v)", ack[1])
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{conn: conn, done: make(chan bool)}
+	go c.readLoop(r)
+	if keepAlive > 0 {
+		go c.pingLoop(opts.KeepAlive)
+	}
+	return c, nil
+}
+
+// Publish sends a message to the broker. Retained messages are kept by
+// the broker and sent to clients that subscribe later.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	var header byte = packetPublish << 4
+	if retain {
+		header |= 0x01
+	}
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	return c.write(packet(header, body))
+}
+
+// Done returns a channel that is closed when the connection is lost.
+func (c *Client) Done() <-chan bool {
+	return c.done
+}
+
+// Err returns the error that ended the connection.
+func (c *Client) Err() error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.err
+}
+
+// Close disconnects from the broker.
+func (c *Client) Close() error {
+	c.write(packet(packetDisconnect<<4, nil))
+	c.fail(ErrClosed)
+	return nil
+}
+
+func (c *Client) write(buf []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.closed {
+		return c.err
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// fail closes the connection, recording the reason.
+func (c *Client) fail(err error) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.err = err
+	c.conn.Close()
+	close(c.done)
+}
+
+// readLoop discards the broker's PINGRESP packets until the connection
+// is lost.
+func (c *Client) readLoop(r *bufio.Reader) {
+	for {
+		typ, _, err := readPacket(r)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		if typ != packetPingResp {
+			c.fail(fmt.Errorf("mqtt: unexpected packet type %v", typ))
+			return
+		}
+	}
+}
+
+func (c *Client) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.write(packet(packetPingReq<<4, nil)); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// packet builds a control packet from its fixed header byte and body.
+func packet(header byte, body []byte) []byte {
+	buf := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(buf, body...)
+}
+
+// readPacket reads a control packet, returning its type and body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, mult := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * mult
+		mult *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header >> 4, body, nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	var l [2]byte
+	binary.BigEndian.PutUint16(l[:], uint16(len(s)))
+	buf = append(buf, l[:]...)
+	return append(buf, s...)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestPacketLength(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384} {
+		buf := packet(packetPublish<<4, make([]byte, n))
+		typ, body, err := readPacket(bufio.NewReader(bytes.NewReader(buf)))
+		if err != nil {
+			t.Fatalf("length %v: %v", n, err)
+		}
+		if typ != packetPublish || len(body) != n {
+			t.Errorf("length %v: got type %v, length %v", n, typ, len(body))
+		}
+	}
+}
+
+// broker runs a fake broker on conn that answers the handshake with
+// the given return code and sends the packets it receives to got.
+func broker(conn net.Conn, code byte, got chan<- []byte) {
+	r := bufio.NewReader(conn)
+	typ, body, err := readPacket(r)
+	if err != nil || typ != packetConnect {
+		conn.Close()
+		return
+	}
+	got <- body
+	conn.Write([]byte{packetConnAck << 4, 2, 0, code})
+	for {
+		typ, body, err := readPacket(r)
+		if err != nil {
+			close(got)
+			return
+		}
+		got <- append([]byte{typ}, body...)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	client, server := net.Pipe()
+	got := make(chan []byte, 4)
+	go broker(server, 0, got)
+
+	c, err := NewClient(client, Options{ClientID: "grumble", Username: "user", Password: "pw"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	connect := <-got
+	want := append(appendString(nil, "MQTT"), 4, 0xc2, 0, 0)
+	if !bytes.HasPrefix(connect, want) {
+		t.Errorf("unexpected CONNECT body %x", connect)
+	}
+
+	if err := c.Publish("grumble/1/events", []byte("hello"), false); err != nil {
+		t.Fatal(err)
+	}
+	publish := <-got
+	want = append([]byte{packetPublish}, appendString(nil, "grumble/1/events")...)
+	want = append(want, "hello"...)
+	if !bytes.Equal(publish, want) {
+		t.Errorf("unexpected PUBLISH %x, expected %x", publish, want)
+	}
+
+	c.Close()
+	if disconnect := <-got; disconnect[0] != packetDisconnect {
+		t.Errorf("expected DISCONNECT, got type %v", disconnect[0])
+	}
+	if err := c.Publish("x", nil, false); err != ErrClosed {
+		t.Errorf("publish after close returned %v", err)
+	}
+}
+
+func TestConnectRefused(t *testing.T) {
+	client, server := net.Pipe()
+	got := make(chan []byte, 4)
+	go broker(server, 5, got)
+
+	_, err := NewClient(client, Options{ClientID: "grumble"})
+	if err == nil {
+		t.Fatal("expected the connection to be refused")
+	}
+}
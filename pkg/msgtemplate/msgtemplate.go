@@ -0,0 +1,130 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package msgtemplate implements localized templates for messages
+// the server sends to clients.
+//
+// Templates are read from a file with one template per line:
+//
+//	# kind  locale  template
+//	kick    *       You were kicked by {actor}: {reason}
+//	kick    de      Du wurdest von {actor} gekickt: {reason}
+//	ban     *       You were banned by {actor}: {reason}
+//
+// The kind names the message the template is used for. The locale is
+// a language tag such as de or pt-BR, or * for the template used when
+// no other locale matches. The template is the rest of the line, in
+// which {name} is replaced by the value of the variable name.
+package msgtemplate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Fallback is the locale of the templates used when no other locale
+// matches.
+const Fallback = "*"
+
+// Templates holds message templates by kind and locale.
+type Templates struct {
+	templates map[string]map[string]string
+}
+
+// Load reads a templates file.
+func Load(fn string) (*Templates, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads templates from r.
+func Parse(r io.Reader) (*Templates, error) {
+	t := &Templates{templates: make(map[string]map[string]string)}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		kind, rest := nextField(text)
+		locale, rest := nextField(rest)
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("line %v: missing template", line)
+		}
+		kind = strings.ToLower(kind)
+		if t.templates[kind] == nil {
+			t.templates[kind] = make(map[string]string)
+		}
+		t.templates[kind][normalize(locale)] = rest
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// nextField splits the first whitespace-separated field off s.
+func nextField(s string) (field, rest string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// normalize brings a locale into the form used as a key, so that
+// pt_BR and pt-br are the same locale.
+func normalize(locale string) string {
+	return strings.ToLower(strings.Replace(locale, "_", "-", -1))
+}
+
+// Lookup finds the template of the given kind for locale. It tries
+// the locale itself, then its language alone (de for de-AT), and
+// then the fallback template.
+func (t *Templates) Lookup(kind, locale string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	byLocale, ok := t.templates[strings.ToLower(kind)]
+	if !ok {
+		return "", false
+	}
+	locale = normalize(locale)
+	candidates := []string{locale}
+	if i := strings.Index(locale, "-"); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, Fallback)
+	for _, candidate := range candidates {
+		if tmpl, ok := byLocale[candidate]; ok {
+			return tmpl, true
+		}
+	}
+	return "", false
+}
+
+// Render fills in the template of the given kind for locale with
+// vars. It returns false if there is no such template.
+func (t *Templates) Render(kind, locale string, vars map[string]string) (string, bool) {
+	tmpl, ok := t.Lookup(kind, locale)
+	if !ok {
+		return "", false
+	}
+	pairs := make([]string, 0, 2*len(vars))
+	for name, value := range vars {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(tmpl), true
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package msgtemplate
+
+import (
+	"strings"
+	"testing"
+)
+
+const testTemplates = `
+# kind  locale  template
+kick    *       You were kicked by {actor}: {reason}
+KICK    de      Du wurdest von {actor} gekickt: {reason}
+kick	pt_BR	Você foi expulso por {actor}: {reason}
+ban     de      Gebannt: {reason}
+`
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse(strings.NewReader(testTemplates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"actor": "alice", "reason": "spam"}
+	for _, tc := range []struct {
+		kind, locale, expected string
+	}{
+		{"kick", "en", "You were kicked by alice: spam"},
+		{"kick", "de", "Du wurdest von alice gekickt: spam"},
+		{"kick", "de-AT", "Du wurdest von alice gekickt: spam"},
+		{"kick", "pt-br", "Você foi expulso por alice: spam"},
+		{"ban", "de_DE", "Gebannt: spam"},
+	} {
+		got, ok := tmpl.Render(tc.kind, tc.locale, vars)
+		if !ok || got != tc.expected {
+			t.Errorf("Render(%q, %q) = %q, %v; expected %q", tc.kind, tc.locale, got, ok, tc.expected)
+		}
+	}
+	if _, ok := tmpl.Render("ban", "en", vars); ok {
+		t.Errorf("Expected no ban template for en")
+	}
+	var none *Templates
+	if _, ok := none.Render("kick", "en", vars); ok {
+		t.Errorf("Expected no template from nil Templates")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, bad := range []string{"kick", "kick de"} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
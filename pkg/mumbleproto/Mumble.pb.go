@@ -781,6 +781,8 @@ type ChannelState struct {
 	IsEnterRestricted *bool `protobuf:"varint,12,opt,name=is_enter_restricted,json=isEnterRestricted" json:"is_enter_restricted,omitempty"`
 	// Whether the receiver of this msg is considered to be able to enter this channel
 	CanEnter             *bool    `protobuf:"varint,13,opt,name=can_enter,json=canEnter" json:"can_enter,omitempty"`
+	NoVoice              *bool    `protobuf:"varint,100,opt,name=no_voice,json=noVoice" json:"no_voice,omitempty"`
+	Silent               *bool    `protobuf:"varint,101,opt,name=silent" json:"silent,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -905,6 +907,20 @@ func (m *ChannelState) GetCanEnter() bool {
 	return false
 }
 
+func (m *ChannelState) GetNoVoice() bool {
+	if m != nil && m.NoVoice != nil {
+		return *m.NoVoice
+	}
+	return false
+}
+
+func (m *ChannelState) GetSilent() bool {
+	if m != nil && m.Silent != nil {
+		return *m.Silent
+	}
+	return false
+}
+
 // Used to communicate user leaving or being kicked. May be sent by the client
 // when it attempts to kick a user. Sent by the server when it informs the
 // clients that a user is not present anymore.
@@ -919,6 +935,7 @@ type UserRemove struct {
 	Reason *string `protobuf:"bytes,3,opt,name=reason" json:"reason,omitempty"`
 	// True if the kick should result in a ban.
 	Ban                  *bool    `protobuf:"varint,4,opt,name=ban" json:"ban,omitempty"`
+	BanDuration          *uint32  `protobuf:"varint,100,opt,name=ban_duration,json=banDuration" json:"ban_duration,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -977,6 +994,13 @@ func (m *UserRemove) GetBan() bool {
 	return false
 }
 
+func (m *UserRemove) GetBanDuration() uint32 {
+	if m != nil && m.BanDuration != nil {
+		return *m.BanDuration
+	}
+	return 0
+}
+
 // Sent by the server when it communicates new and changed users to client.
 // First seen during login procedure. May be sent by the client when it wishes
 // to alter its state.
@@ -1028,6 +1052,7 @@ type UserState struct {
 	Recording *bool `protobuf:"varint,19,opt,name=recording" json:"recording,omitempty"`
 	// A list of temporary acces tokens to be respected when processing this request.
 	TemporaryAccessTokens []string `protobuf:"bytes,20,rep,name=temporary_access_tokens,json=temporaryAccessTokens" json:"temporary_access_tokens,omitempty"`
+	MuteDuration          *uint32  `protobuf:"varint,100,opt,name=mute_duration,json=muteDuration" json:"mute_duration,omitempty"`
 	XXX_NoUnkeyedLiteral  struct{} `json:"-"`
 	XXX_unrecognized      []byte   `json:"-"`
 	XXX_sizecache         int32    `json:"-"`
@@ -1198,6 +1223,13 @@ func (m *UserState) GetTemporaryAccessTokens() []string {
 	return nil
 }
 
+func (m *UserState) GetMuteDuration() uint32 {
+	if m != nil && m.MuteDuration != nil {
+		return *m.MuteDuration
+	}
+	return 0
+}
+
 // Relays information on the bans. The client may send the BanList message to
 // either modify the list of bans or query them from the server. The server
 // sends this list only after a client queries for it.
@@ -2441,6 +2473,11 @@ type UserStats struct {
 	// True if the user has a strong certificate.
 	StrongCertificate    *bool    `protobuf:"varint,18,opt,name=strong_certificate,json=strongCertificate,def=0" json:"strong_certificate,omitempty"`
 	Opus                 *bool    `protobuf:"varint,19,opt,name=opus,def=0" json:"opus,omitempty"`
+	VoiceGood            *uint32  `protobuf:"varint,100,opt,name=voice_good,json=voiceGood" json:"voice_good,omitempty"`
+	VoiceLate            *uint32  `protobuf:"varint,101,opt,name=voice_late,json=voiceLate" json:"voice_late,omitempty"`
+	VoiceLost            *uint32  `protobuf:"varint,102,opt,name=voice_lost,json=voiceLost" json:"voice_lost,omitempty"`
+	VoiceLostBursts      *uint32  `protobuf:"varint,103,opt,name=voice_lost_bursts,json=voiceLostBursts" json:"voice_lost_bursts,omitempty"`
+	VoiceJitter          *float32 `protobuf:"fixed32,104,opt,name=voice_jitter,json=voiceJitter" json:"voice_jitter,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -2608,6 +2645,41 @@ func (m *UserStats) GetOpus() bool {
 	return Default_UserStats_Opus
 }
 
+func (m *UserStats) GetVoiceGood() uint32 {
+	if m != nil && m.VoiceGood != nil {
+		return *m.VoiceGood
+	}
+	return 0
+}
+
+func (m *UserStats) GetVoiceLate() uint32 {
+	if m != nil && m.VoiceLate != nil {
+		return *m.VoiceLate
+	}
+	return 0
+}
+
+func (m *UserStats) GetVoiceLost() uint32 {
+	if m != nil && m.VoiceLost != nil {
+		return *m.VoiceLost
+	}
+	return 0
+}
+
+func (m *UserStats) GetVoiceLostBursts() uint32 {
+	if m != nil && m.VoiceLostBursts != nil {
+		return *m.VoiceLostBursts
+	}
+	return 0
+}
+
+func (m *UserStats) GetVoiceJitter() float32 {
+	if m != nil && m.VoiceJitter != nil {
+		return *m.VoiceJitter
+	}
+	return 0
+}
+
 type UserStats_Stats struct {
 	// The amount of good packets received.
 	Good *uint32 `protobuf:"varint,1,opt,name=good" json:"good,omitempty"`
@@ -2931,164 +3003,171 @@ func init() {
 func init() { proto.RegisterFile("Mumble.proto", fileDescriptor_56c09c2dce0fb003) }
 
 var fileDescriptor_56c09c2dce0fb003 = []byte{
-	// 2540 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x58, 0xcd, 0x72, 0x24, 0x47,
-	0x11, 0x76, 0xcf, 0xff, 0xe4, 0xcc, 0x48, 0xad, 0x5a, 0xd9, 0x1e, 0x64, 0xaf, 0x2d, 0xf7, 0x82,
-	0x2d, 0x83, 0x43, 0x18, 0x85, 0x83, 0x08, 0x3b, 0x82, 0x83, 0x56, 0x6b, 0xa3, 0x0d, 0xa4, 0xf5,
-	0xd2, 0x92, 0xd7, 0x07, 0x0e, 0x4d, 0xa9, 0xbb, 0x34, 0xd3, 0xa8, 0xa7, 0xab, 0xdd, 0x55, 0xad,
-	0xdd, 0x89, 0xe0, 0x08, 0x5c, 0xf1, 0x8d, 0x1b, 0x0f, 0xe0, 0x83, 0x23, 0x78, 0x05, 0x9e, 0x80,
-	0x03, 0x4f, 0x40, 0x70, 0xe3, 0x46, 0x04, 0x77, 0x22, 0xb3, 0xaa, 0xff, 0xb4, 0xb2, 0xd7, 0x5c,
-	0xb9, 0xcc, 0x74, 0x7e, 0x99, 0x55, 0x95, 0x95, 0x95, 0x7f, 0x55, 0x30, 0x3d, 0x2d, 0x56, 0x17,
-	0x89, 0xd8, 0xcf, 0x72, 0xa9, 0x25, 0x9b, 0xac, 0x88, 0x22, 0xc2, 0xfb, 0xa3, 0x03, 0xc3, 0x27,
-	0x22, 0x57, 0xb1, 0x4c, 0xd9, 0x5b, 0x30, 0x0d, 0xf3, 0x75, 0xa6, 0x65, 0xb0, 0x92, 0x91, 0x50,
-	0xf3, 0xfe, 0x6e, 0x77, 0x6f, 0xec, 0x4f, 0x0c, 0x76, 0x8a, 0x10, 0x9b, 0xc3, 0xf0, 0xda, 0x48,
-	0xcf, 0x9d, 0x5d, 0x67, 0x6f, 0xe6, 0x97, 0x24, 0x72, 0x72, 0x91, 0x08, 0xae, 0xc4, 0xbc, 0xb3,
-	0xeb, 0xec, 0x8d, 0xfd, 0x92, 0x64, 0x1b, 0xd0, 0x91, 0x6a, 0xde, 0x25, 0xb0, 0x23, 0x15, 0xbb,
-	0x0b, 0x20, 0x55, 0x50, 0x4e, 0xd3, 0x23, 0x7c, 0x2c, 0x95, 0xd5, 0xc2, 0xbb, 0x07, 0xe3, 0xcf,
-	0x1e, 0x3c, 0x3e, 0x2f, 0xd2, 0x54, 0x24, 0xec, 0x15, 0x18, 0x64, 0x3c, 0xbc, 0x12, 0x7a, 0xee,
-	0xec, 0x76, 0xf6, 0xa6, 0xbe, 0xa5, 0xbc, 0x3f, 0x3b, 0x30, 0x3d, 0x2c, 0xf4, 0x52, 0xa4, 0x3a,
-	0x0e, 0xb9, 0x16, 0x6c, 0x07, 0x46, 0x85, 0x12, 0x79, 0xca, 0x57, 0x82, 0x34, 0x1b, 0xfb, 0x15,
-	0x8d, 0xbc, 0x8c, 0x2b, 0xf5, 0x54, 0xe6, 0x91, 0xd5, 0xad, 0xa2, 0x71, 0x01, 0x2d, 0xaf, 0x44,
-	0x8a, 0x0a, 0xe2, 0x6e, 0x2d, 0xc5, 0xee, 0xc1, 0x2c, 0x14, 0x89, 0x2e, 0xd5, 0x54, 0xf3, 0xde,
-	0x6e, 0x77, 0xaf, 0xef, 0x4f, 0x11, 0xb4, 0x9a, 0x2a, 0xf6, 0x3d, 0xe8, 0xc9, 0xac, 0x40, 0x43,
-	0x39, 0x7b, 0xa3, 0x8f, 0xfa, 0x97, 0x3c, 0x51, 0xc2, 0x27, 0xc8, 0xfb, 0x6b, 0x07, 0x7a, 0x8f,
-	0xe3, 0x74, 0xc1, 0x5e, 0x87, 0xb1, 0x8e, 0x57, 0x42, 0x69, 0xbe, 0xca, 0x48, 0xb3, 0x9e, 0x5f,
-	0x03, 0x8c, 0x41, 0x6f, 0x21, 0xa5, 0x51, 0x6b, 0xe6, 0xd3, 0x37, 0x62, 0x09, 0xd7, 0x82, 0x2c,
-	0x36, 0xf3, 0xe9, 0x9b, 0x30, 0xa9, 0x34, 0x59, 0x0b, 0x31, 0xa9, 0x34, 0xaa, 0x9e, 0x0b, 0xb5,
-	0x4e, 0x43, 0x5a, 0x7f, 0xe6, 0x5b, 0x8a, 0xbd, 0x09, 0x93, 0x22, 0xca, 0x02, 0x63, 0x29, 0x35,
-	0x1f, 0x10, 0x13, 0x8a, 0x28, 0x7b, 0x6c, 0x10, 0x14, 0xd0, 0x61, 0x2d, 0x30, 0x34, 0x02, 0x3a,
-	0xac, 0x04, 0x76, 0x61, 0x4a, 0x33, 0xc4, 0xe9, 0x22, 0xe0, 0xd7, 0x8b, 0xf9, 0x68, 0xd7, 0xd9,
-	0xeb, 0x98, 0x29, 0xe2, 0x74, 0x71, 0x78, 0xbd, 0x68, 0x49, 0x5c, 0xf3, 0x7c, 0x3e, 0x6e, 0x49,
-	0x3c, 0xe1, 0x39, 0x4a, 0xd0, 0x22, 0xe5, 0x1c, 0x60, 0x24, 0x70, 0x95, 0x7a, 0x8e, 0x4a, 0x02,
-	0xe7, 0x98, 0xb4, 0x24, 0x9e, 0xf0, 0xdc, 0xfb, 0x7d, 0x07, 0x06, 0xbe, 0xf8, 0x8d, 0x08, 0x35,
-	0x3b, 0x80, 0x9e, 0x5e, 0x67, 0xe6, 0x6c, 0x37, 0x0e, 0xde, 0xd8, 0x6f, 0xf8, 0xf0, 0xbe, 0x11,
-	0xb1, 0x7f, 0xe7, 0xeb, 0x4c, 0xf8, 0x24, 0x6b, 0x0c, 0xc4, 0x95, 0x4c, 0xed, 0xa9, 0x5b, 0xca,
-	0xfb, 0xda, 0x01, 0xa8, 0x85, 0xd9, 0x08, 0x7a, 0x8f, 0x64, 0x2a, 0xdc, 0x97, 0x98, 0x0b, 0xd3,
-	0xcf, 0x73, 0x99, 0x2e, 0xec, 0x01, 0xbb, 0x0e, 0xbb, 0x03, 0x9b, 0x0f, 0xd3, 0x6b, 0x9e, 0xc4,
-	0xd1, 0x67, 0xd6, 0x9b, 0xdc, 0x0e, 0xdb, 0x84, 0x09, 0x89, 0x21, 0xf4, 0xf8, 0x73, 0xb7, 0xcb,
-	0xb6, 0x60, 0x46, 0xc0, 0x99, 0xc8, 0xaf, 0x09, 0xea, 0x21, 0x54, 0x8e, 0x78, 0x98, 0x7e, 0xa6,
-	0x84, 0xdb, 0x67, 0x1b, 0x00, 0x46, 0xe0, 0x93, 0x22, 0x49, 0xdc, 0x01, 0x8a, 0x3c, 0x92, 0x47,
-	0x22, 0xd7, 0xf1, 0x25, 0xf9, 0xb0, 0x3b, 0x64, 0x2f, 0xc3, 0x56, 0xc3, 0xab, 0x65, 0xfe, 0x09,
-	0x8f, 0x13, 0x77, 0xe4, 0x7d, 0xe9, 0x94, 0x43, 0xcf, 0xf0, 0x80, 0xe7, 0x30, 0x54, 0x42, 0x35,
-	0x83, 0xd0, 0x92, 0xe8, 0xb5, 0x2b, 0xfe, 0x2c, 0xb8, 0xe0, 0x69, 0xf4, 0x34, 0x8e, 0xf4, 0xd2,
-	0xfa, 0xd5, 0x74, 0xc5, 0x9f, 0xdd, 0x2f, 0x31, 0x0c, 0xf3, 0xa7, 0x22, 0x09, 0xe5, 0x4a, 0x04,
-	0x5a, 0x3c, 0xd3, 0x36, 0x32, 0x27, 0x16, 0x3b, 0x17, 0xcf, 0x34, 0xdb, 0x85, 0x49, 0x26, 0xf2,
-	0x55, 0xac, 0x4a, 0xdf, 0x47, 0xb7, 0x6d, 0x42, 0xde, 0x3e, 0xcc, 0x8e, 0x96, 0x1c, 0x63, 0xd4,
-	0x17, 0x2b, 0x79, 0x2d, 0x30, 0xaa, 0x43, 0x03, 0x04, 0x71, 0x44, 0xd1, 0x3a, 0xf3, 0xc7, 0x16,
-	0x79, 0x18, 0x79, 0x5f, 0x75, 0x61, 0x6a, 0x07, 0x9c, 0x69, 0xf4, 0xe8, 0x9b, 0xf2, 0x4e, 0x4b,
-	0xde, 0x04, 0x7e, 0x2e, 0x52, 0x6d, 0xb7, 0x60, 0x29, 0x0c, 0x04, 0x8a, 0x71, 0xa3, 0x34, 0x7d,
-	0xb3, 0x6d, 0xe8, 0x27, 0x71, 0x7a, 0x65, 0x62, 0x74, 0xe6, 0x1b, 0x02, 0xf7, 0x10, 0x09, 0x15,
-	0xe6, 0x71, 0xa6, 0xd1, 0x52, 0x7d, 0xb3, 0xcb, 0x06, 0xc4, 0x5e, 0x83, 0x31, 0x89, 0x06, 0x3c,
-	0x8a, 0xe6, 0x03, 0x1a, 0x3b, 0x22, 0xe0, 0x30, 0x8a, 0xd0, 0x4a, 0x86, 0x99, 0xd3, 0xfe, 0xe6,
-	0x43, 0xe2, 0x4f, 0x08, 0xb3, 0x5b, 0xbe, 0x07, 0x63, 0x2d, 0x56, 0x99, 0xcc, 0x79, 0xbe, 0xa6,
-	0x18, 0xa9, 0x72, 0x40, 0x8d, 0xb3, 0xbb, 0x30, 0xca, 0xa4, 0x8a, 0x49, 0x07, 0x8c, 0x92, 0xfe,
-	0x47, 0xce, 0xfb, 0x7e, 0x05, 0xb1, 0x77, 0xc1, 0x6d, 0xa8, 0x14, 0x2c, 0xb9, 0x5a, 0x52, 0xa8,
-	0x4c, 0xfd, 0xcd, 0x06, 0x7e, 0xcc, 0xd5, 0x12, 0xd5, 0xc5, 0xc3, 0xc5, 0xb4, 0xa6, 0x28, 0x58,
-	0x66, 0xfe, 0x68, 0xc5, 0x9f, 0xa1, 0x9b, 0x29, 0xb6, 0x0f, 0x77, 0x62, 0x15, 0x88, 0x54, 0x8b,
-	0x3c, 0xc8, 0x85, 0xd2, 0x79, 0x1c, 0x6a, 0x11, 0xcd, 0xa7, 0xa8, 0x95, 0xbf, 0x15, 0xab, 0x8f,
-	0x91, 0xe3, 0x57, 0x0c, 0x9c, 0x2c, 0xe4, 0xa9, 0x19, 0x30, 0x9f, 0x91, 0xd4, 0x28, 0xe4, 0x29,
-	0x89, 0x79, 0x97, 0x00, 0x38, 0xab, 0xdd, 0x66, 0xcb, 0xdd, 0x3a, 0x4d, 0x77, 0xdb, 0x86, 0x3e,
-	0x0f, 0xb5, 0xcc, 0xed, 0x19, 0x19, 0xa2, 0x11, 0x76, 0xdd, 0x66, 0xd8, 0x31, 0x17, 0xba, 0x17,
-	0xdc, 0x24, 0xfc, 0x91, 0x8f, 0x9f, 0xde, 0x3f, 0x7b, 0x30, 0xc6, 0x85, 0x8c, 0x47, 0x7c, 0xb3,
-	0x5b, 0xdf, 0xbe, 0xce, 0x6d, 0xae, 0xf0, 0x2a, 0x0c, 0xd1, 0x3e, 0xe8, 0x52, 0x26, 0x55, 0x0e,
-	0x90, 0x7c, 0x18, 0xdd, 0x70, 0xb7, 0xfe, 0x4d, 0x77, 0x63, 0xd0, 0x5b, 0x15, 0x5a, 0x50, 0xb2,
-	0x1c, 0xf9, 0xf4, 0x8d, 0x58, 0x24, 0xf8, 0x25, 0xe5, 0xc7, 0x91, 0x4f, 0xdf, 0x58, 0x4a, 0x54,
-	0x91, 0x65, 0xb9, 0x50, 0xca, 0x9c, 0xb8, 0x5f, 0xd1, 0x68, 0x52, 0x25, 0x92, 0xcb, 0x80, 0x26,
-	0x1a, 0x5b, 0xa6, 0x48, 0x2e, 0x4f, 0x71, 0xb2, 0x92, 0x49, 0x33, 0x42, 0xcd, 0x7c, 0x80, 0xb3,
-	0xce, 0x61, 0x88, 0x91, 0x58, 0xe4, 0x82, 0xce, 0x75, 0xea, 0x97, 0x24, 0xfb, 0x01, 0x6c, 0x64,
-	0x49, 0xb1, 0x88, 0xd3, 0x20, 0x94, 0x29, 0x45, 0xeb, 0x94, 0x04, 0x66, 0x06, 0x3d, 0x32, 0x20,
-	0x7b, 0x07, 0x36, 0xad, 0x58, 0x1c, 0x61, 0xf2, 0xd0, 0x6b, 0x3a, 0xd3, 0xb1, 0x6f, 0x47, 0x3f,
-	0xb4, 0x28, 0xae, 0x14, 0xca, 0xd5, 0x0a, 0xe3, 0x6a, 0xc3, 0x54, 0x69, 0x4b, 0xe2, 0x6e, 0xc9,
-	0xf9, 0x36, 0x8d, 0x35, 0xf1, 0x9b, 0x1a, 0x02, 0xc3, 0x36, 0x8e, 0xe9, 0xd2, 0xda, 0x13, 0x8b,
-	0x1d, 0x5b, 0x11, 0xab, 0xab, 0x11, 0xd9, 0x32, 0x22, 0x16, 0x23, 0x91, 0x77, 0xc1, 0xcd, 0xf2,
-	0x58, 0xe6, 0xb1, 0x5e, 0x07, 0x2a, 0x13, 0xfc, 0x4a, 0xe4, 0x73, 0x46, 0x16, 0xd8, 0x2c, 0xf1,
-	0x33, 0x03, 0x63, 0xb1, 0xcc, 0x45, 0x28, 0xf3, 0x28, 0x4e, 0x17, 0xf3, 0x3b, 0x24, 0x53, 0x03,
-	0xec, 0xa7, 0xf0, 0x6a, 0x15, 0x57, 0x01, 0x0f, 0x43, 0xa1, 0x54, 0x60, 0x8b, 0xf7, 0x36, 0x15,
-	0xef, 0x97, 0x2b, 0xf6, 0x21, 0x71, 0xcf, 0x89, 0xe9, 0xfd, 0xa1, 0x03, 0xc3, 0xfb, 0x3c, 0x3d,
-	0x89, 0x95, 0x66, 0x3f, 0x81, 0xde, 0x05, 0x4f, 0xd5, 0xdc, 0xd9, 0xed, 0xee, 0x4d, 0x0e, 0xee,
-	0xb6, 0xea, 0x88, 0x95, 0xc1, 0xff, 0x8f, 0x53, 0x9d, 0xaf, 0x7d, 0x12, 0x65, 0xaf, 0x41, 0xff,
-	0x8b, 0x42, 0xe4, 0x6b, 0xf2, 0xbe, 0x2a, 0xc4, 0x0d, 0xb6, 0xf3, 0x95, 0x03, 0xa3, 0x52, 0x1e,
-	0xad, 0xcb, 0xa3, 0x88, 0x9c, 0xc3, 0xb4, 0x2b, 0x25, 0x49, 0xfe, 0xc5, 0xd5, 0xd5, 0xbc, 0x43,
-	0x01, 0x44, 0xdf, 0xb7, 0xfa, 0x6f, 0x79, 0x0a, 0xbd, 0xc6, 0x29, 0xd4, 0xf1, 0xd4, 0x6f, 0xc5,
-	0xd3, 0x36, 0xf4, 0x95, 0xe6, 0xb9, 0x26, 0xa7, 0x1d, 0xfb, 0x86, 0x40, 0x0f, 0x8d, 0x8a, 0x9c,
-	0x53, 0xbe, 0x31, 0x95, 0xbd, 0xa2, 0xb1, 0xd9, 0x9b, 0x60, 0x7e, 0x3f, 0x15, 0x4a, 0xf1, 0x85,
-	0xa8, 0xe3, 0xca, 0x69, 0xc6, 0x55, 0x23, 0x0e, 0x3b, 0x94, 0xf4, 0xaa, 0x38, 0x6c, 0x07, 0x51,
-	0x97, 0x98, 0x8d, 0x20, 0x7a, 0x15, 0x86, 0x3a, 0x17, 0xc2, 0x04, 0x1f, 0xf2, 0x06, 0x48, 0x3e,
-	0x8c, 0x70, 0xc6, 0x95, 0x59, 0x72, 0xde, 0xdf, 0xed, 0xa0, 0xd7, 0x59, 0x12, 0xcb, 0x82, 0xfb,
-	0xb8, 0x2a, 0x2b, 0x0f, 0x44, 0x1a, 0x8b, 0x88, 0xbd, 0x01, 0x50, 0x97, 0x1a, 0xab, 0x5b, 0x03,
-	0xb9, 0xa1, 0x46, 0xe7, 0x66, 0x2c, 0x37, 0xf4, 0xef, 0xb6, 0xf3, 0x48, 0x6d, 0xc9, 0x5e, 0xcb,
-	0x92, 0x1f, 0xd9, 0xe6, 0xa2, 0x4f, 0xcd, 0xc5, 0xdb, 0x2d, 0xa7, 0xb8, 0xa9, 0xdd, 0xfe, 0x03,
-	0x91, 0xae, 0x1b, 0x4d, 0x46, 0x79, 0x8a, 0x83, 0xfa, 0x14, 0xbd, 0xbf, 0x3b, 0x30, 0x2a, 0xc5,
-	0xb0, 0xbd, 0x40, 0x9b, 0xbb, 0x2f, 0x61, 0x03, 0x50, 0xcf, 0xe6, 0x3a, 0x6c, 0x06, 0xe3, 0xb3,
-	0x22, 0x13, 0x39, 0xa6, 0x40, 0xd3, 0x56, 0xd8, 0x0a, 0xf9, 0x08, 0xfb, 0x8c, 0x2e, 0x02, 0x38,
-	0xf2, 0x5c, 0xca, 0x13, 0x99, 0x2e, 0xdc, 0x1e, 0x1b, 0x42, 0xf7, 0xf8, 0xc3, 0x5f, 0xb8, 0x7d,
-	0xb6, 0x0d, 0xee, 0x79, 0xe9, 0xea, 0x76, 0x8c, 0x3b, 0x60, 0xaf, 0x00, 0x3b, 0xc5, 0xc9, 0xd3,
-	0x45, 0xbb, 0xab, 0x98, 0xc2, 0x08, 0x97, 0xa0, 0x59, 0x47, 0x8d, 0x65, 0xa8, 0x0f, 0x19, 0x63,
-	0xd7, 0xf3, 0x48, 0x28, 0x1d, 0xa7, 0x8b, 0x93, 0x78, 0x15, 0x6b, 0x17, 0xb0, 0x0d, 0xb1, 0x22,
-	0x47, 0xb2, 0x48, 0xb5, 0x81, 0x27, 0xde, 0xef, 0xfa, 0xd0, 0x3d, 0x3c, 0x3a, 0x79, 0x41, 0xa9,
-	0x67, 0xef, 0xc0, 0x34, 0x4e, 0x97, 0x22, 0x8f, 0x75, 0xc0, 0xc3, 0x44, 0xd9, 0xb0, 0xe9, 0xe9,
-	0xbc, 0x10, 0xfe, 0xc4, 0x72, 0x0e, 0xc3, 0x44, 0xb1, 0x03, 0x18, 0x2c, 0x72, 0x59, 0x64, 0xa6,
-	0xf7, 0x9e, 0x1c, 0xec, 0xb4, 0x0c, 0x7f, 0x78, 0x74, 0xb2, 0x8f, 0x5a, 0xfc, 0x1c, 0x45, 0x7c,
-	0x2b, 0xc9, 0xde, 0x83, 0x1e, 0x4d, 0xda, 0xa3, 0x11, 0xf3, 0x5b, 0x47, 0x1c, 0x1e, 0x9d, 0xf8,
-	0x24, 0x55, 0x87, 0x6e, 0xff, 0x96, 0xd0, 0xfd, 0x87, 0x03, 0xe3, 0x6a, 0x81, 0xea, 0x1c, 0x1d,
-	0x72, 0x50, 0x13, 0x8d, 0x1e, 0x8c, 0xad, 0xbe, 0x22, 0x6a, 0x6d, 0xa3, 0x86, 0xd9, 0x1b, 0x30,
-	0xb4, 0x04, 0x79, 0x5b, 0x29, 0x51, 0x82, 0xec, 0x6d, 0x28, 0xf7, 0xcc, 0x2f, 0x12, 0x61, 0xaa,
-	0xdf, 0x0d, 0x63, 0x20, 0x03, 0xab, 0x23, 0xb6, 0x21, 0x7d, 0x0a, 0x1c, 0xfc, 0x34, 0xde, 0x4a,
-	0xbd, 0x87, 0xe9, 0x4d, 0x2c, 0xc5, 0x7e, 0x04, 0x5b, 0xd5, 0xf2, 0xc1, 0x4a, 0xac, 0x2e, 0xb0,
-	0x1f, 0x30, 0xed, 0x89, 0x5b, 0x31, 0x4e, 0x0d, 0xbe, 0xf3, 0x37, 0x07, 0x86, 0xd6, 0x26, 0xec,
-	0x1e, 0x00, 0xcf, 0xb2, 0x64, 0x1d, 0x2c, 0x45, 0x6e, 0x3a, 0xe9, 0x6a, 0x3f, 0x84, 0x1f, 0x8b,
-	0x5c, 0xd4, 0x42, 0xaa, 0xb8, 0x68, 0x9f, 0x9d, 0x11, 0x3a, 0x2b, 0x2e, 0x54, 0xdb, 0x30, 0xdd,
-	0xdb, 0x0d, 0xf3, 0x8d, 0xa5, 0x78, 0x1b, 0xfa, 0x74, 0x98, 0x36, 0x9d, 0x19, 0xc2, 0xa0, 0x3c,
-	0xd5, 0xf6, 0xbe, 0x62, 0x08, 0x53, 0x83, 0xd3, 0xb5, 0xcd, 0x64, 0xf4, 0xed, 0x7d, 0x00, 0xf0,
-	0x4b, 0x3c, 0x40, 0xd3, 0xf8, 0xb8, 0xd0, 0x8d, 0x23, 0x93, 0xcf, 0x67, 0x3e, 0x7e, 0xe2, 0x4c,
-	0x78, 0x7a, 0x8a, 0xb2, 0xd7, 0xd8, 0x37, 0x84, 0x17, 0x01, 0x1c, 0xe1, 0x45, 0xf6, 0x4c, 0xe8,
-	0x22, 0xc3, 0x51, 0x57, 0x62, 0x4d, 0x36, 0x98, 0xfa, 0xf8, 0x49, 0xb5, 0x2e, 0x89, 0xb1, 0xd4,
-	0xa5, 0x32, 0x0d, 0xcd, 0x25, 0x16, 0x6b, 0x1d, 0x61, 0x8f, 0x10, 0x42, 0x11, 0x45, 0x5d, 0xb8,
-	0x15, 0xe9, 0x1a, 0x11, 0x83, 0x91, 0x88, 0xf7, 0x1f, 0x07, 0xee, 0xd8, 0xa2, 0x7c, 0x18, 0x62,
-	0xce, 0x3d, 0x95, 0x51, 0x7c, 0xb9, 0xc6, 0xb3, 0xe4, 0x44, 0x5b, 0xff, 0xb2, 0x14, 0xee, 0x8f,
-	0xaa, 0xba, 0xb9, 0xa0, 0xd0, 0xb7, 0xa9, 0xd1, 0x69, 0xd5, 0x9a, 0xcf, 0xfc, 0x92, 0x64, 0xc7,
-	0x30, 0x96, 0x99, 0xb0, 0xc9, 0xbd, 0x47, 0xc9, 0xea, 0x87, 0xad, 0x08, 0xb8, 0x65, 0xe9, 0xfd,
-	0x4f, 0xcb, 0x11, 0x7e, 0x3d, 0xd8, 0x7b, 0x0f, 0x86, 0x65, 0xef, 0x00, 0x30, 0x30, 0x77, 0x0b,
-	0xd7, 0x61, 0x13, 0xe3, 0x2c, 0x98, 0x4e, 0x3a, 0x98, 0xb8, 0x28, 0x33, 0xf5, 0xbc, 0x5d, 0x18,
-	0x57, 0xb3, 0x60, 0x12, 0x3a, 0x8c, 0x22, 0xf7, 0x25, 0x1c, 0x68, 0x3a, 0x44, 0xd7, 0xf1, 0x7e,
-	0x0d, 0xb3, 0xd6, 0xda, 0xdf, 0xd2, 0xcc, 0xbd, 0x20, 0x7b, 0xd7, 0x96, 0xea, 0x36, 0x2d, 0xe5,
-	0xfd, 0xc5, 0x31, 0x59, 0x8c, 0xaa, 0xf8, 0xfb, 0xd0, 0x37, 0x6d, 0xb0, 0x73, 0x4b, 0xe2, 0x28,
-	0xa5, 0xe8, 0xc3, 0x37, 0x82, 0x3b, 0xca, 0x6c, 0xa6, 0xe9, 0x95, 0x26, 0x71, 0x95, 0x5e, 0x59,
-	0xc6, 0x7f, 0xa7, 0x51, 0x8d, 0xf1, 0x82, 0xc0, 0x95, 0x0e, 0x94, 0x10, 0x65, 0x33, 0x3b, 0x42,
-	0xe0, 0x4c, 0x08, 0x7a, 0x2d, 0x21, 0xa6, 0x55, 0xdd, 0x3a, 0xf9, 0x04, 0x31, 0x6b, 0x43, 0xef,
-	0xdf, 0x0e, 0x4c, 0x9e, 0xc8, 0x38, 0x14, 0xe7, 0x3c, 0x5f, 0x08, 0xcd, 0x36, 0xa0, 0x53, 0xdd,
-	0x75, 0x3a, 0x71, 0xc4, 0x3e, 0x84, 0xa1, 0x26, 0x8e, 0xf1, 0xd5, 0xc9, 0xc1, 0x9b, 0xad, 0x8d,
-	0x34, 0x86, 0xee, 0x9b, 0x3f, 0xbf, 0x94, 0xdf, 0xf9, 0x93, 0x03, 0x03, 0x3b, 0x6b, 0xcb, 0xd4,
-	0xdd, 0xff, 0xc1, 0xd4, 0x55, 0x20, 0x76, 0x9b, 0x81, 0xf8, 0x5a, 0x7d, 0x9b, 0x6a, 0xe6, 0x4c,
-	0x73, 0xa9, 0x7a, 0x0b, 0x46, 0xe1, 0x32, 0x4e, 0xa2, 0x5c, 0xa4, 0xed, 0x9c, 0x5a, 0xc1, 0x9e,
-	0x84, 0xcd, 0xba, 0xca, 0x51, 0xa0, 0xbe, 0xe8, 0xae, 0x77, 0xe3, 0xb6, 0x69, 0xf4, 0x6c, 0x42,
-	0xa8, 0xd3, 0x65, 0x52, 0xa8, 0xa5, 0xcd, 0x35, 0xa5, 0x4e, 0x84, 0x79, 0xbf, 0x85, 0xe9, 0x91,
-	0x8c, 0x44, 0x58, 0x3e, 0x63, 0x61, 0x57, 0x93, 0x64, 0x4b, 0x4e, 0x07, 0xdc, 0xf7, 0x0d, 0x81,
-	0xe7, 0x7b, 0x21, 0x34, 0xa7, 0x0e, 0xac, 0xef, 0xd3, 0x37, 0x56, 0xaa, 0x2c, 0x17, 0x97, 0x22,
-	0x0f, 0xcc, 0x00, 0xf4, 0xb8, 0x2a, 0x39, 0x1b, 0xce, 0x21, 0x0d, 0x2e, 0x1f, 0x7a, 0x7a, 0xcf,
-	0x3f, 0xf4, 0x7c, 0x3d, 0xa8, 0xef, 0x30, 0xea, 0x5b, 0xdc, 0xfe, 0xfb, 0x00, 0x0a, 0x45, 0x02,
-	0x99, 0x26, 0x37, 0x5a, 0xc9, 0x31, 0x31, 0x3e, 0x4d, 0x93, 0x35, 0xf3, 0x60, 0x1a, 0xd6, 0xb5,
-	0xdb, 0x14, 0xc6, 0xa9, 0xdf, 0xc2, 0xd8, 0xcf, 0x60, 0x72, 0x99, 0xcb, 0x55, 0x60, 0x52, 0x13,
-	0xe9, 0x34, 0x39, 0x78, 0xfd, 0xb9, 0x10, 0x20, 0x85, 0xf6, 0xe9, 0xd7, 0x07, 0x1c, 0x70, 0x44,
-	0xf2, 0xd5, 0x70, 0x93, 0xb6, 0xe8, 0x14, 0xbf, 0xd3, 0x70, 0x93, 0x24, 0xfe, 0x7f, 0x5e, 0x97,
-	0xd8, 0x7e, 0xfd, 0x96, 0x39, 0x25, 0x23, 0x6c, 0xb7, 0xa3, 0xcf, 0xf0, 0xea, 0x17, 0xce, 0xe7,
-	0x9e, 0x04, 0x67, 0xb7, 0x3c, 0x09, 0x36, 0xae, 0x00, 0x1b, 0xe6, 0x2a, 0x57, 0x5e, 0x01, 0x5e,
-	0x87, 0x71, 0xfd, 0x2e, 0xb3, 0x69, 0x62, 0xa0, 0x02, 0xb0, 0xe7, 0x95, 0x69, 0x12, 0xa7, 0x42,
-	0x89, 0x50, 0xd1, 0x45, 0x6b, 0xe6, 0x37, 0x10, 0x6c, 0xeb, 0xe3, 0x28, 0x31, 0xdc, 0x2d, 0xd3,
-	0xd6, 0x97, 0x34, 0xfb, 0x00, 0x98, 0xd2, 0xb9, 0x4c, 0x17, 0x41, 0xc3, 0x4f, 0xcc, 0x15, 0xab,
-	0x74, 0xb1, 0x2d, 0x23, 0xd0, 0xe8, 0x0b, 0x2b, 0x9f, 0xbe, 0xf3, 0x9c, 0x4f, 0xef, 0xfc, 0x0a,
-	0xfa, 0xc6, 0x9d, 0xcb, 0xe7, 0x49, 0xe7, 0x96, 0xe7, 0xc9, 0xce, 0x2d, 0xcf, 0x93, 0xdd, 0x5b,
-	0x9f, 0x27, 0x7b, 0xcd, 0xe7, 0x49, 0xef, 0x4b, 0x07, 0x26, 0xbe, 0xf8, 0xa2, 0x10, 0x4a, 0xdf,
-	0x4f, 0xe4, 0x05, 0xde, 0x5d, 0x6d, 0x8c, 0x04, 0xe5, 0x25, 0xd8, 0xa4, 0xb1, 0x0d, 0x0b, 0x9f,
-	0xdb, 0xbb, 0x70, 0x43, 0xb0, 0xbc, 0xc3, 0x76, 0x5a, 0x82, 0x47, 0xf6, 0x2a, 0xfb, 0x63, 0xb8,
-	0x53, 0xa6, 0x9b, 0xe6, 0x0b, 0x90, 0xb9, 0xaf, 0x30, 0xcb, 0x7a, 0x50, 0x73, 0xbc, 0x7f, 0x39,
-	0x30, 0x35, 0xee, 0x7d, 0x24, 0xd3, 0xcb, 0x78, 0xf1, 0xfc, 0x3b, 0x9a, 0xf3, 0x1d, 0xde, 0xd1,
-	0x3a, 0xcf, 0xbf, 0xa3, 0xdd, 0x05, 0xe0, 0x49, 0x22, 0x9f, 0x06, 0x4b, 0xbd, 0x4a, 0x4c, 0xf2,
-	0xf2, 0xc7, 0x84, 0x1c, 0xeb, 0x55, 0x82, 0xb7, 0x7b, 0x7b, 0x11, 0x0a, 0x12, 0x91, 0x2e, 0xf4,
-	0xd2, 0x9a, 0x6a, 0x66, 0xd1, 0x13, 0x02, 0xd9, 0xfb, 0xb0, 0x1d, 0xaf, 0x50, 0xe8, 0x86, 0xb0,
-	0x79, 0xc5, 0x60, 0xc4, 0x3b, 0x6d, 0x8d, 0x68, 0x3d, 0x15, 0x0d, 0xda, 0x4f, 0x45, 0xde, 0x15,
-	0xcc, 0xce, 0x8a, 0xc5, 0x42, 0x28, 0x6d, 0x77, 0xfb, 0xcd, 0x8f, 0xfa, 0x78, 0x13, 0xb3, 0x2f,
-	0x55, 0x3c, 0x31, 0x49, 0xcb, 0x6f, 0x20, 0x18, 0x64, 0x59, 0xa1, 0x96, 0x81, 0x96, 0x81, 0xe6,
-	0xc9, 0x95, 0xdd, 0x21, 0x20, 0x76, 0x2e, 0xcf, 0x79, 0x72, 0x75, 0xbf, 0x73, 0xec, 0xfc, 0x37,
-	0x00, 0x00, 0xff, 0xff, 0x44, 0xa7, 0x15, 0x87, 0x7f, 0x18, 0x00, 0x00,
+	// 2654 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x59, 0x4b, 0x73, 0x24, 0x47,
+	0x11, 0x76, 0xcf, 0x43, 0x33, 0x93, 0x33, 0x23, 0x8d, 0x6a, 0x65, 0xbb, 0x2d, 0x7b, 0x6d, 0xb9,
+	0x17, 0x6c, 0xd9, 0x38, 0x84, 0x51, 0x38, 0x88, 0xb0, 0x23, 0x38, 0x68, 0xb5, 0xb6, 0xb5, 0x20,
+	0xad, 0x97, 0x96, 0xbc, 0x3e, 0x70, 0x68, 0x4a, 0xdd, 0xa5, 0x99, 0xb6, 0x7a, 0xba, 0xda, 0x5d,
+	0xd5, 0xda, 0x9d, 0x08, 0x8e, 0x40, 0x84, 0x4f, 0x70, 0xe3, 0xc6, 0x0f, 0xf0, 0x81, 0x08, 0xfe,
+	0x02, 0xbf, 0x80, 0x03, 0x27, 0x22, 0xb8, 0x70, 0xe5, 0x46, 0x04, 0x77, 0x22, 0xb3, 0xaa, 0x5f,
+	0x92, 0xfc, 0xe0, 0xca, 0x45, 0x53, 0xf9, 0xe5, 0x57, 0xd5, 0xf5, 0xc8, 0xcc, 0xca, 0x4a, 0xc1,
+	0xe4, 0xa4, 0x58, 0x9e, 0x27, 0x62, 0x2f, 0xcb, 0xa5, 0x96, 0x6c, 0xbc, 0x24, 0x89, 0x04, 0xef,
+	0x77, 0x0e, 0x0c, 0x9e, 0x88, 0x5c, 0xc5, 0x32, 0x65, 0xaf, 0xc3, 0x24, 0xcc, 0x57, 0x99, 0x96,
+	0xc1, 0x52, 0x46, 0x42, 0xb9, 0xfd, 0x9d, 0xee, 0xee, 0xc8, 0x1f, 0x1b, 0xec, 0x04, 0x21, 0xe6,
+	0xc2, 0xe0, 0xca, 0xb0, 0x5d, 0x67, 0xc7, 0xd9, 0x9d, 0xfa, 0xa5, 0x88, 0x9a, 0x5c, 0x24, 0x82,
+	0x2b, 0xe1, 0x76, 0x76, 0x9c, 0xdd, 0x91, 0x5f, 0x8a, 0x6c, 0x1d, 0x3a, 0x52, 0xb9, 0x5d, 0x02,
+	0x3b, 0x52, 0xb1, 0xbb, 0x00, 0x52, 0x05, 0xe5, 0x30, 0x3d, 0xc2, 0x47, 0x52, 0xd9, 0x59, 0x78,
+	0xf7, 0x60, 0xf4, 0xe9, 0x83, 0xc7, 0x67, 0x45, 0x9a, 0x8a, 0x84, 0xbd, 0x00, 0x6b, 0x19, 0x0f,
+	0x2f, 0x85, 0x76, 0x9d, 0x9d, 0xce, 0xee, 0xc4, 0xb7, 0x92, 0xf7, 0x47, 0x07, 0x26, 0x07, 0x85,
+	0x5e, 0x88, 0x54, 0xc7, 0x21, 0xd7, 0x82, 0x6d, 0xc3, 0xb0, 0x50, 0x22, 0x4f, 0xf9, 0x52, 0xd0,
+	0xcc, 0x46, 0x7e, 0x25, 0xa3, 0x2e, 0xe3, 0x4a, 0x3d, 0x95, 0x79, 0x64, 0xe7, 0x56, 0xc9, 0xf8,
+	0x01, 0x2d, 0x2f, 0x45, 0x8a, 0x13, 0xc4, 0xd5, 0x5a, 0x89, 0xdd, 0x83, 0x69, 0x28, 0x12, 0x5d,
+	0x4e, 0x53, 0xb9, 0xbd, 0x9d, 0xee, 0x6e, 0xdf, 0x9f, 0x20, 0x68, 0x67, 0xaa, 0xd8, 0x4b, 0xd0,
+	0x93, 0x59, 0x81, 0x1b, 0xe5, 0xec, 0x0e, 0x3f, 0xe8, 0x5f, 0xf0, 0x44, 0x09, 0x9f, 0x20, 0xef,
+	0x2f, 0x1d, 0xe8, 0x3d, 0x8e, 0xd3, 0x39, 0x7b, 0x05, 0x46, 0x3a, 0x5e, 0x0a, 0xa5, 0xf9, 0x32,
+	0xa3, 0x99, 0xf5, 0xfc, 0x1a, 0x60, 0x0c, 0x7a, 0x73, 0x29, 0xcd, 0xb4, 0xa6, 0x3e, 0xb5, 0x11,
+	0x4b, 0xb8, 0x16, 0xb4, 0x63, 0x53, 0x9f, 0xda, 0x84, 0x49, 0xa5, 0xdd, 0x9e, 0xc5, 0xa4, 0xd2,
+	0x38, 0xf5, 0x5c, 0xa8, 0x55, 0x1a, 0xd2, 0xf7, 0xa7, 0xbe, 0x95, 0xd8, 0x6b, 0x30, 0x2e, 0xa2,
+	0x2c, 0x30, 0x3b, 0xa5, 0xdc, 0x35, 0x52, 0x42, 0x11, 0x65, 0x8f, 0x0d, 0x82, 0x04, 0x1d, 0xd6,
+	0x84, 0x81, 0x21, 0xe8, 0xb0, 0x22, 0xec, 0xc0, 0x84, 0x46, 0x88, 0xd3, 0x79, 0xc0, 0xaf, 0xe6,
+	0xee, 0x70, 0xc7, 0xd9, 0xed, 0x98, 0x21, 0xe2, 0x74, 0x7e, 0x70, 0x35, 0x6f, 0x31, 0xae, 0x78,
+	0xee, 0x8e, 0x5a, 0x8c, 0x27, 0x3c, 0x47, 0x86, 0x0e, 0x2d, 0x03, 0xc7, 0x00, 0xc3, 0xd0, 0x61,
+	0x73, 0x0c, 0x1d, 0x36, 0xc6, 0x18, 0xb7, 0x18, 0x4f, 0x78, 0xee, 0xfd, 0xa6, 0x03, 0x6b, 0xbe,
+	0xf8, 0x5c, 0x84, 0x9a, 0xed, 0x43, 0x4f, 0xaf, 0x32, 0x73, 0xb6, 0xeb, 0xfb, 0xaf, 0xee, 0x35,
+	0x6c, 0x78, 0xcf, 0x50, 0xec, 0xcf, 0xd9, 0x2a, 0x13, 0x3e, 0x71, 0xcd, 0x06, 0x71, 0x25, 0x53,
+	0x7b, 0xea, 0x56, 0xf2, 0xfe, 0xe4, 0x00, 0xd4, 0x64, 0x36, 0x84, 0xde, 0x23, 0x99, 0x8a, 0xd9,
+	0x73, 0x6c, 0x06, 0x93, 0xcf, 0x72, 0x99, 0xce, 0xed, 0x01, 0xcf, 0x1c, 0x76, 0x07, 0x36, 0x1e,
+	0xa6, 0x57, 0x3c, 0x89, 0xa3, 0x4f, 0xad, 0x35, 0xcd, 0x3a, 0x6c, 0x03, 0xc6, 0x44, 0x43, 0xe8,
+	0xf1, 0x67, 0xb3, 0x2e, 0xdb, 0x84, 0x29, 0x01, 0xa7, 0x22, 0xbf, 0x22, 0xa8, 0x87, 0x50, 0xd9,
+	0xe3, 0x61, 0xfa, 0xa9, 0x12, 0xb3, 0x3e, 0x5b, 0x07, 0x30, 0x84, 0x8f, 0x8a, 0x24, 0x99, 0xad,
+	0x21, 0xe5, 0x91, 0x3c, 0x14, 0xb9, 0x8e, 0x2f, 0xc8, 0x86, 0x67, 0x03, 0xf6, 0x3c, 0x6c, 0x36,
+	0xac, 0x5a, 0xe6, 0x1f, 0xf1, 0x38, 0x99, 0x0d, 0xbd, 0xdf, 0x3b, 0x65, 0xd7, 0x53, 0x3c, 0x60,
+	0x17, 0x06, 0x4a, 0xa8, 0xa6, 0x13, 0x5a, 0x11, 0xad, 0x76, 0xc9, 0x9f, 0x05, 0xe7, 0x3c, 0x8d,
+	0x9e, 0xc6, 0x91, 0x5e, 0x58, 0xbb, 0x9a, 0x2c, 0xf9, 0xb3, 0xfb, 0x25, 0x86, 0x6e, 0xfe, 0x54,
+	0x24, 0xa1, 0x5c, 0x8a, 0x40, 0x8b, 0x67, 0xda, 0x7a, 0xe6, 0xd8, 0x62, 0x67, 0xe2, 0x99, 0x66,
+	0x3b, 0x30, 0xce, 0x44, 0xbe, 0x8c, 0x55, 0x69, 0xfb, 0x68, 0xb6, 0x4d, 0xc8, 0xdb, 0x83, 0xe9,
+	0xe1, 0x82, 0xa3, 0x8f, 0xfa, 0x62, 0x29, 0xaf, 0x04, 0x7a, 0x75, 0x68, 0x80, 0x20, 0x8e, 0xc8,
+	0x5b, 0xa7, 0xfe, 0xc8, 0x22, 0x0f, 0x23, 0xef, 0x1f, 0x5d, 0x98, 0xd8, 0x0e, 0xa7, 0x9a, 0xeb,
+	0x9b, 0x7c, 0xa7, 0xc5, 0x37, 0x8e, 0x9f, 0x8b, 0x54, 0xdb, 0x25, 0x58, 0x09, 0x1d, 0x81, 0x7c,
+	0xdc, 0x4c, 0x9a, 0xda, 0x6c, 0x0b, 0xfa, 0x49, 0x9c, 0x5e, 0x1a, 0x1f, 0x9d, 0xfa, 0x46, 0xc0,
+	0x35, 0x44, 0x42, 0x85, 0x79, 0x9c, 0x69, 0xdc, 0xa9, 0xbe, 0x59, 0x65, 0x03, 0x62, 0x2f, 0xc3,
+	0x88, 0xa8, 0x01, 0x8f, 0x22, 0x77, 0x8d, 0xfa, 0x0e, 0x09, 0x38, 0x88, 0x22, 0xdc, 0x25, 0xa3,
+	0xcc, 0x69, 0x7d, 0xee, 0x80, 0xf4, 0x63, 0xc2, 0xec, 0x92, 0xef, 0xc1, 0x48, 0x8b, 0x65, 0x26,
+	0x73, 0x9e, 0xaf, 0xdc, 0x61, 0x33, 0x06, 0xd4, 0x38, 0xbb, 0x0b, 0xc3, 0x4c, 0xaa, 0x98, 0xe6,
+	0x80, 0x5e, 0xd2, 0xff, 0xc0, 0x79, 0xd7, 0xaf, 0x20, 0xf6, 0x16, 0xcc, 0x1a, 0x53, 0x0a, 0x16,
+	0x5c, 0x2d, 0xc8, 0x55, 0x26, 0xfe, 0x46, 0x03, 0x3f, 0xe2, 0x6a, 0x81, 0xd3, 0xc5, 0xc3, 0xc5,
+	0xb0, 0xa6, 0xc8, 0x59, 0xa6, 0xfe, 0x70, 0xc9, 0x9f, 0xa1, 0x99, 0x29, 0xb6, 0x07, 0x77, 0x62,
+	0x15, 0x88, 0x54, 0x8b, 0x3c, 0xc8, 0x85, 0xd2, 0x79, 0x1c, 0x6a, 0x11, 0xb9, 0x13, 0x9c, 0x95,
+	0xbf, 0x19, 0xab, 0x0f, 0x51, 0xe3, 0x57, 0x0a, 0x1c, 0x2c, 0xe4, 0xa9, 0xe9, 0xe0, 0x4e, 0x89,
+	0x35, 0x0c, 0x79, 0x4a, 0x34, 0xf6, 0x12, 0x0c, 0x53, 0x19, 0x5c, 0xc9, 0x38, 0x14, 0x6e, 0x44,
+	0xba, 0x41, 0x2a, 0x9f, 0xa0, 0x88, 0xe7, 0xa2, 0xe2, 0x04, 0xcf, 0x45, 0x90, 0xc2, 0x4a, 0xde,
+	0x97, 0x0e, 0x00, 0xce, 0xc4, 0x6e, 0x4d, 0xcb, 0x44, 0x3b, 0x4d, 0x13, 0xdd, 0x82, 0x3e, 0x0f,
+	0xb5, 0xcc, 0xed, 0xb9, 0x1a, 0xa1, 0xe1, 0xaa, 0xdd, 0xa6, 0xab, 0xb2, 0x19, 0x74, 0xcf, 0xb9,
+	0xb9, 0x24, 0x86, 0x3e, 0x36, 0xf1, 0x5c, 0xce, 0x79, 0x1a, 0x44, 0x45, 0xce, 0x69, 0x4f, 0x23,
+	0x1a, 0x66, 0x7c, 0xce, 0xd3, 0x07, 0x16, 0xf2, 0xbe, 0xec, 0xc3, 0x08, 0xe7, 0x62, 0x0c, 0xed,
+	0xeb, 0xbd, 0xe5, 0xf6, 0xa9, 0xdc, 0x66, 0x61, 0x2f, 0xc2, 0x00, 0xb7, 0x1d, 0x2d, 0xd5, 0x44,
+	0xe0, 0x35, 0x14, 0x1f, 0x46, 0xd7, 0xac, 0xb8, 0x7f, 0xdd, 0x8a, 0x19, 0xf4, 0x96, 0x85, 0x16,
+	0x14, 0x83, 0x87, 0x3e, 0xb5, 0x11, 0x8b, 0x04, 0xbf, 0xa0, 0xb0, 0x3b, 0xf4, 0xa9, 0x8d, 0x37,
+	0x94, 0x2a, 0xb2, 0x2c, 0x17, 0x4a, 0x19, 0x43, 0xf2, 0x2b, 0x19, 0x4f, 0x4a, 0x89, 0xe4, 0x22,
+	0xa0, 0x81, 0x46, 0x56, 0x29, 0x92, 0x8b, 0x13, 0x1c, 0xac, 0x54, 0xd2, 0x88, 0x50, 0x2b, 0x1f,
+	0xe0, 0xa8, 0x2e, 0x0c, 0xd0, 0xc1, 0x8b, 0x5c, 0x90, 0xb9, 0x4c, 0xfc, 0x52, 0x64, 0xdf, 0x87,
+	0xf5, 0x2c, 0x29, 0xe6, 0x71, 0x1a, 0x84, 0x32, 0x45, 0x90, 0x0c, 0x65, 0xe2, 0x4f, 0x0d, 0x7a,
+	0x68, 0x40, 0xf6, 0x26, 0x6c, 0x58, 0x5a, 0x1c, 0x61, 0x4c, 0xd2, 0x2b, 0x32, 0x95, 0x91, 0x6f,
+	0x7b, 0x3f, 0xb4, 0x28, 0x7e, 0x29, 0x94, 0xcb, 0x25, 0x9a, 0xc5, 0xba, 0xb9, 0xfc, 0xad, 0x88,
+	0xab, 0x25, 0x9b, 0xde, 0x30, 0xbb, 0x89, 0x6d, 0xca, 0x33, 0x8c, 0xda, 0xd8, 0xfb, 0x8c, 0xbe,
+	0x3d, 0xb6, 0xd8, 0x91, 0xa5, 0xd8, 0xb9, 0x1a, 0xca, 0xa6, 0xa1, 0x58, 0x8c, 0x28, 0x6f, 0xc1,
+	0x2c, 0xcb, 0x63, 0x99, 0xc7, 0x7a, 0x15, 0xa8, 0x4c, 0xf0, 0x4b, 0x91, 0xbb, 0x8c, 0x76, 0x60,
+	0xa3, 0xc4, 0x4f, 0x0d, 0x8c, 0x77, 0x70, 0x2e, 0x42, 0x99, 0x47, 0x71, 0x3a, 0x77, 0xef, 0x10,
+	0xa7, 0x06, 0xd8, 0x8f, 0xe1, 0xc5, 0xca, 0x5d, 0x03, 0x1e, 0x86, 0x42, 0xa9, 0xc0, 0xe6, 0x04,
+	0x5b, 0x94, 0x13, 0x3c, 0x5f, 0xa9, 0x0f, 0x48, 0x7b, 0x56, 0xa5, 0x08, 0x78, 0x26, 0xd7, 0x4d,
+	0x71, 0x82, 0x60, 0x65, 0x8b, 0xbf, 0xed, 0xc0, 0xe0, 0x3e, 0x4f, 0x8f, 0x63, 0xa5, 0xd9, 0x8f,
+	0xa0, 0x77, 0xce, 0x53, 0xe5, 0x3a, 0x3b, 0xdd, 0xdd, 0xf1, 0xfe, 0xdd, 0xd6, 0x1d, 0x66, 0x39,
+	0xf8, 0xfb, 0x61, 0xaa, 0xf3, 0x95, 0x4f, 0x54, 0xf6, 0x32, 0xf4, 0xbf, 0x28, 0x44, 0xbe, 0x72,
+	0x3b, 0xcd, 0xf0, 0x62, 0xb0, 0xed, 0xaf, 0x1c, 0x18, 0x96, 0x7c, 0x3c, 0x02, 0x1e, 0x45, 0x64,
+	0x41, 0x26, 0x55, 0x2a, 0x45, 0x32, 0x42, 0xae, 0x2e, 0xdd, 0x0e, 0x39, 0x22, 0xb5, 0x6f, 0x35,
+	0xf2, 0xf2, 0xa8, 0x7a, 0x8d, 0xa3, 0xaa, 0xfd, 0xb2, 0xdf, 0xf2, 0xcb, 0x2d, 0xe8, 0x2b, 0xcd,
+	0x73, 0x4d, 0x96, 0x3d, 0xf2, 0x8d, 0x80, 0x66, 0x5c, 0x6d, 0x86, 0xc9, 0x2a, 0x2a, 0x19, 0x13,
+	0xcd, 0x31, 0xde, 0x2d, 0x27, 0x42, 0x29, 0x3e, 0x17, 0xb5, 0xf3, 0x39, 0x4d, 0xe7, 0x6b, 0x38,
+	0x6b, 0x87, 0x02, 0x6e, 0x29, 0x5e, 0xf3, 0xb4, 0xee, 0x4e, 0xb7, 0xed, 0x69, 0x2f, 0xc2, 0x40,
+	0xe7, 0x42, 0x18, 0x0f, 0x45, 0xdd, 0x1a, 0x8a, 0x0f, 0x23, 0x1c, 0x71, 0x69, 0x3e, 0xe9, 0xf6,
+	0x77, 0x3a, 0x68, 0x9a, 0x56, 0xf4, 0xbe, 0xea, 0xc2, 0xec, 0x71, 0x75, 0xa5, 0x3d, 0x10, 0x69,
+	0x2c, 0x22, 0xf6, 0x2a, 0x40, 0x7d, 0xcd, 0xd9, 0xb9, 0x35, 0x90, 0x6b, 0xd3, 0xe8, 0x5c, 0x77,
+	0xf8, 0xc6, 0xfc, 0xbb, 0xed, 0x60, 0x53, 0xef, 0x64, 0xaf, 0xb5, 0x93, 0x1f, 0xd8, 0xc4, 0xa6,
+	0x4f, 0x89, 0xcd, 0x1b, 0x2d, 0xa3, 0xb8, 0x3e, 0xbb, 0xbd, 0x07, 0x22, 0x5d, 0x35, 0x12, 0x9c,
+	0xf2, 0x14, 0xd7, 0xea, 0x53, 0xf4, 0xfe, 0xe6, 0xc0, 0xb0, 0xa4, 0x61, 0x6a, 0x83, 0x7b, 0x3e,
+	0x7b, 0x0e, 0x93, 0x8f, 0x7a, 0xb4, 0x99, 0xc3, 0xa6, 0x30, 0x3a, 0x2d, 0x32, 0x91, 0x63, 0x9c,
+	0x34, 0x29, 0x8d, 0xbd, 0x9d, 0x1f, 0x61, 0x8e, 0xd3, 0x45, 0x00, 0x7b, 0x9e, 0x49, 0x79, 0x2c,
+	0xd3, 0xf9, 0xac, 0xc7, 0x06, 0xd0, 0x3d, 0x7a, 0xff, 0x67, 0xb3, 0x3e, 0xdb, 0x82, 0xd9, 0x59,
+	0xe9, 0x0f, 0xb6, 0xcf, 0x6c, 0x8d, 0xbd, 0x00, 0xec, 0x04, 0x07, 0x4f, 0xe7, 0xed, 0x8c, 0x66,
+	0x02, 0x43, 0xfc, 0x04, 0x8d, 0x3a, 0x6c, 0x7c, 0x86, 0x72, 0xa0, 0x11, 0x66, 0x5c, 0x8f, 0x84,
+	0xd2, 0x71, 0x3a, 0x3f, 0x8e, 0x97, 0xb1, 0x9e, 0x01, 0xa6, 0x40, 0x96, 0x72, 0x28, 0x8b, 0x54,
+	0x1b, 0x78, 0xec, 0xfd, 0xba, 0x0f, 0xdd, 0x83, 0xc3, 0xe3, 0x6f, 0x49, 0x33, 0xd8, 0x9b, 0x30,
+	0x89, 0xd3, 0x85, 0xc8, 0x63, 0x1d, 0xf0, 0x30, 0x51, 0xd6, 0x6d, 0x7a, 0x3a, 0x2f, 0x84, 0x3f,
+	0xb6, 0x9a, 0x83, 0x30, 0x51, 0x6c, 0x1f, 0xd6, 0xe6, 0xb9, 0x2c, 0x32, 0x93, 0xf7, 0x8f, 0xf7,
+	0xb7, 0x5b, 0x1b, 0x7f, 0x70, 0x78, 0xbc, 0x87, 0xb3, 0xf8, 0x18, 0x29, 0xbe, 0x65, 0xb2, 0x77,
+	0xa0, 0x47, 0x83, 0xf6, 0xa8, 0x87, 0x7b, 0x6b, 0x8f, 0x83, 0xc3, 0x63, 0x9f, 0x58, 0xb5, 0xeb,
+	0xf6, 0x6f, 0x71, 0xdd, 0x7f, 0x3a, 0x30, 0xaa, 0x3e, 0x50, 0x9d, 0xa3, 0x43, 0x06, 0x4a, 0x6d,
+	0xe6, 0xc1, 0xc8, 0xce, 0x57, 0x44, 0xad, 0x65, 0xd4, 0x30, 0x7b, 0x15, 0x06, 0x56, 0x70, 0xbb,
+	0x0d, 0x46, 0x09, 0xb2, 0x37, 0xa0, 0x5c, 0x33, 0x3f, 0x4f, 0x84, 0xdb, 0x6b, 0x70, 0x9a, 0x0a,
+	0xbc, 0x65, 0x31, 0x05, 0xea, 0x93, 0xe3, 0x60, 0xd3, 0x58, 0x2b, 0xe5, 0x3d, 0x26, 0x2f, 0xb2,
+	0x12, 0xfb, 0x01, 0x6c, 0x56, 0x9f, 0x0f, 0x96, 0x62, 0x79, 0x8e, 0xb9, 0x88, 0x49, 0x8d, 0x66,
+	0x95, 0xe2, 0xc4, 0xe0, 0xdb, 0x7f, 0x75, 0x60, 0x60, 0xf7, 0x84, 0xdd, 0x03, 0xe0, 0x59, 0x96,
+	0xac, 0x82, 0x85, 0xc8, 0x4d, 0x16, 0x5f, 0xad, 0x87, 0xf0, 0x23, 0x91, 0x8b, 0x9a, 0xa4, 0x8a,
+	0xf3, 0xf6, 0xd9, 0x19, 0xd2, 0x69, 0x71, 0xae, 0xda, 0x1b, 0xd3, 0xbd, 0x7d, 0x63, 0xbe, 0xf6,
+	0xbe, 0xde, 0x82, 0x3e, 0x1d, 0xa6, 0x0d, 0x67, 0x46, 0x30, 0x28, 0x4f, 0xb5, 0x7d, 0x2b, 0x19,
+	0xc1, 0x5c, 0xd4, 0xe9, 0xca, 0x46, 0x32, 0x6a, 0x7b, 0xef, 0x01, 0xfc, 0x1c, 0x0f, 0xd0, 0x24,
+	0x5d, 0x33, 0xe8, 0xc6, 0x91, 0x89, 0xe7, 0x53, 0x1f, 0x9b, 0x38, 0x12, 0x9e, 0x9e, 0xa2, 0xe8,
+	0x35, 0xf2, 0x8d, 0xe0, 0x45, 0x00, 0x87, 0xf8, 0x88, 0x3e, 0x15, 0xba, 0xc8, 0xb0, 0xd7, 0xa5,
+	0x58, 0xd1, 0x1e, 0x4c, 0x7c, 0x6c, 0xd2, 0x85, 0x98, 0xc4, 0x78, 0x1f, 0xa6, 0x32, 0x0d, 0xcd,
+	0x03, 0x1a, 0x2f, 0x44, 0xc2, 0x1e, 0x21, 0x84, 0x14, 0x45, 0x2f, 0x00, 0x4b, 0xe9, 0x1a, 0x8a,
+	0xc1, 0x88, 0xe2, 0xfd, 0xc7, 0x81, 0x3b, 0xf6, 0xe6, 0x3e, 0x08, 0x31, 0xe6, 0x9e, 0xc8, 0x28,
+	0xbe, 0x58, 0xe1, 0x59, 0x72, 0x92, 0xad, 0x7d, 0x59, 0x09, 0xd7, 0x87, 0x5c, 0xfb, 0x38, 0xa2,
+	0xb6, 0xb9, 0xc8, 0xd3, 0xea, 0x59, 0x30, 0xf5, 0x4b, 0x91, 0x1d, 0xc1, 0x48, 0x66, 0xc2, 0x06,
+	0xf7, 0x1e, 0x05, 0xab, 0xb7, 0x5b, 0x1e, 0x70, 0xcb, 0xa7, 0xf7, 0x3e, 0x29, 0x7b, 0xf8, 0x75,
+	0x67, 0xef, 0x1d, 0x18, 0x58, 0x2e, 0x03, 0x58, 0x33, 0xef, 0x9a, 0x99, 0xc3, 0xc6, 0x30, 0x28,
+	0xc3, 0x49, 0x07, 0x03, 0x17, 0x45, 0xa6, 0x9e, 0xb7, 0x03, 0xa3, 0x6a, 0x14, 0x0c, 0x42, 0x07,
+	0x51, 0x34, 0x7b, 0x0e, 0x3b, 0x9a, 0x4c, 0x73, 0xe6, 0x78, 0xbf, 0x84, 0x69, 0xeb, 0xdb, 0xdf,
+	0x90, 0xf1, 0x7d, 0x4b, 0xf4, 0xae, 0x77, 0xaa, 0xdb, 0xdc, 0x29, 0xef, 0xcf, 0x8e, 0x89, 0x62,
+	0x74, 0x8b, 0xbf, 0x0b, 0x7d, 0x93, 0x82, 0x3b, 0xb7, 0x04, 0x8e, 0x92, 0x45, 0x0d, 0xdf, 0x10,
+	0xb7, 0x95, 0x59, 0x4c, 0xd3, 0x2a, 0x4d, 0xe0, 0x2a, 0xad, 0xb2, 0xf4, 0xff, 0x4e, 0xe3, 0x36,
+	0xc6, 0xc7, 0x09, 0x57, 0x3a, 0x50, 0x42, 0x94, 0x49, 0xf1, 0x10, 0x81, 0x53, 0x21, 0x28, 0x09,
+	0x26, 0xa5, 0x9d, 0xba, 0x35, 0xf2, 0x31, 0x62, 0x76, 0x0f, 0xbd, 0x7f, 0x3b, 0x30, 0xa6, 0x94,
+	0xfd, 0x8c, 0xe7, 0x73, 0xa1, 0xb1, 0x0a, 0x53, 0xbd, 0xb3, 0x3a, 0x71, 0xc4, 0xde, 0x87, 0x81,
+	0x26, 0x8d, 0xb1, 0xd5, 0xf1, 0xfe, 0x6b, 0xad, 0x85, 0x34, 0xba, 0xee, 0x99, 0x1f, 0xbf, 0xe4,
+	0x6f, 0xff, 0xc1, 0x81, 0x35, 0x3b, 0x6a, 0x6b, 0xab, 0xbb, 0xff, 0xc3, 0x56, 0x57, 0x8e, 0xd8,
+	0x6d, 0x3a, 0xe2, 0xcb, 0xf5, 0x4b, 0xae, 0x19, 0x33, 0x09, 0x63, 0xaf, 0xc3, 0x30, 0x5c, 0xc4,
+	0x49, 0x94, 0x8b, 0xb4, 0x1d, 0x53, 0x2b, 0xd8, 0x93, 0xb0, 0x51, 0xdf, 0x72, 0xe4, 0xa8, 0xdf,
+	0xf6, 0xce, 0xbc, 0xf6, 0xd2, 0x35, 0xf3, 0x6c, 0x42, 0x38, 0xa7, 0x8b, 0xa4, 0x50, 0x0b, 0xb7,
+	0xdb, 0xfc, 0xa6, 0xc1, 0xbc, 0x5f, 0xc1, 0xe4, 0x50, 0x46, 0x22, 0x2c, 0x4b, 0x68, 0x98, 0xd5,
+	0x24, 0xd9, 0x82, 0xd3, 0x01, 0xf7, 0x7d, 0x23, 0xe0, 0xf9, 0x9e, 0x0b, 0xcd, 0x29, 0x03, 0xeb,
+	0xfb, 0xd4, 0xc6, 0x9b, 0x2a, 0xcb, 0xc5, 0x85, 0xc8, 0x03, 0xd3, 0x01, 0x2d, 0xae, 0x0a, 0xce,
+	0x46, 0x73, 0x40, 0x9d, 0xcb, 0x22, 0x53, 0xef, 0x66, 0x91, 0xe9, 0xef, 0x83, 0xfa, 0xa1, 0xa3,
+	0xbe, 0xc1, 0xec, 0xbf, 0x07, 0xa0, 0x90, 0x12, 0xc8, 0x34, 0xb9, 0x96, 0x4a, 0x8e, 0x48, 0xf1,
+	0x49, 0x9a, 0xac, 0x98, 0x07, 0x93, 0xb0, 0xbe, 0xbb, 0xcd, 0xc5, 0x38, 0xf1, 0x5b, 0x18, 0xfb,
+	0x09, 0x8c, 0x2f, 0x72, 0xb9, 0x0c, 0x4c, 0x68, 0xa2, 0x39, 0x8d, 0xf7, 0x5f, 0xb9, 0xe1, 0x02,
+	0x34, 0xa1, 0x3d, 0xfa, 0xeb, 0x03, 0x76, 0x38, 0x24, 0x7e, 0xd5, 0xdd, 0x84, 0x2d, 0xb7, 0xff,
+	0x5d, 0xbb, 0x9b, 0x20, 0xf1, 0xff, 0x53, 0xd9, 0x62, 0x7b, 0x75, 0x1d, 0x75, 0x42, 0x9b, 0xb0,
+	0xd5, 0xf6, 0x3e, 0xa3, 0xab, 0xab, 0xab, 0x37, 0xca, 0x91, 0xd3, 0x5b, 0xca, 0x91, 0x8d, 0x27,
+	0xc0, 0xba, 0x79, 0xef, 0x59, 0x11, 0x1f, 0x40, 0x75, 0x4d, 0x68, 0xc3, 0xf8, 0x40, 0x05, 0x60,
+	0xce, 0x2b, 0xd3, 0x24, 0x4e, 0x85, 0x12, 0xa1, 0xa2, 0xd7, 0xd8, 0xd4, 0x6f, 0x20, 0x98, 0xd6,
+	0xc7, 0x51, 0x62, 0xb4, 0x9b, 0xa4, 0xad, 0x64, 0xf6, 0x1e, 0x30, 0xa5, 0xb1, 0xf6, 0x15, 0x34,
+	0xec, 0xc4, 0x65, 0x4d, 0x13, 0xdb, 0x34, 0x84, 0x46, 0x5e, 0x58, 0xd9, 0xf4, 0x9d, 0x1b, 0x36,
+	0x8d, 0xfe, 0x4a, 0x85, 0x87, 0x80, 0xea, 0xa2, 0xe6, 0x49, 0x35, 0x22, 0xe4, 0x63, 0x2c, 0x8e,
+	0x56, 0x6a, 0x2a, 0x91, 0x8a, 0x86, 0xfa, 0xd8, 0x56, 0x95, 0xac, 0x5a, 0x2a, 0xed, 0x5e, 0x34,
+	0xd5, 0x52, 0x69, 0xf6, 0x36, 0x6c, 0xd6, 0xea, 0xe0, 0xbc, 0xc8, 0x95, 0x56, 0xee, 0x9c, 0x58,
+	0x1b, 0x15, 0xeb, 0x3e, 0xc1, 0x18, 0x63, 0x0d, 0xf7, 0xf3, 0x58, 0x6b, 0x91, 0xbb, 0x0b, 0x3a,
+	0xc4, 0x31, 0x61, 0x3f, 0x25, 0x68, 0xfb, 0x17, 0xd0, 0x37, 0xae, 0x57, 0x96, 0x71, 0x9d, 0x5b,
+	0xca, 0xb8, 0x9d, 0x5b, 0xca, 0xb8, 0xdd, 0x5b, 0xcb, 0xb8, 0xbd, 0x66, 0x19, 0x17, 0x8b, 0x7e,
+	0x63, 0x5f, 0x7c, 0x51, 0x08, 0xa5, 0xef, 0x27, 0xf2, 0x1c, 0x1f, 0xe3, 0xd6, 0x9f, 0x83, 0xf2,
+	0x55, 0x6f, 0x42, 0xee, 0xba, 0x85, 0xcf, 0x0c, 0xda, 0x24, 0x96, 0x8f, 0xf2, 0x4e, 0x8b, 0x78,
+	0x68, 0x50, 0xf6, 0x43, 0xb8, 0x53, 0x86, 0xc6, 0x66, 0xa5, 0xcc, 0xbc, 0xad, 0x98, 0x55, 0x3d,
+	0xa8, 0x35, 0xde, 0xbf, 0x1c, 0x98, 0x18, 0x57, 0x3c, 0x94, 0xe9, 0x45, 0x3c, 0xbf, 0x59, 0x6f,
+	0x74, 0xbe, 0x43, 0xbd, 0xb1, 0x73, 0xb3, 0xde, 0x78, 0x17, 0x80, 0x27, 0x89, 0x7c, 0x1a, 0x2c,
+	0xf4, 0x32, 0x31, 0x81, 0xd6, 0x1f, 0x11, 0x72, 0xa4, 0x97, 0x09, 0x96, 0x2b, 0xec, 0xa3, 0x2d,
+	0x48, 0x44, 0x3a, 0xd7, 0x0b, 0xbb, 0x55, 0x53, 0x8b, 0x1e, 0x13, 0xc8, 0xde, 0x85, 0xad, 0x78,
+	0x89, 0xa4, 0x6b, 0x64, 0x53, 0x96, 0x61, 0xa4, 0x3b, 0x69, 0xf5, 0x68, 0x95, 0xd4, 0xd6, 0xda,
+	0x25, 0x35, 0xef, 0x12, 0xa6, 0xa7, 0xc5, 0x7c, 0x2e, 0x94, 0xb6, 0xab, 0xfd, 0xfa, 0x7f, 0x7e,
+	0xe0, 0xab, 0xd1, 0x56, 0xf4, 0x78, 0x62, 0x02, 0xac, 0xdf, 0x40, 0x30, 0x20, 0x64, 0x85, 0x5a,
+	0x04, 0x5a, 0x06, 0x9a, 0x27, 0x97, 0x76, 0x85, 0x80, 0xd8, 0x99, 0x3c, 0xe3, 0xc9, 0xe5, 0xfd,
+	0xce, 0x91, 0xf3, 0xdf, 0x01, 0x00, 0xa4, 0x26, 0xc9, 0x9f, 0xa7, 0x19, 0x00, 0x00,
 }
@@ -16,6 +16,19 @@ var replacements = []string{
 	// Add crypto_modes to Version message.
 	// It is only present in Grumble, not in upstream Murmur.
 	`(?m)^(message Version {)$`, "$1\n\trepeated string crypto_modes = 5;\n",
+
+	// Add no_voice and silent to ChannelState message.
+	// They are only present in Grumble, not in upstream Murmur.
+	`(?m)^(message ChannelState {)$`, "$1\n\toptional bool no_voice = 100;\n\toptional bool silent = 101;\n",
+
+	// Add ban_duration to UserRemove and mute_duration to UserState message.
+	// They are only present in Grumble, not in upstream Murmur.
+	`(?m)^(message UserRemove {)$`, "$1\n\toptional uint32 ban_duration = 100;\n",
+	`(?m)^(message UserState {)$`, "$1\n\toptional uint32 mute_duration = 100;\n",
+
+	// Add the voice packet loss statistics to UserStats message.
+	// They are only present in Grumble, not in upstream Murmur.
+	`(?m)^(message UserStats {)$`, "$1\n\toptional uint32 voice_good = 100;\n\toptional uint32 voice_late = 101;\n\toptional uint32 voice_lost = 102;\n\toptional uint32 voice_lost_bursts = 103;\n\toptional float voice_jitter = 104;\n",
 }
 
 func main() {
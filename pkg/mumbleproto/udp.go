@@ -0,0 +1,165 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package mumbleproto
+
+import (
+	"fmt"
+
+	"mumble.info/grumble/pkg/packetdata"
+)
+
+// A UDPPacket is a decrypted voice or ping packet in the legacy UDP
+// format, as sent by a client.
+type UDPPacket struct {
+	// The packet's type, one of the UDPMessage constants.
+	Kind byte
+	// The voice target; 0 for normal talking, 0x1f for the server
+	// loopback. Always 0 for pings.
+	Target byte
+
+	// The sequence number of a voice packet, or the timestamp of a
+	// ping.
+	Sequence uint64
+	// The encoded audio frames of a voice packet, with their
+	// headers. It shares the memory of the parsed buffer.
+	Audio []byte
+	// Whether the voice packet ends the speaker's talk spurt: an Opus
+	// frame with the terminator bit, or an empty last frame.
+	Terminator bool
+	// Whether the voice packet ends with the speaker's position.
+	HasPosition bool
+	Position    [3]float32
+}
+
+// A UDPParseError describes a packet that could not be parsed.
+type UDPParseError struct {
+	// The offset in the packet at which parsing failed.
+	Offset int
+	Reason string
+}
+
+func (err *UDPParseError) Error() string {
+	return fmt.Sprintf("invalid UDP packet at offset %v: %v", err.Offset, err.Reason)
+}
+
+// The largest size of an Opus frame, from the 13 bits of its header
+// that give it.
+const maxOpusFrameSize = 0x1fff
+
+// The bit of an Opus frame's header that marks the last frame of a
+// talk spurt.
+const opusTerminator = 0x2000
+
+// ParseUDPPacket parses buf, a decrypted UDP packet from a client.
+// Arbitrary input never makes it panic; packets that cannot be parsed
+// are reported with a *UDPParseError.
+func ParseUDPPacket(buf []byte) (*UDPPacket, error) {
+	if len(buf) == 0 {
+		return nil, &UDPParseError{Offset: 0, Reason: "empty packet"}
+	}
+	packet := &UDPPacket{
+		Kind:   (buf[0] >> 5) & 0x07,
+		Target: buf[0] & 0x1f,
+	}
+	body := buf[1:]
+	pds := packetdata.New(body)
+	fail := func(reason string) (*UDPPacket, error) {
+		return nil, &UDPParseError{Offset: 1 + pds.Size(), Reason: reason}
+	}
+
+	packet.Sequence = pds.GetUint64()
+	if !pds.IsValid() {
+		return fail("truncated sequence number")
+	}
+
+	switch packet.Kind {
+	case UDPMessagePing:
+		packet.Target = 0
+		return packet, nil
+	case UDPMessageVoiceCELTAlpha, UDPMessageVoiceSpeex, UDPMessageVoiceCELTBeta:
+		start := pds.Size()
+		for {
+			header := pds.Next8()
+			if !pds.IsValid() {
+				return fail("truncated audio frame header")
+			}
+			pds.Skip(int(header & 0x7f))
+			if !pds.IsValid() {
+				return fail("truncated audio frame")
+			}
+			if header&0x80 == 0 {
+				packet.Terminator = header&0x7f == 0
+				break
+			}
+		}
+		packet.Audio = body[start:pds.Size()]
+	case UDPMessageVoiceOpus:
+		start := pds.Size()
+		header := pds.GetUint64()
+		if !pds.IsValid() {
+			return fail("truncated audio frame header")
+		}
+		size := header & maxOpusFrameSize
+		packet.Terminator = header&opusTerminator != 0
+		if uint64(pds.Left()) < size {
+			return fail("truncated audio frame")
+		}
+		pds.Skip(int(size))
+		packet.Audio = body[start:pds.Size()]
+	default:
+		return fail(fmt.Sprintf("unknown packet type %v", packet.Kind))
+	}
+
+	// Positional audio data follows the audio.
+	if pds.Left() >= 12 {
+		for i := range packet.Position {
+			packet.Position[i] = pds.GetFloat32()
+		}
+		if !pds.IsValid() {
+			return fail("truncated position")
+		}
+		packet.HasPosition = true
+	}
+	return packet, nil
+}
+
+// Encode returns the packet in the legacy UDP format, as ParseUDPPacket
+// reads it. Audio is written as it is, frame headers included, so a
+// voice packet ends its talk spurt only if its Audio says so; see
+// OpusAudio. Pings carry only their Sequence.
+func (packet *UDPPacket) Encode() []byte {
+	buf := make([]byte, 1+9+len(packet.Audio)+12)
+	pds := packetdata.New(buf[1:])
+	pds.PutUint64(packet.Sequence)
+	if packet.Kind == UDPMessagePing {
+		buf[0] = UDPMessagePing << 5
+		return buf[:1+pds.Size()]
+	}
+	pds.PutBytes(packet.Audio)
+	if packet.HasPosition {
+		for _, coord := range packet.Position {
+			pds.PutFloat32(coord)
+		}
+	}
+	buf[0] = packet.Kind<<5 | packet.Target&0x1f
+	return buf[:1+pds.Size()]
+}
+
+// OpusAudio returns frame with the header an Opus voice packet gives
+// it, for the Audio of a UDPPacket. last marks the end of a talk spurt.
+func OpusAudio(frame []byte, last bool) ([]byte, error) {
+	if len(frame) > maxOpusFrameSize {
+		return nil, fmt.Errorf("Opus frame of %v bytes is too large", len(frame))
+	}
+	header := uint64(len(frame))
+	if last {
+		header |= opusTerminator
+	}
+	buf := make([]byte, 9+len(frame))
+	pds := packetdata.New(buf)
+	pds.PutUint64(header)
+	pds.PutBytes(frame)
+	return buf[:pds.Size()], nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build go1.18
+// +build go1.18
+
+package mumbleproto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzParseUDPPacket(f *testing.F) {
+	f.Add([]byte{UDPMessageVoiceOpus<<5 | 2, 0x05, 0x03, 1, 2, 3})
+	f.Add([]byte{UDPMessageVoiceSpeex << 5, 0x01, 0x82, 1, 2, 0x01, 3})
+	f.Add([]byte{UDPMessageVoiceCELTAlpha << 5, 0x01, 0x00, 0x3f, 0x80, 0, 0, 0x40, 0, 0, 0, 0x40, 0x40, 0, 0})
+	f.Add([]byte{UDPMessagePing << 5, 0xf4, 1, 2, 3, 4, 5, 6, 7, 8})
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		packet, err := ParseUDPPacket(buf)
+		if err != nil {
+			if _, ok := err.(*UDPParseError); !ok {
+				t.Fatalf("unexpected error type %T", err)
+			}
+			return
+		}
+		if packet.Kind != (buf[0]>>5)&0x07 {
+			t.Fatalf("kind %v doesn't match header %#x", packet.Kind, buf[0])
+		}
+		if len(packet.Audio) > 0 && !bytes.Contains(buf[1:], packet.Audio) {
+			t.Fatalf("audio %v isn't part of the packet", packet.Audio)
+		}
+		// Positions may be NaN, so they are compared in their
+		// encoding.
+		encoded := packet.Encode()
+		again, err := ParseUDPPacket(encoded)
+		if err != nil {
+			t.Fatalf("re-encoded packet doesn't parse: %v", err)
+		}
+		if again.Kind != packet.Kind || again.Target != packet.Target || again.Sequence != packet.Sequence ||
+			!bytes.Equal(again.Audio, packet.Audio) || again.Terminator != packet.Terminator ||
+			again.HasPosition != packet.HasPosition || !bytes.Equal(again.Encode(), encoded) {
+			t.Fatalf("round trip of %+v gave %+v", packet, again)
+		}
+	})
+}
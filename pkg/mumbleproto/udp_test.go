@@ -0,0 +1,159 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package mumbleproto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseUDPPacket(t *testing.T) {
+	opus := []byte{
+		UDPMessageVoiceOpus<<5 | 2,
+		0x05,          // sequence
+		0x03, 1, 2, 3, // frame
+		0x3f, 0x80, 0, 0, // position
+		0x40, 0, 0, 0,
+		0x40, 0x40, 0, 0,
+	}
+	packet, err := ParseUDPPacket(opus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Kind != UDPMessageVoiceOpus || packet.Target != 2 || packet.Sequence != 5 {
+		t.Errorf("Unexpected packet %+v", packet)
+	}
+	if !bytes.Equal(packet.Audio, []byte{0x03, 1, 2, 3}) {
+		t.Errorf("Unexpected audio %v", packet.Audio)
+	}
+	if !packet.HasPosition || packet.Position != [3]float32{1, 2, 3} {
+		t.Errorf("Unexpected position %v", packet.Position)
+	}
+	if packet.Terminator {
+		t.Errorf("Unexpected terminator")
+	}
+
+	last := []byte{UDPMessageVoiceOpus << 5, 0x06, 0xa0, 0x02, 1, 2}
+	packet, err = ParseUDPPacket(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !packet.Terminator || !bytes.Equal(packet.Audio, []byte{0xa0, 0x02, 1, 2}) {
+		t.Errorf("Unexpected packet %+v", packet)
+	}
+
+	speex := []byte{UDPMessageVoiceSpeex << 5, 0x01, 0x82, 1, 2, 0x01, 3}
+	packet, err = ParseUDPPacket(speex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packet.Audio, []byte{0x82, 1, 2, 0x01, 3}) || packet.HasPosition || packet.Terminator {
+		t.Errorf("Unexpected packet %+v", packet)
+	}
+
+	speexLast := []byte{UDPMessageVoiceSpeex << 5, 0x02, 0x82, 1, 2, 0x00}
+	packet, err = ParseUDPPacket(speexLast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !packet.Terminator {
+		t.Errorf("Expected a terminator in %+v", packet)
+	}
+
+	ping := []byte{UDPMessagePing << 5, 0x7f}
+	packet, err = ParseUDPPacket(ping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.Kind != UDPMessagePing || packet.Sequence != 0x7f {
+		t.Errorf("Unexpected packet %+v", packet)
+	}
+
+	for _, bad := range [][]byte{
+		{},
+		{UDPMessageVoiceOpus << 5},
+		{UDPMessageVoiceOpus << 5, 0x01, 0x05, 1, 2},
+		{UDPMessageVoiceSpeex << 5, 0x01, 0x81, 1},
+		{UDPMessageVoiceCELTAlpha << 5, 0x01, 0x7f},
+		{UDPMessageVoiceOpus << 5, 0xf4, 0, 0},
+		{7 << 5, 0x01},
+	} {
+		_, err := ParseUDPPacket(bad)
+		if _, ok := err.(*UDPParseError); !ok {
+			t.Errorf("Expected a UDPParseError for %v, got %v", bad, err)
+		}
+	}
+}
+
+func TestUDPPacketRoundTrip(t *testing.T) {
+	opus, err := OpusAudio([]byte{1, 2, 3}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opusLast, err := OpusAudio(make([]byte, 200), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, packet := range []*UDPPacket{
+		{Kind: UDPMessageVoiceOpus, Target: 2, Sequence: 5, Audio: opus},
+		{Kind: UDPMessageVoiceOpus, Target: 0x1f, Sequence: 1 << 40, Audio: opusLast, Terminator: true},
+		{Kind: UDPMessageVoiceOpus, Sequence: 300, Audio: opus, HasPosition: true, Position: [3]float32{1.5, -2, 1e6}},
+		{Kind: UDPMessageVoiceSpeex, Target: 1, Sequence: 2, Audio: []byte{0x82, 1, 2, 0x01, 3}},
+		{Kind: UDPMessageVoiceCELTAlpha, Sequence: 3, Audio: []byte{0x00}, Terminator: true, HasPosition: true, Position: [3]float32{0, 0, -1}},
+		{Kind: UDPMessageVoiceCELTBeta, Sequence: 4, Audio: []byte{0x81, 9, 0x02, 7, 8}},
+		{Kind: UDPMessagePing, Sequence: 0xdeadbeefcafe},
+	} {
+		buf := packet.Encode()
+		parsed, err := ParseUDPPacket(buf)
+		if err != nil {
+			t.Errorf("Unable to parse %v encoded as %v: %v", packet, buf, err)
+			continue
+		}
+		if parsed.Kind != packet.Kind || parsed.Target != packet.Target || parsed.Sequence != packet.Sequence ||
+			!bytes.Equal(parsed.Audio, packet.Audio) || parsed.Terminator != packet.Terminator ||
+			parsed.HasPosition != packet.HasPosition || parsed.Position != packet.Position {
+			t.Errorf("Round trip of %+v gave %+v", packet, parsed)
+		}
+		if again := parsed.Encode(); !bytes.Equal(again, buf) {
+			t.Errorf("Re-encoding %v gave %v", buf, again)
+		}
+	}
+
+	// Pings have no target, and encoding drops one set by mistake.
+	ping := (&UDPPacket{Kind: UDPMessagePing, Target: 3, Sequence: 1}).Encode()
+	if !bytes.Equal(ping, []byte{UDPMessagePing << 5, 0x01}) {
+		t.Errorf("Unexpected ping %v", ping)
+	}
+}
+
+func TestOpusAudio(t *testing.T) {
+	audio, err := OpusAudio([]byte{1, 2}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(audio, []byte{0xa0, 0x02, 1, 2}) {
+		t.Errorf("Unexpected audio %v", audio)
+	}
+	if _, err := OpusAudio(make([]byte, maxOpusFrameSize), false); err != nil {
+		t.Errorf("Unexpected error for the largest frame: %v", err)
+	}
+	if _, err := OpusAudio(make([]byte, maxOpusFrameSize+1), false); err == nil {
+		t.Errorf("Expected an error for an oversized frame")
+	}
+}
+
+func BenchmarkParseUDPPacket(b *testing.B) {
+	buf := make([]byte, 1+2+2+120+12)
+	buf[0] = UDPMessageVoiceOpus << 5
+	buf[1], buf[2] = 0x80|0x01, 0x00 // sequence
+	buf[3], buf[4] = 0x80, 120       // frame
+	b.SetBytes(int64(len(buf)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseUDPPacket(buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package oidc implements a minimal OpenID Connect relying party.
+//
+// Only the authorization code flow is supported, and ID tokens
+// must be signed using RS256.
+package oidc
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidToken  = errors.New("oidc: invalid ID token")
+	ErrUnknownKey    = errors.New("oidc: ID token signed with unknown key")
+	ErrTokenExpired  = errors.New("oidc: ID token expired")
+	ErrClaimMismatch = errors.New("oidc: ID token claims do not match")
+)
+
+// A Provider is an OpenID Connect identity provider, as seen
+// by a single client.
+type Provider struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	authURL  string
+	tokenURL string
+	jwksURL  string
+
+	client *http.Client
+
+	keysMutex sync.Mutex
+	keys      map[string]*rsa.PublicKey
+}
+
+type discoveryDocument struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+}
+
+// Discover fetches the provider's configuration from its
+// /.well-known/openid-configuration document.
+func Discover(issuer, clientID, clientSecret, redirectURL string) (*Provider, error) {
+	p := &Provider{
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+
+	var doc discoveryDocument
+	err := p.getJSON(p.Issuer+"/.well-known/openid-configuration", &doc)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch: expected %v, got %v", p.Issuer, doc.Issuer)
+	}
+	if len(doc.AuthURL) == 0 || len(doc.TokenURL) == 0 || len(doc.JWKSURL) == 0 {
+		return nil, errors.New("oidc: incomplete discovery document")
+	}
+	p.authURL = doc.AuthURL
+	p.tokenURL = doc.TokenURL
+	p.jwksURL = doc.JWKSURL
+	return p, nil
+}
+
+// AuthCodeURL returns the URL the user should be redirected to
+// in order to authenticate.
+func (p *Provider) AuthCodeURL(state, nonce string) string {
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.ClientID)
+	v.Set("redirect_uri", p.RedirectURL)
+	v.Set("scope", "openid profile email")
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+
+	sep := "?"
+	if strings.Contains(p.authURL, "?") {
+		sep = "&"
+	}
+	return p.authURL + sep + v.Encode()
+}
+
+// Exchange exchanges an authorization code for the user's
+// claims. The ID token returned by the provider is verified
+// against the expected nonce.
+func (p *Provider) Exchange(code, nonce string) (map[string]interface{}, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+
+	req, err := http.NewRequest(http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: token endpoint returned %v", resp.Status)
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens.IDToken) == 0 {
+		return nil, errors.New("oidc: no ID token in token response")
+	}
+
+	return p.Verify(tokens.IDToken, nonce)
+}
+
+// Verify verifies the signature and standard claims of an ID token
+// and returns its claims.
+func (p *Provider) Verify(token, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("oidc: unsupported signing algorithm %v", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := p.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != p.Issuer {
+		return nil, ErrClaimMismatch
+	}
+	if !audienceContains(claims["aud"], p.ClientID) {
+		return nil, ErrClaimMismatch
+	}
+	if n, _ := claims["nonce"].(string); n != nonce {
+		return nil, ErrClaimMismatch
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Now().After(time.Unix(int64(exp), 0)) {
+		return nil, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the provider's signing key with the given id. The
+// key set is re-fetched if the key is not known, to handle key
+// rotation at the provider.
+func (p *Provider) key(kid string) (*rsa.PublicKey, error) {
+	p.keysMutex.Lock()
+	defer p.keysMutex.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(p.jwksURL, &jwks); err != nil {
+		return nil, err
+	}
+
+	p.keys = make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) > 4 {
+			continue
+		}
+		p.keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (p *Provider) getJSON(url string, v interface{}) error {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %v returned %v", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(buf, v)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string
+}
+
+func (tp *testProvider) sign(claims map[string]interface{}) string {
+	enc := func(v interface{}) string {
+		buf, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(buf)
+	}
+	signed := enc(map[string]string{"alg": "RS256", "kid": "k1"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, tp.key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tp := &testProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 tp.URL,
+			"authorization_endpoint": tp.URL + "/auth",
+			"token_endpoint":         tp.URL + "/token",
+			"jwks_uri":               tp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "grumble" || pass != "secret" || r.FormValue("code") != "c0de" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token": tp.sign(map[string]interface{}{
+				"iss":                tp.URL,
+				"aud":                "grumble",
+				"exp":                time.Now().Add(time.Minute).Unix(),
+				"nonce":              tp.nonce,
+				"preferred_username": "alice",
+			}),
+		})
+	})
+	tp.Server = httptest.NewServer(mux)
+	return tp
+}
+
+func TestExchange(t *testing.T) {
+	tp := newTestProvider(t)
+	defer tp.Close()
+	tp.nonce = "n0nce"
+
+	p, err := Discover(tp.URL, "grumble", "secret", "https://example.com/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := p.Exchange("c0de", "n0nce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims["preferred_username"] != "alice" {
+		t.Errorf("Unexpected claims %v", claims)
+	}
+
+	_, err = p.Exchange("c0de", "other")
+	if err != ErrClaimMismatch {
+		t.Errorf("Expected nonce mismatch, got %v", err)
+	}
+}
+
+func TestVerifyExpired(t *testing.T) {
+	tp := newTestProvider(t)
+	defer tp.Close()
+
+	p, err := Discover(tp.URL, "grumble", "secret", "https://example.com/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := tp.sign(map[string]interface{}{
+		"iss": tp.URL,
+		"aud": []string{"grumble"},
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+	if _, err := p.Verify(token, ""); err != ErrTokenExpired {
+		t.Errorf("Expected expired token, got %v", err)
+	}
+
+	if _, err := p.Verify(token[:len(token)-4]+"AAAA", ""); err != ErrInvalidToken {
+		t.Errorf("Expected invalid signature, got %v", err)
+	}
+}
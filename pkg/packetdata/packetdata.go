@@ -30,7 +30,7 @@ func (pds *PacketData) IsValid() bool {
 }
 
 func (pds *PacketData) Skip(skip int) {
-	if pds.Left() >= skip {
+	if skip >= 0 && pds.Left() >= skip {
 		pds.offset += skip
 	} else {
 		pds.ok = false
@@ -103,6 +103,7 @@ func (pds *PacketData) addVarint(val uint64) {
 		if i <= 0x3 {
 			// Short for -1 to -4
 			pds.append(0xfc | i)
+			return
 		} else {
 			pds.append(0xf8)
 		}
@@ -114,11 +115,17 @@ func (pds *PacketData) addVarint(val uint64) {
 		// Needs two top bits clear
 		pds.append((i >> 8) | 0x80)
 		pds.append(i & 0xff)
-	} else if i < 0x10000000 {
+	} else if i < 0x200000 {
 		// Needs three top bits clear
 		pds.append((i >> 16) | 0xc0)
 		pds.append((i >> 8) & 0xff)
 		pds.append(i & 0xff)
+	} else if i < 0x10000000 {
+		// Needs four top bits clear
+		pds.append((i >> 24) | 0xe0)
+		pds.append((i >> 16) & 0xff)
+		pds.append((i >> 8) & 0xff)
+		pds.append(i & 0xff)
 	} else if i < 0x100000000 {
 		// Full 32 bit integer
 		pds.append(0xf0)
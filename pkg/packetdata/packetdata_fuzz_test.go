@@ -0,0 +1,39 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+//go:build go1.18
+// +build go1.18
+
+package packetdata
+
+import "testing"
+
+func FuzzGet(f *testing.F) {
+	f.Add([]byte{0x05, 0x80, 0x01, 0xf4, 1, 2, 3, 4, 5, 6, 7, 8})
+	f.Add([]byte{0xf8, 0xf8, 0xfc, 0x3f, 0x80, 0, 0})
+	f.Add([]byte{0xf0, 0xff})
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		pds := New(buf)
+		for pds.IsValid() && pds.Left() > 0 {
+			before := pds.Left()
+			switch buf[pds.Size()] % 4 {
+			case 0:
+				pds.GetUint64()
+			case 1:
+				pds.GetFloat32()
+			case 2:
+				pds.Skip(int(pds.Next8()&0x7f) - 8)
+			case 3:
+				pds.CopyBytes(make([]byte, 3))
+				pds.Skip(3)
+			}
+			if pds.Left() < 0 || pds.Left() > len(buf) {
+				t.Fatalf("offset out of range: %v left of %v", pds.Left(), len(buf))
+			}
+			if pds.IsValid() && pds.Left() >= before {
+				t.Fatalf("no progress at %v left", before)
+			}
+		}
+	})
+}
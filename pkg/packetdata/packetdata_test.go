@@ -53,6 +53,41 @@ func TestSelfUint64(t *testing.T) {
 	}
 }
 
+func TestVarintSizes(t *testing.T) {
+	for _, tc := range []struct {
+		val  uint64
+		size int
+	}{
+		{0x7f, 1},
+		{0x80, 2},
+		{0x3fff, 2},
+		{0x4000, 3},
+		{0x1fffff, 3},
+		{0x200000, 4},
+		{0x6303030, 4},
+		{0xfffffff, 4},
+		{0x10000000, 5},
+		{0xffffffff, 5},
+		{0x100000000, 9},
+		{^uint64(0), 1},
+		{^uint64(3), 1},
+		{^uint64(4), 2},
+		{^uint64(0xffffffff), 6},
+	} {
+		buf := make([]byte, 9)
+		pds := New(buf)
+		pds.PutUint64(tc.val)
+		if !pds.IsValid() || pds.Size() != tc.size {
+			t.Errorf("%#x: encoded in %v bytes (valid %v), expected %v", tc.val, pds.Size(), pds.IsValid(), tc.size)
+			continue
+		}
+		pds2 := New(buf[:pds.Size()])
+		if val := pds2.GetUint64(); !pds2.IsValid() || val != tc.val {
+			t.Errorf("%#x: read back %#x", tc.val, val)
+		}
+	}
+}
+
 func TestSelfMumbleVoicePacket(t *testing.T) {
 	buf := make([]byte, 500)
 	pds := New(buf)
@@ -142,3 +177,21 @@ func TestSelfBytes(t *testing.T) {
 		}
 	}
 }
+
+// Benchmark rewriting a voice packet for its listeners, the way the
+// server prepends the speaker's session to each packet it forwards.
+func BenchmarkForwardVoice(b *testing.B) {
+	in := make([]byte, 1+2+2+120)
+	out := make([]byte, 1024)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pds := New(out[1:])
+		pds.PutUint32(uint32(i))
+		pds.PutBytes(in[1:])
+		if !pds.IsValid() {
+			b.Fatal("invalid PDS")
+		}
+		out[0] = in[0] & 0xe0
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package password hashes passwords for storage, and checks passwords
+// against stored hashes.
+//
+// New hashes use argon2id and are stored as
+//
+//	argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>
+//
+// with the salt and key in unpadded base64. Legacy hashes of the form
+// sha1$<hex salt>$<hex digest> can still be checked, so that they can
+// be replaced when their password is next used.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	saltLen = 16
+	keyLen  = 32
+)
+
+// Params are the cost parameters of argon2id.
+type Params struct {
+	// The number of passes over the memory.
+	Time uint32
+	// The memory used, in KiB.
+	Memory uint32
+	// The number of threads used.
+	Threads uint8
+}
+
+// DefaultParams are the parameters used when none are configured.
+var DefaultParams = Params{Time: 3, Memory: 64 * 1024, Threads: 2}
+
+// The bounds of the parameters accepted in stored hashes, as allowed
+// for the PasswordHash configuration keys. argon2id panics with no time
+// or threads, and memory must be bounded to keep a stored hash from
+// exhausting it.
+const (
+	minMemory = 8
+	maxMemory = 4 * 1024 * 1024
+	maxTime   = 100
+)
+
+// ErrUnknownScheme is returned for hashes this package can't parse.
+var ErrUnknownScheme = errors.New("password: unknown hash scheme")
+
+var b64 = base64.RawStdEncoding
+
+// Hash returns an argon2id hash of password, using a random salt.
+func Hash(password string, p Params) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, keyLen)
+	return fmt.Sprintf("argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// argon2Hash is a parsed argon2id hash.
+type argon2Hash struct {
+	params Params
+	salt   []byte
+	key    []byte
+}
+
+func parseArgon2(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[0] != "argon2id" {
+		return nil, ErrUnknownScheme
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[1], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrUnknownScheme
+	}
+	h := &argon2Hash{}
+	_, err := fmt.Sscanf(parts[2], "m=%d,t=%d,p=%d", &h.params.Memory, &h.params.Time, &h.params.Threads)
+	if err != nil {
+		return nil, ErrUnknownScheme
+	}
+	if h.params.Time < 1 || h.params.Time > maxTime || h.params.Threads < 1 ||
+		h.params.Memory < minMemory || h.params.Memory > maxMemory {
+		return nil, ErrUnknownScheme
+	}
+	if h.salt, err = b64.DecodeString(parts[3]); err != nil {
+		return nil, ErrUnknownScheme
+	}
+	if h.key, err = b64.DecodeString(parts[4]); err != nil || len(h.key) == 0 {
+		return nil, ErrUnknownScheme
+	}
+	return h, nil
+}
+
+// Verify reports whether password matches hash. Hashes it can't parse
+// match no password.
+func Verify(password, hash string) bool {
+	if strings.HasPrefix(hash, "sha1$") {
+		return verifySHA1(password, hash)
+	}
+	h, err := parseArgon2(hash)
+	if err != nil {
+		return false
+	}
+	key := argon2.IDKey([]byte(password), h.salt, h.params.Time, h.params.Memory, h.params.Threads, uint32(len(h.key)))
+	return subtle.ConstantTimeCompare(key, h.key) == 1
+}
+
+// verifySHA1 checks a password against a legacy salted SHA-1 hash.
+// The salt may be empty.
+func verifySHA1(password, hash string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 3 || len(parts[2]) == 0 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	digest, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	h := sha1.New()
+	h.Write(salt)
+	h.Write([]byte(password))
+	return subtle.ConstantTimeCompare(h.Sum(nil), digest) == 1
+}
+
+// NeedsRehash reports whether hash should be replaced by a new hash
+// with the parameters p: legacy hashes and argon2id hashes with other
+// parameters do.
+func NeedsRehash(hash string, p Params) bool {
+	h, err := parseArgon2(hash)
+	if err != nil {
+		return true
+	}
+	return h.params != p || len(h.salt) < saltLen || len(h.key) != keyLen
+}
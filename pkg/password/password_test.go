@@ -0,0 +1,100 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+// Cheap parameters, to keep the tests fast.
+var testParams = Params{Time: 1, Memory: 64, Threads: 1}
+
+func TestHashVerify(t *testing.T) {
+	hash, err := Hash("secret", testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("unexpected hash %q", hash)
+	}
+	if !Verify("secret", hash) {
+		t.Error("password does not match its hash")
+	}
+	if Verify("Secret", hash) {
+		t.Error("wrong password matches")
+	}
+
+	other, err := Hash("secret", testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == hash {
+		t.Error("hashes of the same password are equal")
+	}
+}
+
+func TestVerifySHA1(t *testing.T) {
+	for _, tc := range []struct {
+		hash string
+		ok   bool
+	}{
+		// sha1(0x0102 + "secret")
+		{"sha1$0102$f808618647068a1c44219ca9b5035ce178b2b0d3", true},
+		{"sha1$0103$f808618647068a1c44219ca9b5035ce178b2b0d3", false},
+		// sha1("secret"), as imported from Murmur
+		{"sha1$$e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4", true},
+		{"sha1$$", false},
+		{"sha1$zz$e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4", false},
+		{"md5$$5ebe2294ecd0e0f08eab7690d2a6ee69", false},
+	} {
+		if ok := Verify("secret", tc.hash); ok != tc.ok {
+			t.Errorf("Verify(%q) = %v, want %v", tc.hash, ok, tc.ok)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	hash, err := Hash("secret", testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NeedsRehash(hash, testParams) {
+		t.Error("fresh hash needs rehash")
+	}
+	if !NeedsRehash(hash, Params{Time: 2, Memory: 64, Threads: 1}) {
+		t.Error("hash with other parameters does not need rehash")
+	}
+	if !NeedsRehash("sha1$$e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4", testParams) {
+		t.Error("legacy hash does not need rehash")
+	}
+}
+
+func TestParamBounds(t *testing.T) {
+	hash, err := Hash("secret", testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name   string
+		params string
+	}{
+		{"no time", "m=64,t=0,p=1"},
+		{"too much time", "m=64,t=101,p=1"},
+		{"no threads", "m=64,t=1,p=0"},
+		{"too little memory", "m=7,t=1,p=1"},
+		{"too much memory", "m=4194305,t=1,p=1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bad := strings.Replace(hash, "m=64,t=1,p=1", tc.params, 1)
+			if _, err := parseArgon2(bad); err != ErrUnknownScheme {
+				t.Errorf("parseArgon2(%q) = %v, want ErrUnknownScheme", bad, err)
+			}
+			if Verify("secret", bad) {
+				t.Errorf("password matches %q", bad)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package pkcs12 implements a minimal PKCS#12 (RFC 7292) encoder.
+//
+// It produces files holding a single certificate and its private key,
+// in the form Mumble clients expect when importing a certificate: the
+// private key is stored in a shrouded key bag encrypted using
+// pbeWithSHAAnd3-KeyTripleDES-CBC, and the file is integrity protected
+// by a SHA-1 HMAC.
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"unicode/utf16"
+)
+
+var (
+	oidDataContentType   = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 7, 1})
+	oidCertBag           = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 10, 1, 3})
+	oidShroudedKeyBag    = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 10, 1, 2})
+	oidCertTypeX509      = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 22, 1})
+	oidFriendlyName      = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 20})
+	oidLocalKeyID        = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 9, 21})
+	oidPBEWithSHA3DESCBC = asn1.ObjectIdentifier([]int{1, 2, 840, 113549, 1, 12, 1, 3})
+	oidSHA1              = asn1.ObjectIdentifier([]int{1, 3, 14, 3, 2, 26})
+)
+
+// The number of key derivation iterations used for
+// encryption and MAC keys.
+const iterations = 2048
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData
+}
+
+// The [0] EXPLICIT fields of contentInfo and safeBag are built
+// using explicitTag, as encoding/asn1 does not add the explicit
+// tag to RawValues.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type safeBag struct {
+	Id         asn1.ObjectIdentifier
+	Value      asn1.RawValue
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	Id    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	Id   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     algorithmIdentifier
+	EncryptedData []byte
+}
+
+type digestInfo struct {
+	Algorithm algorithmIdentifier
+	Digest    []byte
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int
+}
+
+// Encode encodes cert and its private key into a PKCS#12 file
+// protected by password. The password may be empty. The friendly
+// name is shown by most applications when importing the file.
+func Encode(cert *x509.Certificate, key interface{}, friendlyName string, password string) ([]byte, error) {
+	pass := bmpString(password)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The local key id pairs up the key and certificate bags.
+	localKeyID := sha1.Sum(cert.Raw)
+	attrs, err := bagAttributes(friendlyName, localKeyID[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// Certificate bag
+	certBagDER, err := asn1.Marshal(certBag{Id: oidCertTypeX509, Data: cert.Raw})
+	if err != nil {
+		return nil, err
+	}
+	certSafe, err := marshalSafeContents(safeBag{
+		Id:         oidCertBag,
+		Value:      explicitTag(certBagDER),
+		Attributes: attrs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Shrouded key bag
+	keySalt, err := randomSalt()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encrypt3DES(keyDER, pass, keySalt)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbeParams{Salt: keySalt, Iterations: iterations})
+	if err != nil {
+		return nil, err
+	}
+	keyBagDER, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: algorithmIdentifier{
+			Algorithm:  oidPBEWithSHA3DESCBC,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		EncryptedData: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	keySafe, err := marshalSafeContents(safeBag{
+		Id:         oidShroudedKeyBag,
+		Value:      explicitTag(keyBagDER),
+		Attributes: attrs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticated safe
+	authSafe, err := asn1.Marshal([]contentInfo{
+		dataContentInfo(certSafe),
+		dataContentInfo(keySafe),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	macSalt, err := randomSalt()
+	if err != nil {
+		return nil, err
+	}
+	macKey := pbkdf(pass, macSalt, 3, sha1.Size)
+	mac := hmac.New(sha1.New, macKey)
+	mac.Write(authSafe)
+
+	return asn1.Marshal(pfxPdu{
+		Version:  3,
+		AuthSafe: dataContentInfo(authSafe),
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: algorithmIdentifier{
+					Algorithm:  oidSHA1,
+					Parameters: asn1.NullRawValue,
+				},
+				Digest: mac.Sum(nil),
+			},
+			MacSalt:    macSalt,
+			Iterations: iterations,
+		},
+	})
+}
+
+// bagAttributes returns the attributes attached to both safe bags.
+func bagAttributes(friendlyName string, localKeyID []byte) ([]pkcs12Attribute, error) {
+	idDER, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []pkcs12Attribute{{
+		Id:    oidLocalKeyID,
+		Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: idDER},
+	}}
+	if len(friendlyName) > 0 {
+		name := bmpString(friendlyName)
+		nameDER, err := asn1.Marshal(asn1.RawValue{
+			Class: asn1.ClassUniversal,
+			Tag:   asn1.TagBMPString,
+			Bytes: name[:len(name)-2],
+		})
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, pkcs12Attribute{
+			Id:    oidFriendlyName,
+			Value: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: nameDER},
+		})
+	}
+	return attrs, nil
+}
+
+// marshalSafeContents encodes bag as a SafeContents sequence.
+func marshalSafeContents(bag safeBag) ([]byte, error) {
+	return asn1.Marshal([]safeBag{bag})
+}
+
+// dataContentInfo wraps data in a ContentInfo of type data.
+func dataContentInfo(data []byte) contentInfo {
+	octets, _ := asn1.Marshal(data)
+	return contentInfo{
+		ContentType: oidDataContentType,
+		Content:     explicitTag(octets),
+	}
+}
+
+// explicitTag wraps der in a [0] EXPLICIT tag.
+func explicitTag(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+func randomSalt() ([]byte, error) {
+	salt := make([]byte, 8)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// encrypt3DES encrypts data using pbeWithSHAAnd3-KeyTripleDES-CBC.
+func encrypt3DES(data, password, salt []byte) ([]byte, error) {
+	key := pbkdf(password, salt, 1, 24)
+	iv := pbkdf(password, salt, 2, des.BlockSize)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// PKCS#7 padding
+	padding := des.BlockSize - len(data)%des.BlockSize
+	padded := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	out := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
+	return out, nil
+}
+
+// bmpString encodes s as a null-terminated big-endian UTF-16
+// string, as used for PKCS#12 passwords.
+func bmpString(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	buf := make([]byte, 0, 2*len(units)+2)
+	for _, u := range units {
+		buf = append(buf, byte(u>>8), byte(u))
+	}
+	return append(buf, 0, 0)
+}
+
+// pbkdf implements the PKCS#12 key derivation function
+// (RFC 7292, appendix B.2) using SHA-1.
+func pbkdf(password, salt []byte, id byte, size int) []byte {
+	const u = sha1.Size
+	const v = 64
+
+	fill := func(in []byte) []byte {
+		if len(in) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(in)+v-1)/v))
+		for i := range out {
+			out[i] = in[i%len(in)]
+		}
+		return out
+	}
+
+	D := bytes.Repeat([]byte{id}, v)
+	I := append(fill(salt), fill(password)...)
+
+	var result []byte
+	for len(result) < size {
+		h := sha1.New()
+		h.Write(D)
+		h.Write(I)
+		A := h.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			sum := sha1.Sum(A)
+			A = sum[:]
+		}
+		result = append(result, A...)
+
+		// I_j = (I_j + B + 1) mod 2^(v*8) for each v-byte block of I.
+		B := make([]byte, v)
+		for i := range B {
+			B[i] = A[i%u]
+		}
+		for j := 0; j < len(I); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(I[j+k]) + int(B[k]) + carry
+				I[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return result[:size]
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package pkcs12
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	xpkcs12 "golang.org/x/crypto/pkcs12"
+)
+
+func testCert(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestRoundTrip(t *testing.T) {
+	cert, key := testCert(t)
+
+	for _, password := range []string{"", "hunter2"} {
+		pfx, err := Encode(cert, key, "Test Certificate", password)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decodedKey, decodedCert, err := xpkcs12.Decode(pfx, password)
+		if err != nil {
+			t.Fatalf("Decode with password %q: %v", password, err)
+		}
+		if !decodedCert.Equal(cert) {
+			t.Errorf("Decoded certificate does not match")
+		}
+		ecKey, ok := decodedKey.(*ecdsa.PrivateKey)
+		if !ok || ecKey.D.Cmp(key.D) != 0 {
+			t.Errorf("Decoded key does not match")
+		}
+
+		_, _, err = xpkcs12.Decode(pfx, password+"wrong")
+		if err == nil {
+			t.Errorf("Expected decoding with wrong password to fail")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package plugin_test
+
+import (
+	"strings"
+
+	"mumble.info/grumble/pkg/plugin"
+)
+
+// greeter welcomes users, keeps out users named in its configuration,
+// and drops messages that shout.
+type greeter struct {
+	server plugin.Server
+	banned []string
+}
+
+func (g *greeter) Start(server plugin.Server) error {
+	g.server = server
+	g.banned = strings.Fields(server.Config("GreeterBanned"))
+	server.RegisterAuthenticator(g)
+	server.RegisterMessageFilter(g)
+	server.RegisterEventHandler(g)
+	return nil
+}
+
+func (g *greeter) Stop() {}
+
+func (g *greeter) Authenticate(req *plugin.AuthRequest) plugin.AuthResult {
+	for _, name := range g.banned {
+		if req.Username == name {
+			return plugin.AuthResult{Decision: plugin.Deny, Reason: "Go away"}
+		}
+	}
+	return plugin.AuthResult{Decision: plugin.Pass}
+}
+
+func (g *greeter) FilterMessage(user plugin.User, text string) (string, bool) {
+	return text, strings.ToUpper(text) != text
+}
+
+func (g *greeter) HandleEvent(ev plugin.Event) {
+	if ev.Type == plugin.Connect {
+		g.server.SendMessage(ev.User.Session, "Welcome, "+ev.User.Name+"!")
+	}
+}
+
+func Example() {
+	plugin.Register(plugin.Info{
+		Name:       "greeter",
+		New:        func() plugin.Plugin { return &greeter{} },
+		ConfigKeys: []string{"GreeterBanned"},
+	})
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package plugin defines the interface between Grumble and compiled-in
+// server extensions.
+//
+// A plugin package registers itself from an init function:
+//
+//	func init() {
+//		plugin.Register(plugin.Info{
+//			Name: "greeter",
+//			New:  func() plugin.Plugin { return &greeter{} },
+//		})
+//	}
+//
+// and is compiled into the server by importing it for its side effects
+// from a file in cmd/grumble, usually guarded by a build tag:
+//
+//	// +build greeter
+//
+//	package main
+//
+//	import _ "example.com/grumble-greeter"
+//
+// A server runs the plugins named by its Plugins configuration key.
+// Each server gets its own instance of each plugin. When the server
+// starts, the plugin's Start method registers the capabilities it
+// provides (authenticators, message filters and event handlers) with
+// the Server it is given.
+package plugin
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"mumble.info/grumble/pkg/serverconf"
+)
+
+// A Plugin is a server extension.
+type Plugin interface {
+	// Start is called when the server starts, before it accepts
+	// connections. An error prevents the server from starting.
+	Start(server Server) error
+	// Stop is called once the server has stopped.
+	Stop()
+}
+
+// Info describes a plugin for the registry.
+type Info struct {
+	// The name used to enable the plugin in the Plugins key.
+	Name string
+	// New creates an instance of the plugin for a server.
+	New func() Plugin
+	// Configuration keys read by the plugin. They are accepted in
+	// configuration files, and read using Server.Config.
+	ConfigKeys []string
+}
+
+var (
+	registryLock sync.Mutex
+	registry     = make(map[string]Info)
+)
+
+// Register makes a plugin available to servers. It is meant to be
+// called from init functions, and panics if a plugin of the same name
+// has already been registered.
+func Register(info Info) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if len(info.Name) == 0 || info.New == nil {
+		panic("plugin: Register called without a name or constructor")
+	}
+	if _, exists := registry[info.Name]; exists {
+		panic("plugin: " + info.Name + " registered twice")
+	}
+	for _, key := range info.ConfigKeys {
+		serverconf.RegisterKey(key)
+	}
+	registry[info.Name] = info
+}
+
+// Names returns the names of the registered plugins, sorted.
+func Names() []string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ErrUnknownPlugin is returned by New for names that have not been
+// registered.
+var ErrUnknownPlugin = errors.New("plugin: unknown plugin")
+
+// New creates an instance of the named plugin.
+func New(name string) (Plugin, error) {
+	registryLock.Lock()
+	info, ok := registry[name]
+	registryLock.Unlock()
+
+	if !ok {
+		return nil, ErrUnknownPlugin
+	}
+	return info.New(), nil
+}
+
+// A User is a connected user, as seen by plugins.
+type User struct {
+	Session  uint32
+	Name     string
+	UserId   int // -1 if unregistered
+	Channel  int
+	CertHash string
+	Address  net.IP
+}
+
+// The types of server events.
+type EventType int
+
+const (
+	Connect EventType = iota
+	Disconnect
+	Message
+	ChannelJoin
+	TalkingStart
+	TalkingStop
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Connect:
+		return "connect"
+	case Disconnect:
+		return "disconnect"
+	case Message:
+		return "message"
+	case ChannelJoin:
+		return "channel_join"
+	case TalkingStart:
+		return "talking_start"
+	case TalkingStop:
+		return "talking_stop"
+	}
+	return "unknown"
+}
+
+// An Event is something that happened on the server.
+type Event struct {
+	Type EventType
+	User User
+	// The text of a Message event, after filtering.
+	Text string
+	// The channel entered and left in a ChannelJoin event. PrevChannel
+	// is -1 if the user had not been in a channel.
+	Channel     int
+	PrevChannel int
+	// The length of the talk spurt ended by a TalkingStop event.
+	Duration time.Duration
+}
+
+// An AuthRequest describes a user trying to log in.
+type AuthRequest struct {
+	Username string
+	Password string
+	Tokens   []string
+	CertHash string
+	Address  net.IP
+	// The id of the registered user matched by name or certificate,
+	// or -1.
+	UserId int
+}
+
+// The decision of an Authenticator.
+type Decision int
+
+const (
+	// Pass leaves the decision to the next authenticator, or to the
+	// server's own checks.
+	Pass Decision = iota
+	// Allow lets the user in without the server password.
+	Allow
+	// Deny rejects the user.
+	Deny
+)
+
+// An AuthResult is the outcome of an authentication attempt. Reason is
+// shown to denied users.
+type AuthResult struct {
+	Decision Decision
+	Reason   string
+}
+
+// An Authenticator decides whether users may log in. Authenticate is
+// called from the connecting client's goroutine, so it may block, but
+// must be safe for concurrent use. SuperUser logins are not passed to
+// authenticators.
+type Authenticator interface {
+	Authenticate(req *AuthRequest) AuthResult
+}
+
+// A MessageFilter inspects text messages before they are delivered.
+// FilterMessage returns the text to deliver, and false to drop the
+// message. It is called from the server's handler goroutine.
+type MessageFilter interface {
+	FilterMessage(user User, text string) (string, bool)
+}
+
+// An EventHandler is told about server events once they have happened.
+// HandleEvent is called from the server's handler goroutine.
+type EventHandler interface {
+	HandleEvent(ev Event)
+}
+
+// Server is the interface a server presents to its plugins.
+//
+// The Register methods may only be called from Start. Users,
+// SendMessage and Kick may only be called from the hooks run on the
+// server's handler goroutine (message filters and event handlers), or
+// from a function run by Do. Do runs a function on the handler
+// goroutine; it must not be called from those hooks, and fails while
+// the server is not running, which includes the time Start is called.
+type Server interface {
+	Id() int64
+	Config(key string) string
+	Printf(format string, v ...interface{})
+	Do(fn func()) error
+
+	Users() []User
+	SendMessage(session uint32, text string) error
+	Kick(session uint32, reason string) error
+
+	RegisterAuthenticator(a Authenticator)
+	RegisterMessageFilter(f MessageFilter)
+	RegisterEventHandler(h EventHandler)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package plugin
+
+import (
+	"testing"
+)
+
+type nopPlugin struct{}
+
+func (nopPlugin) Start(server Server) error { return nil }
+func (nopPlugin) Stop()                     {}
+
+func TestRegistry(t *testing.T) {
+	Register(Info{Name: "test-nop", New: func() Plugin { return nopPlugin{} }})
+
+	found := false
+	for _, name := range Names() {
+		if name == "test-nop" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Registered plugin missing from %v", Names())
+	}
+
+	if p, err := New("test-nop"); err != nil || p == nil {
+		t.Errorf("Unable to create plugin: %v", err)
+	}
+	if _, err := New("test-missing"); err != ErrUnknownPlugin {
+		t.Errorf("Expected ErrUnknownPlugin, got %v", err)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	Register(Info{Name: "test-twice", New: func() Plugin { return nopPlugin{} }})
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic")
+		}
+	}()
+	Register(Info{Name: "test-twice", New: func() Plugin { return nopPlugin{} }})
+}
+
+func TestEventTypeString(t *testing.T) {
+	for typ, name := range map[EventType]string{
+		Connect:      "connect",
+		Disconnect:   "disconnect",
+		Message:      "message",
+		ChannelJoin:  "channel_join",
+		TalkingStart: "talking_start",
+		TalkingStop:  "talking_stop",
+	} {
+		if typ.String() != name {
+			t.Errorf("Expected %v, got %v", name, typ)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package proxyproto implements the receiving side of the HAProxy
+// PROXY protocol, versions 1 and 2.
+//
+// A load balancer that speaks the PROXY protocol sends a header with
+// the original client's address before any other data. A Listener
+// reads and strips that header, and reports the original address as
+// the connection's remote address.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The signature that starts a version 2 header.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	// The longest possible version 1 header, including the CRLF.
+	v1MaxLength = 107
+
+	// The default time allowed for a client to send its header.
+	DefaultHeaderTimeout = 5 * time.Second
+)
+
+var errClosed = errors.New("proxyproto: listener closed")
+
+// A Listener accepts connections that start with a PROXY protocol header.
+// Connections without a valid header are closed.
+type Listener struct {
+	listener net.Listener
+
+	// Networks that are allowed to send PROXY headers. If empty,
+	// connections from all addresses are rejected, since any peer
+	// could otherwise choose the address it is seen as.
+	Trusted []*net.IPNet
+
+	// The time allowed for a client to send its header.
+	HeaderTimeout time.Duration
+
+	// Logger for rejected connections. If nil, they are not logged.
+	ErrorLog *log.Logger
+
+	conns     chan net.Conn
+	err       error
+	done      chan struct{}
+	closeOnce sync.Once
+	startOnce sync.Once
+}
+
+// NewListener wraps l in a Listener.
+func NewListener(l net.Listener) *Listener {
+	return &Listener{
+		listener:      l,
+		HeaderTimeout: DefaultHeaderTimeout,
+		conns:         make(chan net.Conn),
+		done:          make(chan struct{}),
+	}
+}
+
+// Accept waits for the next connection with a valid header.
+// Headers are read concurrently, so that a slow client cannot
+// block the connections of others.
+func (l *Listener) Accept() (net.Conn, error) {
+	l.startOnce.Do(func() { go l.acceptLoop() })
+	select {
+	case conn, ok := <-l.conns:
+		if !ok {
+			return nil, l.err
+		}
+		return conn, nil
+	case <-l.done:
+		return nil, errClosed
+	}
+}
+
+// Close closes the underlying listener.
+func (l *Listener) Close() error {
+	err := l.listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+// Addr returns the underlying listener's address.
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+func (l *Listener) acceptLoop() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+			l.err = err
+			close(l.conns)
+			return
+		}
+		go l.handshake(conn)
+	}
+}
+
+// handshake reads the header of a new connection, and hands the
+// connection to Accept.
+func (l *Listener) handshake(conn net.Conn) {
+	if !l.isTrusted(conn.RemoteAddr()) {
+		l.logf("proxyproto: rejected connection from untrusted address %v", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+
+	if l.HeaderTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(l.HeaderTimeout))
+	}
+	pc := &Conn{Conn: conn, reader: bufio.NewReader(conn)}
+	err := pc.readHeader()
+	if err != nil {
+		l.logf("proxyproto: rejected connection from %v: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	select {
+	case l.conns <- pc:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	tcpaddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range l.Trusted {
+		if n.Contains(tcpaddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Listener) logf(format string, args ...interface{}) {
+	if l.ErrorLog != nil {
+		l.ErrorLog.Printf(format, args...)
+	}
+}
+
+// A Conn is a connection whose PROXY header has been read.
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+
+	// The addresses given in the header. They are nil if the
+	// header did not carry addresses, such as for health checks.
+	source      *net.TCPAddr
+	destination *net.TCPAddr
+}
+
+// Read reads data following the header.
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the original client's address, as given in the header.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.source != nil {
+		return c.source
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the original client connected to,
+// as given in the header.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.destination != nil {
+		return c.destination
+	}
+	return c.Conn.LocalAddr()
+}
+
+// ProxyAddr returns the address of the proxy.
+func (c *Conn) ProxyAddr() net.Addr {
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) readHeader() error {
+	sig, err := c.reader.Peek(len(v2Signature))
+	if err == nil && bytes.Equal(sig, v2Signature) {
+		return c.readV2Header()
+	}
+	if len(sig) >= 6 && string(sig[:6]) == "PROXY " {
+		return c.readV1Header()
+	}
+	if err != nil {
+		return err
+	}
+	return errors.New("missing PROXY protocol header")
+}
+
+// readV1Header reads a human-readable version 1 header, such as
+//
+//	PROXY TCP4 192.0.2.1 198.51.100.1 56324 64738\r\n
+func (c *Conn) readV1Header() error {
+	var line []byte
+	for {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= v1MaxLength {
+			return errors.New("PROXY v1 header too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return errors.New("PROXY v1 header not terminated by CRLF")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return fmt.Errorf("malformed PROXY v1 header %q", line)
+	}
+	src, err := parseV1Addr(fields[2], fields[4], fields[1] == "TCP4")
+	if err != nil {
+		return err
+	}
+	dst, err := parseV1Addr(fields[3], fields[5], fields[1] == "TCP4")
+	if err != nil {
+		return err
+	}
+	c.source, c.destination = src, dst
+	return nil
+}
+
+func parseV1Addr(host, port string, v4 bool) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil || (ip.To4() != nil) != v4 {
+		return nil, fmt.Errorf("invalid address %q in PROXY v1 header", host)
+	}
+	// Leading zeroes are not allowed.
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || (len(port) > 1 && port[0] == '0') {
+		return nil, fmt.Errorf("invalid port %q in PROXY v1 header", port)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// readV2Header reads a binary version 2 header.
+func (c *Conn) readV2Header() error {
+	hdr := make([]byte, 16)
+	_, err := io.ReadFull(c.reader, hdr)
+	if err != nil {
+		return err
+	}
+	if hdr[12]>>4 != 2 {
+		return fmt.Errorf("unsupported PROXY protocol version %v", hdr[12]>>4)
+	}
+	command := hdr[12] & 0xf
+	family := hdr[13]
+	length := int(binary.BigEndian.Uint16(hdr[14:16]))
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.reader, body)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case 0x0:
+		// LOCAL: the connection was made by the proxy itself.
+		return nil
+	case 0x1:
+		// PROXY
+	default:
+		return fmt.Errorf("unsupported PROXY v2 command %v", command)
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if length < 12 {
+			return errors.New("short PROXY v2 address block")
+		}
+		c.source = &net.TCPAddr{IP: net.IP(append([]byte(nil), body[0:4]...)), Port: int(binary.BigEndian.Uint16(body[8:10]))}
+		c.destination = &net.TCPAddr{IP: net.IP(append([]byte(nil), body[4:8]...)), Port: int(binary.BigEndian.Uint16(body[10:12]))}
+	case 0x21: // TCP over IPv6
+		if length < 36 {
+			return errors.New("short PROXY v2 address block")
+		}
+		c.source = &net.TCPAddr{IP: net.IP(append([]byte(nil), body[0:16]...)), Port: int(binary.BigEndian.Uint16(body[32:34]))}
+		c.destination = &net.TCPAddr{IP: net.IP(append([]byte(nil), body[16:32]...)), Port: int(binary.BigEndian.Uint16(body[34:36]))}
+	default:
+		// Unspecified, UDP or UNIX socket addresses are of
+		// no use to us; keep the proxy's address.
+	}
+	return nil
+}
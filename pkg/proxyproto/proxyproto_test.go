@@ -0,0 +1,192 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parse runs readHeader on input, and returns the resulting
+// connection along with the data that follows the header.
+func parse(t *testing.T, input []byte) (*Conn, string, error) {
+	client, server := net.Pipe()
+	go func() {
+		client.Write(input)
+		client.Close()
+	}()
+	c := &Conn{Conn: server, reader: bufio.NewReader(server)}
+	err := c.readHeader()
+	if err != nil {
+		return nil, "", err
+	}
+	rest, _ := ioutil.ReadAll(c)
+	return c, string(rest), nil
+}
+
+func v2Header(command, family byte, addrs []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(v2Signature)
+	buf.WriteByte(0x20 | command)
+	buf.WriteByte(family)
+	binary.Write(buf, binary.BigEndian, uint16(len(addrs)))
+	buf.Write(addrs)
+	return buf.Bytes()
+}
+
+func TestV1(t *testing.T) {
+	c, rest, err := parse(t, []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 64738\r\nhello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RemoteAddr().String() != "192.0.2.1:56324" {
+		t.Errorf("Unexpected remote address %v", c.RemoteAddr())
+	}
+	if c.LocalAddr().String() != "198.51.100.1:64738" {
+		t.Errorf("Unexpected local address %v", c.LocalAddr())
+	}
+	if rest != "hello" {
+		t.Errorf("Unexpected data after header %q", rest)
+	}
+
+	c, _, err = parse(t, []byte("PROXY TCP6 2001:db8::1 2001:db8::2 1 2\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RemoteAddr().String() != "[2001:db8::1]:1" {
+		t.Errorf("Unexpected remote address %v", c.RemoteAddr())
+	}
+
+	// UNKNOWN keeps the proxy's address.
+	c, _, err = parse(t, []byte("PROXY UNKNOWN\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.source != nil {
+		t.Errorf("Unexpected source address for UNKNOWN")
+	}
+}
+
+func TestV2(t *testing.T) {
+	addrs := []byte{192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0xfb, 0x62}
+	// A TLV after the addresses must be skipped.
+	addrs = append(addrs, 0x04, 0x00, 0x01, 0xff)
+	c, rest, err := parse(t, append(v2Header(1, 0x11, addrs), "hello"...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RemoteAddr().String() != "192.0.2.1:56324" {
+		t.Errorf("Unexpected remote address %v", c.RemoteAddr())
+	}
+	if rest != "hello" {
+		t.Errorf("Unexpected data after header %q", rest)
+	}
+
+	addrs6 := make([]byte, 36)
+	copy(addrs6, net.ParseIP("2001:db8::1"))
+	copy(addrs6[16:], net.ParseIP("2001:db8::2"))
+	binary.BigEndian.PutUint16(addrs6[32:], 1234)
+	binary.BigEndian.PutUint16(addrs6[34:], 64738)
+	c, _, err = parse(t, v2Header(1, 0x21, addrs6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RemoteAddr().String() != "[2001:db8::1]:1234" {
+		t.Errorf("Unexpected remote address %v", c.RemoteAddr())
+	}
+
+	// LOCAL connections keep the proxy's address.
+	c, _, err = parse(t, v2Header(0, 0x00, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.source != nil {
+		t.Errorf("Unexpected source address for LOCAL")
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	for _, input := range [][]byte{
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+		[]byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324\r\n"),
+		[]byte("PROXY TCP4 2001:db8::1 198.51.100.1 1 2\r\n"),
+		[]byte("PROXY TCP4 192.0.2.1 198.51.100.1 01 2\r\n"),
+		[]byte("PROXY TCP4 192.0.2.1 198.51.100.1 1 2\n"),
+		[]byte("PROXY " + strings.Repeat("x", 200)),
+		v2Header(1, 0x11, []byte{1, 2, 3}),
+		v2Header(2, 0x11, nil),
+	} {
+		if _, _, err := parse(t, input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewListener(raw)
+	defer l.Close()
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	l.Trusted = []*net.IPNet{loopback}
+	l.HeaderTimeout = 500 * time.Millisecond
+
+	// A client that never sends its header must not block others.
+	slow, err := net.Dial("tcp", raw.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+
+	conn, err := net.Dial("tcp", raw.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("PROXY TCP4 192.0.2.1 127.0.0.1 56324 64738\r\n"))
+
+	accepted, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer accepted.Close()
+	if accepted.RemoteAddr().String() != "192.0.2.1:56324" {
+		t.Errorf("Unexpected remote address %v", accepted.RemoteAddr())
+	}
+	if accepted.(*Conn).ProxyAddr().String() != conn.LocalAddr().String() {
+		t.Errorf("Unexpected proxy address %v", accepted.(*Conn).ProxyAddr())
+	}
+}
+
+func TestListenerUntrusted(t *testing.T) {
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewListener(raw)
+	defer l.Close()
+	go l.Accept()
+
+	// Without trusted networks, no one may send a header.
+	conn, err := net.Dial("tcp", raw.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("PROXY TCP4 192.0.2.1 127.0.0.1 56324 64738\r\n"))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the connection to be closed, got %v", err)
+	}
+}
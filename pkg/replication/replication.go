@@ -0,0 +1,194 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package replication implements the protocol that streams the state
+// of a Grumble instance's virtual servers to a standby instance.
+//
+// The primary sends a Hello, and then, for each of its servers, a
+// snapshot of the server's state: the contents of its main.fz. Once it
+// has sent all of them, it sends a Synced frame. From then on, each
+// transaction record written to a server's log.fz is sent as a Log
+// frame, and a new snapshot is sent each time the server's state is
+// written to main.fz again. Ping frames are sent when nothing else is,
+// so that the standby can tell a quiet primary from one that is gone.
+//
+// A Store writes the frames to the standby's server directories, in the
+// layout the primary uses, so that the standby can load the servers as
+// its own once it takes over.
+package replication
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Version is the version of the protocol.
+const Version = 1
+
+// MaxFrameSize is the largest payload of a frame.
+const MaxFrameSize = 64 << 20
+
+// The types of frames.
+const (
+	// A Hello (JSON) opens the stream.
+	FrameHello byte = iota + 1
+	// A snapshot of a server's state, as written to main.fz.
+	FrameSnapshot
+	// A transaction record, as written to a server's log.fz.
+	FrameLog
+	// All servers' snapshots have been sent.
+	FrameSynced
+	// Nothing happened.
+	FramePing
+)
+
+// ErrFrameTooLarge is returned for frames larger than MaxFrameSize.
+var ErrFrameTooLarge = errors.New("replication: frame too large")
+
+// Hello opens the stream. Servers holds the ids of the primary's
+// servers.
+type Hello struct {
+	Version int     `json:"version"`
+	Servers []int64 `json:"servers"`
+}
+
+// WriteFrame writes a frame of the given type for the server with the
+// given id to w: its type, the server id as a 64-bit big-endian
+// integer, the length of its payload as a 32-bit big-endian integer,
+// and the payload.
+func WriteFrame(w io.Writer, typ byte, id int64, payload []byte) error {
+	if len(payload) > MaxFrameSize {
+		return ErrFrameTooLarge
+	}
+	buf := make([]byte, 13+len(payload))
+	buf[0] = typ
+	binary.BigEndian.PutUint64(buf[1:9], uint64(id))
+	binary.BigEndian.PutUint32(buf[9:13], uint32(len(payload)))
+	copy(buf[13:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+// WriteJSON writes a frame of the given type with v as its payload.
+func WriteJSON(w io.Writer, typ byte, id int64, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return WriteFrame(w, typ, id, payload)
+}
+
+// ReadFrame reads a frame from r.
+func ReadFrame(r io.Reader) (typ byte, id int64, payload []byte, err error) {
+	var header [13]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[9:])
+	if size > MaxFrameSize {
+		return 0, 0, nil, ErrFrameTooLarge
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, nil, err
+	}
+	return header[0], int64(binary.BigEndian.Uint64(header[1:9])), payload, nil
+}
+
+// A Store writes the state streamed by a primary to the directories of
+// the standby's servers.
+type Store struct {
+	dir  func(id int64) string
+	logs map[int64]*os.File
+}
+
+// NewStore returns a Store that writes the state of each server to the
+// directory returned by dir.
+func NewStore(dir func(id int64) string) *Store {
+	return &Store{
+		dir:  dir,
+		logs: map[int64]*os.File{},
+	}
+}
+
+// Snapshot replaces the state of the server with the given id by
+// snapshot. The previous snapshot is kept as backup.fz, and the log is
+// emptied.
+func (s *Store) Snapshot(id int64, snapshot []byte) error {
+	dir := s.dir(id)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	if f, ok := s.logs[id]; ok {
+		f.Close()
+		delete(s.logs, id)
+	}
+
+	f, err := ioutil.TempFile(dir, ".main.fz_")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(snapshot)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	dst := filepath.Join(dir, "main.fz")
+	backup := filepath.Join(dir, "backup.fz")
+	os.Remove(backup)
+	if err := os.Link(dst, backup); err != nil && !os.IsNotExist(err) {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), dst); err != nil {
+		return err
+	}
+
+	log, err := os.Create(filepath.Join(dir, "log.fz"))
+	if err != nil {
+		return err
+	}
+	s.logs[id] = log
+	return nil
+}
+
+// Append appends a transaction record to the log of the server with
+// the given id, whose snapshot must have been written first.
+func (s *Store) Append(id int64, record []byte) error {
+	f, ok := s.logs[id]
+	if !ok {
+		return errors.New("replication: log record before snapshot")
+	}
+	if _, err := f.Write(record); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Close closes the logs of all servers.
+func (s *Store) Close() error {
+	var err error
+	for id, f := range s.logs {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		delete(s.logs, id)
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package replication
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, FrameHello, 0, Hello{Version: Version, Servers: []int64{1, 7}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFrame(&buf, FrameLog, 7, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, id, payload, err := ReadFrame(&buf)
+	if err != nil || typ != FrameHello || id != 0 {
+		t.Fatalf("Unexpected frame %v for %v: %v", typ, id, err)
+	}
+	if string(payload) != `{"version":1,"servers":[1,7]}` {
+		t.Errorf("Unexpected hello %s", payload)
+	}
+	typ, id, payload, err = ReadFrame(&buf)
+	if err != nil || typ != FrameLog || id != 7 || !bytes.Equal(payload, []byte{1, 2, 3}) {
+		t.Fatalf("Unexpected frame %v for %v: %v %v", typ, id, payload, err)
+	}
+	if _, _, _, err := ReadFrame(&buf); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+
+	if err := WriteFrame(&buf, FrameLog, 1, make([]byte, MaxFrameSize+1)); err != ErrFrameTooLarge {
+		t.Errorf("Expected ErrFrameTooLarge, got %v", err)
+	}
+	header := []byte{FrameLog, 0, 0, 0, 0, 0, 0, 0, 1}
+	if _, _, _, err := ReadFrame(bytes.NewReader(append(header, 0xff, 0xff, 0xff, 0xff))); err != ErrFrameTooLarge {
+		t.Errorf("Expected ErrFrameTooLarge, got %v", err)
+	}
+	if _, _, _, err := ReadFrame(bytes.NewReader(append(header, 0, 0, 0, 4, 'a'))); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func readFile(t *testing.T, fn string) string {
+	buf, err := ioutil.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+func TestStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "replication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "3")
+
+	s := NewStore(func(id int64) string {
+		return filepath.Join(root, strconv.FormatInt(id, 10))
+	})
+	defer s.Close()
+
+	if err := s.Append(3, []byte("tx")); err == nil {
+		t.Errorf("Expected error for a record before the snapshot")
+	}
+	if err := s.Snapshot(3, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(3, []byte("tx1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(3, []byte("tx2")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "main.fz")); got != "first" {
+		t.Errorf("Unexpected main.fz %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "log.fz")); got != "tx1tx2" {
+		t.Errorf("Unexpected log.fz %q", got)
+	}
+
+	if err := s.Snapshot(3, []byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, filepath.Join(dir, "main.fz")); got != "second" {
+		t.Errorf("Unexpected main.fz %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "backup.fz")); got != "first" {
+		t.Errorf("Unexpected backup.fz %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "log.fz")); got != "" {
+		t.Errorf("Expected an empty log.fz, got %q", got)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(3, []byte("tx3")); err == nil {
+		t.Errorf("Expected error for a record after Close")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package scripting runs Lua scripts that react to server events.
+//
+// Scripts run in a sandbox: only the base, table, string and math
+// libraries are available, without functions that load code from
+// files. Scripts act on the server through the grumble table:
+//
+//	grumble.on(event, fn)                  -- subscribe to an event
+//	grumble.send_message(session, text)    -- message a user
+//	grumble.send_channel_message(id, text) -- message a channel
+//	grumble.move_user(session, channel)
+//	grumble.set_mute(session, muted)
+//	grumble.users()                        -- list connected users
+//	grumble.log(text)
+//
+// Users are passed to scripts as tables with the fields session, name,
+// user_id (-1 if unregistered) and channel.
+package scripting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// The time a script may run for each event, or when it is loaded.
+const DefaultTimeout = 100 * time.Millisecond
+
+// A User is a connected user, as seen by scripts.
+type User struct {
+	Session uint32
+	Name    string
+	UserId  int
+	Channel int
+}
+
+// A Host carries out the actions requested by scripts.
+type Host interface {
+	SendMessage(session uint32, text string) error
+	SendChannelMessage(channel int, text string) error
+	MoveUser(session uint32, channel int) error
+	SetMute(session uint32, mute bool) error
+	Users() []User
+	Log(text string)
+}
+
+// A Script is a loaded Lua script.
+type Script struct {
+	Name    string
+	Timeout time.Duration
+
+	host     Host
+	state    *lua.LState
+	handlers map[string][]*lua.LFunction
+}
+
+// Load reads and runs the script in fn. The script's top level code
+// is expected to subscribe to events using grumble.on.
+func Load(name, fn string, host Host) (*Script, error) {
+	return load(name, host, func(L *lua.LState) error {
+		return L.DoFile(fn)
+	})
+}
+
+// LoadString runs the script given in source.
+func LoadString(name, source string, host Host) (*Script, error) {
+	return load(name, host, func(L *lua.LState) error {
+		return L.DoString(source)
+	})
+}
+
+func load(name string, host Host, run func(L *lua.LState) error) (*Script, error) {
+	script := &Script{
+		Name:     name,
+		Timeout:  DefaultTimeout,
+		host:     host,
+		state:    lua.NewState(lua.Options{SkipOpenLibs: true}),
+		handlers: make(map[string][]*lua.LFunction),
+	}
+	script.openLibs()
+
+	ctx, cancel := context.WithTimeout(context.Background(), script.Timeout)
+	defer cancel()
+	script.state.SetContext(ctx)
+	err := run(script.state)
+	script.state.RemoveContext()
+	if err != nil {
+		script.state.Close()
+		return nil, err
+	}
+	return script, nil
+}
+
+// Close releases the script's resources.
+func (script *Script) Close() {
+	script.state.Close()
+}
+
+// openLibs sets up the sandboxed environment.
+func (script *Script) openLibs() {
+	L := script.state
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "module", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	L.SetGlobal("print", L.NewFunction(script.luaLog))
+
+	L.SetGlobal("grumble", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"on":                   script.luaOn,
+		"send_message":         script.luaSendMessage,
+		"send_channel_message": script.luaSendChannelMessage,
+		"move_user":            script.luaMoveUser,
+		"set_mute":             script.luaSetMute,
+		"users":                script.luaUsers,
+		"log":                  script.luaLog,
+	}))
+}
+
+// Emit calls the script's handlers for event. The user and any extra
+// arguments (strings, ints, float64s and bools) are passed to the
+// handlers.
+// Errors are reported to the host's log, and do not stop other handlers.
+func (script *Script) Emit(event string, user User, args ...interface{}) {
+	handlers := script.handlers[event]
+	if len(handlers) == 0 {
+		return
+	}
+
+	L := script.state
+	largs := []lua.LValue{script.userTable(user)}
+	for _, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			largs = append(largs, lua.LString(v))
+		case int:
+			largs = append(largs, lua.LNumber(v))
+		case float64:
+			largs = append(largs, lua.LNumber(v))
+		case bool:
+			largs = append(largs, lua.LBool(v))
+		default:
+			largs = append(largs, lua.LNil)
+		}
+	}
+
+	for _, fn := range handlers {
+		ctx, cancel := context.WithTimeout(context.Background(), script.Timeout)
+		L.SetContext(ctx)
+		err := L.CallByParam(lua.P{Fn: fn, NRet: 0, Protect: true}, largs...)
+		L.RemoveContext()
+		cancel()
+		if err != nil {
+			script.host.Log(fmt.Sprintf("error in %v handler: %v", event, err))
+		}
+	}
+}
+
+func (script *Script) userTable(user User) *lua.LTable {
+	t := script.state.NewTable()
+	t.RawSetString("session", lua.LNumber(user.Session))
+	t.RawSetString("name", lua.LString(user.Name))
+	t.RawSetString("user_id", lua.LNumber(user.UserId))
+	t.RawSetString("channel", lua.LNumber(user.Channel))
+	return t
+}
+
+// check raises a Lua error if err is set.
+func check(L *lua.LState, err error) int {
+	if err != nil {
+		L.RaiseError("%v", err)
+	}
+	return 0
+}
+
+func (script *Script) luaOn(L *lua.LState) int {
+	event := L.CheckString(1)
+	fn := L.CheckFunction(2)
+	script.handlers[event] = append(script.handlers[event], fn)
+	return 0
+}
+
+func (script *Script) luaSendMessage(L *lua.LState) int {
+	return check(L, script.host.SendMessage(uint32(L.CheckInt64(1)), L.CheckString(2)))
+}
+
+func (script *Script) luaSendChannelMessage(L *lua.LState) int {
+	return check(L, script.host.SendChannelMessage(L.CheckInt(1), L.CheckString(2)))
+}
+
+func (script *Script) luaMoveUser(L *lua.LState) int {
+	return check(L, script.host.MoveUser(uint32(L.CheckInt64(1)), L.CheckInt(2)))
+}
+
+func (script *Script) luaSetMute(L *lua.LState) int {
+	return check(L, script.host.SetMute(uint32(L.CheckInt64(1)), L.CheckBool(2)))
+}
+
+func (script *Script) luaUsers(L *lua.LState) int {
+	t := L.NewTable()
+	for _, user := range script.host.Users() {
+		t.Append(script.userTable(user))
+	}
+	L.Push(t)
+	return 1
+}
+
+func (script *Script) luaLog(L *lua.LState) int {
+	text := ""
+	for i := 1; i <= L.GetTop(); i++ {
+		if i > 1 {
+			text += " "
+		}
+		text += L.ToStringMeta(L.Get(i)).String()
+	}
+	script.host.Log(text)
+	return 0
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testHost struct {
+	actions []string
+	logs    []string
+}
+
+func (h *testHost) SendMessage(session uint32, text string) error {
+	h.actions = append(h.actions, fmt.Sprintf("message %v %v", session, text))
+	return nil
+}
+
+func (h *testHost) SendChannelMessage(channel int, text string) error {
+	h.actions = append(h.actions, fmt.Sprintf("channel message %v %v", channel, text))
+	return nil
+}
+
+func (h *testHost) MoveUser(session uint32, channel int) error {
+	if channel == 99 {
+		return errors.New("no such channel")
+	}
+	h.actions = append(h.actions, fmt.Sprintf("move %v %v", session, channel))
+	return nil
+}
+
+func (h *testHost) SetMute(session uint32, mute bool) error {
+	h.actions = append(h.actions, fmt.Sprintf("mute %v %v", session, mute))
+	return nil
+}
+
+func (h *testHost) Users() []User {
+	return []User{{Session: 1, Name: "alice", UserId: 3}, {Session: 2, Name: "bob", UserId: -1}}
+}
+
+func (h *testHost) Log(text string) {
+	h.logs = append(h.logs, text)
+}
+
+func TestEvents(t *testing.T) {
+	host := &testHost{}
+	script, err := LoadString("greeter", `
+		grumble.on("connect", function(user)
+			grumble.send_message(user.session, "Welcome, " .. user.name .. "!")
+			print("greeted", user.name, #grumble.users())
+		end)
+		grumble.on("message", function(user, text)
+			if text == "!afk" then
+				grumble.move_user(user.session, 5)
+				grumble.set_mute(user.session, true)
+			end
+		end)
+		grumble.on("talking_stop", function(user, seconds)
+			if seconds > 60 then
+				grumble.send_message(user.session, "Breathe!")
+			end
+		end)
+	`, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer script.Close()
+
+	script.Emit("connect", User{Session: 7, Name: "carol", UserId: -1})
+	script.Emit("message", User{Session: 7, Name: "carol"}, "hello")
+	script.Emit("message", User{Session: 7, Name: "carol"}, "!afk")
+	script.Emit("talking_stop", User{Session: 7, Name: "carol"}, 2.5)
+	script.Emit("talking_stop", User{Session: 7, Name: "carol"}, 61.5)
+	script.Emit("disconnect", User{Session: 7, Name: "carol"})
+
+	want := "message 7 Welcome, carol!|move 7 5|mute 7 true|message 7 Breathe!"
+	if got := strings.Join(host.actions, "|"); got != want {
+		t.Errorf("Unexpected actions %q, want %q", got, want)
+	}
+	if len(host.logs) != 1 || host.logs[0] != "greeted carol 2" {
+		t.Errorf("Unexpected log %q", host.logs)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	host := &testHost{}
+	script, err := LoadString("broken", `
+		grumble.on("message", function(user, text) grumble.move_user(user.session, 99) end)
+		grumble.on("message", function(user, text) grumble.send_message(user.session, "still here") end)
+	`, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer script.Close()
+
+	// A failing handler does not stop the others.
+	script.Emit("message", User{Session: 1}, "x")
+	if len(host.logs) != 1 || !strings.Contains(host.logs[0], "no such channel") {
+		t.Errorf("Unexpected log %q", host.logs)
+	}
+	if len(host.actions) != 1 {
+		t.Errorf("Unexpected actions %q", host.actions)
+	}
+
+	if _, err := LoadString("syntax", `grumble.on(`, host); err == nil {
+		t.Errorf("Expected syntax error")
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	host := &testHost{}
+	for _, source := range []string{
+		`os.exit(1)`,
+		`io.open("/etc/passwd")`,
+		`dofile("/etc/passwd")`,
+		`require("os")`,
+		`loadstring("return 1")()`,
+	} {
+		if script, err := LoadString("sandbox", source, host); err == nil {
+			script.Close()
+			t.Errorf("Expected error for %q", source)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	host := &testHost{}
+	start := time.Now()
+	if _, err := LoadString("loop", `while true do end`, host); err == nil {
+		t.Errorf("Expected error for endless loop")
+	}
+
+	script, err := LoadString("loop", `grumble.on("connect", function() while true do end end)`, host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer script.Close()
+	script.Emit("connect", User{})
+	if len(host.logs) != 1 {
+		t.Errorf("Expected endless loop to be reported, got %q", host.logs)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Timeout took too long")
+	}
+}
@@ -15,16 +15,53 @@ var defaultCfg = map[string]string{
 	"MaxUsersPerChannel":    "0",
 	"MaxTextMessageLength":  "5000",
 	"MaxImageMessageLength": "131072",
+	"MaxMessageSize":        "1048576",
+	"MessageLimit":          "1",
+	"MessageBurst":          "5",
+	"MessageFloodKick":      "20",
+	"ControlMessageLimit":   "20",
+	"ControlMessageBurst":   "100",
 	"AllowHTML":             "true",
 	"DefaultChannel":        "0",
 	"RememberChannel":       "true",
 	"WelcomeText":           "Welcome to this server running <b>Grumble</b>.",
 	"SendVersion":           "true",
+	"ServerRelease":         "Grumble",
+	"EnrollOIDCUserClaim":   "preferred_username",
+	"DiscordMessageLimit":   "1",
+	"DiscordMessageBurst":   "5",
+	"MQTTTopicPrefix":       "grumble",
+	"PasswordHashTime":      "3",
+	"PasswordHashMemory":    "65536",
+	"PasswordHashThreads":   "2",
+	"CertRecheckInterval":   "3600",
+	"CertRotation":          "true",
+	"OfflineMessageQuota":   "20",
+	"OfflineMessageExpiry":  "30",
+	"AuditLogRetention":     "365",
+	"CryptRekeyInterval":    "3600",
+	"UDPTimeout":            "30",
+	"UDPSockets":            "1",
+	"VersionTimeout":        "10",
+	"AuthTimeout":           "30",
+	"UDPPingRate":           "500",
+	"UDPPingHostRate":       "5",
+	"ChannelSyncBatch":      "256",
+	"ChannelCreateQuota":    "3",
+	"ShutdownMessage":       "The server is shutting down.",
+	"RestartMessage":        "The server is restarting. Please reconnect in a moment.",
+	"MaintenanceMessage":    "The server is down for maintenance. Please try again later.",
+	"ClientDenylistMessage": "This version of Mumble isn't supported here. Please upgrade: https://www.mumble.info/downloads/",
+
+	"DirectorySyncInterval":     "900",
+	"DirectoryGroupFilter":      "(|(objectClass=groupOfNames)(objectClass=groupOfUniqueNames)(objectClass=posixGroup))",
+	"DirectoryMemberAttributes": "member,uniqueMember,memberUid",
 }
 
 type Config struct {
-	cfgMap map[string]string
-	mutex  sync.RWMutex
+	cfgMap  map[string]string
+	fileMap map[string]string
+	mutex   sync.RWMutex
 }
 
 // Create a new Config using cfgMap as the intial internal config map.
@@ -48,6 +85,15 @@ func (cfg *Config) GetAll() (all map[string]string) {
 	return
 }
 
+// SetFileValues replaces the values read from the configuration file.
+// File values take precedence over the built-in defaults, but not over
+// values set on the Config itself.
+func (cfg *Config) SetFileValues(values map[string]string) {
+	cfg.mutex.Lock()
+	defer cfg.mutex.Unlock()
+	cfg.fileMap = values
+}
+
 // Set a new value for a config key
 func (cfg *Config) Set(key string, value string) {
 	cfg.mutex.Lock()
@@ -55,6 +101,15 @@ func (cfg *Config) Set(key string, value string) {
 	cfg.cfgMap[key] = value
 }
 
+// IsSet reports whether key has a value set on the Config itself,
+// rather than one from the configuration file or the defaults.
+func (cfg *Config) IsSet(key string) bool {
+	cfg.mutex.RLock()
+	defer cfg.mutex.RUnlock()
+	_, exists := cfg.cfgMap[key]
+	return exists
+}
+
 // Reset the value of a config key
 func (cfg *Config) Reset(key string) {
 	cfg.mutex.Lock()
@@ -72,6 +127,11 @@ func (cfg *Config) StringValue(key string) (value string) {
 		return value
 	}
 
+	value, exists = cfg.fileMap[key]
+	if exists {
+		return value
+	}
+
 	value, exists = defaultCfg[key]
 	if exists {
 		return value
@@ -5,6 +5,7 @@
 package serverconf
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -38,3 +39,42 @@ func TestBoolValue(t *testing.T) {
 		t.Errorf("Expected true")
 	}
 }
+
+func TestFileValues(t *testing.T) {
+	cf, err := ReadFile(strings.NewReader(`
+# Global values
+WelcomeText = Hello, world
+MaxUsers = 50
+
+[server 2]
+MaxUsers = 10
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := New(nil)
+	cfg.SetFileValues(cf.ValuesForServer(2))
+	if cfg.StringValue("WelcomeText") != "Hello, world" {
+		t.Errorf("Expected global file value")
+	}
+	if cfg.IntValue("MaxUsers") != 10 {
+		t.Errorf("Expected per-server file value to override global value")
+	}
+	if cfg.IntValue("MaxBandwidth") != 72000 {
+		t.Errorf("Expected default value")
+	}
+
+	cfg.Set("MaxUsers", "5")
+	if cfg.IntValue("MaxUsers") != 5 {
+		t.Errorf("Expected set value to override file value")
+	}
+}
+
+func TestFileErrors(t *testing.T) {
+	for _, input := range []string{"NoEquals", "[server x]", "[channel 1]", " = 3"} {
+		if _, err := ReadFile(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
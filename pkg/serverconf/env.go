@@ -0,0 +1,119 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables that hold
+// configuration values.
+const EnvPrefix = "GRUMBLE_"
+
+// NormalizeEnvName returns name in the form used to match environment
+// variables against configuration keys: in upper case, and without
+// underscores or dashes. Both GRUMBLE_MAX_USERS and GRUMBLE_MAXUSERS
+// thus set MaxUsers.
+func NormalizeEnvName(name string) string {
+	name = strings.Replace(name, "_", "", -1)
+	name = strings.Replace(name, "-", "", -1)
+	return strings.ToUpper(name)
+}
+
+// envKeys maps the normalized names of the known keys to the keys.
+func envKeys() map[string]string {
+	keys := make(map[string]string, len(schema))
+	for key := range schema {
+		keys[NormalizeEnvName(key)] = key
+	}
+	return keys
+}
+
+// splitEnvServer splits the name of a per-server variable, with the
+// prefix removed, such as SERVER2_MAX_USERS, into its server id and
+// key. For any other name, it returns 0 and name.
+func splitEnvServer(name string) (int64, string) {
+	if !strings.HasPrefix(name, "SERVER") {
+		return 0, name
+	}
+	rest := name[len("SERVER"):]
+	sep := strings.Index(rest, "_")
+	if sep < 1 {
+		return 0, name
+	}
+	id, err := strconv.ParseInt(rest[:sep], 10, 64)
+	if err != nil || id < 1 {
+		return 0, name
+	}
+	return id, rest[sep+1:]
+}
+
+// AddEnv adds the configuration values held in environment variables to
+// the file, where environ holds "NAME=value" strings as returned by
+// os.Environ. GRUMBLE_<KEY> sets a key for all virtual servers, and
+// GRUMBLE_SERVER<ID>_<KEY> for the virtual server with id ID only.
+//
+// Values from the environment take precedence over those in the file:
+// a key set for all servers replaces the key in every section of the
+// file. AddEnv returns the names of the variables with the GRUMBLE_
+// prefix that don't name a configuration key, in sorted order.
+func (cf *ConfigFile) AddEnv(environ []string) (unknown []string) {
+	keys := envKeys()
+	var global, perServer []Entry
+	for _, kv := range environ {
+		eq := strings.Index(kv, "=")
+		if eq == -1 || !strings.HasPrefix(kv[:eq], EnvPrefix) {
+			continue
+		}
+		name, value := kv[:eq], kv[eq+1:]
+		server, rest := splitEnvServer(name[len(EnvPrefix):])
+		key, ok := keys[NormalizeEnvName(rest)]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		e := Entry{Server: server, Key: key, Value: value, Env: name}
+		if server == 0 {
+			global = append(global, e)
+		} else {
+			perServer = append(perServer, e)
+		}
+	}
+
+	// Per-server values are added last, so that they take precedence
+	// over the values for all servers.
+	for _, e := range global {
+		for _, section := range cf.Servers {
+			delete(section, e.Key)
+		}
+		cf.Add(e)
+	}
+	for _, e := range perServer {
+		cf.Add(e)
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Source describes where the value of key used by the virtual server
+// with the given id was set: "line N", the name of an environment
+// variable, or "" if it isn't set in the file or the environment.
+func (cf *ConfigFile) Source(id int64, key string) string {
+	server := int64(0)
+	if _, ok := cf.Servers[id][key]; ok {
+		server = id
+	} else if _, ok := cf.Global[key]; !ok {
+		return ""
+	}
+	for i := len(cf.Entries) - 1; i >= 0; i-- {
+		e := cf.Entries[i]
+		if e.Server == server && e.Key == key {
+			return e.where()
+		}
+	}
+	return ""
+}
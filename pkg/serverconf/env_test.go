@@ -0,0 +1,69 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddEnv(t *testing.T) {
+	cf, err := ReadFile(strings.NewReader("MaxUsers = 5\nWelcomeText = Hi\n[server 2]\nMaxUsers = 10\nPort = 64739\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := cf.AddEnv([]string{
+		"HOME=/root",
+		"GRUMBLE_MAX_USERS=20",
+		"GRUMBLE_SERVER2_PORT=64740",
+		"GRUMBLE_SERVER3_ALLOWHTML=false",
+		"GRUMBLE_SERVER_PASSWORD=secret",
+		"GRUMBLE_DATADIR=/data",
+	})
+	if err := cf.Validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	if len(unknown) != 1 || unknown[0] != "GRUMBLE_DATADIR" {
+		t.Errorf("Unexpected unknown variables %v", unknown)
+	}
+
+	expected := map[int64]map[string]string{
+		1: {"MaxUsers": "20", "WelcomeText": "Hi", "ServerPassword": "secret"},
+		2: {"MaxUsers": "20", "Port": "64740"},
+		3: {"MaxUsers": "20", "AllowHTML": "false"},
+	}
+	for id, values := range expected {
+		got := cf.ValuesForServer(id)
+		for k, v := range values {
+			if got[k] != v {
+				t.Errorf("Server %v: expected %v = %q, got %q", id, k, v, got[k])
+			}
+		}
+	}
+
+	sources := map[string]string{
+		"MaxUsers":    "GRUMBLE_MAX_USERS",
+		"WelcomeText": "line 2",
+		"Port":        "GRUMBLE_SERVER2_PORT",
+		"AllowHTML":   "",
+	}
+	for k, v := range sources {
+		if got := cf.Source(2, k); got != v {
+			t.Errorf("Expected source %q for %v, got %q", v, k, got)
+		}
+	}
+	if !IsSecret("ServerPassword") || !IsSecret("SuperUserPassword") || IsSecret("MaxUsers") {
+		t.Errorf("Unexpected secret keys")
+	}
+}
+
+func TestAddEnvInvalid(t *testing.T) {
+	cf := NewConfigFile()
+	cf.AddEnv([]string{"GRUMBLE_PORT=none"})
+	err := cf.Validate()
+	if err == nil || !strings.Contains(err.Error(), "GRUMBLE_PORT:") {
+		t.Errorf("Expected an error naming the variable, got %v", err)
+	}
+}
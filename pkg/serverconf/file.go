@@ -0,0 +1,233 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ValueKind is the type of a value as written in a configuration file.
+type ValueKind int
+
+const (
+	// KindUntyped is used by formats without typed values. Such
+	// values are parsed according to the schema.
+	KindUntyped ValueKind = iota
+	KindString
+	KindInt
+	KindBool
+	KindFloat
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case KindString:
+		return "a string"
+	case KindInt:
+		return "an integer"
+	case KindBool:
+		return "a boolean"
+	case KindFloat:
+		return "a float"
+	}
+	return "an untyped value"
+}
+
+// An Entry is a single value read from a configuration file.
+type Entry struct {
+	// The virtual server the entry applies to, or 0 for all servers.
+	Server int64
+	Key    string
+	Value  string
+	Kind   ValueKind
+	Line   int
+	// The environment variable the entry was read from, if any.
+	Env string
+}
+
+// where describes where the entry was read from, for messages.
+func (e Entry) where() string {
+	if len(e.Env) > 0 {
+		return e.Env
+	}
+	return fmt.Sprintf("line %v", e.Line)
+}
+
+// A ConfigFile holds the configuration values read from a
+// configuration file.
+//
+// Configuration files can be written in TOML (.toml), YAML (.yaml, .yml)
+// or the simple format described below (any other extension).
+//
+// The simple format consists of "Key = Value" lines. Lines starting with
+// '#' or ';' are comments. Keys that appear before any section
+// header apply to all virtual servers. Keys that follow a
+// "[server N]" header only apply to the virtual server with id N:
+//
+//	WelcomeText = Welcome to our server!
+//	MaxBandwidth = 72000
+//
+//	[server 2]
+//	MaxUsers = 10
+//
+// A file named murmur.ini is read as a Murmur configuration file.
+type ConfigFile struct {
+	Global  map[string]string
+	Servers map[int64]map[string]string
+	Entries []Entry
+
+	// Problems that did not prevent the file from being read,
+	// such as unsupported settings in a murmur.ini.
+	Warnings []string
+}
+
+// NewConfigFile creates an empty ConfigFile.
+func NewConfigFile() *ConfigFile {
+	return &ConfigFile{
+		Global:  make(map[string]string),
+		Servers: make(map[int64]map[string]string),
+	}
+}
+
+// LoadFile reads and validates the configuration file fn.
+// The format of the file is determined by its extension.
+func LoadFile(fn string) (*ConfigFile, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var cf *ConfigFile
+	switch strings.ToLower(filepath.Ext(fn)) {
+	case ".ini":
+		if strings.EqualFold(filepath.Base(fn), "murmur.ini") {
+			cf, err = ReadMurmurINI(f)
+		} else {
+			cf, err = ReadFile(f)
+		}
+	case ".toml":
+		cf, err = ReadTOML(f)
+	case ".yaml", ".yml":
+		cf, err = ReadYAML(f)
+	default:
+		cf, err = ReadFile(f)
+	}
+	if err == nil {
+		err = cf.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", fn, err)
+	}
+	return cf, nil
+}
+
+// Add adds an entry to the file.
+func (cf *ConfigFile) Add(e Entry) {
+	cf.Entries = append(cf.Entries, e)
+	if e.Server == 0 {
+		cf.Global[e.Key] = e.Value
+	} else {
+		cf.Server(e.Server)[e.Key] = e.Value
+	}
+}
+
+// Validate checks all entries against the known configuration keys,
+// their types and their allowed ranges. All problems found are reported
+// in a single ValidationError.
+func (cf *ConfigFile) Validate() error {
+	verr := &ValidationError{}
+	for _, e := range cf.Entries {
+		if err := checkEntry(e); err != nil {
+			verr.Problems = append(verr.Problems, err.Error())
+		}
+	}
+	if len(verr.Problems) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// ReadFile reads a configuration file in the simple format from r.
+func ReadFile(r io.Reader) (*ConfigFile, error) {
+	cf := NewConfigFile()
+	var server int64
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %v: malformed section header", lineno)
+			}
+			fields := strings.Fields(line[1 : len(line)-1])
+			if len(fields) != 2 || fields[0] != "server" {
+				return nil, fmt.Errorf("line %v: expected [server <id>]", lineno)
+			}
+			id, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil || id < 1 {
+				return nil, fmt.Errorf("line %v: invalid server id %q", lineno, fields[1])
+			}
+			server = id
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			return nil, fmt.Errorf("line %v: expected Key = Value", lineno)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if len(key) == 0 {
+			return nil, fmt.Errorf("line %v: missing key", lineno)
+		}
+		cf.Add(Entry{
+			Server: server,
+			Key:    key,
+			Value:  strings.TrimSpace(line[eq+1:]),
+			Line:   lineno,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cf, nil
+}
+
+// Server returns the section for the virtual server with the
+// given id, creating it if necessary.
+func (cf *ConfigFile) Server(id int64) map[string]string {
+	section, ok := cf.Servers[id]
+	if !ok {
+		section = make(map[string]string)
+		cf.Servers[id] = section
+	}
+	return section
+}
+
+// ValuesForServer returns the configuration values that apply
+// to the virtual server with the given id.
+func (cf *ConfigFile) ValuesForServer(id int64) map[string]string {
+	values := make(map[string]string)
+	for k, v := range cf.Global {
+		values[k] = v
+	}
+	for k, v := range cf.Servers[id] {
+		values[k] = v
+	}
+	return values
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"strings"
+	"testing"
+)
+
+const tomlConfig = `# Grumble configuration
+WelcomeText = """
+Welcome to <b>our</b> server!\
+ Enjoy."""
+MaxBandwidth = 72_000
+AllowHTML = true
+RegisterName = 'My "quoted" server' # trailing comment
+
+[server.2]
+MaxUsers = 10
+"Port" = 64739
+`
+
+const yamlConfig = `---
+# Grumble configuration
+WelcomeText: |
+  Welcome to <b>our</b> server!
+  Enjoy.
+MaxBandwidth: 72000
+AllowHTML: true
+RegisterName: "My \"quoted\" server" # trailing comment
+
+server:
+  2:
+    MaxUsers: 10
+    'Port': 64739
+`
+
+func checkParsed(t *testing.T, cf *ConfigFile, welcome string) {
+	if err := cf.Validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	values := cf.ValuesForServer(2)
+	expected := map[string]string{
+		"WelcomeText":  welcome,
+		"MaxBandwidth": "72000",
+		"AllowHTML":    "true",
+		"RegisterName": `My "quoted" server`,
+		"MaxUsers":     "10",
+		"Port":         "64739",
+	}
+	for k, v := range expected {
+		if values[k] != v {
+			t.Errorf("%v: expected %q, got %q", k, v, values[k])
+		}
+	}
+	if _, ok := cf.ValuesForServer(1)["MaxUsers"]; ok {
+		t.Errorf("Per-server value leaked to other server")
+	}
+}
+
+func TestReadTOML(t *testing.T) {
+	cf, err := ReadTOML(strings.NewReader(tomlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParsed(t, cf, "Welcome to <b>our</b> server!Enjoy.")
+}
+
+func TestReadYAML(t *testing.T) {
+	cf, err := ReadYAML(strings.NewReader(yamlConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkParsed(t, cf, "Welcome to <b>our</b> server!\nEnjoy.\n")
+}
+
+func TestTOMLLines(t *testing.T) {
+	cf, err := ReadTOML(strings.NewReader("Name = '''\nPort = 1\n'''\nPort = 2\n\n[server.3]\n# comment\nPort = 3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []int{1, 4, 8}
+	if len(cf.Entries) != len(lines) {
+		t.Fatalf("Expected %v entries, got %v", len(lines), cf.Entries)
+	}
+	for i, e := range cf.Entries {
+		if e.Line != lines[i] {
+			t.Errorf("%v: expected line %v, got %v", e.Key, lines[i], e.Line)
+		}
+	}
+}
+
+func TestTOMLSyntaxErrors(t *testing.T) {
+	for _, input := range []string{
+		`WelcomeText = unquoted`,
+		`WelcomeText = "unterminated`,
+		`MaxUsers = [1, 2]`,
+		`[channel.1]`,
+		`[[server]]`,
+		`MaxUsers = 1 2`,
+		`server = 3`,
+		"[server.2.foo]\nMaxUsers = 1",
+		`Started = 1979-05-27T07:32:00Z`,
+	} {
+		if _, err := ReadTOML(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestYAMLSyntaxErrors(t *testing.T) {
+	for _, input := range []string{
+		"MaxUsers:\n  - 1\n",
+		"MaxUsers: [1, 2]\n",
+		"server:\n  foo:\n    MaxUsers: 1\n",
+		"server: 3\n",
+		"WelcomeText: \"unterminated\n",
+		"MaxUsers: ~\n",
+	} {
+		if _, err := ReadYAML(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cf, err := ReadTOML(strings.NewReader(`
+MaxUser = 10
+Port = 70000
+AllowHTML = "yes"
+MaxBandwidth = 72000.5
+WelcomeText = "ok"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cf.Validate()
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	expected := []string{
+		"line 2: unknown key MaxUser (did you mean MaxUsers?)",
+		"line 3: Port must be between 1 and 65535, got 70000",
+		"line 4: AllowHTML must be a boolean, got a string",
+		"line 5: MaxBandwidth must be an integer, got a float",
+	}
+	if len(verr.Problems) != len(expected) {
+		t.Fatalf("Expected %v problems, got %v", len(expected), verr.Problems)
+	}
+	for i, msg := range expected {
+		if verr.Problems[i] != msg {
+			t.Errorf("Expected %q, got %q", msg, verr.Problems[i])
+		}
+	}
+}
+
+func TestValidateUntyped(t *testing.T) {
+	cf, err := ReadFile(strings.NewReader("MaxUsers = lots\nSendVersion = maybe\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verr, ok := cf.Validate().(*ValidationError)
+	if !ok || len(verr.Problems) != 2 {
+		t.Fatalf("Expected 2 problems, got %v", verr)
+	}
+}
+
+func TestRegisterKey(t *testing.T) {
+	cf, err := ReadTOML(strings.NewReader("TestPluginKey = \"on\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cf.Validate() == nil {
+		t.Fatalf("Expected unknown key error")
+	}
+	RegisterKey("TestPluginKey")
+	defer delete(schema, "TestPluginKey")
+	if err := cf.Validate(); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+const murmurConfig = `; Murmur configuration
+database=
+welcometext="<br />Welcome to \"our\" server!<br />"
+port=64739
+host=
+bandwidth=72000
+users=10
+serverpassword=secret
+certrequired=True
+registerName=Mumble Server
+uname=mumble
+
+[Ice]
+Ice.Warn.UnknownProperties=1
+`
+
+func TestReadMurmurINI(t *testing.T) {
+	cf, err := ReadMurmurINI(strings.NewReader(murmurConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cf.Validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	expected := map[string]string{
+		"WelcomeText":  `<br />Welcome to "our" server!<br />`,
+		"Port":         "64739",
+		"MaxBandwidth": "72000",
+		"MaxUsers":     "10",
+		"CertRequired": "True",
+		"RegisterName": "Mumble Server",
+	}
+	for k, v := range expected {
+		if cf.Global[k] != v {
+			t.Errorf("%v: expected %q, got %q", k, v, cf.Global[k])
+		}
+	}
+	if _, ok := cf.Global["Address"]; ok {
+		t.Errorf("Empty host was not ignored")
+	}
+	if pw := cf.Global["ServerPassword"]; !strings.HasPrefix(pw, "argon2id$") || strings.Contains(pw, "secret") {
+		t.Errorf("Server password was not hashed: %q", pw)
+	}
+	if len(cf.Warnings) != 2 {
+		t.Errorf("Expected warnings for uname and [Ice], got %v", cf.Warnings)
+	}
+}
+
+func TestReadMurmurINIChannelLimit(t *testing.T) {
+	cf, err := ReadMurmurINI(strings.NewReader("usersperchannel=5\ndefaultchannel=3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cf.Validate(); err != nil {
+		t.Fatalf("Unexpected validation error: %v", err)
+	}
+	// MaxChannelUsers is the key the server enforces per channel.
+	if v := cf.Global["MaxChannelUsers"]; v != "5" {
+		t.Errorf("MaxChannelUsers: expected %q, got %q", "5", v)
+	}
+	if _, ok := cf.Global["DefaultChannel"]; ok {
+		t.Errorf("defaultchannel was imported")
+	}
+	if len(cf.Warnings) != 1 || !strings.Contains(cf.Warnings[0], "defaultchannel") {
+		t.Errorf("Expected a warning for defaultchannel, got %v", cf.Warnings)
+	}
+}
+
+func TestMurmurKey(t *testing.T) {
+	for key, expected := range map[string]string{
+		"users":       "MaxUsers",
+		"WelcomeText": "WelcomeText",
+		"welcometext": "WelcomeText",
+		"MaxUsers":    "MaxUsers",
+	} {
+		if got, ok := MurmurKey(key); !ok || got != expected {
+			t.Errorf("MurmurKey(%q): expected %q, got %q, %v", key, expected, got, ok)
+		}
+	}
+	for _, key := range []string{"defaultchannel", "NoSuchKey"} {
+		if _, ok := MurmurKey(key); ok {
+			t.Errorf("MurmurKey(%q): expected unknown key", key)
+		}
+	}
+}
+
+func TestCheckValue(t *testing.T) {
+	for _, kv := range [][2]string{
+		{"MaxUsers", "10"},
+		{"AllowHTML", "false"},
+		{"WelcomeText", "Hi"},
+	} {
+		if err := CheckValue(kv[0], kv[1]); err != nil {
+			t.Errorf("CheckValue(%q, %q): %v", kv[0], kv[1], err)
+		}
+	}
+	for _, kv := range [][2]string{
+		{"PasswordHashThreads", "0"},
+		{"PasswordHashTime", "0"},
+		{"MaxUsers", "many"},
+		{"AllowHTML", "maybe"},
+		{"UsernameRegex", "[a-z"},
+		{"NoSuchKey", "1"},
+	} {
+		if err := CheckValue(kv[0], kv[1]); err == nil {
+			t.Errorf("CheckValue(%q, %q): expected an error", kv[0], kv[1])
+		}
+	}
+}
+
+func TestValidateRegexp(t *testing.T) {
+	cf, err := ReadTOML(strings.NewReader("UsernameRegex = \"[a-z]+\"\nChannelNameRegex = \"[a-z\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verr, ok := cf.Validate().(*ValidationError)
+	if !ok || len(verr.Problems) != 1 {
+		t.Fatalf("Expected 1 problem, got %v", verr)
+	}
+	if !strings.HasPrefix(verr.Problems[0], "line 2: ChannelNameRegex must be a regular expression") {
+		t.Errorf("Unexpected problem %q", verr.Problems[0])
+	}
+}
+
+func TestValidatePerServer(t *testing.T) {
+	cf, err := ReadFile(strings.NewReader("BlobDir = blobs\n[server 2]\nServerDir = /srv/tenant2\nBlobDir = /srv/tenant2/blob\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verr, ok := cf.Validate().(*ValidationError)
+	if !ok || len(verr.Problems) != 1 {
+		t.Fatalf("Expected 1 problem, got %v", verr)
+	}
+	if verr.Problems[0] != "line 1: BlobDir can only be set in a [server N] section" {
+		t.Errorf("Unexpected problem %q", verr.Problems[0])
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"mumble.info/grumble/pkg/password"
+)
+
+// murmurKeys maps Murmur's configuration keys onto Grumble's.
+var murmurKeys = map[string]string{
+	"welcometext":        "WelcomeText",
+	"host":               "Address",
+	"port":               "Port",
+	"bandwidth":          "MaxBandwidth",
+	"users":              "MaxUsers",
+	"usersperchannel":    "MaxChannelUsers",
+	"textmessagelength":  "MaxTextMessageLength",
+	"imagemessagelength": "MaxImageMessageLength",
+	"messagelimit":       "MessageLimit",
+	"messageburst":       "MessageBurst",
+	"allowhtml":          "AllowHTML",
+	"sendversion":        "SendVersion",
+	"serverpassword":     "ServerPassword",
+	"certrequired":       "CertRequired",
+	"bonjour":            "Bonjour",
+	"registername":       "RegisterName",
+	"registerhostname":   "RegisterHost",
+	"registerpassword":   "RegisterPassword",
+	"registerurl":        "RegisterWebUrl",
+	"registerlocation":   "RegisterLocation",
+	"username":           "UsernameRegex",
+	"channelname":        "ChannelNameRegex",
+}
+
+// MurmurKey returns the Grumble key that key stands for. Murmur's keys,
+// as in murmur.ini, are matched regardless of case. Grumble's own keys
+// are accepted as they are. ok is false if key is neither.
+func MurmurKey(key string) (grumbleKey string, ok bool) {
+	if grumbleKey, ok = murmurKeys[strings.ToLower(key)]; ok {
+		return grumbleKey, true
+	}
+	if _, ok = schema[key]; ok {
+		return key, true
+	}
+	return "", false
+}
+
+// ReadMurmurINI reads a Murmur configuration file (murmur.ini) from r.
+//
+// Murmur's well-known keys are mapped onto the equivalent Grumble keys
+// and apply to all virtual servers. Keys without a Grumble equivalent,
+// and keys in sections such as [Ice], are ignored with a warning in the
+// returned file's Warnings. The plain-text serverpassword is stored
+// hashed, the way Grumble stores it.
+func ReadMurmurINI(r io.Reader) (*ConfigFile, error) {
+	cf := NewConfigFile()
+	section := ""
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %v: malformed section header", lineno)
+			}
+			section = line[1 : len(line)-1]
+			if !strings.EqualFold(section, "General") {
+				cf.warnf("line %v: ignoring section [%v]", lineno, section)
+			}
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			return nil, fmt.Errorf("line %v: expected key=value", lineno)
+		}
+		if len(section) > 0 && !strings.EqualFold(section, "General") {
+			continue
+		}
+
+		murmurKey := strings.TrimSpace(line[:eq])
+		value, err := unquoteMurmurValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", lineno, err)
+		}
+
+		// murmur.ini lists most settings with an empty value,
+		// meaning Murmur's default.
+		if len(value) == 0 {
+			continue
+		}
+
+		key, ok := murmurKeys[strings.ToLower(murmurKey)]
+		if !ok {
+			cf.warnf("line %v: ignoring unsupported Murmur setting %v", lineno, murmurKey)
+			continue
+		}
+
+		switch key {
+		case "ServerPassword":
+			value, err = password.Hash(value, password.DefaultParams)
+			if err != nil {
+				return nil, err
+			}
+		}
+		cf.Add(Entry{Key: key, Value: value, Line: lineno})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cf, nil
+}
+
+// warnf records a non-fatal problem found while reading the file.
+func (cf *ConfigFile) warnf(format string, args ...interface{}) {
+	cf.Warnings = append(cf.Warnings, fmt.Sprintf(format, args...))
+}
+
+// unquoteMurmurValue removes the quotes around a value, as written by Qt.
+func unquoteMurmurValue(s string) (string, error) {
+	if len(s) == 0 || s[0] != '"' {
+		return s, nil
+	}
+	if len(s) < 2 || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s = s[1 : len(s)-1]
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,357 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The type of a configuration key.
+type keyType int
+
+const (
+	typeString keyType = iota
+	typeInt
+	typeBool
+	typeRegexp
+)
+
+func (t keyType) String() string {
+	switch t {
+	case typeInt:
+		return "an integer"
+	case typeBool:
+		return "a boolean"
+	case typeRegexp:
+		return "a regular expression"
+	}
+	return "a string"
+}
+
+// A keySpec describes the values accepted for a configuration key.
+// Min and Max are only used for integer keys. The values of Secret keys
+// are left out of logs. PerServer keys may only be set for a single
+// virtual server.
+type keySpec struct {
+	Type      keyType
+	Min       int64
+	Max       int64
+	Secret    bool
+	PerServer bool
+}
+
+func stringKey() keySpec {
+	return keySpec{Type: typeString}
+}
+
+func secretKey() keySpec {
+	return keySpec{Type: typeString, Secret: true}
+}
+
+func perServerKey() keySpec {
+	return keySpec{Type: typeString, PerServer: true}
+}
+
+func boolKey() keySpec {
+	return keySpec{Type: typeBool}
+}
+
+func regexpKey() keySpec {
+	return keySpec{Type: typeRegexp}
+}
+
+func intKey(min, max int64) keySpec {
+	return keySpec{Type: typeInt, Min: min, Max: max}
+}
+
+// schema lists the keys that may appear in a configuration file.
+var schema = map[string]keySpec{
+	"Address":               stringKey(),
+	"ProxyAddress":          stringKey(),
+	"ProxyTrustedNetworks":  stringKey(),
+	"Port":                  intKey(1, 65535),
+	"WebPort":               intKey(1, 65535),
+	"NoWebServer":           boolKey(),
+	"MaxBandwidth":          intKey(8000, 10000000),
+	"MaxUsers":              intKey(1, 1000000),
+	"MaxUsersPerChannel":    intKey(0, 1000000),
+	"MaxChannelUsers":       intKey(0, 1000000),
+	"QueueChannel":          intKey(0, math.MaxInt32),
+	"AFKChannel":            intKey(0, math.MaxInt32),
+	"Maintenance":           boolKey(),
+	"MaintenanceMessage":    stringKey(),
+	"MaintenanceChannel":    intKey(0, math.MaxInt32),
+	"ChannelSyncBatch":      intKey(1, 65536),
+	"MaxTextMessageLength":  intKey(0, math.MaxInt32),
+	"MaxImageMessageLength": intKey(0, math.MaxInt32),
+	"MaxMessageSize":        intKey(65536, math.MaxInt32),
+	"MessageLimit":          intKey(0, math.MaxInt32),
+	"MessageBurst":          intKey(0, math.MaxInt32),
+	"MessageFloodKick":      intKey(0, math.MaxInt32),
+	"ControlMessageLimit":   intKey(0, math.MaxInt32),
+	"ControlMessageBurst":   intKey(0, math.MaxInt32),
+	"AllowHTML":             boolKey(),
+	"WordFilter":            stringKey(),
+	"MessageTemplates":      stringKey(),
+	"ChannelTemplates":      stringKey(),
+	"EscalationRules":       stringKey(),
+	"ClientFeatures":        stringKey(),
+	"ClientDenylist":        stringKey(),
+	"ClientDenylistMessage": stringKey(),
+	"DefaultLocale":         stringKey(),
+	"Scripts":               stringKey(),
+	"Plugins":               stringKey(),
+	"ChatCommandsDisabled":  stringKey(),
+	"OfflineMessageQuota":   intKey(0, 1000),
+	"OfflineMessageExpiry":  intKey(0, 3650),
+	"AuditLogRetention":     intKey(0, 36500),
+	"DefaultChannel":        intKey(0, math.MaxInt32),
+	"RememberChannel":       boolKey(),
+	"WelcomeText":           stringKey(),
+	"ShutdownMessage":       stringKey(),
+	"RestartMessage":        stringKey(),
+	"SendVersion":           boolKey(),
+	"SendOSInfo":            boolKey(),
+	"ServerRelease":         stringKey(),
+	"HideClientOS":          boolKey(),
+	"ServerPassword":        secretKey(),
+	"CertRequired":          boolKey(),
+	"CertRequireVerified":   boolKey(),
+	"CertCAFile":            stringKey(),
+	"CertCRLFile":           stringKey(),
+	"CertOCSPResponder":     stringKey(),
+	"CertRecheckInterval":   intKey(0, math.MaxInt32),
+	"CryptRekeyInterval":    intKey(0, math.MaxInt32),
+	"CryptRekeyPackets":     intKey(0, math.MaxInt32),
+	"UDPTimeout":            intKey(0, math.MaxInt32),
+	"UDPSockets":            intKey(1, 64),
+	"VersionTimeout":        intKey(0, math.MaxInt32),
+	"AuthTimeout":           intKey(0, math.MaxInt32),
+	"UDPPingRate":           intKey(0, math.MaxInt32),
+	"UDPPingHostRate":       intKey(0, math.MaxInt32),
+	"UDPPingSessionOnly":    boolKey(),
+	"TunnelJitterDelay":     intKey(0, 1000),
+	"PresenceBatchInterval": intKey(0, 10000),
+	"VoiceLossWarning":      intKey(0, 100),
+	"PositionalRadius":      intKey(0, math.MaxInt32),
+	"PingSummaryInterval":   intKey(0, math.MaxInt32),
+	"ChannelCreateGroup":    stringKey(),
+	"ChannelCreateQuota":    intKey(0, math.MaxInt32),
+	"PasswordHashTime":      intKey(1, 100),
+	"PasswordHashMemory":    intKey(8, 4*1024*1024),
+	"PasswordHashThreads":   intKey(1, 255),
+	"Bonjour":               boolKey(),
+
+	"RegisterName":     stringKey(),
+	"RegisterHost":     stringKey(),
+	"RegisterPassword": secretKey(),
+	"RegisterWebUrl":   stringKey(),
+	"RegisterLocation": stringKey(),
+
+	"EnrollEnabled":          boolKey(),
+	"EnrollOIDCIssuer":       stringKey(),
+	"EnrollOIDCClientID":     stringKey(),
+	"EnrollOIDCClientSecret": secretKey(),
+	"EnrollOIDCRedirectURL":  stringKey(),
+	"EnrollOIDCUserClaim":    stringKey(),
+
+	"InviteOnly": boolKey(),
+
+	"SelfRegisterVerified": boolKey(),
+	"NamesCaseInsensitive": boolKey(),
+	"HashUserAddresses":    boolKey(),
+
+	"UsernameRegex":        regexpKey(),
+	"UsernameMaxLength":    intKey(0, math.MaxInt32),
+	"ReservedNamePrefixes": stringKey(),
+	"ChannelNameRegex":     regexpKey(),
+	"ChannelNameMaxLength": intKey(0, math.MaxInt32),
+
+	"CertAutoRegister":       boolKey(),
+	"CertAutoRegisterCAFile": stringKey(),
+	"CertRotation":           boolKey(),
+
+	"DiscordToken":         secretKey(),
+	"DiscordChannel":       stringKey(),
+	"DiscordBridgeChannel": intKey(0, math.MaxInt32),
+	"DiscordMessageLimit":  intKey(0, math.MaxInt32),
+	"DiscordMessageBurst":  intKey(0, math.MaxInt32),
+
+	"MQTTBroker":      stringKey(),
+	"MQTTTopicPrefix": stringKey(),
+	"MQTTClientID":    stringKey(),
+	"MQTTUsername":    stringKey(),
+	"MQTTPassword":    secretKey(),
+
+	"FederationAddress": stringKey(),
+	"FederationLinks":   stringKey(),
+
+	"DirectoryURL":              stringKey(),
+	"DirectoryBindDN":           stringKey(),
+	"DirectoryBindPassword":     secretKey(),
+	"DirectoryBaseDN":           stringKey(),
+	"DirectoryGroupFilter":      stringKey(),
+	"DirectoryMemberAttributes": stringKey(),
+	"DirectoryToken":            secretKey(),
+	"DirectoryGroupMap":         stringKey(),
+	"DirectorySyncInterval":     intKey(0, math.MaxInt32),
+	"DirectoryDryRun":           boolKey(),
+
+	"ServerDir": perServerKey(),
+	"BlobDir":   perServerKey(),
+}
+
+// RegisterKey adds a string-valued key to the keys that may appear in
+// a configuration file. It is meant to be called from init functions,
+// such as those of plugins that take their own settings, and panics if
+// the key is already known.
+func RegisterKey(key string) {
+	if _, exists := schema[key]; exists {
+		panic("serverconf: key " + key + " registered twice")
+	}
+	schema[key] = stringKey()
+}
+
+// IsSecret reports whether the values of key, such as passwords, must
+// be left out of logs. Unknown keys, such as those Grumble keeps in a
+// server's state, are taken to be secret.
+func IsSecret(key string) bool {
+	spec, ok := schema[key]
+	return !ok || spec.Secret
+}
+
+// A ValidationError lists the problems found in a configuration file.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0]
+	}
+	return fmt.Sprintf("%v problems:\n\t%v", len(e.Problems), strings.Join(e.Problems, "\n\t"))
+}
+
+// checkEntry checks a single entry against the schema.
+func checkEntry(e Entry) error {
+	spec, ok := schema[e.Key]
+	if !ok {
+		msg := fmt.Sprintf("%v: unknown key %v", e.where(), e.Key)
+		if suggestion := suggestKey(e.Key); len(suggestion) > 0 {
+			msg += fmt.Sprintf(" (did you mean %v?)", suggestion)
+		}
+		return fmt.Errorf("%v", msg)
+	}
+
+	if spec.PerServer && e.Server == 0 {
+		return fmt.Errorf("%v: %v can only be set in a [server N] section", e.where(), e.Key)
+	}
+
+	if e.Kind != KindUntyped && e.Kind != spec.kind() {
+		return fmt.Errorf("%v: %v must be %v, got %v", e.where(), e.Key, spec.Type, e.Kind)
+	}
+
+	if err := spec.check(e.Key, e.Value); err != nil {
+		return fmt.Errorf("%v: %v", e.where(), err)
+	}
+	return nil
+}
+
+// CheckValue checks a value for key against the schema, the way
+// Validate checks the entries of a file.
+func CheckValue(key, value string) error {
+	spec, ok := schema[key]
+	if !ok {
+		return fmt.Errorf("unknown key %v", key)
+	}
+	return spec.check(key, value)
+}
+
+// check checks that value is of the spec's type and within its range.
+func (spec keySpec) check(key, value string) error {
+	switch spec.Type {
+	case typeInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%v must be %v, got %q", key, spec.Type, value)
+		}
+		if n < spec.Min || n > spec.Max {
+			return fmt.Errorf("%v must be between %v and %v, got %v", key, spec.Min, spec.Max, n)
+		}
+	case typeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%v must be %v (true or false), got %q", key, spec.Type, value)
+		}
+	case typeRegexp:
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("%v must be %v: %v", key, spec.Type, err)
+		}
+	}
+	return nil
+}
+
+func (spec keySpec) kind() ValueKind {
+	switch spec.Type {
+	case typeInt:
+		return KindInt
+	case typeBool:
+		return KindBool
+	}
+	return KindString
+}
+
+// suggestKey returns the known key closest to key, if any is close enough.
+func suggestKey(key string) string {
+	best := ""
+	bestDist := 3
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d := editDistance(strings.ToLower(key), strings.ToLower(name))
+		if d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance computes the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ReadTOML reads a configuration file in TOML format from r.
+//
+// Top-level keys apply to all virtual servers. Keys in a [server.N]
+// table apply to the virtual server with id N:
+//
+//	WelcomeText = """
+//	Welcome to <b>our</b> server!"""
+//	MaxBandwidth = 72_000
+//
+//	[server.2]
+//	MaxUsers = 10
+//
+// Values must be strings, integers, floats or booleans.
+func ReadTOML(r io.Reader) (*ConfigFile, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	md, err := toml.Decode(string(buf), &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := newTOMLLines(string(buf))
+	cf := NewConfigFile()
+	for _, key := range md.Keys() {
+		line := lines.find(key)
+		typ := md.Type(key...)
+		if key[0] != "server" {
+			if len(key) != 1 || typ == "Hash" || typ == "ArrayHash" {
+				return nil, fmt.Errorf("line %v: unsupported table [%v], expected [server.<id>]", line, key[0])
+			}
+			e, err := tomlEntry(0, key[0], doc[key[0]], line)
+			if err != nil {
+				return nil, err
+			}
+			cf.Add(e)
+			continue
+		}
+
+		if typ != "Hash" && len(key) < 3 {
+			return nil, fmt.Errorf("line %v: %v must be a table [server.<id>]", line, key)
+		}
+		if len(key) == 1 {
+			continue
+		}
+		id, err := parseServerId(key[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+		if len(key) == 2 {
+			continue
+		}
+		if len(key) > 3 || typ == "Hash" {
+			return nil, fmt.Errorf("line %v: unsupported table [%v]", line, key)
+		}
+		section := doc["server"].(map[string]interface{})[key[1]].(map[string]interface{})
+		e, err := tomlEntry(id, key[2], section[key[2]], line)
+		if err != nil {
+			return nil, err
+		}
+		cf.Add(e)
+	}
+	return cf, nil
+}
+
+func tomlEntry(server int64, key string, value interface{}, line int) (Entry, error) {
+	e := Entry{Server: server, Key: key, Line: line}
+	switch v := value.(type) {
+	case string:
+		e.Value, e.Kind = v, KindString
+	case int64:
+		e.Value, e.Kind = strconv.FormatInt(v, 10), KindInt
+	case float64:
+		e.Value, e.Kind = strconv.FormatFloat(v, 'g', -1, 64), KindFloat
+	case bool:
+		e.Value, e.Kind = strconv.FormatBool(v), KindBool
+	default:
+		return e, fmt.Errorf("line %v: %v must be a string, an integer, a float or a boolean", line, key)
+	}
+	return e, nil
+}
+
+func parseServerId(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid server id %q", s)
+	}
+	return id, nil
+}
+
+// tomlLines finds the lines keys are defined on, which the decoder
+// doesn't report. It does list the keys in the order they appear in,
+// so each key is looked for from the line of the previous one on.
+// Lines within multi-line strings are skipped.
+type tomlLines struct {
+	lines []string
+	// Whether the line starts within a multi-line string.
+	quoted []bool
+	next   int
+}
+
+func newTOMLLines(src string) *tomlLines {
+	tl := &tomlLines{lines: strings.Split(src, "\n")}
+	open := ""
+	for _, line := range tl.lines {
+		tl.quoted = append(tl.quoted, len(open) > 0)
+		for {
+			if len(open) > 0 {
+				i := strings.Index(line, open)
+				if i == -1 {
+					break
+				}
+				line, open = line[i+3:], ""
+				continue
+			}
+			i, j := strings.Index(line, `"""`), strings.Index(line, `'''`)
+			if i == -1 || j != -1 && j < i {
+				i = j
+			}
+			if i == -1 {
+				break
+			}
+			line, open = line[i+3:], line[i:i+3]
+		}
+	}
+	return tl
+}
+
+// find returns the line on which key is defined, or 0 if it can't be
+// found. A table header defines both the table and its parents.
+func (tl *tomlLines) find(key toml.Key) int {
+	for i := tl.next; i < len(tl.lines); i++ {
+		if tl.quoted[i] {
+			continue
+		}
+		line := strings.TrimSpace(tl.lines[i])
+		if strings.HasPrefix(line, "[") {
+			end := strings.LastIndex(line, "]")
+			if end == -1 {
+				continue
+			}
+			name := splitTOMLKey(strings.Trim(line[:end], "[]"))
+			if len(name) >= len(key) && equalKeys(name[:len(key)], key) {
+				tl.next = i
+				return i + 1
+			}
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq == -1 {
+			continue
+		}
+		name := splitTOMLKey(line[:eq])
+		if len(name) <= len(key) && equalKeys(name, key[len(key)-len(name):]) {
+			tl.next = i + 1
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// splitTOMLKey splits a dotted key, and removes the quotes around its
+// parts.
+func splitTOMLKey(s string) []string {
+	parts := strings.Split(s, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return parts
+}
+
+func equalKeys(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
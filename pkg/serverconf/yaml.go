@@ -0,0 +1,110 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package serverconf
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadYAML reads a configuration file in YAML format from r.
+//
+// Top-level keys apply to all virtual servers. Keys in the mapping
+// under server.N apply to the virtual server with id N:
+//
+//	WelcomeText: |
+//	  Welcome to <b>our</b> server!
+//	MaxBandwidth: 72000
+//
+//	server:
+//	  2:
+//	    MaxUsers: 10
+//
+// Values must be scalars; null values are not allowed.
+func ReadYAML(r io.Reader) (*ConfigFile, error) {
+	cf := NewConfigFile()
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err == io.EOF {
+		return cf, nil
+	} else if err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %v: expected a mapping of keys to values", root.Line)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], yamlValue(root.Content[i+1])
+		if key.Value != "server" {
+			e, err := yamlEntry(0, key, value)
+			if err != nil {
+				return nil, err
+			}
+			cf.Add(e)
+			continue
+		}
+		if value.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %v: server must be a mapping of server ids", key.Line)
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			srvKey, srv := value.Content[j], yamlValue(value.Content[j+1])
+			id, err := parseServerId(srvKey.Value)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", srvKey.Line, err)
+			}
+			if srv.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("line %v: server %v must be a mapping", srvKey.Line, id)
+			}
+			for k := 0; k+1 < len(srv.Content); k += 2 {
+				e, err := yamlEntry(id, srv.Content[k], yamlValue(srv.Content[k+1]))
+				if err != nil {
+					return nil, err
+				}
+				cf.Add(e)
+			}
+		}
+	}
+	return cf, nil
+}
+
+// yamlValue returns the node an alias refers to, or node itself.
+func yamlValue(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		return node.Alias
+	}
+	return node
+}
+
+func yamlEntry(server int64, key, value *yaml.Node) (Entry, error) {
+	e := Entry{Server: server, Key: key.Value, Value: value.Value, Line: key.Line}
+	if value.Kind != yaml.ScalarNode {
+		return e, fmt.Errorf("line %v: %v must be a scalar", key.Line, key.Value)
+	}
+	switch value.ShortTag() {
+	case "!!int":
+		var n int64
+		if err := value.Decode(&n); err != nil {
+			return e, fmt.Errorf("line %v: %v", key.Line, err)
+		}
+		e.Value, e.Kind = strconv.FormatInt(n, 10), KindInt
+	case "!!float":
+		e.Kind = KindFloat
+	case "!!bool":
+		var b bool
+		if err := value.Decode(&b); err != nil {
+			return e, fmt.Errorf("line %v: %v", key.Line, err)
+		}
+		e.Value, e.Kind = strconv.FormatBool(b), KindBool
+	case "!!null":
+		return e, fmt.Errorf("line %v: %v must not be null", key.Line, key.Value)
+	default:
+		e.Kind = KindString
+	}
+	return e, nil
+}
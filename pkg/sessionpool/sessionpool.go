@@ -75,6 +75,42 @@ func (pool *SessionPool) Get() (id uint32) {
 	return
 }
 
+// Reserve takes a given session ID out of the SessionPool, as if it had
+// been returned by Get(). It is used for sessions carried over from
+// another process. Reserving an ID that is in use panics.
+func (pool *SessionPool) Reserve(id uint32) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if id == 0 {
+		panic("Attempt to reserve invalid session ID")
+	}
+
+	if id > pool.cur {
+		// The IDs skipped over are free to be handed out.
+		for skipped := pool.cur + 1; skipped < id; skipped++ {
+			pool.unused = append(pool.unused, skipped)
+		}
+		pool.cur = id
+	} else {
+		found := false
+		for i, unused := range pool.unused {
+			if unused == id {
+				pool.unused = append(pool.unused[:i], pool.unused[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			panic("Attempt to reserve session ID in use")
+		}
+	}
+
+	if pool.used != nil {
+		pool.used[id] = true
+	}
+}
+
 // Reclaim a session ID so it can be reused.
 func (pool *SessionPool) Reclaim(id uint32) {
 	pool.mutex.Lock()
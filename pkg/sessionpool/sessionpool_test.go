@@ -49,3 +49,33 @@ func TestUseTracking(t *testing.T) {
 	pool.EnableUseTracking()
 	pool.Reclaim(42)
 }
+
+func TestReserve(t *testing.T) {
+	pool := New()
+	pool.EnableUseTracking()
+	pool.Reserve(3)
+	pool.Reserve(1)
+
+	if id := pool.Get(); id != 2 {
+		t.Errorf("Got %v, expected 2", id)
+	}
+	if id := pool.Get(); id != 4 {
+		t.Errorf("Got %v, expected 4", id)
+	}
+
+	pool.Reclaim(3)
+	pool.Reserve(3)
+}
+
+func TestReserveInUse(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r != "Attempt to reserve session ID in use" {
+			t.Errorf("Expected reservation panic")
+		}
+	}()
+
+	pool := New()
+	pool.Get()
+	pool.Reserve(1)
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+// Package wordfilter implements regular expression based filtering
+// of chat messages.
+//
+// A filter is read from a rules file with one rule per line:
+//
+//	# action  punishment  pattern
+//	censor    none        (?i)\bdarn\b
+//	drop      kick        (?i)free nitro
+//	flag      warn        (?i)\bidiot\b
+//
+// The action says what happens to a matching message: it is dropped,
+// the matching text is censored, or the message is passed on but
+// flagged. The punishment says what happens to its sender: nothing,
+// a warning, a server mute or a kick. The pattern is the rest of the
+// line, in the syntax of package regexp.
+package wordfilter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// An Action says what happens to a matching message.
+type Action int
+
+const (
+	// Flag passes the message on unchanged.
+	Flag Action = iota
+	// Censor replaces the matching text with asterisks.
+	Censor
+	// Drop discards the message.
+	Drop
+)
+
+var actionNames = []string{"flag", "censor", "drop"}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// A Punishment says what happens to the sender of a matching message.
+type Punishment int
+
+const (
+	None Punishment = iota
+	Warn
+	Mute
+	Kick
+)
+
+var punishmentNames = []string{"none", "warn", "mute", "kick"}
+
+func (p Punishment) String() string {
+	return punishmentNames[p]
+}
+
+// A Rule is a single line of a rules file.
+type Rule struct {
+	Line       int
+	Action     Action
+	Punishment Punishment
+	Pattern    *regexp.Regexp
+}
+
+// A Filter is an ordered list of rules.
+type Filter struct {
+	Rules []*Rule
+}
+
+// A Result describes the outcome of filtering a message.
+type Result struct {
+	// The message text, with censored parts replaced.
+	Text string
+	// The rules that matched the message.
+	Matched []*Rule
+	// Whether the message should be dropped.
+	Drop bool
+	// The most severe punishment of the matched rules.
+	Punishment Punishment
+}
+
+// Load reads a rules file.
+func Load(fn string) (*Filter, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads rules from r.
+func Parse(r io.Reader) (*Filter, error) {
+	filter := &Filter{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+
+		name, rest := nextField(text)
+		action, ok := lookup(actionNames, name)
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown action %q", line, name)
+		}
+		name, rest = nextField(rest)
+		punishment, ok := lookup(punishmentNames, name)
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown punishment %q", line, name)
+		}
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("line %v: missing pattern", line)
+		}
+		pattern, err := regexp.Compile(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", line, err)
+		}
+
+		filter.Rules = append(filter.Rules, &Rule{
+			Line:       line,
+			Action:     Action(action),
+			Punishment: Punishment(punishment),
+			Pattern:    pattern,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// nextField splits the first whitespace-separated field off s.
+func nextField(s string) (field, rest string) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+func lookup(names []string, name string) (int, bool) {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Check runs text through the filter.
+func (filter *Filter) Check(text string) Result {
+	result := Result{Text: text}
+	for _, rule := range filter.Rules {
+		if !rule.Pattern.MatchString(result.Text) {
+			continue
+		}
+		result.Matched = append(result.Matched, rule)
+		if rule.Punishment > result.Punishment {
+			result.Punishment = rule.Punishment
+		}
+		switch rule.Action {
+		case Drop:
+			result.Drop = true
+		case Censor:
+			result.Text = rule.Pattern.ReplaceAllStringFunc(result.Text, func(match string) string {
+				return strings.Repeat("*", utf8.RuneCountInString(match))
+			})
+		}
+	}
+	if result.Drop {
+		result.Text = ""
+	}
+	return result
+}
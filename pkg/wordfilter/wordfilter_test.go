@@ -0,0 +1,68 @@
+// Copyright (c) 2026 The Grumble Authors
+// The use of this source code is goverened by a BSD-style
+// license that can be found in the LICENSE-file.
+
+package wordfilter
+
+import (
+	"strings"
+	"testing"
+)
+
+const testRules = `
+# action  punishment  pattern
+censor    none        (?i)\bdarn\b
+flag	warn	(?i)\bidiot\b
+DROP      kick        (?i)free  nitro
+`
+
+func TestParse(t *testing.T) {
+	filter, err := Parse(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filter.Rules) != 3 {
+		t.Fatalf("Expected 3 rules, got %v", len(filter.Rules))
+	}
+	rule := filter.Rules[2]
+	if rule.Line != 5 || rule.Action != Drop || rule.Punishment != Kick || rule.Pattern.String() != "(?i)free  nitro" {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+
+	for _, bad := range []string{
+		"censor",
+		"censor none",
+		"block none x",
+		"censor ban x",
+		"censor none (",
+	} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	filter, err := Parse(strings.NewReader(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := filter.Check("hello world")
+	if result.Text != "hello world" || len(result.Matched) != 0 || result.Drop || result.Punishment != None {
+		t.Errorf("Unexpected result for clean text %+v", result)
+	}
+
+	result = filter.Check("Darn it, you idiot")
+	if result.Text != "**** it, you idiot" {
+		t.Errorf("Unexpected censored text %q", result.Text)
+	}
+	if len(result.Matched) != 2 || result.Drop || result.Punishment != Warn {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	result = filter.Check("get FREE  NITRO, darn")
+	if !result.Drop || result.Text != "" || result.Punishment != Kick {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}